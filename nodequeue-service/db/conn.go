@@ -50,7 +50,21 @@ func OpenFromEnv() (*sql.DB, error) {
 		return nil, nil
 	}
 
-	db, err := sql.Open("pgx", cfg.DSN())
+	return open(cfg.DSN())
+}
+
+// OpenReplicaFromEnv opens the read replica named by DB_REPLICA_DSN. It returns nil, nil when that
+// is not set.
+func OpenReplicaFromEnv() (*sql.DB, error) {
+	dsn := os.Getenv("DB_REPLICA_DSN")
+	if dsn == "" {
+		return nil, nil
+	}
+	return open(dsn)
+}
+
+func open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -1,5 +1,7 @@
 -- Schema for NodeQueue persistence/audit layer.
--- This file runs once when the Postgres data volume is first initialized.
+-- This file runs when the Postgres data volume is first initialized and again on every service
+-- startup (see db.Migrate), so every statement must be idempotent. Columns added to an existing
+-- table must also be added in 02_migrations.sql.
 
 -- For UUID helpers (optional but handy for manual inserts).
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
@@ -19,7 +21,27 @@ CREATE TABLE IF NOT EXISTS entities (
 CREATE TABLE IF NOT EXISTS resources (
   id         text PRIMARY KEY,
   capacity   integer NOT NULL CHECK (capacity >= 0),
-  created_at timestamptz NOT NULL DEFAULT now()
+  paused     boolean NOT NULL DEFAULT false,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  -- Settings (see resource.Resource); 0/false/NULL leaves each one off.
+  auto_promote          boolean NOT NULL DEFAULT false,
+  max_per_entity        integer NOT NULL DEFAULT 0,
+  fifo_strict           boolean NOT NULL DEFAULT false,
+  pressure_waiting      integer NOT NULL DEFAULT 0,
+  pressure_seconds      integer NOT NULL DEFAULT 0,
+  max_wait_ms           bigint NOT NULL DEFAULT 0,
+  -- Waiting lane names in priority order, as a JSON array.
+  lanes                 jsonb,
+  reserved_for_priority integer NOT NULL DEFAULT 0,
+  alloc_rate_per_sec    double precision NOT NULL DEFAULT 0
+);
+
+-- Key/value metadata on resources (see GET /resources?label=).
+CREATE TABLE IF NOT EXISTS resource_labels (
+  resource_id text NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+  key         text NOT NULL,
+  value       text NOT NULL,
+  PRIMARY KEY (resource_id, key)
 );
 
 CREATE TABLE IF NOT EXISTS nodes (
@@ -27,7 +49,17 @@ CREATE TABLE IF NOT EXISTS nodes (
   entity_id   uuid NOT NULL REFERENCES entities(id) ON DELETE RESTRICT,
   resource_id text REFERENCES resources(id) ON DELETE SET NULL,
   completed   boolean NOT NULL DEFAULT false,
-  created_at  timestamptz NOT NULL DEFAULT now()
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  -- Capacity units the node consumes while in service.
+  weight      integer NOT NULL DEFAULT 1 CHECK (weight >= 1),
+  -- Retry state (see QueueService.FailNode).
+  attempts    integer NOT NULL DEFAULT 0,
+  not_before  timestamptz,
+  failed      boolean NOT NULL DEFAULT false,
+  -- When an active node is cancelled (see QueueService.SetNodeDeadline).
+  deadline_ts timestamptz,
+  -- Idempotency token the node was completed with (see QueueService.CompleteNodeWithToken).
+  completion_token text
 );
 
 CREATE TABLE IF NOT EXISTS node_logs (
@@ -35,11 +67,53 @@ CREATE TABLE IF NOT EXISTS node_logs (
   node_id     uuid NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
   action      text NOT NULL,
   resource_id text REFERENCES resources(id) ON DELETE SET NULL,
+  -- Resource a moved_to_waiting_queue entry moved the node off (NULL when it was not a move).
+  -- Not a foreign key, so the history survives the resource being deleted.
+  from_resource_id text,
   ts          timestamptz NOT NULL DEFAULT now(),
   details     jsonb
 );
 
+-- Append-only operator notes on nodes.
+CREATE TABLE IF NOT EXISTS node_notes (
+  id      bigserial PRIMARY KEY,
+  node_id uuid NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+  author  text NOT NULL,
+  text    text NOT NULL,
+  ts      timestamptz NOT NULL DEFAULT now()
+);
+
+-- Free-form labels on nodes (see GET /nodes?tag=).
+CREATE TABLE IF NOT EXISTS node_tags (
+  node_id uuid NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+  tag     text NOT NULL,
+  PRIMARY KEY (node_id, tag)
+);
+
+-- Resources a node may be placed on; a node without rows may use any resource.
+CREATE TABLE IF NOT EXISTS node_allowed_resources (
+  node_id     uuid NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+  resource_id text NOT NULL,
+  PRIMARY KEY (node_id, resource_id)
+);
+
+-- Outcome (and optional small JSON result) recorded when a node completes.
+CREATE TABLE IF NOT EXISTS node_results (
+  node_id uuid PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+  outcome text NOT NULL,
+  result  jsonb,
+  ts      timestamptz NOT NULL DEFAULT now()
+);
+
+-- Completed nodes that have been purged from the service's memory.
+CREATE TABLE IF NOT EXISTS node_archive (
+  node_id     uuid PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+  archived_at timestamptz NOT NULL DEFAULT now()
+);
+
 CREATE INDEX IF NOT EXISTS idx_nodes_resource_id ON nodes(resource_id);
 CREATE INDEX IF NOT EXISTS idx_node_logs_node_ts ON node_logs(node_id, ts);
+CREATE INDEX IF NOT EXISTS idx_node_notes_node_ts ON node_notes(node_id, ts);
+CREATE INDEX IF NOT EXISTS idx_node_tags_tag ON node_tags(tag);
 
 
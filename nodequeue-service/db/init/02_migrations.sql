@@ -0,0 +1,34 @@
+-- Migrations for databases created by an older version of 00_schema.sql.
+-- Postgres only runs this directory's scripts on an empty data volume, so the service also applies
+-- this file on every startup (see db.Migrate), after 00_schema.sql has created any missing tables.
+-- Every statement must be idempotent.
+
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS paused boolean NOT NULL DEFAULT false;
+
+-- Retry state (see QueueService.FailNode).
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS not_before timestamptz;
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS failed boolean NOT NULL DEFAULT false;
+
+-- Capacity units the node consumes while in service.
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS weight integer NOT NULL DEFAULT 1 CHECK (weight >= 1);
+
+-- Resource a moved_to_waiting_queue entry moved the node off.
+ALTER TABLE IF EXISTS node_logs ADD COLUMN IF NOT EXISTS from_resource_id text;
+
+-- Resource settings (see resource.Resource).
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS auto_promote boolean NOT NULL DEFAULT false;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS max_per_entity integer NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS fifo_strict boolean NOT NULL DEFAULT false;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS pressure_waiting integer NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS pressure_seconds integer NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS max_wait_ms bigint NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS lanes jsonb;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS reserved_for_priority integer NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS resources ADD COLUMN IF NOT EXISTS alloc_rate_per_sec double precision NOT NULL DEFAULT 0;
+
+-- Deadline after which an active node is cancelled.
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS deadline_ts timestamptz;
+
+-- Idempotency token the node was completed with.
+ALTER TABLE IF EXISTS nodes ADD COLUMN IF NOT EXISTS completion_token text;
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"nodequeue-service/resource"
+)
+
+// InstrumentedStore is a Store decorator that times every call to an inner Store and reports it
+// to a callback, keeping latency metrics out of the concrete stores.
+//
+// op is the Store method name (e.g. "InsertNodeLog"); d and err are that call's duration and
+// error. Results are forwarded unchanged.
+type InstrumentedStore struct {
+	inner  Store
+	onCall func(op string, d time.Duration, err error)
+}
+
+// NewInstrumentedStore wraps inner so each call is reported to onCall after it returns.
+// onCall runs on the caller's goroutine and should be cheap.
+func NewInstrumentedStore(inner Store, onCall func(op string, d time.Duration, err error)) *InstrumentedStore {
+	return &InstrumentedStore{inner: inner, onCall: onCall}
+}
+
+func (s *InstrumentedStore) observe(op string, start time.Time, err error) {
+	s.onCall(op, time.Since(start), err)
+}
+
+func (s *InstrumentedStore) ListResources(ctx context.Context) ([]*resource.Resource, error) {
+	start := time.Now()
+	out, err := s.inner.ListResources(ctx)
+	s.observe("ListResources", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) ListNodes(ctx context.Context) ([]PersistedNode, error) {
+	start := time.Now()
+	out, err := s.inner.ListNodes(ctx)
+	s.observe("ListNodes", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) ListAllNodes(ctx context.Context) ([]PersistedNode, error) {
+	start := time.Now()
+	out, err := s.inner.ListAllNodes(ctx)
+	s.observe("ListAllNodes", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) ListLatestNodeStates(ctx context.Context) (map[string]NodeState, error) {
+	start := time.Now()
+	out, err := s.inner.ListLatestNodeStates(ctx)
+	s.observe("ListLatestNodeStates", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]NodeLogRow, error) {
+	start := time.Now()
+	out, err := s.inner.ListNodeLogs(ctx, nodeIDs)
+	s.observe("ListNodeLogs", start, err)
+	return out, err
+}
+
+// EachNodeLog's reported duration includes the time spent in fn.
+func (s *InstrumentedStore) EachNodeLog(ctx context.Context, q NodeLogQuery, fn func(NodeLogRow) error) error {
+	start := time.Now()
+	err := s.inner.EachNodeLog(ctx, q, fn)
+	s.observe("EachNodeLog", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]NodeExtras, error) {
+	start := time.Now()
+	out, err := s.inner.ListNodeExtras(ctx, nodeIDs)
+	s.observe("ListNodeExtras", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) InsertResource(ctx context.Context, r *resource.Resource) error {
+	start := time.Now()
+	err := s.inner.InsertResource(ctx, r)
+	s.observe("InsertResource", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) InsertResources(ctx context.Context, resources []*resource.Resource) error {
+	start := time.Now()
+	err := s.inner.InsertResources(ctx, resources)
+	s.observe("InsertResources", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) SetResourcePaused(ctx context.Context, id string, paused bool) error {
+	start := time.Now()
+	err := s.inner.SetResourcePaused(ctx, id, paused)
+	s.observe("SetResourcePaused", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) SetResourceLabels(ctx context.Context, id string, labels map[string]string) error {
+	start := time.Now()
+	err := s.inner.SetResourceLabels(ctx, id, labels)
+	s.observe("SetResourceLabels", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	start := time.Now()
+	err := s.inner.PersistNodeCreated(ctx, nodeID, entityID, entityName, weight, createdAt)
+	s.observe("PersistNodeCreated", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error {
+	start := time.Now()
+	err := s.inner.PersistNodeCreatedWithResource(ctx, nodeID, entityID, entityName, weight, createdAt, resourceID, assignedAt)
+	s.observe("PersistNodeCreatedWithResource", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error {
+	start := time.Now()
+	err := s.inner.UpdateNodeResource(ctx, nodeID, resourceID)
+	s.observe("UpdateNodeResource", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) MarkNodeCompleted(ctx context.Context, nodeID string, completed bool) error {
+	start := time.Now()
+	err := s.inner.MarkNodeCompleted(ctx, nodeID, completed)
+	s.observe("MarkNodeCompleted", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	start := time.Now()
+	err := s.inner.InsertNodeLog(ctx, nodeID, action, resourceID, ts)
+	s.observe("InsertNodeLog", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error {
+	start := time.Now()
+	err := s.inner.InsertNodeMoveLog(ctx, nodeID, action, resourceID, fromResourceID, ts)
+	s.observe("InsertNodeMoveLog", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error {
+	start := time.Now()
+	err := s.inner.InsertNodeNote(ctx, nodeID, author, text, ts)
+	s.observe("InsertNodeNote", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error {
+	start := time.Now()
+	err := s.inner.InsertNodeResult(ctx, nodeID, outcome, result, ts)
+	s.observe("InsertNodeResult", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error {
+	start := time.Now()
+	err := s.inner.SetNodeAllowedResources(ctx, nodeID, resourceIDs)
+	s.observe("SetNodeAllowedResources", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) AddNodeTag(ctx context.Context, nodeID, tag string) error {
+	start := time.Now()
+	err := s.inner.AddNodeTag(ctx, nodeID, tag)
+	s.observe("AddNodeTag", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveNodeTag(ctx context.Context, nodeID, tag string) error {
+	start := time.Now()
+	err := s.inner.RemoveNodeTag(ctx, nodeID, tag)
+	s.observe("RemoveNodeTag", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error {
+	start := time.Now()
+	err := s.inner.UpdateNodeRetry(ctx, nodeID, attempts, notBefore, failed)
+	s.observe("UpdateNodeRetry", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error {
+	start := time.Now()
+	err := s.inner.UpdateNodeDeadline(ctx, nodeID, deadline)
+	s.observe("UpdateNodeDeadline", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error {
+	start := time.Now()
+	err := s.inner.UpdateNodeCompletionToken(ctx, nodeID, token)
+	s.observe("UpdateNodeCompletionToken", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error) {
+	start := time.Now()
+	token, err := s.inner.GetNodeCompletionToken(ctx, nodeID)
+	s.observe("GetNodeCompletionToken", start, err)
+	return token, err
+}
+
+func (s *InstrumentedStore) DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	start := time.Now()
+	n, err := s.inner.DeleteLogsOlderThan(ctx, cutoff)
+	s.observe("DeleteLogsOlderThan", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) DeleteAllNodes(ctx context.Context) error {
+	start := time.Now()
+	err := s.inner.DeleteAllNodes(ctx)
+	s.observe("DeleteAllNodes", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
+	start := time.Now()
+	err := s.inner.ArchiveCompletedNode(ctx, nodeID, archivedAt)
+	s.observe("ArchiveCompletedNode", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) ListArchivedNodes(ctx context.Context, q ArchiveQuery) ([]ArchivedNode, error) {
+	start := time.Now()
+	out, err := s.inner.ListArchivedNodes(ctx, q)
+	s.observe("ListArchivedNodes", start, err)
+	return out, err
+}
@@ -0,0 +1,564 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"nodequeue-service/resource"
+)
+
+// MemoryStore is an in-process Store that mirrors PostgresStore's semantics without a database.
+// It is intended for tests and local runs; nothing survives a process restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	resources map[string]memResource
+	nodes     map[string]*memNode
+	logs      []NodeLogRow
+	notes     []NodeNoteRow
+	results   map[string]NodeResultRow
+	tags      map[string]map[string]bool
+	allowed   map[string][]string
+	archive   map[string]time.Time
+}
+
+type memResource struct {
+	// config holds the capacity and settings (see resource.Resource.CloneConfig); its labels are
+	// not used.
+	config *resource.Resource
+	paused bool
+	labels map[string]string
+}
+
+type memNode struct {
+	entityName string
+	resourceID *string
+	completed  bool
+	createdAt  time.Time
+	weight     int
+	attempts   int
+	notBefore  *time.Time
+	failed     bool
+	deadline   *time.Time
+	token      string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		resources: make(map[string]memResource),
+		nodes:     make(map[string]*memNode),
+		results:   make(map[string]NodeResultRow),
+		tags:      make(map[string]map[string]bool),
+		allowed:   make(map[string][]string),
+		archive:   make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) ListResources(ctx context.Context) ([]*resource.Resource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.resources))
+	for id := range s.resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]*resource.Resource, 0, len(ids))
+	for _, id := range ids {
+		mr := s.resources[id]
+		r := mr.config.CloneConfig(id)
+		r.Paused = mr.paused
+		r.Labels = maps.Clone(mr.labels)
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListNodes(ctx context.Context) ([]PersistedNode, error) {
+	return s.listNodes(false), nil
+}
+
+func (s *MemoryStore) ListAllNodes(ctx context.Context) ([]PersistedNode, error) {
+	return s.listNodes(true), nil
+}
+
+func (s *MemoryStore) listNodes(includeCompleted bool) []PersistedNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]PersistedNode, 0)
+	for id, n := range s.nodes {
+		if n.completed && !includeCompleted {
+			continue
+		}
+		out = append(out, PersistedNode{
+			NodeID:          id,
+			EntityName:      n.entityName,
+			ResourceID:      copyStringPtr(n.resourceID),
+			Completed:       n.completed,
+			CreatedAt:       n.createdAt,
+			Weight:          n.weight,
+			Attempts:        n.attempts,
+			NotBefore:       copyTimePtr(n.notBefore),
+			Failed:          n.failed,
+			DeadlineTS:      copyTimePtr(n.deadline),
+			CompletionToken: n.token,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *MemoryStore) ListLatestNodeStates(ctx context.Context) (map[string]NodeState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]NodeState)
+	for _, l := range s.logs {
+		var kind QueueKind
+		switch l.Action {
+		case "moved_to_waiting_queue":
+			kind = QueueKindWaiting
+		case "moved_to_service_queue":
+			kind = QueueKindService
+		default:
+			continue
+		}
+		if st, ok := out[l.NodeID]; ok && st.TS.After(l.TS) {
+			continue
+		}
+		out[l.NodeID] = NodeState{Queue: kind, TS: l.TS}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]NodeLogRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	want := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		want[id] = true
+	}
+	out := make(map[string][]NodeLogRow)
+	for _, l := range s.logs {
+		if want[l.NodeID] {
+			out[l.NodeID] = append(out[l.NodeID], copyLogRow(l))
+		}
+	}
+	for _, rows := range out {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].TS.Before(rows[j].TS) })
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) EachNodeLog(ctx context.Context, q NodeLogQuery, fn func(NodeLogRow) error) error {
+	s.mu.Lock()
+	rows := make([]NodeLogRow, 0, len(s.logs))
+	for _, l := range s.logs {
+		if (!q.Since.IsZero() && l.TS.Before(q.Since)) || (!q.Until.IsZero() && !l.TS.Before(q.Until)) {
+			continue
+		}
+		rows = append(rows, copyLogRow(l))
+	}
+	s.mu.Unlock()
+
+	// fn may be slow (it typically writes to a client), so it runs on a copy without the lock.
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].TS.Before(rows[j].TS) })
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]NodeExtras, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	want := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		want[id] = true
+	}
+
+	out := make(map[string]NodeExtras)
+	update := func(id string, fn func(*NodeExtras)) {
+		if !want[id] {
+			return
+		}
+		ex := out[id]
+		fn(&ex)
+		out[id] = ex
+	}
+	for id, set := range s.tags {
+		update(id, func(ex *NodeExtras) {
+			for tag := range set {
+				ex.Tags = append(ex.Tags, tag)
+			}
+			sort.Strings(ex.Tags)
+		})
+	}
+	for id, rids := range s.allowed {
+		update(id, func(ex *NodeExtras) { ex.AllowedResources = append([]string(nil), rids...) })
+	}
+	for _, nr := range s.notes {
+		update(nr.NodeID, func(ex *NodeExtras) { ex.Notes = append(ex.Notes, nr) })
+	}
+	for id, rr := range s.results {
+		update(id, func(ex *NodeExtras) {
+			rr.Result = copyBytes(rr.Result)
+			ex.Result = &rr
+		})
+	}
+	for _, ex := range out {
+		sort.SliceStable(ex.Notes, func(i, j int) bool { return ex.Notes[i].TS.Before(ex.Notes[j].TS) })
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) InsertResource(ctx context.Context, r *resource.Resource) error {
+	return s.InsertResources(ctx, []*resource.Resource{r})
+}
+
+func (s *MemoryStore) InsertResources(ctx context.Context, resources []*resource.Resource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range resources {
+		if _, exists := s.resources[r.ID]; !exists {
+			config := r.CloneConfig(r.ID)
+			labels := config.Labels
+			config.Labels = nil
+			s.resources[r.ID] = memResource{config: config, labels: labels}
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetResourcePaused(ctx context.Context, id string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mr, exists := s.resources[id]; exists {
+		mr.paused = paused
+		s.resources[id] = mr
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetResourceLabels(ctx context.Context, id string, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mr, exists := s.resources[id]; exists {
+		mr.labels = nil
+		if len(labels) > 0 {
+			mr.labels = maps.Clone(labels)
+		}
+		s.resources[id] = mr
+	}
+	return nil
+}
+
+func (s *MemoryStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.nodes[nodeID]; exists {
+		return ErrNodeExists
+	}
+	s.nodes[nodeID] = &memNode{entityName: entityName, weight: weight, createdAt: createdAt}
+	return nil
+}
+
+func (s *MemoryStore) PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.nodes[nodeID]; exists {
+		return ErrNodeExists
+	}
+	s.nodes[nodeID] = &memNode{entityName: entityName, weight: weight, createdAt: createdAt, resourceID: copyStringPtr(&resourceID)}
+	s.logs = append(s.logs,
+		NodeLogRow{NodeID: nodeID, Action: "created", TS: createdAt},
+		NodeLogRow{NodeID: nodeID, Action: "moved_to_waiting_queue", ResourceID: copyStringPtr(&resourceID), TS: assignedAt},
+	)
+	return nil
+}
+
+func (s *MemoryStore) UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		n.resourceID = copyStringPtr(resourceID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) MarkNodeCompleted(ctx context.Context, nodeID string, completed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		n.completed = completed
+		if completed {
+			n.resourceID = nil
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	return s.InsertNodeMoveLog(ctx, nodeID, action, resourceID, nil, ts)
+}
+
+func (s *MemoryStore) InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logs = append(s.logs, NodeLogRow{
+		NodeID:         nodeID,
+		Action:         action,
+		ResourceID:     copyStringPtr(resourceID),
+		FromResourceID: copyStringPtr(fromResourceID),
+		TS:             ts,
+	})
+	return nil
+}
+
+func (s *MemoryStore) InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notes = append(s.notes, NodeNoteRow{NodeID: nodeID, Author: author, Text: text, TS: ts})
+	return nil
+}
+
+func (s *MemoryStore) InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.results[nodeID]; !exists {
+		s.results[nodeID] = NodeResultRow{NodeID: nodeID, Outcome: outcome, Result: copyBytes(result), TS: ts}
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddNodeTag(ctx context.Context, nodeID, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tags[nodeID] == nil {
+		s.tags[nodeID] = make(map[string]bool)
+	}
+	s.tags[nodeID][tag] = true
+	return nil
+}
+
+func (s *MemoryStore) RemoveNodeTag(ctx context.Context, nodeID, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tags[nodeID], tag)
+	if len(s.tags[nodeID]) == 0 {
+		delete(s.tags, nodeID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(resourceIDs) == 0 {
+		delete(s.allowed, nodeID)
+		return nil
+	}
+	rids := append([]string(nil), resourceIDs...)
+	sort.Strings(rids)
+	s.allowed[nodeID] = rids
+	return nil
+}
+
+func (s *MemoryStore) UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		n.attempts = attempts
+		n.notBefore = copyTimePtr(notBefore)
+		n.failed = failed
+	}
+	return nil
+}
+
+func (s *MemoryStore) UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		n.deadline = copyTimePtr(deadline)
+	}
+	return nil
+}
+
+func (s *MemoryStore) UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		n.token = token
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.nodes[nodeID]; exists {
+		return n.token, nil
+	}
+	return "", nil
+}
+
+func (s *MemoryStore) DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.logs[:0]
+	deleted := 0
+	for _, l := range s.logs {
+		n, exists := s.nodes[l.NodeID]
+		if exists && n.completed && l.TS.Before(cutoff) && !isRetainedLogAction(l.Action) {
+			deleted++
+			continue
+		}
+		kept = append(kept, l)
+	}
+	s.logs = kept
+	return deleted, nil
+}
+
+func (s *MemoryStore) DeleteAllNodes(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nodes = make(map[string]*memNode)
+	s.logs = nil
+	s.notes = nil
+	s.results = make(map[string]NodeResultRow)
+	s.tags = make(map[string]map[string]bool)
+	s.allowed = make(map[string][]string)
+	s.archive = make(map[string]time.Time)
+	return nil
+}
+
+func (s *MemoryStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, exists := s.nodes[nodeID]
+	if !exists || !n.completed {
+		return nil
+	}
+	if _, archived := s.archive[nodeID]; !archived {
+		s.archive[nodeID] = archivedAt
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListArchivedNodes(ctx context.Context, q ArchiveQuery) ([]ArchivedNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]ArchivedNode, 0)
+	for id, archivedAt := range s.archive {
+		n := s.nodes[id]
+		an := ArchivedNode{
+			NodeID:     id,
+			EntityName: n.entityName,
+			CreatedAt:  n.createdAt,
+			ArchivedAt: archivedAt,
+		}
+		var lastTS time.Time
+		for _, l := range s.logs {
+			if l.NodeID != id {
+				continue
+			}
+			an.LogCount++
+			if (l.Action == "completed" || l.Action == "failed") && (an.CompletedAt == nil || l.TS.After(*an.CompletedAt)) {
+				ts := l.TS
+				an.CompletedAt = &ts
+			}
+			if l.ResourceID != nil && !l.TS.Before(lastTS) {
+				lastTS = l.TS
+				an.LastResourceID = copyStringPtr(l.ResourceID)
+			}
+		}
+		if !q.Since.IsZero() && (an.CompletedAt == nil || an.CompletedAt.Before(q.Since)) {
+			continue
+		}
+		if !q.Until.IsZero() && (an.CompletedAt == nil || !an.CompletedAt.Before(q.Until)) {
+			continue
+		}
+		out = append(out, an)
+	}
+
+	// Same ordering as PostgresStore: completed_at ascending with NULLs first, then node ID.
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].CompletedAt, out[j].CompletedAt
+		switch {
+		case a == nil && b == nil:
+			return out[i].NodeID < out[j].NodeID
+		case a == nil || b == nil:
+			return a == nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return out[i].NodeID < out[j].NodeID
+	})
+
+	if q.Offset >= len(out) {
+		return []ArchivedNode{}, nil
+	}
+	out = out[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(out) {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func copyStringPtr(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func copyTimePtr(p *time.Time) *time.Time {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func copyLogRow(l NodeLogRow) NodeLogRow {
+	l.ResourceID = copyStringPtr(l.ResourceID)
+	l.FromResourceID = copyStringPtr(l.FromResourceID)
+	return l
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
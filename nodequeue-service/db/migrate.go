@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+)
+
+// schemaSQL creates any missing tables and indexes; every statement in it is idempotent.
+//
+//go:embed init/00_schema.sql
+var schemaSQL string
+
+// migrationsSQL brings a database created from an older init/00_schema.sql up to date. Postgres
+// only runs the init scripts on an empty data volume, so columns added to 00_schema.sql later
+// must also be added here.
+//
+//go:embed init/02_migrations.sql
+var migrationsSQL string
+
+// Migrate applies init/00_schema.sql and then init/02_migrations.sql to db, creating tables added
+// since the database was initialized and adding new columns to existing ones. Both files are
+// idempotent, so it is safe to run on every startup, against both fresh and existing databases.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	// Without arguments pgx uses the simple query protocol, which runs all statements in a file.
+	if _, err := db.ExecContext(ctx, schemaSQL); err != nil {
+		return fmt.Errorf("applying schema: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, migrationsSQL); err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	return nil
+}
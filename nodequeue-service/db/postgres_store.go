@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,14 +14,43 @@ import (
 
 type PostgresStore struct {
 	db *sql.DB
+	// replica, if set, serves the read-only queries (the List* methods and EachNodeLog).
+	replica *sql.DB
 }
 
 func NewPostgresStore(db *sql.DB) *PostgresStore {
 	return &PostgresStore{db: db}
 }
 
+// NewPostgresStoreWithReplica returns a store that writes to primary and reads from replica. A nil
+// replica reads from primary, as NewPostgresStore does.
+func NewPostgresStoreWithReplica(primary, replica *sql.DB) *PostgresStore {
+	return &PostgresStore{db: primary, replica: replica}
+}
+
+type primaryReadsKey struct{}
+
+// WithPrimaryReads returns a context whose store reads go to the primary even when a replica is
+// configured, for callers that cannot tolerate replica lag.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// reader returns the connection read-only queries on ctx should use.
+func (s *PostgresStore) reader(ctx context.Context) *sql.DB {
+	if s.replica == nil || ctx.Value(primaryReadsKey{}) != nil {
+		return s.db
+	}
+	return s.replica
+}
+
 func (s *PostgresStore) ListResources(ctx context.Context) ([]*resource.Resource, error) {
-	rows, err := s.db.QueryContext(ctx, `SELECT id, capacity FROM resources ORDER BY id`)
+	rows, err := s.reader(ctx).QueryContext(ctx,
+		`SELECT id, capacity, paused, auto_promote, max_per_entity, fifo_strict, pressure_waiting,
+		        pressure_seconds, max_wait_ms, lanes, reserved_for_priority, alloc_rate_per_sec
+		 FROM resources
+		 ORDER BY id`,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -27,27 +58,76 @@ func (s *PostgresStore) ListResources(ctx context.Context) ([]*resource.Resource
 
 	out := make([]*resource.Resource, 0)
 	for rows.Next() {
-		var id string
-		var cap int
-		if err := rows.Scan(&id, &cap); err != nil {
+		r := resource.NewResource("", 0)
+		var lanes []byte
+		if err := rows.Scan(&r.ID, &r.Capacity, &r.Paused, &r.AutoPromote, &r.MaxPerEntity, &r.FIFOStrict,
+			&r.PressureWaiting, &r.PressureSeconds, &r.MaxWaitMS, &lanes, &r.ReservedForPriority,
+			&r.AllocRatePerSec); err != nil {
 			return nil, err
 		}
-		out = append(out, resource.NewResource(id, cap))
+		if lanes != nil {
+			if err := json.Unmarshal(lanes, &r.LaneOrder); err != nil {
+				return nil, fmt.Errorf("resource %s: decoding lanes: %w", r.ID, err)
+			}
+		}
+		out = append(out, r)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	if err := s.loadResourceLabels(ctx, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
+// loadResourceLabels fills in the labels of resources from resource_labels.
+func (s *PostgresStore) loadResourceLabels(ctx context.Context, resources []*resource.Resource) error {
+	byID := make(map[string]*resource.Resource, len(resources))
+	for _, r := range resources {
+		byID[r.ID] = r
+	}
+
+	rows, err := s.reader(ctx).QueryContext(ctx, `SELECT resource_id, key, value FROM resource_labels`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, key, value string
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return err
+		}
+		r, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[key] = value
+	}
+	return rows.Err()
+}
+
 func (s *PostgresStore) ListNodes(ctx context.Context) ([]PersistedNode, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT n.id::text, e.name, n.resource_id, n.completed, n.created_at
+	return s.listNodes(ctx, false)
+}
+
+func (s *PostgresStore) ListAllNodes(ctx context.Context) ([]PersistedNode, error) {
+	return s.listNodes(ctx, true)
+}
+
+func (s *PostgresStore) listNodes(ctx context.Context, includeCompleted bool) ([]PersistedNode, error) {
+	rows, err := s.reader(ctx).QueryContext(ctx, `
+		SELECT n.id::text, e.name, n.resource_id, n.completed, n.created_at, n.weight, n.attempts, n.not_before, n.failed, n.deadline_ts,
+		       coalesce(n.completion_token, '')
 		FROM nodes n
 		JOIN entities e ON e.id = n.entity_id
-		WHERE n.completed = false
+		WHERE $1 OR n.completed = false
 		ORDER BY n.created_at ASC
-	`)
+	`, includeCompleted)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +136,7 @@ func (s *PostgresStore) ListNodes(ctx context.Context) ([]PersistedNode, error)
 	out := make([]PersistedNode, 0)
 	for rows.Next() {
 		var pn PersistedNode
-		if err := rows.Scan(&pn.NodeID, &pn.EntityName, &pn.ResourceID, &pn.Completed, &pn.CreatedAt); err != nil {
+		if err := rows.Scan(&pn.NodeID, &pn.EntityName, &pn.ResourceID, &pn.Completed, &pn.CreatedAt, &pn.Weight, &pn.Attempts, &pn.NotBefore, &pn.Failed, &pn.DeadlineTS, &pn.CompletionToken); err != nil {
 			return nil, err
 		}
 		out = append(out, pn)
@@ -69,11 +149,11 @@ func (s *PostgresStore) ListNodes(ctx context.Context) ([]PersistedNode, error)
 
 func (s *PostgresStore) ListLatestNodeStates(ctx context.Context) (map[string]NodeState, error) {
 	// Latest service/waiting state per node based on node_logs.
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.reader(ctx).QueryContext(ctx, `
 		SELECT DISTINCT ON (node_id) node_id::text, action, ts
 		FROM node_logs
 		WHERE action IN ('moved_to_waiting_queue', 'moved_to_service_queue')
-		ORDER BY node_id, ts DESC
+		ORDER BY node_id, ts DESC, id DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -109,7 +189,7 @@ func (s *PostgresStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map
 	// Build a safe IN list: ($1::uuid, $2::uuid, ...)
 	var b strings.Builder
 	b.WriteString(`
-		SELECT node_id::text, action, resource_id, ts
+		SELECT node_id::text, action, resource_id, from_resource_id, ts
 		FROM node_logs
 		WHERE node_id IN (`)
 	args := make([]any, 0, len(nodeIDs))
@@ -121,10 +201,10 @@ func (s *PostgresStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map
 		args = append(args, id)
 	}
 	b.WriteString(`)
-		ORDER BY node_id, ts ASC
+		ORDER BY node_id, ts ASC, id ASC
 	`)
 
-	rows, err := s.db.QueryContext(ctx, b.String(), args...)
+	rows, err := s.reader(ctx).QueryContext(ctx, b.String(), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -133,21 +213,17 @@ func (s *PostgresStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map
 	for rows.Next() {
 		var nodeID string
 		var action string
-		var rid sql.NullString
+		var rid, from sql.NullString
 		var ts time.Time
-		if err := rows.Scan(&nodeID, &action, &rid, &ts); err != nil {
+		if err := rows.Scan(&nodeID, &action, &rid, &from, &ts); err != nil {
 			return nil, err
 		}
-		var rp *string
-		if rid.Valid {
-			v := rid.String
-			rp = &v
-		}
 		out[nodeID] = append(out[nodeID], NodeLogRow{
-			NodeID:     nodeID,
-			Action:     action,
-			ResourceID: rp,
-			TS:         ts,
+			NodeID:         nodeID,
+			Action:         action,
+			ResourceID:     nullStringPtr(rid),
+			FromResourceID: nullStringPtr(from),
+			TS:             ts,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -156,30 +232,262 @@ func (s *PostgresStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map
 	return out, nil
 }
 
-func (s *PostgresStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, createdAt time.Time) error {
+func (s *PostgresStore) EachNodeLog(ctx context.Context, q NodeLogQuery, fn func(NodeLogRow) error) error {
+	var since, until sql.NullTime
+	if !q.Since.IsZero() {
+		since = sql.NullTime{Time: q.Since, Valid: true}
+	}
+	if !q.Until.IsZero() {
+		until = sql.NullTime{Time: q.Until, Valid: true}
+	}
+
+	rows, err := s.reader(ctx).QueryContext(ctx, `
+		SELECT node_id::text, action, resource_id, from_resource_id, ts
+		FROM node_logs
+		WHERE ($1::timestamptz IS NULL OR ts >= $1)
+		  AND ($2::timestamptz IS NULL OR ts < $2)
+		ORDER BY ts ASC, id ASC
+	`, since, until)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row NodeLogRow
+		var rid, from sql.NullString
+		if err := rows.Scan(&row.NodeID, &row.Action, &rid, &from, &row.TS); err != nil {
+			return err
+		}
+		row.ResourceID = nullStringPtr(rid)
+		row.FromResourceID = nullStringPtr(from)
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// extrasBatchSize caps how many node IDs one ListNodeExtras query binds, well under Postgres's
+// 65535-parameter limit.
+const extrasBatchSize = 5000
+
+func (s *PostgresStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]NodeExtras, error) {
+	out := make(map[string]NodeExtras)
+	for start := 0; start < len(nodeIDs); start += extrasBatchSize {
+		end := min(start+extrasBatchSize, len(nodeIDs))
+		if err := s.listNodeExtrasBatch(ctx, nodeIDs[start:end], out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// listNodeExtrasBatch reads tags, allowed resources, notes and results for nodeIDs in a single
+// UNION ALL query and merges them into out.
+func (s *PostgresStore) listNodeExtrasBatch(ctx context.Context, nodeIDs []string, out map[string]NodeExtras) error {
+	// Build a safe VALUES list: ($1::uuid), ($2::uuid), ...
+	var ids strings.Builder
+	args := make([]any, 0, len(nodeIDs))
+	for i, id := range nodeIDs {
+		if i > 0 {
+			ids.WriteString(", ")
+		}
+		ids.WriteString(fmt.Sprintf("($%d::uuid)", i+1))
+		args = append(args, id)
+	}
+
+	rows, err := s.reader(ctx).QueryContext(ctx, `
+		WITH ids(node_id) AS (VALUES `+ids.String()+`)
+		SELECT t.node_id::text, 'tag', t.tag, NULL::text, NULL::jsonb, NULL::timestamptz, 0::bigint
+		FROM node_tags t JOIN ids USING (node_id)
+		UNION ALL
+		SELECT a.node_id::text, 'allowed', a.resource_id, NULL, NULL, NULL, 0
+		FROM node_allowed_resources a JOIN ids USING (node_id)
+		UNION ALL
+		SELECT n.node_id::text, 'note', n.author, n.text, NULL, n.ts, n.id
+		FROM node_notes n JOIN ids USING (node_id)
+		UNION ALL
+		SELECT r.node_id::text, 'result', r.outcome, NULL, r.result, r.ts, 0
+		FROM node_results r JOIN ids USING (node_id)
+		ORDER BY 1, 2, 6, 7, 3
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nodeID, kind, first string
+		var second sql.NullString
+		var result []byte
+		var ts sql.NullTime
+		var seq int64
+		if err := rows.Scan(&nodeID, &kind, &first, &second, &result, &ts, &seq); err != nil {
+			return err
+		}
+		ex := out[nodeID]
+		switch kind {
+		case "tag":
+			ex.Tags = append(ex.Tags, first)
+		case "allowed":
+			ex.AllowedResources = append(ex.AllowedResources, first)
+		case "note":
+			ex.Notes = append(ex.Notes, NodeNoteRow{NodeID: nodeID, Author: first, Text: second.String, TS: ts.Time})
+		case "result":
+			ex.Result = &NodeResultRow{NodeID: nodeID, Outcome: first, Result: result, TS: ts.Time}
+		}
+		out[nodeID] = ex
+	}
+	return rows.Err()
+}
+
+func (s *PostgresStore) InsertResource(ctx context.Context, r *resource.Resource) error {
+	return s.InsertResources(ctx, []*resource.Resource{r})
+}
+
+func (s *PostgresStore) InsertResources(ctx context.Context, resources []*resource.Resource) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range resources {
+		r = r.CloneConfig(r.ID)
+		var lanes any
+		if len(r.LaneOrder) > 0 {
+			b, err := json.Marshal(r.LaneOrder)
+			if err != nil {
+				return err
+			}
+			lanes = string(b)
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO resources (id, capacity, auto_promote, max_per_entity, fifo_strict,
+			   pressure_waiting, pressure_seconds, max_wait_ms, lanes, reserved_for_priority,
+			   alloc_rate_per_sec)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, $10, $11)
+			 ON CONFLICT (id) DO NOTHING`,
+			r.ID, r.Capacity, r.AutoPromote, r.MaxPerEntity, r.FIFOStrict, r.PressureWaiting,
+			r.PressureSeconds, r.MaxWaitMS, lanes, r.ReservedForPriority, r.AllocRatePerSec,
+		)
+		if err != nil {
+			return err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted == 0 {
+			continue
+		}
+		if err := insertResourceLabelsTx(ctx, tx, r.ID, r.Labels); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) SetResourceLabels(ctx context.Context, id string, labels map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM resource_labels WHERE resource_id = $1`, id); err != nil {
+		return err
+	}
+	if err := insertResourceLabelsTx(ctx, tx, id, labels); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertResourceLabelsTx writes labels for resource id inside tx.
+func insertResourceLabelsTx(ctx context.Context, tx *sql.Tx, id string, labels map[string]string) error {
+	for key, value := range labels {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO resource_labels (resource_id, key, value) VALUES ($1, $2, $3)
+			 ON CONFLICT (resource_id, key) DO UPDATE SET value = EXCLUDED.value`,
+			id, key, value,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) SetResourcePaused(ctx context.Context, id string, paused bool) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE resources SET paused = $2 WHERE id = $1`,
+		id, paused,
+	)
+	return err
+}
+
+func (s *PostgresStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	if err := insertNodeTx(ctx, tx, nodeID, entityID, entityName, weight, createdAt, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertNodeTx(ctx, tx, nodeID, entityID, entityName, weight, createdAt, &resourceID); err != nil {
+		return err
+	}
+
 	if _, err := tx.ExecContext(ctx,
-		`INSERT INTO entities (id, name, created_at) VALUES ($1::uuid, $2, $3)
-		 ON CONFLICT (id) DO NOTHING`,
-		entityID, entityName, createdAt,
+		`INSERT INTO node_logs (node_id, action, resource_id, ts) VALUES ($1::uuid, 'created', NULL, $2), ($1::uuid, 'moved_to_waiting_queue', $3, $4)`,
+		nodeID, createdAt, resourceID, assignedAt,
 	); err != nil {
 		return err
 	}
 
+	return tx.Commit()
+}
+
+// insertNodeTx inserts the entity and node rows for a newly created node inside tx.
+func insertNodeTx(ctx context.Context, tx *sql.Tx, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID *string) error {
 	if _, err := tx.ExecContext(ctx,
-		`INSERT INTO nodes (id, entity_id, completed, created_at) VALUES ($1::uuid, $2::uuid, false, $3)
+		`INSERT INTO entities (id, name, created_at) VALUES ($1::uuid, $2, $3)
 		 ON CONFLICT (id) DO NOTHING`,
-		nodeID, entityID, createdAt,
+		entityID, entityName, createdAt,
 	); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO nodes (id, entity_id, resource_id, completed, created_at, weight) VALUES ($1::uuid, $2::uuid, $3, false, $4, $5)
+		 ON CONFLICT (id) DO NOTHING`,
+		nodeID, entityID, resourceID, createdAt, weight,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNodeExists
+	}
+	return nil
 }
 
 func (s *PostgresStore) UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error {
@@ -199,9 +507,204 @@ func (s *PostgresStore) MarkNodeCompleted(ctx context.Context, nodeID string, co
 }
 
 func (s *PostgresStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	return s.InsertNodeMoveLog(ctx, nodeID, action, resourceID, nil, ts)
+}
+
+func (s *PostgresStore) InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO node_logs (node_id, action, resource_id, from_resource_id, ts) VALUES ($1::uuid, $2, $3, $4, $5)`,
+		nodeID, action, resourceID, fromResourceID, ts,
+	)
+	return err
+}
+
+func (s *PostgresStore) InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO node_notes (node_id, author, text, ts) VALUES ($1::uuid, $2, $3, $4)`,
+		nodeID, author, text, ts,
+	)
+	return err
+}
+
+func (s *PostgresStore) InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error {
+	// A nil result must reach Postgres as NULL rather than an empty (invalid) jsonb value.
+	var raw sql.NullString
+	if len(result) > 0 {
+		raw = sql.NullString{String: string(result), Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO node_results (node_id, outcome, result, ts) VALUES ($1::uuid, $2, $3::jsonb, $4)
+		 ON CONFLICT (node_id) DO NOTHING`,
+		nodeID, outcome, raw, ts,
+	)
+	return err
+}
+
+func (s *PostgresStore) AddNodeTag(ctx context.Context, nodeID, tag string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO node_tags (node_id, tag) VALUES ($1::uuid, $2) ON CONFLICT DO NOTHING`,
+		nodeID, tag,
+	)
+	return err
+}
+
+func (s *PostgresStore) RemoveNodeTag(ctx context.Context, nodeID, tag string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM node_tags WHERE node_id = $1::uuid AND tag = $2`,
+		nodeID, tag,
+	)
+	return err
+}
+
+func (s *PostgresStore) SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM node_allowed_resources WHERE node_id = $1::uuid`, nodeID); err != nil {
+		return err
+	}
+	for _, rid := range resourceIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO node_allowed_resources (node_id, resource_id) VALUES ($1::uuid, $2) ON CONFLICT DO NOTHING`,
+			nodeID, rid,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE nodes SET attempts = $2, not_before = $3, failed = $4 WHERE id = $1::uuid`,
+		nodeID, attempts, notBefore, failed,
+	)
+	return err
+}
+
+func (s *PostgresStore) UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE nodes SET deadline_ts = $2 WHERE id = $1::uuid`,
+		nodeID, deadline,
+	)
+	return err
+}
+
+func (s *PostgresStore) UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE nodes SET completion_token = $2 WHERE id = $1::uuid`,
+		nodeID, token,
+	)
+	return err
+}
+
+func (s *PostgresStore) GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error) {
+	var token string
+	err := s.reader(ctx).QueryRowContext(ctx,
+		`SELECT coalesce(completion_token, '') FROM nodes WHERE id = $1::uuid`,
+		nodeID,
+	).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return token, err
+}
+
+func (s *PostgresStore) DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	// Only completed nodes are touched, so ListLatestNodeStates is unchanged for active ones.
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM node_logs l
+		USING nodes n
+		WHERE l.node_id = n.id
+		  AND n.completed = true
+		  AND l.ts < $1
+		  AND l.action <> ALL($2)
+	`, cutoff, RetainedLogActions)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *PostgresStore) DeleteAllNodes(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `TRUNCATE node_archive, node_results, node_allowed_resources, node_tags, node_notes, node_logs, nodes, entities`)
+	return err
+}
+
+func (s *PostgresStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO node_logs (node_id, action, resource_id, ts) VALUES ($1::uuid, $2, $3, $4)`,
-		nodeID, action, resourceID, ts,
+		`INSERT INTO node_archive (node_id, archived_at)
+		 SELECT id, $2 FROM nodes WHERE id = $1::uuid AND completed = true
+		 ON CONFLICT (node_id) DO NOTHING`,
+		nodeID, archivedAt,
 	)
 	return err
 }
+
+func (s *PostgresStore) ListArchivedNodes(ctx context.Context, q ArchiveQuery) ([]ArchivedNode, error) {
+	var since, until sql.NullTime
+	if !q.Since.IsZero() {
+		since = sql.NullTime{Time: q.Since, Valid: true}
+	}
+	if !q.Until.IsZero() {
+		until = sql.NullTime{Time: q.Until, Valid: true}
+	}
+
+	rows, err := s.reader(ctx).QueryContext(ctx, `
+		SELECT n.id::text, e.name, n.created_at,
+		       max(l.ts) FILTER (WHERE l.action IN ('completed', 'failed')) AS completed_at,
+		       (array_agg(l.resource_id ORDER BY l.ts DESC) FILTER (WHERE l.resource_id IS NOT NULL))[1] AS last_resource_id,
+		       count(l.id) AS log_count,
+		       a.archived_at
+		FROM node_archive a
+		JOIN nodes n ON n.id = a.node_id
+		JOIN entities e ON e.id = n.entity_id
+		LEFT JOIN node_logs l ON l.node_id = n.id
+		GROUP BY n.id, e.name, n.created_at, a.archived_at
+		HAVING ($1::timestamptz IS NULL OR max(l.ts) FILTER (WHERE l.action IN ('completed', 'failed')) >= $1)
+		   AND ($2::timestamptz IS NULL OR max(l.ts) FILTER (WHERE l.action IN ('completed', 'failed')) < $2)
+		ORDER BY completed_at ASC NULLS FIRST, n.id
+		LIMIT $3 OFFSET $4
+	`, since, until, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ArchivedNode, 0)
+	for rows.Next() {
+		var an ArchivedNode
+		var completedAt sql.NullTime
+		var rid sql.NullString
+		if err := rows.Scan(&an.NodeID, &an.EntityName, &an.CreatedAt, &completedAt, &rid, &an.LogCount, &an.ArchivedAt); err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			v := completedAt.Time
+			an.CompletedAt = &v
+		}
+		if rid.Valid {
+			v := rid.String
+			an.LastResourceID = &v
+		}
+		out = append(out, an)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// nullStringPtr returns a pointer to s's value, or nil when s is NULL.
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	v := s.String
+	return &v
+}
@@ -2,17 +2,29 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"nodequeue-service/resource"
 )
 
+// ErrNodeExists is returned by PersistNodeCreated and PersistNodeCreatedWithResource when a node
+// with the same ID was already persisted, e.g. one since archived or evicted from memory.
+var ErrNodeExists = errors.New("node already persisted")
+
 type PersistedNode struct {
 	NodeID     string
 	EntityName string
 	ResourceID *string
 	Completed  bool
 	CreatedAt  time.Time
+	Weight     int
+	Attempts   int
+	NotBefore  *time.Time
+	Failed     bool
+	DeadlineTS *time.Time
+	// CompletionToken is the idempotency token the node was completed with ("" for none).
+	CompletionToken string
 }
 
 type QueueKind string
@@ -33,7 +45,79 @@ type NodeLogRow struct {
 	NodeID     string
 	Action     string
 	ResourceID *string
-	TS         time.Time
+	// FromResourceID is the resource a move came from; nil for other entries and older rows.
+	FromResourceID *string
+	TS             time.Time
+}
+
+// RetainedLogActions are node_logs actions that log compaction never deletes, so metrics can still
+// compute a node's total time in system after its intermediate events are trimmed.
+var RetainedLogActions = []string{"created", "completed", "failed"}
+
+func isRetainedLogAction(action string) bool {
+	for _, a := range RetainedLogActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeNoteRow is a persisted operator note on a node.
+type NodeNoteRow struct {
+	NodeID string
+	Author string
+	Text   string
+	TS     time.Time
+}
+
+// NodeResultRow is the persisted completion outcome of a node. Result is raw JSON (nil if the
+// node completed with an outcome only).
+type NodeResultRow struct {
+	NodeID  string
+	Outcome string
+	Result  []byte
+	TS      time.Time
+}
+
+// NodeExtras is what ListNodeExtras returns for one node: everything persisted about it besides
+// its row, placement and logs.
+type NodeExtras struct {
+	// Tags and AllowedResources are sorted.
+	Tags             []string
+	AllowedResources []string
+	// Notes are in timestamp order, ties in insertion order.
+	Notes []NodeNoteRow
+	// Result is nil unless the node completed with one.
+	Result *NodeResultRow
+}
+
+// ArchivedNode is a summarized row for a completed node that has been moved to the archive.
+// CompletedAt and LastResourceID are derived from node_logs and may be nil for legacy rows.
+type ArchivedNode struct {
+	NodeID         string
+	EntityName     string
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+	LastResourceID *string
+	LogCount       int
+	ArchivedAt     time.Time
+}
+
+// ArchiveQuery filters and pages ListArchivedNodes by completion time.
+// Zero Since/Until leave that side of the range open.
+type ArchiveQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// NodeLogQuery filters EachNodeLog by timestamp: Since is inclusive, Until exclusive. Zero
+// Since/Until leave that side of the range open.
+type NodeLogQuery struct {
+	Since time.Time
+	Until time.Time
 }
 
 // Store is an optional persistence/audit sink for QueueService.
@@ -41,11 +125,69 @@ type NodeLogRow struct {
 type Store interface {
 	ListResources(ctx context.Context) ([]*resource.Resource, error)
 	ListNodes(ctx context.Context) ([]PersistedNode, error)
+	// ListAllNodes is ListNodes including completed (and archived) nodes.
+	ListAllNodes(ctx context.Context) ([]PersistedNode, error)
+	// ListLatestNodeStates returns each node's latest waiting/service placement from node_logs.
+	// Rows with equal timestamps are ordered by insertion, so the last one written wins.
 	ListLatestNodeStates(ctx context.Context) (map[string]NodeState, error)
+	// ListNodeLogs returns the given nodes' node_logs in timestamp order, ties in insertion order.
 	ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]NodeLogRow, error)
+	// EachNodeLog calls fn for every node_logs row in q's range, in timestamp order (ties in
+	// insertion order), without loading them all first. An error from fn stops the iteration and
+	// is returned.
+	EachNodeLog(ctx context.Context, q NodeLogQuery, fn func(NodeLogRow) error) error
+	// ListNodeExtras returns the tags, allowed resources, notes and result of the given nodes in
+	// one batched read. Nodes with none of them are absent from the map.
+	ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]NodeExtras, error)
 
-	PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, createdAt time.Time) error
+	// InsertResource persists a new resource's capacity, settings (see resource.Resource.CloneConfig)
+	// and labels. An existing resource with the same ID is left unchanged.
+	InsertResource(ctx context.Context, r *resource.Resource) error
+	// InsertResources is InsertResource for several resources, written in one transaction.
+	InsertResources(ctx context.Context, resources []*resource.Resource) error
+	SetResourcePaused(ctx context.Context, id string, paused bool) error
+	// SetResourceLabels replaces a resource's labels; an empty map removes them.
+	SetResourceLabels(ctx context.Context, id string, labels map[string]string) error
+	// PersistNodeCreated writes a new node's entity and node rows. It returns ErrNodeExists, writing
+	// nothing, if the node ID is already taken.
+	PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error
+	// PersistNodeCreatedWithResource is PersistNodeCreated for a node assigned to resourceID's
+	// waiting queue on creation. The node row (with its resource) and its "created" and
+	// "moved_to_waiting_queue" logs are written in one transaction.
+	PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error
 	UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error
 	MarkNodeCompleted(ctx context.Context, nodeID string, completed bool) error
 	InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error
+	// InsertNodeMoveLog is InsertNodeLog for an entry that also records the resource the node was
+	// moved off (NodeLogRow.FromResourceID).
+	InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error
+	InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error
+	// InsertNodeResult records a node's completion outcome; a node has at most one.
+	InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error
+	// AddNodeTag tags a node; adding a tag it already has is a no-op.
+	AddNodeTag(ctx context.Context, nodeID, tag string) error
+	// RemoveNodeTag untags a node; removing a tag it does not have is a no-op.
+	RemoveNodeTag(ctx context.Context, nodeID, tag string) error
+	// SetNodeAllowedResources replaces a node's resource whitelist; an empty list removes it.
+	SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error
+	// UpdateNodeRetry records a node's failed-attempt count, backoff deadline and terminal failure.
+	UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error
+	// UpdateNodeDeadline records when an active node is cancelled; nil clears it.
+	UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error
+	// UpdateNodeCompletionToken records the idempotency token a node was completed with.
+	UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error
+	// GetNodeCompletionToken returns the idempotency token a node was completed with, or "" if it
+	// has none or is unknown.
+	GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error)
+
+	// DeleteLogsOlderThan trims node_logs rows older than cutoff for completed nodes and returns
+	// how many were deleted. Rows whose action is in RetainedLogActions are always kept.
+	DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error)
+
+	// DeleteAllNodes removes every node and its entities, logs, notes, tags, results and archive rows.
+	// Resources are kept. It backs the staging-only admin reset.
+	DeleteAllNodes(ctx context.Context) error
+
+	ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error
+	ListArchivedNodes(ctx context.Context, q ArchiveQuery) ([]ArchivedNode, error)
 }
@@ -6,14 +6,37 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"time"
 
 	"nodequeue-service/db"
+	"nodequeue-service/node"
 	"nodequeue-service/queueservice"
+	"nodequeue-service/resource"
+	"nodequeue-service/tracing"
+	"nodequeue-service/utils"
 )
 
 // main is the program entry point. It initializes resources, registers routes,
 // and starts the HTTP server.
 func main() {
+	// Optional tracing: exports to OTLP when OTEL_EXPORTER_OTLP_ENDPOINT is set, no-op otherwise.
+	shutdownTracing, err := tracing.SetupFromEnv(context.Background())
+	if err != nil {
+		log.Printf("[Tracing] disabled (exporter setup failed): %v", err)
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	// Error response format: simple {"error": ...} (default) or RFC 7807 problem+json.
+	errorFormat := utils.ErrorFormatSimple
+	if raw := os.Getenv("ERROR_FORMAT"); raw != "" {
+		if !slices.Contains(utils.ValidErrorFormats, raw) {
+			log.Fatalf("invalid ERROR_FORMAT %q: must be one of %v", raw, utils.ValidErrorFormats)
+		}
+		errorFormat = raw
+	}
+
 	// Optional DB connection (best-effort). If env vars are not set or DB is down, we run in-memory.
 	dbConn, err := db.OpenFromEnv()
 	if err != nil {
@@ -21,29 +44,342 @@ func main() {
 	}
 	if dbConn != nil {
 		defer dbConn.Close()
+
+		// The init scripts only run on an empty volume, so bring older databases up to date here.
+		if err := db.Migrate(context.Background(), dbConn); err != nil {
+			log.Printf("[DB] schema migration failed: %v", err)
+		}
 	}
 
 	var store db.Store
 	if dbConn != nil {
-		store = db.NewPostgresStore(dbConn)
+		// Optionally serve read-only queries from a replica. Without one (or if it is down) all
+		// queries go to the primary.
+		replica, err := db.OpenReplicaFromEnv()
+		if err != nil {
+			log.Printf("[DB] replica disabled (failed to connect): %v", err)
+		}
+		if replica != nil {
+			defer replica.Close()
+			log.Printf("[DB] reading from replica")
+		}
+		store = db.NewPostgresStoreWithReplica(dbConn, replica)
+
+		// Optionally log store calls slower than DB_SLOW_CALL_THRESHOLD to diagnose DB latency.
+		if raw := os.Getenv("DB_SLOW_CALL_THRESHOLD"); raw != "" {
+			threshold, err := time.ParseDuration(raw)
+			if err != nil || threshold <= 0 {
+				log.Fatalf("invalid DB_SLOW_CALL_THRESHOLD %q: must be a positive duration", raw)
+			}
+			store = db.NewInstrumentedStore(store, func(op string, d time.Duration, err error) {
+				if d >= threshold {
+					log.Printf("[DB] slow call: %s took %v (err=%v)", op, d, err)
+				}
+			})
+			log.Printf("[DB] logging store calls slower than %v", threshold)
+		}
 	}
 
 	// Initialize queue service
 	queueService := queueservice.NewQueueServiceWithStore(store)
 
-	// Load resources from config (or fall back to defaults).
-	resources := setupResources("config.txt", queueService, store)
+	// Resource IDs are case-sensitive unless RESOURCE_ID_CASE=insensitive; set before resources load.
+	switch raw := os.Getenv("RESOURCE_ID_CASE"); raw {
+	case "", "sensitive":
+	case "insensitive":
+		queueService.FoldResourceIDCase = true
+	default:
+		log.Fatalf("invalid RESOURCE_ID_CASE %q: must be sensitive or insensitive", raw)
+	}
+
+	// Optional JSON format for node log timestamps (always UTC); set before nodes are restored.
+	if raw := os.Getenv("TIME_FORMAT"); raw != "" {
+		if !slices.Contains(node.ValidTimeFormats, raw) {
+			log.Fatalf("invalid TIME_FORMAT %q: must be one of %v", raw, node.ValidTimeFormats)
+		}
+		queueService.TimeFormat = raw
+	}
+
+	// Opt-in: only nodes in service may be completed.
+	if raw := os.Getenv("STRICT_LIFECYCLE"); raw != "" {
+		strict, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Fatalf("invalid STRICT_LIFECYCLE %q: %v", raw, err)
+		}
+		queueService.StrictLifecycle = strict
+		if strict {
+			log.Printf("Strict lifecycle enabled: nodes must be in service to complete")
+		}
+	}
+
+	// Opt-in: coalesce concurrent allocations under one lock acquisition.
+	if raw := os.Getenv("ALLOCATION_BATCHING"); raw != "" {
+		batching, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Fatalf("invalid ALLOCATION_BATCHING %q: %v", raw, err)
+		}
+		queueService.AllocationBatching = batching
+	}
+
+	// Retry/backoff policy for POST /nodes/{id}/fail.
+	if raw := os.Getenv("RETRY_MAX_ATTEMPTS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid RETRY_MAX_ATTEMPTS %q: must be a positive integer", raw)
+		}
+		queueService.Retry.MaxAttempts = n
+	}
+	if raw := os.Getenv("RETRY_BASE_BACKOFF"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("invalid RETRY_BASE_BACKOFF %q: must be a non-negative duration", raw)
+		}
+		queueService.Retry.BaseBackoff = d
+	}
+	if raw := os.Getenv("RETRY_MAX_BACKOFF"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			log.Fatalf("invalid RETRY_MAX_BACKOFF %q: must be a non-negative duration", raw)
+		}
+		queueService.Retry.MaxBackoff = d
+	}
+
+	// Defaults for GET /resources/{id}/recommendation.
+	if raw := os.Getenv("RECOMMEND_WINDOW"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid RECOMMEND_WINDOW %q: must be a positive duration", raw)
+		}
+		queueService.Recommendation.Window = d
+	}
+	if raw := os.Getenv("RECOMMEND_TARGET_P90_WAIT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid RECOMMEND_TARGET_P90_WAIT %q: must be a positive duration", raw)
+		}
+		queueService.Recommendation.TargetP90Wait = d
+	}
+	if raw := os.Getenv("RECOMMEND_LOW_UTILIZATION"); raw != "" {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || f < 0 || f > 1 {
+			log.Fatalf("invalid RECOMMEND_LOW_UTILIZATION %q: must be a number between 0 and 1", raw)
+		}
+		queueService.Recommendation.LowUtilization = f
+	}
+
+	// Opt-in guard against nodes bouncing between resources forever (0 = unlimited).
+	if raw := os.Getenv("MAX_MOVES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid MAX_MOVES %q: must be a non-negative integer", raw)
+		}
+		queueService.MaxMoves = n
+	}
+
+	// Opt-in cap on nodes held in memory; completed nodes are evicted (archived first with a DB).
+	if raw := os.Getenv("MAX_NODES_IN_MEMORY"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid MAX_NODES_IN_MEMORY %q: must be a non-negative integer", raw)
+		}
+		queueService.MaxNodesInMemory = n
+		if n > 0 && store == nil {
+			log.Printf("MAX_NODES_IN_MEMORY is set without a database; evicted completed nodes will not be kept anywhere")
+		}
+	}
+
+	// Opt-in: /readyz reports degraded after this many store writes fail in a row.
+	if raw := os.Getenv("STORE_DEGRADE_AFTER"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid STORE_DEGRADE_AFTER %q: must be a non-negative integer", raw)
+		}
+		queueService.StoreDegradeAfter = n
+	}
+
+	// Opt-in: at most one non-completed node per entity name.
+	if raw := os.Getenv("UNIQUE_ACTIVE_ENTITY"); raw != "" {
+		unique, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Fatalf("invalid UNIQUE_ACTIVE_ENTITY %q: %v", raw, err)
+		}
+		queueService.UniqueActiveEntity = unique
+		if unique {
+			log.Printf("Unique active entity enabled: one active node per entity")
+		}
+	}
+
+	// Persistence mode: strict fails creation/completion when their critical write fails.
+	persistMode, err := queueservice.ParsePersistMode(os.Getenv("PERSIST_MODE"))
+	if err != nil {
+		log.Fatalf("invalid PERSIST_MODE: %v", err)
+	}
+	queueService.PersistMode = persistMode
+
+	// Load resources from config (or fall back to defaults). CONFIG_STRICT turns malformed rows
+	// into a startup failure; CONFIG_DEFAULT_CAPACITY fills in rows without a capacity.
+	configOpts := resource.LoadOptions{FoldIDCase: queueService.FoldResourceIDCase}
+	if raw := os.Getenv("CONFIG_STRICT"); raw != "" {
+		strict, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Fatalf("invalid CONFIG_STRICT %q: %v", raw, err)
+		}
+		configOpts.Strict = strict
+	}
+	if raw := os.Getenv("CONFIG_DEFAULT_CAPACITY"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid CONFIG_DEFAULT_CAPACITY %q: must be a positive integer", raw)
+		}
+		configOpts.DefaultCapacity = n
+	}
+	// RESTORE_STRICT fails startup unless resources and nodes are fully restored from the DB;
+	// otherwise whatever can be read is restored and /readyz reports which parts failed.
+	if raw := os.Getenv("RESTORE_STRICT"); raw != "" {
+		strict, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Fatalf("invalid RESTORE_STRICT %q: %v", raw, err)
+		}
+		queueService.RestoreStrict = strict
+	}
+	resources, err := setupResources("config.txt", configOpts, queueService, store)
+	if err != nil {
+		log.Fatalf("loading resources: %v", err)
+	}
 	log.Printf("Initialized %d resources", len(resources))
 
-	// Restore nodes + queue membership from DB (best-effort).
+	// Restore nodes + queue membership from DB (best-effort unless RESTORE_STRICT).
 	if store != nil {
 		if err := queueService.RestoreFromStore(context.Background()); err != nil {
-			log.Printf("[DB] restore state failed (continuing with empty node state): %v", err)
+			log.Fatalf("[DB] restore state failed (RESTORE_STRICT): %v", err)
+		}
+		if status, ok := queueService.RestoreStatus(); ok && status.Partial {
+			log.Printf("[DB] partial restore, continuing without some stored state: %+v", status.Sources)
+		}
+		// Restored nodes may reference resources missing from the current config.
+		queueService.OrphanFallbackResourceID = os.Getenv("ORPHAN_FALLBACK_RESOURCE")
+		queueService.ReconcileOrphans()
+	}
+
+	// Optionally move old completed nodes out of memory into the DB archive.
+	if store != nil {
+		if raw := os.Getenv("ARCHIVE_COMPLETED_AFTER"); raw != "" {
+			olderThan, err := time.ParseDuration(raw)
+			if err != nil || olderThan <= 0 {
+				log.Printf("[DB] archiver disabled: invalid ARCHIVE_COMPLETED_AFTER %q", raw)
+			} else {
+				go queueService.RunArchiver(context.Background(), time.Minute, olderThan)
+				log.Printf("[DB] archiving nodes completed more than %v ago", olderThan)
+			}
+		}
+	}
+
+	// Optionally trim old node_logs rows for completed nodes.
+	if store != nil {
+		if raw := os.Getenv("NODE_LOG_RETENTION"); raw != "" {
+			retention, err := time.ParseDuration(raw)
+			if err != nil || retention <= 0 {
+				log.Printf("[DB] log compaction disabled: invalid NODE_LOG_RETENTION %q", raw)
+			} else {
+				interval := time.Hour
+				if rawInterval := os.Getenv("NODE_LOG_COMPACTION_INTERVAL"); rawInterval != "" {
+					if d, err := time.ParseDuration(rawInterval); err == nil && d > 0 {
+						interval = d
+					} else {
+						log.Printf("[DB] invalid NODE_LOG_COMPACTION_INTERVAL %q, using %v", rawInterval, interval)
+					}
+				}
+				go queueService.RunLogCompaction(context.Background(), interval, retention)
+				log.Printf("[DB] compacting node logs older than %v every %v", retention, interval)
+			}
+		}
+	}
+
+	// Autoscaling signal for resources with pressure thresholds; optionally posted to a webhook.
+	var pressureHook *queueservice.Webhook
+	if url := os.Getenv("PRESSURE_WEBHOOK_URL"); url != "" {
+		pressureHook = queueservice.NewWebhook(url)
+	}
+	monitor := queueservice.NewPressureMonitor(queueService, queueservice.PressureNotifier(pressureHook))
+	go monitor.Run(context.Background(), 5*time.Second)
+
+	// Waiting-time SLA breaches for resources with max_wait_ms; optionally posted to a webhook.
+	var slaHook *queueservice.Webhook
+	if url := os.Getenv("SLA_WEBHOOK_URL"); url != "" {
+		slaHook = queueservice.NewWebhook(url)
+	}
+	slaMonitor := queueservice.NewSLAMonitor(queueService, queueservice.SLANotifier(slaHook))
+	go slaMonitor.Run(context.Background(), 5*time.Second)
+
+	// Cancel nodes past their deadline_ts, checked every DEADLINE_CHECK_INTERVAL (default 1s).
+	deadlineInterval := time.Second
+	if raw := os.Getenv("DEADLINE_CHECK_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			log.Fatalf("invalid DEADLINE_CHECK_INTERVAL %q: must be a positive duration", raw)
+		}
+		deadlineInterval = interval
+	}
+	deadlineMonitor := queueservice.NewDeadlineMonitor(queueService)
+	go deadlineMonitor.Run(context.Background(), deadlineInterval)
+
+	// Optionally reclaim nodes stuck in service (e.g. their worker died) after SERVICE_TIMEOUT.
+	if raw := os.Getenv("SERVICE_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			log.Fatalf("invalid SERVICE_TIMEOUT %q: must be a positive duration", raw)
+		}
+		policy, err := queueservice.ParseServiceTimeoutPolicy(os.Getenv("SERVICE_TIMEOUT_POLICY"))
+		if err != nil {
+			log.Fatalf("invalid SERVICE_TIMEOUT_POLICY: %v", err)
+		}
+		watchdog := queueservice.NewServiceTimeoutWatchdog(queueService, timeout, policy)
+		go watchdog.Run(context.Background(), min(5*time.Second, timeout))
+		log.Printf("Reclaiming nodes in service longer than %v (policy %s)", timeout, policy)
+	}
+
+	// Optionally post each completed node, with its computed metrics, to a webhook.
+	if url := os.Getenv("COMPLETION_WEBHOOK_URL"); url != "" {
+		queueService.OnComplete = queueservice.CompletionNotifier(queueservice.NewWebhook(url))
+		log.Printf("Posting node completions to %s", url)
+	}
+
+	// Admin endpoints such as /admin/reset are refused unless ENABLE_ADMIN is set.
+	admin := utils.AdminGuardFromEnv()
+	if admin.Enabled {
+		if admin.APIKey == "" {
+			log.Printf("ENABLE_ADMIN is set without ADMIN_API_KEY; admin requests will be rejected")
+		} else {
+			log.Printf("Admin endpoints enabled")
 		}
 	}
 
 	// Setup HTTP routes
-	setupRoutes(queueService)
+	setupRoutes(queueService, admin)
+
+	// Optional per-client rate limiting (RATE_LIMIT_RPS / RATE_LIMIT_BURST / RATE_LIMIT_API_KEYS).
+	var handler http.Handler = http.DefaultServeMux
+	if limiter := utils.RateLimiterFromEnv(); limiter != nil {
+		go limiter.RunCleanup(context.Background(), time.Minute, 10*time.Minute)
+		handler = limiter.Handler(handler)
+		log.Printf("Rate limiting enabled")
+	}
+	// Tracing is outermost so rate-limited requests are traced too.
+	handler = tracing.Middleware(http.DefaultServeMux, handler)
+	// Wraps everything so rate-limit errors are written as problem+json as well.
+	if errorFormat == utils.ErrorFormatProblem {
+		handler = utils.ProblemMiddleware(handler)
+	}
+	// DEBUG_HTTP logs every request with headers and truncated bodies; outermost so it sees the
+	// final status. Not installed at all when disabled.
+	debugLogger, err := utils.DebugLoggerFromEnv()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if debugLogger != nil {
+		handler = debugLogger.Handler(handler)
+		log.Printf("HTTP debug logging enabled (bodies truncated to %d bytes; redacting %v)", debugLogger.BodyLimit, debugLogger.RedactHeaders)
+	}
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -54,15 +390,66 @@ func main() {
 	addr := fmt.Sprintf(":%s", port)
 	log.Printf("Starting server on %s", addr)
 	log.Println("API Endpoints:")
-	log.Println("  POST   /nodes - Create a new node")
-	log.Println("  GET    /nodes - List all nodes")
-	log.Println("  GET    /nodes/{id} - Get a specific node")
+	log.Println("  POST   /nodes[?require_capacity=true] - Create a new node (optionally only if it can start now)")
+	log.Println("  GET    /nodes?tag= - List all nodes (optionally only those with every tag)")
+	log.Println("  POST   /nodes/bulk - Complete, cancel or move several nodes at once")
+	log.Println("  GET    /nodes/archive?since=&until= - Query archived (completed) nodes from the DB")
+	log.Println("  GET    /nodes/logs.jsonl?since=&until= - Stream every node log row as JSON Lines")
+	log.Println("  GET    /nodes/active - List active (non-completed) nodes")
+	log.Println("  GET    /nodes/waiting?resource_id=&sort=age|position - List waiting nodes across resources")
+	log.Println("  GET    /nodes/throughput?bucket=5m&window=6h&resource_id= - Completions per time bucket")
+	log.Println("  GET    /nodes/outcomes?since=&until=&resource_id= - Completion outcome counts and success rate")
+	log.Println("  GET    /nodes/{id}[?wait=&since_version=] - Get a specific node (optionally long-poll for changes)")
 	log.Println("  POST   /nodes/{id}/move - Move a node to another resource")
 	log.Println("  POST   /nodes/{id}/allocate - Allocate a waiting node into the service queue (capacity enforced)")
+	log.Println("  POST   /nodes/{id}/transfer - Move a node to another resource and allocate it there atomically")
+	log.Println("  GET    /nodes/{id}/can-allocate - Check whether a node could be allocated (dry run)")
+	log.Println("  GET    /nodes/{id}/eligible-resources - List resources a node could be moved to")
+	log.Println("  GET    /nodes/{id}/metrics - Get timers/metrics for a single node")
+	log.Println("  POST   /nodes/metrics/batch - Get timers/metrics for a list of node IDs")
+	log.Println("  POST   /nodes/{id}/claim - Allocate a waiting node using a capacity reservation")
 	log.Println("  POST   /nodes/{id}/complete - Complete a node")
-	log.Println("  GET    /resources - List all resources")
+	log.Println("  POST   /nodes/{id}/fail - Record a failed attempt (re-queue with backoff)")
+	log.Println("  PUT    /nodes/{id}/position - Reorder a waiting node within its resource")
+	log.Println("  POST   /nodes/{id}/expedite - Move a waiting node to the front of its queue")
+	log.Println("  POST   /nodes/{id}/defer - Move a waiting node to the back of its queue")
+	log.Println("  POST   /nodes/{id}/notes - Attach an operator note to a node")
+	log.Println("  POST   /nodes/{id}/tags - Add tags to a node")
+	log.Println("  DELETE /nodes/{id}/tags/{tag} - Remove a tag from a node")
+	log.Println("  POST   /entities/{name}/move?to={id} - Move every active node of an entity to a resource")
+	log.Println("  POST   /resources - Create a new resource")
+	log.Println("  POST   /resources/batch - Create several resources from a JSON array")
+	log.Println("  GET    /resources?sort=id|utilization|waiting&order=asc|desc&label=k=v - List resources with their load")
+	log.Println("  GET    /resources.csv - Export resources in config.txt CSV format")
+	log.Println("  GET    /resources/{id} - Get a resource with its waiting/service queues")
+	log.Println("  PATCH  /resources/{id} - Set or remove resource labels")
+	log.Println("  GET    /resources/{id}/oldest - Get the longest-waiting node on a resource")
+	log.Println("  GET    /resources/{id}/waiting?include=entity - List a resource's waiting node IDs in order")
+	log.Println("  GET    /resources/{id}/recommendation?window=&target_p90_wait= - Suggest a capacity change from recent waits and utilization")
+	log.Println("  POST   /resources/{id}/reserve - Hold capacity for an incoming node")
+	log.Println("  POST   /resources/{id}/fill - Allocate waiting nodes until the resource is full")
+	log.Println("  POST   /resources/{id}/pause - Stop allocations into a resource")
+	log.Println("  POST   /resources/{id}/resume - Resume allocations into a resource")
+	log.Println("  POST   /resources/{id}/redirect - Send moves into a resource to another one (empty to clears)")
+	log.Println("  POST   /resources/{id}/drain?to={id} - Move all waiting nodes to another resource")
+	log.Println("  POST   /resources/{id}/release - Move every service node back to waiting, freeing all capacity")
+	log.Println("  POST   /resources/{id}/swap - Swap the positions of two waiting nodes")
+	log.Println("  POST   /resources/{id}/clone - Create an empty resource with the same configuration")
+	log.Println("  POST   /admin/reset?purge_db=true - Clear all node state (ENABLE_ADMIN only)")
+	log.Println("  POST   /admin/restore - Merge DB state into memory (ENABLE_ADMIN only)")
+	log.Println("  POST   /admin/rebuild - Rebuild nodes and queues by replaying DB logs (ENABLE_ADMIN only)")
+	log.Println("  POST   /admin/reconcile - Repair nodes assigned to missing resources (ENABLE_ADMIN only)")
+	log.Println("  GET    /admin/consistency - Report broken queue invariants (ENABLE_ADMIN only)")
+	log.Println("  GET    /stats - Global node/resource counters")
+	log.Println("  GET    /sla/breaches - Waiting nodes over their resource's max_wait_ms")
+	log.Println("  GET    /debug/internals - Goroutines, subscribers and queue sizes (ENABLE_ADMIN only)")
+	log.Println("  GET    /healthz - Liveness probe")
+	log.Println("  GET    /readyz - Readiness probe (503 while the store is degraded; reports the startup restore)")
+	log.Println("  GET    /metrics - Prometheus store write counters")
+	log.Println("  GET    /admin/store-health - Store write counters, last error and last success (ENABLE_ADMIN only)")
+	log.Println("  GET    /ws - WebSocket: stream node events and issue node commands")
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
@@ -1,8 +1,15 @@
 package node
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Entity is the domain object referenced by a Node.
@@ -19,6 +26,10 @@ type Entity struct {
 // - allocated into the Resource's service queue (consumes capacity)
 // - completed (removed from resource queues, no further moves/allocations allowed)
 //
+// A node in service may instead fail (see QueueService.FailNode): it re-enters the waiting queue
+// with Attempts incremented and is not allocatable before NotBeforeTS. Once it runs out of
+// attempts it is completed with Failed set.
+//
 // All state transitions are recorded in Log.
 type Node struct {
 	ID     string  `json:"id"`
@@ -28,8 +39,85 @@ type Node struct {
 	Completed   bool      `json:"completed"`
 	CreatedAt   time.Time `json:"created_at"`
 	resourceIDs []string
-	Log         []NodeLog `json:"log"`
-	mu          sync.RWMutex
+	Log         []NodeLog  `json:"log"`
+	Notes       []NodeNote `json:"notes,omitempty"`
+	// Tags are free-form labels (e.g. "region:eu"), kept sorted and unique.
+	Tags []string `json:"tags,omitempty"`
+	// AllowedResources, when non-empty, lists the only resources the node may be placed on,
+	// sorted and unique (see AllowsResource).
+	AllowedResources []string `json:"allowed_resources,omitempty"`
+	// Weight is how many capacity units the node consumes while in service (see CapacityWeight).
+	Weight int `json:"weight"`
+	// Version increases with every lifecycle log entry; long-polling clients compare against it.
+	Version int64 `json:"version"`
+	// Attempts counts failed service attempts; NotBeforeTS is when the node may next be allocated.
+	Attempts      int        `json:"attempts,omitempty"`
+	NotBeforeTS   *time.Time `json:"not_before_ts,omitempty"`
+	Failed        bool       `json:"failed,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	// DeadlineTS, if set, is when the node stops being worth serving: once it passes, the
+	// node is cancelled wherever it is (see queueservice.DeadlineMonitor) and DeadlineExceeded
+	// is set.
+	DeadlineTS       *time.Time `json:"deadline_ts,omitempty"`
+	DeadlineExceeded bool       `json:"deadline_exceeded,omitempty"`
+	// Result is the outcome recorded when the node was completed, if the caller supplied one.
+	Result *NodeResult `json:"result,omitempty"`
+	// CompletionToken is the idempotency token the node was completed with, if any. A repeated
+	// completion carrying the same token succeeds instead of failing (see CompleteNodeRequest).
+	CompletionToken string `json:"-"`
+	// timeFormat is how log timestamps are written to JSON (see SetTimeFormat).
+	timeFormat string
+	mu         sync.RWMutex
+}
+
+// CapacityWeight returns the capacity units the node consumes in service. Nodes without an
+// explicit Weight count as 1.
+func (n *Node) CapacityWeight() int {
+	if n.Weight < 1 {
+		return 1
+	}
+	return n.Weight
+}
+
+// Snapshot returns a copy of n that shares no mutable state with it: Entity, Log, Notes, Tags,
+// AllowedResources, NotBeforeTS, DeadlineTS and Result are copied, so the result can be serialized while n
+// keeps changing.
+// Like AddLog, it is not concurrency-safe on its own; callers must hold whatever lock guards n.
+func (n *Node) Snapshot() *Node {
+	snap := &Node{
+		ID:               n.ID,
+		ResourceID:       n.ResourceID,
+		Completed:        n.Completed,
+		CreatedAt:        n.CreatedAt,
+		Log:              slices.Clone(n.Log),
+		Notes:            slices.Clone(n.Notes),
+		Tags:             slices.Clone(n.Tags),
+		AllowedResources: slices.Clone(n.AllowedResources),
+		Weight:           n.Weight,
+		Version:          n.Version,
+		Attempts:         n.Attempts,
+		Failed:           n.Failed,
+		FailureReason:    n.FailureReason,
+		DeadlineExceeded: n.DeadlineExceeded,
+		timeFormat:       n.timeFormat,
+	}
+	if n.Entity != nil {
+		entity := *n.Entity
+		snap.Entity = &entity
+	}
+	if n.NotBeforeTS != nil {
+		notBefore := *n.NotBeforeTS
+		snap.NotBeforeTS = &notBefore
+	}
+	if n.DeadlineTS != nil {
+		deadline := *n.DeadlineTS
+		snap.DeadlineTS = &deadline
+	}
+	snap.Result = n.Result.Clone()
+	n.mu.RLock()
+	snap.resourceIDs = slices.Clone(n.resourceIDs)
+	n.mu.RUnlock()
+	return snap
 }
 
 // AddResourceID records that this node has been associated with a resource.
@@ -44,32 +132,440 @@ func (n *Node) AddResourceID(resourceID string) bool {
 // addLog appends a lifecycle event to the node log.
 // It is not concurrency-safe on its own; callers should ensure appropriate external locking.
 func (n *Node) AddLog(action, resourceID string) {
+	n.AddMoveLog(action, resourceID, "")
+}
+
+// AddMoveLog is AddLog for an entry that also records the resource the node came from.
+func (n *Node) AddMoveLog(action, resourceID, fromResourceID string) {
 	n.Log = append(n.Log, NodeLog{
-		Action:     action,
-		ResourceID: resourceID,
-		Timestamp:  time.Now(),
+		Action:         action,
+		ResourceID:     resourceID,
+		FromResourceID: fromResourceID,
+		Timestamp:      Now(),
+		timeFormat:     n.timeFormat,
 	})
 }
 
+// AddNote appends an operator note. Notes are append-only, so the slice stays in timestamp order.
+// Like AddLog, it is not concurrency-safe on its own.
+func (n *Node) AddNote(author, text string) NodeNote {
+	note := NodeNote{
+		Author:    author,
+		Text:      text,
+		Timestamp: Now(),
+	}
+	n.Notes = append(n.Notes, note)
+	return note
+}
+
+// AddTag adds tag to n.Tags, keeping them sorted, and reports whether it was new.
+// Like AddLog, it is not concurrency-safe on its own.
+func (n *Node) AddTag(tag string) bool {
+	i, found := slices.BinarySearch(n.Tags, tag)
+	if found {
+		return false
+	}
+	n.Tags = slices.Insert(n.Tags, i, tag)
+	return true
+}
+
+// RemoveTag removes tag from n.Tags and reports whether it was present.
+// Like AddLog, it is not concurrency-safe on its own.
+func (n *Node) RemoveTag(tag string) bool {
+	i, found := slices.BinarySearch(n.Tags, tag)
+	if !found {
+		return false
+	}
+	n.Tags = slices.Delete(n.Tags, i, i+1)
+	return true
+}
+
+// HasTags reports whether n carries every one of tags (true for none).
+func (n *Node) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if _, found := slices.BinarySearch(n.Tags, tag); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SetAllowedResources replaces n.AllowedResources with resourceIDs, sorted and deduplicated
+// (nil or empty allows every resource). Like AddLog, it is not concurrency-safe on its own.
+func (n *Node) SetAllowedResources(resourceIDs []string) {
+	if len(resourceIDs) == 0 {
+		n.AllowedResources = nil
+		return
+	}
+	allowed := slices.Clone(resourceIDs)
+	slices.Sort(allowed)
+	n.AllowedResources = slices.Compact(allowed)
+}
+
+// AllowsResource reports whether n may be placed on resourceID: always true without a whitelist.
+func (n *Node) AllowsResource(resourceID string) bool {
+	if len(n.AllowedResources) == 0 {
+		return true
+	}
+	_, found := slices.BinarySearch(n.AllowedResources, resourceID)
+	return found
+}
+
+// MaxTagLength caps the size of a single tag; MaxTags caps how many tags a request may carry.
+const (
+	MaxTagLength = 64
+	MaxTags      = 32
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:=-]*$`)
+
+// ValidTag reports whether tag is acceptable: 1 to MaxTagLength letters, digits or "_.:=-",
+// starting with a letter or digit. Tags never contain "/", so they can appear in URL paths.
+func ValidTag(tag string) bool {
+	return len(tag) <= MaxTagLength && tagPattern.MatchString(tag)
+}
+
+// validateTags reports the first problem with tags under field, if any.
+func validateTags(fields map[string]string, field string, tags []string) {
+	if len(tags) > MaxTags {
+		fields[field] = fmt.Sprintf("must have at most %d tags", MaxTags)
+		return
+	}
+	for _, tag := range tags {
+		if !ValidTag(tag) {
+			fields[field] = fmt.Sprintf("invalid tag %q: must be 1-%d letters, digits or \"_.:=-\"", tag, MaxTagLength)
+			return
+		}
+	}
+}
+
+// ValidID reports whether id is acceptable as a client-supplied node ID. Node IDs must be UUIDs:
+// the store keys nodes by uuid.
+func ValidID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// CanonicalID returns the form node IDs are registered and looked up under: a UUID in any form
+// uuid.Parse accepts (upper case, braces, "urn:uuid:", no hyphens) becomes lower-case and hyphenated,
+// matching how the store keys nodes. Other IDs are returned as is.
+func CanonicalID(id string) string {
+	if u, err := uuid.Parse(id); err == nil {
+		return u.String()
+	}
+	return id
+}
+
 // CreateNodeRequest is the request payload for POST /nodes.
 //
-// If ResourceID is provided, the newly created node is immediately assigned to that resource's
-// waiting queue (via MoveNode).
+// If ID is provided it is used as the node ID instead of a generated UUID, so client retries
+// are naturally idempotent. Weight defaults to 1. If ResourceID is provided, the newly created node is immediately
+// assigned to that resource's waiting queue (via MoveNode).
 type CreateNodeRequest struct {
-	EntityName string `json:"entity_name"`
-	ResourceID string `json:"resource_id,omitempty"` // Optional: add to resource immediately
+	ID         string   `json:"id,omitempty"` // Optional: client-supplied node ID
+	EntityName string   `json:"entity_name"`
+	ResourceID string   `json:"resource_id,omitempty"` // Optional: add to resource immediately
+	Weight     int      `json:"weight,omitempty"`      // Optional: capacity units consumed in service (default 1)
+	Tags       []string `json:"tags,omitempty"`        // Optional: initial tags
+	// Optional: the only resources the node may be placed on
+	AllowedResources []string `json:"allowed_resources,omitempty"`
+	// Optional: place the node only on resources carrying all of these labels. It is resolved into
+	// AllowedResources (intersected with it if both are given) when the node is created.
+	ResourceLabels map[string]string `json:"resource_labels,omitempty"`
+	// Optional: cancel the node if it is still active at this time; must be in the future
+	DeadlineTS *time.Time `json:"deadline_ts,omitempty"`
+}
+
+// Validate reports missing or invalid fields.
+func (req CreateNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if strings.TrimSpace(req.EntityName) == "" {
+		fields["entity_name"] = "is required"
+	}
+	if req.Weight < 0 {
+		fields["weight"] = "must be at least 1"
+	}
+	if req.ID != "" && !ValidID(req.ID) {
+		fields["id"] = "must be a UUID"
+	}
+	validateTags(fields, "tags", req.Tags)
+	if slices.Contains(req.AllowedResources, "") {
+		fields["allowed_resources"] = "must not contain empty resource IDs"
+	} else if req.ResourceID != "" && len(req.AllowedResources) > 0 && !slices.Contains(req.AllowedResources, req.ResourceID) {
+		fields["resource_id"] = "must be one of allowed_resources"
+	}
+	for key := range req.ResourceLabels {
+		if strings.TrimSpace(key) == "" {
+			fields["resource_labels"] = "label keys must not be empty"
+		}
+	}
+	if req.DeadlineTS != nil && !req.DeadlineTS.After(Now()) {
+		fields["deadline_ts"] = "must be in the future"
+	}
+	return fields
 }
 
 // MoveNodeRequest is the request payload for POST /nodes/{id}/move.
+//
+// Lane optionally selects a named waiting lane on the target resource (default lane if empty).
+// FromResourceID, if set, makes the move conditional on the node currently being on that resource.
 type MoveNodeRequest struct {
 	TargetResourceID string `json:"target_resource_id"`
+	Lane             string `json:"lane,omitempty"`
+	FromResourceID   string `json:"from_resource_id,omitempty"`
+}
+
+// Validate reports missing or invalid fields.
+func (req MoveNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.TargetResourceID == "" {
+		fields["target_resource_id"] = "is required"
+	}
+	return fields
+}
+
+// TransferNodeRequest is the request payload for POST /nodes/{id}/transfer.
+type TransferNodeRequest struct {
+	TargetResourceID string `json:"target_resource_id"`
+}
+
+// Validate reports missing or invalid fields.
+func (req TransferNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.TargetResourceID == "" {
+		fields["target_resource_id"] = "is required"
+	}
+	return fields
+}
+
+// ReorderNodeRequest is the request payload for PUT /nodes/{id}/position.
+//
+// Position is a zero-based index into the node's current waiting queue; out-of-range values are clamped.
+type ReorderNodeRequest struct {
+	Position *int `json:"position"`
+}
+
+// Validate reports missing or invalid fields.
+func (req ReorderNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.Position == nil {
+		fields["position"] = "is required"
+	}
+	return fields
+}
+
+// ClaimReservationRequest is the request payload for POST /nodes/{id}/claim.
+type ClaimReservationRequest struct {
+	ReservationID string `json:"reservation_id"`
+}
+
+// Validate reports missing or invalid fields.
+func (req ClaimReservationRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.ReservationID == "" {
+		fields["reservation_id"] = "is required"
+	}
+	return fields
+}
+
+// MaxNoteLength caps the size of a single note's text.
+const MaxNoteLength = 2000
+
+// AddNoteRequest is the request payload for POST /nodes/{id}/notes.
+type AddNoteRequest struct {
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+// Validate reports missing or invalid fields.
+func (req AddNoteRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if strings.TrimSpace(req.Author) == "" {
+		fields["author"] = "is required"
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		fields["text"] = "is required"
+	} else if len(req.Text) > MaxNoteLength {
+		fields["text"] = fmt.Sprintf("must be at most %d bytes", MaxNoteLength)
+	}
+	return fields
+}
+
+// AddTagsRequest is the request payload for POST /nodes/{id}/tags.
+type AddTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// Validate reports missing or invalid fields.
+func (req AddTagsRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if len(req.Tags) == 0 {
+		fields["tags"] = "is required"
+	}
+	validateTags(fields, "tags", req.Tags)
+	return fields
+}
+
+// FailNodeRequest is the request payload for POST /nodes/{id}/fail.
+type FailNodeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate reports missing or invalid fields.
+func (req FailNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if strings.TrimSpace(req.Reason) == "" {
+		fields["reason"] = "is required"
+	} else if len(req.Reason) > MaxNoteLength {
+		fields["reason"] = fmt.Sprintf("must be at most %d bytes", MaxNoteLength)
+	}
+	return fields
+}
+
+// Completion outcomes accepted by POST /nodes/{id}/complete.
+const (
+	OutcomeSuccess   = "success"
+	OutcomeFailure   = "failure"
+	OutcomeCancelled = "cancelled"
+)
+
+// ValidOutcomes lists the accepted completion outcomes.
+var ValidOutcomes = []string{OutcomeSuccess, OutcomeFailure, OutcomeCancelled}
+
+// Bulk actions accepted by POST /nodes/bulk.
+const (
+	BulkActionComplete = "complete"
+	BulkActionCancel   = "cancel"
+	BulkActionMove     = "move"
+)
+
+// ValidBulkActions lists the accepted bulk actions.
+var ValidBulkActions = []string{BulkActionComplete, BulkActionCancel, BulkActionMove}
+
+// MaxBulkIDs caps how many nodes one bulk request may act on.
+const MaxBulkIDs = 500
+
+// BulkActionRequest is the request payload for POST /nodes/bulk.
+type BulkActionRequest struct {
+	IDs    []string `json:"ids"`
+	Action string   `json:"action"`
+	// TargetResourceID is required for the move action and ignored otherwise.
+	TargetResourceID string `json:"target_resource_id,omitempty"`
+}
+
+// Validate reports missing or invalid fields.
+func (req BulkActionRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	switch {
+	case len(req.IDs) == 0:
+		fields["ids"] = "is required"
+	case len(req.IDs) > MaxBulkIDs:
+		fields["ids"] = fmt.Sprintf("must have at most %d entries", MaxBulkIDs)
+	case slices.Contains(req.IDs, ""):
+		fields["ids"] = "must not contain empty IDs"
+	}
+	if !slices.Contains(ValidBulkActions, req.Action) {
+		fields["action"] = "must be one of: " + strings.Join(ValidBulkActions, ", ")
+	}
+	if req.Action == BulkActionMove && strings.TrimSpace(req.TargetResourceID) == "" {
+		fields["target_resource_id"] = "is required for move"
+	}
+	return fields
+}
+
+// NodeMetricsBatchRequest is the request payload for POST /nodes/metrics/batch.
+type NodeMetricsBatchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// Validate reports missing or invalid fields.
+func (req NodeMetricsBatchRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	switch {
+	case len(req.IDs) == 0:
+		fields["ids"] = "is required"
+	case len(req.IDs) > MaxBulkIDs:
+		fields["ids"] = fmt.Sprintf("must have at most %d entries", MaxBulkIDs)
+	case slices.Contains(req.IDs, ""):
+		fields["ids"] = "must not contain empty IDs"
+	}
+	return fields
+}
+
+// MaxResultBytes caps the size of a completion result payload.
+const MaxResultBytes = 4096
+
+// NodeResult is the outcome of a completed node, with an optional small JSON result blob.
+type NodeResult struct {
+	Outcome string          `json:"outcome"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+// Clone returns a deep copy of r (nil for nil).
+func (r *NodeResult) Clone() *NodeResult {
+	if r == nil {
+		return nil
+	}
+	return &NodeResult{Outcome: r.Outcome, Result: slices.Clone(r.Result)}
+}
+
+// CompleteNodeRequest is the optional request payload for POST /nodes/{id}/complete.
+//
+// Without Outcome the node completes without a result; Result requires an Outcome.
+// CompletionToken makes retries safe: completing an already completed node with the token it was
+// completed with succeeds again.
+type CompleteNodeRequest struct {
+	Outcome         string          `json:"outcome,omitempty"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	CompletionToken string          `json:"completion_token,omitempty"`
+}
+
+// MaxCompletionTokenLength caps CompleteNodeRequest.CompletionToken.
+const MaxCompletionTokenLength = 128
+
+// Validate reports missing or invalid fields.
+func (req CompleteNodeRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.Outcome != "" && !slices.Contains(ValidOutcomes, req.Outcome) {
+		fields["outcome"] = "must be one of: " + strings.Join(ValidOutcomes, ", ")
+	} else if req.Outcome == "" && len(req.Result) > 0 {
+		fields["outcome"] = "is required when result is set"
+	}
+	if len(req.Result) > MaxResultBytes {
+		fields["result"] = fmt.Sprintf("must be at most %d bytes", MaxResultBytes)
+	}
+	if len(req.CompletionToken) > MaxCompletionTokenLength {
+		fields["completion_token"] = fmt.Sprintf("must be at most %d characters", MaxCompletionTokenLength)
+	}
+	return fields
+}
+
+// NodeResult returns the result described by req, or nil if it carries no outcome.
+func (req CompleteNodeRequest) NodeResult() *NodeResult {
+	if req.Outcome == "" {
+		return nil
+	}
+	return &NodeResult{Outcome: req.Outcome, Result: req.Result}
 }
 
 // NodeLog records an action taken on a node (with optional Resource context) and when it occurred.
+// Timestamp is in UTC and is written to JSON in the format of the node it was added to (see
+// Node.SetTimeFormat).
 //
 // Action values are intentionally simple strings to keep the API stable and human-readable.
 type NodeLog struct {
-	Action     string    `json:"action"`
-	ResourceID string    `json:"resource_id,omitempty"`
-	Timestamp  time.Time `json:"timestamp"`
+	Action     string `json:"action"`
+	ResourceID string `json:"resource_id,omitempty"`
+	// FromResourceID is the resource a node was moved off, on moved_to_waiting_queue entries that
+	// relocate it between resources.
+	FromResourceID string    `json:"from_resource_id,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	timeFormat     string
+}
+
+// NodeNote is a freeform operator annotation on a node (e.g. "escalated by support").
+type NodeNote struct {
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp"`
 }
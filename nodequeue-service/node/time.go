@@ -0,0 +1,58 @@
+package node
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Values accepted by Node.SetTimeFormat.
+const (
+	// TimeFormatRFC3339Nano writes timestamps as RFC 3339 strings with nanoseconds (the default).
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	// TimeFormatRFC3339 writes timestamps as RFC 3339 strings truncated to the second.
+	TimeFormatRFC3339 = "rfc3339"
+	// TimeFormatUnixMilli writes timestamps as integer milliseconds since the Unix epoch.
+	TimeFormatUnixMilli = "unix_ms"
+)
+
+// ValidTimeFormats lists the accepted time format values.
+var ValidTimeFormats = []string{TimeFormatRFC3339Nano, TimeFormatRFC3339, TimeFormatUnixMilli}
+
+// Now returns the current time in UTC. Every timestamp recorded on a node goes through it, so
+// API output does not depend on the server's local timezone.
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// SetTimeFormat selects how n's log timestamps are written to JSON: one of ValidTimeFormats, with
+// "" meaning TimeFormatRFC3339Nano. It applies to the entries already in n.Log and to those added
+// later, and carries over to snapshots. QueueService sets it from its TimeFormat.
+// Like AddLog, it is not concurrency-safe on its own.
+func (n *Node) SetTimeFormat(format string) {
+	n.timeFormat = format
+	for i := range n.Log {
+		n.Log[i].timeFormat = format
+	}
+}
+
+// MarshalJSON writes Timestamp in UTC using the format of the node the entry belongs to.
+func (l NodeLog) MarshalJSON() ([]byte, error) {
+	type plain NodeLog
+	return json.Marshal(struct {
+		plain
+		Timestamp any `json:"timestamp"`
+	}{plain(l), formatTime(l.Timestamp, l.timeFormat)})
+}
+
+// formatTime renders t in UTC according to format (see ValidTimeFormats).
+func formatTime(t time.Time, format string) any {
+	t = t.UTC()
+	switch format {
+	case TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeFormatUnixMilli:
+		return t.UnixMilli()
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
@@ -0,0 +1,43 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// ListActiveNodes returns a snapshot of every node that has not completed, oldest first (ties by
+// ID). It reads the active node index rather than scanning every node, so its cost grows with the
+// number of active nodes only.
+func (qs *QueueService) ListActiveNodes() []*node.Node {
+	qs.mu.RLock()
+	nodes := make([]*node.Node, 0, len(qs.activeNodes))
+	for id := range qs.activeNodes {
+		nodes = append(nodes, qs.nodes[id].Snapshot())
+	}
+	qs.mu.RUnlock()
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].CreatedAt.Equal(nodes[j].CreatedAt) {
+			return nodes[i].CreatedAt.Before(nodes[j].CreatedAt)
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+// ListActiveNodesHandler handles GET /nodes/active.
+func (qs *QueueService) ListActiveNodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /nodes/active - Request")
+	nodes := qs.ListActiveNodes()
+	log.Printf("[API] GET /nodes/active - SUCCESS: Returning %d active nodes", len(nodes))
+	utils.RespondWithJSON(w, http.StatusOK, nodes)
+}
@@ -0,0 +1,29 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+
+	"nodequeue-service/node"
+	"nodequeue-service/resource"
+)
+
+// AdmissionFunc decides whether node may be promoted into resource's service queue. A non-nil
+// error vetoes the allocation; it is returned to the caller wrapped in ErrAdmissionDenied.
+//
+// It runs for AllocateNode, TransferAndAllocate, claims and automatic fills, after the built-in
+// checks pass, but not for the CanAllocate dry run. It is called with the service lock held, so
+// it must be fast and must not call back into the QueueService.
+type AdmissionFunc func(ctx context.Context, n *node.Node, r *resource.Resource) error
+
+// admitLocked runs qs.AdmissionFunc, if set, for an allocation that has already passed the
+// built-in checks. Callers must hold qs.mu for writing.
+func (qs *QueueService) admitLocked(ctx context.Context, n *node.Node, r *resource.Resource) error {
+	if qs.AdmissionFunc == nil {
+		return nil
+	}
+	if err := qs.AdmissionFunc(ctx, n, r); err != nil {
+		return fmt.Errorf("%w: %w", ErrAdmissionDenied, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package queueservice
+
+import "sync"
+
+// MaxAllocationBatch caps how many coalesced operations run under one acquisition of qs.mu, so a
+// steady stream of allocations cannot keep other writers out indefinitely.
+const MaxAllocationBatch = 256
+
+// allocBatcher queues allocation work while another goroutine holds qs.mu for it.
+type allocBatcher struct {
+	mu      sync.Mutex
+	pending []*allocOp
+	running bool
+}
+
+// allocOp is one queued operation; done is closed once it has run. If run panicked, recovered
+// holds the value so it can be re-raised in the goroutine that queued the operation.
+type allocOp struct {
+	run       func()
+	done      chan struct{}
+	recovered any
+}
+
+// runRecovered runs op and closes op.done. A panic is recovered into op.recovered rather than
+// unwinding the leader, which runs op on another caller's behalf.
+func (op *allocOp) runRecovered() {
+	defer close(op.done)
+	defer func() { op.recovered = recover() }()
+	op.run()
+}
+
+// take removes the next batch of at most MaxAllocationBatch operations from the queue. Once the
+// queue is empty it clears running, under the same lock, and returns nil.
+func (b *allocBatcher) take() []*allocOp {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		b.pending = nil
+		b.running = false
+		return nil
+	}
+	n := min(len(b.pending), MaxAllocationBatch)
+	batch := b.pending[:n:n]
+	b.pending = b.pending[n:]
+	return batch
+}
+
+// withAllocLock runs fn while holding qs.mu for writing.
+//
+// With AllocationBatching, concurrent callers are coalesced: the first becomes the leader, takes
+// qs.mu once and runs every operation queued in the meantime, in arrival order, up to
+// MaxAllocationBatch per acquisition; the others wait for theirs to have run. Each operation still
+// sees the state the previous one left, so the outcome is the same as running them one after the
+// other. A panic in fn is re-raised in its own caller once qs.mu is released, and does not stop
+// the leader from running the rest of the queue. fn must not take qs.mu itself.
+func (qs *QueueService) withAllocLock(fn func()) {
+	if !qs.AllocationBatching {
+		qs.mu.Lock()
+		defer qs.mu.Unlock()
+		fn()
+		return
+	}
+
+	op := &allocOp{run: fn, done: make(chan struct{})}
+	b := &qs.allocBatch
+	b.mu.Lock()
+	b.pending = append(b.pending, op)
+	if b.running {
+		b.mu.Unlock()
+		<-op.done
+	} else {
+		b.running = true
+		b.mu.Unlock()
+		qs.leadAllocBatches()
+	}
+	if op.recovered != nil {
+		panic(op.recovered)
+	}
+}
+
+// leadAllocBatches runs queued operations, one batch per acquisition of qs.mu, until the queue is
+// empty. Only the leader calls it, with running set.
+func (qs *QueueService) leadAllocBatches() {
+	b := &qs.allocBatch
+	drained := false
+	defer func() {
+		if !drained {
+			// Unreachable unless the batch loop itself panicked: let the next caller lead.
+			b.mu.Lock()
+			b.running = false
+			b.mu.Unlock()
+		}
+	}()
+
+	for batch := b.take(); batch != nil; batch = b.take() {
+		qs.runAllocBatch(batch)
+	}
+	drained = true
+}
+
+// runAllocBatch runs batch in order under one acquisition of qs.mu.
+func (qs *QueueService) runAllocBatch(batch []*allocOp) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	for _, op := range batch {
+		op.runRecovered()
+	}
+}
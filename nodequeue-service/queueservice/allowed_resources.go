@@ -0,0 +1,31 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+
+	"nodequeue-service/node"
+)
+
+// checkAllowedResource returns an error wrapping ErrResourceNotAllowed if n's whitelist excludes
+// resourceID (see node.Node.AllowsResource).
+func checkAllowedResource(n *node.Node, resourceID string) error {
+	if !n.AllowsResource(resourceID) {
+		return fmt.Errorf("%w: %s", ErrResourceNotAllowed, resourceID)
+	}
+	return nil
+}
+
+// setAllowedResourcesLocked sets the whitelist of a node fresh from createNodeLocked and persists
+// it (best-effort). An empty list leaves the node free to use any resource. Callers must hold qs.mu
+// for writing.
+func (qs *QueueService) setAllowedResourcesLocked(ctx context.Context, n *node.Node, resourceIDs []string) {
+	if len(resourceIDs) == 0 {
+		return
+	}
+	n.SetAllowedResources(resourceIDs)
+	allowed := n.AllowedResources
+	qs.bestEffortPersist(ctx, "SetNodeAllowedResources", func(ctx context.Context) error {
+		return qs.store.SetNodeAllowedResources(ctx, n.ID, allowed)
+	})
+}
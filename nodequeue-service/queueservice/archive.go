@@ -0,0 +1,226 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+const (
+	// defaultArchivePageSize is used when GET /nodes/archive has no limit.
+	defaultArchivePageSize = 100
+	// maxArchivePageSize caps a single archive page.
+	maxArchivePageSize = 1000
+)
+
+// completedAt returns the timestamp of the node's "completed" (or terminal "failed") log entry.
+func completedAt(n *node.Node) (time.Time, bool) {
+	for i := len(n.Log) - 1; i >= 0; i-- {
+		if a := n.Log[i].Action; a == "completed" || a == "failed" {
+			return n.Log[i].Timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// PurgeCompletedNodes archives nodes that completed more than olderThan ago and drops them from
+// memory. A node is only dropped once the store has accepted it, so a DB outage never loses
+// history; those nodes are retried on the next purge.
+//
+// Returns ErrArchiveUnavailable when the service has no store.
+func (qs *QueueService) PurgeCompletedNodes(ctx context.Context, olderThan time.Duration) (int, error) {
+	if qs.store == nil {
+		return 0, ErrArchiveUnavailable
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	qs.mu.RLock()
+	candidates := make([]string, 0)
+	for id, n := range qs.nodes {
+		if !n.Completed {
+			continue
+		}
+		if ts, ok := completedAt(n); ok && ts.Before(cutoff) {
+			candidates = append(candidates, id)
+		}
+	}
+	qs.mu.RUnlock()
+
+	// Archive without holding qs.mu; completed nodes are immutable so the snapshot stays valid.
+	archived := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		if err := qs.store.ArchiveCompletedNode(ctx, id, time.Now()); err != nil {
+			log.Printf("[DB] ArchiveCompletedNode(%s) failed: %v", id, err)
+			continue
+		}
+		archived = append(archived, id)
+	}
+
+	qs.mu.Lock()
+	for _, id := range archived {
+		delete(qs.nodes, id)
+	}
+	qs.mu.Unlock()
+
+	return len(archived), ctx.Err()
+}
+
+// RunArchiver calls PurgeCompletedNodes every interval until ctx is cancelled.
+func (qs *QueueService) RunArchiver(ctx context.Context, interval, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := qs.PurgeCompletedNodes(ctx, olderThan)
+			if err != nil {
+				log.Printf("[QueueService] archive purge stopped early: %v", err)
+			}
+			if n > 0 {
+				log.Printf("[QueueService] archived %d completed nodes", n)
+			}
+		}
+	}
+}
+
+// ListArchivedNodes queries archived nodes straight from the store; memory is not consulted.
+func (qs *QueueService) ListArchivedNodes(ctx context.Context, q db.ArchiveQuery) ([]db.ArchivedNode, error) {
+	if qs.store == nil {
+		return nil, ErrArchiveUnavailable
+	}
+	return qs.store.ListArchivedNodes(ctx, q)
+}
+
+// ArchivedNodeSummary is one row of GET /nodes/archive.
+type ArchivedNodeSummary struct {
+	ID             string     `json:"id"`
+	EntityName     string     `json:"entity_name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	LastResourceID *string    `json:"last_resource_id,omitempty"`
+	LogCount       int        `json:"log_count"`
+	ArchivedAt     time.Time  `json:"archived_at"`
+}
+
+// ArchiveResponse is the response payload for GET /nodes/archive.
+//
+// NextOffset is set when the page is full and more rows may follow.
+type ArchiveResponse struct {
+	Nodes      []ArchivedNodeSummary `json:"nodes"`
+	Limit      int                   `json:"limit"`
+	Offset     int                   `json:"offset"`
+	NextOffset *int                  `json:"next_offset,omitempty"`
+}
+
+// parseArchiveQuery reads since/until (RFC 3339) and limit/offset from the query string.
+func parseArchiveQuery(r *http.Request) (db.ArchiveQuery, map[string]string) {
+	q := db.ArchiveQuery{Limit: defaultArchivePageSize}
+	fields := make(map[string]string)
+	values := r.URL.Query()
+
+	for _, name := range []string{"since", "until"} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields[name] = "must be an RFC 3339 timestamp"
+			continue
+		}
+		if name == "since" {
+			q.Since = ts
+		} else {
+			q.Until = ts
+		}
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		fields["until"] = "must be after since"
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxArchivePageSize {
+			fields["limit"] = "must be between 1 and " + strconv.Itoa(maxArchivePageSize)
+		} else {
+			q.Limit = n
+		}
+	}
+	if raw := values.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			fields["offset"] = "must be a non-negative integer"
+		} else {
+			q.Offset = n
+		}
+	}
+	return q, fields
+}
+
+// ArchivedNodesHandler handles GET /nodes/archive[?since=&until=&limit=&offset=].
+//
+// since/until filter on completion time. Returns 503 if persistence is disabled.
+func (qs *QueueService) ArchivedNodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /nodes/archive - Request")
+
+	q, fields := parseArchiveQuery(r)
+	if len(fields) > 0 {
+		err := &utils.ValidationError{Fields: fields}
+		log.Printf("[API] GET /nodes/archive - ERROR: %v", err)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	rows, err := qs.ListArchivedNodes(r.Context(), q)
+	if err != nil {
+		log.Printf("[API] GET /nodes/archive - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	resp := ArchiveResponse{
+		Nodes:  make([]ArchivedNodeSummary, 0, len(rows)),
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	for _, row := range rows {
+		resp.Nodes = append(resp.Nodes, ArchivedNodeSummary{
+			ID:             row.NodeID,
+			EntityName:     row.EntityName,
+			CreatedAt:      row.CreatedAt,
+			CompletedAt:    row.CompletedAt,
+			LastResourceID: row.LastResourceID,
+			LogCount:       row.LogCount,
+			ArchivedAt:     row.ArchivedAt,
+		})
+	}
+	if len(rows) == q.Limit {
+		next := q.Offset + q.Limit
+		resp.NextOffset = &next
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/archive - SUCCESS: Returning %d archived nodes (took %v)", len(rows), duration)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,20 @@
+package queueservice
+
+import "nodequeue-service/node"
+
+// blockedReason runs checkAllocatable for a waiting node and returns the failing error code.
+// Callers must hold qs.mu (read or write).
+func (qs *QueueService) blockedReason(n *node.Node) string {
+	if n.Completed || n.ResourceID == "" {
+		return ""
+	}
+	resource, exists := qs.resources[n.ResourceID]
+	if !exists || !resource.IsWaiting(n.ID) {
+		return ""
+	}
+	if _, _, err := qs.checkAllocatable(n.ID); err != nil {
+		_, code := errorStatus(err)
+		return code
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// BulkResult is the outcome of a bulk action on one node.
+type BulkResult struct {
+	ID string `json:"id"`
+	OK bool   `json:"ok"`
+	// Error and Code describe why the action failed; Code is the same as the single-node
+	// endpoint would return.
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// BulkActionResponse is the response payload for POST /nodes/bulk.
+type BulkActionResponse struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// BulkAction is BulkActionContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) BulkAction(ids []string, action, targetResourceID string) ([]BulkResult, error) {
+	return qs.BulkActionContext(context.Background(), ids, action, targetResourceID)
+}
+
+// BulkActionContext applies one action to each node in ids, in order, under a single hold of
+// qs.mu, and returns a result per ID:
+//
+//   - complete behaves like CompleteNode, including StrictLifecycle.
+//   - cancel completes the node with a cancelled outcome from any state.
+//   - move behaves like MoveNode onto targetResourceID.
+//
+// Each node is applied (and persisted) on its own: a failure on one ID is reported in its result
+// and does not roll back the others. Completion callbacks and auto-promotion run once the lock is
+// released. Only an unknown action fails the whole call.
+func (qs *QueueService) BulkActionContext(ctx context.Context, ids []string, action, targetResourceID string) (_ []BulkResult, err error) {
+	ctx, span := startSpan(ctx, "QueueService.BulkAction")
+	defer func() { endSpan(span, err) }()
+
+	if !slices.Contains(node.ValidBulkActions, action) {
+		return nil, ErrInvalidBulkAction
+	}
+
+	results, events, freed := qs.applyBulkAction(ctx, ids, action, targetResourceID)
+
+	if qs.OnComplete != nil {
+		for _, ev := range events {
+			go qs.OnComplete(ev)
+		}
+	}
+	for _, rid := range freed {
+		qs.autoPromote(ctx, rid)
+	}
+	return results, nil
+}
+
+// applyBulkAction applies action to each node under qs.mu. It returns the per-ID results,
+// the completion events to publish and the resources whose service slots were freed.
+func (qs *QueueService) applyBulkAction(ctx context.Context, ids []string, action, targetResourceID string) ([]BulkResult, []CompletionEvent, []string) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	results := make([]BulkResult, 0, len(ids))
+	var events []CompletionEvent
+	var freed []string
+	for _, id := range ids {
+		var err error
+		n, exists := qs.nodes[id]
+		switch {
+		case !exists:
+			err = ErrNodeNotFound
+		case action == node.BulkActionMove:
+			err = qs.moveLocked(ctx, n, targetResourceID, "")
+		default:
+			var result *node.NodeResult
+			if action == node.BulkActionCancel {
+				// Cancelling skips StrictLifecycle: a waiting node may be withdrawn.
+				result = &node.NodeResult{Outcome: node.OutcomeCancelled}
+				if n.Completed {
+					err = ErrNodeCompleted
+				}
+			} else {
+				err = qs.checkCompletable(n)
+			}
+			if err == nil {
+				rid, ev := qs.completeLocked(ctx, n, result)
+				events = append(events, ev)
+				if rid != "" && !slices.Contains(freed, rid) {
+					freed = append(freed, rid)
+				}
+			}
+		}
+
+		res := BulkResult{ID: id, OK: err == nil}
+		if err != nil {
+			_, res.Code = errorStatus(err)
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, events, freed
+}
+
+// BulkActionHandler handles POST /nodes/bulk.
+//
+// Always returns 200 once the request is valid; per-node failures are reported in the results.
+func (qs *QueueService) BulkActionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+
+	var req node.BulkActionRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/bulk - ERROR: %v", err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	log.Printf("[API] POST /nodes/bulk - Request: action=%s, ids=%d", req.Action, len(req.IDs))
+
+	results, err := qs.BulkActionContext(r.Context(), req.IDs, req.Action, req.TargetResourceID)
+	if err != nil {
+		log.Printf("[API] POST /nodes/bulk - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	resp := BulkActionResponse{Results: results}
+	for _, res := range results {
+		if res.OK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/bulk - SUCCESS: %s applied to %d nodes, %d failed (took %v)",
+		req.Action, resp.Succeeded, resp.Failed, duration)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
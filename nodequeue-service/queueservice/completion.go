@@ -0,0 +1,67 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// EventNodeCompleted is the CompletionEvent.Type sent when a node completes.
+const EventNodeCompleted = "node_completed"
+
+// CompletionEventVersion is the CompletionEvent payload version. Bump it whenever a field is
+// removed or changes meaning; adding fields is backward compatible and keeps the version.
+const CompletionEventVersion = 1
+
+// CompletionEvent is the payload posted when a node completes. Metrics is computed from the
+// node's log at completion time, so receivers get the waiting segments without calling back.
+type CompletionEvent struct {
+	Version    int         `json:"version"`
+	Type       string      `json:"type"`
+	NodeID     string      `json:"node_id"`
+	ResourceID string      `json:"resource_id,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Metrics    NodeMetrics `json:"metrics"`
+	// Result is the outcome supplied when completing the node, if any.
+	Result *node.NodeResult `json:"result,omitempty"`
+}
+
+// completionEvent builds the CompletionEvent for n, which must already have its "completed" log
+// entry. Callers must hold qs.mu.
+func completionEvent(n *node.Node, resourceID string) CompletionEvent {
+	entry := n.Log[len(n.Log)-1]
+	entityName := ""
+	if n.Entity != nil {
+		entityName = n.Entity.Name
+	}
+	snap := nodeSnapshot{
+		ID:        n.ID,
+		Entity:    entityName,
+		CreatedAt: n.CreatedAt,
+		Completed: true,
+	}
+	return CompletionEvent{
+		Version:    CompletionEventVersion,
+		Type:       EventNodeCompleted,
+		NodeID:     n.ID,
+		ResourceID: resourceID,
+		Timestamp:  entry.Timestamp,
+		Metrics:    computeNodeMetrics(entry.Timestamp, snap, toNodeEventsFromInMemory(n.Log)),
+		Result:     n.Result.Clone(),
+	}
+}
+
+// CompletionNotifier posts each completion to hook (nothing is sent when hook is nil).
+// Delivery failures are logged and not retried.
+func CompletionNotifier(hook *Webhook) func(CompletionEvent) {
+	return func(ev CompletionEvent) {
+		if hook == nil {
+			return
+		}
+		if err := hook.Post(context.Background(), ev); err != nil {
+			log.Printf("[Completion] webhook delivery for node %s failed: %v", ev.NodeID, err)
+		}
+	}
+}
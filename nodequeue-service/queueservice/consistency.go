@@ -0,0 +1,173 @@
+package queueservice
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// Kinds of Violation reported by CheckConsistency.
+const (
+	// ViolationMissingResource: a node is assigned to a resource that does not exist.
+	ViolationMissingResource = "missing_resource"
+	// ViolationNotQueued: a node is assigned to a resource but is in neither of its queues.
+	ViolationNotQueued = "not_queued"
+	// ViolationDuplicate: a node appears more than once in one resource's queues.
+	ViolationDuplicate = "duplicate_in_resource"
+	// ViolationMultipleResources: a node appears in the queues of more than one resource.
+	ViolationMultipleResources = "multiple_resources"
+	// ViolationWrongResource: a node is queued on a resource other than the one it is assigned to.
+	ViolationWrongResource = "wrong_resource"
+	// ViolationUnknownNode: a queued node is not known to the service.
+	ViolationUnknownNode = "unknown_node"
+	// ViolationOverCapacity: a resource's service queue weighs more than its capacity.
+	ViolationOverCapacity = "over_capacity"
+)
+
+// Violation is one broken queue invariant found by CheckConsistency.
+type Violation struct {
+	Kind       string `json:"kind"`
+	NodeID     string `json:"node_id,omitempty"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// ConsistencyResponse is the response payload for GET /admin/consistency.
+type ConsistencyResponse struct {
+	Consistent bool        `json:"consistent"`
+	Violations []Violation `json:"violations"`
+}
+
+// CheckConsistency checks the queue invariants under the read lock and returns every violation
+// found, ordered by resource then node. It never repairs anything:
+//   - every node with a ResourceID is in exactly one of that resource's queues;
+//   - no node is queued on more than one resource, or on a resource it is not assigned to;
+//   - every queued node is known to the service;
+//   - no service queue weighs more than its resource's capacity.
+func (qs *QueueService) CheckConsistency() []Violation {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	violations := make([]Violation, 0)
+	// queuedOn maps node ID -> the resources whose queues hold it.
+	queuedOn := make(map[string][]string)
+	for _, r := range qs.resources {
+		service, waiting := r.QueueSnapshot()
+		seen := make(map[string]bool, len(service)+len(waiting))
+		used := 0
+		for _, n := range service {
+			used += n.CapacityWeight()
+		}
+		if used > r.Capacity {
+			violations = append(violations, Violation{
+				Kind:       ViolationOverCapacity,
+				ResourceID: r.ID,
+				Detail:     fmt.Sprintf("service queue weighs %d, capacity is %d", used, r.Capacity),
+			})
+		}
+		for _, n := range append(service, waiting...) {
+			if seen[n.ID] {
+				violations = append(violations, Violation{
+					Kind:       ViolationDuplicate,
+					NodeID:     n.ID,
+					ResourceID: r.ID,
+					Detail:     "node appears more than once in the resource's queues",
+				})
+				continue
+			}
+			seen[n.ID] = true
+			queuedOn[n.ID] = append(queuedOn[n.ID], r.ID)
+
+			tracked, ok := qs.nodes[n.ID]
+			switch {
+			case !ok:
+				violations = append(violations, Violation{
+					Kind:       ViolationUnknownNode,
+					NodeID:     n.ID,
+					ResourceID: r.ID,
+					Detail:     "queued node is not known to the service",
+				})
+			case tracked.ResourceID != r.ID:
+				violations = append(violations, Violation{
+					Kind:       ViolationWrongResource,
+					NodeID:     n.ID,
+					ResourceID: r.ID,
+					Detail:     fmt.Sprintf("node is assigned to %q", tracked.ResourceID),
+				})
+			}
+		}
+	}
+
+	for id, rids := range queuedOn {
+		if len(rids) > 1 {
+			sort.Strings(rids)
+			violations = append(violations, Violation{
+				Kind:   ViolationMultipleResources,
+				NodeID: id,
+				Detail: fmt.Sprintf("node is queued on %v", rids),
+			})
+		}
+	}
+
+	for id, n := range qs.nodes {
+		if n.ResourceID == "" {
+			continue
+		}
+		if _, ok := qs.resources[n.ResourceID]; !ok {
+			violations = append(violations, Violation{
+				Kind:       ViolationMissingResource,
+				NodeID:     id,
+				ResourceID: n.ResourceID,
+				Detail:     "node is assigned to a resource that does not exist",
+			})
+			continue
+		}
+		if !slices.Contains(queuedOn[id], n.ResourceID) {
+			violations = append(violations, Violation{
+				Kind:       ViolationNotQueued,
+				NodeID:     id,
+				ResourceID: n.ResourceID,
+				Detail:     "node is in neither of its resource's queues",
+			})
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.ResourceID != b.ResourceID {
+			return a.ResourceID < b.ResourceID
+		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Kind < b.Kind
+	})
+	return violations
+}
+
+// ConsistencyHandler handles GET /admin/consistency.
+//
+// It always returns 200; consistent is false when any violation was found.
+func (qs *QueueService) ConsistencyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /admin/consistency - Request")
+
+	violations := qs.CheckConsistency()
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /admin/consistency - SUCCESS: %d violations (took %v)", len(violations), duration)
+	utils.RespondWithJSON(w, http.StatusOK, ConsistencyResponse{
+		Consistent: len(violations) == 0,
+		Violations: violations,
+	})
+}
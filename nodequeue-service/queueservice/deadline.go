@@ -0,0 +1,155 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// setDeadlineLocked sets n's deadline (nil clears it) and persists it (best-effort), so it survives
+// a restore. Setting no deadline on a node that has none writes nothing. Callers must hold qs.mu
+// for writing.
+func (qs *QueueService) setDeadlineLocked(ctx context.Context, n *node.Node, deadline *time.Time) {
+	if deadline == nil && n.DeadlineTS == nil {
+		return
+	}
+	var ts *time.Time
+	if deadline != nil {
+		utc := deadline.UTC()
+		ts = &utc
+	}
+	n.DeadlineTS = ts
+	nodeID := n.ID
+	qs.bestEffortPersist(ctx, "UpdateNodeDeadline", func(ctx context.Context) error {
+		return qs.store.UpdateNodeDeadline(ctx, nodeID, ts)
+	})
+}
+
+// SetNodeDeadline is SetNodeDeadlineContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) SetNodeDeadline(nodeID string, deadline *time.Time) error {
+	return qs.SetNodeDeadlineContext(context.Background(), nodeID, deadline)
+}
+
+// SetNodeDeadlineContext sets the time after which an active node is cancelled by the
+// DeadlineMonitor; nil clears it. It does not check that deadline is in the future (see
+// node.CreateNodeRequest.Validate), so a past deadline takes effect on the monitor's next check.
+// Returns ErrNodeNotFound or ErrNodeCompleted.
+func (qs *QueueService) SetNodeDeadlineContext(ctx context.Context, nodeID string, deadline *time.Time) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+	if n.Completed {
+		return ErrNodeCompleted
+	}
+	qs.setDeadlineLocked(ctx, n, deadline)
+	return nil
+}
+
+// DeadlineMonitor cancels active nodes whose DeadlineTS has passed, whether they are unassigned,
+// waiting or in service: late work is worthless, so there is no requeue option.
+//
+// Each cancelled node gets a "deadline_exceeded" log entry, has DeadlineExceeded set and is then
+// completed with a "cancelled" outcome. A freed service slot is offered to AutoPromote. Now is
+// the monitor's clock and may be replaced in tests.
+type DeadlineMonitor struct {
+	qs  *QueueService
+	Now func() time.Time
+
+	// mu serializes Check so overlapping runs do not cancel the same node twice.
+	mu sync.Mutex
+}
+
+// NewDeadlineMonitor returns a deadline monitor for qs.
+func NewDeadlineMonitor(qs *QueueService) *DeadlineMonitor {
+	return &DeadlineMonitor{qs: qs, Now: time.Now}
+}
+
+// deadlinePassed reports whether n is active and its deadline is at or before now.
+func deadlinePassed(n *node.Node, now time.Time) bool {
+	return !n.Completed && n.DeadlineTS != nil && !now.Before(*n.DeadlineTS)
+}
+
+// Check cancels every active node whose deadline is at or before Now and returns their IDs.
+func (m *DeadlineMonitor) Check(ctx context.Context) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+
+	m.qs.mu.RLock()
+	late := make([]string, 0)
+	for id, n := range m.qs.nodes {
+		if deadlinePassed(n, now) {
+			late = append(late, id)
+		}
+	}
+	m.qs.mu.RUnlock()
+	sort.Strings(late)
+
+	cancelled := make([]string, 0, len(late))
+	for _, id := range late {
+		freedResourceID, ev, ok := m.cancel(ctx, id, now)
+		if !ok {
+			continue
+		}
+		cancelled = append(cancelled, id)
+		if m.qs.OnComplete != nil {
+			go m.qs.OnComplete(ev)
+		}
+		if freedResourceID != "" {
+			m.qs.autoPromote(ctx, freedResourceID)
+		}
+	}
+	return cancelled
+}
+
+// cancel completes one late node under qs.mu. It re-checks the deadline, since the node may have
+// completed or had its deadline changed since Check sampled it.
+func (m *DeadlineMonitor) cancel(ctx context.Context, nodeID string, now time.Time) (string, CompletionEvent, bool) {
+	qs := m.qs
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists || !deadlinePassed(n, now) {
+		return "", CompletionEvent{}, false
+	}
+
+	var rid *string
+	if n.ResourceID != "" {
+		resourceID := n.ResourceID
+		rid = &resourceID
+	}
+	ts := qs.addNodeLog(n, "deadline_exceeded", n.ResourceID)
+	qs.bestEffortPersist(ctx, "InsertNodeLog(deadline_exceeded)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "deadline_exceeded", rid, ts)
+	})
+
+	n.DeadlineExceeded = true
+	freedResourceID, ev := qs.completeLocked(ctx, n, &node.NodeResult{Outcome: node.OutcomeCancelled})
+	return freedResourceID, ev, true
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (m *DeadlineMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ids := m.Check(ctx); len(ids) > 0 {
+				log.Printf("[QueueService] cancelled %d nodes past their deadline: %v", len(ids), ids)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package queueservice
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// EligibleResource is one resource a node could be moved to, as listed by EligibleResources.
+type EligibleResource struct {
+	ResourceID string `json:"resource_id"`
+	// AvailableCapacity is how many free units the node could use there, excluding capacity
+	// reserved for other lanes.
+	AvailableCapacity int `json:"available_capacity"`
+	// Allocatable is true when the node could go straight into service there (see
+	// TransferAndAllocate); otherwise Code and Reason say why not.
+	Allocatable bool   `json:"allocatable"`
+	Code        string `json:"code,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// EligibleResourcesResponse is the response payload for GET /nodes/{id}/eligible-resources.
+type EligibleResourcesResponse struct {
+	NodeID    string             `json:"node_id"`
+	Resources []EligibleResource `json:"resources"`
+}
+
+// EligibleResources lists the resources a node could be moved to: every resource other than its
+// current one that its AllowedResources permit and whose total capacity fits the node's weight.
+// Each entry reports whether the node could also be allocated there right away, using the same
+// checks as TransferAndAllocate apart from AdmissionFunc. Entries are sorted by AvailableCapacity,
+// largest first, then by resource ID.
+//
+// Returns ErrNodeNotFound, ErrNodeCompleted, or ErrMoveLimit if the node may not move at all.
+func (qs *QueueService) EligibleResources(nodeID string) ([]EligibleResource, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return nil, ErrNodeNotFound
+	}
+	if n.Completed {
+		return nil, fmt.Errorf("cannot move node: %w", ErrNodeCompleted)
+	}
+	if err := qs.checkMoveLimit(n); err != nil {
+		return nil, err
+	}
+
+	eligible := make([]EligibleResource, 0, len(qs.resources))
+	for id, r := range qs.resources {
+		if id == n.ResourceID || !n.AllowsResource(id) || r.Capacity < n.CapacityWeight() {
+			continue
+		}
+		lane := qs.transferLaneLocked(n, r)
+		entry := EligibleResource{
+			ResourceID:        id,
+			AvailableCapacity: max(r.AvailableCapacityForLane(lane), 0),
+		}
+		if err := checkTransferTarget(n, r, lane); err != nil {
+			_, entry.Code = errorStatus(err)
+			entry.Reason = err.Error()
+		} else {
+			entry.Allocatable = true
+		}
+		eligible = append(eligible, entry)
+	}
+	sort.Slice(eligible, func(i, j int) bool {
+		if eligible[i].AvailableCapacity != eligible[j].AvailableCapacity {
+			return eligible[i].AvailableCapacity > eligible[j].AvailableCapacity
+		}
+		return eligible[i].ResourceID < eligible[j].ResourceID
+	})
+	return eligible, nil
+}
+
+// EligibleResourcesHandler handles GET /nodes/{id}/eligible-resources.
+func (qs *QueueService) EligibleResourcesHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] GET /nodes/%s/eligible-resources - Request", nodeID)
+
+	eligible, err := qs.EligibleResources(nodeID)
+	if err != nil {
+		log.Printf("[API] GET /nodes/%s/eligible-resources - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/%s/eligible-resources - SUCCESS: %d resources (took %v)", nodeID, len(eligible), duration)
+	utils.RespondWithJSON(w, http.StatusOK, EligibleResourcesResponse{NodeID: nodeID, Resources: eligible})
+}
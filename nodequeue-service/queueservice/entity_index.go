@@ -0,0 +1,96 @@
+package queueservice
+
+import (
+	"fmt"
+
+	"nodequeue-service/node"
+)
+
+// EntityActiveError is returned by node creation when UniqueActiveEntity is set and the entity
+// already has an active node. It wraps ErrEntityActive and carries that node's ID so clients can
+// reuse it.
+type EntityActiveError struct {
+	EntityName string
+	NodeID     string
+}
+
+func (e *EntityActiveError) Error() string {
+	return fmt.Sprintf("%v: entity %q has active node %s", ErrEntityActive, e.EntityName, e.NodeID)
+}
+
+func (e *EntityActiveError) Unwrap() error { return ErrEntityActive }
+
+// checkUniqueEntityLocked returns an *EntityActiveError if UniqueActiveEntity is set and
+// entityName already has an active node. Callers must hold qs.mu.
+func (qs *QueueService) checkUniqueEntityLocked(entityName string) error {
+	if !qs.UniqueActiveEntity {
+		return nil
+	}
+	if id, ok := qs.activeNodeForEntityLocked(entityName); ok {
+		return &EntityActiveError{EntityName: entityName, NodeID: id}
+	}
+	return nil
+}
+
+// activeNodeForEntityLocked returns an active node of entityName, the oldest if there are
+// several. Callers must hold qs.mu.
+func (qs *QueueService) activeNodeForEntityLocked(entityName string) (string, bool) {
+	var found *node.Node
+	for id := range qs.activeByEntity[entityName] {
+		n := qs.nodes[id]
+		if found == nil || n.CreatedAt.Before(found.CreatedAt) {
+			found = n
+		}
+	}
+	if found == nil {
+		return "", false
+	}
+	return found.ID, true
+}
+
+// indexActiveLocked records n as an active node, and as one of its entity's. Callers must hold
+// qs.mu for writing.
+func (qs *QueueService) indexActiveLocked(n *node.Node) {
+	if qs.activeNodes == nil {
+		qs.activeNodes = make(map[string]bool)
+	}
+	qs.activeNodes[n.ID] = true
+	if n.Entity == nil {
+		return
+	}
+	if qs.activeByEntity == nil {
+		qs.activeByEntity = make(map[string]map[string]bool)
+	}
+	ids := qs.activeByEntity[n.Entity.Name]
+	if ids == nil {
+		ids = make(map[string]bool)
+		qs.activeByEntity[n.Entity.Name] = ids
+	}
+	ids[n.ID] = true
+}
+
+// unindexActiveLocked drops n from the active nodes, e.g. once it completes. Callers must hold
+// qs.mu for writing.
+func (qs *QueueService) unindexActiveLocked(n *node.Node) {
+	delete(qs.activeNodes, n.ID)
+	if n.Entity == nil {
+		return
+	}
+	ids := qs.activeByEntity[n.Entity.Name]
+	delete(ids, n.ID)
+	if len(ids) == 0 {
+		delete(qs.activeByEntity, n.Entity.Name)
+	}
+}
+
+// rebuildEntityIndexLocked recomputes the active node indexes from qs.nodes after they have been
+// replaced wholesale. Callers must hold qs.mu for writing.
+func (qs *QueueService) rebuildEntityIndexLocked() {
+	qs.activeNodes = nil
+	qs.activeByEntity = nil
+	for _, n := range qs.nodes {
+		if !n.Completed {
+			qs.indexActiveLocked(n)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// EntityMoveResponse is the response payload for POST /entities/{name}/move.
+type EntityMoveResponse struct {
+	EntityName string `json:"entity_name"`
+	To         string `json:"to"`
+	Moved      int    `json:"moved"`
+}
+
+// MoveEntityNodes is MoveEntityNodesContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) MoveEntityNodes(entityName, targetResourceID string) (int, error) {
+	return qs.MoveEntityNodesContext(context.Background(), entityName, targetResourceID)
+}
+
+// MoveEntityNodesContext moves every active node of entityName onto targetResourceID's waiting
+// queue under one hold of qs.mu, as MoveNode would, and returns how many moved. Completed nodes
+// and nodes already on the target are skipped; the rest are moved in creation order.
+//
+// It is all or nothing: if any node may not move (AllowedResources, MaxMoves) nothing is moved
+// and that node's error is returned. An entity without active nodes moves 0.
+func (qs *QueueService) MoveEntityNodesContext(ctx context.Context, entityName, targetResourceID string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "QueueService.MoveEntityNodes", attrTargetResourceID.String(targetResourceID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	targetResourceID = qs.LookupResourceID(targetResourceID)
+	if _, exists := qs.resources[targetResourceID]; !exists {
+		return 0, fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+
+	nodes := make([]*node.Node, 0, len(qs.activeByEntity[entityName]))
+	for id := range qs.activeByEntity[entityName] {
+		n := qs.nodes[id]
+		if n.Completed || n.ResourceID == targetResourceID {
+			continue
+		}
+		if err := checkAllowedResource(n, targetResourceID); err != nil {
+			return 0, fmt.Errorf("node %s: %w", n.ID, err)
+		}
+		if err := qs.checkMoveLimit(n); err != nil {
+			return 0, fmt.Errorf("node %s: %w", n.ID, err)
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].CreatedAt.Equal(nodes[j].CreatedAt) {
+			return nodes[i].CreatedAt.Before(nodes[j].CreatedAt)
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	for i, n := range nodes {
+		if err := qs.moveLocked(ctx, n, targetResourceID, ""); err != nil {
+			// Unreachable while qs.mu is held: every node was checked above.
+			return i, err
+		}
+	}
+	return len(nodes), nil
+}
+
+// MoveEntityNodesHandler handles POST /entities/{name}/move?to={target}.
+func (qs *QueueService) MoveEntityNodesHandler(w http.ResponseWriter, r *http.Request, entityName string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /entities/%s/move - Request", entityName)
+
+	toID := r.URL.Query().Get("to")
+	if toID == "" {
+		log.Printf("[API] POST /entities/%s/move - ERROR: to is required", entityName)
+		utils.RespondWithErrorCode(w, http.StatusBadRequest, CodeInvalidRequest, "to is required")
+		return
+	}
+
+	moved, err := qs.MoveEntityNodesContext(r.Context(), entityName, toID)
+	if err != nil {
+		log.Printf("[API] POST /entities/%s/move - ERROR: %v", entityName, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /entities/%s/move - SUCCESS: Moved %d nodes to %s (took %v)", entityName, moved, toID, duration)
+	utils.RespondWithJSON(w, http.StatusOK, EntityMoveResponse{EntityName: entityName, To: toID, Moved: moved})
+}
@@ -0,0 +1,152 @@
+package queueservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// Sentinel errors returned by QueueService operations.
+//
+// Callers should match on these with errors.Is rather than comparing messages; some operations
+// wrap them with extra context (e.g. "target resource not found").
+var (
+	ErrNodeNotFound           = errors.New("node not found")
+	ErrResourceNotFound       = errors.New("resource not found")
+	ErrNodeCompleted          = errors.New("node is already completed")
+	ErrNodeNotAssigned        = errors.New("node is not assigned to a resource")
+	ErrNodeInService          = errors.New("node is already in service queue")
+	ErrNodeNotWaiting         = resource.ErrNodeNotWaiting
+	ErrNodeNotInService       = errors.New("node must be in service to complete: allocate it first (waiting -> service -> complete)")
+	ErrCapacityFull           = errors.New("resource is at full capacity")
+	ErrReservationNotFound    = errors.New("reservation not found or expired")
+	ErrInvalidTTL             = errors.New("ttl must be positive")
+	ErrSameResource           = errors.New("source and target resource are the same")
+	ErrResourceExists         = errors.New("resource already exists")
+	ErrNodeExists             = errors.New("node already exists")
+	ErrResourcePaused         = errors.New("resource is paused")
+	ErrArchiveUnavailable     = errors.New("node archive requires a persistent store")
+	ErrStoreUnavailable       = errors.New("this operation requires a persistent store")
+	ErrInvalidSort            = errors.New("sort must be one of: age, position")
+	ErrInvalidResourceSort    = errors.New("sort must be one of: id, utilization, waiting")
+	ErrInvalidSortOrder       = errors.New("order must be one of: asc, desc")
+	ErrEntityLimit            = errors.New("entity has reached its concurrent service limit on this resource")
+	ErrNodeBackingOff         = errors.New("node is backing off after a failed attempt")
+	ErrMoveLimit              = errors.New("node has reached its move limit")
+	ErrNotQueueHead           = errors.New("not at head of queue")
+	ErrInvalidBulkAction      = errors.New("action must be one of: complete, cancel, move")
+	ErrCapacityUnavailable    = errors.New("no service capacity available")
+	ErrEntityActive           = errors.New("entity already has an active node")
+	ErrResourceNotAllowed     = errors.New("resource is not in the node's allowed resources")
+	ErrResourceMismatch       = errors.New("node is not on the expected resource")
+	ErrPersistFailed          = errors.New("failed to persist change")
+	ErrAdmissionDenied        = errors.New("allocation rejected by admission check")
+	ErrAllocationRateExceeded = errors.New("allocation rate exceeded")
+	ErrTooManyLabels          = fmt.Errorf("a resource may have at most %d labels", resource.MaxLabels)
+	ErrNoMatchingResources    = errors.New("no resource matches the requested labels")
+	ErrUnknownLane            = errors.New("lane is not defined on the resource")
+)
+
+// Machine-readable error codes included in ErrorResponse.Code.
+const (
+	CodeNodeNotFound           = "node_not_found"
+	CodeResourceNotFound       = "resource_not_found"
+	CodeNodeCompleted          = "node_completed"
+	CodeNodeNotAssigned        = "node_not_assigned"
+	CodeNodeInService          = "node_in_service"
+	CodeNodeNotWaiting         = "node_not_waiting"
+	CodeNodeNotInService       = "node_not_in_service"
+	CodeCapacityFull           = "capacity_full"
+	CodeReservationNotFound    = "reservation_not_found"
+	CodeResourceExists         = "resource_exists"
+	CodeNodeExists             = "node_exists"
+	CodeResourcePaused         = "resource_paused"
+	CodeArchiveUnavailable     = "archive_unavailable"
+	CodeStoreUnavailable       = "store_unavailable"
+	CodeEntityLimit            = "entity_limit_reached"
+	CodeNodeBackingOff         = "node_backing_off"
+	CodeMoveLimit              = "move_limit_reached"
+	CodeNotQueueHead           = "not_queue_head"
+	CodeCapacityUnavailable    = "capacity_unavailable"
+	CodeEntityActive           = "entity_active"
+	CodeResourceNotAllowed     = "resource_not_allowed"
+	CodeResourceMismatch       = "resource_mismatch"
+	CodePersistFailed          = "persist_failed"
+	CodeAdmissionDenied        = "admission_denied"
+	CodeAllocationRateExceeded = "allocation_rate_exceeded"
+	CodeNoMatchingResources    = "no_matching_resources"
+	CodeInvalidRequest         = "invalid_request"
+	CodeInternal               = "internal_error"
+)
+
+// errorMapping pairs a sentinel error with its HTTP status and error code.
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+var errorMappings = []errorMapping{
+	{ErrNodeNotFound, http.StatusNotFound, CodeNodeNotFound},
+	{ErrResourceNotFound, http.StatusNotFound, CodeResourceNotFound},
+	{ErrNodeCompleted, http.StatusBadRequest, CodeNodeCompleted},
+	{ErrNodeNotAssigned, http.StatusBadRequest, CodeNodeNotAssigned},
+	{ErrNodeInService, http.StatusBadRequest, CodeNodeInService},
+	{ErrNodeNotWaiting, http.StatusBadRequest, CodeNodeNotWaiting},
+	{ErrNodeNotInService, http.StatusBadRequest, CodeNodeNotInService},
+	{ErrCapacityFull, http.StatusBadRequest, CodeCapacityFull},
+	{ErrResourcePaused, http.StatusBadRequest, CodeResourcePaused},
+	{ErrReservationNotFound, http.StatusNotFound, CodeReservationNotFound},
+	{ErrInvalidTTL, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrSameResource, http.StatusBadRequest, CodeInvalidRequest},
+	{resource.ErrInvalidResourceID, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrResourceExists, http.StatusConflict, CodeResourceExists},
+	{ErrNodeExists, http.StatusConflict, CodeNodeExists},
+	{ErrEntityActive, http.StatusConflict, CodeEntityActive},
+	{ErrResourceMismatch, http.StatusConflict, CodeResourceMismatch},
+	{ErrInvalidSort, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrInvalidResourceSort, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrInvalidSortOrder, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrInvalidBulkAction, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrEntityLimit, http.StatusBadRequest, CodeEntityLimit},
+	{ErrNodeBackingOff, http.StatusBadRequest, CodeNodeBackingOff},
+	{ErrMoveLimit, http.StatusBadRequest, CodeMoveLimit},
+	{ErrNotQueueHead, http.StatusBadRequest, CodeNotQueueHead},
+	{ErrResourceNotAllowed, http.StatusBadRequest, CodeResourceNotAllowed},
+	{ErrArchiveUnavailable, http.StatusServiceUnavailable, CodeArchiveUnavailable},
+	{ErrStoreUnavailable, http.StatusServiceUnavailable, CodeStoreUnavailable},
+	{ErrCapacityUnavailable, http.StatusServiceUnavailable, CodeCapacityUnavailable},
+	{ErrPersistFailed, http.StatusInternalServerError, CodePersistFailed},
+	{ErrAdmissionDenied, http.StatusForbidden, CodeAdmissionDenied},
+	{ErrAllocationRateExceeded, http.StatusTooManyRequests, CodeAllocationRateExceeded},
+	{ErrTooManyLabels, http.StatusBadRequest, CodeInvalidRequest},
+	{resource.ErrInvalidLabelSelector, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrNoMatchingResources, http.StatusBadRequest, CodeNoMatchingResources},
+	{ErrUnknownLane, http.StatusBadRequest, CodeInvalidRequest},
+}
+
+// errorStatus maps a service error to an HTTP status code and machine-readable error code.
+// Unknown errors map to 500/internal_error.
+func errorStatus(err error) (int, string) {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// respondWithServiceError writes err using the status/code derived from errorStatus. An
+// *EntityActiveError also reports the existing node under node_id.
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	status, code := errorStatus(err)
+	var active *EntityActiveError
+	if errors.As(err, &active) {
+		utils.RespondWithJSON(w, status, utils.ErrorResponse{Error: err.Error(), Code: code, NodeID: active.NodeID})
+		return
+	}
+	utils.RespondWithErrorCode(w, status, code, err.Error())
+}
@@ -0,0 +1,97 @@
+package queueservice
+
+import (
+	"sync"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// eventBufferSize is the per-subscriber channel buffer. Slow subscribers drop events rather than
+// blocking queue operations.
+const eventBufferSize = 64
+
+// NodeEvent is a lifecycle event published whenever a node log entry is recorded.
+type NodeEvent struct {
+	NodeID         string    `json:"node_id"`
+	Action         string    `json:"action"`
+	ResourceID     string    `json:"resource_id,omitempty"`
+	FromResourceID string    `json:"from_resource_id,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// eventBus fans out NodeEvents to subscribers.
+//
+// It has its own lock so publishing from inside qs.mu never contends with subscribe/unsubscribe.
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[chan NodeEvent]struct{}
+}
+
+func (b *eventBus) subscribe() chan NodeEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[chan NodeEvent]struct{})
+	}
+	ch := make(chan NodeEvent, eventBufferSize)
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+func (b *eventBus) unsubscribe(ch chan NodeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *eventBus) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *eventBus) publish(ev NodeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			// Subscriber is not keeping up; drop rather than block.
+		}
+	}
+}
+
+// Subscribe registers for node lifecycle events. The returned cancel func must be called to
+// release the subscription; it closes the channel.
+func (qs *QueueService) Subscribe() (<-chan NodeEvent, func()) {
+	ch := qs.events.subscribe()
+	return ch, func() { qs.events.unsubscribe(ch) }
+}
+
+// addNodeLog appends a log entry to the node, bumps its Version and publishes it as a NodeEvent.
+// It returns the entry's Timestamp, which callers pass to the store so the persisted row matches
+// the in-memory log. Callers must hold qs.mu.
+func (qs *QueueService) addNodeLog(n *node.Node, action, resourceID string) time.Time {
+	return qs.addNodeMoveLog(n, action, resourceID, "")
+}
+
+// addNodeMoveLog is addNodeLog for an entry that also records the resource the node came from
+// (see node.NodeLog.FromResourceID). Callers must hold qs.mu.
+func (qs *QueueService) addNodeMoveLog(n *node.Node, action, resourceID, fromResourceID string) time.Time {
+	n.AddMoveLog(action, resourceID, fromResourceID)
+	n.Version++
+	entry := n.Log[len(n.Log)-1]
+	qs.events.publish(NodeEvent{
+		NodeID:         n.ID,
+		Action:         entry.Action,
+		ResourceID:     entry.ResourceID,
+		FromResourceID: entry.FromResourceID,
+		Timestamp:      entry.Timestamp,
+	})
+	return entry.Timestamp
+}
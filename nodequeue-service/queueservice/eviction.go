@@ -0,0 +1,77 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// evictOverCap enforces MaxNodesInMemory after a node is created: while more nodes are held than
+// the cap allows, completed nodes are dropped from memory, oldest completion first. Active nodes
+// are never evicted, so the cap can be exceeded while they alone fill it.
+//
+// With a store each node is archived before it is dropped (and stays in memory if that fails),
+// so it remains available from GET /nodes/archive. Without a store evicted nodes are gone.
+// Must be called without holding qs.mu.
+func (qs *QueueService) evictOverCap(ctx context.Context) {
+	if qs.MaxNodesInMemory <= 0 {
+		return
+	}
+
+	type candidate struct {
+		id string
+		at time.Time
+	}
+	qs.mu.RLock()
+	excess := len(qs.nodes) - qs.MaxNodesInMemory
+	candidates := make([]candidate, 0)
+	if excess > 0 {
+		for id, n := range qs.nodes {
+			if !n.Completed {
+				continue
+			}
+			at, _ := completedAt(n)
+			candidates = append(candidates, candidate{id: id, at: at})
+		}
+	}
+	qs.mu.RUnlock()
+	if excess <= 0 || len(candidates) == 0 {
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].at.Equal(candidates[j].at) {
+			return candidates[i].at.Before(candidates[j].at)
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	if len(candidates) > excess {
+		candidates = candidates[:excess]
+	}
+
+	// Archive without holding qs.mu; completed nodes are immutable so the snapshot stays valid.
+	evicted := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if qs.store != nil {
+			id := c.id
+			if err := traceStore(ctx, "ArchiveCompletedNode", func(ctx context.Context) error {
+				return qs.store.ArchiveCompletedNode(ctx, id, time.Now())
+			}); err != nil {
+				log.Printf("[DB] ArchiveCompletedNode(%s) failed, keeping it in memory: %v", id, err)
+				continue
+			}
+		}
+		evicted = append(evicted, c.id)
+	}
+
+	qs.mu.Lock()
+	for _, id := range evicted {
+		delete(qs.nodes, id)
+	}
+	qs.mu.Unlock()
+
+	if len(evicted) > 0 {
+		log.Printf("[QueueService] evicted %d completed nodes (MaxNodesInMemory=%d)", len(evicted), qs.MaxNodesInMemory)
+	}
+}
@@ -0,0 +1,79 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"sort"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// ResourceInternals is one resource's queue sizes in InternalsResponse.
+type ResourceInternals struct {
+	ID           string `json:"id"`
+	ServiceCount int    `json:"service_count"`
+	WaitingCount int    `json:"waiting_count"`
+	Reservations int    `json:"reservations"`
+}
+
+// InternalsResponse is the response payload for GET /debug/internals.
+type InternalsResponse struct {
+	Goroutines int `json:"goroutines"`
+	// EventSubscribers counts open WebSocket connections and in-flight long-polls.
+	EventSubscribers int `json:"event_subscribers"`
+	// StoreEnabled reports whether persistence is on. Store writes are synchronous, so there is
+	// no persister queue to report.
+	StoreEnabled  bool                `json:"store_enabled"`
+	Nodes         int                 `json:"nodes"`
+	Resources     []ResourceInternals `json:"resources"`
+	LastRestoreAt *time.Time          `json:"last_restore_at,omitempty"`
+}
+
+// Internals returns a point-in-time view of the service's internals for debugging leaks. qs.mu is
+// only held for reading while the queues are counted; the subscriber count uses the event bus's
+// own lock.
+func (qs *QueueService) Internals() InternalsResponse {
+	resp := InternalsResponse{
+		Goroutines:       runtime.NumGoroutine(),
+		EventSubscribers: qs.events.count(),
+		StoreEnabled:     qs.store != nil,
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	resp.Nodes = len(qs.nodes)
+	resp.Resources = make([]ResourceInternals, 0, len(qs.resources))
+	for _, r := range qs.resources {
+		service, waiting := r.QueueSnapshot()
+		resp.Resources = append(resp.Resources, ResourceInternals{
+			ID:           r.ID,
+			ServiceCount: len(service),
+			WaitingCount: len(waiting),
+			Reservations: r.ActiveReservations(),
+		})
+	}
+	sort.Slice(resp.Resources, func(i, j int) bool { return resp.Resources[i].ID < resp.Resources[j].ID })
+	if !qs.lastRestore.IsZero() {
+		at := qs.lastRestore
+		resp.LastRestoreAt = &at
+	}
+	return resp
+}
+
+// DebugInternalsHandler handles GET /debug/internals.
+//
+// The route is wrapped in utils.AdminGuard, so it is refused unless ENABLE_ADMIN is set.
+func (qs *QueueService) DebugInternalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /debug/internals - Request")
+	resp := qs.Internals()
+	log.Printf("[API] GET /debug/internals - SUCCESS: %d goroutines, %d subscribers", resp.Goroutines, resp.EventSubscribers)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
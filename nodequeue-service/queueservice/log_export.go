@@ -0,0 +1,148 @@
+package queueservice
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/utils"
+)
+
+// NodeLogLine is one line of GET /nodes/logs.jsonl.
+type NodeLogLine struct {
+	NodeID         string    `json:"node_id"`
+	Action         string    `json:"action"`
+	ResourceID     *string   `json:"resource_id"`
+	FromResourceID *string   `json:"from_resource_id,omitempty"`
+	TS             time.Time `json:"ts"`
+}
+
+// parseNodeLogQuery reads since/until (RFC 3339) from the query string.
+func parseNodeLogQuery(r *http.Request) (db.NodeLogQuery, map[string]string) {
+	var q db.NodeLogQuery
+	fields := make(map[string]string)
+	values := r.URL.Query()
+
+	for _, name := range []string{"since", "until"} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields[name] = "must be an RFC 3339 timestamp"
+			continue
+		}
+		if name == "since" {
+			q.Since = ts
+		} else {
+			q.Until = ts
+		}
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		fields["until"] = "must be after since"
+	}
+	return q, fields
+}
+
+// memoryNodeLogs returns the in-memory log rows of every node in q's range, in timestamp order.
+func (qs *QueueService) memoryNodeLogs(q db.NodeLogQuery) []db.NodeLogRow {
+	qs.mu.RLock()
+	rows := make([]db.NodeLogRow, 0)
+	for _, n := range qs.nodes {
+		for _, l := range n.Log {
+			if (!q.Since.IsZero() && l.Timestamp.Before(q.Since)) || (!q.Until.IsZero() && !l.Timestamp.Before(q.Until)) {
+				continue
+			}
+			row := db.NodeLogRow{
+				NodeID:         n.ID,
+				Action:         l.Action,
+				ResourceID:     optionalString(l.ResourceID),
+				FromResourceID: optionalString(l.FromResourceID),
+				TS:             l.Timestamp,
+			}
+			rows = append(rows, row)
+		}
+	}
+	qs.mu.RUnlock()
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].TS.Equal(rows[j].TS) {
+			return rows[i].TS.Before(rows[j].TS)
+		}
+		return rows[i].NodeID < rows[j].NodeID
+	})
+	return rows
+}
+
+// ExportNodeLogsHandler handles GET /nodes/logs.jsonl[?since=&until=].
+//
+// Streams every node log row as newline-delimited JSON (NodeLogLine), oldest first. Rows come from
+// the store when one is configured, so logs of archived nodes are included; without a store, or if
+// the store fails before the first row, the in-memory logs are exported instead. Once rows have
+// been sent a failure can only truncate the stream, and is logged.
+func (qs *QueueService) ExportNodeLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /nodes/logs.jsonl - Request")
+
+	q, errs := parseNodeLogQuery(r)
+	if len(errs) > 0 {
+		log.Printf("[API] GET /nodes/logs.jsonl - ERROR: %v", &utils.ValidationError{Fields: errs})
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: errs,
+		})
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	written := 0
+	write := func(row db.NodeLogRow) error {
+		if written == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		written++
+		return enc.Encode(NodeLogLine{NodeID: row.NodeID, Action: row.Action, ResourceID: row.ResourceID, FromResourceID: row.FromResourceID, TS: row.TS.UTC()})
+	}
+
+	source := "memory"
+	if qs.store != nil {
+		source = "db"
+		err := traceStore(r.Context(), "EachNodeLog", func(ctx context.Context) error {
+			return qs.store.EachNodeLog(ctx, q, write)
+		})
+		switch {
+		case err != nil && written > 0:
+			// Headers are already sent; all we can do is log.
+			log.Printf("[API] GET /nodes/logs.jsonl - ERROR: stream aborted after %d rows: %v", written, err)
+			return
+		case err != nil:
+			log.Printf("[DB] EachNodeLog failed (falling back to in-memory logs): %v", err)
+			source = "memory"
+		}
+	}
+	if source == "memory" {
+		for _, row := range qs.memoryNodeLogs(q) {
+			if err := write(row); err != nil {
+				log.Printf("[API] GET /nodes/logs.jsonl - ERROR: stream aborted after %d rows: %v", written, err)
+				return
+			}
+		}
+	}
+
+	if written == 0 {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+	log.Printf("[API] GET /nodes/logs.jsonl - SUCCESS: Exported %d rows from %s", written, source)
+}
@@ -0,0 +1,50 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// CompactNodeLogs deletes persisted log rows older than retention for completed nodes, keeping
+// the "created" and "completed" rows (db.RetainedLogActions) so metrics still report a total
+// time. Active nodes are never touched, so restore placement is unaffected. In-memory logs are
+// left alone; the archiver is what bounds memory.
+//
+// Without a store there is nothing to compact and it returns 0.
+func (qs *QueueService) CompactNodeLogs(ctx context.Context, retention time.Duration) (n int, err error) {
+	if qs.store == nil {
+		return 0, nil
+	}
+
+	ctx, span := startSpan(ctx, "QueueService.CompactNodeLogs")
+	defer func() { endSpan(span, err) }()
+
+	cutoff := time.Now().Add(-retention)
+	err = traceStore(ctx, "DeleteLogsOlderThan", func(ctx context.Context) (err error) {
+		n, err = qs.store.DeleteLogsOlderThan(ctx, cutoff)
+		return err
+	})
+	return n, err
+}
+
+// RunLogCompaction calls CompactNodeLogs every interval until ctx is cancelled.
+func (qs *QueueService) RunLogCompaction(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := qs.CompactNodeLogs(ctx, retention)
+			if err != nil {
+				log.Printf("[DB] node log compaction failed: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[QueueService] compacted %d node log rows older than %v", n, retention)
+			}
+		}
+	}
+}
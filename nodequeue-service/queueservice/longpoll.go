@@ -0,0 +1,92 @@
+package queueservice
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxLongPollWait caps ?wait= on GET /nodes/{id}; longer requests are shortened to it.
+const MaxLongPollWait = 60 * time.Second
+
+// longPoll is a parsed GET /nodes/{id}?wait=&since_version= request. Wait is zero for a plain GET.
+type longPoll struct {
+	Wait         time.Duration
+	SinceVersion int64
+}
+
+// parseLongPoll reads ?wait= (a Go duration, capped at MaxLongPollWait) and ?since_version=,
+// which is required when wait is set. Invalid values are reported as field->message pairs.
+func parseLongPoll(r *http.Request) (longPoll, map[string]string) {
+	q := r.URL.Query()
+	errs := make(map[string]string)
+
+	var lp longPoll
+	rawWait := q.Get("wait")
+	if rawWait == "" {
+		if q.Get("since_version") != "" {
+			errs["wait"] = "is required with since_version"
+		}
+		return lp, errs
+	}
+	d, err := time.ParseDuration(rawWait)
+	if err != nil || d <= 0 {
+		errs["wait"] = "must be a positive duration (e.g. 30s)"
+	}
+	lp.Wait = min(d, MaxLongPollWait)
+
+	rawVersion := q.Get("since_version")
+	if rawVersion == "" {
+		errs["since_version"] = "is required with wait"
+	} else if v, err := strconv.ParseInt(rawVersion, 10, 64); err != nil || v < 0 {
+		errs["since_version"] = "must be a non-negative integer"
+	} else {
+		lp.SinceVersion = v
+	}
+	return lp, errs
+}
+
+// nodeVersion returns the node's current Version.
+func (qs *QueueService) nodeVersion(nodeID string) (int64, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return 0, ErrNodeNotFound
+	}
+	return n.Version, nil
+}
+
+// WaitForNodeVersion blocks until the node's Version exceeds sinceVersion, timeout elapses or ctx
+// is done. It reports whether the version advanced; ctx errors are returned as-is.
+//
+// It subscribes to node events before checking the version so a change between the check and
+// the wait cannot be missed. Every event triggers a re-check, which also covers events dropped
+// for this subscriber.
+func (qs *QueueService) WaitForNodeVersion(ctx context.Context, nodeID string, sinceVersion int64, timeout time.Duration) (bool, error) {
+	events, cancel := qs.Subscribe()
+	defer cancel()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		version, err := qs.nodeVersion(nodeID)
+		if err != nil {
+			return false, err
+		}
+		if version > sinceVersion {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timer.C:
+			return false, nil
+		case <-events:
+		}
+	}
+}
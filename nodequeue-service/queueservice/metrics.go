@@ -1,6 +1,7 @@
 package queueservice
 
 import (
+	"net/http"
 	"sort"
 	"time"
 
@@ -26,6 +27,18 @@ type NodeMetrics struct {
 	Completed           bool             `json:"completed"`
 	TotalTimeInSystemMS int64            `json:"total_time_in_system_ms"`
 	WaitingSegments     []WaitingSegment `json:"waiting_segments"`
+	// Churn counts, derived from the same log as the segments.
+	WaitingCount   int `json:"waiting_count"`
+	ServiceEntries int `json:"service_entries"`
+	// Deallocations counts how often the node was put back into a waiting queue while in service.
+	Deallocations int `json:"deallocations"`
+	// Failures counts failed attempts, service timeouts and terminal failures.
+	Failures int `json:"failures"`
+	// ChurnScore is 0 for a node that waited once and was served once; every extra wait and every
+	// deallocation adds 1.
+	ChurnScore int `json:"churn_score"`
+	// DeadlineExceeded marks a node cancelled by the DeadlineMonitor for missing its deadline.
+	DeadlineExceeded bool `json:"deadline_exceeded,omitempty"`
 }
 
 // NodesMetricsResponse is the response payload for GET /nodes/metrics.
@@ -34,6 +47,68 @@ type NodesMetricsResponse struct {
 	CompletedNodes []NodeMetrics `json:"completed_nodes"`
 }
 
+// NodeMetricsSummary is NodeMetrics without the per-segment detail, for GET
+// /nodes/metrics?detail=summary.
+type NodeMetricsSummary struct {
+	ID                  string    `json:"id"`
+	EntityName          string    `json:"entity_name"`
+	CreatedAt           time.Time `json:"created_at"`
+	Completed           bool      `json:"completed"`
+	TotalTimeInSystemMS int64     `json:"total_time_in_system_ms"`
+	// TotalWaitingMS is the sum of the waiting segments' durations.
+	TotalWaitingMS int64 `json:"total_waiting_ms"`
+	WaitingCount   int   `json:"waiting_count"`
+	ServiceEntries int   `json:"service_entries"`
+	Deallocations  int   `json:"deallocations"`
+	Failures       int   `json:"failures"`
+	ChurnScore     int   `json:"churn_score"`
+	// DeadlineExceeded is as in NodeMetrics.
+	DeadlineExceeded bool `json:"deadline_exceeded,omitempty"`
+}
+
+// NodesMetricsSummaryResponse is the response payload for GET /nodes/metrics?detail=summary.
+type NodesMetricsSummaryResponse struct {
+	ActiveNodes    []NodeMetricsSummary `json:"active_nodes"`
+	CompletedNodes []NodeMetricsSummary `json:"completed_nodes"`
+}
+
+// summarizeNodeMetrics drops the segments from m, keeping totals.
+func summarizeNodeMetrics(m NodeMetrics) NodeMetricsSummary {
+	var waiting int64
+	for _, seg := range m.WaitingSegments {
+		waiting += seg.DurationMS
+	}
+	return NodeMetricsSummary{
+		ID:                  m.ID,
+		EntityName:          m.EntityName,
+		CreatedAt:           m.CreatedAt,
+		Completed:           m.Completed,
+		TotalTimeInSystemMS: m.TotalTimeInSystemMS,
+		TotalWaitingMS:      waiting,
+		WaitingCount:        m.WaitingCount,
+		ServiceEntries:      m.ServiceEntries,
+		Deallocations:       m.Deallocations,
+		Failures:            m.Failures,
+		ChurnScore:          m.ChurnScore,
+		DeadlineExceeded:    m.DeadlineExceeded,
+	}
+}
+
+// summarize returns resp with every entry reduced to its NodeMetricsSummary.
+func (resp NodesMetricsResponse) summarize() NodesMetricsSummaryResponse {
+	out := NodesMetricsSummaryResponse{
+		ActiveNodes:    make([]NodeMetricsSummary, 0, len(resp.ActiveNodes)),
+		CompletedNodes: make([]NodeMetricsSummary, 0, len(resp.CompletedNodes)),
+	}
+	for _, m := range resp.ActiveNodes {
+		out.ActiveNodes = append(out.ActiveNodes, summarizeNodeMetrics(m))
+	}
+	for _, m := range resp.CompletedNodes {
+		out.CompletedNodes = append(out.CompletedNodes, summarizeNodeMetrics(m))
+	}
+	return out
+}
+
 type nodeEvent struct {
 	Action     string
 	ResourceID string
@@ -47,6 +122,101 @@ type nodeSnapshot struct {
 	Completed bool
 }
 
+// Values for GET /nodes/metrics?source=.
+const (
+	metricsSourceMemory = "memory"
+	metricsSourceDB     = "db"
+)
+
+// Values for GET /nodes/metrics?detail=.
+const (
+	metricsDetailFull    = "full"
+	metricsDetailSummary = "summary"
+)
+
+// metricsFilter scopes GET /nodes/metrics. Zero values leave that filter off.
+type metricsFilter struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ResourceID    string
+	// Source selects where nodes come from: metricsSourceMemory (default) or metricsSourceDB.
+	Source string
+	// Detail is metricsDetailFull (default) or metricsDetailSummary, which leaves out segments.
+	Detail string
+}
+
+// parseMetricsFilter reads created_after/created_before (RFC 3339), resource_id, source and
+// detail from the query string.
+func parseMetricsFilter(r *http.Request) (metricsFilter, map[string]string) {
+	var f metricsFilter
+	fields := make(map[string]string)
+	values := r.URL.Query()
+
+	for _, name := range []string{"created_after", "created_before"} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields[name] = "must be an RFC 3339 timestamp"
+			continue
+		}
+		if name == "created_after" {
+			f.CreatedAfter = ts
+		} else {
+			f.CreatedBefore = ts
+		}
+	}
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedBefore.After(f.CreatedAfter) {
+		fields["created_before"] = "must be after created_after"
+	}
+	f.ResourceID = values.Get("resource_id")
+
+	switch source := values.Get("source"); source {
+	case "", metricsSourceMemory:
+		f.Source = metricsSourceMemory
+	case metricsSourceDB:
+		f.Source = metricsSourceDB
+	default:
+		fields["source"] = "must be one of: memory, db"
+	}
+
+	switch detail := values.Get("detail"); detail {
+	case "", metricsDetailFull:
+		f.Detail = metricsDetailFull
+	case metricsDetailSummary:
+		f.Detail = metricsDetailSummary
+	default:
+		fields["detail"] = "must be one of: summary, full"
+	}
+	return f, fields
+}
+
+// includesCreated reports whether a node created at t falls inside the window.
+func (f metricsFilter) includesCreated(t time.Time) bool {
+	if !f.CreatedAfter.IsZero() && t.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !t.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// includesEvents reports whether a node's events touched the filtered resource.
+func (f metricsFilter) includesEvents(events []nodeEvent) bool {
+	if f.ResourceID == "" {
+		return true
+	}
+	for _, ev := range events {
+		if ev.ResourceID == f.ResourceID {
+			return true
+		}
+	}
+	return false
+}
+
 func toNodeEventsFromInMemory(logs []node.NodeLog) []nodeEvent {
 	out := make([]nodeEvent, 0, len(logs))
 	for _, l := range logs {
@@ -69,7 +239,7 @@ func toNodeEventsFromDB(rows []db.NodeLogRow) []nodeEvent {
 		out = append(out, nodeEvent{
 			Action:     r.Action,
 			ResourceID: rid,
-			TS:         r.TS,
+			TS:         r.TS.UTC(),
 		})
 	}
 	return out
@@ -82,6 +252,9 @@ func computeNodeMetrics(now time.Time, n nodeSnapshot, events []nodeEvent) NodeM
 	segments := make([]WaitingSegment, 0)
 	openIdx := -1
 	var completedTS *time.Time
+	inService := false
+	var serviceEntries, deallocations, failures int
+	deadlineExceeded := false
 
 	closeOpen := func(end time.Time) {
 		if openIdx == -1 {
@@ -99,6 +272,10 @@ func computeNodeMetrics(now time.Time, n nodeSnapshot, events []nodeEvent) NodeM
 	for _, ev := range events {
 		switch ev.Action {
 		case "moved_to_waiting_queue":
+			if inService {
+				deallocations++
+				inService = false
+			}
 			// If we were already waiting somewhere, treat this as leaving that wait state.
 			closeOpen(ev.TS)
 			segments = append(segments, WaitingSegment{
@@ -108,12 +285,24 @@ func computeNodeMetrics(now time.Time, n nodeSnapshot, events []nodeEvent) NodeM
 			openIdx = len(segments) - 1
 
 		case "moved_to_service_queue":
+			serviceEntries++
+			inService = true
 			// Only close if it matches the currently open wait segment.
 			if openIdx != -1 && segments[openIdx].ResourceID == ev.ResourceID {
 				closeOpen(ev.TS)
 			}
 
-		case "completed":
+		case "failed_attempt", "service_timeout":
+			failures++
+
+		case "deadline_exceeded":
+			deadlineExceeded = true
+
+		case "completed", "failed":
+			if ev.Action == "failed" {
+				failures++
+			}
+			inService = false
 			// Freeze totals at completion time; also stop any ongoing waiting.
 			ts := ev.TS
 			completedTS = &ts
@@ -139,5 +328,11 @@ func computeNodeMetrics(now time.Time, n nodeSnapshot, events []nodeEvent) NodeM
 		Completed:           n.Completed,
 		TotalTimeInSystemMS: total.Milliseconds(),
 		WaitingSegments:     segments,
+		WaitingCount:        len(segments),
+		ServiceEntries:      serviceEntries,
+		Deallocations:       deallocations,
+		Failures:            failures,
+		ChurnScore:          max(len(segments)-1, 0) + deallocations,
+		DeadlineExceeded:    deadlineExceeded,
 	}
 }
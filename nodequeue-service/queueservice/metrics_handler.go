@@ -1,6 +1,7 @@
 package queueservice
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"sort"
@@ -11,8 +12,14 @@ import (
 	"nodequeue-service/utils"
 )
 
-// NodesMetricsHandler handles GET /nodes/metrics.
+// NodesMetricsHandler handles GET /nodes/metrics[?created_after=&created_before=&resource_id=&source=&detail=].
 // It returns all nodes (active + completed) along with computed time-in-system and waiting segments.
+// created_after (inclusive) and created_before (exclusive) bound node creation time; resource_id
+// keeps only nodes whose logs touched that resource.
+//
+// source=db builds the response purely from the store (see computeMetricsFromStore) instead of
+// the nodes held in memory; it returns 503 when persistence is disabled. detail=summary replaces
+// each entry with its NodeMetricsSummary.
 func (qs *QueueService) NodesMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -20,33 +27,58 @@ func (qs *QueueService) NodesMetricsHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	startTime := time.Now()
-	now := time.Now()
 	log.Printf("[API] GET /nodes/metrics - Request")
 
+	filter, fields := parseMetricsFilter(r)
+	if len(fields) > 0 {
+		err := &utils.ValidationError{Fields: fields}
+		log.Printf("[API] GET /nodes/metrics - ERROR: %v", err)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	var resp NodesMetricsResponse
+	if filter.Source == metricsSourceDB {
+		var err error
+		resp, err = qs.computeMetricsFromStore(r.Context(), filter)
+		if err != nil {
+			log.Printf("[API] GET /nodes/metrics - ERROR: %v", err)
+			respondWithServiceError(w, err)
+			return
+		}
+	} else {
+		resp = qs.computeMetricsFromMemory(r.Context(), filter)
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/metrics - SUCCESS: Returning %d active, %d completed (took %v)", len(resp.ActiveNodes), len(resp.CompletedNodes), duration)
+	if filter.Detail == metricsDetailSummary {
+		utils.RespondWithJSON(w, http.StatusOK, resp.summarize())
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// computeMetricsFromMemory computes metrics for the nodes currently held in memory, preferring
+// their persisted logs when a store is configured.
+func (qs *QueueService) computeMetricsFromMemory(ctx context.Context, filter metricsFilter) NodesMetricsResponse {
+	now := node.Now()
+
 	qs.mu.RLock()
 	nodeIDs := make([]string, 0, len(qs.nodes))
 	snaps := make(map[string]nodeSnapshot, len(qs.nodes))
 	memLogs := make(map[string][]node.NodeLog, len(qs.nodes))
 	for id, n := range qs.nodes {
-		entityName := ""
-		if n.Entity != nil {
-			entityName = n.Entity.Name
-		}
-		snaps[id] = nodeSnapshot{
-			ID:        n.ID,
-			Entity:    entityName,
-			CreatedAt: n.CreatedAt,
-			Completed: n.Completed,
+		// Filter before copying logs or hitting the DB.
+		if !filter.includesCreated(n.CreatedAt) {
+			continue
 		}
+		snaps[id], memLogs[id] = metricsSnapshot(n)
 		nodeIDs = append(nodeIDs, id)
-
-		if len(n.Log) > 0 {
-			cp := make([]node.NodeLog, len(n.Log))
-			copy(cp, n.Log)
-			memLogs[id] = cp
-		} else {
-			memLogs[id] = nil
-		}
 	}
 	qs.mu.RUnlock()
 
@@ -54,25 +86,262 @@ func (qs *QueueService) NodesMetricsHandler(w http.ResponseWriter, r *http.Reque
 	var dbLogs map[string][]db.NodeLogRow
 	if qs.store != nil && len(nodeIDs) > 0 {
 		var err error
-		dbLogs, err = qs.store.ListNodeLogs(r.Context(), nodeIDs)
+		dbLogs, err = qs.store.ListNodeLogs(ctx, nodeIDs)
+		if err != nil {
+			log.Printf("[DB] ListNodeLogs failed (falling back to in-memory logs): %v", err)
+			dbLogs = nil
+		}
+	}
+
+	events := make(map[string][]nodeEvent, len(snaps))
+	for id := range snaps {
+		if rows := dbLogs[id]; len(rows) > 0 {
+			events[id] = toNodeEventsFromDB(rows)
+		} else {
+			events[id] = toNodeEventsFromInMemory(memLogs[id])
+		}
+	}
+	return buildMetricsResponse(now, filter, snaps, events)
+}
+
+// metricsSnapshot copies what computeNodeMetrics needs from n, so it can run after qs.mu is
+// released. Callers must hold qs.mu (read or write).
+func metricsSnapshot(n *node.Node) (nodeSnapshot, []node.NodeLog) {
+	entityName := ""
+	if n.Entity != nil {
+		entityName = n.Entity.Name
+	}
+	snap := nodeSnapshot{
+		ID:        n.ID,
+		Entity:    entityName,
+		CreatedAt: n.CreatedAt,
+		Completed: n.Completed,
+	}
+	var logs []node.NodeLog
+	if len(n.Log) > 0 {
+		logs = make([]node.NodeLog, len(n.Log))
+		copy(logs, n.Log)
+	}
+	return snap, logs
+}
+
+// GetNodeMetrics is GetNodeMetricsContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) GetNodeMetrics(nodeID string) (NodeMetrics, error) {
+	return qs.GetNodeMetricsContext(context.Background(), nodeID)
+}
+
+// GetNodeMetricsContext computes the metrics of one in-memory node the same way GET /nodes/metrics
+// does: from its persisted logs when a store is configured and has them, else from its in-memory
+// log. Returns ErrNodeNotFound for unknown nodes.
+func (qs *QueueService) GetNodeMetricsContext(ctx context.Context, nodeID string) (NodeMetrics, error) {
+	now := node.Now()
+
+	qs.mu.RLock()
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		qs.mu.RUnlock()
+		return NodeMetrics{}, ErrNodeNotFound
+	}
+	snap, memLogs := metricsSnapshot(n)
+	qs.mu.RUnlock()
+
+	events := toNodeEventsFromInMemory(memLogs)
+	if qs.store != nil {
+		var rows map[string][]db.NodeLogRow
+		err := traceStore(ctx, "ListNodeLogs", func(ctx context.Context) (err error) {
+			rows, err = qs.store.ListNodeLogs(ctx, []string{nodeID})
+			return err
+		})
+		if err != nil {
+			log.Printf("[DB] ListNodeLogs failed (falling back to in-memory logs): %v", err)
+		} else if len(rows[nodeID]) > 0 {
+			events = toNodeEventsFromDB(rows[nodeID])
+		}
+	}
+	return computeNodeMetrics(now, snap, events), nil
+}
+
+// NodeMetricsHandler handles GET /nodes/{id}/metrics.
+//
+// Returns the NodeMetrics of a single node, as found in GET /nodes/metrics; 404 for unknown nodes.
+func (qs *QueueService) NodeMetricsHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] GET /nodes/%s/metrics - Request", nodeID)
+
+	metrics, err := qs.GetNodeMetricsContext(r.Context(), nodeID)
+	if err != nil {
+		log.Printf("[API] GET /nodes/%s/metrics - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/%s/metrics - SUCCESS: Returning %d waiting segments (took %v)", nodeID, len(metrics.WaitingSegments), duration)
+	utils.RespondWithJSON(w, http.StatusOK, metrics)
+}
+
+// NodeMetricsResult is one entry of POST /nodes/metrics/batch: the node's metrics, or why they
+// could not be computed.
+type NodeMetricsResult struct {
+	ID      string       `json:"id"`
+	OK      bool         `json:"ok"`
+	Metrics *NodeMetrics `json:"metrics,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+}
+
+// NodeMetricsBatchResponse is the response payload for POST /nodes/metrics/batch.
+type NodeMetricsBatchResponse struct {
+	Results []NodeMetricsResult `json:"results"`
+	Found   int                 `json:"found"`
+	Missing int                 `json:"missing"`
+}
+
+// GetNodeMetricsBatch is GetNodeMetricsBatchContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) GetNodeMetricsBatch(ids []string) []NodeMetricsResult {
+	return qs.GetNodeMetricsBatchContext(context.Background(), ids)
+}
+
+// GetNodeMetricsBatchContext computes the metrics of the given in-memory nodes like
+// GetNodeMetrics, with one ListNodeLogs call for all of them. It returns a result per ID, in
+// request order; unknown IDs get ErrNodeNotFound's code.
+func (qs *QueueService) GetNodeMetricsBatchContext(ctx context.Context, ids []string) []NodeMetricsResult {
+	now := node.Now()
+
+	qs.mu.RLock()
+	snaps := make(map[string]nodeSnapshot, len(ids))
+	memLogs := make(map[string][]node.NodeLog, len(ids))
+	found := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, seen := snaps[id]; seen {
+			continue
+		}
+		if n, exists := qs.nodes[id]; exists {
+			snaps[id], memLogs[id] = metricsSnapshot(n)
+			found = append(found, id)
+		}
+	}
+	qs.mu.RUnlock()
+
+	var dbLogs map[string][]db.NodeLogRow
+	if qs.store != nil && len(found) > 0 {
+		err := traceStore(ctx, "ListNodeLogs", func(ctx context.Context) (err error) {
+			dbLogs, err = qs.store.ListNodeLogs(ctx, found)
+			return err
+		})
 		if err != nil {
 			log.Printf("[DB] ListNodeLogs failed (falling back to in-memory logs): %v", err)
 			dbLogs = nil
 		}
 	}
 
+	results := make([]NodeMetricsResult, 0, len(ids))
+	for _, id := range ids {
+		snap, exists := snaps[id]
+		if !exists {
+			_, code := errorStatus(ErrNodeNotFound)
+			results = append(results, NodeMetricsResult{ID: id, Error: ErrNodeNotFound.Error(), Code: code})
+			continue
+		}
+		events := toNodeEventsFromInMemory(memLogs[id])
+		if rows := dbLogs[id]; len(rows) > 0 {
+			events = toNodeEventsFromDB(rows)
+		}
+		m := computeNodeMetrics(now, snap, events)
+		results = append(results, NodeMetricsResult{ID: id, OK: true, Metrics: &m})
+	}
+	return results
+}
+
+// NodeMetricsBatchHandler handles POST /nodes/metrics/batch.
+//
+// Returns a result per requested ID: 200 when every node was found, otherwise 207 Multi-Status
+// with the missing IDs reported in their results.
+func (qs *QueueService) NodeMetricsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+
+	var req node.NodeMetricsBatchRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/metrics/batch - ERROR: %v", err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	log.Printf("[API] POST /nodes/metrics/batch - Request: ids=%d", len(req.IDs))
+
+	resp := NodeMetricsBatchResponse{Results: qs.GetNodeMetricsBatchContext(r.Context(), req.IDs)}
+	for _, res := range resp.Results {
+		if res.OK {
+			resp.Found++
+		} else {
+			resp.Missing++
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Missing > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/metrics/batch - SUCCESS: %d found, %d missing (took %v)", resp.Found, resp.Missing, duration)
+	utils.RespondWithJSON(w, status, resp)
+}
+
+// computeMetricsFromStore computes metrics purely from the store via ListAllNodes + ListNodeLogs,
+// so completed nodes that were purged from memory and nodes not yet touched since a restart are
+// included. Returns ErrStoreUnavailable without a store.
+func (qs *QueueService) computeMetricsFromStore(ctx context.Context, filter metricsFilter) (NodesMetricsResponse, error) {
+	if qs.store == nil {
+		return NodesMetricsResponse{}, ErrStoreUnavailable
+	}
+	now := node.Now()
+
+	persisted, err := qs.store.ListAllNodes(ctx)
+	if err != nil {
+		return NodesMetricsResponse{}, err
+	}
+
+	nodeIDs := make([]string, 0, len(persisted))
+	snaps := make(map[string]nodeSnapshot, len(persisted))
+	for _, pn := range persisted {
+		if !filter.includesCreated(pn.CreatedAt) {
+			continue
+		}
+		snaps[pn.NodeID] = nodeSnapshot{
+			ID:        pn.NodeID,
+			Entity:    pn.EntityName,
+			CreatedAt: pn.CreatedAt.UTC(),
+			Completed: pn.Completed,
+		}
+		nodeIDs = append(nodeIDs, pn.NodeID)
+	}
+
+	rows, err := qs.store.ListNodeLogs(ctx, nodeIDs)
+	if err != nil {
+		return NodesMetricsResponse{}, err
+	}
+	events := make(map[string][]nodeEvent, len(snaps))
+	for id := range snaps {
+		events[id] = toNodeEventsFromDB(rows[id])
+	}
+	return buildMetricsResponse(now, filter, snaps, events), nil
+}
+
+// buildMetricsResponse applies the resource filter, computes per-node metrics and splits them
+// into active and completed sections ordered by creation time.
+func buildMetricsResponse(now time.Time, filter metricsFilter, snaps map[string]nodeSnapshot, events map[string][]nodeEvent) NodesMetricsResponse {
 	active := make([]NodeMetrics, 0)
 	completed := make([]NodeMetrics, 0)
 	for id, snap := range snaps {
-		var evs []nodeEvent
-		if dbLogs != nil {
-			if rows := dbLogs[id]; len(rows) > 0 {
-				evs = toNodeEventsFromDB(rows)
-			} else {
-				evs = toNodeEventsFromInMemory(memLogs[id])
-			}
-		} else {
-			evs = toNodeEventsFromInMemory(memLogs[id])
+		evs := events[id]
+		if !filter.includesEvents(evs) {
+			continue
 		}
 
 		m := computeNodeMetrics(now, snap, evs)
@@ -87,12 +356,8 @@ func (qs *QueueService) NodesMetricsHandler(w http.ResponseWriter, r *http.Reque
 	sort.SliceStable(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
 	sort.SliceStable(completed, func(i, j int) bool { return completed[i].CreatedAt.Before(completed[j].CreatedAt) })
 
-	resp := NodesMetricsResponse{
+	return NodesMetricsResponse{
 		ActiveNodes:    active,
 		CompletedNodes: completed,
 	}
-
-	duration := time.Since(startTime)
-	log.Printf("[API] GET /nodes/metrics - SUCCESS: Returning %d active, %d completed (took %v)", len(active), len(completed), duration)
-	utils.RespondWithJSON(w, http.StatusOK, resp)
 }
@@ -0,0 +1,35 @@
+package queueservice
+
+import (
+	"fmt"
+
+	"nodequeue-service/node"
+)
+
+// moveCount returns how many resource transitions a node has made, counted from its log: every
+// "moved_to_waiting_queue" entry onto a resource other than the previous one. Re-queues onto the
+// same resource (e.g. after FailNode) do not count.
+func moveCount(n *node.Node) int {
+	count := 0
+	prev := ""
+	for _, entry := range n.Log {
+		if entry.Action != "moved_to_waiting_queue" || entry.ResourceID == prev {
+			continue
+		}
+		prev = entry.ResourceID
+		count++
+	}
+	return count
+}
+
+// checkMoveLimit returns ErrMoveLimit if moving n to another resource would exceed MaxMoves.
+// Callers must hold qs.mu (read or write).
+func (qs *QueueService) checkMoveLimit(n *node.Node) error {
+	if qs.MaxMoves <= 0 {
+		return nil
+	}
+	if moves := moveCount(n); moves >= qs.MaxMoves {
+		return fmt.Errorf("node has made %d of %d allowed moves: %w", moves, qs.MaxMoves, ErrMoveLimit)
+	}
+	return nil
+}
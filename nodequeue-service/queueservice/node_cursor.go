@@ -0,0 +1,53 @@
+package queueservice
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// errInvalidCursor is reported for a ?cursor= that DecodeNodeCursor cannot read.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// NodeCursor is a position in GET /nodes order: the creation time and ID of the last node seen.
+// Nodes are listed by CreatedAt, then ID, so nodes created after a page was fetched always sort
+// after it and paging neither skips nor repeats existing nodes.
+type NodeCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// cursorFor returns the cursor positioned at n. Callers must hold qs.mu (read or write).
+func cursorFor(n *node.Node) NodeCursor {
+	return NodeCursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+// Before reports whether c sorts before other in GET /nodes order.
+func (c NodeCursor) Before(other NodeCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return c.ID < other.ID
+}
+
+// Encode returns c as the opaque token clients pass back as ?cursor=.
+func (c NodeCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeNodeCursor parses a token produced by NodeCursor.Encode.
+func DecodeNodeCursor(token string) (NodeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return NodeCursor{}, errInvalidCursor
+	}
+	var c NodeCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return NodeCursor{}, errInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,91 @@
+package queueservice
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"nodequeue-service/node"
+	"nodequeue-service/resource"
+)
+
+// Queue kinds accepted by NodeFilter.Queue (?queue=).
+const (
+	NodeQueueWaiting = "waiting"
+	NodeQueueService = "service"
+)
+
+// NodeFilter narrows GET /nodes. The zero value matches every node.
+type NodeFilter struct {
+	// Tags keeps only nodes carrying every listed tag.
+	Tags []string
+	// ResourceID keeps only nodes queued on that resource; it must exist.
+	ResourceID string
+	// Queue keeps only nodes currently in that kind of queue: NodeQueueWaiting or NodeQueueService.
+	Queue string
+}
+
+// parseNodeFilter reads ?tag=, ?resource_id= and ?queue=, recording invalid values in errs.
+func parseNodeFilter(r *http.Request, errs map[string]string) NodeFilter {
+	q := r.URL.Query()
+	filter := NodeFilter{Tags: q["tag"], ResourceID: q.Get("resource_id"), Queue: q.Get("queue")}
+	for _, tag := range filter.Tags {
+		if !node.ValidTag(tag) {
+			errs["tag"] = fmt.Sprintf("invalid tag %q", tag)
+			break
+		}
+	}
+	switch filter.Queue {
+	case "", NodeQueueWaiting, NodeQueueService:
+	default:
+		errs["queue"] = "must be one of: waiting, service"
+	}
+	return filter
+}
+
+// filterNodesLocked returns the nodes matching filter, in no particular order. With a resource or
+// queue filter it walks the resource queues instead of scanning every node. Callers must hold
+// qs.mu (read or write).
+func (qs *QueueService) filterNodesLocked(filter NodeFilter) ([]*node.Node, error) {
+	var candidates []*node.Node
+	switch {
+	case filter.ResourceID != "":
+		r, exists := qs.resources[qs.LookupResourceID(filter.ResourceID)]
+		if !exists {
+			return nil, ErrResourceNotFound
+		}
+		candidates = nodesInQueue(r, filter.Queue)
+	case filter.Queue != "":
+		for _, r := range qs.resources {
+			candidates = append(candidates, nodesInQueue(r, filter.Queue)...)
+		}
+	default:
+		candidates = make([]*node.Node, 0, len(qs.nodes))
+		for _, n := range qs.nodes {
+			candidates = append(candidates, n)
+		}
+	}
+
+	if len(filter.Tags) == 0 {
+		return candidates, nil
+	}
+	out := candidates[:0:0]
+	for _, n := range candidates {
+		if n.HasTags(filter.Tags) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// nodesInQueue returns r's service or waiting nodes for a queue kind, or both for "".
+func nodesInQueue(r *resource.Resource, queue string) []*node.Node {
+	service, waiting := r.QueueSnapshot()
+	switch queue {
+	case NodeQueueService:
+		return service
+	case NodeQueueWaiting:
+		return waiting
+	}
+	return slices.Concat(service, waiting)
+}
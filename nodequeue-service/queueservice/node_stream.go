@@ -0,0 +1,203 @@
+package queueservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"nodequeue-service/utils"
+)
+
+// streamBatchSize is how many node views GET /nodes?stream=true builds per read lock. It bounds
+// both the memory held at once and how long a slow client can delay writers.
+const streamBatchSize = 256
+
+// NodePage selects a window of GET /nodes in (created_at, id) order: nodes after After (from the
+// start when nil), at most Limit of them (0 means no limit).
+//
+// With ByID the window is in node ID order instead, starting after the node ID AfterID (from the
+// start when empty), as for ?after=.
+type NodePage struct {
+	After   *NodeCursor
+	ByID    bool
+	AfterID string
+	Limit   int
+}
+
+// listNodeQuery is the parsed pagination/streaming part of GET /nodes.
+type listNodeQuery struct {
+	page   NodePage
+	paged  bool
+	stream bool
+}
+
+// parseListNodeQuery reads ?cursor=, ?after=, ?limit= and ?stream= into q, recording invalid
+// values in errs.
+//
+// ?after= (even empty) pages in node ID order, from the node ID it names. A ?cursor= token passed
+// as ?after= continues in cursor order, so clients that only ever pass back X-Next-Cursor keep
+// working whichever parameter they use.
+func parseListNodeQuery(r *http.Request, errs map[string]string) listNodeQuery {
+	values := r.URL.Query()
+	var q listNodeQuery
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := DecodeNodeCursor(raw)
+		if err != nil {
+			errs["cursor"] = err.Error()
+		} else {
+			q.page.After = &cursor
+			q.paged = true
+		}
+	}
+
+	if values.Has("after") {
+		raw := values.Get("after")
+		if values.Get("cursor") != "" {
+			errs["after"] = "cannot be combined with cursor"
+		} else if cursor, err := DecodeNodeCursor(raw); raw != "" && err == nil {
+			q.page.After = &cursor
+		} else {
+			q.page.ByID, q.page.AfterID = true, raw
+		}
+		q.paged = true
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			errs["limit"] = "must be a positive integer"
+		} else {
+			q.page.Limit = n
+			q.paged = true
+		}
+	}
+	if raw := values.Get("stream"); raw != "" {
+		stream, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs["stream"] = "must be true or false"
+		}
+		q.stream = stream
+	}
+	return q
+}
+
+// pageNodeIDsLocked returns the IDs of nodes matching filter, in (created_at, id) order and
+// restricted to page, plus the encoded cursor for the next page ("" on the last page). With
+// page.ByID they are in ID order and the next page's cursor is the last ID. Callers must hold
+// qs.mu (read or write).
+func (qs *QueueService) pageNodeIDsLocked(filter NodeFilter, page NodePage) ([]string, string, error) {
+	nodes, err := qs.filterNodesLocked(filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if page.ByID {
+		ids := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			if n.ID > page.AfterID {
+				ids = append(ids, n.ID)
+			}
+		}
+		slices.Sort(ids)
+		if page.Limit > 0 && len(ids) > page.Limit {
+			ids = ids[:page.Limit]
+			return ids, ids[len(ids)-1], nil
+		}
+		return ids, "", nil
+	}
+
+	cursors := make([]NodeCursor, 0, len(nodes))
+	for _, n := range nodes {
+		c := cursorFor(n)
+		if page.After == nil || page.After.Before(c) {
+			cursors = append(cursors, c)
+		}
+	}
+	slices.SortFunc(cursors, func(a, b NodeCursor) int {
+		switch {
+		case a.Before(b):
+			return -1
+		case b.Before(a):
+			return 1
+		}
+		return 0
+	})
+
+	next := ""
+	if page.Limit > 0 && len(cursors) > page.Limit {
+		cursors = cursors[:page.Limit]
+		next = cursors[len(cursors)-1].Encode()
+	}
+	ids := make([]string, len(cursors))
+	for i, c := range cursors {
+		ids[i] = c.ID
+	}
+	return ids, next, nil
+}
+
+// ListNodeViewsPage is ListNodeViews for one page, in (created_at, id) order (ID order with
+// page.ByID). It also returns the cursor for the next page, or "" when this page is the last.
+func (qs *QueueService) ListNodeViewsPage(fields NodeFields, filter NodeFilter, page NodePage) ([]NodeView, string, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	ids, next, err := qs.pageNodeIDsLocked(filter, page)
+	if err != nil {
+		return nil, "", err
+	}
+	views := make([]NodeView, 0, len(ids))
+	for _, id := range ids {
+		views = append(views, qs.nodeView(qs.nodes[id], fields))
+	}
+	return views, next, nil
+}
+
+// streamNodeViews writes the views of ids to w as a JSON array, one element at a time, building
+// at most streamBatchSize views per read lock. Nodes removed since ids was taken are skipped. It
+// returns how many nodes were written.
+//
+// The status line has already been sent when it runs, so an error leaves a truncated array for
+// the client to detect; the caller can only log it.
+func (qs *QueueService) streamNodeViews(w http.ResponseWriter, naming string, fields NodeFields, ids []string) (int, error) {
+	enc := json.NewEncoder(w)
+	if _, err := w.Write([]byte("[")); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	views := make([]NodeView, 0, min(len(ids), streamBatchSize))
+	for batch := range slices.Chunk(ids, streamBatchSize) {
+		views = views[:0]
+		qs.mu.RLock()
+		for _, id := range batch {
+			if n, exists := qs.nodes[id]; exists {
+				views = append(views, qs.nodeView(n, fields))
+			}
+		}
+		qs.mu.RUnlock()
+
+		for _, v := range views {
+			var payload any = v
+			if naming == namingCamel {
+				camel, err := utils.CamelCaseKeys(v)
+				if err != nil {
+					return written, err
+				}
+				payload = camel
+			}
+			if written > 0 {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return written, err
+				}
+			}
+			if err := enc.Encode(payload); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+
+	_, err := w.Write([]byte("]\n"))
+	return written, err
+}
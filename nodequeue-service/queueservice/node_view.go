@@ -0,0 +1,159 @@
+package queueservice
+
+import (
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// NodeFields selects how much of a node GET /nodes and GET /nodes/{id} return (?fields=).
+type NodeFields string
+
+const (
+	// NodeFieldsSummary omits the lifecycle log, which dominates response size on lists.
+	NodeFieldsSummary NodeFields = "summary"
+	// NodeFieldsFull includes everything, including the log.
+	NodeFieldsFull NodeFields = "full"
+)
+
+const (
+	namingSnake = "snake"
+	namingCamel = "camel"
+)
+
+// NodeView is the JSON shape of a node on GET /nodes and GET /nodes/{id}. It adds fields that are
+// computed from live queue state at read time rather than stored on the node.
+type NodeView struct {
+	*node.Node
+	// Log shadows Node.Log so summary views can leave it out; it is nil in NodeFieldsSummary.
+	Log []node.NodeLog `json:"log,omitempty"`
+	// BlockedReason is the error code allocation would currently fail with (e.g. "capacity_full",
+	// "entity_limit_reached", "resource_paused"). Empty unless the node is waiting and blocked.
+	BlockedReason string `json:"blocked_reason,omitempty"`
+	// CurrentWaitMS is how long the node has been in its current waiting queue as of the read, and
+	// CurrentServiceMS how long it has been in service. Each is only set while the node is in
+	// that queue, and only on single-node reads (GetNodeView), so lists stay stable.
+	CurrentWaitMS    *int64 `json:"current_wait_ms,omitempty"`
+	CurrentServiceMS *int64 `json:"current_service_ms,omitempty"`
+}
+
+// nodeView builds the view of n for fields from a snapshot of n, so the view can be encoded after
+// qs.mu is released. Callers must hold qs.mu (read or write).
+func (qs *QueueService) nodeView(n *node.Node, fields NodeFields) NodeView {
+	snap := n.Snapshot()
+	v := NodeView{Node: snap, BlockedReason: qs.blockedReason(n)}
+	if fields != NodeFieldsSummary {
+		v.Log = snap.Log
+	}
+	return v
+}
+
+// setCurrentDurations fills v's CurrentWaitMS or CurrentServiceMS from the node's last queue move
+// in its log. Neither is set for completed or unassigned nodes, or when the log does not record how
+// the node got into its current queue (e.g. after a restart). Callers must hold qs.mu (read or
+// write).
+func (qs *QueueService) setCurrentDurations(v *NodeView, now time.Time) {
+	if v.Completed || v.ResourceID == "" {
+		return
+	}
+	resource, exists := qs.resources[v.ResourceID]
+	if !exists {
+		return
+	}
+
+	var last *node.NodeLog
+	for i := len(v.Node.Log) - 1; i >= 0; i-- {
+		if a := v.Node.Log[i].Action; a == "moved_to_waiting_queue" || a == "moved_to_service_queue" {
+			last = &v.Node.Log[i]
+			break
+		}
+	}
+	if last == nil || last.ResourceID != v.ResourceID {
+		return
+	}
+	ms := max(now.Sub(last.Timestamp).Milliseconds(), 0)
+	switch {
+	case last.Action == "moved_to_waiting_queue" && resource.IsWaiting(v.ID):
+		v.CurrentWaitMS = &ms
+	case last.Action == "moved_to_service_queue" && resource.IsInService(v.ID):
+		v.CurrentServiceMS = &ms
+	}
+}
+
+// GetNodeView returns a node with its BlockedReason and current queue durations computed now,
+// including its log.
+func (qs *QueueService) GetNodeView(nodeID string) (NodeView, error) {
+	return qs.GetNodeViewFields(nodeID, NodeFieldsFull)
+}
+
+// GetNodeViewFields is GetNodeView with a choice of fields.
+func (qs *QueueService) GetNodeViewFields(nodeID string, fields NodeFields) (NodeView, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return NodeView{}, ErrNodeNotFound
+	}
+	v := qs.nodeView(n, fields)
+	qs.setCurrentDurations(&v, time.Now())
+	return v, nil
+}
+
+// ListNodeViews is ListNodes with each node's BlockedReason computed now, restricted to the
+// nodes matching filter. It returns ErrResourceNotFound when filter names an unknown resource.
+func (qs *QueueService) ListNodeViews(fields NodeFields, filter NodeFilter) ([]NodeView, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	nodes, err := qs.filterNodesLocked(filter)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]NodeView, 0, len(nodes))
+	for _, n := range nodes {
+		views = append(views, qs.nodeView(n, fields))
+	}
+	return views, nil
+}
+
+// parseNodeViewQuery reads ?fields=summary|full (defaulting to def) and ?naming=snake|camel.
+// Invalid values are reported as field->message pairs.
+func parseNodeViewQuery(r *http.Request, def NodeFields) (NodeFields, string, map[string]string) {
+	q := r.URL.Query()
+	errs := make(map[string]string)
+
+	fields := def
+	switch raw := NodeFields(q.Get("fields")); raw {
+	case "":
+	case NodeFieldsSummary, NodeFieldsFull:
+		fields = raw
+	default:
+		errs["fields"] = "must be one of: summary, full"
+	}
+
+	naming := namingSnake
+	switch raw := q.Get("naming"); raw {
+	case "", namingSnake:
+	case namingCamel:
+		naming = namingCamel
+	default:
+		errs["naming"] = "must be one of: snake, camel"
+	}
+	return fields, naming, errs
+}
+
+// respondWithNodeJSON writes payload, re-keying it to camelCase when naming is "camel".
+func respondWithNodeJSON(w http.ResponseWriter, naming string, payload any) {
+	if naming == namingCamel {
+		camel, err := utils.CamelCaseKeys(payload)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		payload = camel
+	}
+	utils.RespondWithJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,65 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// AddNodeNote is AddNodeNoteContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) AddNodeNote(nodeID, author, text string) (node.NodeNote, error) {
+	return qs.AddNodeNoteContext(context.Background(), nodeID, author, text)
+}
+
+// AddNodeNoteContext appends an operator note to a node. Notes are append-only and may be added
+// at any point in the node's lifecycle, including after completion.
+func (qs *QueueService) AddNodeNoteContext(ctx context.Context, nodeID, author, text string) (note node.NodeNote, err error) {
+	ctx, span := startSpan(ctx, "QueueService.AddNodeNote", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return node.NodeNote{}, ErrNodeNotFound
+	}
+
+	note = n.AddNote(author, text)
+
+	// Persist note (best-effort).
+	qs.bestEffortPersist(ctx, "InsertNodeNote", func(ctx context.Context) error {
+		return qs.store.InsertNodeNote(ctx, nodeID, note.Author, note.Text, note.Timestamp)
+	})
+	return note, nil
+}
+
+// AddNodeNoteHandler handles POST /nodes/{id}/notes.
+//
+// Returns 201 with the created note; the full list is included in the node JSON.
+func (qs *QueueService) AddNodeNoteHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/notes - Request", nodeID)
+
+	var req node.AddNoteRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/notes - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	note, err := qs.AddNodeNoteContext(r.Context(), nodeID, req.Author, req.Text)
+	if err != nil {
+		log.Printf("[API] POST /nodes/%s/notes - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/notes - SUCCESS: Note added by %s (took %v)", nodeID, note.Author, duration)
+	utils.RespondWithJSON(w, http.StatusCreated, note)
+}
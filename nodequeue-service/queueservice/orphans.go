@@ -0,0 +1,120 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// Actions reported by ReconcileOrphans.
+const (
+	OrphanCleared = "cleared"
+	OrphanMoved   = "moved"
+)
+
+// OrphanedNode describes one node repaired by ReconcileOrphans.
+type OrphanedNode struct {
+	NodeID string `json:"node_id"`
+	// MissingResourceID is the resource the node referenced that no longer exists.
+	MissingResourceID string `json:"missing_resource_id"`
+	Action            string `json:"action"`
+	// FallbackResourceID is set when Action is OrphanMoved.
+	FallbackResourceID string `json:"fallback_resource_id,omitempty"`
+}
+
+// ReconcileResponse is the response payload for POST /admin/reconcile.
+type ReconcileResponse struct {
+	Orphans []OrphanedNode `json:"orphans"`
+}
+
+// ReconcileOrphans is ReconcileOrphansContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ReconcileOrphans() []OrphanedNode {
+	return qs.ReconcileOrphansContext(context.Background())
+}
+
+// ReconcileOrphansContext repairs active nodes whose ResourceID points at a resource that no
+// longer exists (e.g. it was restored from the DB but is missing from the current config), which
+// would otherwise leave them stuck with ErrResourceNotFound.
+//
+// If OrphanFallbackResourceID names an existing resource that the orphan's AllowedResources permit,
+// it is moved to its waiting queue; otherwise its assignment is cleared and an "orphaned" log entry
+// is recorded. Results are
+// ordered by node ID.
+func (qs *QueueService) ReconcileOrphansContext(ctx context.Context) (orphans []OrphanedNode) {
+	ctx, span := startSpan(ctx, "QueueService.ReconcileOrphans")
+	defer func() { endSpan(span, nil) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	fallback, hasFallback := qs.resources[qs.OrphanFallbackResourceID]
+
+	orphans = make([]OrphanedNode, 0)
+	for _, n := range qs.nodes {
+		if n.Completed || n.ResourceID == "" {
+			continue
+		}
+		if _, exists := qs.resources[n.ResourceID]; exists {
+			continue
+		}
+
+		orphan := OrphanedNode{NodeID: n.ID, MissingResourceID: n.ResourceID}
+		if hasFallback && n.AllowsResource(fallback.ID) {
+			// AddNode overwrites ResourceID, so keep the missing resource for the audit trail.
+			fromRID := n.ResourceID
+			fallback.AddNode(n)
+			ts := qs.addNodeMoveLog(n, "moved_to_waiting_queue", fallback.ID, fromRID)
+			orphan.Action = OrphanMoved
+			orphan.FallbackResourceID = fallback.ID
+
+			rid := fallback.ID
+			qs.bestEffortPersist(ctx, "UpdateNodeResource(reconcile)", func(ctx context.Context) error {
+				return qs.store.UpdateNodeResource(ctx, n.ID, &rid)
+			})
+			qs.bestEffortPersist(ctx, "InsertNodeMoveLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+				return qs.store.InsertNodeMoveLog(ctx, n.ID, "moved_to_waiting_queue", &rid, &fromRID, ts)
+			})
+		} else {
+			ts := qs.addNodeLog(n, "orphaned", n.ResourceID)
+			n.ResourceID = ""
+			orphan.Action = OrphanCleared
+
+			qs.bestEffortPersist(ctx, "UpdateNodeResource(reconcile)", func(ctx context.Context) error {
+				return qs.store.UpdateNodeResource(ctx, n.ID, nil)
+			})
+			// The missing resource may not exist in the resources table either, so the persisted
+			// row carries no resource_id.
+			qs.bestEffortPersist(ctx, "InsertNodeLog(orphaned)", func(ctx context.Context) error {
+				return qs.store.InsertNodeLog(ctx, n.ID, "orphaned", nil, ts)
+			})
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].NodeID < orphans[j].NodeID })
+	if len(orphans) > 0 {
+		log.Printf("[QueueService] reconciled %d orphaned nodes", len(orphans))
+	}
+	return orphans
+}
+
+// ReconcileOrphansHandler handles POST /admin/reconcile.
+func (qs *QueueService) ReconcileOrphansHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] POST /admin/reconcile - Request")
+
+	orphans := qs.ReconcileOrphansContext(r.Context())
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /admin/reconcile - SUCCESS: Reconciled %d orphans (took %v)", len(orphans), duration)
+	utils.RespondWithJSON(w, http.StatusOK, ReconcileResponse{Orphans: orphans})
+}
@@ -0,0 +1,248 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// OutcomeSummary counts recorded completion outcomes.
+type OutcomeSummary struct {
+	Total     int            `json:"total"`
+	ByOutcome map[string]int `json:"by_outcome"`
+	// SuccessRate is success / (success + failure); cancelled nodes do not count against it. It is
+	// null when there are neither.
+	SuccessRate *float64 `json:"success_rate"`
+}
+
+// OutcomesResponse is the response payload for GET /nodes/outcomes: the overall summary plus one
+// per resource the nodes completed on.
+type OutcomesResponse struct {
+	OutcomeSummary
+	ByResource map[string]*OutcomeSummary `json:"by_resource"`
+}
+
+// completionOutcome is one node's recorded outcome, when and where it completed.
+type completionOutcome struct {
+	Outcome    string
+	ResourceID string
+	TS         time.Time
+}
+
+// outcomesFilter scopes GET /nodes/outcomes. Zero values leave that filter off.
+type outcomesFilter struct {
+	Since      time.Time
+	Until      time.Time
+	ResourceID string
+}
+
+func (f outcomesFilter) includes(c completionOutcome) bool {
+	if !f.Since.IsZero() && c.TS.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !c.TS.Before(f.Until) {
+		return false
+	}
+	return f.ResourceID == "" || c.ResourceID == f.ResourceID
+}
+
+// parseOutcomesFilter reads since/until (RFC 3339) and resource_id from the query string.
+func (qs *QueueService) parseOutcomesFilter(r *http.Request) (outcomesFilter, map[string]string) {
+	var f outcomesFilter
+	fields := make(map[string]string)
+	values := r.URL.Query()
+
+	for _, name := range []string{"since", "until"} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields[name] = "must be an RFC 3339 timestamp"
+			continue
+		}
+		if name == "since" {
+			f.Since = ts
+		} else {
+			f.Until = ts
+		}
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Until.After(f.Since) {
+		fields["until"] = "must be after since"
+	}
+	if raw := values.Get("resource_id"); raw != "" {
+		f.ResourceID = qs.LookupResourceID(raw)
+	}
+	return f, fields
+}
+
+func (s *OutcomeSummary) add(outcome string) {
+	s.Total++
+	s.ByOutcome[outcome]++
+}
+
+func (s *OutcomeSummary) finish() {
+	judged := s.ByOutcome[node.OutcomeSuccess] + s.ByOutcome[node.OutcomeFailure]
+	if judged > 0 {
+		rate := float64(s.ByOutcome[node.OutcomeSuccess]) / float64(judged)
+		s.SuccessRate = &rate
+	}
+}
+
+func newOutcomeSummary() *OutcomeSummary {
+	return &OutcomeSummary{ByOutcome: make(map[string]int)}
+}
+
+// summarizeOutcomes aggregates the outcomes f includes. Nodes that completed unassigned count in
+// the totals only.
+func summarizeOutcomes(outcomes []completionOutcome, f outcomesFilter) OutcomesResponse {
+	resp := OutcomesResponse{
+		OutcomeSummary: *newOutcomeSummary(),
+		ByResource:     make(map[string]*OutcomeSummary),
+	}
+	for _, c := range outcomes {
+		if !f.includes(c) {
+			continue
+		}
+		resp.add(c.Outcome)
+		if c.ResourceID == "" {
+			continue
+		}
+		rs, ok := resp.ByResource[c.ResourceID]
+		if !ok {
+			rs = newOutcomeSummary()
+			resp.ByResource[c.ResourceID] = rs
+		}
+		rs.add(c.Outcome)
+	}
+	resp.finish()
+	for _, rs := range resp.ByResource {
+		rs.finish()
+	}
+	return resp
+}
+
+// completionOutcomes returns every recorded completion outcome.
+//
+// With a store the persisted results are used, so nodes archived out of memory or not yet
+// reloaded after a restart still count; if the store fails it falls back to the nodes held in
+// memory.
+func (qs *QueueService) completionOutcomes(ctx context.Context) []completionOutcome {
+	if qs.store != nil {
+		outcomes, err := qs.completionOutcomesFromStore(ctx)
+		if err == nil {
+			return outcomes
+		}
+		log.Printf("[DB] completion outcomes failed (falling back to in-memory nodes): %v", err)
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	outcomes := make([]completionOutcome, 0)
+	for _, n := range qs.nodes {
+		if !n.Completed || n.Result == nil {
+			continue
+		}
+		c := completionOutcome{Outcome: n.Result.Outcome}
+		for _, entry := range n.Log {
+			if entry.Action == "completed" {
+				c.ResourceID, c.TS = entry.ResourceID, entry.Timestamp
+			}
+		}
+		outcomes = append(outcomes, c)
+	}
+	return outcomes
+}
+
+func (qs *QueueService) completionOutcomesFromStore(ctx context.Context) ([]completionOutcome, error) {
+	persisted, err := qs.store.ListAllNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs := make([]string, 0, len(persisted))
+	for _, pn := range persisted {
+		if pn.Completed {
+			nodeIDs = append(nodeIDs, pn.NodeID)
+		}
+	}
+	if len(nodeIDs) == 0 {
+		return []completionOutcome{}, nil
+	}
+
+	extras, err := qs.store.ListNodeExtras(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	withResult := make([]string, 0, len(extras))
+	for id, ex := range extras {
+		if ex.Result != nil {
+			withResult = append(withResult, id)
+		}
+	}
+	if len(withResult) == 0 {
+		return []completionOutcome{}, nil
+	}
+	logs, err := qs.store.ListNodeLogs(ctx, withResult)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]completionOutcome, 0, len(withResult))
+	for _, id := range withResult {
+		res := extras[id].Result
+		c := completionOutcome{Outcome: res.Outcome, TS: res.TS}
+		for _, row := range logs[id] {
+			if row.Action == "completed" && row.ResourceID != nil {
+				c.ResourceID = *row.ResourceID
+			}
+		}
+		outcomes = append(outcomes, c)
+	}
+	return outcomes, nil
+}
+
+// Outcomes aggregates recorded completion outcomes by outcome and by resource (see
+// completionOutcomes).
+func (qs *QueueService) Outcomes(ctx context.Context, since, until time.Time, resourceID string) OutcomesResponse {
+	f := outcomesFilter{Since: since, Until: until}
+	if resourceID != "" {
+		f.ResourceID = qs.LookupResourceID(resourceID)
+	}
+	return summarizeOutcomes(qs.completionOutcomes(ctx), f)
+}
+
+// OutcomesHandler handles GET /nodes/outcomes[?since=&until=&resource_id=].
+// since (inclusive) and until (exclusive) are RFC 3339 bounds on completion time.
+func (qs *QueueService) OutcomesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /nodes/outcomes - Request")
+
+	f, fields := qs.parseOutcomesFilter(r)
+	if len(fields) > 0 {
+		err := &utils.ValidationError{Fields: fields}
+		log.Printf("[API] GET /nodes/outcomes - ERROR: %v", err)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	resp := qs.Outcomes(r.Context(), f.Since, f.Until, f.ResourceID)
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/outcomes - SUCCESS: %d outcomes across %d resources (took %v)", resp.Total, len(resp.ByResource), duration)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
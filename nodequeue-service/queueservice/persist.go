@@ -0,0 +1,80 @@
+package queueservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+)
+
+// Persistence modes (PERSIST_MODE).
+const (
+	// PersistBestEffort logs failed store writes and keeps the in-memory change.
+	PersistBestEffort = "best_effort"
+	// PersistStrict fails node creation and completion when their critical write fails, leaving
+	// the in-memory state as it was (see criticalPersist).
+	PersistStrict = "strict"
+)
+
+// ParsePersistMode validates a persistence mode; empty means PersistBestEffort.
+func ParsePersistMode(raw string) (string, error) {
+	switch raw {
+	case "":
+		return PersistBestEffort, nil
+	case PersistBestEffort, PersistStrict:
+		return raw, nil
+	}
+	return "", fmt.Errorf("unknown persist mode %q (want %q or %q)", raw, PersistBestEffort, PersistStrict)
+}
+
+// criticalPersist is bestEffortPersist for the writes an audited deployment cannot lose:
+//
+//   - the node row written on creation (PersistNodeCreated, PersistNodeCreatedWithResource)
+//   - MarkNodeCompleted on CompleteNode
+//
+// In PersistStrict mode a failure is returned wrapped in ErrPersistFailed and the caller must
+// leave memory unchanged; otherwise it is only logged. Every other write (logs, notes, tags,
+// results, ...) is best-effort in both modes.
+//
+// db.ErrNodeExists is not a store failure: the node ID belongs to a node no longer in memory
+// (archived or evicted), so it is returned as ErrNodeExists in both modes.
+func (qs *QueueService) criticalPersist(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if qs.store == nil {
+		return nil
+	}
+	err := traceStore(ctx, op, fn)
+	if errors.Is(err, db.ErrNodeExists) {
+		qs.storeHealth.record(op, nil, time.Now())
+		return ErrNodeExists
+	}
+	qs.storeHealth.record(op, err, time.Now())
+	if err != nil {
+		log.Printf("[DB] %s failed: %v", op, err)
+		if qs.PersistMode == PersistStrict {
+			return fmt.Errorf("%w: %s: %v", ErrPersistFailed, op, err)
+		}
+	}
+	return nil
+}
+
+// discardCreatedLocked undoes createNodeLocked (and enqueueCreatedLocked) for a node whose
+// creation could not be persisted or was vetoed. Callers must hold qs.mu for writing.
+func (qs *QueueService) discardCreatedLocked(n *node.Node) {
+	if r, exists := qs.resources[n.ResourceID]; exists {
+		r.RemoveNode(n.ID)
+	}
+	delete(qs.nodes, n.ID)
+	qs.unindexActiveLocked(n)
+}
+
+// optionalString returns nil for "" and a pointer to s otherwise, for nullable store columns.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
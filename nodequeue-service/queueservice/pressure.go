@@ -0,0 +1,152 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// EventResourcePressure is the PressureEvent.Type sent to autoscalers.
+const EventResourcePressure = "resource_pressure"
+
+// PressureEvent signals that a resource has stayed full with a deep waiting queue.
+type PressureEvent struct {
+	Type            string    `json:"type"`
+	ResourceID      string    `json:"resource_id"`
+	Capacity        int       `json:"capacity"`
+	WaitingDepth    int       `json:"waiting_depth"`
+	Since           time.Time `json:"since"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// pressureState tracks one resource's ongoing pressure condition.
+type pressureState struct {
+	since time.Time
+	fired bool
+}
+
+// PressureMonitor watches resources for sustained pressure (full capacity and more than
+// PressureWaiting waiting nodes for PressureSeconds) and reports it to notify.
+//
+// Each sustained condition is reported once; the resource is re-armed only after the condition
+// clears. Now is the monitor's clock and may be replaced in tests.
+type PressureMonitor struct {
+	qs     *QueueService
+	notify func(PressureEvent)
+	Now    func() time.Time
+
+	mu    sync.Mutex
+	state map[string]*pressureState
+}
+
+// NewPressureMonitor returns a monitor for qs that reports events to notify.
+func NewPressureMonitor(qs *QueueService, notify func(PressureEvent)) *PressureMonitor {
+	return &PressureMonitor{
+		qs:     qs,
+		notify: notify,
+		Now:    time.Now,
+		state:  make(map[string]*pressureState),
+	}
+}
+
+// Check evaluates every resource once and returns the events it fired.
+func (m *PressureMonitor) Check() []PressureEvent {
+	now := m.Now()
+
+	type sample struct {
+		id        string
+		capacity  int
+		waiting   int
+		threshold int
+		window    time.Duration
+		full      bool
+	}
+	m.qs.mu.RLock()
+	samples := make([]sample, 0, len(m.qs.resources))
+	for id, r := range m.qs.resources {
+		if r.PressureWaiting <= 0 {
+			continue
+		}
+		_, waiting := r.QueueSnapshot()
+		samples = append(samples, sample{
+			id:        id,
+			capacity:  r.Capacity,
+			waiting:   len(waiting),
+			threshold: r.PressureWaiting,
+			window:    time.Duration(r.PressureSeconds) * time.Second,
+			full:      r.IsFull(),
+		})
+	}
+	m.qs.mu.RUnlock()
+
+	m.mu.Lock()
+	fired := make([]PressureEvent, 0)
+	seen := make(map[string]bool, len(samples))
+	for _, s := range samples {
+		seen[s.id] = true
+		if !s.full || s.waiting <= s.threshold {
+			delete(m.state, s.id)
+			continue
+		}
+		st, ok := m.state[s.id]
+		if !ok {
+			st = &pressureState{since: now}
+			m.state[s.id] = st
+		}
+		if st.fired || now.Sub(st.since) < s.window {
+			continue
+		}
+		st.fired = true
+		fired = append(fired, PressureEvent{
+			Type:            EventResourcePressure,
+			ResourceID:      s.id,
+			Capacity:        s.capacity,
+			WaitingDepth:    s.waiting,
+			Since:           st.since,
+			DurationSeconds: now.Sub(st.since).Seconds(),
+			Timestamp:       now,
+		})
+	}
+	// Forget resources that were removed or had the signal disabled.
+	for id := range m.state {
+		if !seen[id] {
+			delete(m.state, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, ev := range fired {
+		m.notify(ev)
+	}
+	return fired
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (m *PressureMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// PressureNotifier logs each event and, when hook is non-nil, posts it to the webhook.
+// Delivery failures are logged and not retried.
+func PressureNotifier(hook *Webhook) func(PressureEvent) {
+	return func(ev PressureEvent) {
+		log.Printf("[Pressure] resource %s full with %d waiting for %.0fs", ev.ResourceID, ev.WaitingDepth, ev.DurationSeconds)
+		if hook == nil {
+			return
+		}
+		if err := hook.Post(context.Background(), ev); err != nil {
+			log.Printf("[Pressure] webhook delivery failed: %v", err)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package queueservice
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"math"
 	"net/http"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -27,11 +30,99 @@ import (
 // Semantics:
 // - Moving/assigning a node to a resource places it into that resource's waiting queue.
 // - Allocation (waiting -> service) is where capacity is enforced.
+//
+// Context:
+//   - Mutations have an XxxContext(ctx, ...) form; handlers pass r.Context() so cancellation reaches
+//     the store. The plain Xxx(...) forms use context.Background().
 type QueueService struct {
 	resources map[string]*resource.Resource
 	nodes     map[string]*node.Node
 	store     db.Store
+	events    eventBus
 	mu        sync.RWMutex
+
+	// lastRestore is when RestoreFromStore or MergeFromStore last applied store state (guarded
+	// by mu).
+	lastRestore time.Time
+	// restoreSources records the outcome of every store read made by RestoreResourcesFromStore
+	// and RestoreFromStore (see RestoreStatus; guarded by mu).
+	restoreSources []RestoreSourceStatus
+
+	// activeNodes holds the IDs of non-completed nodes for ListActiveNodes, and activeByEntity
+	// indexes them by entity name for UniqueActiveEntity (both guarded by mu). The latter is kept
+	// up to date whether or not the mode is on.
+	activeNodes    map[string]bool
+	activeByEntity map[string]map[string]bool
+
+	// redirects maps a resource ID to the resource moves into it are sent to instead (see
+	// SetResourceRedirect; guarded by mu).
+	redirects map[string]string
+
+	// StrictLifecycle makes CompleteNode reject nodes that are not in a service queue, forcing
+	// the waiting -> service -> complete path. Set it before serving requests (STRICT_LIFECYCLE).
+	StrictLifecycle bool
+
+	// OrphanFallbackResourceID is where ReconcileOrphans moves nodes whose resource no longer
+	// exists. When empty (or unknown), their assignment is cleared instead (ORPHAN_FALLBACK_RESOURCE).
+	OrphanFallbackResourceID string
+
+	// OnComplete, when set, receives a CompletionEvent for every completed node. It is called on
+	// its own goroutine after the completion is applied (see CompletionNotifier).
+	OnComplete func(CompletionEvent)
+
+	// Retry controls how FailNode backs off and when it gives up. NewQueueService sets it to
+	// DefaultRetryPolicy; override it before serving requests (RETRY_* env vars).
+	Retry RetryPolicy
+
+	// Recommendation sets the defaults of GET /resources/{id}/recommendation. NewQueueService
+	// sets it to DefaultRecommendationPolicy (RECOMMEND_* env vars).
+	Recommendation RecommendationPolicy
+
+	// UniqueActiveEntity makes node creation fail with an *EntityActiveError while another
+	// non-completed node exists for the same entity name (UNIQUE_ACTIVE_ENTITY).
+	UniqueActiveEntity bool
+
+	// MaxMoves caps how many resource transitions a node may make via MoveNode or
+	// TransferAndAllocate; further moves return ErrMoveLimit. 0 means unlimited (MAX_MOVES).
+	MaxMoves int
+
+	// PersistMode is PersistBestEffort (the default, also when empty) or PersistStrict, which
+	// makes node creation and CompleteNode fail with ErrPersistFailed when their critical store
+	// write fails (PERSIST_MODE).
+	PersistMode string
+
+	// AdmissionFunc, when set, is consulted before every allocation into a service queue and can
+	// veto it (see AdmissionFunc). nil admits everything.
+	AdmissionFunc AdmissionFunc
+
+	// MaxNodesInMemory caps how many nodes are held in memory; creating a node beyond it evicts
+	// the oldest completed nodes (see evictOverCap). 0 means unlimited (MAX_NODES_IN_MEMORY).
+	MaxNodesInMemory int
+
+	// AllocationBatching coalesces concurrent AllocateNode, FillResource and auto-promotion calls
+	// under a single acquisition of mu (see withAllocLock). Set it before serving requests
+	// (ALLOCATION_BATCHING).
+	AllocationBatching bool
+	allocBatch         allocBatcher
+
+	// StoreDegradeAfter makes /readyz report degraded once this many store writes in a row have
+	// failed (see StoreHealth). 0 never degrades (STORE_DEGRADE_AFTER).
+	StoreDegradeAfter int
+	storeHealth       storeHealth
+
+	// RestoreStrict makes RestoreFromStore fail, restoring nothing, when any store read fails
+	// instead of restoring what it can (RESTORE_STRICT).
+	RestoreStrict bool
+
+	// FoldResourceIDCase makes resource IDs case-insensitive: they are registered and looked up
+	// lower-cased (see resource.CanonicalResourceID). Set it before adding resources
+	// (RESOURCE_ID_CASE=insensitive).
+	FoldResourceIDCase bool
+
+	// TimeFormat selects how node log timestamps are written to JSON: one of
+	// node.ValidTimeFormats, "" meaning node.TimeFormatRFC3339Nano (see node.Node.SetTimeFormat).
+	// Set it before creating or restoring nodes (TIME_FORMAT).
+	TimeFormat string
 }
 
 // NewQueueService constructs a QueueService with initialized maps.
@@ -43,9 +134,11 @@ func NewQueueService() *QueueService {
 // The store is used on a best-effort basis to avoid changing API behavior if the DB is down.
 func NewQueueServiceWithStore(store db.Store) *QueueService {
 	return &QueueService{
-		resources: make(map[string]*resource.Resource),
-		nodes:     make(map[string]*node.Node),
-		store:     store,
+		resources:      make(map[string]*resource.Resource),
+		nodes:          make(map[string]*node.Node),
+		store:          store,
+		Retry:          DefaultRetryPolicy,
+		Recommendation: DefaultRecommendationPolicy,
 	}
 }
 
@@ -53,186 +146,936 @@ func (qs *QueueService) bestEffortPersist(ctx context.Context, op string, fn fun
 	if qs.store == nil {
 		return
 	}
-	if err := fn(ctx); err != nil {
+	err := traceStore(ctx, op, fn)
+	qs.storeHealth.record(op, err, time.Now())
+	if err != nil {
 		log.Printf("[DB] %s failed: %v", op, err)
 	}
 }
 
-// AddResource registers a Resource by ID, replacing any existing entry with the same ID.
+// canonicalResourceID is resource.CanonicalResourceID with FoldResourceIDCase.
+func (qs *QueueService) canonicalResourceID(id string) (string, error) {
+	return resource.CanonicalResourceID(id, qs.FoldResourceIDCase)
+}
+
+// LookupResourceID returns the ID a resource named resourceID is registered under (see
+// resource.LookupID), honouring FoldResourceIDCase.
+func (qs *QueueService) LookupResourceID(resourceID string) string {
+	return resource.LookupID(resourceID, qs.FoldResourceIDCase)
+}
+
+// AddResource registers a Resource under its canonical ID (see canonicalResourceID, which r.ID
+// is rewritten to), replacing any existing entry with the same ID. A resource whose ID
+// has no canonical form is logged and not registered.
 func (qs *QueueService) AddResource(r *resource.Resource) {
+	id, err := qs.canonicalResourceID(r.ID)
+	if err != nil {
+		log.Printf("[QueueService] not adding resource %q: %v", r.ID, err)
+		return
+	}
+	r.ID = id
+
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.resources[r.ID] = r
 }
 
-// CreateNode creates and stores a new node for the provided entity name.
-// The node is created unassigned (ResourceID empty) and includes an initial "created" log entry.
+// CreateResource is CreateResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateResource(r *resource.Resource) error {
+	return qs.CreateResourceContext(context.Background(), r)
+}
+
+// CreateResourceContext registers a new resource under its canonical ID and persists it.
+// Unlike AddResource it refuses to replace an existing resource with the same ID, and returns an
+// error wrapping resource.ErrInvalidResourceID for an ID with no canonical form.
+func (qs *QueueService) CreateResourceContext(ctx context.Context, r *resource.Resource) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.CreateResource", attrResourceID.String(r.ID))
+	defer func() { endSpan(span, err) }()
+
+	id, err := qs.canonicalResourceID(r.ID)
+	if err != nil {
+		return err
+	}
+	r.ID = id
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	if _, exists := qs.resources[r.ID]; exists {
+		return ErrResourceExists
+	}
+
+	qs.resources[r.ID] = r
+
+	// Persist resource definition, settings and labels (best-effort).
+	config := r.CloneConfig(r.ID)
+	qs.bestEffortPersist(ctx, "InsertResource", func(ctx context.Context) error {
+		return qs.store.InsertResource(ctx, config)
+	})
+
+	return nil
+}
+
+// CreateNode is CreateNodeContext with context.Background(), for non-HTTP callers.
 func (qs *QueueService) CreateNode(entityName string) (*node.Node, error) {
+	return qs.CreateNodeContext(context.Background(), entityName)
+}
+
+// CreateNodeContext creates and stores a new node for the provided entity name.
+// The node is created unassigned (ResourceID empty) and includes an initial "created" log entry.
+func (qs *QueueService) CreateNodeContext(ctx context.Context, entityName string) (*node.Node, error) {
+	return qs.CreateNodeWithIDContext(ctx, "", entityName)
+}
+
+// CreateNodeWithID is CreateNodeWithIDContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateNodeWithID(nodeID, entityName string) (*node.Node, error) {
+	return qs.CreateNodeWithIDContext(context.Background(), nodeID, entityName)
+}
+
+// CreateNodeWithIDContext is CreateNodeContext with a caller-chosen node ID. An empty nodeID
+// generates a UUID. Returns ErrNodeExists if a node with that ID is in memory or already
+// persisted (e.g. since archived or evicted); callers are expected to have validated the ID (see
+// node.ValidID).
+func (qs *QueueService) CreateNodeWithIDContext(ctx context.Context, nodeID, entityName string) (*node.Node, error) {
+	return qs.CreateWeightedNodeContext(ctx, nodeID, entityName, 1)
+}
+
+// CreateWeightedNode is CreateWeightedNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateWeightedNode(nodeID, entityName string, weight int) (*node.Node, error) {
+	return qs.CreateWeightedNodeContext(context.Background(), nodeID, entityName, weight)
+}
+
+// CreateWeightedNodeContext is CreateNodeWithIDContext for a node that consumes weight capacity
+// units while in service. Weights below 1 are stored as 1.
+func (qs *QueueService) CreateWeightedNodeContext(ctx context.Context, nodeID, entityName string, weight int) (*node.Node, error) {
+	return qs.CreateTaggedNodeContext(ctx, nodeID, entityName, weight, nil, nil)
+}
+
+// CreateTaggedNode is CreateTaggedNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateTaggedNode(nodeID, entityName string, weight int, tags, allowedResources []string) (*node.Node, error) {
+	return qs.CreateTaggedNodeContext(context.Background(), nodeID, entityName, weight, tags, allowedResources)
+}
+
+// CreateTaggedNodeContext is CreateWeightedNodeContext for a node that starts with tags (see
+// AddNodeTags); callers are expected to have validated them (see node.ValidTag). A non-empty
+// allowedResources restricts which resources the node may later be moved to (see
+// node.Node.AllowedResources).
+func (qs *QueueService) CreateTaggedNodeContext(ctx context.Context, nodeID, entityName string, weight int, tags, allowedResources []string) (*node.Node, error) {
+	return qs.createTaggedNode(ctx, nodeID, entityName, weight, tags, allowedResources, nil)
+}
+
+// createTaggedNode is CreateTaggedNodeContext for a node created with a deadline (nil for none;
+// see SetNodeDeadline), set under the same lock as the rest of the node.
+func (qs *QueueService) createTaggedNode(ctx context.Context, nodeID, entityName string, weight int, tags, allowedResources []string, deadline *time.Time) (_ *node.Node, err error) {
+	ctx, span := startSpan(ctx, "QueueService.CreateNode")
+	defer func() { endSpan(span, err) }()
+	defer qs.evictOverCap(ctx) // runs after the unlock below
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	node, err := qs.createNodeLocked(nodeID, entityName, weight)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attrNodeID.String(node.ID))
+
+	if err := qs.persistNodeCreated(ctx, node); err != nil {
+		qs.discardCreatedLocked(node)
+		return nil, err
+	}
+	qs.addTagsLocked(ctx, node, tags)
+	qs.setAllowedResourcesLocked(ctx, node, allowedResources)
+	qs.setDeadlineLocked(ctx, node, deadline)
+	return node, nil
+}
+
+// CreateNodeOnResource is CreateNodeOnResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateNodeOnResource(nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string) (*node.Node, error) {
+	return qs.CreateNodeOnResourceContext(context.Background(), nodeID, entityName, weight, resourceID, tags, allowedResources)
+}
+
+// CreateNodeOnResourceContext is CreateWeightedNodeContext followed by a move into resourceID's
+// waiting queue (default lane), applied under one lock and persisted with a single
+// Store.PersistNodeCreatedWithResource so the audit trail cannot hold the creation without the
+// assignment.
+//
+// If the resource does not exist, or allowedResources excludes it, the node is still created,
+// unassigned, and returned together with the move error, matching a CreateNode followed by a
+// failed MoveNode. tags and allowedResources (nil for none) are applied as in
+// CreateTaggedNodeContext.
+func (qs *QueueService) CreateNodeOnResourceContext(ctx context.Context, nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string) (*node.Node, error) {
+	return qs.createNodeOnResource(ctx, nodeID, entityName, weight, resourceID, tags, allowedResources, nil)
+}
+
+// createNodeOnResource is CreateNodeOnResourceContext for a node created with a deadline (nil for
+// none; see SetNodeDeadline).
+func (qs *QueueService) createNodeOnResource(ctx context.Context, nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string, deadline *time.Time) (_ *node.Node, err error) {
+	ctx, span := startSpan(ctx, "QueueService.CreateNode", attrTargetResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+	defer qs.evictOverCap(ctx) // runs after the unlock below
+
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 
+	resourceID = qs.LookupResourceID(resourceID)
+	target, exists := qs.resources[resourceID]
+
+	node, err := qs.createNodeLocked(nodeID, entityName, weight)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attrNodeID.String(node.ID))
+
+	node.SetAllowedResources(allowedResources)
+	if !exists || !node.AllowsResource(resourceID) {
+		if err := qs.persistNodeCreated(ctx, node); err != nil {
+			qs.discardCreatedLocked(node)
+			return nil, err
+		}
+		qs.addTagsLocked(ctx, node, tags)
+		qs.setAllowedResourcesLocked(ctx, node, allowedResources)
+		qs.setDeadlineLocked(ctx, node, deadline)
+		if !exists {
+			return node, fmt.Errorf("target %w", ErrResourceNotFound)
+		}
+		return node, checkAllowedResource(node, resourceID)
+	}
+
+	if err := qs.enqueueCreatedLocked(ctx, node, target, tags, allowedResources, deadline); err != nil {
+		qs.discardCreatedLocked(node)
+		return nil, err
+	}
+	return node, nil
+}
+
+// enqueueCreatedLocked puts a node fresh from createNodeLocked into target's waiting queue and
+// persists the creation and assignment together, followed by its tags, allowed resources and
+// deadline.
+// It returns the critical write's error (see criticalPersist), in which case nothing else is
+// persisted and the caller should discard the node. Callers must hold qs.mu for writing.
+func (qs *QueueService) enqueueCreatedLocked(ctx context.Context, node *node.Node, target *resource.Resource, tags, allowedResources []string, deadline *time.Time) error {
+	target.AddNode(node)
+	assignedAt := qs.addNodeLog(node, "moved_to_waiting_queue", target.ID)
+
+	// Persist node, assignment and both log entries in one transaction (best-effort).
+	entityID := uuid.New().String()
+	entityName, resourceID := node.Entity.Name, target.ID
+	createdAt := node.CreatedAt
+	if err := qs.criticalPersist(ctx, "PersistNodeCreatedWithResource", func(ctx context.Context) error {
+		return qs.store.PersistNodeCreatedWithResource(ctx, node.ID, entityID, entityName, node.Weight, createdAt, resourceID, assignedAt)
+	}); err != nil {
+		return err
+	}
+	qs.addTagsLocked(ctx, node, tags)
+	qs.setAllowedResourcesLocked(ctx, node, allowedResources)
+	qs.setDeadlineLocked(ctx, node, deadline)
+	return nil
+}
+
+// createNodeLocked builds a node with its "created" log entry and registers it. An empty nodeID
+// generates a UUID; others are canonicalized (see node.CanonicalID). With UniqueActiveEntity it
+// fails if the entity already has an active node. Callers must hold qs.mu for writing.
+func (qs *QueueService) createNodeLocked(nodeID, entityName string, weight int) (*node.Node, error) {
+	if nodeID == "" {
+		nodeID = uuid.New().String()
+	} else if nodeID = node.CanonicalID(nodeID); qs.nodes[nodeID] != nil {
+		return nil, ErrNodeExists
+	}
+	if err := qs.checkUniqueEntityLocked(entityName); err != nil {
+		return nil, err
+	}
+
 	node := &node.Node{
-		ID:        uuid.New().String(),
+		ID:        nodeID,
 		Entity:    &node.Entity{Name: entityName},
 		Completed: false,
-		CreatedAt: time.Now(),
+		Weight:    max(weight, 1),
 	}
-	node.AddLog("created", "")
+	node.SetTimeFormat(qs.TimeFormat)
+	node.CreatedAt = qs.addNodeLog(node, "created", "")
 
 	qs.nodes[node.ID] = node
+	qs.indexActiveLocked(node)
+	return node, nil
+}
 
-	// Persist audit trail (best-effort).
-	ctx := context.Background()
+// persistNodeCreated writes a new, unassigned node and its "created" log entry. It returns the
+// node row's error (see criticalPersist), in which case the log entry is not written; the log
+// entry itself is best-effort.
+func (qs *QueueService) persistNodeCreated(ctx context.Context, node *node.Node) error {
 	entityID := uuid.New().String()
+	entityName := node.Entity.Name
 	createdAt := node.CreatedAt
-	qs.bestEffortPersist(ctx, "PersistNodeCreated", func(ctx context.Context) error {
-		return qs.store.PersistNodeCreated(ctx, node.ID, entityID, entityName, createdAt)
-	})
+	if err := qs.criticalPersist(ctx, "PersistNodeCreated", func(ctx context.Context) error {
+		return qs.store.PersistNodeCreated(ctx, node.ID, entityID, entityName, node.Weight, createdAt)
+	}); err != nil {
+		return err
+	}
 	qs.bestEffortPersist(ctx, "InsertNodeLog(created)", func(ctx context.Context) error {
 		return qs.store.InsertNodeLog(ctx, node.ID, "created", nil, createdAt)
 	})
+	return nil
+}
 
-	return node, nil
+// MoveNode is MoveNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) MoveNode(nodeID, targetResourceID string) error {
+	return qs.MoveNodeContext(context.Background(), nodeID, targetResourceID)
 }
 
-// MoveNode assigns a node to a target resource.
+// MoveNodeContext assigns a node to a target resource.
 //
 // If the node was already assigned to another resource, it is removed from that resource
 // (both waiting and service queues are searched).
 //
-// The node is always enqueued into the target resource's waiting queue; capacity is not checked here.
-func (qs *QueueService) MoveNode(nodeID, targetResourceID string) error {
+// The node is always enqueued into the target resource's waiting queue (default lane); capacity
+// is not checked here. With MaxMoves set, a move onto a different resource fails with
+// ErrMoveLimit once the node has used up its moves. A target outside the node's AllowedResources
+// fails with ErrResourceNotAllowed. A target with a redirect (see SetResourceRedirect) is replaced
+// by the redirect's target before any of these checks, and a "redirected" log entry records the
+// original target.
+func (qs *QueueService) MoveNodeContext(ctx context.Context, nodeID, targetResourceID string) error {
+	return qs.MoveNodeToLaneContext(ctx, nodeID, targetResourceID, "")
+}
+
+// MoveNodeToLane is MoveNodeToLaneContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) MoveNodeToLane(nodeID, targetResourceID, lane string) error {
+	return qs.MoveNodeToLaneContext(context.Background(), nodeID, targetResourceID, lane)
+}
+
+// MoveNodeToLaneContext is MoveNodeContext with an explicit waiting lane on the target resource
+// ("" means resource.DefaultLane). A lane the target resource does not define (see
+// resource.Resource.HasLane) returns ErrUnknownLane.
+func (qs *QueueService) MoveNodeToLaneContext(ctx context.Context, nodeID, targetResourceID, lane string) error {
+	return qs.MoveNodeIfContext(ctx, nodeID, "", targetResourceID, lane)
+}
+
+// MoveNodeIf is MoveNodeIfContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) MoveNodeIf(nodeID, fromResourceID, targetResourceID, lane string) error {
+	return qs.MoveNodeIfContext(context.Background(), nodeID, fromResourceID, targetResourceID, lane)
+}
+
+// MoveNodeIfContext is MoveNodeToLaneContext that only moves the node if it is currently on
+// fromResourceID, so a client acting on stale state cannot move a node someone else already moved.
+// Otherwise it returns an error wrapping ErrResourceMismatch and naming the node's actual resource.
+// An empty fromResourceID moves unconditionally.
+func (qs *QueueService) MoveNodeIfContext(ctx context.Context, nodeID, fromResourceID, targetResourceID, lane string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.MoveNode", attrNodeID.String(nodeID), attrTargetResourceID.String(targetResourceID))
+	defer func() { endSpan(span, err) }()
+
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 
 	node, exists := qs.nodes[nodeID]
 	if !exists {
-		return errors.New("node not found")
+		return ErrNodeNotFound
 	}
+	if fromResourceID != "" && node.ResourceID != fromResourceID {
+		return fmt.Errorf("%w: node is on %q, not %q", ErrResourceMismatch, node.ResourceID, fromResourceID)
+	}
+	return qs.moveLocked(ctx, node, targetResourceID, lane)
+}
 
+// moveLocked is MoveNodeToLane without locking or the node lookup. Callers must hold qs.mu for
+// writing.
+func (qs *QueueService) moveLocked(ctx context.Context, node *node.Node, targetResourceID, lane string) error {
 	if node.Completed {
-		return errors.New("cannot move completed node")
+		return fmt.Errorf("cannot move node: %w", ErrNodeCompleted)
+	}
+
+	targetResourceID = qs.LookupResourceID(targetResourceID)
+	requestedResourceID := targetResourceID
+	if to, ok := qs.redirects[targetResourceID]; ok {
+		targetResourceID = to
 	}
 
 	targetResource, exists := qs.resources[targetResourceID]
 	if !exists {
-		return errors.New("target resource not found")
+		return fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+
+	if err := checkAllowedResource(node, targetResourceID); err != nil {
+		return err
+	}
+	if !targetResource.HasLane(lane) {
+		return fmt.Errorf("%w: %q on %q", ErrUnknownLane, lane, targetResourceID)
+	}
+
+	if node.ResourceID != targetResourceID {
+		if err := qs.checkMoveLimit(node); err != nil {
+			return err
+		}
 	}
 
 	// Remove from current resource if it exists
-	if node.ResourceID != "" {
-		if currentResource, exists := qs.resources[node.ResourceID]; exists {
-			currentResource.RemoveNode(nodeID)
+	fromResourceID := node.ResourceID
+	if fromResourceID != "" {
+		if currentResource, exists := qs.resources[fromResourceID]; exists {
+			currentResource.RemoveNode(node.ID)
 		}
 	}
 
+	if requestedResourceID != targetResourceID {
+		ts := qs.addNodeLog(node, "redirected", requestedResourceID)
+		qs.bestEffortPersist(ctx, "InsertNodeLog(redirected)", func(ctx context.Context) error {
+			return qs.store.InsertNodeLog(ctx, node.ID, "redirected", &requestedResourceID, ts)
+		})
+	}
+
 	// Assign to target resource (always goes to waiting queue)
-	targetResource.AddNode(node)
-	node.AddLog("moved_to_waiting_queue", targetResourceID)
+	targetResource.AddNodeToLane(node, lane)
+	ts := qs.addNodeMoveLog(node, "moved_to_waiting_queue", targetResourceID, fromResourceID)
 
 	// Persist audit trail (best-effort).
-	ctx := context.Background()
 	rid := targetResourceID
 	qs.bestEffortPersist(ctx, "UpdateNodeResource(move)", func(ctx context.Context) error {
 		return qs.store.UpdateNodeResource(ctx, node.ID, &rid)
 	})
-	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_waiting_queue)", func(ctx context.Context) error {
-		return qs.store.InsertNodeLog(ctx, node.ID, "moved_to_waiting_queue", &rid, time.Now())
+	qs.bestEffortPersist(ctx, "InsertNodeMoveLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeMoveLog(ctx, node.ID, "moved_to_waiting_queue", &rid, optionalString(fromResourceID), ts)
 	})
 
 	return nil
 }
 
-// AllocateNode promotes a node from its resource waiting queue into the service queue.
+// checkAllocatable runs the allocation preconditions for a node without mutating state.
+// Callers must hold qs.mu (read or write).
+//
+// It is shared by AllocateNode and CanAllocate so the dry run and the real call cannot diverge.
+func (qs *QueueService) checkAllocatable(nodeID string) (*node.Node, *resource.Resource, error) {
+	node, exists := qs.nodes[nodeID]
+	if !exists {
+		return nil, nil, ErrNodeNotFound
+	}
+
+	if node.Completed {
+		return nil, nil, fmt.Errorf("cannot allocate node: %w", ErrNodeCompleted)
+	}
+
+	if node.ResourceID == "" {
+		return nil, nil, ErrNodeNotAssigned
+	}
+
+	resource, exists := qs.resources[node.ResourceID]
+	if !exists {
+		return nil, nil, ErrResourceNotFound
+	}
+
+	// Ensure node is currently in the waiting queue, and enforce capacity on promotion to service
+	if resource.IsInService(nodeID) {
+		return nil, nil, ErrNodeInService
+	}
+
+	if resource.IsPaused() {
+		return nil, nil, ErrResourcePaused
+	}
+
+	if resource.IsFull() {
+		return nil, nil, ErrCapacityFull
+	}
+
+	if !resource.IsWaiting(nodeID) {
+		return nil, nil, ErrNodeNotWaiting
+	}
+
+	if resource.FIFOStrict && !resource.IsWaitingHead(nodeID) {
+		return nil, nil, ErrNotQueueHead
+	}
+
+	if available := resource.GetAvailableCapacity(); node.CapacityWeight() > available {
+		return nil, nil, fmt.Errorf("node weight %d exceeds remaining capacity %d: %w", node.CapacityWeight(), available, ErrCapacityFull)
+	}
+
+	if available := resource.AvailableCapacityForLane(resource.LaneOf(nodeID)); node.CapacityWeight() > available {
+		return nil, nil, fmt.Errorf("remaining capacity is reserved for the %s lane: %w", resource.PriorityLane(), ErrCapacityFull)
+	}
+
+	if node.NotBeforeTS != nil && time.Now().Before(*node.NotBeforeTS) {
+		return nil, nil, ErrNodeBackingOff
+	}
+
+	if node.Entity != nil && resource.EntityAtLimit(node.Entity.Name) {
+		return nil, nil, ErrEntityLimit
+	}
+
+	// Only peeked here so CanAllocate does not use up a token; allocateLocked takes it.
+	if !resource.AllocationAvailable(time.Now()) {
+		return nil, nil, ErrAllocationRateExceeded
+	}
+
+	return node, resource, nil
+}
+
+// CanAllocate reports whether AllocateNode would currently succeed for the node.
+// It returns nil if allocation is allowed, otherwise the error AllocateNode would return.
+func (qs *QueueService) CanAllocate(nodeID string) error {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	_, _, err := qs.checkAllocatable(nodeID)
+	return err
+}
+
+// DrainResource is DrainResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) DrainResource(fromID, toID string, includeService bool) (int, error) {
+	return qs.DrainResourceContext(context.Background(), fromID, toID, includeService)
+}
+
+// DrainResourceContext relocates every waiting node (and, if includeService, every service node) from
+// one resource to another in a single atomic step, preserving relative order.
+//
+// Drained nodes land in the target's waiting queue like any other move, so target capacity is
+// not checked here. Nodes whose AllowedResources exclude the target stay on the source. Each
+// relocation is logged and persisted as "moved_to_waiting_queue".
+func (qs *QueueService) DrainResourceContext(ctx context.Context, fromID, toID string, includeService bool) (_ int, err error) {
+	ctx, span := startSpan(ctx, "QueueService.DrainResource", attrResourceID.String(fromID), attrTargetResourceID.String(toID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	fromID, toID = qs.LookupResourceID(fromID), qs.LookupResourceID(toID)
+	from, exists := qs.resources[fromID]
+	if !exists {
+		return 0, ErrResourceNotFound
+	}
+
+	to, exists := qs.resources[toID]
+	if !exists {
+		return 0, fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+
+	if fromID == toID {
+		return 0, ErrSameResource
+	}
+
+	moved := resource.TransferNodes(from, to, includeService, func(n *node.Node) bool { return n.AllowsResource(toID) })
+
+	rid, fromRID := toID, fromID
+	for _, n := range moved {
+		ts := qs.addNodeMoveLog(n, "moved_to_waiting_queue", toID, fromID)
+
+		// Persist audit trail (best-effort).
+		nodeID := n.ID
+		qs.bestEffortPersist(ctx, "UpdateNodeResource(drain)", func(ctx context.Context) error {
+			return qs.store.UpdateNodeResource(ctx, nodeID, &rid)
+		})
+		qs.bestEffortPersist(ctx, "InsertNodeMoveLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+			return qs.store.InsertNodeMoveLog(ctx, nodeID, "moved_to_waiting_queue", &rid, &fromRID, ts)
+		})
+	}
+
+	return len(moved), nil
+}
+
+// AllocateNode is AllocateNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) AllocateNode(nodeID string) error {
+	return qs.AllocateNodeContext(context.Background(), nodeID)
+}
+
+// AllocateNodeContext promotes a node from its resource waiting queue into the service queue.
 //
 // Errors include:
 // - node/resource not found
 // - node not assigned to a resource
 // - node already in service queue
-// - resource at full capacity
+// - resource at full capacity, or the node's weight exceeds the remaining capacity
 // - node not present in the waiting queue
-func (qs *QueueService) AllocateNode(nodeID string) error {
+// - the node's entity already has MaxPerEntity nodes in service on the resource
+// - the resource is paused
+// - the resource is FIFOStrict and the node is not at the head of the waiting queue
+// - the node is still backing off after a failed attempt (see FailNode)
+// - AdmissionFunc rejected the allocation (ErrAdmissionDenied)
+// - the resource's AllocRatePerSec has been used up (ErrAllocationRateExceeded)
+func (qs *QueueService) AllocateNodeContext(ctx context.Context, nodeID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.AllocateNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	qs.withAllocLock(func() { err = qs.allocateLocked(ctx, nodeID) })
+	return err
+}
+
+// allocateLocked is AllocateNode without locking. Callers must hold qs.mu for writing.
+func (qs *QueueService) allocateLocked(ctx context.Context, nodeID string) error {
+	node, resource, err := qs.checkAllocatable(nodeID)
+	if err != nil {
+		return err
+	}
+	if err := qs.admitLocked(ctx, node, resource); err != nil {
+		return err
+	}
+	if !resource.AllowAllocation(time.Now()) {
+		return ErrAllocationRateExceeded
+	}
+	return qs.promoteLocked(ctx, node, resource)
+}
+
+// promoteLocked moves node from resource's waiting queue into its service queue and records it,
+// without running the allocation checks. Callers must hold qs.mu for writing.
+func (qs *QueueService) promoteLocked(ctx context.Context, node *node.Node, resource *resource.Resource) error {
+	if ok := resource.AllocateWaitingNode(node.ID); !ok {
+		return ErrNodeNotWaiting
+	}
+
+	ts := qs.addNodeLog(node, "moved_to_service_queue", node.ResourceID)
+
+	// Persist audit trail (best-effort).
+	rid := node.ResourceID
+	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_service_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, node.ID, "moved_to_service_queue", &rid, ts)
+	})
+	return nil
+}
+
+// ReorderWaitingNode is ReorderWaitingNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ReorderWaitingNode(nodeID string, position int) error {
+	return qs.ReorderWaitingNodeContext(context.Background(), nodeID, position)
+}
+
+// ReorderWaitingNodeContext repositions a node within its current resource's waiting queue.
+//
+// Position is zero-based and clamped to the queue bounds. Nodes in the service queue cannot be
+// reordered. A "reordered" log entry is recorded on success.
+func (qs *QueueService) ReorderWaitingNodeContext(ctx context.Context, nodeID string, position int) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.ReorderWaitingNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	return qs.repositionWaiting(ctx, nodeID, position, "reordered")
+}
+
+// ExpediteNode is ExpediteNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ExpediteNode(nodeID string) error {
+	return qs.ExpediteNodeContext(context.Background(), nodeID)
+}
+
+// ExpediteNodeContext moves a waiting node to the front of its resource's waiting queue and
+// records an "expedited" log entry. It is a shortcut for reordering to position 0.
+func (qs *QueueService) ExpediteNodeContext(ctx context.Context, nodeID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.ExpediteNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	return qs.repositionWaiting(ctx, nodeID, 0, "expedited")
+}
+
+// DeferNode is DeferNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) DeferNode(nodeID string) error {
+	return qs.DeferNodeContext(context.Background(), nodeID)
+}
+
+// DeferNodeContext moves a waiting node to the back of its resource's waiting queue and records
+// a "deferred" log entry.
+func (qs *QueueService) DeferNodeContext(ctx context.Context, nodeID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.DeferNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	// Positions past the end are clamped to the last slot.
+	return qs.repositionWaiting(ctx, nodeID, math.MaxInt, "deferred")
+}
+
+// repositionWaiting moves a waiting node to position in its resource's waiting queue and logs
+// action. It backs reorder, expedite and defer so they share the same preconditions.
+func (qs *QueueService) repositionWaiting(ctx context.Context, nodeID string, position int, action string) error {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 
 	node, exists := qs.nodes[nodeID]
 	if !exists {
-		return errors.New("node not found")
+		return ErrNodeNotFound
 	}
 
 	if node.Completed {
-		return errors.New("cannot allocate completed node")
+		return fmt.Errorf("cannot reposition node: %w", ErrNodeCompleted)
 	}
 
 	if node.ResourceID == "" {
-		return errors.New("node is not assigned to a resource")
+		return ErrNodeNotAssigned
 	}
 
 	resource, exists := qs.resources[node.ResourceID]
 	if !exists {
-		return errors.New("resource not found")
+		return ErrResourceNotFound
 	}
 
-	// Ensure node is currently in the waiting queue, and enforce capacity on promotion to service
 	if resource.IsInService(nodeID) {
-		return errors.New("node is already in service queue")
+		return ErrNodeInService
 	}
 
-	if resource.IsFull() {
-		return errors.New("resource is at full capacity")
-	}
-
-	if ok := resource.AllocateWaitingNode(nodeID); !ok {
-		return errors.New("node is not in waiting queue")
+	if err := resource.ReorderWaitingNode(node.ResourceID, nodeID, position); err != nil {
+		return err
 	}
 
-	node.AddLog("moved_to_service_queue", node.ResourceID)
+	ts := qs.addNodeLog(node, action, node.ResourceID)
 
 	// Persist audit trail (best-effort).
-	ctx := context.Background()
 	rid := node.ResourceID
-	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_service_queue)", func(ctx context.Context) error {
-		return qs.store.InsertNodeLog(ctx, node.ID, "moved_to_service_queue", &rid, time.Now())
+	qs.bestEffortPersist(ctx, "InsertNodeLog("+action+")", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, node.ID, action, &rid, ts)
 	})
 	return nil
 }
 
-// CompleteNode marks a node as completed and removes it from any resource queues.
-// Completed nodes cannot be moved or allocated again.
+// inService reports whether n is currently in its resource's service queue.
+// Callers must hold qs.mu (read or write).
+func (qs *QueueService) inService(n *node.Node) bool {
+	if n.ResourceID == "" {
+		return false
+	}
+	resource, exists := qs.resources[n.ResourceID]
+	return exists && resource.IsInService(n.ID)
+}
+
+// CompleteNode is CompleteNodeContext with context.Background(), for non-HTTP callers.
 func (qs *QueueService) CompleteNode(nodeID string) error {
+	return qs.CompleteNodeContext(context.Background(), nodeID)
+}
+
+// CompleteNodeContext marks a node as completed and removes it from any resource queues.
+// Completed nodes cannot be moved or allocated again.
+//
+// If the node was in service on a resource with AutoPromote enabled, the next waiting node on
+// that resource is promoted once the completion has been applied.
+//
+// With StrictLifecycle set, only nodes currently in service can be completed; others return
+// ErrNodeNotInService. With PersistMode PersistStrict, a failure to store the completion returns
+// ErrPersistFailed and leaves the node as it was.
+func (qs *QueueService) CompleteNodeContext(ctx context.Context, nodeID string) error {
+	return qs.CompleteNodeWithResultContext(ctx, nodeID, nil)
+}
+
+// CompleteNodeWithResult is CompleteNodeWithResultContext with context.Background(), for non-HTTP
+// callers.
+func (qs *QueueService) CompleteNodeWithResult(nodeID string, result *node.NodeResult) error {
+	return qs.CompleteNodeWithResultContext(context.Background(), nodeID, result)
+}
+
+// CompleteNodeWithResultContext is CompleteNodeContext that also records the node's outcome.
+// result (nil for none) is stored on the node, persisted to the store and included in the
+// CompletionEvent; callers are expected to have validated it (see node.CompleteNodeRequest).
+func (qs *QueueService) CompleteNodeWithResultContext(ctx context.Context, nodeID string, result *node.NodeResult) error {
+	return qs.CompleteNodeWithTokenContext(ctx, nodeID, result, "")
+}
+
+// CompleteNodeWithToken is CompleteNodeWithTokenContext with context.Background(), for non-HTTP
+// callers.
+func (qs *QueueService) CompleteNodeWithToken(nodeID string, result *node.NodeResult, token string) error {
+	return qs.CompleteNodeWithTokenContext(context.Background(), nodeID, result, token)
+}
+
+// CompleteNodeWithTokenContext is CompleteNodeWithResultContext with an idempotency token. The
+// token is stored on the node and persisted; completing it again with the same token returns nil
+// without changing anything, so a client can retry a completion whose response it lost. This
+// holds after the node has left memory (restart, archive or eviction) too, as long as the store
+// still has it. A different (or no) token on a completed node still returns ErrNodeCompleted.
+func (qs *QueueService) CompleteNodeWithTokenContext(ctx context.Context, nodeID string, result *node.NodeResult, token string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.CompleteNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	freedResourceID, ev, replayed, err := qs.completeNode(ctx, nodeID, result, token)
+	if errors.Is(err, ErrNodeNotFound) && token != "" && qs.completedWithToken(ctx, nodeID, token) {
+		return nil
+	}
+	if err != nil || replayed {
+		return err
+	}
+	if qs.OnComplete != nil {
+		go qs.OnComplete(ev)
+	}
+	if freedResourceID != "" {
+		qs.autoPromote(ctx, freedResourceID)
+	}
+	return nil
+}
+
+// completeNode applies the completion under qs.mu and returns the resource ID whose service slot
+// was freed (empty if the node was not in service) along with the node's CompletionEvent.
+// replayed reports a repeat of a completion made with the same non-empty token, in which case
+// nothing was changed.
+func (qs *QueueService) completeNode(ctx context.Context, nodeID string, result *node.NodeResult, token string) (_ string, _ CompletionEvent, replayed bool, _ error) {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 
 	node, exists := qs.nodes[nodeID]
 	if !exists {
-		return errors.New("node not found")
+		return "", CompletionEvent{}, false, ErrNodeNotFound
+	}
+
+	if token != "" && node.Completed && node.CompletionToken == token {
+		return "", CompletionEvent{}, true, nil
+	}
+	if err := qs.checkCompletable(node); err != nil {
+		return "", CompletionEvent{}, false, err
+	}
+
+	// In strict mode the completion must be stored before memory changes; completeLocked then
+	// repeats the (idempotent) write best-effort as in the default mode.
+	if qs.PersistMode == PersistStrict {
+		if err := qs.criticalPersist(ctx, "MarkNodeCompleted(true)", func(ctx context.Context) error {
+			return qs.store.MarkNodeCompleted(ctx, nodeID, true)
+		}); err != nil {
+			return "", CompletionEvent{}, false, err
+		}
+	}
+
+	node.CompletionToken = token
+	freedResourceID, ev := qs.completeLocked(ctx, node, result)
+	if token != "" {
+		qs.bestEffortPersist(ctx, "UpdateNodeCompletionToken", func(ctx context.Context) error {
+			return qs.store.UpdateNodeCompletionToken(ctx, nodeID, token)
+		})
+	}
+	return freedResourceID, ev, false, nil
+}
+
+// completedWithToken reports whether the store has nodeID, which is no longer in memory, completed
+// with token. Reads go to the primary, so a retry right after the completion sees it. A failed
+// read is logged and reported as false.
+func (qs *QueueService) completedWithToken(ctx context.Context, nodeID, token string) bool {
+	if qs.store == nil {
+		return false
+	}
+	var stored string
+	if err := traceStore(ctx, "GetNodeCompletionToken", func(ctx context.Context) (err error) {
+		stored, err = qs.store.GetNodeCompletionToken(db.WithPrimaryReads(ctx), nodeID)
+		return err
+	}); err != nil {
+		log.Printf("[DB] GetNodeCompletionToken failed: %v", err)
+		return false
 	}
+	return stored == token
+}
 
+// checkCompletable runs the completion preconditions for a node. Callers must hold qs.mu.
+func (qs *QueueService) checkCompletable(node *node.Node) error {
 	if node.Completed {
-		return errors.New("node is already completed")
+		return ErrNodeCompleted
+	}
+	if qs.StrictLifecycle && !qs.inService(node) {
+		return ErrNodeNotInService
 	}
+	return nil
+}
 
+// completeLocked marks an active node completed and removes it from its resource. It returns
+// the resource ID whose service slot was freed (empty if the node was not in service) and the
+// node's CompletionEvent. Callers must hold qs.mu for writing.
+func (qs *QueueService) completeLocked(ctx context.Context, node *node.Node, result *node.NodeResult) (string, CompletionEvent) {
+	nodeID := node.ID
 	node.Completed = true
-	node.AddLog("completed", node.ResourceID)
+	node.Result = result
+	qs.unindexActiveLocked(node)
+	completedAt := qs.addNodeLog(node, "completed", node.ResourceID)
+	ev := completionEvent(node, node.ResourceID)
+
+	if result != nil {
+		outcome, raw, ts := result.Outcome, []byte(result.Result), ev.Timestamp
+		qs.bestEffortPersist(ctx, "InsertNodeResult", func(ctx context.Context) error {
+			return qs.store.InsertNodeResult(ctx, nodeID, outcome, raw, ts)
+		})
+	}
 
 	// Remove from current resource
+	freedResourceID := ""
 	if node.ResourceID != "" {
 		if resource, exists := qs.resources[node.ResourceID]; exists {
+			if resource.IsInService(nodeID) {
+				freedResourceID = resource.ID
+			}
 			resource.RemoveNode(nodeID)
 		}
 		// Persist node completion + clear resource (best-effort).
-		ctx := context.Background()
 		rid := node.ResourceID
 		qs.bestEffortPersist(ctx, "MarkNodeCompleted(true)", func(ctx context.Context) error {
 			return qs.store.MarkNodeCompleted(ctx, node.ID, true)
 		})
 		qs.bestEffortPersist(ctx, "InsertNodeLog(completed)", func(ctx context.Context) error {
-			return qs.store.InsertNodeLog(ctx, node.ID, "completed", &rid, time.Now())
+			return qs.store.InsertNodeLog(ctx, node.ID, "completed", &rid, completedAt)
 		})
 		node.ResourceID = ""
 	}
 
-	return nil
+	return freedResourceID, ev
+}
+
+// autoPromote allocates the first eligible waiting node if the resource has AutoPromote enabled
+// and a slot is available.
+//
+// It is called after the triggering operation has released qs.mu, and takes the lock itself
+// (see withAllocLock).
+func (qs *QueueService) autoPromote(ctx context.Context, resourceID string) {
+	qs.withAllocLock(func() {
+		resource, exists := qs.resources[resourceID]
+		if !exists || !resource.AutoPromote || resource.IsPaused() {
+			return
+		}
+		qs.fillLocked(ctx, resource, 1)
+	})
+}
+
+// FillResource is FillResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) FillResource(resourceID string) ([]string, error) {
+	return qs.FillResourceContext(context.Background(), resourceID)
+}
+
+// FillResourceContext allocates waiting nodes in queue order until the resource is full and
+// returns the allocated node IDs. Nodes whose entity is at the resource's MaxPerEntity limit, or
+// that are backing off after a failure, are passed over. A full resource (or empty waiting queue)
+// returns an empty list.
+func (qs *QueueService) FillResourceContext(ctx context.Context, resourceID string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "QueueService.FillResource", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	var allocated []string
+	qs.withAllocLock(func() {
+		resourceID = qs.LookupResourceID(resourceID)
+		resource, exists := qs.resources[resourceID]
+		if !exists {
+			err = ErrResourceNotFound
+			return
+		}
+		if resource.IsPaused() {
+			err = ErrResourcePaused
+			return
+		}
+		allocated = qs.fillLocked(ctx, resource, 0)
+	})
+	return allocated, err
+}
+
+// fillLocked allocates waiting nodes on resource in queue order until it is full or max nodes
+// have been allocated (max <= 0 means no limit). Nodes whose entity is at MaxPerEntity, nodes
+// still backing off after a failure, nodes AdmissionFunc rejects, and nodes heavier than the
+// remaining capacity are passed over so they cannot stall the queue. Filling stops once the
+// resource's AllocRatePerSec is used up. Callers must hold qs.mu for writing.
+func (qs *QueueService) fillLocked(ctx context.Context, resource *resource.Resource, max int) []string {
+	allocated := make([]string, 0)
+	_, waiting := resource.QueueSnapshot()
+	for _, next := range waiting {
+		if max > 0 && len(allocated) >= max {
+			break
+		}
+		err := qs.allocateLocked(ctx, next.ID)
+		switch {
+		case err == nil:
+			allocated = append(allocated, next.ID)
+		case errors.Is(err, ErrEntityLimit), errors.Is(err, ErrNodeBackingOff), errors.Is(err, ErrAdmissionDenied):
+			continue
+		case errors.Is(err, ErrNotQueueHead):
+			// A FIFOStrict resource waits for its head node rather than passing it over.
+			return allocated
+		case errors.Is(err, ErrAllocationRateExceeded):
+			return allocated
+		case errors.Is(err, ErrCapacityFull):
+			if resource.IsFull() {
+				return allocated
+			}
+			// Too heavy for what is left; a lighter node further back may still fit.
+			continue
+		default:
+			log.Printf("[QueueService] fill on %s stopped at node %s: %v", resource.ID, next.ID, err)
+			return allocated
+		}
+	}
+	return allocated
 }
 
 // GetNode returns a node by ID.
@@ -242,7 +1085,7 @@ func (qs *QueueService) GetNode(nodeID string) (*node.Node, error) {
 
 	node, exists := qs.nodes[nodeID]
 	if !exists {
-		return nil, errors.New("node not found")
+		return nil, ErrNodeNotFound
 	}
 
 	return node, nil
@@ -253,22 +1096,23 @@ func (qs *QueueService) GetResource(resourceID string) (*resource.Resource, erro
 	qs.mu.RLock()
 	defer qs.mu.RUnlock()
 
-	resource, exists := qs.resources[resourceID]
+	resource, exists := qs.resources[qs.LookupResourceID(resourceID)]
 	if !exists {
-		return nil, errors.New("resource not found")
+		return nil, ErrResourceNotFound
 	}
 
 	return resource, nil
 }
 
-// ListResources returns a snapshot slice of all resources currently registered.
+// ListResources returns snapshots (see resource.Resource.Snapshot) of all resources currently
+// registered. Changes to the returned resources do not affect the service.
 func (qs *QueueService) ListResources() []*resource.Resource {
 	qs.mu.RLock()
 	defer qs.mu.RUnlock()
 
 	resources := make([]*resource.Resource, 0, len(qs.resources))
 	for _, resource := range qs.resources {
-		resources = append(resources, resource)
+		resources = append(resources, resource.Snapshot())
 	}
 	sort.Slice(resources, func(i, j int) bool {
 		return resources[i].ID < resources[j].ID
@@ -276,120 +1120,27 @@ func (qs *QueueService) ListResources() []*resource.Resource {
 	return resources
 }
 
-// ListNodes returns a snapshot slice of all nodes currently stored.
+// ListNodes returns snapshots (see node.Node.Snapshot) of all nodes currently stored, so they can
+// be serialized without racing later queue operations.
 func (qs *QueueService) ListNodes() []*node.Node {
 	qs.mu.RLock()
 	defer qs.mu.RUnlock()
 
 	nodes := make([]*node.Node, 0, len(qs.nodes))
 	for _, node := range qs.nodes {
-		nodes = append(nodes, node)
+		nodes = append(nodes, node.Snapshot())
 	}
 	return nodes
 }
 
-// RestoreFromStore rebuilds the in-memory node state from the configured Store.
-// It is intended to be called on startup after resources have been loaded into qs.
-//
-// Placement rules:
-//   - nodes with resource_id get placed into waiting or service queue based on latest node_log action
-//     (moved_to_waiting_queue vs moved_to_service_queue)
-//   - ordering within each queue is by that latest relevant log timestamp ascending.
-func (qs *QueueService) RestoreFromStore(ctx context.Context) error {
-	if qs.store == nil {
-		return nil
-	}
-
-	persisted, err := qs.store.ListNodes(ctx)
-	if err != nil {
-		return err
-	}
-	states, err := qs.store.ListLatestNodeStates(ctx)
-	if err != nil {
-		return err
-	}
-
-	qs.mu.Lock()
-	defer qs.mu.Unlock()
-
-	// Clear existing in-memory nodes and resource queues to avoid duplicates.
-	qs.nodes = make(map[string]*node.Node, len(persisted))
-	for _, r := range qs.resources {
-		r.Nodes = nil
-		r.WaitingQueue = nil
-	}
-
-	type queued struct {
-		n  *node.Node
-		ts time.Time
-	}
-	waitingByRes := make(map[string][]queued)
-	serviceByRes := make(map[string][]queued)
-
-	for _, pn := range persisted {
-		n := &node.Node{
-			ID:        pn.NodeID,
-			Entity:    &node.Entity{Name: pn.EntityName},
-			Completed: pn.Completed,
-			CreatedAt: pn.CreatedAt,
-		}
-		if pn.ResourceID != nil {
-			n.ResourceID = *pn.ResourceID
-		}
-		qs.nodes[n.ID] = n
-
-		// Only enqueue nodes assigned to a known resource.
-		if n.ResourceID == "" {
-			continue
-		}
-		if _, ok := qs.resources[n.ResourceID]; !ok {
-			continue
-		}
-
-		st, ok := states[n.ID]
-		queueTS := pn.CreatedAt
-		queueKind := db.QueueKindWaiting
-		if ok {
-			queueTS = st.TS
-			queueKind = st.Queue
-		}
-
-		switch queueKind {
-		case db.QueueKindService:
-			serviceByRes[n.ResourceID] = append(serviceByRes[n.ResourceID], queued{n: n, ts: queueTS})
-		default:
-			waitingByRes[n.ResourceID] = append(waitingByRes[n.ResourceID], queued{n: n, ts: queueTS})
-		}
-	}
-
-	// Apply sorted queues to resources.
-	for rid, items := range waitingByRes {
-		sort.Slice(items, func(i, j int) bool { return items[i].ts.Before(items[j].ts) })
-		r := qs.resources[rid]
-		r.WaitingQueue = make([]*node.Node, 0, len(items))
-		for _, it := range items {
-			r.WaitingQueue = append(r.WaitingQueue, it.n)
-		}
-	}
-	for rid, items := range serviceByRes {
-		sort.Slice(items, func(i, j int) bool { return items[i].ts.Before(items[j].ts) })
-		r := qs.resources[rid]
-		r.Nodes = make([]*node.Node, 0, len(items))
-		for _, it := range items {
-			r.Nodes = append(r.Nodes, it.n)
-		}
-	}
-
-	return nil
-}
-
 // Handlers being called from API end point
 
-// CreateNodeHandler handles POST /nodes.
+// CreateNodeHandler handles POST /nodes[?require_capacity=true].
 //
 // Behavior:
 // - Validates payload and creates a node.
 // - Optionally assigns it to a resource waiting queue if resource_id is provided.
+// - With require_capacity=true, allocates it at once or returns 503 (see CreateNodeWithCapacity).
 // - Returns the created node (with its lifecycle log).
 func (qs *QueueService) CreateNodeHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
@@ -400,38 +1151,71 @@ func (qs *QueueService) CreateNodeHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	var req node.CreateNodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[API] POST /nodes - ERROR: Invalid request body - %v", err)
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if req.EntityName == "" {
-		log.Printf("[API] POST /nodes - ERROR: entity_name is required")
-		utils.RespondWithError(w, http.StatusBadRequest, "entity_name is required")
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes - ERROR: %v", err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
 		return
 	}
 
 	log.Printf("[API] POST /nodes - Request: entity_name=%s, resource_id=%s", req.EntityName, req.ResourceID)
 
-	node, err := qs.CreateNode(req.EntityName)
+	allowed, err := qs.allowedResourcesForLabels(req.ResourceLabels, req.AllowedResources, req.ResourceID)
 	if err != nil {
 		log.Printf("[API] POST /nodes - ERROR: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(w, err)
+		return
+	}
+	req.AllowedResources = allowed
+
+	if r.URL.Query().Get("require_capacity") == "true" {
+		if req.ResourceID == "" {
+			fields := map[string]string{"require_capacity": "requires resource_id"}
+			log.Printf("[API] POST /nodes - ERROR: %v", &utils.ValidationError{Fields: fields})
+			utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+				Error:  "Invalid query parameters",
+				Code:   CodeInvalidRequest,
+				Fields: fields,
+			})
+			return
+		}
+		node, err := qs.createNodeWithCapacity(r.Context(), req.ID, req.EntityName, req.Weight, req.ResourceID, req.Tags, req.AllowedResources, req.DeadlineTS)
+		if err != nil {
+			log.Printf("[API] POST /nodes - ERROR: %v", err)
+			respondWithServiceError(w, err)
+			return
+		}
+		duration := time.Since(startTime)
+		log.Printf("[API] POST /nodes - SUCCESS: Created node %s in service on resource %s (took %v)", node.ID, req.ResourceID, duration)
+		utils.RespondWithJSON(w, http.StatusCreated, node)
 		return
 	}
 
-	// If resource_id is provided, add node to that resource
+	// If resource_id is provided, create the node directly on that resource
 	if req.ResourceID != "" {
-		log.Printf("[API] POST /nodes - Moving node %s to resource %s", node.ID, req.ResourceID)
-		if err := qs.MoveNode(node.ID, req.ResourceID); err != nil {
+		log.Printf("[API] POST /nodes - Creating node on resource %s", req.ResourceID)
+		node, err := qs.createNodeOnResource(r.Context(), req.ID, req.EntityName, req.Weight, req.ResourceID, req.Tags, req.AllowedResources, req.DeadlineTS)
+		if node == nil {
+			log.Printf("[API] POST /nodes - ERROR: %v", err)
+			respondWithServiceError(w, err)
+			return
+		}
+		if err != nil {
+			// If the assignment fails, still return the created node
 			log.Printf("[API] POST /nodes - ERROR moving node: %v", err)
-			// If move fails, still return the created node
 			utils.RespondWithJSON(w, http.StatusCreated, node)
 			return
 		}
-		// Refresh node to get updated state
-		node, _ = qs.GetNode(node.ID)
+		duration := time.Since(startTime)
+		log.Printf("[API] POST /nodes - SUCCESS: Created node %s on resource %s (took %v)", node.ID, req.ResourceID, duration)
+		utils.RespondWithJSON(w, http.StatusCreated, node)
+		return
+	}
+
+	node, err := qs.createTaggedNode(r.Context(), req.ID, req.EntityName, req.Weight, req.Tags, req.AllowedResources, req.DeadlineTS)
+	if err != nil {
+		log.Printf("[API] POST /nodes - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
 	}
 
 	duration := time.Since(startTime)
@@ -442,32 +1226,23 @@ func (qs *QueueService) CreateNodeHandler(w http.ResponseWriter, r *http.Request
 // MoveNodeHandler handles POST /nodes/{id}/move.
 //
 // This assigns the node to the target resource by placing it in the target's waiting queue.
-// It does not allocate the node into service; use POST /nodes/{id}/allocate for that.
+// It does not allocate the node into service; use POST /nodes/{id}/allocate for that. With
+// from_resource_id the move is conditional (see MoveNodeIfContext) and a mismatch returns 409.
 func (qs *QueueService) MoveNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
 	startTime := time.Now()
 	log.Printf("[API] POST /nodes/%s/move - Request", nodeID)
 
 	var req node.MoveNodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[API] POST /nodes/%s/move - ERROR: Invalid request body - %v", nodeID, err)
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if req.TargetResourceID == "" {
-		log.Printf("[API] POST /nodes/%s/move - ERROR: target_resource_id is required", nodeID)
-		utils.RespondWithError(w, http.StatusBadRequest, "target_resource_id is required")
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/move - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
 		return
 	}
 
 	log.Printf("[API] POST /nodes/%s/move - Moving to resource %s", nodeID, req.TargetResourceID)
-	if err := qs.MoveNode(nodeID, req.TargetResourceID); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "node not found" || err.Error() == "target resource not found" {
-			statusCode = http.StatusNotFound
-		}
+	if err := qs.MoveNodeIfContext(r.Context(), nodeID, req.FromResourceID, req.TargetResourceID, req.Lane); err != nil {
 		log.Printf("[API] POST /nodes/%s/move - ERROR: %v", nodeID, err)
-		utils.RespondWithError(w, statusCode, err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -480,41 +1255,57 @@ func (qs *QueueService) MoveNodeHandler(w http.ResponseWriter, r *http.Request,
 // CompleteNodeHandler handles POST /nodes/{id}/complete.
 //
 // Completion marks a node immutable (no further moves/allocations) and removes it from any queues.
+// The body is optional; {"outcome": ..., "result": {...}} records the node's result, and
+// "completion_token" makes a retried completion return 200 (see CompleteNodeWithTokenContext).
 func (qs *QueueService) CompleteNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
 	startTime := time.Now()
 	log.Printf("[API] POST /nodes/%s/complete - Request", nodeID)
 
-	if err := qs.CompleteNode(nodeID); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "node not found" {
-			statusCode = http.StatusNotFound
-		}
+	var req node.CompleteNodeRequest
+	if err := utils.DecodeOptionalAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/complete - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.CompleteNodeWithTokenContext(r.Context(), nodeID, req.NodeResult(), req.CompletionToken); err != nil {
 		log.Printf("[API] POST /nodes/%s/complete - ERROR: %v", nodeID, err)
-		utils.RespondWithError(w, statusCode, err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
 	duration := time.Since(startTime)
 	log.Printf("[API] POST /nodes/%s/complete - SUCCESS: Node completed (took %v)", nodeID, duration)
-	node, _ := qs.GetNode(nodeID)
-	utils.RespondWithJSON(w, http.StatusOK, node)
+	completed, err := qs.GetNode(nodeID)
+	if err != nil {
+		// A retried completion of a node that has since left memory (see CompleteNodeWithTokenContext).
+		completed = &node.Node{ID: nodeID, Completed: true, CompletionToken: req.CompletionToken}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, completed)
 }
 
 // AllocateNodeHandler handles POST /nodes/{id}/allocate.
 //
 // Allocation promotes a node from the assigned resource's waiting queue into the service queue.
 // This is the step where resource capacity is enforced.
+//
+// With ?idempotent=true a node that is already in service returns 200 with the unchanged node
+// instead of a node_in_service error, so clients can safely retry.
 func (qs *QueueService) AllocateNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
 	startTime := time.Now()
 	log.Printf("[API] POST /nodes/%s/allocate - Request", nodeID)
 
-	if err := qs.AllocateNode(nodeID); err != nil {
-		statusCode := http.StatusBadRequest
-		if err.Error() == "node not found" || err.Error() == "resource not found" {
-			statusCode = http.StatusNotFound
+	idempotent := r.URL.Query().Get("idempotent") == "true"
+	if err := qs.AllocateNodeContext(r.Context(), nodeID); err != nil {
+		if idempotent && errors.Is(err, ErrNodeInService) {
+			// A retried allocate for a node already in service is a success; nothing changes.
+			log.Printf("[API] POST /nodes/%s/allocate - SUCCESS: Node already in service (idempotent)", nodeID)
+			node, _ := qs.GetNode(nodeID)
+			utils.RespondWithJSON(w, http.StatusOK, node)
+			return
 		}
 		log.Printf("[API] POST /nodes/%s/allocate - ERROR: %v", nodeID, err)
-		utils.RespondWithError(w, statusCode, err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 
@@ -524,21 +1315,162 @@ func (qs *QueueService) AllocateNodeHandler(w http.ResponseWriter, r *http.Reque
 	utils.RespondWithJSON(w, http.StatusOK, node)
 }
 
-// GetNodeHandler handles GET /nodes/{id}.
-// Returns 404 if the node does not exist.
+// ReorderNodeHandler handles PUT /nodes/{id}/position.
+//
+// Repositions a waiting node within its current resource's waiting queue without changing its resource.
+func (qs *QueueService) ReorderNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] PUT /nodes/%s/position - Request", nodeID)
+
+	var req node.ReorderNodeRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] PUT /nodes/%s/position - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.ReorderWaitingNodeContext(r.Context(), nodeID, *req.Position); err != nil {
+		log.Printf("[API] PUT /nodes/%s/position - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] PUT /nodes/%s/position - SUCCESS: Moved to position %d (took %v)", nodeID, *req.Position, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
+
+// ExpediteNodeHandler handles POST /nodes/{id}/expedite.
+//
+// Moves a waiting node to the front of its resource's waiting queue.
+func (qs *QueueService) ExpediteNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/expedite - Request", nodeID)
+
+	if err := qs.ExpediteNodeContext(r.Context(), nodeID); err != nil {
+		log.Printf("[API] POST /nodes/%s/expedite - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/expedite - SUCCESS: Moved to front (took %v)", nodeID, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
+
+// DeferNodeHandler handles POST /nodes/{id}/defer.
+//
+// Moves a waiting node to the back of its resource's waiting queue.
+func (qs *QueueService) DeferNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/defer - Request", nodeID)
+
+	if err := qs.DeferNodeContext(r.Context(), nodeID); err != nil {
+		log.Printf("[API] POST /nodes/%s/defer - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/defer - SUCCESS: Moved to back (took %v)", nodeID, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
+
+// CanAllocateResponse is the response payload for GET /nodes/{id}/can-allocate.
+type CanAllocateResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// CanAllocateHandler handles GET /nodes/{id}/can-allocate.
+//
+// Runs the same preconditions as POST /nodes/{id}/allocate without mutating state. A node that
+// cannot be allocated still returns 200 with allowed=false; only unknown nodes return 404.
+func (qs *QueueService) CanAllocateHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	log.Printf("[API] GET /nodes/%s/can-allocate - Request", nodeID)
+
+	err := qs.CanAllocate(nodeID)
+	if errors.Is(err, ErrNodeNotFound) {
+		log.Printf("[API] GET /nodes/%s/can-allocate - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	resp := CanAllocateResponse{Allowed: err == nil}
+	if err != nil {
+		_, resp.Code = errorStatus(err)
+		resp.Reason = err.Error()
+	}
+
+	log.Printf("[API] GET /nodes/%s/can-allocate - SUCCESS: allowed=%t", nodeID, resp.Allowed)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// GetNodeHandler handles GET /nodes/{id}[?fields=summary|full&naming=snake|camel].
+// Returns 404 if the node does not exist. Waiting nodes that cannot be allocated include a
+// blocked_reason. The full node (including its log) is returned unless fields=summary.
+//
+// With ?wait=30s&since_version=N it long-polls: the response is held until the node's version
+// exceeds N (then the node is returned) or the wait elapses (304 Not Modified). The wait is capped
+// at MaxLongPollWait and ends early if the client disconnects.
 func (qs *QueueService) GetNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
 	log.Printf("[API] GET /nodes/%s - Request", nodeID)
-	node, err := qs.GetNode(nodeID)
+
+	fields, naming, errs := parseNodeViewQuery(r, NodeFieldsFull)
+	poll, pollErrs := parseLongPoll(r)
+	for k, v := range pollErrs {
+		errs[k] = v
+	}
+	if len(errs) > 0 {
+		log.Printf("[API] GET /nodes/%s - ERROR: %v", nodeID, &utils.ValidationError{Fields: errs})
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: errs,
+		})
+		return
+	}
+
+	if poll.Wait > 0 {
+		changed, err := qs.WaitForNodeVersion(r.Context(), nodeID, poll.SinceVersion, poll.Wait)
+		if r.Context().Err() != nil {
+			log.Printf("[API] GET /nodes/%s - client went away during long-poll", nodeID)
+			return
+		}
+		if err != nil {
+			log.Printf("[API] GET /nodes/%s - ERROR: %v", nodeID, err)
+			respondWithServiceError(w, err)
+			return
+		}
+		if !changed {
+			log.Printf("[API] GET /nodes/%s - SUCCESS: Not modified since version %d", nodeID, poll.SinceVersion)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	node, err := qs.GetNodeViewFields(nodeID, fields)
 	if err != nil {
 		log.Printf("[API] GET /nodes/%s - ERROR: %v", nodeID, err)
-		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		respondWithServiceError(w, err)
 		return
 	}
 	log.Printf("[API] GET /nodes/%s - SUCCESS", nodeID)
-	utils.RespondWithJSON(w, http.StatusOK, node)
+	respondWithNodeJSON(w, naming, node)
 }
 
-// ListNodesHandler handles GET /nodes.
+// ListNodesHandler handles GET /nodes[?fields=summary|full&naming=snake|camel&tag=...
+// &resource_id=&queue=waiting|service&cursor=&after=&limit=&stream=true].
+//
+// Nodes are returned as summaries (no log) unless fields=full. Repeated tag parameters return
+// only nodes carrying every listed tag; resource_id and queue keep only nodes in that resource's
+// or that kind of queue. cursor/limit page through the nodes in (created_at, id)
+// order, and after/limit in node ID order, with the next page's cursor in the X-Next-Cursor header. stream=true encodes the array element by element
+// instead of building the whole response in memory.
 func (qs *QueueService) ListNodesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -546,12 +1478,111 @@ func (qs *QueueService) ListNodesHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	log.Printf("[API] GET /nodes - Request")
-	nodes := qs.ListNodes()
+
+	fields, naming, errs := parseNodeViewQuery(r, NodeFieldsSummary)
+	filter := parseNodeFilter(r, errs)
+	list := parseListNodeQuery(r, errs)
+	if len(errs) > 0 {
+		log.Printf("[API] GET /nodes - ERROR: %v", &utils.ValidationError{Fields: errs})
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: errs,
+		})
+		return
+	}
+
+	if list.stream {
+		qs.mu.RLock()
+		ids, next, err := qs.pageNodeIDsLocked(filter, list.page)
+		qs.mu.RUnlock()
+		if err != nil {
+			log.Printf("[API] GET /nodes - ERROR: %v", err)
+			respondWithServiceError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
+		w.WriteHeader(http.StatusOK)
+		written, err := qs.streamNodeViews(w, naming, fields, ids)
+		if err != nil {
+			// Headers are already sent; all we can do is log.
+			log.Printf("[API] GET /nodes - ERROR: stream aborted after %d nodes: %v", written, err)
+			return
+		}
+		log.Printf("[API] GET /nodes - SUCCESS: Streamed %d nodes", written)
+		return
+	}
+
+	var nodes []NodeView
+	var next string
+	var err error
+	if list.paged {
+		nodes, next, err = qs.ListNodeViewsPage(fields, filter, list.page)
+	} else {
+		nodes, err = qs.ListNodeViews(fields, filter)
+	}
+	if err != nil {
+		log.Printf("[API] GET /nodes - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 	log.Printf("[API] GET /nodes - SUCCESS: Returning %d nodes", len(nodes))
-	utils.RespondWithJSON(w, http.StatusOK, nodes)
+	respondWithNodeJSON(w, naming, nodes)
 }
 
-// ListResourcesHandler handles GET /resources.
+// CreateResourceHandler handles POST /resources.
+//
+// Creates a new empty resource. Returns 409 if a resource with the same ID already exists.
+func (qs *QueueService) CreateResourceHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	var req resource.CreateResourceRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources - ERROR: %v", err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	log.Printf("[API] POST /resources - Request: id=%s, capacity=%d", req.ID, req.Capacity)
+
+	res := resourceFromRequest(req)
+	if err := qs.CreateResourceContext(r.Context(), res); err != nil {
+		log.Printf("[API] POST /resources - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources - SUCCESS: Created resource %s (took %v)", res.ID, duration)
+	utils.RespondWithJSON(w, http.StatusCreated, res)
+}
+
+// resourceFromRequest builds the resource described by a validated create request.
+func resourceFromRequest(req resource.CreateResourceRequest) *resource.Resource {
+	res := resource.NewResource(req.ID, req.Capacity)
+	res.AutoPromote = req.AutoPromote
+	res.MaxPerEntity = req.MaxPerEntity
+	res.FIFOStrict = req.FIFOStrict
+	res.PressureWaiting = req.PressureWaiting
+	res.PressureSeconds = req.PressureSeconds
+	res.MaxWaitMS = req.MaxWaitMS
+	res.LaneOrder = req.Lanes
+	res.ReservedForPriority = req.ReservedForPriority
+	res.AllocRatePerSec = req.AllocRatePerSec
+	res.Labels = maps.Clone(req.Labels)
+	return res
+}
+
+// ListResourcesHandler handles GET /resources[?sort=id|utilization|waiting&order=asc|desc][&label=k=v].
+// Resources are annotated with their load (see ListResourcesSorted). label may be repeated; only
+// resources carrying every given label are returned.
 func (qs *QueueService) ListResourcesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -559,7 +1590,114 @@ func (qs *QueueService) ListResourcesHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	log.Printf("[API] GET /resources - Request")
-	resources := qs.ListResources()
+	q := r.URL.Query()
+	selector, err := resource.ParseLabelSelector(q["label"])
+	if err != nil {
+		log.Printf("[API] GET /resources - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	resources, err := qs.ListResourcesSorted(q.Get("sort"), q.Get("order"))
+	if err != nil {
+		log.Printf("[API] GET /resources - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+	if len(selector) > 0 {
+		resources = slices.DeleteFunc(resources, func(l ResourceLoad) bool { return !l.MatchesLabels(selector) })
+	}
 	log.Printf("[API] GET /resources - SUCCESS: Returning %d resources", len(resources))
 	utils.RespondWithJSON(w, http.StatusOK, resources)
 }
+
+// ExportResourcesCSVHandler handles GET /resources.csv.
+//
+// Streams the current resources (including runtime-created ones) in the config.txt CSV format so
+// operators can export, edit and redeploy them.
+func (qs *QueueService) ExportResourcesCSVHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /resources.csv - Request")
+	resources := qs.ListResources()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="resources.csv"`)
+	w.WriteHeader(http.StatusOK)
+	if err := resource.WriteCSV(w, resources); err != nil {
+		// Headers are already sent; all we can do is log.
+		log.Printf("[API] GET /resources.csv - ERROR: %v", err)
+		return
+	}
+	log.Printf("[API] GET /resources.csv - SUCCESS: Exported %d resources", len(resources))
+}
+
+// FillResponse is the response payload for POST /resources/{id}/fill.
+type FillResponse struct {
+	ResourceID string   `json:"resource_id"`
+	Allocated  []string `json:"allocated"`
+	Waiting    int      `json:"waiting"`
+}
+
+// FillResourceHandler handles POST /resources/{id}/fill.
+//
+// Allocates waiting nodes in order until the resource is full, returning the allocated node IDs
+// and how many nodes remain waiting.
+func (qs *QueueService) FillResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/fill - Request", resourceID)
+
+	resourceID = qs.LookupResourceID(resourceID)
+	allocated, err := qs.FillResourceContext(r.Context(), resourceID)
+	if err != nil {
+		log.Printf("[API] POST /resources/%s/fill - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	waiting := 0
+	if res, err := qs.GetResource(resourceID); err == nil {
+		_, queue := res.QueueSnapshot()
+		waiting = len(queue)
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/fill - SUCCESS: Allocated %d nodes, %d waiting (took %v)", resourceID, len(allocated), waiting, duration)
+	utils.RespondWithJSON(w, http.StatusOK, FillResponse{ResourceID: resourceID, Allocated: allocated, Waiting: waiting})
+}
+
+// DrainResponse is the response payload for POST /resources/{id}/drain.
+type DrainResponse struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Moved int    `json:"moved"`
+}
+
+// DrainResourceHandler handles POST /resources/{id}/drain?to={target}[&include_service=true].
+//
+// Moves all waiting nodes (and optionally service nodes) to the target resource's waiting queue.
+func (qs *QueueService) DrainResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/drain - Request", resourceID)
+
+	toID := r.URL.Query().Get("to")
+	if toID == "" {
+		log.Printf("[API] POST /resources/%s/drain - ERROR: to is required", resourceID)
+		utils.RespondWithErrorCode(w, http.StatusBadRequest, CodeInvalidRequest, "to is required")
+		return
+	}
+	includeService := r.URL.Query().Get("include_service") == "true"
+
+	moved, err := qs.DrainResourceContext(r.Context(), resourceID, toID, includeService)
+	if err != nil {
+		log.Printf("[API] POST /resources/%s/drain - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/drain - SUCCESS: Moved %d nodes to %s (took %v)", resourceID, moved, toID, duration)
+	utils.RespondWithJSON(w, http.StatusOK, DrainResponse{From: resourceID, To: toID, Moved: moved})
+}
@@ -0,0 +1,186 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// replayedNode is a node's state derived from its node_logs alone.
+type replayedNode struct {
+	resourceID *string
+	completed  bool
+	state      *db.NodeState
+	log        []node.NodeLog
+}
+
+// replayNodeLogs derives a node's assignment, completion and queue placement from its log rows,
+// in timestamp order (ties keep their order in rows). Only created, moved_to_waiting_queue,
+// moved_to_service_queue, orphaned, completed and failed (a terminal failure) change state; every
+// row is kept in the rebuilt log.
+func replayNodeLogs(rows []db.NodeLogRow) replayedNode {
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].TS.Before(rows[j].TS) })
+
+	var rn replayedNode
+	rn.log = make([]node.NodeLog, 0, len(rows))
+	for _, row := range rows {
+		entry := node.NodeLog{Action: row.Action, Timestamp: row.TS.UTC()}
+		if row.ResourceID != nil {
+			entry.ResourceID = *row.ResourceID
+		}
+		if row.FromResourceID != nil {
+			entry.FromResourceID = *row.FromResourceID
+		}
+		rn.log = append(rn.log, entry)
+
+		switch row.Action {
+		case "created":
+			rn.resourceID, rn.completed, rn.state = row.ResourceID, false, nil
+		case "moved_to_waiting_queue":
+			rn.resourceID = row.ResourceID
+			rn.state = &db.NodeState{Queue: db.QueueKindWaiting, TS: row.TS}
+		case "moved_to_service_queue":
+			rn.resourceID = row.ResourceID
+			rn.state = &db.NodeState{Queue: db.QueueKindService, TS: row.TS}
+		case "orphaned":
+			// ReconcileOrphans cleared the assignment to a missing resource.
+			rn.resourceID, rn.state = nil, nil
+		case "completed", "failed":
+			// Completion releases the node's resource, as CompleteNode does.
+			rn.resourceID, rn.completed, rn.state = nil, true, nil
+		}
+	}
+	return rn
+}
+
+// RebuildFromLogs is RebuildFromLogsContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) RebuildFromLogs() (RestoreSummary, error) {
+	return qs.RebuildFromLogsContext(context.Background())
+}
+
+// RebuildFromLogsContext replaces the in-memory nodes and resource queues with state replayed from
+// the store's node_logs (see replayNodeLogs), for recovering from corrupted in-memory queues.
+//
+// Unlike RestoreFromStore it does not trust the nodes table's resource_id and completed columns or
+// ListLatestNodeStates: each node's assignment, completion, queue and position come from its own
+// log, and its in-memory log is replaced by the persisted one. The nodes table still supplies
+// entity, weight and retry state, and notes, tags and results are read as on restore. Nodes
+// without a nodes row cannot be rebuilt and are dropped; completed nodes are only rebuilt if they
+// are still in memory. Reads go to the primary. Without a store it returns ErrStoreUnavailable.
+func (qs *QueueService) RebuildFromLogsContext(ctx context.Context) (summary RestoreSummary, err error) {
+	if qs.store == nil {
+		return RestoreSummary{}, ErrStoreUnavailable
+	}
+
+	ctx, span := startSpan(ctx, "QueueService.RebuildFromLogs")
+	defer func() { endSpan(span, err) }()
+	ctx = db.WithPrimaryReads(ctx)
+
+	var persisted []db.PersistedNode
+	if err := traceStore(ctx, "ListAllNodes", func(ctx context.Context) (err error) {
+		persisted, err = qs.store.ListAllNodes(ctx)
+		return err
+	}); err != nil {
+		return RestoreSummary{}, err
+	}
+	rowsByNode := make(map[string][]db.NodeLogRow)
+	if err := traceStore(ctx, "EachNodeLog", func(ctx context.Context) error {
+		return qs.store.EachNodeLog(ctx, db.NodeLogQuery{}, func(row db.NodeLogRow) error {
+			rowsByNode[row.NodeID] = append(rowsByNode[row.NodeID], row)
+			return nil
+		})
+	}); err != nil {
+		return RestoreSummary{}, err
+	}
+
+	st := &storeState{
+		persisted: make([]db.PersistedNode, 0, len(persisted)),
+		states:    make(map[string]db.NodeState, len(persisted)),
+	}
+	logs := make(map[string][]node.NodeLog, len(persisted))
+	nodeIDs := make([]string, 0, len(persisted))
+	for _, pn := range persisted {
+		rn := replayNodeLogs(rowsByNode[pn.NodeID])
+		pn.ResourceID, pn.Completed = rn.resourceID, rn.completed
+		if rn.state != nil {
+			st.states[pn.NodeID] = *rn.state
+		}
+		logs[pn.NodeID] = rn.log
+		st.persisted = append(st.persisted, pn)
+		nodeIDs = append(nodeIDs, pn.NodeID)
+	}
+	if err := traceStore(ctx, "ListNodeExtras", func(ctx context.Context) (err error) {
+		st.extras, err = qs.store.ListNodeExtras(ctx, nodeIDs)
+		return err
+	}); err != nil {
+		return RestoreSummary{}, err
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	// As on merge, completed nodes that have left memory (e.g. archived) are not brought back.
+	kept := st.persisted[:0]
+	for _, pn := range st.persisted {
+		if _, inMemory := qs.nodes[pn.NodeID]; pn.Completed && !inMemory {
+			continue
+		}
+		kept = append(kept, pn)
+	}
+	st.persisted = kept
+
+	// Versions only move forward, so long-polling clients see the rebuild as a change.
+	versions := make(map[string]int64, len(qs.nodes))
+	for id, n := range qs.nodes {
+		versions[id] = n.Version
+	}
+	summary = qs.applyStoreState(st, false)
+	for id, n := range qs.nodes {
+		n.Log = logs[id]
+		n.SetTimeFormat(qs.TimeFormat)
+		n.Version = int64(len(n.Log))
+		if prev, ok := versions[id]; ok {
+			n.Version = max(n.Version, prev+1)
+		}
+	}
+	qs.lastRestore = time.Now()
+	span.SetAttributes(
+		attribute.Int("nodes.restored", summary.NodesRestored),
+		attribute.Int("queues.rebuilt", summary.QueuesRebuilt),
+	)
+	return summary, nil
+}
+
+// RebuildFromLogsHandler handles POST /admin/rebuild.
+//
+// The route is wrapped in utils.AdminGuard, so it is refused unless ENABLE_ADMIN is set. Returns
+// a RestoreSummary; 503 (store_unavailable) when persistence is disabled.
+func (qs *QueueService) RebuildFromLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] POST /admin/rebuild - Request")
+
+	summary, err := qs.RebuildFromLogsContext(r.Context())
+	if err != nil {
+		log.Printf("[API] POST /admin/rebuild - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /admin/rebuild - SUCCESS: Rebuilt %d nodes, %d queues (took %v)",
+		summary.NodesRestored, summary.QueuesRebuilt, duration)
+	utils.RespondWithJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,344 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// RecommendationPolicy controls when GET /resources/{id}/recommendation suggests a capacity
+// change.
+type RecommendationPolicy struct {
+	// Window is how much recent history is considered.
+	Window time.Duration
+	// TargetP90Wait is the p90 waiting time above which more capacity is recommended.
+	TargetP90Wait time.Duration
+	// LowUtilization is the average utilization (0-1) below which less capacity is recommended,
+	// provided the service queue never filled up during the window.
+	LowUtilization float64
+	// MinSamples is how many waits the window must hold before an increase is recommended.
+	MinSamples int
+}
+
+// DefaultRecommendationPolicy is the RecommendationPolicy NewQueueService starts with.
+var DefaultRecommendationPolicy = RecommendationPolicy{
+	Window:         time.Hour,
+	TargetP90Wait:  5 * time.Minute,
+	LowUtilization: 0.3,
+	MinSamples:     5,
+}
+
+// Values of CapacityRecommendation.Action.
+const (
+	RecommendIncrease = "increase"
+	RecommendDecrease = "decrease"
+	RecommendNone     = "none"
+)
+
+// ResourceMetrics summarizes a resource's recent history (see computeResourceMetrics).
+type ResourceMetrics struct {
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Capacity    int       `json:"capacity"`
+	// WaitSamples counts waits on the resource that ended (or are still open) inside the window.
+	WaitSamples int   `json:"wait_samples"`
+	P50WaitMS   int64 `json:"p50_wait_ms"`
+	P90WaitMS   int64 `json:"p90_wait_ms"`
+	// Utilization is the average share of capacity in use over the window (node weights count).
+	Utilization float64 `json:"utilization"`
+	// PeakInService is the most capacity units in use at any point in the window.
+	PeakInService int `json:"peak_in_service"`
+}
+
+// CapacityRecommendation is the response payload for GET /resources/{id}/recommendation. It is
+// advisory only: nothing is changed.
+type CapacityRecommendation struct {
+	ResourceID          string          `json:"resource_id"`
+	Stats               ResourceMetrics `json:"stats"`
+	TargetP90WaitMS     int64           `json:"target_p90_wait_ms"`
+	Action              string          `json:"action"`
+	CurrentCapacity     int             `json:"current_capacity"`
+	RecommendedCapacity int             `json:"recommended_capacity"`
+	Delta               int             `json:"delta"`
+	Rationale           string          `json:"rationale"`
+}
+
+// nodeHistory is what computeResourceMetrics needs about one node.
+type nodeHistory struct {
+	snap   nodeSnapshot
+	weight int
+	events []nodeEvent
+}
+
+// nodeHistories returns the history of every node.
+//
+// With a store the persisted nodes and logs are used, so archived nodes and nodes not yet
+// reloaded after a restart still count; if the store fails it falls back to the nodes held in
+// memory (as completionTimes does).
+func (qs *QueueService) nodeHistories(ctx context.Context) []nodeHistory {
+	if qs.store != nil {
+		histories, err := qs.nodeHistoriesFromStore(ctx)
+		if err == nil {
+			return histories
+		}
+		log.Printf("[DB] node history failed (falling back to in-memory logs): %v", err)
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	histories := make([]nodeHistory, 0, len(qs.nodes))
+	for _, n := range qs.nodes {
+		snap, logs := metricsSnapshot(n)
+		histories = append(histories, nodeHistory{snap: snap, weight: n.Weight, events: toNodeEventsFromInMemory(logs)})
+	}
+	return histories
+}
+
+func (qs *QueueService) nodeHistoriesFromStore(ctx context.Context) ([]nodeHistory, error) {
+	persisted, err := qs.store.ListAllNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(persisted) == 0 {
+		return []nodeHistory{}, nil
+	}
+	nodeIDs := make([]string, len(persisted))
+	for i, pn := range persisted {
+		nodeIDs[i] = pn.NodeID
+	}
+	logs, err := qs.store.ListNodeLogs(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]nodeHistory, 0, len(persisted))
+	for _, pn := range persisted {
+		histories = append(histories, nodeHistory{
+			snap: nodeSnapshot{
+				ID:        pn.NodeID,
+				Entity:    pn.EntityName,
+				CreatedAt: pn.CreatedAt.UTC(),
+				Completed: pn.Completed,
+			},
+			weight: pn.Weight,
+			events: toNodeEventsFromDB(logs[pn.NodeID]),
+		})
+	}
+	return histories, nil
+}
+
+// computeResourceMetrics summarizes how resourceID behaved over [now-window, now].
+//
+// Waits are the node metrics' waiting segments on the resource that end inside the window
+// (segments still open end at now). A node is in service on the resource from its
+// moved_to_service_queue entry until its next log entry of any kind, or until now.
+func computeResourceMetrics(now time.Time, window time.Duration, resourceID string, capacity int, histories []nodeHistory) ResourceMetrics {
+	start := now.Add(-window)
+	m := ResourceMetrics{WindowStart: start, WindowEnd: now, Capacity: capacity}
+
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	waits := make([]int64, 0)
+	edges := make([]edge, 0)
+	var busy float64 // capacity-unit nanoseconds in service inside the window
+
+	for _, h := range histories {
+		metrics := computeNodeMetrics(now, h.snap, h.events)
+		for _, seg := range metrics.WaitingSegments {
+			if seg.ResourceID == resourceID && !seg.EndTS.Before(start) && !seg.EndTS.After(now) {
+				waits = append(waits, seg.DurationMS)
+			}
+		}
+
+		// computeNodeMetrics sorted h.events in place.
+		weight := max(h.weight, 1)
+		for i, ev := range h.events {
+			if ev.Action != "moved_to_service_queue" || ev.ResourceID != resourceID {
+				continue
+			}
+			from, to := ev.TS, now
+			if i+1 < len(h.events) {
+				to = h.events[i+1].TS
+			}
+			if from.Before(start) {
+				from = start
+			}
+			if to.After(now) {
+				to = now
+			}
+			if !to.After(from) {
+				continue
+			}
+			busy += float64(weight) * float64(to.Sub(from))
+			edges = append(edges, edge{at: from, delta: weight}, edge{at: to, delta: -weight})
+		}
+	}
+
+	m.WaitSamples = len(waits)
+	if len(waits) > 0 {
+		sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+		m.P50WaitMS = percentile(waits, 50)
+		m.P90WaitMS = percentile(waits, 90)
+	}
+	if capacity > 0 && window > 0 {
+		m.Utilization = busy / (float64(capacity) * float64(window))
+	}
+
+	// Ends sort before starts at the same instant, so back-to-back nodes do not overlap.
+	sort.Slice(edges, func(i, j int) bool {
+		if !edges[i].at.Equal(edges[j].at) {
+			return edges[i].at.Before(edges[j].at)
+		}
+		return edges[i].delta < edges[j].delta
+	})
+	inService := 0
+	for _, e := range edges {
+		inService += e.delta
+		m.PeakInService = max(m.PeakInService, inService)
+	}
+	return m
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must not be empty.
+func percentile(sorted []int64, p int) int64 {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// recommendCapacity turns m into a recommendation under policy.
+//
+// An increase scales capacity by p90/target, growing by at most the current capacity at a time.
+// A decrease trims capacity to the peak in use, and is only offered when waits are on target.
+// Nothing is recommended for a resource with no activity in the window.
+func recommendCapacity(resourceID string, m ResourceMetrics, policy RecommendationPolicy) CapacityRecommendation {
+	rec := CapacityRecommendation{
+		ResourceID:          resourceID,
+		Stats:               m,
+		TargetP90WaitMS:     policy.TargetP90Wait.Milliseconds(),
+		Action:              RecommendNone,
+		CurrentCapacity:     m.Capacity,
+		RecommendedCapacity: m.Capacity,
+	}
+	target := policy.TargetP90Wait.Milliseconds()
+
+	switch {
+	case m.WaitSamples == 0 && m.PeakInService == 0:
+		rec.Rationale = "no activity on the resource in the window"
+
+	case m.WaitSamples > 0 && m.WaitSamples < policy.MinSamples && m.P90WaitMS > target:
+		rec.Rationale = fmt.Sprintf("p90 wait %dms is above the %dms target, but only %d waits were seen (need %d)", m.P90WaitMS, target, m.WaitSamples, policy.MinSamples)
+
+	case m.WaitSamples >= policy.MinSamples && m.P90WaitMS > target:
+		want := m.Capacity
+		if target > 0 {
+			want = int(math.Ceil(float64(max(m.Capacity, 1)) * float64(m.P90WaitMS) / float64(target)))
+		}
+		delta := min(max(want-m.Capacity, 1), max(m.Capacity, 1))
+		rec.Action = RecommendIncrease
+		rec.Delta = delta
+		rec.RecommendedCapacity = m.Capacity + delta
+		rec.Rationale = fmt.Sprintf("p90 wait %dms over %d waits is above the %dms target", m.P90WaitMS, m.WaitSamples, target)
+
+	case m.Utilization < policy.LowUtilization && m.PeakInService < m.Capacity && m.Capacity > 1:
+		recommended := max(m.PeakInService, 1)
+		rec.Action = RecommendDecrease
+		rec.Delta = recommended - m.Capacity
+		rec.RecommendedCapacity = recommended
+		rec.Rationale = fmt.Sprintf("average utilization %.0f%% is below %.0f%% and at most %d of %d units were in use", m.Utilization*100, policy.LowUtilization*100, m.PeakInService, m.Capacity)
+
+	default:
+		rec.Rationale = fmt.Sprintf("p90 wait %dms is within the %dms target and average utilization is %.0f%%", m.P90WaitMS, target, m.Utilization*100)
+	}
+	return rec
+}
+
+// RecommendCapacity is RecommendCapacityContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) RecommendCapacity(resourceID string, policy RecommendationPolicy) (CapacityRecommendation, error) {
+	return qs.RecommendCapacityContext(context.Background(), resourceID, policy)
+}
+
+// RecommendCapacityContext computes resourceID's recent metrics (see computeResourceMetrics) and
+// the capacity change policy suggests for them. Returns ErrResourceNotFound for unknown resources.
+func (qs *QueueService) RecommendCapacityContext(ctx context.Context, resourceID string, policy RecommendationPolicy) (_ CapacityRecommendation, err error) {
+	ctx, span := startSpan(ctx, "QueueService.RecommendCapacity")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(attrResourceID.String(resourceID))
+
+	qs.mu.RLock()
+	resourceID = qs.LookupResourceID(resourceID)
+	r, exists := qs.resources[resourceID]
+	capacity := 0
+	if exists {
+		capacity = r.Capacity
+	}
+	qs.mu.RUnlock()
+	if !exists {
+		return CapacityRecommendation{}, ErrResourceNotFound
+	}
+
+	m := computeResourceMetrics(node.Now(), policy.Window, resourceID, capacity, qs.nodeHistories(ctx))
+	return recommendCapacity(resourceID, m, policy), nil
+}
+
+// RecommendationHandler handles GET /resources/{id}/recommendation[?window=&target_p90_wait=].
+// window and target_p90_wait are Go durations overriding qs.Recommendation for this request.
+func (qs *QueueService) RecommendationHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /resources/%s/recommendation - Request", resourceID)
+
+	policy := qs.Recommendation
+	q := r.URL.Query()
+	fields := make(map[string]string)
+	if raw := q.Get("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			fields["window"] = "must be a positive duration (e.g. 1h)"
+		} else {
+			policy.Window = d
+		}
+	}
+	if raw := q.Get("target_p90_wait"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			fields["target_p90_wait"] = "must be a positive duration (e.g. 5m)"
+		} else {
+			policy.TargetP90Wait = d
+		}
+	}
+	if len(fields) > 0 {
+		err := &utils.ValidationError{Fields: fields}
+		log.Printf("[API] GET /resources/%s/recommendation - ERROR: %v", resourceID, err)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	rec, err := qs.RecommendCapacityContext(r.Context(), resourceID, policy)
+	if err != nil {
+		log.Printf("[API] GET /resources/%s/recommendation - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /resources/%s/recommendation - SUCCESS: %s to %d (took %v)", resourceID, rec.Action, rec.RecommendedCapacity, duration)
+	utils.RespondWithJSON(w, http.StatusOK, rec)
+}
@@ -0,0 +1,84 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// SetResourceRedirect is SetResourceRedirectContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) SetResourceRedirect(fromID, toID string) error {
+	return qs.SetResourceRedirectContext(context.Background(), fromID, toID)
+}
+
+// SetResourceRedirectContext sends moves into fromID to toID instead, e.g. while fromID is down
+// for maintenance, so clients keep working without knowing about it. An empty toID clears the
+// redirect. Redirects apply to MoveNode, bulk and entity moves only, are not chained (a move into
+// fromID lands on toID even if toID is itself redirected) and are held in memory only.
+//
+// Both resources must exist; redirecting a resource to itself returns ErrSameResource.
+func (qs *QueueService) SetResourceRedirectContext(ctx context.Context, fromID, toID string) (err error) {
+	_, span := startSpan(ctx, "QueueService.SetResourceRedirect",
+		attrResourceID.String(fromID), attrTargetResourceID.String(toID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	fromID = qs.LookupResourceID(fromID)
+	if _, exists := qs.resources[fromID]; !exists {
+		return ErrResourceNotFound
+	}
+	if toID == "" {
+		delete(qs.redirects, fromID)
+		return nil
+	}
+	toID = qs.LookupResourceID(toID)
+	if toID == fromID {
+		return ErrSameResource
+	}
+	if _, exists := qs.resources[toID]; !exists {
+		return fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+	if qs.redirects == nil {
+		qs.redirects = make(map[string]string)
+	}
+	qs.redirects[fromID] = toID
+	return nil
+}
+
+// SetResourceRedirectHandler handles POST /resources/{id}/redirect.
+//
+// Returns the resource detail, including redirect_to while a redirect is set.
+func (qs *QueueService) SetResourceRedirectHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/redirect - Request", resourceID)
+
+	var req resource.RedirectRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources/%s/redirect - ERROR: %v", resourceID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.SetResourceRedirectContext(r.Context(), resourceID, req.To); err != nil {
+		log.Printf("[API] POST /resources/%s/redirect - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	detail, err := qs.GetResourceDetail(resourceID, false)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/redirect - SUCCESS: redirect_to=%q (took %v)", resourceID, detail.RedirectTo, duration)
+	utils.RespondWithJSON(w, http.StatusOK, detail)
+}
@@ -0,0 +1,91 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/resource"
+)
+
+// CreateNodeWithCapacity is CreateNodeWithCapacityContext with context.Background(), for non-HTTP
+// callers.
+func (qs *QueueService) CreateNodeWithCapacity(nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string) (*node.Node, error) {
+	return qs.CreateNodeWithCapacityContext(context.Background(), nodeID, entityName, weight, resourceID, tags, allowedResources)
+}
+
+// CreateNodeWithCapacityContext is CreateNodeOnResourceContext for clients that only submit work
+// the system can start immediately: the node is created and allocated into resourceID's service
+// queue under one lock, or not created at all.
+//
+// If the resource could not take the node right now (paused, too little free capacity for its
+// weight, its entity at MaxPerEntity, a FIFOStrict resource with nodes already waiting, or its
+// AllocRatePerSec used up) it returns an error wrapping ErrCapacityUnavailable. Unlike
+// CreateNodeOnResourceContext, an unknown resource, one allowedResources excludes, or a veto from
+// AdmissionFunc creates nothing.
+func (qs *QueueService) CreateNodeWithCapacityContext(ctx context.Context, nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string) (*node.Node, error) {
+	return qs.createNodeWithCapacity(ctx, nodeID, entityName, weight, resourceID, tags, allowedResources, nil)
+}
+
+// createNodeWithCapacity is CreateNodeWithCapacityContext for a node created with a deadline (nil
+// for none; see SetNodeDeadline).
+func (qs *QueueService) createNodeWithCapacity(ctx context.Context, nodeID, entityName string, weight int, resourceID string, tags, allowedResources []string, deadline *time.Time) (_ *node.Node, err error) {
+	ctx, span := startSpan(ctx, "QueueService.CreateNodeWithCapacity", attrTargetResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+	defer qs.evictOverCap(ctx) // runs after the unlock below
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	target, exists := qs.resources[resourceID]
+	if !exists {
+		return nil, fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+	if len(allowedResources) > 0 && !slices.Contains(allowedResources, resourceID) {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotAllowed, resourceID)
+	}
+
+	var cause error
+	_, waiting := target.QueueSnapshot()
+	switch {
+	case target.IsPaused():
+		cause = ErrResourcePaused
+	case max(weight, 1) > target.AvailableCapacityForLane(resource.DefaultLane):
+		cause = ErrCapacityFull
+	case target.EntityAtLimit(entityName):
+		cause = ErrEntityLimit
+	case target.FIFOStrict && len(waiting) > 0:
+		cause = ErrNotQueueHead
+	}
+	if cause != nil {
+		return nil, fmt.Errorf("%w on %s: %v", ErrCapacityUnavailable, resourceID, cause)
+	}
+
+	node, err := qs.createNodeLocked(nodeID, entityName, weight)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attrNodeID.String(node.ID))
+
+	// Consult AdmissionFunc before anything is persisted, so a veto leaves nothing behind.
+	if err := qs.admitLocked(ctx, node, target); err != nil {
+		qs.discardCreatedLocked(node)
+		return nil, err
+	}
+	if !target.AllowAllocation(time.Now()) {
+		qs.discardCreatedLocked(node)
+		return nil, fmt.Errorf("%w on %s: %v", ErrCapacityUnavailable, resourceID, ErrAllocationRateExceeded)
+	}
+	if err := qs.enqueueCreatedLocked(ctx, node, target, tags, allowedResources, deadline); err != nil {
+		qs.discardCreatedLocked(node)
+		return nil, err
+	}
+	if err := qs.promoteLocked(ctx, node, target); err != nil {
+		// Unreachable while qs.mu is held: the preconditions were checked above.
+		return node, err
+	}
+	return node, nil
+}
@@ -0,0 +1,120 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReserveCapacity holds one unit of capacity on a resource for an incoming node.
+//
+// The hold counts against IsFull/GetAvailableCapacity until it is claimed via ClaimReservation
+// or the TTL elapses, whichever comes first. Expired holds are released automatically.
+func (qs *QueueService) ReserveCapacity(resourceID string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", ErrInvalidTTL
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	resource, exists := qs.resources[resourceID]
+	if !exists {
+		return "", ErrResourceNotFound
+	}
+
+	reservationID := uuid.New().String()
+	if ok := resource.Reserve(reservationID, time.Now().Add(ttl)); !ok {
+		return "", ErrCapacityFull
+	}
+	return reservationID, nil
+}
+
+// ClaimReservation is ClaimReservationContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ClaimReservation(reservationID, nodeID string) error {
+	return qs.ClaimReservationContext(context.Background(), reservationID, nodeID)
+}
+
+// ClaimReservationContext converts an active reservation into an allocation for the given node.
+//
+// The node must be waiting on the reserved resource. On success the node is promoted into the
+// service queue (using the reserved slot) and a "moved_to_service_queue" log entry is recorded.
+// The claim counts against the resource's AllocRatePerSec like any other allocation.
+func (qs *QueueService) ClaimReservationContext(ctx context.Context, reservationID, nodeID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.ClaimReservation", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	node, exists := qs.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	if node.Completed {
+		return fmt.Errorf("cannot claim reservation: %w", ErrNodeCompleted)
+	}
+
+	var resourceID string
+	for id, r := range qs.resources {
+		if r.HasReservation(reservationID) {
+			resourceID = id
+			break
+		}
+	}
+	if resourceID == "" {
+		return ErrReservationNotFound
+	}
+
+	if node.ResourceID != resourceID {
+		return fmt.Errorf("node must be waiting on reserved resource %s: %w", resourceID, ErrNodeNotWaiting)
+	}
+
+	resource := qs.resources[resourceID]
+	if resource.IsInService(nodeID) {
+		return ErrNodeInService
+	}
+
+	if resource.IsPaused() {
+		return ErrResourcePaused
+	}
+
+	// A reservation holds capacity, not a fairness exemption.
+	if node.Entity != nil && resource.EntityAtLimit(node.Entity.Name) {
+		return ErrEntityLimit
+	}
+
+	if err := qs.admitLocked(ctx, node, resource); err != nil {
+		return err
+	}
+
+	// Nor an exemption from AllocRatePerSec.
+	if !resource.AllowAllocation(time.Now()) {
+		return ErrAllocationRateExceeded
+	}
+
+	if ok := resource.ClaimReservation(reservationID, nodeID); !ok {
+		// The hold expired between checks, the node left the waiting queue, or the node is heavier
+		// than the one unit the reservation held and the rest does not fit.
+		if !resource.HasReservation(reservationID) {
+			return ErrReservationNotFound
+		}
+		if !resource.IsWaiting(nodeID) {
+			return ErrNodeNotWaiting
+		}
+		return ErrCapacityFull
+	}
+
+	ts := qs.addNodeLog(node, "moved_to_service_queue", resourceID)
+
+	// Persist audit trail (best-effort).
+	rid := resourceID
+	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_service_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, node.ID, "moved_to_service_queue", &rid, ts)
+	})
+	return nil
+}
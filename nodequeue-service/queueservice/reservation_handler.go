@@ -0,0 +1,75 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// ReservationResponse is the response payload for POST /resources/{id}/reserve.
+type ReservationResponse struct {
+	ReservationID string    `json:"reservation_id"`
+	ResourceID    string    `json:"resource_id"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ReserveCapacityHandler handles POST /resources/{id}/reserve.
+//
+// Holds one unit of capacity for ttl_seconds; claim it with POST /nodes/{id}/claim.
+func (qs *QueueService) ReserveCapacityHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/reserve - Request", resourceID)
+
+	var req resource.ReserveCapacityRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources/%s/reserve - ERROR: %v", resourceID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	reservationID, err := qs.ReserveCapacity(resourceID, ttl)
+	if err != nil {
+		log.Printf("[API] POST /resources/%s/reserve - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/reserve - SUCCESS: Reservation %s (took %v)", resourceID, reservationID, duration)
+	utils.RespondWithJSON(w, http.StatusCreated, ReservationResponse{
+		ReservationID: reservationID,
+		ResourceID:    resourceID,
+		ExpiresAt:     startTime.Add(ttl),
+	})
+}
+
+// ClaimReservationHandler handles POST /nodes/{id}/claim.
+//
+// Converts a reservation into an allocation for a node waiting on the reserved resource.
+func (qs *QueueService) ClaimReservationHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/claim - Request", nodeID)
+
+	var req node.ClaimReservationRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/claim - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.ClaimReservationContext(r.Context(), req.ReservationID, nodeID); err != nil {
+		log.Printf("[API] POST /nodes/%s/claim - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/claim - SUCCESS: Claimed reservation %s (took %v)", nodeID, req.ReservationID, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
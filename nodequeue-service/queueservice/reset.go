@@ -0,0 +1,95 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// ResetResponse is the response payload for POST /admin/reset.
+type ResetResponse struct {
+	NodesRemoved int  `json:"nodes_removed"`
+	PurgedDB     bool `json:"purged_db"`
+}
+
+// Reset is ResetContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) Reset(purgeDB bool) (int, error) {
+	return qs.ResetContext(context.Background(), purgeDB)
+}
+
+// ResetContext drops every node and empties all resource queues and reservations in one step;
+// resources themselves are kept. With purgeDB, the store's node tables are truncated first and
+// memory is left untouched if that fails. It is meant for tests and staging and returns the
+// number of nodes removed.
+//
+// purgeDB without a store returns ErrStoreUnavailable.
+func (qs *QueueService) ResetContext(ctx context.Context, purgeDB bool) (removed int, err error) {
+	ctx, span := startSpan(ctx, "QueueService.Reset")
+	defer func() { endSpan(span, err) }()
+
+	if purgeDB && qs.store == nil {
+		return 0, ErrStoreUnavailable
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	if purgeDB {
+		if err := traceStore(ctx, "DeleteAllNodes", qs.store.DeleteAllNodes); err != nil {
+			return 0, err
+		}
+	}
+
+	removed = len(qs.nodes)
+	qs.nodes = make(map[string]*node.Node)
+	qs.activeNodes = nil
+	qs.activeByEntity = nil
+	for _, r := range qs.resources {
+		r.Clear()
+	}
+	return removed, nil
+}
+
+// ResetHandler handles POST /admin/reset[?purge_db=true].
+//
+// The route is wrapped in utils.AdminGuard, so it is refused unless ENABLE_ADMIN is set.
+func (qs *QueueService) ResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] POST /admin/reset - Request")
+
+	purgeDB := false
+	if raw := r.URL.Query().Get("purge_db"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Printf("[API] POST /admin/reset - ERROR: invalid purge_db %q", raw)
+			utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+				Error:  "Invalid query parameters",
+				Code:   CodeInvalidRequest,
+				Fields: map[string]string{"purge_db": "must be a boolean"},
+			})
+			return
+		}
+		purgeDB = v
+	}
+
+	removed, err := qs.ResetContext(r.Context(), purgeDB)
+	if err != nil {
+		log.Printf("[API] POST /admin/reset - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /admin/reset - SUCCESS: Removed %d nodes, purge_db=%t (took %v)", removed, purgeDB, duration)
+	utils.RespondWithJSON(w, http.StatusOK, ResetResponse{NodesRemoved: removed, PurgedDB: purgeDB})
+}
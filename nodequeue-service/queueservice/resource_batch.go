@@ -0,0 +1,106 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// CreateResourcesResponse is the response payload for POST /resources/batch.
+type CreateResourcesResponse struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// CreateResources is CreateResourcesContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CreateResources(resources []*resource.Resource) []BulkResult {
+	return qs.CreateResourcesContext(context.Background(), resources)
+}
+
+// CreateResourcesContext registers each resource like CreateResourceContext, under a single hold
+// of qs.mu, and returns a result per resource in order. A resource whose ID already exists, or
+// appears earlier in the same batch, fails with ErrResourceExists; the others are still created.
+// All created resources are persisted in one store transaction (best-effort).
+func (qs *QueueService) CreateResourcesContext(ctx context.Context, resources []*resource.Resource) []BulkResult {
+	ctx, span := startSpan(ctx, "QueueService.CreateResources")
+	defer func() { endSpan(span, nil) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	results := make([]BulkResult, 0, len(resources))
+	created := make([]*resource.Resource, 0, len(resources))
+	for _, r := range resources {
+		res := BulkResult{ID: r.ID, OK: true}
+		id, err := qs.canonicalResourceID(r.ID)
+		if err == nil {
+			if _, exists := qs.resources[id]; exists {
+				err = ErrResourceExists
+			}
+		}
+		if err != nil {
+			res.OK = false
+			_, res.Code = errorStatus(err)
+			res.Error = err.Error()
+		} else {
+			r.ID = id
+			res.ID = id
+			qs.resources[id] = r
+			created = append(created, r)
+		}
+		results = append(results, res)
+	}
+
+	if len(created) > 0 {
+		qs.bestEffortPersist(ctx, "InsertResources", func(ctx context.Context) error {
+			return qs.store.InsertResources(ctx, created)
+		})
+	}
+	return results
+}
+
+// CreateResourcesHandler handles POST /resources/batch.
+//
+// Returns 200 once the request is valid; resources that could not be created (duplicate IDs)
+// are reported in the results.
+func (qs *QueueService) CreateResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+
+	var req resource.CreateResourcesRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources/batch - ERROR: %v", err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	log.Printf("[API] POST /resources/batch - Request: resources=%d", len(req))
+
+	resources := make([]*resource.Resource, 0, len(req))
+	for _, item := range req {
+		resources = append(resources, resourceFromRequest(item))
+	}
+	results := qs.CreateResourcesContext(r.Context(), resources)
+
+	resp := CreateResourcesResponse{Results: results}
+	for _, res := range results {
+		if res.OK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/batch - SUCCESS: %d created, %d failed (took %v)", resp.Succeeded, resp.Failed, duration)
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
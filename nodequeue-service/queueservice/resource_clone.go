@@ -0,0 +1,78 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// CloneResource is CloneResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) CloneResource(resourceID, newID string) (*resource.Resource, error) {
+	return qs.CloneResourceContext(context.Background(), resourceID, newID)
+}
+
+// CloneResourceContext creates and persists an empty resource newID with resourceID's
+// configuration (see resource.Resource.CloneConfig) and returns a snapshot of it. It returns
+// ErrResourceNotFound if resourceID does not exist and ErrResourceExists if newID does.
+func (qs *QueueService) CloneResourceContext(ctx context.Context, resourceID, newID string) (_ *resource.Resource, err error) {
+	ctx, span := startSpan(ctx, "QueueService.CloneResource",
+		attrResourceID.String(resourceID), attrTargetResourceID.String(newID))
+	defer func() { endSpan(span, err) }()
+
+	newID, err = qs.canonicalResourceID(newID)
+	if err != nil {
+		return nil, err
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	src, exists := qs.resources[qs.LookupResourceID(resourceID)]
+	if !exists {
+		return nil, ErrResourceNotFound
+	}
+	if _, exists := qs.resources[newID]; exists {
+		return nil, ErrResourceExists
+	}
+
+	clone := src.CloneConfig(newID)
+	qs.resources[newID] = clone
+
+	// Persist resource definition, settings and labels (best-effort).
+	config := clone.CloneConfig(newID)
+	qs.bestEffortPersist(ctx, "InsertResource", func(ctx context.Context) error {
+		return qs.store.InsertResource(ctx, config)
+	})
+
+	return clone.Snapshot(), nil
+}
+
+// CloneResourceHandler handles POST /resources/{id}/clone.
+//
+// Returns 201 with the new, empty resource.
+func (qs *QueueService) CloneResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/clone - Request", resourceID)
+
+	var req resource.CloneResourceRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources/%s/clone - ERROR: %v", resourceID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	clone, err := qs.CloneResourceContext(r.Context(), resourceID, req.NewID)
+	if err != nil {
+		log.Printf("[API] POST /resources/%s/clone - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/clone - SUCCESS: Created resource %s (took %v)", resourceID, clone.ID, duration)
+	utils.RespondWithJSON(w, http.StatusCreated, clone)
+}
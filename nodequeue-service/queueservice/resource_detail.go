@@ -0,0 +1,108 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// NodeSummary is a lightweight view of a node inside a resource queue.
+//
+// Status is the node's queue on the resource ("waiting" or "service"). Node is only populated
+// when the caller asks for full nodes (?include=nodes).
+type NodeSummary struct {
+	ID         string     `json:"id"`
+	EntityName string     `json:"entity_name"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Node       *node.Node `json:"node,omitempty"`
+}
+
+// ResourceDetailResponse is the response payload for GET /resources/{id}.
+type ResourceDetailResponse struct {
+	ID                string              `json:"id"`
+	Capacity          int                 `json:"capacity"`
+	AvailableCapacity int                 `json:"available_capacity"`
+	AutoPromote       bool                `json:"auto_promote"`
+	MaxPerEntity      int                 `json:"max_per_entity"`
+	FIFOStrict        bool                `json:"fifo_strict"`
+	Paused            bool                `json:"paused"`
+	RedirectTo        string              `json:"redirect_to,omitempty"`
+	LaneOrder         []string            `json:"lane_order,omitempty"`
+	Lanes             map[string][]string `json:"lanes"`
+	WaitingCount      int                 `json:"waiting_count"`
+	ServiceCount      int                 `json:"service_count"`
+	Waiting           []NodeSummary       `json:"waiting"`
+	Service           []NodeSummary       `json:"service"`
+}
+
+func summarizeNodes(nodes []*node.Node, status string, includeNodes bool) []NodeSummary {
+	out := make([]NodeSummary, 0, len(nodes))
+	for _, n := range nodes {
+		entityName := ""
+		if n.Entity != nil {
+			entityName = n.Entity.Name
+		}
+		s := NodeSummary{
+			ID:         n.ID,
+			EntityName: entityName,
+			Status:     status,
+			CreatedAt:  n.CreatedAt,
+		}
+		if includeNodes {
+			s.Node = n
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// GetResourceDetail builds a focused view of a single resource's queues.
+func (qs *QueueService) GetResourceDetail(resourceID string, includeNodes bool) (*ResourceDetailResponse, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	resource, exists := qs.resources[qs.LookupResourceID(resourceID)]
+	if !exists {
+		return nil, ErrResourceNotFound
+	}
+
+	service, waiting := resource.QueueSnapshot()
+	return &ResourceDetailResponse{
+		ID:                resource.ID,
+		Capacity:          resource.Capacity,
+		AvailableCapacity: resource.GetAvailableCapacity(),
+		AutoPromote:       resource.AutoPromote,
+		MaxPerEntity:      resource.MaxPerEntity,
+		FIFOStrict:        resource.FIFOStrict,
+		Paused:            resource.IsPaused(),
+		RedirectTo:        qs.redirects[resource.ID],
+		LaneOrder:         resource.LaneOrder,
+		Lanes:             resource.Lanes(),
+		WaitingCount:      len(waiting),
+		ServiceCount:      len(service),
+		Waiting:           summarizeNodes(waiting, string(db.QueueKindWaiting), includeNodes),
+		Service:           summarizeNodes(service, string(db.QueueKindService), includeNodes),
+	}, nil
+}
+
+// GetResourceHandler handles GET /resources/{id}[?include=nodes].
+// Returns 404 if the resource does not exist.
+func (qs *QueueService) GetResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	log.Printf("[API] GET /resources/%s - Request", resourceID)
+
+	includeNodes := r.URL.Query().Get("include") == "nodes"
+	detail, err := qs.GetResourceDetail(resourceID, includeNodes)
+	if err != nil {
+		log.Printf("[API] GET /resources/%s - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	log.Printf("[API] GET /resources/%s - SUCCESS: %d waiting, %d in service", resourceID, detail.WaitingCount, detail.ServiceCount)
+	utils.RespondWithJSON(w, http.StatusOK, detail)
+}
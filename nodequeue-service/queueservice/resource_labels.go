@@ -0,0 +1,129 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// PatchResource is PatchResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) PatchResource(resourceID string, req resource.PatchResourceRequest) (*resource.Resource, error) {
+	return qs.PatchResourceContext(context.Background(), resourceID, req)
+}
+
+// PatchResourceContext merges req.Labels into the resource's labels (a nil value removes the
+// label), persists the result (best-effort) and returns a snapshot of the resource. It returns
+// ErrResourceNotFound if the resource does not exist and ErrTooManyLabels if the merge would
+// leave more than resource.MaxLabels.
+func (qs *QueueService) PatchResourceContext(ctx context.Context, resourceID string, req resource.PatchResourceRequest) (_ *resource.Resource, err error) {
+	ctx, span := startSpan(ctx, "QueueService.PatchResource", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	res, exists := qs.resources[qs.LookupResourceID(resourceID)]
+	if !exists {
+		return nil, ErrResourceNotFound
+	}
+
+	labels := res.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string, len(req.Labels))
+	}
+	for key, value := range req.Labels {
+		if value == nil {
+			delete(labels, key)
+			continue
+		}
+		labels[key] = *value
+	}
+	if len(labels) > resource.MaxLabels {
+		return nil, ErrTooManyLabels
+	}
+	res.SetLabels(labels)
+	qs.persistResourceLabels(ctx, res.ID, labels)
+
+	return res.Snapshot(), nil
+}
+
+// persistResourceLabels writes a resource's full label set to the store (best-effort).
+func (qs *QueueService) persistResourceLabels(ctx context.Context, resourceID string, labels map[string]string) {
+	labels = maps.Clone(labels)
+	qs.bestEffortPersist(ctx, "SetResourceLabels", func(ctx context.Context) error {
+		return qs.store.SetResourceLabels(ctx, resourceID, labels)
+	})
+}
+
+// ResourcesMatchingLabels returns the sorted IDs of the resources carrying every label in
+// selector (see resource.Resource.MatchesLabels).
+func (qs *QueueService) ResourcesMatchingLabels(selector map[string]string) []string {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	ids := make([]string, 0, len(qs.resources))
+	for id, res := range qs.resources {
+		if res.MatchesLabels(selector) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// allowedResourcesForLabels narrows a new node's resource whitelist to the resources matching
+// labels: the matches themselves if allowed is empty, otherwise their intersection with allowed.
+// It returns ErrNoMatchingResources if nothing is left, and an error wrapping
+// ErrResourceNotAllowed if resourceID is set but excluded. With no labels, allowed is returned
+// unchanged.
+func (qs *QueueService) allowedResourcesForLabels(labels map[string]string, allowed []string, resourceID string) ([]string, error) {
+	if len(labels) == 0 {
+		return allowed, nil
+	}
+	matches := qs.ResourcesMatchingLabels(labels)
+	if len(allowed) > 0 {
+		matches = slices.DeleteFunc(matches, func(id string) bool {
+			return !slices.Contains(allowed, id)
+		})
+	}
+	if len(matches) == 0 {
+		return nil, ErrNoMatchingResources
+	}
+	if resourceID != "" && !slices.Contains(matches, qs.LookupResourceID(resourceID)) {
+		return nil, fmt.Errorf("%w: %s does not match resource_labels", ErrResourceNotAllowed, resourceID)
+	}
+	return matches, nil
+}
+
+// PatchResourceHandler handles PATCH /resources/{id}.
+//
+// Returns the updated resource. Only labels can be patched.
+func (qs *QueueService) PatchResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] PATCH /resources/%s - Request", resourceID)
+
+	var req resource.PatchResourceRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] PATCH /resources/%s - ERROR: %v", resourceID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	res, err := qs.PatchResourceContext(r.Context(), resourceID, req)
+	if err != nil {
+		log.Printf("[API] PATCH /resources/%s - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] PATCH /resources/%s - SUCCESS: %d labels (took %v)", resourceID, len(res.Labels), duration)
+	utils.RespondWithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,113 @@
+package queueservice
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"slices"
+
+	"nodequeue-service/resource"
+)
+
+// Sort keys accepted by ListResourcesSorted (GET /resources?sort=).
+const (
+	ResourceSortID          = "id"
+	ResourceSortUtilization = "utilization"
+	ResourceSortWaiting     = "waiting"
+)
+
+// Sort orders accepted by ListResourcesSorted (GET /resources?order=).
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// ResourceLoad is a resource annotated with its current load, as returned by GET /resources.
+//
+// Utilization is the fraction of capacity units in use (service nodes by weight plus active
+// reservations); a resource with no capacity reports 0.
+type ResourceLoad struct {
+	*resource.Resource
+	Utilization  float64 `json:"utilization"`
+	ServiceCount int     `json:"service_count"`
+	WaitingDepth int     `json:"waiting_depth"`
+}
+
+// MarshalJSON writes the resource's own JSON (see resource.Resource.MarshalJSON, which would
+// otherwise be promoted and drop the load fields) with the load fields appended.
+func (l ResourceLoad) MarshalJSON() ([]byte, error) {
+	base, err := json.Marshal(l.Resource)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := json.Marshal(struct {
+		Utilization  float64 `json:"utilization"`
+		ServiceCount int     `json:"service_count"`
+		WaitingDepth int     `json:"waiting_depth"`
+	}{l.Utilization, l.ServiceCount, l.WaitingDepth})
+	if err != nil {
+		return nil, err
+	}
+	// Both are JSON objects: drop base's closing brace and extra's opening one.
+	base = bytes.TrimSuffix(bytes.TrimSpace(base), []byte("}"))
+	return append(append(base, ','), extra[1:]...), nil
+}
+
+func newResourceLoad(snap *resource.Resource) ResourceLoad {
+	load := ResourceLoad{
+		Resource:     snap,
+		ServiceCount: len(snap.Nodes),
+		WaitingDepth: len(snap.WaitingQueue),
+	}
+	if snap.Capacity > 0 {
+		load.Utilization = float64(snap.Capacity-snap.GetAvailableCapacity()) / float64(snap.Capacity)
+	}
+	return load
+}
+
+// ListResourcesSorted returns snapshots of all resources annotated with their load.
+//
+// sortBy is ResourceSortID (the default), ResourceSortUtilization or ResourceSortWaiting; order is
+// SortOrderAsc (the default) or SortOrderDesc. Ties are broken by ascending ID.
+func (qs *QueueService) ListResourcesSorted(sortBy, order string) ([]ResourceLoad, error) {
+	switch sortBy {
+	case "":
+		sortBy = ResourceSortID
+	case ResourceSortID, ResourceSortUtilization, ResourceSortWaiting:
+	default:
+		return nil, ErrInvalidResourceSort
+	}
+	switch order {
+	case "":
+		order = SortOrderAsc
+	case SortOrderAsc, SortOrderDesc:
+	default:
+		return nil, ErrInvalidSortOrder
+	}
+
+	snaps := qs.ListResources()
+	out := make([]ResourceLoad, 0, len(snaps))
+	for _, snap := range snaps {
+		out = append(out, newResourceLoad(snap))
+	}
+
+	slices.SortStableFunc(out, func(a, b ResourceLoad) int {
+		var c int
+		switch sortBy {
+		case ResourceSortUtilization:
+			c = cmp.Compare(a.Utilization, b.Utilization)
+		case ResourceSortWaiting:
+			c = cmp.Compare(a.WaitingDepth, b.WaitingDepth)
+		default:
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if order == SortOrderDesc {
+			c = -c
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		return c
+	})
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// PauseResource is PauseResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) PauseResource(resourceID string) error {
+	return qs.PauseResourceContext(context.Background(), resourceID)
+}
+
+// PauseResourceContext stops allocations into a resource for maintenance.
+//
+// While paused, AllocateNode, ClaimReservation and FillResource return ErrResourcePaused and
+// auto-promotion skips the resource. Nodes may still be moved into its waiting queue, and nodes
+// already in service are unaffected. Pausing a paused resource is a no-op.
+func (qs *QueueService) PauseResourceContext(ctx context.Context, resourceID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.PauseResource", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	return qs.setPaused(ctx, resourceID, true)
+}
+
+// ResumeResource is ResumeResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ResumeResource(resourceID string) error {
+	return qs.ResumeResourceContext(context.Background(), resourceID)
+}
+
+// ResumeResourceContext re-enables allocations into a paused resource. If the resource has
+// AutoPromote enabled, waiting nodes are promoted into any free slots straight away since
+// completions during the pause did not promote anyone.
+func (qs *QueueService) ResumeResourceContext(ctx context.Context, resourceID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.ResumeResource", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	return qs.setPaused(ctx, resourceID, false)
+}
+
+func (qs *QueueService) setPaused(ctx context.Context, resourceID string, paused bool) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	resource, exists := qs.resources[resourceID]
+	if !exists {
+		return ErrResourceNotFound
+	}
+	resource.SetPaused(paused)
+
+	// Persist pause state (best-effort).
+	qs.bestEffortPersist(ctx, "SetResourcePaused", func(ctx context.Context) error {
+		return qs.store.SetResourcePaused(ctx, resourceID, paused)
+	})
+
+	if !paused && resource.AutoPromote {
+		qs.fillLocked(ctx, resource, 0)
+	}
+	return nil
+}
+
+// PauseResourceHandler handles POST /resources/{id}/pause.
+func (qs *QueueService) PauseResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	qs.setPausedHandler(w, r, resourceID, "pause", qs.PauseResourceContext)
+}
+
+// ResumeResourceHandler handles POST /resources/{id}/resume.
+func (qs *QueueService) ResumeResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	qs.setPausedHandler(w, r, resourceID, "resume", qs.ResumeResourceContext)
+}
+
+// setPausedHandler runs op for POST /resources/{id}/{action} and returns the resource detail.
+func (qs *QueueService) setPausedHandler(w http.ResponseWriter, r *http.Request, resourceID, action string, op func(context.Context, string) error) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/%s - Request", resourceID, action)
+
+	if err := op(r.Context(), resourceID); err != nil {
+		log.Printf("[API] POST /resources/%s/%s - ERROR: %v", resourceID, action, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	detail, err := qs.GetResourceDetail(resourceID, false)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/%s - SUCCESS: paused=%t (took %v)", resourceID, action, detail.Paused, duration)
+	utils.RespondWithJSON(w, http.StatusOK, detail)
+}
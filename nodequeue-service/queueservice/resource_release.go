@@ -0,0 +1,77 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// ReleaseResponse is the response payload for POST /resources/{id}/release.
+type ReleaseResponse struct {
+	ResourceID string `json:"resource_id"`
+	Released   int    `json:"released"`
+}
+
+// ReleaseResource is ReleaseResourceContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) ReleaseResource(resourceID string) (int, error) {
+	return qs.ReleaseResourceContext(context.Background(), resourceID)
+}
+
+// ReleaseResourceContext moves every node in a resource's service queue back to its waiting queue
+// in a single atomic step (see resource.Resource.ReleaseServiceNodes), freeing all of its capacity
+// apart from reservations, and returns how many nodes were released.
+//
+// Each released node is logged and persisted as "released" followed by "moved_to_waiting_queue".
+// Auto-promotion is not run, so the freed slots stay free until nodes are allocated again; pause
+// the resource first to keep fill from refilling it. It returns ErrResourceNotFound if the
+// resource does not exist.
+func (qs *QueueService) ReleaseResourceContext(ctx context.Context, resourceID string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "QueueService.ReleaseResource", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	res, exists := qs.resources[qs.LookupResourceID(resourceID)]
+	if !exists {
+		return 0, ErrResourceNotFound
+	}
+
+	released := res.ReleaseServiceNodes()
+	rid := res.ID
+	for _, n := range released {
+		releasedAt := qs.addNodeLog(n, "released", rid)
+		requeuedAt := qs.addNodeLog(n, "moved_to_waiting_queue", rid)
+
+		// Persist audit trail (best-effort).
+		nodeID := n.ID
+		qs.bestEffortPersist(ctx, "InsertNodeLog(released)", func(ctx context.Context) error {
+			return qs.store.InsertNodeLog(ctx, nodeID, "released", &rid, releasedAt)
+		})
+		qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+			return qs.store.InsertNodeLog(ctx, nodeID, "moved_to_waiting_queue", &rid, requeuedAt)
+		})
+	}
+
+	return len(released), nil
+}
+
+// ReleaseResourceHandler handles POST /resources/{id}/release.
+func (qs *QueueService) ReleaseResourceHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/release - Request", resourceID)
+
+	released, err := qs.ReleaseResourceContext(r.Context(), resourceID)
+	if err != nil {
+		log.Printf("[API] POST /resources/%s/release - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/release - SUCCESS: Released %d nodes (took %v)", resourceID, released, duration)
+	utils.RespondWithJSON(w, http.StatusOK, ReleaseResponse{ResourceID: qs.LookupResourceID(resourceID), Released: released})
+}
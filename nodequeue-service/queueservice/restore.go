@@ -0,0 +1,387 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RestoreSummary reports what RestoreFromStore or MergeFromStore rebuilt.
+type RestoreSummary struct {
+	// NodesRestored is how many nodes were (re)built from the store.
+	NodesRestored int `json:"nodes_restored"`
+	// NodesKept is how many in-memory nodes the store did not know and were left as they were.
+	NodesKept int `json:"nodes_kept"`
+	// QueuesRebuilt is how many resources received restored nodes in their queues.
+	QueuesRebuilt int `json:"queues_rebuilt"`
+}
+
+// storeState is everything RestoreFromStore reads from the store, fetched before qs.mu is taken.
+type storeState struct {
+	persisted []db.PersistedNode
+	states    map[string]db.NodeState
+	extras    map[string]db.NodeExtras
+
+	// sources records every read's outcome in order, and errs each failed read's error wrapped
+	// with its source (see record).
+	sources []RestoreSourceStatus
+	errs    []error
+}
+
+// loadStoreState reads the store's node state. With includeCompleted, completed nodes are read
+// too (see Store.ListAllNodes). Reads go to the primary even when the store has a read replica, so a
+// lagging replica cannot drop recent nodes from the rebuilt state.
+//
+// Without partial, the first failed read of nodes, node states or node extras is returned as the
+// error. With partial, failures are only recorded in the returned state and the remaining reads
+// go ahead, except that nothing else is read once the nodes cannot be. A failed node log backfill
+// is always only recorded (see backfillNodeStates). The returned state is never nil.
+func (qs *QueueService) loadStoreState(ctx context.Context, includeCompleted, partial bool) (*storeState, error) {
+	ctx = db.WithPrimaryReads(ctx)
+	st := &storeState{}
+	op := "ListNodes"
+	if includeCompleted {
+		op = "ListAllNodes"
+	}
+	err := traceStore(ctx, op, func(ctx context.Context) (err error) {
+		if includeCompleted {
+			st.persisted, err = qs.store.ListAllNodes(ctx)
+		} else {
+			st.persisted, err = qs.store.ListNodes(ctx)
+		}
+		return err
+	})
+	st.record(RestoreSourceNodes, err)
+	if err != nil {
+		if partial {
+			return st, nil
+		}
+		return st, st.errs[len(st.errs)-1]
+	}
+
+	err = traceStore(ctx, "ListLatestNodeStates", func(ctx context.Context) (err error) {
+		st.states, err = qs.store.ListLatestNodeStates(ctx)
+		return err
+	})
+	st.record(RestoreSourceNodeStates, err)
+	if err != nil && !partial {
+		return st, st.errs[len(st.errs)-1]
+	}
+	qs.backfillNodeStates(ctx, st)
+
+	nodeIDs := make([]string, len(st.persisted))
+	for i, pn := range st.persisted {
+		nodeIDs[i] = pn.NodeID
+	}
+	err = traceStore(ctx, "ListNodeExtras", func(ctx context.Context) (err error) {
+		st.extras, err = qs.store.ListNodeExtras(ctx, nodeIDs)
+		return err
+	})
+	st.record(RestoreSourceNodeExtras, err)
+	if err != nil && !partial {
+		return st, st.errs[len(st.errs)-1]
+	}
+	return st, nil
+}
+
+// backfillNodeStates reconstructs the queue placement of active, assigned nodes that
+// ListLatestNodeStates did not report (all of them, if it failed), by replaying their full
+// node_logs, so a node whose last placement was moved_to_service_queue is not restored as
+// waiting. Nodes with no placement log at all keep the default (waiting, ordered by CreatedAt).
+// A store error is recorded as RestoreSourceNodeLogs and leaves the defaults in place.
+func (qs *QueueService) backfillNodeStates(ctx context.Context, st *storeState) {
+	missing := make([]string, 0)
+	for _, pn := range st.persisted {
+		if _, ok := st.states[pn.NodeID]; !ok && pn.ResourceID != nil && !pn.Completed {
+			missing = append(missing, pn.NodeID)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	var logs map[string][]db.NodeLogRow
+	err := traceStore(ctx, "ListNodeLogs", func(ctx context.Context) (err error) {
+		logs, err = qs.store.ListNodeLogs(ctx, missing)
+		return err
+	})
+	st.record(RestoreSourceNodeLogs, err)
+	if err != nil {
+		log.Printf("[DB] restoring %d nodes without a state as waiting", len(missing))
+		return
+	}
+
+	if st.states == nil {
+		st.states = make(map[string]db.NodeState, len(missing))
+	}
+	for _, id := range missing {
+		for _, row := range logs[id] {
+			switch row.Action {
+			case "moved_to_waiting_queue":
+				st.states[id] = db.NodeState{Queue: db.QueueKindWaiting, TS: row.TS}
+			case "moved_to_service_queue":
+				st.states[id] = db.NodeState{Queue: db.QueueKindService, TS: row.TS}
+			}
+		}
+	}
+}
+
+// RestoreFromStore rebuilds the in-memory node state from the configured Store.
+// It is intended to be called on startup after resources have been loaded into qs; any nodes
+// already in memory are dropped (see MergeFromStore to keep them).
+//
+// Placement rules:
+//   - nodes with resource_id get placed into waiting or service queue based on latest node_log action
+//     (moved_to_waiting_queue vs moved_to_service_queue), replaying the node's full log when
+//     ListLatestNodeStates has no state for it (see backfillNodeStates)
+//   - ordering within each queue is by that latest relevant log timestamp ascending.
+//
+// A failed read does not stop the restore; it restores what it can and records every source's
+// outcome in RestoreStatus:
+//   - nodes: no nodes are restored.
+//   - node_states: placements are replayed from node_logs instead.
+//   - node_logs: nodes without a state are restored as waiting.
+//   - node_extras: nodes are restored without notes, tags, allowed resources or result.
+//
+// With RestoreStrict, any failed read instead returns an error and nothing is restored.
+func (qs *QueueService) RestoreFromStore(ctx context.Context) (err error) {
+	if qs.store == nil {
+		return nil
+	}
+
+	ctx, span := startSpan(ctx, "QueueService.RestoreFromStore")
+	defer func() { endSpan(span, err) }()
+
+	st, err := qs.loadStoreState(ctx, false, !qs.RestoreStrict)
+	if err == nil && qs.RestoreStrict && len(st.errs) > 0 {
+		err = st.errs[0]
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	qs.recordRestoreSourcesLocked(st.sources...)
+	if err != nil || !st.loaded(RestoreSourceNodes) {
+		return err
+	}
+	span.SetAttributes(attribute.Int("nodes.restored", len(st.persisted)))
+
+	qs.applyStoreState(st, false)
+	qs.lastRestore = time.Now()
+	return nil
+}
+
+// MergeFromStore is MergeFromStoreContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) MergeFromStore() (RestoreSummary, error) {
+	return qs.MergeFromStoreContext(context.Background())
+}
+
+// MergeFromStoreContext re-runs RestoreFromStore on a live service without losing nodes created
+// since boot, for operators recovering after an incident. The merge policy is:
+//
+//   - The store wins for every node it knows: the node's state, notes, tags, allowed resources,
+//     result and queue placement are rebuilt from it as on startup. Its in-memory log and
+//     version are kept. Nodes the store has completed are only rebuilt if they are still in
+//     memory, so archived nodes are not brought back.
+//   - In-memory nodes the store does not know are kept as they are, behind the restored nodes in
+//     their queues and in their current relative order.
+//
+// Merging twice in a row leaves the same state. Without a store it returns ErrStoreUnavailable.
+func (qs *QueueService) MergeFromStoreContext(ctx context.Context) (summary RestoreSummary, err error) {
+	if qs.store == nil {
+		return RestoreSummary{}, ErrStoreUnavailable
+	}
+
+	ctx, span := startSpan(ctx, "QueueService.MergeFromStore")
+	defer func() { endSpan(span, err) }()
+
+	st, err := qs.loadStoreState(ctx, true, false)
+	if err != nil {
+		return RestoreSummary{}, err
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	summary = qs.applyStoreState(st, true)
+	qs.lastRestore = time.Now()
+	span.SetAttributes(
+		attribute.Int("nodes.restored", summary.NodesRestored),
+		attribute.Int("nodes.kept", summary.NodesKept),
+	)
+	return summary, nil
+}
+
+// applyStoreState replaces qs.nodes and every resource's queues with the store's state. With
+// merge, in-memory nodes the store does not know are kept (see MergeFromStoreContext); otherwise
+// they are dropped. Callers must hold qs.mu for writing.
+func (qs *QueueService) applyStoreState(st *storeState, merge bool) RestoreSummary {
+	var summary RestoreSummary
+
+	known := make(map[string]bool, len(st.persisted))
+	for _, pn := range st.persisted {
+		known[pn.NodeID] = true
+	}
+
+	previous := qs.nodes
+	qs.nodes = make(map[string]*node.Node, len(st.persisted))
+
+	// Kept nodes, per resource, in their current queue order.
+	keptService := make(map[string][]*node.Node)
+	keptWaiting := make(map[string][]*node.Node)
+	if merge {
+		for id, n := range previous {
+			if !known[id] {
+				qs.nodes[id] = n
+				summary.NodesKept++
+			}
+		}
+		for rid, r := range qs.resources {
+			service, waiting := r.QueueSnapshot()
+			for _, n := range service {
+				if !known[n.ID] {
+					keptService[rid] = append(keptService[rid], n)
+				}
+			}
+			for _, n := range waiting {
+				if !known[n.ID] {
+					keptWaiting[rid] = append(keptWaiting[rid], n)
+				}
+			}
+		}
+	}
+
+	type queued struct {
+		n  *node.Node
+		ts time.Time
+	}
+	waitingByRes := make(map[string][]queued)
+	serviceByRes := make(map[string][]queued)
+
+	for _, pn := range st.persisted {
+		prev, inMemory := previous[pn.NodeID]
+		if merge && pn.Completed && !inMemory {
+			continue
+		}
+
+		n := &node.Node{
+			ID:              pn.NodeID,
+			Entity:          &node.Entity{Name: pn.EntityName},
+			Completed:       pn.Completed,
+			CreatedAt:       pn.CreatedAt.UTC(),
+			Weight:          max(pn.Weight, 1),
+			Attempts:        pn.Attempts,
+			Failed:          pn.Failed,
+			CompletionToken: pn.CompletionToken,
+		}
+		if merge && inMemory {
+			n.Log = prev.Log
+			n.Version = prev.Version
+			n.FailureReason = prev.FailureReason
+		}
+		n.SetTimeFormat(qs.TimeFormat)
+		if pn.NotBefore != nil {
+			notBefore := pn.NotBefore.UTC()
+			n.NotBeforeTS = &notBefore
+		}
+		if pn.DeadlineTS != nil {
+			deadline := pn.DeadlineTS.UTC()
+			n.DeadlineTS = &deadline
+		}
+		if pn.ResourceID != nil {
+			n.ResourceID = *pn.ResourceID
+		}
+		extras := st.extras[n.ID]
+		if rows := extras.Notes; len(rows) > 0 {
+			sort.SliceStable(rows, func(i, j int) bool { return rows[i].TS.Before(rows[j].TS) })
+			n.Notes = make([]node.NodeNote, 0, len(rows))
+			for _, nr := range rows {
+				n.Notes = append(n.Notes, node.NodeNote{Author: nr.Author, Text: nr.Text, Timestamp: nr.TS.UTC()})
+			}
+		}
+		if rr := extras.Result; rr != nil {
+			n.Result = &node.NodeResult{Outcome: rr.Outcome, Result: rr.Result}
+		}
+		for _, tag := range extras.Tags {
+			n.AddTag(tag)
+		}
+		n.SetAllowedResources(extras.AllowedResources)
+		qs.nodes[n.ID] = n
+		summary.NodesRestored++
+
+		// Only enqueue incomplete nodes assigned to a known resource.
+		if n.ResourceID == "" || n.Completed {
+			continue
+		}
+		if _, ok := qs.resources[n.ResourceID]; !ok {
+			continue
+		}
+
+		state, ok := st.states[n.ID]
+		queueTS := pn.CreatedAt
+		queueKind := db.QueueKindWaiting
+		if ok {
+			queueTS = state.TS
+			queueKind = state.Queue
+		}
+
+		switch queueKind {
+		case db.QueueKindService:
+			serviceByRes[n.ResourceID] = append(serviceByRes[n.ResourceID], queued{n: n, ts: queueTS})
+		default:
+			waitingByRes[n.ResourceID] = append(waitingByRes[n.ResourceID], queued{n: n, ts: queueTS})
+		}
+	}
+
+	// Restored nodes first, ordered by when they entered the queue, then kept nodes.
+	ordered := func(items []queued, kept []*node.Node) []*node.Node {
+		sort.Slice(items, func(i, j int) bool { return items[i].ts.Before(items[j].ts) })
+		out := make([]*node.Node, 0, len(items)+len(kept))
+		for _, it := range items {
+			out = append(out, it.n)
+		}
+		return append(out, kept...)
+	}
+	for rid, r := range qs.resources {
+		if len(serviceByRes[rid]) > 0 || len(waitingByRes[rid]) > 0 {
+			summary.QueuesRebuilt++
+		}
+		r.ReplaceQueues(ordered(serviceByRes[rid], keptService[rid]), ordered(waitingByRes[rid], keptWaiting[rid]))
+	}
+	qs.rebuildEntityIndexLocked()
+	return summary
+}
+
+// MergeFromStoreHandler handles POST /admin/restore.
+//
+// The route is wrapped in utils.AdminGuard, so it is refused unless ENABLE_ADMIN is set. Returns
+// a RestoreSummary; 503 (store_unavailable) when persistence is disabled.
+func (qs *QueueService) MergeFromStoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] POST /admin/restore - Request")
+
+	summary, err := qs.MergeFromStoreContext(r.Context())
+	if err != nil {
+		log.Printf("[API] POST /admin/restore - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /admin/restore - SUCCESS: Restored %d nodes, kept %d, rebuilt %d queues (took %v)",
+		summary.NodesRestored, summary.NodesKept, summary.QueuesRebuilt, duration)
+	utils.RespondWithJSON(w, http.StatusOK, summary)
+}
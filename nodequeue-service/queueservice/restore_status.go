@@ -0,0 +1,125 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"nodequeue-service/db"
+	"nodequeue-service/resource"
+)
+
+// Sources read from the store when restoring state on startup, as reported by RestoreStatus.
+const (
+	// RestoreSourceResources is Store.ListResources (see RestoreResourcesFromStore).
+	RestoreSourceResources = "resources"
+	// RestoreSourceNodes is Store.ListNodes.
+	RestoreSourceNodes = "nodes"
+	// RestoreSourceNodeStates is Store.ListLatestNodeStates.
+	RestoreSourceNodeStates = "node_states"
+	// RestoreSourceNodeLogs is Store.ListNodeLogs, read only for nodes without a state.
+	RestoreSourceNodeLogs = "node_logs"
+	// RestoreSourceNodeExtras is Store.ListNodeExtras.
+	RestoreSourceNodeExtras = "node_extras"
+)
+
+// RestoreSourceStatus is the outcome of reading one restore source.
+type RestoreSourceStatus struct {
+	Source string `json:"source"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newRestoreSourceStatus(source string, err error) RestoreSourceStatus {
+	status := RestoreSourceStatus{Source: source, OK: err == nil}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// RestoreStatus reports which sources the startup restore read successfully, in the order they
+// were read. It is returned in the /readyz body.
+type RestoreStatus struct {
+	// Partial is true when any source failed, so the service is running without some of the
+	// stored state.
+	Partial bool                  `json:"partial"`
+	Sources []RestoreSourceStatus `json:"sources"`
+}
+
+// recordRestoreSourcesLocked stores the outcome of restore reads, replacing earlier outcomes for
+// the same sources. Callers must hold qs.mu for writing.
+func (qs *QueueService) recordRestoreSourcesLocked(sources ...RestoreSourceStatus) {
+	for _, status := range sources {
+		i := slices.IndexFunc(qs.restoreSources, func(s RestoreSourceStatus) bool { return s.Source == status.Source })
+		if i >= 0 {
+			qs.restoreSources[i] = status
+		} else {
+			qs.restoreSources = append(qs.restoreSources, status)
+		}
+	}
+}
+
+// RestoreStatus reports the outcome of RestoreResourcesFromStore and RestoreFromStore. ok is
+// false if neither has read from the store.
+func (qs *QueueService) RestoreStatus() (status RestoreStatus, ok bool) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	if len(qs.restoreSources) == 0 {
+		return RestoreStatus{}, false
+	}
+	status.Sources = slices.Clone(qs.restoreSources)
+	for _, s := range status.Sources {
+		if !s.OK {
+			status.Partial = true
+		}
+	}
+	return status, true
+}
+
+// RestoreResourcesFromStore adds the store's resources to qs and returns them. The read is
+// recorded as RestoreSourceResources in RestoreStatus; if it fails nothing is added and the
+// caller is expected to fall back to another source of resources (or to fail in RestoreStrict
+// mode). Without a store it returns nil.
+func (qs *QueueService) RestoreResourcesFromStore(ctx context.Context) (_ []*resource.Resource, err error) {
+	if qs.store == nil {
+		return nil, nil
+	}
+
+	ctx, span := startSpan(ctx, "QueueService.RestoreResourcesFromStore")
+	defer func() { endSpan(span, err) }()
+
+	var resources []*resource.Resource
+	err = traceStore(db.WithPrimaryReads(ctx), "ListResources", func(ctx context.Context) (err error) {
+		resources, err = qs.store.ListResources(ctx)
+		return err
+	})
+	qs.mu.Lock()
+	qs.recordRestoreSourcesLocked(newRestoreSourceStatus(RestoreSourceResources, err))
+	qs.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("restore %s: %w", RestoreSourceResources, err)
+	}
+
+	for _, r := range resources {
+		qs.AddResource(r)
+	}
+	return resources, nil
+}
+
+// record notes the outcome of reading source into st, logging a failure.
+func (st *storeState) record(source string, err error) {
+	st.sources = append(st.sources, newRestoreSourceStatus(source, err))
+	if err != nil {
+		err = fmt.Errorf("restore %s: %w", source, err)
+		log.Printf("[DB] %v", err)
+		st.errs = append(st.errs, err)
+	}
+}
+
+// loaded reports whether source was read successfully.
+func (st *storeState) loaded(source string) bool {
+	return slices.Contains(st.sources, RestoreSourceStatus{Source: source, OK: true})
+}
@@ -0,0 +1,168 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// RetryPolicy controls how FailNode re-queues a node after a failed service attempt.
+//
+// The n-th failure delays the next allocation by BaseBackoff * 2^(n-1), capped at MaxBackoff
+// (no cap when MaxBackoff <= 0). Once a node has failed MaxAttempts times it is completed as
+// failed instead of re-queued.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy NewQueueService starts with.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: 5 * time.Second,
+	MaxBackoff:  5 * time.Minute,
+}
+
+// Backoff returns how long a node must wait after its attempt-th failure (1-based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		if d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// FailNode is FailNodeContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) FailNode(nodeID, reason string) error {
+	return qs.FailNodeContext(context.Background(), nodeID, reason)
+}
+
+// FailNodeContext records a transient failure of a node in service.
+//
+// The node leaves the service queue and goes to the back of its resource's waiting queue with
+// Attempts incremented; it cannot be allocated (manually or automatically) before NotBeforeTS.
+// Once Attempts reaches Retry.MaxAttempts the node is completed with Failed set and a "failed"
+// log entry instead. Either way the freed slot is offered to AutoPromote.
+//
+// Only nodes in service can fail; others return ErrNodeNotInService.
+func (qs *QueueService) FailNodeContext(ctx context.Context, nodeID, reason string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.FailNode", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	freedResourceID, err := qs.failNode(ctx, nodeID, reason)
+	if err != nil {
+		return err
+	}
+	qs.autoPromote(ctx, freedResourceID)
+	return nil
+}
+
+// failNode applies the failure under qs.mu and returns the resource whose service slot was freed.
+func (qs *QueueService) failNode(ctx context.Context, nodeID, reason string) (string, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return "", ErrNodeNotFound
+	}
+
+	if n.Completed {
+		return "", ErrNodeCompleted
+	}
+
+	if !qs.inService(n) {
+		return "", ErrNodeNotInService
+	}
+
+	resource := qs.resources[n.ResourceID]
+	resource.RemoveNode(nodeID)
+
+	n.Attempts++
+	n.FailureReason = reason
+	rid := n.ResourceID
+
+	if n.Attempts >= qs.Retry.MaxAttempts {
+		// Out of attempts: terminal failure.
+		n.Completed = true
+		n.Failed = true
+		qs.unindexActiveLocked(n)
+		n.NotBeforeTS = nil
+		n.ResourceID = ""
+		failedAt := qs.addNodeLog(n, "failed", rid)
+
+		// Persist terminal state (best-effort).
+		attempts := n.Attempts
+		qs.bestEffortPersist(ctx, "MarkNodeCompleted(true)", func(ctx context.Context) error {
+			return qs.store.MarkNodeCompleted(ctx, nodeID, true)
+		})
+		qs.bestEffortPersist(ctx, "UpdateNodeRetry(failed)", func(ctx context.Context) error {
+			return qs.store.UpdateNodeRetry(ctx, nodeID, attempts, nil, true)
+		})
+		qs.bestEffortPersist(ctx, "InsertNodeLog(failed)", func(ctx context.Context) error {
+			return qs.store.InsertNodeLog(ctx, nodeID, "failed", &rid, failedAt)
+		})
+		return rid, nil
+	}
+
+	notBefore := node.Now().Add(qs.Retry.Backoff(n.Attempts))
+	n.NotBeforeTS = &notBefore
+	resource.AddNode(n)
+	failedAt := qs.addNodeLog(n, "failed_attempt", rid)
+	requeuedAt := qs.addNodeLog(n, "moved_to_waiting_queue", rid)
+
+	// Persist retry state and audit trail (best-effort).
+	attempts := n.Attempts
+	qs.bestEffortPersist(ctx, "UpdateNodeRetry", func(ctx context.Context) error {
+		return qs.store.UpdateNodeRetry(ctx, nodeID, attempts, &notBefore, false)
+	})
+	qs.bestEffortPersist(ctx, "InsertNodeLog(failed_attempt)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "failed_attempt", &rid, failedAt)
+	})
+	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "moved_to_waiting_queue", &rid, requeuedAt)
+	})
+	return rid, nil
+}
+
+// FailNodeHandler handles POST /nodes/{id}/fail.
+//
+// Returns the node: back in the waiting queue with a not_before_ts, or completed with
+// failed=true once it has used up its attempts.
+func (qs *QueueService) FailNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/fail - Request", nodeID)
+
+	var req node.FailNodeRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/fail - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.FailNodeContext(r.Context(), nodeID, req.Reason); err != nil {
+		log.Printf("[API] POST /nodes/%s/fail - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/fail - SUCCESS: Failure recorded (took %v)", nodeID, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
@@ -0,0 +1,157 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// Policies for what ServiceTimeoutWatchdog does with a node that overstays its service slot
+// (SERVICE_TIMEOUT_POLICY).
+const (
+	// ServiceTimeoutRequeue moves the node to the back of its resource's waiting queue.
+	ServiceTimeoutRequeue = "requeue"
+	// ServiceTimeoutCancel completes the node with a "cancelled" outcome.
+	ServiceTimeoutCancel = "cancel"
+)
+
+// ParseServiceTimeoutPolicy validates a policy name; empty means ServiceTimeoutRequeue.
+func ParseServiceTimeoutPolicy(raw string) (string, error) {
+	switch raw {
+	case "":
+		return ServiceTimeoutRequeue, nil
+	case ServiceTimeoutRequeue, ServiceTimeoutCancel:
+		return raw, nil
+	}
+	return "", fmt.Errorf("unknown service timeout policy %q (want %q or %q)", raw, ServiceTimeoutRequeue, ServiceTimeoutCancel)
+}
+
+// serviceEnteredAt returns when n last entered a service queue, from its log.
+func serviceEnteredAt(n *node.Node) (time.Time, bool) {
+	for i := len(n.Log) - 1; i >= 0; i-- {
+		if n.Log[i].Action == "moved_to_service_queue" {
+			return n.Log[i].Timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ServiceTimeoutWatchdog reclaims nodes that have been in service for longer than Timeout, e.g.
+// because their worker died without completing them.
+//
+// Each reclaimed node gets a "service_timeout" log entry and is then handled according to
+// Policy. The freed slot is offered to AutoPromote. Now is the watchdog's clock and may be
+// replaced in tests.
+type ServiceTimeoutWatchdog struct {
+	qs      *QueueService
+	Timeout time.Duration
+	Policy  string
+	Now     func() time.Time
+
+	// mu serializes Check so overlapping runs do not reclaim the same node twice.
+	mu sync.Mutex
+}
+
+// NewServiceTimeoutWatchdog returns a watchdog for qs. policy must be ServiceTimeoutRequeue or
+// ServiceTimeoutCancel (see ParseServiceTimeoutPolicy).
+func NewServiceTimeoutWatchdog(qs *QueueService, timeout time.Duration, policy string) *ServiceTimeoutWatchdog {
+	return &ServiceTimeoutWatchdog{
+		qs:      qs,
+		Timeout: timeout,
+		Policy:  policy,
+		Now:     time.Now,
+	}
+}
+
+// Check reclaims every node whose service entry is at least Timeout old and returns their IDs.
+func (w *ServiceTimeoutWatchdog) Check(ctx context.Context) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cutoff := w.Now().Add(-w.Timeout)
+
+	w.qs.mu.RLock()
+	stuck := make([]string, 0)
+	for id, n := range w.qs.nodes {
+		if n.Completed || !w.qs.inService(n) {
+			continue
+		}
+		if entered, ok := serviceEnteredAt(n); ok && !entered.After(cutoff) {
+			stuck = append(stuck, id)
+		}
+	}
+	w.qs.mu.RUnlock()
+	sort.Strings(stuck)
+
+	reclaimed := make([]string, 0, len(stuck))
+	for _, id := range stuck {
+		freedResourceID, ev, ok := w.reclaim(ctx, id, cutoff)
+		if !ok {
+			continue
+		}
+		reclaimed = append(reclaimed, id)
+		if ev != nil && w.qs.OnComplete != nil {
+			go w.qs.OnComplete(*ev)
+		}
+		w.qs.autoPromote(ctx, freedResourceID)
+	}
+	return reclaimed
+}
+
+// reclaim applies the policy to one node under qs.mu. It re-checks that the node is still stuck,
+// since it may have completed or been re-allocated since Check sampled it. The CompletionEvent is
+// non-nil when the node was cancelled.
+func (w *ServiceTimeoutWatchdog) reclaim(ctx context.Context, nodeID string, cutoff time.Time) (string, *CompletionEvent, bool) {
+	qs := w.qs
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists || n.Completed || !qs.inService(n) {
+		return "", nil, false
+	}
+	if entered, ok := serviceEnteredAt(n); !ok || entered.After(cutoff) {
+		return "", nil, false
+	}
+
+	rid := n.ResourceID
+	timedOutAt := qs.addNodeLog(n, "service_timeout", rid)
+	qs.bestEffortPersist(ctx, "InsertNodeLog(service_timeout)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "service_timeout", &rid, timedOutAt)
+	})
+
+	if w.Policy == ServiceTimeoutCancel {
+		_, ev := qs.completeLocked(ctx, n, &node.NodeResult{Outcome: node.OutcomeCancelled})
+		return rid, &ev, true
+	}
+
+	resource := qs.resources[rid]
+	resource.RemoveNode(nodeID)
+	resource.AddNode(n)
+	requeuedAt := qs.addNodeLog(n, "moved_to_waiting_queue", rid)
+	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "moved_to_waiting_queue", &rid, requeuedAt)
+	})
+	return rid, nil, true
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (w *ServiceTimeoutWatchdog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ids := w.Check(ctx); len(ids) > 0 {
+				log.Printf("[QueueService] reclaimed %d nodes in service longer than %v (%s): %v", len(ids), w.Timeout, w.Policy, ids)
+			}
+		}
+	}
+}
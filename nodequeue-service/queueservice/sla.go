@@ -0,0 +1,177 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// EventSLABreach is the SLAEvent.Type, and the NodeEvent.Action published to subscribers, when a
+// waiting node exceeds its resource's MaxWaitMS.
+const EventSLABreach = "sla_breach"
+
+// SLABreach is a waiting node that has waited longer than its resource's MaxWaitMS.
+type SLABreach struct {
+	NodeID       string    `json:"node_id"`
+	EntityName   string    `json:"entity_name"`
+	ResourceID   string    `json:"resource_id"`
+	WaitingSince time.Time `json:"waiting_since"`
+	WaitingMs    int64     `json:"waiting_ms"`
+	MaxWaitMS    int64     `json:"max_wait_ms"`
+}
+
+// SLAEvent is what SLAMonitor reports when a node crosses its resource's SLA.
+type SLAEvent struct {
+	Type string `json:"type"`
+	SLABreach
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SLABreaches returns every waiting node whose waiting time at now exceeds its resource's
+// MaxWaitMS, longest waiting first. Resources with MaxWaitMS 0 are skipped.
+func (qs *QueueService) SLABreaches(now time.Time) []SLABreach {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	out := make([]SLABreach, 0)
+	for id, r := range qs.resources {
+		if r.MaxWaitMS <= 0 {
+			continue
+		}
+		_, waiting := r.QueueSnapshot()
+		for _, n := range waiting {
+			since := waitingSince(n)
+			waited := now.Sub(since).Milliseconds()
+			if waited <= r.MaxWaitMS {
+				continue
+			}
+			entityName := ""
+			if n.Entity != nil {
+				entityName = n.Entity.Name
+			}
+			out = append(out, SLABreach{
+				NodeID:       n.ID,
+				EntityName:   entityName,
+				ResourceID:   id,
+				WaitingSince: since,
+				WaitingMs:    waited,
+				MaxWaitMS:    r.MaxWaitMS,
+			})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].WaitingMs != out[j].WaitingMs {
+			return out[i].WaitingMs > out[j].WaitingMs
+		}
+		return out[i].NodeID < out[j].NodeID
+	})
+	return out
+}
+
+// SLABreachesHandler handles GET /sla/breaches.
+func (qs *QueueService) SLABreachesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /sla/breaches - Request")
+	breaches := qs.SLABreaches(time.Now())
+	log.Printf("[API] GET /sla/breaches - SUCCESS: Returning %d breaches", len(breaches))
+	utils.RespondWithJSON(w, http.StatusOK, breaches)
+}
+
+// SLAMonitor reports nodes as they cross their resource's MaxWaitMS, to notify and as an
+// EventSLABreach NodeEvent to subscribers.
+//
+// Each node is reported once per waiting stint; it is re-armed when it next enters a waiting
+// queue. Now is the monitor's clock and may be replaced in tests.
+type SLAMonitor struct {
+	qs     *QueueService
+	notify func(SLAEvent)
+	Now    func() time.Time
+
+	mu sync.Mutex
+	// reported maps node IDs to the waitingSince of the stint already reported.
+	reported map[string]time.Time
+}
+
+// NewSLAMonitor returns a monitor for qs that reports events to notify.
+func NewSLAMonitor(qs *QueueService, notify func(SLAEvent)) *SLAMonitor {
+	return &SLAMonitor{
+		qs:       qs,
+		notify:   notify,
+		Now:      time.Now,
+		reported: make(map[string]time.Time),
+	}
+}
+
+// Check evaluates every waiting node once and returns the events it fired.
+func (m *SLAMonitor) Check() []SLAEvent {
+	now := m.Now()
+	breaches := m.qs.SLABreaches(now)
+
+	m.mu.Lock()
+	fired := make([]SLAEvent, 0)
+	breaching := make(map[string]bool, len(breaches))
+	for _, b := range breaches {
+		breaching[b.NodeID] = true
+		if since, ok := m.reported[b.NodeID]; ok && since.Equal(b.WaitingSince) {
+			continue
+		}
+		m.reported[b.NodeID] = b.WaitingSince
+		fired = append(fired, SLAEvent{Type: EventSLABreach, SLABreach: b, Timestamp: now})
+	}
+	// Forget nodes that were allocated, moved or completed.
+	for id := range m.reported {
+		if !breaching[id] {
+			delete(m.reported, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, ev := range fired {
+		m.qs.events.publish(NodeEvent{
+			NodeID:     ev.NodeID,
+			Action:     EventSLABreach,
+			ResourceID: ev.ResourceID,
+			Timestamp:  ev.Timestamp,
+		})
+		m.notify(ev)
+	}
+	return fired
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (m *SLAMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// SLANotifier logs each event and, when hook is non-nil, posts it to the webhook.
+// Delivery failures are logged and not retried.
+func SLANotifier(hook *Webhook) func(SLAEvent) {
+	return func(ev SLAEvent) {
+		log.Printf("[SLA] node %s waiting on %s for %dms (max %dms)", ev.NodeID, ev.ResourceID, ev.WaitingMs, ev.MaxWaitMS)
+		if hook == nil {
+			return
+		}
+		if err := hook.Post(context.Background(), ev); err != nil {
+			log.Printf("[SLA] webhook delivery failed: %v", err)
+		}
+	}
+}
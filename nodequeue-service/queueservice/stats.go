@@ -0,0 +1,81 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// statsCompletedWindow is the window for Stats.CompletedLastHour.
+const statsCompletedWindow = time.Hour
+
+// Stats is the response payload for GET /stats: top-line counters for a dashboard.
+//
+// Node status counts are exclusive: every node is exactly one of unassigned, waiting, in_service
+// or completed.
+type Stats struct {
+	TotalNodes        int `json:"total_nodes"`
+	Unassigned        int `json:"unassigned"`
+	Waiting           int `json:"waiting"`
+	InService         int `json:"in_service"`
+	Completed         int `json:"completed"`
+	TotalResources    int `json:"total_resources"`
+	TotalCapacity     int `json:"total_capacity"`
+	CompletedLastHour int `json:"completed_last_hour"`
+	// DeadlineExceeded counts completed nodes that were cancelled for missing their deadline.
+	DeadlineExceeded int `json:"deadline_exceeded"`
+}
+
+// GlobalStats computes Stats in a single pass under one read lock.
+//
+// Waiting and InService come from the resource queues; the completed-in-window count scans each
+// completed node's log for its completion time.
+func (qs *QueueService) GlobalStats() Stats {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	cutoff := time.Now().Add(-statsCompletedWindow)
+	st := Stats{
+		TotalNodes:     len(qs.nodes),
+		TotalResources: len(qs.resources),
+	}
+
+	for _, r := range qs.resources {
+		service, waiting := r.QueueSnapshot()
+		st.TotalCapacity += r.Capacity
+		st.InService += len(service)
+		st.Waiting += len(waiting)
+	}
+
+	for _, n := range qs.nodes {
+		if !n.Completed {
+			if n.ResourceID == "" {
+				st.Unassigned++
+			}
+			continue
+		}
+		st.Completed++
+		if n.DeadlineExceeded {
+			st.DeadlineExceeded++
+		}
+		if ts, ok := completedAt(n); ok && ts.After(cutoff) {
+			st.CompletedLastHour++
+		}
+	}
+	return st
+}
+
+// StatsHandler handles GET /stats.
+func (qs *QueueService) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /stats - Request")
+	st := qs.GlobalStats()
+	log.Printf("[API] GET /stats - SUCCESS: %d nodes, %d resources", st.TotalNodes, st.TotalResources)
+	utils.RespondWithJSON(w, http.StatusOK, st)
+}
@@ -0,0 +1,189 @@
+package queueservice
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// StoreOpStats counts the outcomes of one store write operation.
+type StoreOpStats struct {
+	Successes int64 `json:"successes"`
+	Failures  int64 `json:"failures"`
+}
+
+// StoreHealth is the response payload for GET /admin/store-health.
+type StoreHealth struct {
+	// Enabled is false when the service runs without a store; nothing else is set then.
+	Enabled bool `json:"enabled"`
+	// Degraded is true once ConsecutiveFailures reaches StoreDegradeAfter (see StoreDegraded).
+	Degraded            bool       `json:"degraded"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	// Operations is keyed by store method, e.g. "InsertNodeLog".
+	Operations map[string]StoreOpStats `json:"operations"`
+}
+
+// storeHealth tracks the outcome of every store write made through bestEffortPersist and
+// criticalPersist. Its zero value is ready to use.
+type storeHealth struct {
+	mu            sync.Mutex
+	ops           map[string]*StoreOpStats
+	consecutive   int
+	lastErr       string
+	lastErrAt     time.Time
+	lastSuccessAt time.Time
+}
+
+// storeOpName strips the detail some callers add to op, e.g. "InsertNodeLog(created)", so
+// counters are kept per store method.
+func storeOpName(op string) string {
+	if i := strings.IndexByte(op, '('); i > 0 {
+		return op[:i]
+	}
+	return op
+}
+
+func (h *storeHealth) record(op string, err error, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ops == nil {
+		h.ops = make(map[string]*StoreOpStats)
+	}
+	name := storeOpName(op)
+	stats, ok := h.ops[name]
+	if !ok {
+		stats = &StoreOpStats{}
+		h.ops[name] = stats
+	}
+	if err != nil {
+		stats.Failures++
+		h.consecutive++
+		h.lastErr = fmt.Sprintf("%s: %v", op, err)
+		h.lastErrAt = now
+		return
+	}
+	stats.Successes++
+	h.consecutive = 0
+	h.lastSuccessAt = now
+}
+
+// StoreHealth reports the store write counters. Failures are counted whether or not the write was
+// critical.
+func (qs *QueueService) StoreHealth() StoreHealth {
+	if qs.store == nil {
+		return StoreHealth{Operations: map[string]StoreOpStats{}}
+	}
+	h := &qs.storeHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := StoreHealth{
+		Enabled:             true,
+		ConsecutiveFailures: h.consecutive,
+		LastError:           h.lastErr,
+		Operations:          make(map[string]StoreOpStats, len(h.ops)),
+	}
+	out.Degraded = qs.StoreDegradeAfter > 0 && h.consecutive >= qs.StoreDegradeAfter
+	if !h.lastErrAt.IsZero() {
+		ts := h.lastErrAt.UTC()
+		out.LastErrorAt = &ts
+	}
+	if !h.lastSuccessAt.IsZero() {
+		ts := h.lastSuccessAt.UTC()
+		out.LastSuccessAt = &ts
+	}
+	for name, stats := range h.ops {
+		out.Operations[name] = *stats
+	}
+	return out
+}
+
+// StoreDegraded reports whether the last StoreDegradeAfter store writes in a row have failed.
+// It is always false when StoreDegradeAfter is 0.
+func (qs *QueueService) StoreDegraded() bool {
+	return qs.StoreHealth().Degraded
+}
+
+// StoreHealthHandler handles GET /admin/store-health.
+func (qs *QueueService) StoreHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /admin/store-health - Request")
+
+	health := qs.StoreHealth()
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /admin/store-health - SUCCESS: %d consecutive failures (took %v)", health.ConsecutiveFailures, duration)
+	utils.RespondWithJSON(w, http.StatusOK, health)
+}
+
+// ReadyResponse is the response payload for GET /readyz.
+type ReadyResponse struct {
+	// Status is "ready", or "degraded" while StoreDegraded.
+	Status string `json:"status"`
+	// Restore is the outcome of the startup restore, once one has read from the store (see
+	// RestoreStatus). A partial restore does not make the service unready.
+	Restore *RestoreStatus `json:"restore,omitempty"`
+}
+
+// ReadyHandler handles GET /readyz: 200 normally, 503 with status "degraded" while StoreDegraded.
+func (qs *QueueService) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	resp := ReadyResponse{Status: "ready"}
+	if restore, ok := qs.RestoreStatus(); ok {
+		resp.Restore = &restore
+	}
+	if qs.StoreDegraded() {
+		resp.Status = "degraded"
+		utils.RespondWithJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// MetricsHandler handles GET /metrics, exposing the store write counters in the Prometheus text
+// format.
+func (qs *QueueService) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	health := qs.StoreHealth()
+
+	var b strings.Builder
+	b.WriteString("# HELP nodequeue_store_operations_total Store writes by operation and result.\n")
+	b.WriteString("# TYPE nodequeue_store_operations_total counter\n")
+	for _, name := range slices.Sorted(maps.Keys(health.Operations)) {
+		stats := health.Operations[name]
+		fmt.Fprintf(&b, "nodequeue_store_operations_total{op=%q,result=\"success\"} %d\n", name, stats.Successes)
+		fmt.Fprintf(&b, "nodequeue_store_operations_total{op=%q,result=\"failure\"} %d\n", name, stats.Failures)
+	}
+	b.WriteString("# HELP nodequeue_store_consecutive_failures Store writes that have failed in a row.\n")
+	b.WriteString("# TYPE nodequeue_store_consecutive_failures gauge\n")
+	fmt.Fprintf(&b, "nodequeue_store_consecutive_failures %d\n", health.ConsecutiveFailures)
+	b.WriteString("# HELP nodequeue_store_degraded Whether consecutive store failures have reached the degrade threshold.\n")
+	b.WriteString("# TYPE nodequeue_store_degraded gauge\n")
+	degraded := 0
+	if health.Degraded {
+		degraded = 1
+	}
+	fmt.Fprintf(&b, "nodequeue_store_degraded %d\n", degraded)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,93 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// SwapWaitingNodes is SwapWaitingNodesContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) SwapWaitingNodes(resourceID, nodeA, nodeB string) error {
+	return qs.SwapWaitingNodesContext(context.Background(), resourceID, nodeA, nodeB)
+}
+
+// SwapWaitingNodesContext exchanges the positions of two nodes in a resource's waiting queue (see
+// resource.Resource.SwapWaitingNodes) and records a "swapped" log entry on both.
+//
+// Both nodes must be waiting on resourceID: a node in service returns ErrNodeInService and a node
+// not waiting there returns ErrNodeNotWaiting.
+func (qs *QueueService) SwapWaitingNodesContext(ctx context.Context, resourceID, nodeA, nodeB string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.SwapWaitingNodes", attrResourceID.String(resourceID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	res, exists := qs.resources[resourceID]
+	if !exists {
+		return ErrResourceNotFound
+	}
+	a, exists := qs.nodes[nodeA]
+	if !exists {
+		return ErrNodeNotFound
+	}
+	b, exists := qs.nodes[nodeB]
+	if !exists {
+		return ErrNodeNotFound
+	}
+	for _, id := range []string{nodeA, nodeB} {
+		if res.IsInService(id) {
+			return fmt.Errorf("cannot swap %s: %w", id, ErrNodeInService)
+		}
+	}
+
+	if ok := res.SwapWaitingNodes(nodeA, nodeB); !ok {
+		return ErrNodeNotWaiting
+	}
+
+	swappedAt := map[string]time.Time{
+		nodeA: qs.addNodeLog(a, "swapped", resourceID),
+		nodeB: qs.addNodeLog(b, "swapped", resourceID),
+	}
+
+	// Persist audit trail (best-effort).
+	for _, id := range []string{nodeA, nodeB} {
+		ts := swappedAt[id]
+		qs.bestEffortPersist(ctx, "InsertNodeLog(swapped)", func(ctx context.Context) error {
+			return qs.store.InsertNodeLog(ctx, id, "swapped", &resourceID, ts)
+		})
+	}
+	return nil
+}
+
+// SwapWaitingNodesHandler handles POST /resources/{id}/swap.
+//
+// Returns the resource with its updated waiting queue.
+func (qs *QueueService) SwapWaitingNodesHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /resources/%s/swap - Request", resourceID)
+
+	var req resource.SwapWaitingNodesRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /resources/%s/swap - ERROR: %v", resourceID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	if err := qs.SwapWaitingNodesContext(r.Context(), resourceID, req.NodeA, req.NodeB); err != nil {
+		log.Printf("[API] POST /resources/%s/swap - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /resources/%s/swap - SUCCESS: Swapped %s and %s (took %v)", resourceID, req.NodeA, req.NodeB, duration)
+	res, _ := qs.GetResource(resourceID)
+	utils.RespondWithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,135 @@
+package queueservice
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// tagsResponse is the body of the tag endpoints: the node's tags after the change.
+type tagsResponse struct {
+	NodeID string   `json:"node_id"`
+	Tags   []string `json:"tags"`
+}
+
+// AddNodeTags is AddNodeTagsContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) AddNodeTags(nodeID string, tags []string) ([]string, error) {
+	return qs.AddNodeTagsContext(context.Background(), nodeID, tags)
+}
+
+// AddNodeTagsContext adds tags to a node and returns its tags afterwards. Tags it already has are
+// ignored. Like notes, tags may be changed at any point in the node's lifecycle; callers are
+// expected to have validated them (see node.ValidTag).
+func (qs *QueueService) AddNodeTagsContext(ctx context.Context, nodeID string, tags []string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "QueueService.AddNodeTags", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return nil, ErrNodeNotFound
+	}
+	qs.addTagsLocked(ctx, n, tags)
+	return append([]string{}, n.Tags...), nil
+}
+
+// RemoveNodeTag is RemoveNodeTagContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) RemoveNodeTag(nodeID, tag string) ([]string, error) {
+	return qs.RemoveNodeTagContext(context.Background(), nodeID, tag)
+}
+
+// RemoveNodeTagContext removes a tag from a node and returns its tags afterwards. Removing a tag
+// the node does not have is not an error.
+func (qs *QueueService) RemoveNodeTagContext(ctx context.Context, nodeID, tag string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "QueueService.RemoveNodeTag", attrNodeID.String(nodeID))
+	defer func() { endSpan(span, err) }()
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return nil, ErrNodeNotFound
+	}
+	if n.RemoveTag(tag) {
+		qs.bestEffortPersist(ctx, "RemoveNodeTag", func(ctx context.Context) error {
+			return qs.store.RemoveNodeTag(ctx, nodeID, tag)
+		})
+	}
+	return append([]string{}, n.Tags...), nil
+}
+
+// addTagsLocked adds tags to n and persists the new ones (best-effort). The node row must already
+// be persisted. Callers must hold qs.mu for writing.
+func (qs *QueueService) addTagsLocked(ctx context.Context, n *node.Node, tags []string) {
+	for _, tag := range tags {
+		if !n.AddTag(tag) {
+			continue
+		}
+		qs.bestEffortPersist(ctx, "AddNodeTag", func(ctx context.Context) error {
+			return qs.store.AddNodeTag(ctx, n.ID, tag)
+		})
+	}
+}
+
+// AddNodeTagsHandler handles POST /nodes/{id}/tags.
+//
+// Returns the node's tags after adding the requested ones.
+func (qs *QueueService) AddNodeTagsHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/tags - Request", nodeID)
+
+	var req node.AddTagsRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/tags - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	tags, err := qs.AddNodeTagsContext(r.Context(), nodeID, req.Tags)
+	if err != nil {
+		log.Printf("[API] POST /nodes/%s/tags - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/tags - SUCCESS: Node has %d tags (took %v)", nodeID, len(tags), duration)
+	utils.RespondWithJSON(w, http.StatusOK, tagsResponse{NodeID: nodeID, Tags: tags})
+}
+
+// RemoveNodeTagHandler handles DELETE /nodes/{id}/tags/{tag}.
+//
+// Returns the node's tags after removing the tag.
+func (qs *QueueService) RemoveNodeTagHandler(w http.ResponseWriter, r *http.Request, nodeID, tag string) {
+	startTime := time.Now()
+	log.Printf("[API] DELETE /nodes/%s/tags/%s - Request", nodeID, tag)
+
+	if !node.ValidTag(tag) {
+		fields := map[string]string{"tag": "is not a valid tag"}
+		log.Printf("[API] DELETE /nodes/%s/tags/%s - ERROR: %v", nodeID, tag, &utils.ValidationError{Fields: fields})
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid path parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	tags, err := qs.RemoveNodeTagContext(r.Context(), nodeID, tag)
+	if err != nil {
+		log.Printf("[API] DELETE /nodes/%s/tags/%s - ERROR: %v", nodeID, tag, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] DELETE /nodes/%s/tags/%s - SUCCESS: Node has %d tags (took %v)", nodeID, tag, len(tags), duration)
+	utils.RespondWithJSON(w, http.StatusOK, tagsResponse{NodeID: nodeID, Tags: tags})
+}
@@ -0,0 +1,170 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// Defaults and limits for GET /nodes/throughput.
+const (
+	DefaultThroughputBucket = 5 * time.Minute
+	DefaultThroughputWindow = 6 * time.Hour
+	MaxThroughputBuckets    = 10000
+)
+
+// ThroughputBucket is one point of the GET /nodes/throughput series: how many nodes completed in
+// [BucketStart, BucketStart+bucket).
+type ThroughputBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Count       int       `json:"count"`
+}
+
+// BucketCompletions counts completion timestamps into consecutive bucket-sized intervals covering
+// the window that ends at now.
+//
+// Buckets are aligned to multiples of bucket (so repeated queries line up) and reported in UTC.
+// The first bucket is the one containing now-window and the last the one containing now; empty
+// buckets are included so the series has no gaps. Timestamps outside that range are ignored.
+func BucketCompletions(completions []time.Time, now time.Time, bucket, window time.Duration) []ThroughputBucket {
+	if bucket <= 0 || window < 0 {
+		return []ThroughputBucket{}
+	}
+	first := now.Add(-window).UTC().Truncate(bucket)
+	n := int(now.Sub(first)/bucket) + 1
+
+	out := make([]ThroughputBucket, n)
+	for i := range out {
+		out[i].BucketStart = first.Add(time.Duration(i) * bucket)
+	}
+	for _, ts := range completions {
+		if ts.Before(first) || ts.After(now) {
+			continue
+		}
+		out[int(ts.Sub(first)/bucket)].Count++
+	}
+	return out
+}
+
+// completionTimes returns the timestamps of every "completed" log entry, optionally only those
+// recorded on resourceID.
+//
+// With a store the persisted logs are used, so nodes archived out of memory or not yet reloaded
+// after a restart still count; if the store fails it falls back to the nodes held in memory.
+func (qs *QueueService) completionTimes(ctx context.Context, resourceID string) []time.Time {
+	if qs.store != nil {
+		times, err := qs.completionTimesFromStore(ctx, resourceID)
+		if err == nil {
+			return times
+		}
+		log.Printf("[DB] completion history failed (falling back to in-memory logs): %v", err)
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	times := make([]time.Time, 0)
+	for _, n := range qs.nodes {
+		for _, entry := range n.Log {
+			if entry.Action == "completed" && (resourceID == "" || entry.ResourceID == resourceID) {
+				times = append(times, entry.Timestamp)
+			}
+		}
+	}
+	return times
+}
+
+func (qs *QueueService) completionTimesFromStore(ctx context.Context, resourceID string) ([]time.Time, error) {
+	persisted, err := qs.store.ListAllNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs := make([]string, 0, len(persisted))
+	for _, pn := range persisted {
+		if pn.Completed {
+			nodeIDs = append(nodeIDs, pn.NodeID)
+		}
+	}
+	if len(nodeIDs) == 0 {
+		return []time.Time{}, nil
+	}
+
+	logs, err := qs.store.ListNodeLogs(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(nodeIDs))
+	for _, rows := range logs {
+		for _, row := range rows {
+			if row.Action != "completed" {
+				continue
+			}
+			if resourceID != "" && (row.ResourceID == nil || *row.ResourceID != resourceID) {
+				continue
+			}
+			times = append(times, row.TS)
+		}
+	}
+	return times, nil
+}
+
+// Throughput returns completions per bucket over the window ending now, optionally limited to
+// nodes completed on resourceID (see BucketCompletions and completionTimes).
+func (qs *QueueService) Throughput(ctx context.Context, resourceID string, bucket, window time.Duration) []ThroughputBucket {
+	return BucketCompletions(qs.completionTimes(ctx, resourceID), time.Now(), bucket, window)
+}
+
+// ThroughputHandler handles GET /nodes/throughput[?bucket=5m&window=6h&resource_id=].
+// bucket and window are Go durations; the response is an array of {bucket_start, count}.
+func (qs *QueueService) ThroughputHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startTime := time.Now()
+	log.Printf("[API] GET /nodes/throughput - Request")
+
+	q := r.URL.Query()
+	fields := make(map[string]string)
+	bucket, window := DefaultThroughputBucket, DefaultThroughputWindow
+	if raw := q.Get("bucket"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			fields["bucket"] = "must be a positive duration (e.g. 5m)"
+		} else {
+			bucket = d
+		}
+	}
+	if raw := q.Get("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			fields["window"] = "must be a positive duration (e.g. 6h)"
+		} else {
+			window = d
+		}
+	}
+	if len(fields) == 0 && window/bucket >= MaxThroughputBuckets {
+		fields["window"] = fmt.Sprintf("must span fewer than %d buckets", MaxThroughputBuckets)
+	}
+	if len(fields) > 0 {
+		err := &utils.ValidationError{Fields: fields}
+		log.Printf("[API] GET /nodes/throughput - ERROR: %v", err)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: fields,
+		})
+		return
+	}
+
+	buckets := qs.Throughput(r.Context(), q.Get("resource_id"), bucket, window)
+
+	duration := time.Since(startTime)
+	log.Printf("[API] GET /nodes/throughput - SUCCESS: Returning %d buckets (took %v)", len(buckets), duration)
+	utils.RespondWithJSON(w, http.StatusOK, buckets)
+}
@@ -0,0 +1,41 @@
+package queueservice
+
+import (
+	"context"
+
+	"nodequeue-service/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys shared by QueueService spans.
+const (
+	attrNodeID           = attribute.Key("node.id")
+	attrResourceID       = attribute.Key("resource.id")
+	attrTargetResourceID = attribute.Key("resource.target_id")
+)
+
+// startSpan opens a child span of ctx for a QueueService operation or store call.
+// Spans are no-ops unless tracing.SetupFromEnv (or a test) installed a provider.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err (if any) on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceStore runs a store call inside a "store.<op>" span.
+func traceStore(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	ctx, span := startSpan(ctx, "store."+op)
+	err := fn(ctx)
+	endSpan(span, err)
+	return err
+}
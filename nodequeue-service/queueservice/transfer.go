@@ -0,0 +1,193 @@
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// TransferAndAllocate is TransferAndAllocateContext with context.Background(), for non-HTTP callers.
+func (qs *QueueService) TransferAndAllocate(nodeID, toResourceID string) error {
+	return qs.TransferAndAllocateContext(context.Background(), nodeID, toResourceID)
+}
+
+// TransferAndAllocateContext moves a node from its current resource straight into the service
+// queue of toResourceID as one step, so no other allocation can take the target slot between
+// the move and the allocate.
+//
+// Every target precondition (allowed resources, pause, capacity for the node's weight,
+// MaxPerEntity, backoff, no nodes waiting on a FIFOStrict target, AllocRatePerSec) is
+// checked before anything changes; on error the node stays where it was. On success the node's
+// old slot (waiting or service) is released and, if it was a service slot, offered to the old
+// resource's AutoPromote.
+func (qs *QueueService) TransferAndAllocateContext(ctx context.Context, nodeID, toResourceID string) (err error) {
+	ctx, span := startSpan(ctx, "QueueService.TransferAndAllocate", attrNodeID.String(nodeID), attrTargetResourceID.String(toResourceID))
+	defer func() { endSpan(span, err) }()
+
+	freedResourceID, err := qs.transferAndAllocate(ctx, nodeID, toResourceID)
+	if err != nil {
+		return err
+	}
+	if freedResourceID != "" {
+		qs.autoPromote(ctx, freedResourceID)
+	}
+	return nil
+}
+
+// transferAndAllocate applies the transfer under qs.mu and returns the resource whose service slot
+// was freed ("" if the node was not in service).
+//
+// qs.mu is held for writing throughout, so the source and target resources cannot change
+// underneath it; their own locks are only taken one at a time, source before target.
+func (qs *QueueService) transferAndAllocate(ctx context.Context, nodeID, toResourceID string) (string, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	n, exists := qs.nodes[nodeID]
+	if !exists {
+		return "", ErrNodeNotFound
+	}
+
+	if n.Completed {
+		return "", fmt.Errorf("cannot transfer node: %w", ErrNodeCompleted)
+	}
+
+	toResourceID = qs.LookupResourceID(toResourceID)
+	target, exists := qs.resources[toResourceID]
+	if !exists {
+		return "", fmt.Errorf("target %w", ErrResourceNotFound)
+	}
+
+	if n.ResourceID == toResourceID {
+		return "", ErrSameResource
+	}
+
+	if err := checkAllowedResource(n, toResourceID); err != nil {
+		return "", err
+	}
+
+	if err := qs.checkMoveLimit(n); err != nil {
+		return "", err
+	}
+
+	lane := qs.transferLaneLocked(n, target)
+	if err := checkTransferTarget(n, target, lane); err != nil {
+		return "", err
+	}
+
+	if err := qs.admitLocked(ctx, n, target); err != nil {
+		return "", err
+	}
+
+	if !target.AllowAllocation(time.Now()) {
+		return "", fmt.Errorf("target %w", ErrAllocationRateExceeded)
+	}
+
+	freedResourceID := ""
+	fromResourceID := n.ResourceID
+	if n.ResourceID != "" {
+		if source, exists := qs.resources[n.ResourceID]; exists {
+			if source.IsInService(nodeID) {
+				freedResourceID = n.ResourceID
+			}
+			source.RemoveNode(nodeID)
+		}
+	}
+
+	target.AddNodeToLane(n, lane)
+	queuedAt := qs.addNodeMoveLog(n, "moved_to_waiting_queue", toResourceID, fromResourceID)
+	if ok := target.AllocateWaitingNode(nodeID); !ok {
+		// Unreachable while qs.mu is held: capacity was checked above.
+		return freedResourceID, ErrCapacityFull
+	}
+	allocatedAt := qs.addNodeLog(n, "moved_to_service_queue", toResourceID)
+
+	// Persist audit trail (best-effort).
+	rid := toResourceID
+	qs.bestEffortPersist(ctx, "UpdateNodeResource(transfer)", func(ctx context.Context) error {
+		return qs.store.UpdateNodeResource(ctx, nodeID, &rid)
+	})
+	qs.bestEffortPersist(ctx, "InsertNodeMoveLog(moved_to_waiting_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeMoveLog(ctx, nodeID, "moved_to_waiting_queue", &rid, optionalString(fromResourceID), queuedAt)
+	})
+	qs.bestEffortPersist(ctx, "InsertNodeLog(moved_to_service_queue)", func(ctx context.Context) error {
+		return qs.store.InsertNodeLog(ctx, nodeID, "moved_to_service_queue", &rid, allocatedAt)
+	})
+	return freedResourceID, nil
+}
+
+// transferLaneLocked returns the lane n joins on target when transferred: the lane it is waiting
+// in on its current resource if target has that lane too, otherwise resource.DefaultLane.
+// Callers must hold qs.mu.
+func (qs *QueueService) transferLaneLocked(n *node.Node, target *resource.Resource) string {
+	source, exists := qs.resources[n.ResourceID]
+	if !exists {
+		return resource.DefaultLane
+	}
+	if lane := source.LaneOf(n.ID); lane != "" && target.HasLane(lane) {
+		return lane
+	}
+	return resource.DefaultLane
+}
+
+// checkTransferTarget reports whether n could be allocated into target's service queue right
+// away from lane, as TransferAndAllocate would: target not paused, room for the node's weight
+// outside other lanes' reservations, no retry backoff, the entity under target's MaxPerEntity
+// and, if target is FIFOStrict, no nodes waiting there to be jumped. AdmissionFunc is not
+// consulted. Callers must hold qs.mu.
+func checkTransferTarget(n *node.Node, target *resource.Resource, lane string) error {
+	if target.IsPaused() {
+		return fmt.Errorf("target %w", ErrResourcePaused)
+	}
+
+	if available := target.AvailableCapacityForLane(lane); n.CapacityWeight() > available {
+		return fmt.Errorf("target resource %s has %d free units, node needs %d: %w", target.ID, available, n.CapacityWeight(), ErrCapacityFull)
+	}
+
+	if n.NotBeforeTS != nil && time.Now().Before(*n.NotBeforeTS) {
+		return ErrNodeBackingOff
+	}
+
+	if n.Entity != nil && target.EntityAtLimit(n.Entity.Name) {
+		return fmt.Errorf("target %w", ErrEntityLimit)
+	}
+
+	if target.FIFOStrict && target.NextWaitingNode() != nil {
+		return fmt.Errorf("target resource %s is FIFO-strict and has nodes waiting: %w", target.ID, ErrNotQueueHead)
+	}
+	return nil
+}
+
+// TransferNodeHandler handles POST /nodes/{id}/transfer.
+//
+// Moves the node to the target resource and allocates it there in one step. Returns the node in
+// the target's service queue, or 400 capacity_full (node unchanged) if the target has no room.
+func (qs *QueueService) TransferNodeHandler(w http.ResponseWriter, r *http.Request, nodeID string) {
+	startTime := time.Now()
+	log.Printf("[API] POST /nodes/%s/transfer - Request", nodeID)
+
+	var req node.TransferNodeRequest
+	if err := utils.DecodeAndValidate(r, &req); err != nil {
+		log.Printf("[API] POST /nodes/%s/transfer - ERROR: %v", nodeID, err)
+		utils.RespondWithValidationError(w, CodeInvalidRequest, err)
+		return
+	}
+
+	log.Printf("[API] POST /nodes/%s/transfer - Transferring to resource %s", nodeID, req.TargetResourceID)
+	if err := qs.TransferAndAllocateContext(r.Context(), nodeID, req.TargetResourceID); err != nil {
+		log.Printf("[API] POST /nodes/%s/transfer - ERROR: %v", nodeID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[API] POST /nodes/%s/transfer - SUCCESS: In service on resource %s (took %v)", nodeID, req.TargetResourceID, duration)
+	node, _ := qs.GetNode(nodeID)
+	utils.RespondWithJSON(w, http.StatusOK, node)
+}
@@ -0,0 +1,255 @@
+package queueservice
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"nodequeue-service/node"
+	"nodequeue-service/utils"
+)
+
+// Sort orders accepted by ListWaiting.
+const (
+	WaitingSortAge      = "age"
+	WaitingSortPosition = "position"
+)
+
+// WaitingNode is one row of the cross-resource waiting view (GET /nodes/waiting).
+//
+// Position is the zero-based index in the resource's waiting queue. WaitingSince is the node's
+// last "moved_to_waiting_queue" timestamp (falling back to CreatedAt).
+type WaitingNode struct {
+	ID           string    `json:"id"`
+	EntityName   string    `json:"entity_name"`
+	ResourceID   string    `json:"resource_id"`
+	Position     int       `json:"position"`
+	WaitingSince time.Time `json:"waiting_since"`
+	WaitingMs    int64     `json:"waiting_ms"`
+}
+
+// waitingSince returns when the node last entered a waiting queue.
+func waitingSince(n *node.Node) time.Time {
+	for i := len(n.Log) - 1; i >= 0; i-- {
+		if n.Log[i].Action == "moved_to_waiting_queue" {
+			return n.Log[i].Timestamp
+		}
+	}
+	return n.CreatedAt
+}
+
+// ListWaiting returns every waiting node across all resources, or only resourceID's when set.
+//
+// sortBy is WaitingSortAge (longest waiting first, the default) or WaitingSortPosition (by
+// resource, then queue position).
+func (qs *QueueService) ListWaiting(resourceID, sortBy string) ([]WaitingNode, error) {
+	switch sortBy {
+	case "":
+		sortBy = WaitingSortAge
+	case WaitingSortAge, WaitingSortPosition:
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	if resourceID != "" {
+		resourceID = qs.LookupResourceID(resourceID)
+		if _, exists := qs.resources[resourceID]; !exists {
+			return nil, ErrResourceNotFound
+		}
+	}
+
+	now := time.Now()
+	out := make([]WaitingNode, 0)
+	for id, r := range qs.resources {
+		if resourceID != "" && id != resourceID {
+			continue
+		}
+		_, waiting := r.QueueSnapshot()
+		for pos, n := range waiting {
+			entityName := ""
+			if n.Entity != nil {
+				entityName = n.Entity.Name
+			}
+			since := waitingSince(n)
+			out = append(out, WaitingNode{
+				ID:           n.ID,
+				EntityName:   entityName,
+				ResourceID:   id,
+				Position:     pos,
+				WaitingSince: since,
+				WaitingMs:    now.Sub(since).Milliseconds(),
+			})
+		}
+	}
+
+	if sortBy == WaitingSortPosition {
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].ResourceID != out[j].ResourceID {
+				return out[i].ResourceID < out[j].ResourceID
+			}
+			return out[i].Position < out[j].Position
+		})
+	} else {
+		sort.Slice(out, func(i, j int) bool {
+			if !out[i].WaitingSince.Equal(out[j].WaitingSince) {
+				return out[i].WaitingSince.Before(out[j].WaitingSince)
+			}
+			return out[i].ID < out[j].ID
+		})
+	}
+	return out, nil
+}
+
+// OldestWaiting returns the node that has been waiting longest on resourceID, judged by
+// waitingSince, for SLA monitoring without listing and sorting the whole queue. Ties go to the
+// node nearer the head of the queue. It returns nil when nothing is waiting.
+func (qs *QueueService) OldestWaiting(resourceID string) (*WaitingNode, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	r, exists := qs.resources[resourceID]
+	if !exists {
+		return nil, ErrResourceNotFound
+	}
+
+	_, waiting := r.QueueSnapshot()
+	oldest := -1
+	var oldestSince time.Time
+	for pos, n := range waiting {
+		if since := waitingSince(n); oldest == -1 || since.Before(oldestSince) {
+			oldest, oldestSince = pos, since
+		}
+	}
+	if oldest == -1 {
+		return nil, nil
+	}
+
+	n := waiting[oldest]
+	entityName := ""
+	if n.Entity != nil {
+		entityName = n.Entity.Name
+	}
+	return &WaitingNode{
+		ID:           n.ID,
+		EntityName:   entityName,
+		ResourceID:   resourceID,
+		Position:     oldest,
+		WaitingSince: oldestSince,
+		WaitingMs:    time.Since(oldestSince).Milliseconds(),
+	}, nil
+}
+
+// OldestWaitingHandler handles GET /resources/{id}/oldest.
+//
+// Returns the WaitingNode that has waited longest, or 204 with no body when the waiting queue is
+// empty.
+func (qs *QueueService) OldestWaitingHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	log.Printf("[API] GET /resources/%s/oldest - Request", resourceID)
+
+	oldest, err := qs.OldestWaiting(resourceID)
+	if err != nil {
+		log.Printf("[API] GET /resources/%s/oldest - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+	if oldest == nil {
+		log.Printf("[API] GET /resources/%s/oldest - SUCCESS: No waiting nodes", resourceID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	log.Printf("[API] GET /resources/%s/oldest - SUCCESS: %s waiting for %dms", resourceID, oldest.ID, oldest.WaitingMs)
+	utils.RespondWithJSON(w, http.StatusOK, oldest)
+}
+
+// WaitingEntry is one row of GET /resources/{id}/waiting?include=entity.
+type WaitingEntry struct {
+	ID         string `json:"id"`
+	EntityName string `json:"entity_name"`
+}
+
+// ResourceWaiting returns resourceID's waiting queue in allocation order (lane by lane, then
+// FIFO), as IDs and entity names only. It returns an empty slice when nothing is waiting.
+func (qs *QueueService) ResourceWaiting(resourceID string) ([]WaitingEntry, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	resourceID = qs.LookupResourceID(resourceID)
+	r, exists := qs.resources[resourceID]
+	if !exists {
+		return nil, ErrResourceNotFound
+	}
+
+	_, waiting := r.QueueSnapshot()
+	out := make([]WaitingEntry, len(waiting))
+	for i, n := range waiting {
+		out[i].ID = n.ID
+		if n.Entity != nil {
+			out[i].EntityName = n.Entity.Name
+		}
+	}
+	return out, nil
+}
+
+// ResourceWaitingHandler handles GET /resources/{id}/waiting[?include=entity].
+//
+// Returns the waiting node IDs in allocation order as a JSON array of strings, or, with
+// include=entity, as WaitingEntry objects.
+func (qs *QueueService) ResourceWaitingHandler(w http.ResponseWriter, r *http.Request, resourceID string) {
+	log.Printf("[API] GET /resources/%s/waiting - Request", resourceID)
+
+	include := r.URL.Query().Get("include")
+	if include != "" && include != "entity" {
+		log.Printf("[API] GET /resources/%s/waiting - ERROR: invalid include %q", resourceID, include)
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.ErrorResponse{
+			Error:  "Invalid query parameters",
+			Code:   CodeInvalidRequest,
+			Fields: map[string]string{"include": "must be entity"},
+		})
+		return
+	}
+
+	entries, err := qs.ResourceWaiting(resourceID)
+	if err != nil {
+		log.Printf("[API] GET /resources/%s/waiting - ERROR: %v", resourceID, err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	log.Printf("[API] GET /resources/%s/waiting - SUCCESS: Returning %d waiting nodes", resourceID, len(entries))
+	if include == "entity" {
+		utils.RespondWithJSON(w, http.StatusOK, entries)
+		return
+	}
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	utils.RespondWithJSON(w, http.StatusOK, ids)
+}
+
+// ListWaitingHandler handles GET /nodes/waiting[?resource_id=&sort=age|position].
+func (qs *QueueService) ListWaitingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[API] GET /nodes/waiting - Request")
+
+	q := r.URL.Query()
+	nodes, err := qs.ListWaiting(q.Get("resource_id"), q.Get("sort"))
+	if err != nil {
+		log.Printf("[API] GET /nodes/waiting - ERROR: %v", err)
+		respondWithServiceError(w, err)
+		return
+	}
+
+	log.Printf("[API] GET /nodes/waiting - SUCCESS: Returning %d waiting nodes", len(nodes))
+	utils.RespondWithJSON(w, http.StatusOK, nodes)
+}
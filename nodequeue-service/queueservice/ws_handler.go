@@ -0,0 +1,206 @@
+package queueservice
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"nodequeue-service/node"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a single frame.
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long we wait for a pong (or any frame) before treating the peer as gone.
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod must be shorter than wsPongWait.
+	wsPingPeriod = (wsPongWait * 9) / 10
+)
+
+var wsUpgrader = websocket.Upgrader{
+	// The HTTP API is already permissive (see corsMiddleware); mirror that for browser clients.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WSCommand is a client->server command frame on GET /ws.
+//
+// Op is one of "create", "move", "allocate", "complete". ID is echoed back on the response
+// frame so clients can correlate responses with commands.
+type WSCommand struct {
+	ID               string `json:"id"`
+	Op               string `json:"op"`
+	NodeID           string `json:"node_id,omitempty"`
+	EntityName       string `json:"entity_name,omitempty"`
+	ResourceID       string `json:"resource_id,omitempty"`
+	TargetResourceID string `json:"target_resource_id,omitempty"`
+}
+
+// WSFrame is a server->client frame on GET /ws.
+//
+// Type is "event" for lifecycle events (Event set) or "response" for command results
+// (ID, OK and either Node or Error/Code set).
+type WSFrame struct {
+	Type  string     `json:"type"`
+	ID    string     `json:"id,omitempty"`
+	OK    bool       `json:"ok,omitempty"`
+	Node  *node.Node `json:"node,omitempty"`
+	Error string     `json:"error,omitempty"`
+	Code  string     `json:"code,omitempty"`
+	Event *NodeEvent `json:"event,omitempty"`
+}
+
+// wsConn serializes writes to a websocket connection; gorilla/websocket allows only one
+// concurrent writer.
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) writePing() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+}
+
+// WebSocketHandler handles GET /ws.
+//
+// The connection streams every node lifecycle event as an "event" frame and accepts JSON
+// command frames (see WSCommand). Each command gets exactly one "response" frame.
+func (qs *QueueService) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[API] GET /ws - Request")
+
+	raw, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error response.
+		log.Printf("[API] GET /ws - ERROR: upgrade failed - %v", err)
+		return
+	}
+	conn := &wsConn{conn: raw}
+	defer raw.Close()
+
+	events, cancel := qs.Subscribe()
+	defer cancel()
+
+	_ = raw.SetReadDeadline(time.Now().Add(wsPongWait))
+	raw.SetPongHandler(func(string) error {
+		return raw.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Writer: events + keepalive pings.
+	go func() {
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := conn.writeJSON(WSFrame{Type: "event", Event: &ev}); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := conn.writePing(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	log.Printf("[API] GET /ws - SUCCESS: connection opened")
+	for {
+		var cmd WSCommand
+		if err := raw.ReadJSON(&cmd); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				log.Printf("[API] GET /ws - connection closed: %v", err)
+			}
+			return
+		}
+		_ = raw.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		if err := conn.writeJSON(qs.runWSCommand(r.Context(), cmd)); err != nil {
+			log.Printf("[API] GET /ws - ERROR: write failed - %v", err)
+			return
+		}
+	}
+}
+
+// runWSCommand maps a command frame onto the matching QueueService method.
+func (qs *QueueService) runWSCommand(ctx context.Context, cmd WSCommand) WSFrame {
+	log.Printf("[API] WS %s - Request: id=%s node_id=%s", cmd.Op, cmd.ID, cmd.NodeID)
+
+	fail := func(code, msg string) WSFrame {
+		log.Printf("[API] WS %s - ERROR: %s", cmd.Op, msg)
+		return WSFrame{Type: "response", ID: cmd.ID, Error: msg, Code: code}
+	}
+	failErr := func(err error) WSFrame {
+		_, code := errorStatus(err)
+		return fail(code, err.Error())
+	}
+
+	var nodeID string
+	switch cmd.Op {
+	case "create":
+		if cmd.EntityName == "" {
+			return fail(CodeInvalidRequest, "entity_name is required")
+		}
+		// node_id on a create is an optional client-supplied ID, as in POST /nodes.
+		if cmd.NodeID != "" && !node.ValidID(cmd.NodeID) {
+			return fail(CodeInvalidRequest, "node_id is not a valid node ID")
+		}
+		n, err := qs.CreateNodeWithIDContext(ctx, cmd.NodeID, cmd.EntityName)
+		if err != nil {
+			return failErr(err)
+		}
+		nodeID = n.ID
+		if cmd.ResourceID != "" {
+			if err := qs.MoveNodeContext(ctx, nodeID, cmd.ResourceID); err != nil {
+				return failErr(err)
+			}
+		}
+	case "move", "allocate", "complete":
+		if cmd.NodeID == "" {
+			return fail(CodeInvalidRequest, "node_id is required")
+		}
+		nodeID = node.CanonicalID(cmd.NodeID)
+		var err error
+		switch cmd.Op {
+		case "move":
+			if cmd.TargetResourceID == "" {
+				return fail(CodeInvalidRequest, "target_resource_id is required")
+			}
+			err = qs.MoveNodeContext(ctx, nodeID, cmd.TargetResourceID)
+		case "allocate":
+			err = qs.AllocateNodeContext(ctx, nodeID)
+		case "complete":
+			err = qs.CompleteNodeContext(ctx, nodeID)
+		}
+		if err != nil {
+			return failErr(err)
+		}
+	default:
+		return fail(CodeInvalidRequest, "unknown op: "+cmd.Op)
+	}
+
+	n, _ := qs.GetNode(nodeID)
+	log.Printf("[API] WS %s - SUCCESS: node %s", cmd.Op, nodeID)
+	return WSFrame{Type: "response", ID: cmd.ID, OK: true, Node: n}
+}
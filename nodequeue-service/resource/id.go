@@ -0,0 +1,41 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxIDLength caps the length of a canonical resource ID, in bytes.
+const MaxIDLength = 128
+
+// ErrInvalidResourceID is returned (wrapped) by CanonicalResourceID.
+var ErrInvalidResourceID = errors.New("invalid resource ID")
+
+// CanonicalResourceID returns the form resources are registered and looked up under: s with
+// leading and trailing whitespace trimmed and inner runs of whitespace collapsed to one space, and
+// lower-cased when foldCase is set, so "Room 1" and "room 1" name the same resource (see
+// QueueService.FoldResourceIDCase). IDs that end up empty or longer than MaxIDLength return an
+// error wrapping ErrInvalidResourceID.
+func CanonicalResourceID(s string, foldCase bool) (string, error) {
+	id := strings.Join(strings.Fields(s), " ")
+	if foldCase {
+		id = strings.ToLower(id)
+	}
+	switch {
+	case id == "":
+		return "", fmt.Errorf("%w: must not be empty", ErrInvalidResourceID)
+	case len(id) > MaxIDLength:
+		return "", fmt.Errorf("%w: must be at most %d bytes", ErrInvalidResourceID, MaxIDLength)
+	}
+	return id, nil
+}
+
+// LookupID is CanonicalResourceID for lookups: an ID with no canonical form is returned as is,
+// so looking it up simply finds nothing.
+func LookupID(s string, foldCase bool) string {
+	if id, err := CanonicalResourceID(s, foldCase); err == nil {
+		return id
+	}
+	return s
+}
@@ -0,0 +1,152 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidLabelSelector is returned by ParseLabelSelector for a selector that is not key=value.
+var ErrInvalidLabelSelector = errors.New("label selector must be key=value")
+
+// MaxLabels caps how many labels one resource may carry.
+const MaxLabels = 32
+
+// labelReserved are the characters the label config and query formats use as separators
+// ("region=eu;hw=gpu", ?label=region=eu), so keys may not contain any of them and values may not
+// contain ';'.
+const labelReserved = "=;,"
+
+// validateLabel reports what is wrong with one label, or "" if it is valid.
+func validateLabel(key, value string) string {
+	switch {
+	case strings.TrimSpace(key) == "":
+		return "label keys must not be empty"
+	case key != strings.TrimSpace(key) || value != strings.TrimSpace(value):
+		return fmt.Sprintf("label %q must not have leading or trailing spaces", key)
+	case strings.ContainsAny(key, labelReserved):
+		return fmt.Sprintf("label key %q must not contain any of %q", key, labelReserved)
+	case strings.Contains(value, ";"):
+		return fmt.Sprintf("label %q value must not contain ';'", key)
+	}
+	return ""
+}
+
+// validateLabels records the first problem with labels under fields[field].
+func validateLabels(fields map[string]string, field string, labels map[string]string) {
+	if len(labels) > MaxLabels {
+		fields[field] = fmt.Sprintf("must have at most %d labels", MaxLabels)
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		if msg := validateLabel(key, labels[key]); msg != "" {
+			fields[field] = msg
+			return
+		}
+	}
+}
+
+// ParseLabels parses the config-file form of labels, "key=value" pairs separated by ';'
+// (e.g. "region=eu;hw=gpu"). An empty string is no labels.
+func ParseLabels(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			return nil, fmt.Errorf("label %q is not key=value", strings.TrimSpace(pair))
+		}
+		if msg := validateLabel(key, value); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		labels[key] = value
+	}
+	if len(labels) > MaxLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+	return labels, nil
+}
+
+// FormatLabels writes labels in the form ParseLabels reads, ordered by key.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// ParseLabelSelector parses selectors of the form "key=value", as given to ?label=, into a map
+// every one of whose labels a resource must carry (see MatchesLabels).
+func ParseLabelSelector(selectors []string) (map[string]string, error) {
+	selector := make(map[string]string, len(selectors))
+	for _, raw := range selectors {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLabelSelector, raw)
+		}
+		selector[key] = value
+	}
+	return selector, nil
+}
+
+// GetLabels returns a copy of r's labels.
+func (r *Resource) GetLabels() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.Labels)
+}
+
+// SetLabels replaces r's labels with a copy of labels.
+func (r *Resource) SetLabels(labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Labels = nil
+	if len(labels) > 0 {
+		r.Labels = maps.Clone(labels)
+	}
+}
+
+// MatchesLabels reports whether r carries every label in selector with the same value. An empty
+// selector matches every resource.
+func (r *Resource) MatchesLabels(selector map[string]string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for key, value := range selector {
+		if got, ok := r.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// PatchResourceRequest is the request payload for PATCH /resources/{id}.
+type PatchResourceRequest struct {
+	// Labels is merged into the resource's labels: a string sets the label, null removes it.
+	Labels map[string]*string `json:"labels"`
+}
+
+// Validate reports missing or invalid fields.
+func (req PatchResourceRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if len(req.Labels) == 0 {
+		fields["labels"] = "is required"
+		return fields
+	}
+	set := make(map[string]string, len(req.Labels))
+	for key, value := range req.Labels {
+		if value != nil {
+			set[key] = *value
+		} else if strings.TrimSpace(key) == "" {
+			fields["labels"] = "label keys must not be empty"
+			return fields
+		}
+	}
+	validateLabels(fields, "labels", set)
+	return fields
+}
@@ -0,0 +1,159 @@
+package resource
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"nodequeue-service/node"
+)
+
+// DefaultLane is the waiting lane nodes join when no lane is given. A resource that never uses
+// lanes behaves exactly like a single FIFO waiting queue.
+const DefaultLane = "default"
+
+// Waiting lanes
+//
+// WaitingQueue holds every waiting node, grouped into contiguous per-lane segments ordered by
+// LaneOrder, so anything that walks WaitingQueue front to back (fill, auto-promote, the next
+// waiting node) drains higher-priority lanes first while lanes still share one capacity.
+// laneOf records the lane of each non-default waiting node.
+
+// laneRankLocked returns lane's priority (lower drains first). Lanes missing from LaneOrder,
+// including DefaultLane unless listed, rank after every listed lane. Callers must hold r.mu.
+func (r *Resource) laneRankLocked(lane string) int {
+	for i, l := range r.LaneOrder {
+		if l == lane {
+			return i
+		}
+	}
+	return len(r.LaneOrder)
+}
+
+// HasLane reports whether nodes may wait in lane on this resource: "" and DefaultLane always,
+// otherwise only lanes listed in LaneOrder.
+func (r *Resource) HasLane(lane string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.hasLaneLocked(lane)
+}
+
+func (r *Resource) hasLaneLocked(lane string) bool {
+	return lane == "" || lane == DefaultLane || slices.Contains(r.LaneOrder, lane)
+}
+
+// PriorityLane returns the lane ReservedForPriority is held for: the first entry of LaneOrder,
+// or "" when the resource has no lanes.
+func (r *Resource) PriorityLane() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.priorityLaneLocked()
+}
+
+func (r *Resource) priorityLaneLocked() string {
+	if len(r.LaneOrder) == 0 {
+		return ""
+	}
+	return r.LaneOrder[0]
+}
+
+// capacityForLaneLocked returns the capacity a node waiting in lane may be allocated into: all of
+// it for the priority lane, otherwise Capacity less ReservedForPriority. Callers must hold r.mu.
+func (r *Resource) capacityForLaneLocked(lane string) int {
+	if r.ReservedForPriority <= 0 || (lane != "" && lane == r.priorityLaneLocked()) {
+		return r.Capacity
+	}
+	return max(r.Capacity-r.ReservedForPriority, 0)
+}
+
+// AvailableCapacityForLane returns the capacity units a node waiting in lane could still be
+// allocated into: like GetAvailableCapacity, less ReservedForPriority unless lane is the
+// priority lane. It may be negative while priority nodes use the reserved units.
+func (r *Resource) AvailableCapacityForLane(lane string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.capacityForLaneLocked(lane) - r.usedLocked(time.Now())
+}
+
+// laneOfLocked returns the lane of a waiting node. Callers must hold r.mu.
+func (r *Resource) laneOfLocked(nodeID string) string {
+	if lane, ok := r.laneOf[nodeID]; ok {
+		return lane
+	}
+	return DefaultLane
+}
+
+// insertWaitingLocked appends n to the end of its lane's segment. A lane the resource does not
+// have (see HasLane), e.g. one a drained node had on its previous resource, is taken as
+// DefaultLane, so every lane stays one contiguous segment. Callers must hold r.mu for writing.
+func (r *Resource) insertWaitingLocked(n *node.Node, lane string) {
+	if lane == "" || !r.hasLaneLocked(lane) {
+		lane = DefaultLane
+	}
+	rank := r.laneRankLocked(lane)
+	idx := len(r.WaitingQueue)
+	for i, w := range r.WaitingQueue {
+		if r.laneRankLocked(r.laneOfLocked(w.ID)) > rank {
+			idx = i
+			break
+		}
+	}
+	r.WaitingQueue = slices.Insert(r.WaitingQueue, idx, n)
+
+	if lane == DefaultLane {
+		delete(r.laneOf, n.ID)
+		return
+	}
+	if r.laneOf == nil {
+		r.laneOf = make(map[string]string)
+	}
+	r.laneOf[n.ID] = lane
+}
+
+// AddNodeToLane assigns a node to the resource by placing it at the back of the given waiting
+// lane ("" means DefaultLane). Capacity is enforced when allocating from waiting -> service.
+func (r *Resource) AddNodeToLane(n *node.Node, lane string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insertWaitingLocked(n, lane)
+	n.ResourceID = r.ID
+	n.AddResourceID(r.ID)
+	return true
+}
+
+// LaneOf returns the waiting lane of a node on this resource, or "" if it is not waiting here.
+func (r *Resource) LaneOf(nodeID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, n := range r.WaitingQueue {
+		if n.ID == nodeID {
+			return r.laneOfLocked(nodeID)
+		}
+	}
+	return ""
+}
+
+// Lanes returns the waiting node IDs grouped by lane, each in queue order.
+func (r *Resource) Lanes() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lanes := make(map[string][]string)
+	for _, n := range r.WaitingQueue {
+		lane := r.laneOfLocked(n.ID)
+		lanes[lane] = append(lanes[lane], n.ID)
+	}
+	return lanes
+}
+
+// MarshalJSON adds the computed "lanes" view (lane -> waiting node IDs) to the resource JSON.
+func (r *Resource) MarshalJSON() ([]byte, error) {
+	type plain Resource
+	return json.Marshal(struct {
+		*plain
+		Lanes map[string][]string `json:"lanes"`
+	}{plain: (*plain)(r), Lanes: r.Lanes()})
+}
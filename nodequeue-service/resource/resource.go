@@ -2,19 +2,34 @@ package resource
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
+	"math"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
 
 	"nodequeue-service/node"
 )
 
+// Errors returned by ReorderWaitingNode.
+var (
+	ErrNodeNotWaiting = errors.New("node is not in waiting queue")
+	ErrWrongResource  = errors.New("resource ID does not match the resource")
+)
+
 // Resource represents a capacity-limited worker pool.
 //
 // Important invariant:
 // - WaitingQueue does NOT consume capacity.
-// - Nodes (service queue) DOES consume capacity.
+// - Nodes (service queue) DOES consume capacity, each node by its CapacityWeight.
 //
 // Nodes are typically added to WaitingQueue first, then promoted into Nodes via AllocateWaitingNode.
 type Resource struct {
@@ -22,8 +37,48 @@ type Resource struct {
 	Capacity int    `json:"capacity"`
 	// Nodes represents the service queue (nodes currently consuming capacity)
 	Nodes []*node.Node `json:"nodes"`
-	// WaitingQueue represents nodes assigned to this resource but not yet consuming capacity
+	// WaitingQueue represents nodes assigned to this resource but not yet consuming capacity.
+	// With lanes it is ordered lane by lane (see LaneOrder); otherwise it is plain FIFO.
 	WaitingQueue []*node.Node `json:"waiting_queue"`
+	// LaneOrder lists named waiting lanes in allocation priority order (e.g. "priority",
+	// "standard"). Lanes share Capacity. Unlisted lanes, including DefaultLane, drain last.
+	LaneOrder []string `json:"lane_order,omitempty"`
+	// AutoPromote allocates the next waiting node whenever a service node completes.
+	AutoPromote bool `json:"auto_promote"`
+	// MaxPerEntity caps how many service nodes may share one entity name (0 = unlimited).
+	// It is a fairness limit on top of Capacity, not a replacement for it.
+	MaxPerEntity int `json:"max_per_entity"`
+	// FIFOStrict only lets the node at the head of the waiting queue be allocated, so clients
+	// cannot jump the queue. The default lets any waiting node be allocated.
+	FIFOStrict bool `json:"fifo_strict"`
+	// PressureWaiting and PressureSeconds configure the autoscaling signal: a resource_pressure
+	// event fires once the resource has been full with more than PressureWaiting waiting nodes for
+	// PressureSeconds. PressureWaiting 0 disables the signal.
+	PressureWaiting int `json:"pressure_waiting,omitempty"`
+	PressureSeconds int `json:"pressure_seconds,omitempty"`
+	// MaxWaitMS is the waiting-time SLA: nodes waiting longer than this many milliseconds are
+	// reported as breaches (GET /sla/breaches, sla_breach events). 0 disables it.
+	MaxWaitMS int64 `json:"max_wait_ms,omitempty"`
+	// ReservedForPriority holds this many capacity units for nodes waiting in the priority lane
+	// (the first entry of LaneOrder): other nodes may only use Capacity - ReservedForPriority.
+	ReservedForPriority int `json:"reserved_for_priority,omitempty"`
+	// AllocRatePerSec caps how many allocations per second the resource accepts, independently of
+	// Capacity, so a burst of allocations cannot stampede a fragile downstream. It is a token
+	// bucket with a burst of one second's worth (at least 1). 0 disables it.
+	AllocRatePerSec float64 `json:"alloc_rate_per_sec,omitempty"`
+	// Labels are arbitrary key/value metadata (e.g. region, hardware type) for grouping and
+	// selecting resources (see MatchesLabels). Use GetLabels/SetLabels once the resource is shared.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Paused blocks allocations into the service queue (moves into the waiting queue still work).
+	// Use IsPaused/SetPaused; the field is exported for JSON.
+	Paused bool `json:"paused"`
+	// reservations holds capacity for incoming nodes, keyed by reservation ID -> expiry.
+	// Active (unexpired) reservations consume capacity just like service nodes.
+	reservations map[string]time.Time
+	// laneOf maps waiting node IDs to their lane; nodes not present are in DefaultLane.
+	laneOf map[string]string
+	// allocLimiter enforces AllocRatePerSec; it is created on first use (see AllowAllocation).
+	allocLimiter *rate.Limiter
 	mu           sync.RWMutex
 }
 
@@ -40,6 +95,139 @@ func (r *Resource) IsInService(nodeID string) bool {
 	return false
 }
 
+// IsWaiting reports whether the given node ID is currently in the waiting queue.
+func (r *Resource) IsWaiting(nodeID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, n := range r.WaitingQueue {
+		if n.ID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueSnapshot returns copies of the service and waiting queues, taken under the resource lock.
+func (r *Resource) QueueSnapshot() (service, waiting []*node.Node) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	service = make([]*node.Node, len(r.Nodes))
+	copy(service, r.Nodes)
+	waiting = make([]*node.Node, len(r.WaitingQueue))
+	copy(waiting, r.WaitingQueue)
+	return service, waiting
+}
+
+// Snapshot returns a copy of r for serialization: its queues hold node.Node snapshots and its
+// lane and reservation state is copied, so encoding it cannot race with later queue changes.
+// Nodes are not guarded by r.mu; callers must also hold whatever lock guards them.
+func (r *Resource) Snapshot() *Resource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snap := &Resource{
+		ID:                  r.ID,
+		Capacity:            r.Capacity,
+		Nodes:               make([]*node.Node, len(r.Nodes)),
+		WaitingQueue:        make([]*node.Node, len(r.WaitingQueue)),
+		LaneOrder:           slices.Clone(r.LaneOrder),
+		AutoPromote:         r.AutoPromote,
+		MaxPerEntity:        r.MaxPerEntity,
+		FIFOStrict:          r.FIFOStrict,
+		PressureWaiting:     r.PressureWaiting,
+		PressureSeconds:     r.PressureSeconds,
+		MaxWaitMS:           r.MaxWaitMS,
+		ReservedForPriority: r.ReservedForPriority,
+		AllocRatePerSec:     r.AllocRatePerSec,
+		Labels:              maps.Clone(r.Labels),
+		Paused:              r.Paused,
+		reservations:        maps.Clone(r.reservations),
+		laneOf:              maps.Clone(r.laneOf),
+	}
+	for i, n := range r.Nodes {
+		snap.Nodes[i] = n.Snapshot()
+	}
+	for i, n := range r.WaitingQueue {
+		snap.WaitingQueue[i] = n.Snapshot()
+	}
+	return snap
+}
+
+// CloneConfig returns a new empty resource with id and r's configuration: capacity, lanes,
+// auto-promotion, per-entity limit, FIFO mode, pressure, SLA, priority reservation, allocation
+// rate and labels. Queues, reservations, the paused state and the rate limiter's tokens are not
+// copied.
+func (r *Resource) CloneConfig(id string) *Resource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewResource(id, r.Capacity)
+	clone.LaneOrder = slices.Clone(r.LaneOrder)
+	clone.AutoPromote = r.AutoPromote
+	clone.MaxPerEntity = r.MaxPerEntity
+	clone.FIFOStrict = r.FIFOStrict
+	clone.PressureWaiting = r.PressureWaiting
+	clone.PressureSeconds = r.PressureSeconds
+	clone.MaxWaitMS = r.MaxWaitMS
+	clone.ReservedForPriority = r.ReservedForPriority
+	clone.AllocRatePerSec = r.AllocRatePerSec
+	clone.Labels = maps.Clone(r.Labels)
+	return clone
+}
+
+// IsWaitingHead reports whether the given node ID is at the front of the waiting queue.
+func (r *Resource) IsWaitingHead(nodeID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.WaitingQueue) > 0 && r.WaitingQueue[0].ID == nodeID
+}
+
+// IsPaused reports whether allocations into the resource are paused.
+func (r *Resource) IsPaused() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Paused
+}
+
+// SetPaused pauses or resumes allocations into the resource.
+func (r *Resource) SetPaused(paused bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Paused = paused
+}
+
+// EntityAtLimit reports whether the service queue already holds MaxPerEntity nodes for the given
+// entity name. It is always false when MaxPerEntity is 0.
+func (r *Resource) EntityAtLimit(entityName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.MaxPerEntity <= 0 {
+		return false
+	}
+	count := 0
+	for _, n := range r.Nodes {
+		if n.Entity != nil && n.Entity.Name == entityName {
+			count++
+		}
+	}
+	return count >= r.MaxPerEntity
+}
+
+// NextWaitingNode returns the node at the head of the waiting queue, or nil if it is empty.
+func (r *Resource) NextWaitingNode() *node.Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.WaitingQueue) == 0 {
+		return nil
+	}
+	return r.WaitingQueue[0]
+}
+
 // NewResource constructs a Resource with initialized queues and the provided capacity.
 func NewResource(id string, capacity int) *Resource {
 	return &Resource{
@@ -50,44 +238,323 @@ func NewResource(id string, capacity int) *Resource {
 	}
 }
 
-// AddNode assigns a node to the resource by placing it into the waiting queue.
-// Capacity is enforced when allocating from waiting -> service.
-func (r *Resource) AddNode(n *node.Node) bool {
+// AllowAllocation takes an allocation token at now and reports whether one was available. It
+// always succeeds when AllocRatePerSec is 0. Callers should only ask once every other allocation
+// check has passed, since a denied caller does not get its token back.
+func (r *Resource) AllowAllocation(now time.Time) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.WaitingQueue = append(r.WaitingQueue, n)
-	n.ResourceID = r.ID
-	n.AddResourceID(r.ID)
-	return true
+	if r.AllocRatePerSec <= 0 {
+		return true
+	}
+	limit := rate.Limit(r.AllocRatePerSec)
+	if r.allocLimiter == nil || r.allocLimiter.Limit() != limit {
+		r.allocLimiter = rate.NewLimiter(limit, max(int(math.Ceil(r.AllocRatePerSec)), 1))
+	}
+	return r.allocLimiter.AllowN(now, 1)
+}
+
+// AllocationAvailable reports whether AllowAllocation would succeed at now, without taking the
+// token. It always succeeds when AllocRatePerSec is 0.
+func (r *Resource) AllocationAvailable(now time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.AllocRatePerSec <= 0 {
+		return true
+	}
+	// A missing or outdated limiter is replaced by a full one on the next AllowAllocation.
+	if r.allocLimiter == nil || r.allocLimiter.Limit() != rate.Limit(r.AllocRatePerSec) {
+		return true
+	}
+	return r.allocLimiter.TokensAt(now) >= 1
+}
+
+// AddNode assigns a node to the resource by placing it at the back of the default waiting lane.
+// Capacity is enforced when allocating from waiting -> service.
+func (r *Resource) AddNode(n *node.Node) bool {
+	return r.AddNodeToLane(n, DefaultLane)
 }
 
 // AllocateWaitingNode promotes a node from the waiting queue into the service queue.
 //
 // Returns false if:
-// - the Resource is already at capacity, or
+// - the node's weight exceeds the remaining capacity (including active reservations), or
+// - the node is outside the priority lane and would use capacity held by ReservedForPriority, or
 // - the node is not present in the waiting queue.
 func (r *Resource) AllocateWaitingNode(nodeID string) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if len(r.Nodes) >= r.Capacity {
+	var weight int
+	for _, n := range r.WaitingQueue {
+		if n.ID == nodeID {
+			weight = n.CapacityWeight()
+			break
+		}
+	}
+	if weight == 0 || r.usedLocked(time.Now())+weight > r.capacityForLaneLocked(r.laneOfLocked(nodeID)) {
 		return false
 	}
 
+	return r.promoteWaitingLocked(nodeID)
+}
+
+// usedLocked returns the capacity units held by service nodes (by weight) and active
+// reservations (one unit each). Callers must hold r.mu.
+func (r *Resource) usedLocked(now time.Time) int {
+	used := r.activeReservations(now)
+	for _, n := range r.Nodes {
+		used += n.CapacityWeight()
+	}
+	return used
+}
+
+// promoteWaitingLocked moves a node from the waiting queue into the service queue without
+// checking capacity. Callers must hold r.mu.
+func (r *Resource) promoteWaitingLocked(nodeID string) bool {
 	for i, node := range r.WaitingQueue {
 		if node.ID == nodeID {
 			// remove the node from the waiting queue
 			r.WaitingQueue = append(r.WaitingQueue[:i], r.WaitingQueue[i+1:]...)
+			delete(r.laneOf, nodeID)
 			// Add this to allocated queue
 			r.Nodes = append(r.Nodes, node)
 			return true
 		}
 	}
-
 	return false
 }
 
+// activeReservations counts unexpired reservations at now. Callers must hold r.mu.
+func (r *Resource) activeReservations(now time.Time) int {
+	count := 0
+	for _, expiresAt := range r.reservations {
+		if now.Before(expiresAt) {
+			count++
+		}
+	}
+	return count
+}
+
+// pruneReservationsLocked drops expired reservations. Callers must hold r.mu for writing.
+func (r *Resource) pruneReservationsLocked(now time.Time) {
+	for id, expiresAt := range r.reservations {
+		if !now.Before(expiresAt) {
+			delete(r.reservations, id)
+		}
+	}
+}
+
+// Reserve holds one unit of capacity under the given reservation ID until expiresAt.
+// Returns false if the resource has no free capacity (service nodes + active reservations).
+// Reservations cannot take the units held by ReservedForPriority.
+func (r *Resource) Reserve(reservationID string, expiresAt time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.pruneReservationsLocked(now)
+	if r.usedLocked(now) >= r.capacityForLaneLocked(DefaultLane) {
+		return false
+	}
+	if r.reservations == nil {
+		r.reservations = make(map[string]time.Time)
+	}
+	r.reservations[reservationID] = expiresAt
+	return true
+}
+
+// HasReservation reports whether the reservation ID is held and not yet expired.
+func (r *Resource) HasReservation(reservationID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expiresAt, ok := r.reservations[reservationID]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// ClaimReservation converts an active reservation into an allocation for a waiting node.
+//
+// The reserved slot is released and the node is promoted into the service queue. A reservation
+// holds one unit, so a heavier node must also fit in the capacity left once that unit is released.
+// Returns false if the reservation is unknown or expired, the node is not in the waiting queue, or
+// the node does not fit.
+func (r *Resource) ClaimReservation(reservationID, nodeID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.pruneReservationsLocked(now)
+	if _, ok := r.reservations[reservationID]; !ok {
+		return false
+	}
+	idx := slices.IndexFunc(r.WaitingQueue, func(n *node.Node) bool { return n.ID == nodeID })
+	if idx == -1 {
+		return false
+	}
+	weight := r.WaitingQueue[idx].CapacityWeight()
+	if r.usedLocked(now)-1+weight > r.capacityForLaneLocked(r.laneOfLocked(nodeID)) {
+		return false
+	}
+	r.promoteWaitingLocked(nodeID)
+	delete(r.reservations, reservationID)
+	return true
+}
+
+// ActiveReservations returns the number of unexpired reservations holding capacity.
+func (r *Resource) ActiveReservations() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.activeReservations(time.Now())
+}
+
+// ReorderWaitingNode moves a node to the given position within its waiting lane on the resource
+// named resourceID, which must be r.
+//
+// Position is zero-based within the lane and clamped to valid bounds (negative -> front, past the
+// end -> back); for a resource without lanes that is the whole waiting queue.
+// Returns ErrWrongResource if resourceID is not r.ID, and ErrNodeNotWaiting if the node is not
+// present in the waiting queue (e.g. it is in service).
+func (r *Resource) ReorderWaitingNode(resourceID, nodeID string, position int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resourceID != r.ID {
+		return fmt.Errorf("%w: %q is not %q", ErrWrongResource, resourceID, r.ID)
+	}
+
+	idx := -1
+	for i, node := range r.WaitingQueue {
+		if node.ID == nodeID {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return ErrNodeNotWaiting
+	}
+
+	// Lanes are contiguous, so the node's lane is the segment [start, end) around idx.
+	lane := r.laneOfLocked(nodeID)
+	start, end := idx, idx+1
+	for start > 0 && r.laneOfLocked(r.WaitingQueue[start-1].ID) == lane {
+		start--
+	}
+	for end < len(r.WaitingQueue) && r.laneOfLocked(r.WaitingQueue[end].ID) == lane {
+		end++
+	}
+
+	if position < 0 {
+		position = 0
+	}
+	if position > end-start-1 {
+		position = end - start - 1
+	}
+	position += start
+
+	n := r.WaitingQueue[idx]
+	r.WaitingQueue = append(r.WaitingQueue[:idx], r.WaitingQueue[idx+1:]...)
+	r.WaitingQueue = append(r.WaitingQueue[:position], append([]*node.Node{n}, r.WaitingQueue[position:]...)...)
+	return nil
+}
+
+// SwapWaitingNodes exchanges the waiting queue positions of two nodes; every other node keeps its
+// position. With lanes, the nodes also take each other's lane, so lanes stay contiguous.
+//
+// Returns false, changing nothing, if either node is not in the waiting queue (e.g. it is in
+// service) or both IDs are the same.
+func (r *Resource) SwapWaitingNodes(nodeA, nodeB string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if nodeA == nodeB {
+		return false
+	}
+	idxA := slices.IndexFunc(r.WaitingQueue, func(n *node.Node) bool { return n.ID == nodeA })
+	idxB := slices.IndexFunc(r.WaitingQueue, func(n *node.Node) bool { return n.ID == nodeB })
+	if idxA == -1 || idxB == -1 {
+		return false
+	}
+
+	r.WaitingQueue[idxA], r.WaitingQueue[idxB] = r.WaitingQueue[idxB], r.WaitingQueue[idxA]
+
+	// laneOf is non-nil here whenever the lanes differ, since one of them is not DefaultLane.
+	setLane := func(nodeID, lane string) {
+		if lane == DefaultLane {
+			delete(r.laneOf, nodeID)
+		} else {
+			r.laneOf[nodeID] = lane
+		}
+	}
+	laneA, laneB := r.laneOfLocked(nodeA), r.laneOfLocked(nodeB)
+	if laneA != laneB {
+		setLane(nodeA, laneB)
+		setLane(nodeB, laneA)
+	}
+	return true
+}
+
+// ReplaceQueues sets the service and waiting queues wholesale, e.g. when restoring from a store.
+// Waiting nodes keep the lane they already had here (others join DefaultLane) and are stably
+// regrouped by lane priority so lanes stay contiguous. Reservations are kept.
+func (r *Resource) ReplaceQueues(service, waiting []*node.Node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	laneOf := make(map[string]string)
+	for _, n := range waiting {
+		if lane, ok := r.laneOf[n.ID]; ok {
+			laneOf[n.ID] = lane
+		}
+	}
+	r.laneOf = laneOf
+	r.Nodes = slices.Clone(service)
+	r.WaitingQueue = slices.Clone(waiting)
+	slices.SortStableFunc(r.WaitingQueue, func(a, b *node.Node) int {
+		return r.laneRankLocked(r.laneOfLocked(a.ID)) - r.laneRankLocked(r.laneOfLocked(b.ID))
+	})
+}
+
+// ReleaseServiceNodes moves every service node back to the waiting queue in one step and returns
+// them. They go ahead of the nodes already waiting in DefaultLane, in their service order, since
+// they were further along. Reservations are kept.
+func (r *Resource) ReleaseServiceNodes() []*node.Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := r.Nodes
+	r.Nodes = make([]*node.Node, 0)
+	if len(released) == 0 {
+		return nil
+	}
+
+	rank := r.laneRankLocked(DefaultLane)
+	idx := len(r.WaitingQueue)
+	for i, w := range r.WaitingQueue {
+		if r.laneRankLocked(r.laneOfLocked(w.ID)) >= rank {
+			idx = i
+			break
+		}
+	}
+	r.WaitingQueue = slices.Insert(r.WaitingQueue, idx, released...)
+	return released
+}
+
+// Clear empties the service and waiting queues and drops all reservations. Configuration
+// (capacity, limits, pause state) is kept.
+func (r *Resource) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Nodes = nil
+	r.WaitingQueue = nil
+	r.reservations = nil
+	r.laneOf = nil
+}
+
 // RemoveNode removes a node from the resource, searching both the service queue and waiting queue.
 // It returns true if a node was removed.
 func (r *Resource) RemoveNode(nodeID string) bool {
@@ -104,6 +571,7 @@ func (r *Resource) RemoveNode(nodeID string) bool {
 	for i, node := range r.WaitingQueue {
 		if node.ID == nodeID {
 			r.WaitingQueue = append(r.WaitingQueue[:i], r.WaitingQueue[i+1:]...)
+			delete(r.laneOf, nodeID)
 			return true
 		}
 	}
@@ -129,75 +597,531 @@ func (r *Resource) GetNode(nodeID string) *node.Node {
 	return nil
 }
 
-// GetAvailableCapacity returns remaining capacity based on the service queue size.
-// Nodes in WaitingQueue do not affect this value.
+// GetAvailableCapacity returns remaining capacity units: Capacity minus the summed weights of
+// service nodes and active reservations. Nodes in WaitingQueue do not affect this value.
 func (r *Resource) GetAvailableCapacity() int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.Capacity - len(r.Nodes)
+	return r.Capacity - r.usedLocked(time.Now())
 }
 
-// IsFull reports whether the service queue has reached capacity.
+// IsFull reports whether the service queue (by weight) plus active reservations has reached capacity.
 func (r *Resource) IsFull() bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return len(r.Nodes) >= r.Capacity
+	return r.usedLocked(time.Now()) >= r.Capacity
+}
+
+// TransferNodes moves every waiting node (and, if includeService, every service node) from one
+// resource to the end of another resource's waiting queue, preserving relative order. Service
+// nodes are placed ahead of waiting nodes since they were further along. Waiting nodes keep their
+// lane; service nodes join DefaultLane. If accept is non-nil, nodes it rejects stay where they are
+// on from.
+//
+// Both resource locks are held for the whole transfer, acquired in ID order so concurrent
+// transfers in opposite directions cannot deadlock. Returns the moved nodes in their new order.
+func TransferNodes(from, to *Resource, includeService bool, accept func(*node.Node) bool) []*node.Node {
+	first, second := from, to
+	if to.ID < from.ID {
+		first, second = to, from
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	moved := make([]*node.Node, 0, len(from.WaitingQueue)+len(from.Nodes))
+	lanes := make([]string, 0, cap(moved))
+	if includeService {
+		keptService := make([]*node.Node, 0)
+		for _, n := range from.Nodes {
+			if accept != nil && !accept(n) {
+				keptService = append(keptService, n)
+				continue
+			}
+			moved = append(moved, n)
+			lanes = append(lanes, DefaultLane)
+		}
+		from.Nodes = keptService
+	}
+	keptWaiting := make([]*node.Node, 0)
+	var keptLanes map[string]string
+	for _, n := range from.WaitingQueue {
+		if accept != nil && !accept(n) {
+			keptWaiting = append(keptWaiting, n)
+			if lane, ok := from.laneOf[n.ID]; ok {
+				if keptLanes == nil {
+					keptLanes = make(map[string]string)
+				}
+				keptLanes[n.ID] = lane
+			}
+			continue
+		}
+		moved = append(moved, n)
+		lanes = append(lanes, from.laneOfLocked(n.ID))
+	}
+	from.WaitingQueue = keptWaiting
+	from.laneOf = keptLanes
+
+	for i, n := range moved {
+		to.insertWaitingLocked(n, lanes[i])
+		n.ResourceID = to.ID
+		n.AddResourceID(to.ID)
+	}
+
+	// Report the nodes in their new queue order.
+	order := make(map[string]int, len(to.WaitingQueue))
+	for i, n := range to.WaitingQueue {
+		order[n.ID] = i
+	}
+	slices.SortStableFunc(moved, func(a, b *node.Node) int { return order[a.ID] - order[b.ID] })
+	return moved
+}
+
+// CreateResourceRequest is the request payload for POST /resources.
+type CreateResourceRequest struct {
+	ID              string `json:"id"`
+	Capacity        int    `json:"capacity"`
+	AutoPromote     bool   `json:"auto_promote,omitempty"`
+	MaxPerEntity    int    `json:"max_per_entity,omitempty"`
+	FIFOStrict      bool   `json:"fifo_strict,omitempty"`
+	PressureWaiting int    `json:"pressure_waiting,omitempty"`
+	PressureSeconds int    `json:"pressure_seconds,omitempty"`
+	MaxWaitMS       int64  `json:"max_wait_ms,omitempty"`
+	// Lanes optionally names waiting lanes in allocation priority order.
+	Lanes []string `json:"lanes,omitempty"`
+	// ReservedForPriority holds capacity for the first lane in Lanes (see Resource).
+	ReservedForPriority int `json:"reserved_for_priority,omitempty"`
+	// AllocRatePerSec caps allocations per second (see Resource). 0 disables it.
+	AllocRatePerSec float64 `json:"alloc_rate_per_sec,omitempty"`
+	// Labels are arbitrary key/value metadata (see Resource).
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Validate reports missing or invalid fields.
+func (req CreateResourceRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if strings.TrimSpace(req.ID) == "" {
+		fields["id"] = "is required"
+	} else if _, err := CanonicalResourceID(req.ID, false); err != nil {
+		fields["id"] = fmt.Sprintf("must be at most %d bytes", MaxIDLength)
+	}
+	if req.Capacity <= 0 {
+		fields["capacity"] = "must be greater than 0"
+	}
+	if req.MaxPerEntity < 0 {
+		fields["max_per_entity"] = "must be 0 (unlimited) or greater"
+	}
+	if req.PressureWaiting < 0 {
+		fields["pressure_waiting"] = "must be 0 (disabled) or greater"
+	}
+	if req.PressureSeconds < 0 {
+		fields["pressure_seconds"] = "must be 0 or greater"
+	}
+	if req.MaxWaitMS < 0 {
+		fields["max_wait_ms"] = "must be 0 (disabled) or greater"
+	}
+	if req.AllocRatePerSec < 0 {
+		fields["alloc_rate_per_sec"] = "must be 0 (disabled) or greater"
+	}
+	validateLabels(fields, "labels", req.Labels)
+	switch {
+	case req.ReservedForPriority < 0:
+		fields["reserved_for_priority"] = "must be 0 (disabled) or greater"
+	case req.ReservedForPriority > 0 && req.ReservedForPriority >= req.Capacity:
+		fields["reserved_for_priority"] = "must be less than capacity"
+	case req.ReservedForPriority > 0 && len(req.Lanes) == 0:
+		fields["reserved_for_priority"] = "requires lanes; the first lane is the priority lane"
+	}
+	seen := make(map[string]bool, len(req.Lanes))
+	for _, lane := range req.Lanes {
+		if strings.TrimSpace(lane) == "" {
+			fields["lanes"] = "lane names must not be empty"
+			break
+		}
+		if seen[lane] {
+			fields["lanes"] = "lane names must be unique"
+			break
+		}
+		seen[lane] = true
+	}
+	return fields
+}
+
+// MaxBatchResources caps how many resources one POST /resources/batch request may create.
+const MaxBatchResources = 100
+
+// CreateResourcesRequest is the request payload for POST /resources/batch: a JSON array of
+// CreateResourceRequest.
+type CreateResourcesRequest []CreateResourceRequest
+
+// Validate reports an empty or oversized batch and each item's invalid fields, keyed by
+// "[index].field". Duplicate IDs are not a validation error; they are reported per item.
+func (req CreateResourcesRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	switch {
+	case len(req) == 0:
+		fields["body"] = "must contain at least one resource"
+	case len(req) > MaxBatchResources:
+		fields["body"] = fmt.Sprintf("must contain at most %d resources", MaxBatchResources)
+	}
+	for i, item := range req {
+		for field, msg := range item.Validate() {
+			fields[fmt.Sprintf("[%d].%s", i, field)] = msg
+		}
+	}
+	return fields
+}
+
+// ReserveCapacityRequest is the request payload for POST /resources/{id}/reserve.
+type ReserveCapacityRequest struct {
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+// Validate reports missing or invalid fields.
+func (req ReserveCapacityRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.TTLSeconds <= 0 {
+		fields["ttl_seconds"] = "must be greater than 0"
+	}
+	return fields
+}
+
+// SwapWaitingNodesRequest is the request payload for POST /resources/{id}/swap.
+type SwapWaitingNodesRequest struct {
+	NodeA string `json:"node_a"`
+	NodeB string `json:"node_b"`
+}
+
+// Validate reports missing or invalid fields.
+func (req SwapWaitingNodesRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if req.NodeA == "" {
+		fields["node_a"] = "is required"
+	}
+	if req.NodeB == "" {
+		fields["node_b"] = "is required"
+	} else if req.NodeB == req.NodeA {
+		fields["node_b"] = "must differ from node_a"
+	}
+	return fields
+}
+
+// CloneResourceRequest is the request payload for POST /resources/{id}/clone.
+type CloneResourceRequest struct {
+	NewID string `json:"new_id"`
+}
+
+// Validate reports missing or invalid fields.
+func (req CloneResourceRequest) Validate() map[string]string {
+	fields := make(map[string]string)
+	if strings.TrimSpace(req.NewID) == "" {
+		fields["new_id"] = "is required"
+	}
+	return fields
+}
+
+// RedirectRequest is the request payload for POST /resources/{id}/redirect. An empty To clears
+// the redirect.
+type RedirectRequest struct {
+	To string `json:"to"`
 }
 
 // Util functions for Resource
 
 type resourceConfig struct {
-	id       string
-	capacity int
+	id              string
+	capacity        int
+	autoPromote     bool
+	maxPerEntity    int
+	pressureWaiting int
+	pressureSeconds int
+	fifoStrict      bool
+	maxWaitMS       int64
+	allocRatePerSec float64
+	labels          map[string]string
+	lanes           []string
+	reserved        int
+}
+
+// LoadOptions controls how LoadResourcesWithOptions parses the config file.
+type LoadOptions struct {
+	// Strict makes any malformed row, non-positive capacity or duplicate ID an error instead of
+	// skipping it.
+	Strict bool
+	// DefaultCapacity, when > 0, is used for rows whose capacity column is missing or empty.
+	DefaultCapacity int
+	// FoldIDCase lower-cases IDs, so rows differing only in case are duplicates (see
+	// CanonicalResourceID).
+	FoldIDCase bool
+}
+
+// ConfigProblem is one config row that was rejected, by 1-based line number.
+type ConfigProblem struct {
+	Line   int
+	Reason string
+}
+
+func (p ConfigProblem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+}
+
+// LoadReport summarizes a config parse for the startup log.
+type LoadReport struct {
+	// Loaded is the number of resources read from the file.
+	Loaded int
+	// Skipped lists rows ignored in lenient mode.
+	Skipped []ConfigProblem
+	// UsedDefaults is set when the file was missing or yielded no resources.
+	UsedDefaults bool
+}
+
+// ConfigError is returned in strict mode and lists every rejected row.
+type ConfigError struct {
+	File     string
+	Problems []ConfigProblem
+}
+
+func (e *ConfigError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.String()
+	}
+	return fmt.Sprintf("%s: %d invalid rows: %s", e.File, len(e.Problems), strings.Join(parts, "; "))
 }
 
 // loadResources attempts to read resource definitions from a CSV file.
 // If the file does not exist (or yields no valid rows), it falls back to defaults.
 //
-// Expected CSV format: id,capacity (with an optional header row like "Name,Capacity").
-func loadResources(fileName string) []resourceConfig {
+// Expected CSV format (see CSVHeader, with an optional header row like "Name,Capacity"):
+//
+//	id,capacity[,auto_promote[,max_per_entity[,pressure_waiting,pressure_seconds[,fifo_strict[,max_wait_ms[,alloc_rate_per_sec[,labels[,lanes[,reserved_for_priority]]]]]]]]]
+//
+// labels is "key=value" pairs and lanes is lane names, each separated by ";".
+//
+// In lenient mode malformed rows are skipped and recorded in the report. In strict mode they, along
+// with non-positive capacities and duplicate IDs, are collected into a *ConfigError.
+func loadResources(fileName string, opts LoadOptions) ([]resourceConfig, LoadReport, error) {
 	resources := make([]resourceConfig, 0)
+	var report LoadReport
+	var problems []ConfigProblem
 
 	configFile, err := os.Open(fileName)
 	if err == nil {
 		defer configFile.Close()
 		reader := csv.NewReader(configFile)
+		reader.FieldsPerRecord = -1 // trailing columns are optional per row
+		seen := make(map[string]int)
 		for {
 			record, err := reader.Read()
 			if err == io.EOF {
 				break
 			}
-			if err != nil || len(record) < 2 || record[0] == "Name" {
-				continue // skip malformed lines and header
-			}
-			cap, err := strconv.Atoi(record[1])
 			if err != nil {
-				continue // skip if capacity field is not integer
+				line := 0
+				if perr, ok := err.(*csv.ParseError); ok {
+					line = perr.StartLine
+				}
+				problems = append(problems, ConfigProblem{Line: line, Reason: err.Error()})
+				continue
 			}
-			resources = append(resources, resourceConfig{id: record[0], capacity: cap})
+			if record[0] == CSVHeader[0] {
+				continue
+			}
+			line, _ := reader.FieldPos(0)
+
+			cfg, reason := parseResourceRecord(record, opts)
+			if reason == "" && opts.Strict {
+				switch first, dup := seen[cfg.id]; {
+				case dup:
+					reason = fmt.Sprintf("duplicate id %q (first defined on line %d)", cfg.id, first)
+				case cfg.capacity <= 0:
+					reason = fmt.Sprintf("capacity must be greater than 0, got %d", cfg.capacity)
+				}
+			}
+			if reason != "" {
+				problems = append(problems, ConfigProblem{Line: line, Reason: reason})
+				continue
+			}
+			if _, dup := seen[cfg.id]; !dup {
+				seen[cfg.id] = line
+			}
+			resources = append(resources, cfg)
 		}
 	}
 
+	if opts.Strict && len(problems) > 0 {
+		return nil, report, &ConfigError{File: fileName, Problems: problems}
+	}
+	report.Skipped = problems
+	report.Loaded = len(resources)
+
 	// If file is missing OR produced no valid resources, use defaults.
 	if len(resources) == 0 {
+		report.UsedDefaults = true
 		resources = []resourceConfig{
 			{id: "Room 1", capacity: 5},
 			{id: "Room 2", capacity: 3},
 			{id: "Room 3", capacity: 4},
 		}
 	}
-	return resources
+	return resources, report, nil
+}
+
+// parseResourceRecord converts one CSV row, returning a non-empty reason when the row is malformed.
+// Invalid optional columns fall back to their zero values.
+func parseResourceRecord(record []string, opts LoadOptions) (resourceConfig, string) {
+	if strings.TrimSpace(record[0]) == "" {
+		return resourceConfig{}, "id is required"
+	}
+	id, err := CanonicalResourceID(record[0], opts.FoldIDCase)
+	if err != nil {
+		return resourceConfig{}, err.Error()
+	}
+	cfg := resourceConfig{id: id}
+
+	rawCap := ""
+	if len(record) >= 2 {
+		rawCap = strings.TrimSpace(record[1])
+	}
+	switch {
+	case rawCap == "" && opts.DefaultCapacity > 0:
+		cfg.capacity = opts.DefaultCapacity
+	case rawCap == "":
+		return resourceConfig{}, "capacity is required"
+	default:
+		cap, err := strconv.Atoi(rawCap)
+		if err != nil {
+			return resourceConfig{}, fmt.Sprintf("capacity %q is not an integer", rawCap)
+		}
+		cfg.capacity = cap
+	}
+
+	if len(record) >= 3 {
+		cfg.autoPromote, _ = strconv.ParseBool(record[2])
+	}
+	if len(record) >= 4 {
+		if max, err := strconv.Atoi(strings.TrimSpace(record[3])); err == nil && max > 0 {
+			cfg.maxPerEntity = max
+		}
+	}
+	if len(record) >= 6 {
+		waiting, werr := strconv.Atoi(strings.TrimSpace(record[4]))
+		seconds, serr := strconv.Atoi(strings.TrimSpace(record[5]))
+		if werr == nil && serr == nil && waiting > 0 && seconds >= 0 {
+			cfg.pressureWaiting, cfg.pressureSeconds = waiting, seconds
+		}
+	}
+	if len(record) >= 7 {
+		cfg.fifoStrict, _ = strconv.ParseBool(strings.TrimSpace(record[6]))
+	}
+	if len(record) >= 8 {
+		if maxWait, err := strconv.ParseInt(strings.TrimSpace(record[7]), 10, 64); err == nil && maxWait > 0 {
+			cfg.maxWaitMS = maxWait
+		}
+	}
+	if len(record) >= 9 {
+		if allocRate, err := strconv.ParseFloat(strings.TrimSpace(record[8]), 64); err == nil && allocRate > 0 {
+			cfg.allocRatePerSec = allocRate
+		}
+	}
+	if len(record) >= 10 {
+		labels, err := ParseLabels(record[9])
+		if err != nil {
+			return resourceConfig{}, err.Error()
+		}
+		cfg.labels = labels
+	}
+	if len(record) >= 11 && strings.TrimSpace(record[10]) != "" {
+		for _, lane := range strings.Split(record[10], ";") {
+			lane = strings.TrimSpace(lane)
+			if lane == "" || slices.Contains(cfg.lanes, lane) {
+				return resourceConfig{}, fmt.Sprintf("lanes %q must be unique, non-empty names", record[10])
+			}
+			cfg.lanes = append(cfg.lanes, lane)
+		}
+	}
+	if len(record) >= 12 {
+		if reserved, err := strconv.Atoi(strings.TrimSpace(record[11])); err == nil && reserved > 0 {
+			switch {
+			case len(cfg.lanes) == 0:
+				return resourceConfig{}, "reserved_for_priority requires lanes"
+			case reserved >= cfg.capacity:
+				return resourceConfig{}, "reserved_for_priority must be less than capacity"
+			}
+			cfg.reserved = reserved
+		}
+	}
+	return cfg, ""
 }
 
 // LoadResources returns initialized Resource instances based on a CSV config file,
 // falling back to built-in defaults when the file is missing or empty.
 func LoadResources(fileName string) []*Resource {
-	cfgs := loadResources(fileName)
+	resources, _, _ := LoadResourcesWithOptions(fileName, LoadOptions{})
+	return resources
+}
+
+// LoadResourcesWithOptions is LoadResources with strict parsing and a default capacity. It also
+// returns a report of what was loaded and skipped; in strict mode any rejected row yields a
+// *ConfigError and no resources.
+func LoadResourcesWithOptions(fileName string, opts LoadOptions) ([]*Resource, LoadReport, error) {
+	cfgs, report, err := loadResources(fileName, opts)
+	if err != nil {
+		return nil, report, err
+	}
 	out := make([]*Resource, 0, len(cfgs))
 	for _, c := range cfgs {
-		out = append(out, NewResource(c.id, c.capacity))
+		r := NewResource(c.id, c.capacity)
+		r.AutoPromote = c.autoPromote
+		r.MaxPerEntity = c.maxPerEntity
+		r.PressureWaiting = c.pressureWaiting
+		r.PressureSeconds = c.pressureSeconds
+		r.FIFOStrict = c.fifoStrict
+		r.MaxWaitMS = c.maxWaitMS
+		r.AllocRatePerSec = c.allocRatePerSec
+		r.Labels = c.labels
+		r.LaneOrder = c.lanes
+		r.ReservedForPriority = c.reserved
+		out = append(out, r)
+	}
+	return out, report, nil
+}
+
+// CSVHeader is the header row WriteCSV emits; loadResources skips it on import.
+var CSVHeader = []string{"Name", "Capacity", "AutoPromote", "MaxPerEntity", "PressureWaiting", "PressureSeconds", "FIFOStrict", "MaxWaitMS", "AllocRatePerSec", "Labels", "Lanes", "ReservedForPriority"}
+
+// WriteCSV writes resources in the format LoadResources reads, with a CSVHeader row, so an
+// exported file can be edited and used as config.txt. Runtime-only state (queues, pause and
+// node reservations) is not included.
+func WriteCSV(w io.Writer, resources []*Resource) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, r := range resources {
+		r.mu.RLock()
+		record := []string{
+			r.ID,
+			strconv.Itoa(r.Capacity),
+			strconv.FormatBool(r.AutoPromote),
+			strconv.Itoa(r.MaxPerEntity),
+			strconv.Itoa(r.PressureWaiting),
+			strconv.Itoa(r.PressureSeconds),
+			strconv.FormatBool(r.FIFOStrict),
+			strconv.FormatInt(r.MaxWaitMS, 10),
+			strconv.FormatFloat(r.AllocRatePerSec, 'g', -1, 64),
+			FormatLabels(r.Labels),
+			strings.Join(r.LaneOrder, ";"),
+			strconv.Itoa(r.ReservedForPriority),
+		}
+		r.mu.RUnlock()
+		if err := cw.Write(record); err != nil {
+			return err
+		}
 	}
-	return out
+	cw.Flush()
+	return cw.Error()
 }
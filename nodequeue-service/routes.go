@@ -7,19 +7,102 @@ import (
 	"strings"
 
 	"nodequeue-service/db"
+	"nodequeue-service/node"
 	"nodequeue-service/queueservice"
 	"nodequeue-service/resource"
+	"nodequeue-service/utils"
 )
 
 // setupRoutes registers the HTTP routes for the NodeQueue service.
 //
 // Note: net/http's DefaultServeMux is used for simplicity.
-func setupRoutes(qs *queueservice.QueueService) {
-	http.HandleFunc("/nodes/metrics", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+// JSON endpoints are gzip-compressed for clients that accept it; /ws is left unwrapped.
+// Destructive admin endpoints are wrapped in admin.
+func setupRoutes(qs *queueservice.QueueService, admin utils.AdminGuard) {
+	// Liveness probe; exempt from rate limiting.
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	// Readiness probe; degrades while store writes keep failing (STORE_DEGRADE_AFTER).
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		qs.ReadyHandler(w, r)
+	})
+
+	// Prometheus scrape endpoint; exempt from rate limiting.
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		qs.MetricsHandler(w, r)
+	})
+
+	http.HandleFunc("/admin/store-health", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.StoreHealthHandler(w, r)
+	}))))
+
+	http.HandleFunc("/admin/reconcile", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.ReconcileOrphansHandler(w, r)
+	}))))
+
+	http.HandleFunc("/admin/consistency", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.ConsistencyHandler(w, r)
+	}))))
+
+	http.HandleFunc("/admin/reset", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.ResetHandler(w, r)
+	}))))
+
+	http.HandleFunc("/admin/restore", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.MergeFromStoreHandler(w, r)
+	}))))
+
+	http.HandleFunc("/admin/rebuild", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.RebuildFromLogsHandler(w, r)
+	}))))
+
+	http.HandleFunc("/debug/internals", corsMiddleware(utils.GzipMiddleware(admin.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		qs.DebugInternalsHandler(w, r)
+	}))))
+
+	http.HandleFunc("/nodes/metrics", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		qs.NodesMetricsHandler(w, r)
-	}))
+	})))
+
+	http.HandleFunc("/nodes/metrics/batch", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.NodeMetricsBatchHandler(w, r)
+	})))
 
-	http.HandleFunc("/nodes", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/nodes/logs.jsonl", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ExportNodeLogsHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/archive", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ArchivedNodesHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/active", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ListActiveNodesHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/waiting", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ListWaitingHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/outcomes", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.OutcomesHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/throughput", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ThroughputHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes/bulk", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.BulkActionHandler(w, r)
+	})))
+
+	http.HandleFunc("/resources.csv", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.ExportResourcesCSVHandler(w, r)
+	})))
+
+	http.HandleFunc("/nodes", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			qs.CreateNodeHandler(w, r)
@@ -28,9 +111,9 @@ func setupRoutes(qs *queueservice.QueueService) {
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
+	})))
 
-	http.HandleFunc("/nodes/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/nodes/", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/nodes/")
 		parts := strings.Split(path, "/")
 
@@ -39,11 +122,43 @@ func setupRoutes(qs *queueservice.QueueService) {
 			return
 		}
 
-		nodeID := parts[0]
+		nodeID := node.CanonicalID(parts[0])
+
+		// Handle DELETE /nodes/{id}/tags/{tag}
+		if len(parts) == 3 && parts[1] == "tags" {
+			if r.Method == http.MethodDelete {
+				qs.RemoveNodeTagHandler(w, r, nodeID, parts[2])
+			} else {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
 
-		// Handle sub-routes: /nodes/{id}/move or /nodes/{id}/complete
+		// Handle sub-routes: /nodes/{id}/move, /allocate, /can-allocate, /eligible-resources, /claim, /complete, /fail, /position,
+		// /expedite, /defer, /notes, /tags, /metrics
 		if len(parts) == 2 {
 			switch parts[1] {
+			case "metrics":
+				if r.Method == http.MethodGet {
+					qs.NodeMetricsHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "notes":
+				if r.Method == http.MethodPost {
+					qs.AddNodeNoteHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "tags":
+				if r.Method == http.MethodPost {
+					qs.AddNodeTagsHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
 			case "move":
 				if r.Method == http.MethodPost {
 					qs.MoveNodeHandler(w, r, nodeID)
@@ -58,6 +173,20 @@ func setupRoutes(qs *queueservice.QueueService) {
 					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				}
 				return
+			case "transfer":
+				if r.Method == http.MethodPost {
+					qs.TransferNodeHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "fail":
+				if r.Method == http.MethodPost {
+					qs.FailNodeHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
 			case "complete":
 				if r.Method == http.MethodPost {
 					qs.CompleteNodeHandler(w, r, nodeID)
@@ -65,6 +194,48 @@ func setupRoutes(qs *queueservice.QueueService) {
 					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				}
 				return
+			case "can-allocate":
+				if r.Method == http.MethodGet {
+					qs.CanAllocateHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "eligible-resources":
+				if r.Method == http.MethodGet {
+					qs.EligibleResourcesHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "claim":
+				if r.Method == http.MethodPost {
+					qs.ClaimReservationHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "position":
+				if r.Method == http.MethodPut {
+					qs.ReorderNodeHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "expedite":
+				if r.Method == http.MethodPost {
+					qs.ExpediteNodeHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "defer":
+				if r.Method == http.MethodPost {
+					qs.DeferNodeHandler(w, r, nodeID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
 			}
 		}
 
@@ -74,31 +245,201 @@ func setupRoutes(qs *queueservice.QueueService) {
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
+	})))
+
+	http.HandleFunc("/entities/", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// Entity names may contain '/', so only the trailing action is split off.
+		entityName, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/entities/"), "/move")
+		if !ok || entityName == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		qs.MoveEntityNodesHandler(w, r, entityName)
+	})))
+
+	http.HandleFunc("/stats", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.StatsHandler(w, r)
+	})))
+
+	http.HandleFunc("/sla/breaches", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.SLABreachesHandler(w, r)
+	})))
+
+	http.HandleFunc("/resources", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			qs.CreateResourceHandler(w, r)
+		case http.MethodGet:
+			qs.ListResourcesHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Registered separately so it takes precedence over /resources/{id}.
+	http.HandleFunc("/resources/batch", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.CreateResourcesHandler(w, r)
+	})))
+
+	http.HandleFunc("/resources/", corsMiddleware(utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/resources/")
+		parts := strings.Split(path, "/")
+
+		if len(parts) == 0 || parts[0] == "" {
+			qs.ListResourcesHandler(w, r)
+			return
+		}
+
+		resourceID := qs.LookupResourceID(parts[0])
+
+		// Handle GET and PATCH /resources/{id}
+		if len(parts) == 1 {
+			switch r.Method {
+			case http.MethodGet:
+				qs.GetResourceHandler(w, r, resourceID)
+			case http.MethodPatch:
+				qs.PatchResourceHandler(w, r, resourceID)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// Handle sub-routes: /resources/{id}/reserve, /drain, /release, /fill, /pause, /resume, /redirect, /swap, /clone, /oldest, /waiting, /recommendation
+		if len(parts) == 2 {
+			switch parts[1] {
+			case "redirect":
+				if r.Method == http.MethodPost {
+					qs.SetResourceRedirectHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "clone":
+				if r.Method == http.MethodPost {
+					qs.CloneResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "recommendation":
+				if r.Method == http.MethodGet {
+					qs.RecommendationHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "waiting":
+				if r.Method == http.MethodGet {
+					qs.ResourceWaitingHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "oldest":
+				if r.Method == http.MethodGet {
+					qs.OldestWaitingHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "swap":
+				if r.Method == http.MethodPost {
+					qs.SwapWaitingNodesHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "drain":
+				if r.Method == http.MethodPost {
+					qs.DrainResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "release":
+				if r.Method == http.MethodPost {
+					qs.ReleaseResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "fill":
+				if r.Method == http.MethodPost {
+					qs.FillResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "pause":
+				if r.Method == http.MethodPost {
+					qs.PauseResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "resume":
+				if r.Method == http.MethodPost {
+					qs.ResumeResourceHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			case "reserve":
+				if r.Method == http.MethodPost {
+					qs.ReserveCapacityHandler(w, r, resourceID)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+		}
 
-	http.HandleFunc("/resources", corsMiddleware(qs.ListResourcesHandler))
+		http.NotFound(w, r)
+	})))
+
+	// WebSocket upgrade requests are GETs; CORS headers don't apply to the upgraded connection.
+	http.HandleFunc("/ws", qs.WebSocketHandler)
 }
 
-func setupResources(fileName string, queueService *queueservice.QueueService, store db.Store) []*resource.Resource {
-	// Prefer DB resources when available, but fall back to local defaults if DB isn't configured/reachable.
+func setupResources(fileName string, opts resource.LoadOptions, queueService *queueservice.QueueService, store db.Store) ([]*resource.Resource, error) {
+	// Prefer DB resources when available, but fall back to local defaults if DB isn't configured/reachable
+	// (unless RESTORE_STRICT requires the DB's).
 	if store != nil {
-		if dbResources, err := store.ListResources(context.Background()); err == nil && len(dbResources) > 0 {
+		if dbResources, err := queueService.RestoreResourcesFromStore(context.Background()); err == nil && len(dbResources) > 0 {
 			for _, r := range dbResources {
-				queueService.AddResource(r)
 				log.Printf("Initialized resource %s with capacity %d (from DB)", r.ID, r.Capacity)
 			}
-			return dbResources
+			return dbResources, nil
 		} else if err != nil {
+			if queueService.RestoreStrict {
+				return nil, err
+			}
 			log.Printf("[DB] load resources failed, falling back to defaults: %v", err)
 		}
 	}
 
-	resources := resource.LoadResources(fileName)
+	resources, report, err := resource.LoadResourcesWithOptions(fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range report.Skipped {
+		log.Printf("[Config] %s: skipped %s", fileName, p)
+	}
+	if report.UsedDefaults {
+		log.Printf("[Config] %s: no resources loaded (%d rows skipped), using built-in defaults", fileName, len(report.Skipped))
+	} else {
+		log.Printf("[Config] %s: loaded %d resources, skipped %d rows", fileName, report.Loaded, len(report.Skipped))
+	}
 	for _, r := range resources {
 		queueService.AddResource(r)
 		log.Printf("Initialized resource %s with capacity %d", r.ID, r.Capacity)
 	}
-	return resources
+	return resources, nil
 }
 
 // corsMiddleware wraps a handler with permissive CORS headers for browser-based clients.
@@ -107,8 +448,8 @@ func setupResources(fileName string, queueService *queueservice.QueueService, st
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+utils.APIKeyHeader)
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
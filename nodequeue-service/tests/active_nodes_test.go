@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"nodequeue-service/db"
+	nodepkg "nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// assertActiveIndex checks ListActiveNodes against a full scan of ListNodes, and against want.
+func assertActiveIndex(t *testing.T, qs *queueservicepkg.QueueService, want ...string) {
+	t.Helper()
+	scanned := make([]string, 0)
+	for _, n := range qs.ListNodes() {
+		if !n.Completed {
+			scanned = append(scanned, n.ID)
+		}
+	}
+	indexed := make([]string, 0)
+	for _, n := range qs.ListActiveNodes() {
+		if n.Completed {
+			t.Errorf("completed node %s listed as active", n.ID)
+		}
+		indexed = append(indexed, n.ID)
+	}
+	slices.Sort(scanned)
+	slices.Sort(want)
+	sortedIndexed := slices.Sorted(slices.Values(indexed))
+	if !slices.Equal(sortedIndexed, scanned) {
+		t.Errorf("index %v disagrees with scan %v", sortedIndexed, scanned)
+	}
+	if !slices.Equal(sortedIndexed, want) {
+		t.Errorf("expected active %v, got %v", want, sortedIndexed)
+	}
+}
+
+func TestListActiveNodes_TracksTransitions(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.Retry.MaxAttempts = 1
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := qs.CreateNodeOnResource(id, "entity-"+id, 1, "Room 1", nil, nil); err != nil {
+			t.Fatalf("CreateNodeOnResource(%s): %v", id, err)
+		}
+	}
+	assertActiveIndex(t, qs, "a", "b", "c", "d", "e")
+
+	qs.AllocateNode("a")
+	qs.AllocateNode("b")
+	qs.AllocateNode("c")
+	assertActiveIndex(t, qs, "a", "b", "c", "d", "e")
+
+	if err := qs.CompleteNode("a"); err != nil {
+		t.Fatalf("CompleteNode: %v", err)
+	}
+	if err := qs.CompleteNodeWithResult("b", &nodepkg.NodeResult{Outcome: nodepkg.OutcomeCancelled}); err != nil {
+		t.Fatalf("CompleteNodeWithResult: %v", err)
+	}
+	// Out of attempts: a terminal failure completes the node.
+	if err := qs.FailNode("c", "crashed"); err != nil {
+		t.Fatalf("FailNode: %v", err)
+	}
+	assertActiveIndex(t, qs, "d", "e")
+
+	// Completed nodes are still held in memory, just not listed.
+	if n := len(qs.ListNodes()); n != 5 {
+		t.Errorf("expected 5 nodes in memory, got %d", n)
+	}
+	if _, err := qs.Reset(false); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	assertActiveIndex(t, qs)
+}
+
+func TestListActiveNodes_RebuiltOnRestore(t *testing.T) {
+	store := db.NewMemoryStore()
+	before := queueservicepkg.NewQueueServiceWithStore(store)
+	before.AddResource(resourcepkg.NewResource("Room 1", 2))
+	active, _ := before.CreateNodeOnResource("", "active", 1, "Room 1", nil, nil)
+	done, _ := before.CreateNodeOnResource("", "done", 1, "Room 1", nil, nil)
+	before.CompleteNode(done.ID)
+
+	after := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := after.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+	assertActiveIndex(t, after, active.ID)
+
+	// A rejected create leaves nothing behind in the index.
+	strict := queueservicepkg.NewQueueServiceWithStore(failingStore{})
+	strict.PersistMode = queueservicepkg.PersistStrict
+	if _, err := strict.CreateNode("rejected"); err == nil {
+		t.Fatal("expected the strict create to fail")
+	}
+	assertActiveIndex(t, strict)
+}
+
+func TestListActiveNodesHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	first, _ := qs.CreateNodeWithID("first", "entity-1")
+	second, _ := qs.CreateNodeWithID("second", "entity-2")
+	done, _ := qs.CreateNode("entity-3")
+	qs.CompleteNode(done.ID)
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes/active", nil)
+	w := httptest.NewRecorder()
+	qs.ListActiveNodesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var nodes []nodepkg.Node
+	if err := json.NewDecoder(w.Body).Decode(&nodes); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0].ID != first.ID || nodes[1].ID != second.ID {
+		t.Errorf("expected [first second] oldest first, got %+v", nodes)
+	}
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+func TestQueueService_Reset(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	r1 := resourcepkg.NewResource("resource-1", 2)
+	qs.AddResource(r1)
+
+	a, _ := qs.CreateNode("e1")
+	b, _ := qs.CreateNode("e2")
+	qs.MoveNode(a.ID, "resource-1")
+	qs.MoveNode(b.ID, "resource-1")
+	qs.AllocateNode(a.ID)
+	if _, err := qs.ReserveCapacity("resource-1", time.Minute); err != nil {
+		t.Fatalf("ReserveCapacity failed: %v", err)
+	}
+
+	removed, err := qs.Reset(true)
+	if err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 nodes removed, got %d", removed)
+	}
+	if n := len(qs.ListNodes()); n != 0 {
+		t.Errorf("Expected no nodes after reset, got %d", n)
+	}
+	service, waiting := r1.QueueSnapshot()
+	if len(service) != 0 || len(waiting) != 0 || r1.IsFull() {
+		t.Errorf("Expected empty resource after reset, got %d service, %d waiting", len(service), len(waiting))
+	}
+	if _, err := qs.GetResource("resource-1"); err != nil {
+		t.Error("Expected resources to be kept")
+	}
+	if persisted, _ := store.ListAllNodes(context.Background()); len(persisted) != 0 {
+		t.Errorf("Expected store purged, got %d nodes", len(persisted))
+	}
+
+	if _, err := queueservicepkg.NewQueueService().Reset(true); !errors.Is(err, queueservicepkg.ErrStoreUnavailable) {
+		t.Errorf("Expected ErrStoreUnavailable for purge without a store, got %v", err)
+	}
+}
+
+func TestResetHandler_AdminGuard(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.CreateNode("e1")
+
+	call := func(guard utils.AdminGuard, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reset", nil)
+		if key != "" {
+			req.Header.Set(utils.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		guard.Wrap(qs.ResetHandler)(w, req)
+		return w
+	}
+
+	w := call(utils.AdminGuard{APIKey: "secret"}, "secret")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d when admin is disabled, got %d", http.StatusForbidden, w.Code)
+	}
+	assertErrorCode(t, w, utils.CodeAdminDisabled)
+
+	w = call(utils.AdminGuard{Enabled: true, APIKey: "secret"}, "wrong")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a wrong key, got %d", http.StatusUnauthorized, w.Code)
+	}
+	w = call(utils.AdminGuard{Enabled: true}, "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d when no key is configured, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if n := len(qs.ListNodes()); n != 1 {
+		t.Fatalf("Refused resets must not clear state, got %d nodes", n)
+	}
+
+	w = call(utils.AdminGuard{Enabled: true, APIKey: "secret"}, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp queueservicepkg.ResetResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.NodesRemoved != 1 || len(qs.ListNodes()) != 0 {
+		t.Errorf("Expected service empty after reset, got %+v", resp)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestAdmissionFunc_AllowAll(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 2)
+	qs.AddResource(r1)
+
+	var calls []string
+	qs.AdmissionFunc = func(ctx context.Context, n *node.Node, r *resourcepkg.Resource) error {
+		calls = append(calls, n.ID+"@"+r.ID)
+		return nil
+	}
+
+	n, err := qs.CreateNodeOnResource("", "entity-1", 1, r1.ID, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource failed: %v", err)
+	}
+	if err := qs.AllocateNode(n.ID); err != nil {
+		t.Fatalf("Expected allow-all admission to allocate, got %v", err)
+	}
+	if !r1.IsInService(n.ID) {
+		t.Fatal("Expected node to be in service")
+	}
+	if len(calls) != 1 || calls[0] != n.ID+"@"+r1.ID {
+		t.Fatalf("Expected one admission call for %s@%s, got %v", n.ID, r1.ID, calls)
+	}
+}
+
+func TestAdmissionFunc_Deny(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 2)
+	qs.AddResource(r1)
+
+	errQuota := errors.New("quota exhausted")
+	qs.AdmissionFunc = func(ctx context.Context, n *node.Node, r *resourcepkg.Resource) error {
+		return errQuota
+	}
+
+	n, err := qs.CreateNodeOnResource("", "entity-1", 1, r1.ID, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource failed: %v", err)
+	}
+
+	err = qs.AllocateNode(n.ID)
+	if !errors.Is(err, queueservicepkg.ErrAdmissionDenied) || !errors.Is(err, errQuota) {
+		t.Fatalf("Expected ErrAdmissionDenied wrapping the hook error, got %v", err)
+	}
+	if !r1.IsWaiting(n.ID) || r1.GetAvailableCapacity() != 2 {
+		t.Fatal("Expected a denied node to stay waiting without consuming capacity")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/allocate", nil)
+	w := httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, n.ID)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeAdmissionDenied)
+
+	if _, err := qs.CreateNodeWithCapacity("", "entity-2", 1, r1.ID, nil, nil); !errors.Is(err, queueservicepkg.ErrAdmissionDenied) {
+		t.Fatalf("Expected CreateNodeWithCapacity to be denied, got %v", err)
+	}
+	if nodes := qs.ListNodes(); len(nodes) != 1 {
+		t.Fatalf("Expected a denied CreateNodeWithCapacity to create nothing, got %d nodes", len(nodes))
+	}
+}
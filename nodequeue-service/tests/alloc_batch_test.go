@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// allocationScenario builds a resource with per-entity limits and weighted nodes waiting on it.
+func allocationScenario(t testing.TB, batching bool) *queueservicepkg.QueueService {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	qs.AllocationBatching = batching
+	room := resourcepkg.NewResource("Room 1", 4)
+	room.MaxPerEntity = 2
+	room.AutoPromote = true
+	qs.AddResource(room)
+
+	for i, spec := range []struct {
+		entity string
+		weight int
+	}{
+		{"a", 1}, {"a", 1}, {"a", 1}, {"b", 3}, {"c", 2}, {"c", 1}, {"d", 1}, {"d", 1},
+	} {
+		id := fmt.Sprintf("n%d", i+1)
+		if _, err := qs.CreateWeightedNode(id, spec.entity, spec.weight); err != nil {
+			t.Fatalf("CreateWeightedNode(%s): %v", id, err)
+		}
+		if err := qs.MoveNode(id, "Room 1"); err != nil {
+			t.Fatalf("MoveNode(%s): %v", id, err)
+		}
+	}
+	return qs
+}
+
+func queueIDs(t testing.TB, qs *queueservicepkg.QueueService) (service, waiting []string) {
+	t.Helper()
+	room, err := qs.GetResource("Room 1")
+	if err != nil {
+		t.Fatalf("GetResource: %v", err)
+	}
+	s, w := room.QueueSnapshot()
+	return ids(s), ids(w)
+}
+
+func TestAllocationBatching_MatchesSequential(t *testing.T) {
+	run := func(batching bool) (results []string, service, waiting []string) {
+		qs := allocationScenario(t, batching)
+		record := func(err error) {
+			code := "ok"
+			if err != nil {
+				code = err.Error()
+			}
+			results = append(results, code)
+		}
+		for _, id := range []string{"n1", "n2", "n3", "n4", "n5"} {
+			record(qs.AllocateNode(id))
+		}
+		// Completing frees capacity; auto-promotion takes the next eligible node.
+		record(qs.CompleteNode("n1"))
+		record(qs.CompleteNode("n2"))
+		filled, err := qs.FillResource("Room 1")
+		record(err)
+		results = append(results, fmt.Sprint(filled))
+		record(qs.AllocateNode("missing"))
+
+		service, waiting = queueIDs(t, qs)
+		return results, service, waiting
+	}
+
+	wantResults, wantService, wantWaiting := run(false)
+	gotResults, gotService, gotWaiting := run(true)
+	if !slices.Equal(gotResults, wantResults) {
+		t.Errorf("results differ:\nbatched:    %v\nsequential: %v", gotResults, wantResults)
+	}
+	if !slices.Equal(gotService, wantService) || !slices.Equal(gotWaiting, wantWaiting) {
+		t.Errorf("queues differ: batched service=%v waiting=%v, sequential service=%v waiting=%v",
+			gotService, gotWaiting, wantService, wantWaiting)
+	}
+}
+
+func TestAllocationBatching_ConcurrentBurstRespectsCapacity(t *testing.T) {
+	for _, batching := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batching=%v", batching), func(t *testing.T) {
+			qs := queueservicepkg.NewQueueService()
+			qs.AllocationBatching = batching
+			qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+			nodeIDs := make([]string, 50)
+			for i := range nodeIDs {
+				n, _ := qs.CreateNode(fmt.Sprintf("entity-%d", i))
+				qs.MoveNode(n.ID, "Room 1")
+				nodeIDs[i] = n.ID
+			}
+
+			var allocated, full atomic.Int32
+			var wg sync.WaitGroup
+			for _, id := range nodeIDs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					switch err := qs.AllocateNode(id); {
+					case err == nil:
+						allocated.Add(1)
+					case errors.Is(err, queueservicepkg.ErrCapacityFull):
+						full.Add(1)
+					default:
+						t.Errorf("AllocateNode(%s): %v", id, err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allocated.Load() != 5 || full.Load() != 45 {
+				t.Errorf("expected 5 allocated and 45 capacity_full, got %d and %d", allocated.Load(), full.Load())
+			}
+			service, waiting := queueIDs(t, qs)
+			if len(service) != 5 || len(waiting) != 45 {
+				t.Errorf("expected 5 in service and 45 waiting, got %d and %d", len(service), len(waiting))
+			}
+		})
+	}
+}
+
+func TestAllocationBatching_PanicReachesOnlyItsCaller(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AllocationBatching = true
+	qs.AddResource(resourcepkg.NewResource("Room 1", 50))
+	qs.AdmissionFunc = func(ctx context.Context, n *node.Node, r *resourcepkg.Resource) error {
+		if n.Entity.Name == "boom" {
+			panic("admission exploded")
+		}
+		return nil
+	}
+	nodeIDs := make([]string, 20)
+	for i := range nodeIDs {
+		entity := fmt.Sprintf("entity-%d", i)
+		if i%5 == 0 {
+			entity = "boom"
+		}
+		n, _ := qs.CreateNode(entity)
+		qs.MoveNode(n.ID, "Room 1")
+		nodeIDs[i] = n.ID
+	}
+
+	var allocated, panicked atomic.Int32
+	var wg sync.WaitGroup
+	for i, id := range nodeIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					if i%5 != 0 {
+						t.Errorf("AllocateNode(%s) panicked for another caller's node: %v", id, r)
+					}
+					panicked.Add(1)
+				}
+			}()
+			if err := qs.AllocateNode(id); err != nil {
+				t.Errorf("AllocateNode(%s): %v", id, err)
+				return
+			}
+			allocated.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	if allocated.Load() != 16 || panicked.Load() != 4 {
+		t.Errorf("expected 16 allocated and 4 panics, got %d and %d", allocated.Load(), panicked.Load())
+	}
+
+	// Neither qs.mu nor the batcher is left held.
+	qs.AdmissionFunc = nil
+	done := make(chan error, 1)
+	go func() { done <- qs.AllocateNode(nodeIDs[0]) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("AllocateNode after the panics: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AllocateNode blocked after a panicking operation")
+	}
+}
+
+func benchmarkConcurrentAllocate(b *testing.B, batching bool) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AllocationBatching = batching
+	qs.AddResource(resourcepkg.NewResource("Room 1", b.N))
+	nodeIDs := make([]string, b.N)
+	for i := range nodeIDs {
+		n, _ := qs.CreateNode("entity")
+		qs.MoveNode(n.ID, "Room 1")
+		nodeIDs[i] = n.ID
+	}
+
+	var next atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			qs.AllocateNode(nodeIDs[next.Add(1)-1])
+		}
+	})
+}
+
+func BenchmarkAllocateNode_Concurrent(b *testing.B) {
+	benchmarkConcurrentAllocate(b, false)
+}
+
+func BenchmarkAllocateNode_ConcurrentBatched(b *testing.B) {
+	benchmarkConcurrentAllocate(b, true)
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestResource_AllowAllocation(t *testing.T) {
+	r := resourcepkg.NewResource("Room 1", 10)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		if !r.AllowAllocation(now) {
+			t.Fatalf("unlimited resource denied allocation %d", i)
+		}
+	}
+
+	r.AllocRatePerSec = 2
+	// A burst of one second's worth, then nothing until a token refills.
+	if !r.AllowAllocation(now) || !r.AllowAllocation(now) {
+		t.Fatal("expected a burst of 2 allocations")
+	}
+	if r.AllowAllocation(now) {
+		t.Error("expected the third allocation in the same instant to be denied")
+	}
+	if r.AllowAllocation(now.Add(400 * time.Millisecond)) {
+		t.Error("expected no token before 500ms")
+	}
+	if !r.AllowAllocation(now.Add(500 * time.Millisecond)) {
+		t.Error("expected a token after 500ms")
+	}
+
+	if r.CloneConfig("Room 2").AllocRatePerSec != 2 {
+		t.Error("expected CloneConfig to copy AllocRatePerSec")
+	}
+}
+
+func TestAllocateNode_AllocRateThrottlesThenRecovers(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	room := resourcepkg.NewResource("Room 1", 10)
+	room.AllocRatePerSec = 5
+	qs.AddResource(room)
+
+	ids := make([]string, 0, 7)
+	for range 7 {
+		n, err := qs.CreateNodeOnResource("", "entity", 1, "Room 1", nil, nil)
+		if err != nil {
+			t.Fatalf("CreateNodeOnResource: %v", err)
+		}
+		ids = append(ids, n.ID)
+	}
+
+	for i, id := range ids[:5] {
+		if err := qs.AllocateNode(id); err != nil {
+			t.Fatalf("allocation %d in the burst failed: %v", i, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+ids[5]+"/allocate", nil)
+	w := httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, ids[5])
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the burst is used, got %d: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeAllocationRateExceeded)
+	if !room.IsWaiting(ids[5]) {
+		t.Error("expected the throttled node to stay waiting")
+	}
+
+	// Capacity is left, but fill stops at the rate limit too.
+	if allocated, err := qs.FillResource("Room 1"); err != nil || len(allocated) != 0 {
+		t.Errorf("expected fill to allocate nothing while throttled, got %v (err=%v)", allocated, err)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if err := qs.AllocateNode(ids[5]); err != nil {
+		t.Fatalf("expected allocation to recover after a refill, got %v", err)
+	}
+	if err := qs.AllocateNode(ids[6]); !errors.Is(err, queueservicepkg.ErrAllocationRateExceeded) {
+		t.Errorf("expected ErrAllocationRateExceeded after one refilled token, got %v", err)
+	}
+}
+
+func TestCanAllocate_ReportsAllocRateWithoutTakingTokens(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	room := resourcepkg.NewResource("Room 1", 10)
+	room.AllocRatePerSec = 2
+	qs.AddResource(room)
+
+	ids := make([]string, 0, 3)
+	for range 3 {
+		n, err := qs.CreateNodeOnResource("", "entity", 1, "Room 1", nil, nil)
+		if err != nil {
+			t.Fatalf("CreateNodeOnResource: %v", err)
+		}
+		ids = append(ids, n.ID)
+	}
+
+	// Dry runs leave the burst of 2 intact.
+	for range 5 {
+		if err := qs.CanAllocate(ids[0]); err != nil {
+			t.Fatalf("expected CanAllocate to pass with tokens left, got %v", err)
+		}
+	}
+	for _, id := range ids[:2] {
+		if err := qs.AllocateNode(id); err != nil {
+			t.Fatalf("AllocateNode: %v", err)
+		}
+	}
+
+	if err := qs.CanAllocate(ids[2]); !errors.Is(err, queueservicepkg.ErrAllocationRateExceeded) {
+		t.Errorf("expected CanAllocate to report ErrAllocationRateExceeded once drained, got %v", err)
+	}
+	if err := qs.AllocateNode(ids[2]); !errors.Is(err, queueservicepkg.ErrAllocationRateExceeded) {
+		t.Errorf("expected AllocateNode to agree with CanAllocate, got %v", err)
+	}
+}
+
+func TestAllocRate_AppliesToEveryPathIntoService(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	room := resourcepkg.NewResource("Room 1", 10)
+	room.AllocRatePerSec = 1
+	qs.AddResource(room)
+	qs.AddResource(resourcepkg.NewResource("Room 2", 10))
+
+	// require_capacity create uses up the single token.
+	if _, err := qs.CreateNodeWithCapacity("", "first", 1, "Room 1", nil, nil); err != nil {
+		t.Fatalf("CreateNodeWithCapacity: %v", err)
+	}
+	_, err := qs.CreateNodeWithCapacity("", "second", 1, "Room 1", nil, nil)
+	if !errors.Is(err, queueservicepkg.ErrCapacityUnavailable) {
+		t.Fatalf("expected ErrCapacityUnavailable while throttled, got %v", err)
+	}
+	if len(qs.ListNodes()) != 1 {
+		t.Errorf("expected the throttled create to leave nothing behind, got %d nodes", len(qs.ListNodes()))
+	}
+
+	// Transfer into the throttled resource.
+	n, _ := qs.CreateNodeOnResource("", "mover", 1, "Room 2", nil, nil)
+	if err := qs.TransferAndAllocate(n.ID, "Room 1"); !errors.Is(err, queueservicepkg.ErrAllocationRateExceeded) {
+		t.Errorf("expected transfer to be throttled, got %v", err)
+	}
+	if got, _ := qs.GetNode(n.ID); got.ResourceID != "Room 2" {
+		t.Errorf("expected the node to stay on Room 2, got %q", got.ResourceID)
+	}
+
+	// Claiming a reservation.
+	reservationID, err := qs.ReserveCapacity("Room 1", time.Minute)
+	if err != nil {
+		t.Fatalf("ReserveCapacity: %v", err)
+	}
+	waiting, _ := qs.CreateNodeOnResource("", "claimer", 1, "Room 1", nil, nil)
+	if err := qs.ClaimReservation(reservationID, waiting.ID); !errors.Is(err, queueservicepkg.ErrAllocationRateExceeded) {
+		t.Errorf("expected claim to be throttled, got %v", err)
+	}
+	if !room.IsWaiting(waiting.ID) || !room.HasReservation(reservationID) {
+		t.Error("expected the node to stay waiting and the reservation to be kept")
+	}
+}
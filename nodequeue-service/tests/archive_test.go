@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+)
+
+// archivingStore records archived node IDs and serves a canned archive page.
+type archivingStore struct {
+	stubStore
+	mu       sync.Mutex
+	archived []string
+	failFor  string
+	lastQ    db.ArchiveQuery
+	page     []db.ArchivedNode
+}
+
+func (s *archivingStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if nodeID == s.failFor {
+		return errors.New("db down")
+	}
+	s.archived = append(s.archived, nodeID)
+	return nil
+}
+
+func (s *archivingStore) ListArchivedNodes(ctx context.Context, q db.ArchiveQuery) ([]db.ArchivedNode, error) {
+	s.lastQ = q
+	return s.page, nil
+}
+
+func TestPurgeCompletedNodes_ArchivesThenDropsFromMemory(t *testing.T) {
+	store := &archivingStore{}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	done, _ := qs.CreateNode("done")
+	failing, _ := qs.CreateNode("failing")
+	active, _ := qs.CreateNode("active")
+	if err := qs.CompleteNode(done.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+	if err := qs.CompleteNode(failing.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+	store.failFor = failing.ID
+
+	n, err := qs.PurgeCompletedNodes(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("PurgeCompletedNodes failed: %v", err)
+	}
+	if n != 1 || len(store.archived) != 1 || store.archived[0] != done.ID {
+		t.Fatalf("expected only %s archived, got n=%d archived=%v", done.ID, n, store.archived)
+	}
+
+	if _, err := qs.GetNode(done.ID); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("expected archived node to be dropped from memory, got err=%v", err)
+	}
+	// A failed archive write must keep the node in memory for the next attempt.
+	if _, err := qs.GetNode(failing.ID); err != nil {
+		t.Errorf("expected node with failed archive to stay in memory: %v", err)
+	}
+	if _, err := qs.GetNode(active.ID); err != nil {
+		t.Errorf("expected active node to stay in memory: %v", err)
+	}
+}
+
+func TestPurgeCompletedNodes_RespectsAgeAndRequiresStore(t *testing.T) {
+	store := &archivingStore{}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	n1, _ := qs.CreateNode("recent")
+	_ = qs.CompleteNode(n1.ID)
+
+	if n, err := qs.PurgeCompletedNodes(context.Background(), time.Hour); err != nil || n != 0 {
+		t.Fatalf("expected recently completed node to be kept, got n=%d err=%v", n, err)
+	}
+
+	memOnly := queueservicepkg.NewQueueService()
+	if _, err := memOnly.PurgeCompletedNodes(context.Background(), 0); !errors.Is(err, queueservicepkg.ErrArchiveUnavailable) {
+		t.Fatalf("expected ErrArchiveUnavailable without a store, got %v", err)
+	}
+}
+
+func TestArchivedNodesHandler(t *testing.T) {
+	completedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &archivingStore{page: []db.ArchivedNode{
+		{NodeID: "n1", EntityName: "e1", CompletedAt: &completedAt, LastResourceID: ptr("Room 1"), LogCount: 4},
+		{NodeID: "n2", EntityName: "e2", LogCount: 1},
+	}}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes/archive?since=2025-01-01T00:00:00Z&until=2025-01-02T00:00:00Z&limit=2&offset=4", nil)
+	w := httptest.NewRecorder()
+	qs.ArchivedNodesHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !store.lastQ.Since.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || store.lastQ.Limit != 2 || store.lastQ.Offset != 4 {
+		t.Errorf("unexpected query passed to store: %+v", store.lastQ)
+	}
+
+	var resp queueservicepkg.ArchiveResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Nodes) != 2 || resp.Nodes[0].ID != "n1" || *resp.Nodes[0].LastResourceID != "Room 1" {
+		t.Errorf("unexpected nodes: %+v", resp.Nodes)
+	}
+	if resp.NextOffset == nil || *resp.NextOffset != 6 {
+		t.Errorf("expected next_offset 6 for a full page, got %v", resp.NextOffset)
+	}
+}
+
+func TestArchivedNodesHandler_Errors(t *testing.T) {
+	qs := queueservicepkg.NewQueueServiceWithStore(&archivingStore{})
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes/archive?since=yesterday", nil)
+	w := httptest.NewRecorder()
+	qs.ArchivedNodesHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for bad since, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+
+	memOnly := queueservicepkg.NewQueueService()
+	req = httptest.NewRequest(http.MethodGet, "/nodes/archive", nil)
+	w = httptest.NewRecorder()
+	memOnly.ArchivedNodesHandler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d without a store, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeArchiveUnavailable)
+}
+
+func TestMaxNodesInMemory_EvictsOldestCompletedNodes(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.MaxNodesInMemory = 3
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := qs.CreateNodeWithID(id, "entity-"+id); err != nil {
+			t.Fatalf("CreateNodeWithID(%s) failed: %v", id, err)
+		}
+	}
+	_ = qs.CompleteNode("a")
+	_ = qs.CompleteNode("b")
+
+	// Over the cap by one: only the oldest completed node goes.
+	if _, err := qs.CreateNodeWithID("d", "entity-d"); err != nil {
+		t.Fatalf("CreateNodeWithID(d) failed: %v", err)
+	}
+	if _, err := qs.GetNode("a"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("expected a to be evicted, got err=%v", err)
+	}
+	if _, err := qs.GetNode("b"); err != nil {
+		t.Errorf("expected b to stay while within the cap: %v", err)
+	}
+
+	if _, err := qs.CreateNodeWithID("e", "entity-e"); err != nil {
+		t.Fatalf("CreateNodeWithID(e) failed: %v", err)
+	}
+	if _, err := qs.GetNode("b"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("expected b to be evicted, got err=%v", err)
+	}
+
+	// Only active nodes are left; they are never evicted, even over the cap.
+	if _, err := qs.CreateNodeWithID("f", "entity-f"); err != nil {
+		t.Fatalf("CreateNodeWithID(f) failed: %v", err)
+	}
+	for _, id := range []string{"c", "d", "e", "f"} {
+		if _, err := qs.GetNode(id); err != nil {
+			t.Errorf("expected active node %s to survive: %v", id, err)
+		}
+	}
+}
+
+func TestMaxNodesInMemory_ArchivesBeforeEvicting(t *testing.T) {
+	store := &archivingStore{failFor: "b"}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.MaxNodesInMemory = 1
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := qs.CreateNodeWithID(id, "entity-"+id); err != nil {
+			t.Fatalf("CreateNodeWithID(%s) failed: %v", id, err)
+		}
+		_ = qs.CompleteNode(id)
+	}
+	if _, err := qs.CreateNodeWithID("c", "entity-c"); err != nil {
+		t.Fatalf("CreateNodeWithID(c) failed: %v", err)
+	}
+
+	if len(store.archived) != 1 || store.archived[0] != "a" {
+		t.Fatalf("expected only a archived, got %v", store.archived)
+	}
+	if _, err := qs.GetNode("a"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("expected archived node a to be evicted, got err=%v", err)
+	}
+	// A failed archive write keeps the node in memory.
+	if _, err := qs.GetNode("b"); err != nil {
+		t.Errorf("expected b to stay in memory after its archive failed: %v", err)
+	}
+}
+
+func TestCreateNodeWithID_RejectsIDOfArchivedNode(t *testing.T) {
+	qs := queueservicepkg.NewQueueServiceWithStore(db.NewMemoryStore())
+	id := "5f0c6a9e-8a57-4d1b-9a36-2f0d6f1c9b11"
+
+	if _, err := qs.CreateNodeWithID(id, "job"); err != nil {
+		t.Fatalf("CreateNodeWithID failed: %v", err)
+	}
+	if err := qs.CompleteNode(id); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+	if n, err := qs.PurgeCompletedNodes(context.Background(), 0); err != nil || n != 1 {
+		t.Fatalf("PurgeCompletedNodes: n=%d err=%v", n, err)
+	}
+
+	// The node is gone from memory, but its ID is still taken in the store.
+	if _, err := qs.CreateNodeWithID(id, "job"); !errors.Is(err, queueservicepkg.ErrNodeExists) {
+		t.Fatalf("expected ErrNodeExists, got %v", err)
+	}
+	if _, err := qs.GetNode(id); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("expected the rejected node not to be kept in memory, got err=%v", err)
+	}
+	if _, err := qs.CreateNodeOnResource(id, "job", 1, "missing", nil, nil); !errors.Is(err, queueservicepkg.ErrNodeExists) {
+		t.Errorf("expected ErrNodeExists creating onto a resource, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nodepkg "nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// consistencyScenario returns a service with two resources and nodes in every queue state.
+func consistencyScenario(t *testing.T) (*queueservicepkg.QueueService, *resourcepkg.Resource, *resourcepkg.Resource) {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 2))
+	for _, spec := range []struct{ id, rid string }{{"a", "Room 1"}, {"b", "Room 1"}, {"c", "Room 1"}, {"d", "Room 2"}} {
+		if _, err := qs.CreateNodeOnResource(spec.id, "entity-"+spec.id, 1, spec.rid, nil, nil); err != nil {
+			t.Fatalf("CreateNodeOnResource(%s): %v", spec.id, err)
+		}
+	}
+	qs.AllocateNode("a")
+	qs.CreateNode("unassigned")
+
+	room1, _ := qs.GetResource("Room 1")
+	room2, _ := qs.GetResource("Room 2")
+	if v := qs.CheckConsistency(); len(v) != 0 {
+		t.Fatalf("expected a consistent starting state, got %+v", v)
+	}
+	return qs, room1, room2
+}
+
+func assertViolation(t *testing.T, violations []queueservicepkg.Violation, kind, nodeID, rid string) {
+	t.Helper()
+	for _, v := range violations {
+		if v.Kind == kind && v.NodeID == nodeID && v.ResourceID == rid {
+			return
+		}
+	}
+	t.Errorf("expected %s violation for node %q on %q, got %+v", kind, nodeID, rid, violations)
+}
+
+func TestCheckConsistency_DetectsCorruption(t *testing.T) {
+	t.Run("not queued", func(t *testing.T) {
+		qs, room1, _ := consistencyScenario(t)
+		room1.RemoveNode("b")
+		v := qs.CheckConsistency()
+		if len(v) != 1 {
+			t.Errorf("expected exactly one violation, got %+v", v)
+		}
+		assertViolation(t, v, queueservicepkg.ViolationNotQueued, "b", "Room 1")
+	})
+
+	t.Run("queued on two resources", func(t *testing.T) {
+		qs, room1, room2 := consistencyScenario(t)
+		// AddNode reassigns the node, so it is now the Room 1 entry that is stale.
+		room2.AddNode(room1.GetNode("b"))
+		v := qs.CheckConsistency()
+		assertViolation(t, v, queueservicepkg.ViolationMultipleResources, "b", "")
+		assertViolation(t, v, queueservicepkg.ViolationWrongResource, "b", "Room 1")
+	})
+
+	t.Run("duplicate in one resource", func(t *testing.T) {
+		qs, room1, _ := consistencyScenario(t)
+		service, waiting := room1.QueueSnapshot()
+		room1.ReplaceQueues(service, append(waiting, service[0]))
+		assertViolation(t, qs.CheckConsistency(), queueservicepkg.ViolationDuplicate, "a", "Room 1")
+	})
+
+	t.Run("over capacity", func(t *testing.T) {
+		qs, room1, _ := consistencyScenario(t)
+		service, waiting := room1.QueueSnapshot()
+		room1.ReplaceQueues(append(service, waiting...), nil)
+		room1.Capacity = 1
+		assertViolation(t, qs.CheckConsistency(), queueservicepkg.ViolationOverCapacity, "", "Room 1")
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		qs, room1, _ := consistencyScenario(t)
+		room1.AddNode(&nodepkg.Node{ID: "ghost", ResourceID: "Room 1"})
+		assertViolation(t, qs.CheckConsistency(), queueservicepkg.ViolationUnknownNode, "ghost", "Room 1")
+	})
+
+	t.Run("missing resource", func(t *testing.T) {
+		qs, room1, _ := consistencyScenario(t)
+		n := room1.GetNode("c")
+		room1.RemoveNode("c")
+		n.ResourceID = "Room 9"
+		v := qs.CheckConsistency()
+		if len(v) != 1 {
+			t.Errorf("expected exactly one violation, got %+v", v)
+		}
+		assertViolation(t, v, queueservicepkg.ViolationMissingResource, "c", "Room 9")
+	})
+}
+
+func TestConsistencyHandler(t *testing.T) {
+	qs, room1, _ := consistencyScenario(t)
+
+	get := func() queueservicepkg.ConsistencyResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/consistency", nil)
+		w := httptest.NewRecorder()
+		qs.ConsistencyHandler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var resp queueservicepkg.ConsistencyResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := get(); !resp.Consistent || len(resp.Violations) != 0 {
+		t.Errorf("expected a consistent report, got %+v", resp)
+	}
+	room1.RemoveNode("b")
+	if resp := get(); resp.Consistent || len(resp.Violations) != 1 {
+		t.Errorf("expected one violation, got %+v", resp)
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+)
+
+// cancellingStore cancels the request context from inside the first store write and records
+// what ctx.Err() each later write observes.
+type cancellingStore struct {
+	stubStore
+	cancel  context.CancelFunc
+	logErrs []error
+}
+
+func (s *cancellingStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	s.cancel()
+	return nil
+}
+
+func (s *cancellingStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	s.logErrs = append(s.logErrs, ctx.Err())
+	return ctx.Err()
+}
+
+func TestCreateNodeContext_StoreSeesCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &cancellingStore{cancel: cancel}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	n, err := qs.CreateNodeContext(ctx, "entity-1")
+	if err != nil {
+		t.Fatalf("CreateNodeContext failed: %v", err)
+	}
+	if n == nil {
+		t.Fatal("expected node to be created in memory despite store cancellation")
+	}
+	if len(store.logErrs) != 1 || !errors.Is(store.logErrs[0], context.Canceled) {
+		t.Fatalf("expected InsertNodeLog to observe context.Canceled, got %v", store.logErrs)
+	}
+}
+
+func TestCreateNodeHandler_PassesRequestContextToStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &cancellingStore{cancel: cancel}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes", strings.NewReader(`{"entity_name": "entity-1"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	qs.CreateNodeHandler(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if len(store.logErrs) != 1 || !errors.Is(store.logErrs[0], context.Canceled) {
+		t.Fatalf("expected store to observe the cancelled request context, got %v", store.logErrs)
+	}
+}
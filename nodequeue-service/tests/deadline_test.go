@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	"nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestDeadlineMonitor_CancelsExactlyAtDeadline(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	res := resourcepkg.NewResource("resource-1", 1)
+	res.AutoPromote = true
+	qs.AddResource(res)
+
+	unassigned, _ := qs.CreateNode("unassigned")
+	inService, _ := qs.CreateNodeOnResource("", "in-service", 1, "resource-1", nil, nil)
+	if err := qs.AllocateNode(inService.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+	waiting, _ := qs.CreateNodeOnResource("", "waiting", 1, "resource-1", nil, nil)
+	spare, _ := qs.CreateNodeOnResource("", "spare", 1, "resource-1", nil, nil)
+
+	deadline := time.Now().Add(time.Hour)
+	for _, id := range []string{unassigned.ID, inService.ID, waiting.ID} {
+		if err := qs.SetNodeDeadline(id, &deadline); err != nil {
+			t.Fatalf("SetNodeDeadline(%s): %v", id, err)
+		}
+	}
+
+	monitor := queueservicepkg.NewDeadlineMonitor(qs)
+	clock := &fakeClock{now: deadline.Add(-time.Nanosecond)}
+	monitor.Now = clock.Now
+
+	if got := monitor.Check(context.Background()); len(got) != 0 {
+		t.Fatalf("expected nothing cancelled before the deadline, got %v", got)
+	}
+
+	clock.Advance(time.Nanosecond)
+	got := monitor.Check(context.Background())
+	want := []string{unassigned.ID, inService.ID, waiting.ID}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v cancelled at the deadline, got %v", want, got)
+	}
+
+	for _, id := range want {
+		n, _ := qs.GetNode(id)
+		if !n.Completed || n.Result == nil || n.Result.Outcome != node.OutcomeCancelled || !n.DeadlineExceeded {
+			t.Errorf("%s: expected a cancelled node marked deadline_exceeded, got completed=%v result=%+v", n.Entity.Name, n.Completed, n.Result)
+		}
+		if len(n.Log) < 2 || n.Log[len(n.Log)-2].Action != "deadline_exceeded" {
+			t.Errorf("%s: expected deadline_exceeded before completed, got %+v", n.Entity.Name, n.Log)
+		}
+		if res.IsInService(id) || res.IsWaiting(id) {
+			t.Errorf("%s: expected the node removed from the resource", n.Entity.Name)
+		}
+	}
+
+	// The freed slot goes to the waiting node without a deadline.
+	if !res.IsInService(spare.ID) {
+		t.Error("expected the spare node auto-promoted into the freed slot")
+	}
+	if n, _ := qs.GetNode(spare.ID); n.Completed || n.DeadlineExceeded {
+		t.Error("expected the node without a deadline to stay active")
+	}
+
+	metrics, err := qs.GetNodeMetrics(inService.ID)
+	if err != nil {
+		t.Fatalf("GetNodeMetrics: %v", err)
+	}
+	if !metrics.DeadlineExceeded {
+		t.Errorf("expected metrics to mark the node deadline_exceeded, got %+v", metrics)
+	}
+	if metrics, _ := qs.GetNodeMetrics(spare.ID); metrics.DeadlineExceeded {
+		t.Error("expected metrics not to mark the spare node")
+	}
+	if st := qs.GlobalStats(); st.DeadlineExceeded != 3 {
+		t.Errorf("expected 3 deadline_exceeded in stats, got %d", st.DeadlineExceeded)
+	}
+
+	logs, err := store.ListNodeLogs(context.Background(), []string{inService.ID})
+	if err != nil {
+		t.Fatalf("ListNodeLogs: %v", err)
+	}
+	if !slices.ContainsFunc(logs[inService.ID], func(l db.NodeLogRow) bool { return l.Action == "deadline_exceeded" }) {
+		t.Errorf("expected a persisted deadline_exceeded log, got %+v", logs)
+	}
+
+	// Already cancelled: a second check is a no-op.
+	if got := monitor.Check(context.Background()); len(got) != 0 {
+		t.Errorf("expected nothing cancelled twice, got %v", got)
+	}
+}
+
+func TestCreateNodeHandler_Deadline(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		qs.CreateNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(body)))
+		return w
+	}
+
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for _, extra := range []string{``, `, "resource_id": "resource-1"`} {
+		body := fmt.Sprintf(`{"entity_name": "job", "deadline_ts": %q%s}`, deadline.Format(time.RFC3339), extra)
+		w := create(body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d: %s", body, w.Code, w.Body.String())
+		}
+		var created node.Node
+		if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if created.DeadlineTS == nil || !created.DeadlineTS.Equal(deadline) {
+			t.Errorf("%s: expected deadline_ts %v, got %v", body, deadline, created.DeadlineTS)
+		}
+	}
+
+	for _, ts := range []time.Time{time.Now().Add(-time.Minute), {}} {
+		before := len(qs.ListNodes())
+		w := create(fmt.Sprintf(`{"entity_name": "job", "deadline_ts": %q}`, ts.Format(time.RFC3339Nano)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d: %s", ts, w.Code, w.Body.String())
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+		if after := len(qs.ListNodes()); after != before {
+			t.Errorf("%v: expected no node to be created", ts)
+		}
+	}
+}
+
+func TestNodeDeadline_PersistedAndRestored(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"entity_name": "job", "resource_id": "resource-1", "deadline_ts": %q}`, deadline.Format(time.RFC3339))
+	w := httptest.NewRecorder()
+	qs.CreateNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes?require_capacity=true", bytes.NewBufferString(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created node.Node
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	cleared, _ := qs.CreateNode("no-deadline")
+	if err := qs.SetNodeDeadline(cleared.ID, &deadline); err != nil {
+		t.Fatalf("SetNodeDeadline: %v", err)
+	}
+	if err := qs.SetNodeDeadline(cleared.ID, nil); err != nil {
+		t.Fatalf("SetNodeDeadline(nil): %v", err)
+	}
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	restarted.AddResource(resourcepkg.NewResource("resource-1", 1))
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+	n, err := restarted.GetNode(created.ID)
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if n.DeadlineTS == nil || !n.DeadlineTS.Equal(deadline) {
+		t.Errorf("expected the restored deadline %v, got %v", deadline, n.DeadlineTS)
+	}
+	if n, _ := restarted.GetNode(cleared.ID); n == nil || n.DeadlineTS != nil {
+		t.Errorf("expected the cleared deadline to stay cleared, got %+v", n)
+	}
+}
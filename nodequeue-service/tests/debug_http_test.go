@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+// newTestDebugLogger returns a DebugLogger whose entries are collected in the returned slice and
+// whose clock advances by step on every reading.
+func newTestDebugLogger(step time.Duration) (*utils.DebugLogger, *[]string) {
+	entries := make([]string, 0)
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	d := utils.NewDebugLogger()
+	d.Logf = func(format string, args ...any) { entries = append(entries, fmt.Sprintf(format, args...)) }
+	d.Now = func() time.Time {
+		now := clock.Now()
+		clock.Advance(step)
+		return now
+	}
+	return d, &entries
+}
+
+func TestDebugLogger_CapturesStatusAndDurationWithoutAlteringResponse(t *testing.T) {
+	var gotBody string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Echo", "yes")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "n1", "entity": "` + strings.Repeat("x", 40) + `"}`))
+	})
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/nodes?dry=1", strings.NewReader(`{"entity_name": "acme"}`))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		req.Header.Set(utils.APIKeyHeader, "secret-key")
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	plain := httptest.NewRecorder()
+	handler.ServeHTTP(plain, newRequest())
+
+	d, entries := newTestDebugLogger(150 * time.Millisecond)
+	d.BodyLimit = 16
+	logged := httptest.NewRecorder()
+	d.Handler(handler).ServeHTTP(logged, newRequest())
+
+	if logged.Code != plain.Code || logged.Body.String() != plain.Body.String() {
+		t.Errorf("expected the response unchanged, got %d %q, want %d %q", logged.Code, logged.Body.String(), plain.Code, plain.Body.String())
+	}
+	for name := range plain.Header() {
+		if logged.Header().Get(name) != plain.Header().Get(name) {
+			t.Errorf("expected header %s unchanged, got %q, want %q", name, logged.Header().Get(name), plain.Header().Get(name))
+		}
+	}
+	if gotBody != `{"entity_name": "acme"}` {
+		t.Errorf("expected the handler to read the full request body, got %q", gotBody)
+	}
+
+	if len(*entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d: %v", len(*entries), *entries)
+	}
+	entry := (*entries)[0]
+	for _, want := range []string{
+		"POST /nodes?dry=1 - 201 (took 150ms)",
+		"Authorization: [REDACTED]",
+		"X-Api-Key: [REDACTED]",
+		"Content-Type: application/json",
+		`request body: "{\"entity_name\": "... (truncated, 23 bytes)`,
+		"X-Request-Echo: yes",
+		`response body: "{\"id\": \"n1\", \"en"... (truncated,`,
+	} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("expected the log entry to contain %q, got:\n%s", want, entry)
+		}
+	}
+	for _, secret := range []string{"secret-token", "secret-key"} {
+		if strings.Contains(entry, secret) {
+			t.Errorf("expected %q to be redacted, got:\n%s", secret, entry)
+		}
+	}
+}
+
+func TestDebugLogger_ImplicitStatusAndStreaming(t *testing.T) {
+	d, entries := newTestDebugLogger(time.Second)
+
+	// A handler that never calls WriteHeader is logged as 200.
+	w := httptest.NewRecorder()
+	d.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if !strings.Contains((*entries)[0], "GET /healthz - 200 (took 1s)") || !strings.Contains((*entries)[0], `response body: "ok"`) {
+		t.Errorf("unexpected log entry:\n%s", (*entries)[0])
+	}
+
+	// SSE requests get the original writer, so they can flush as usual.
+	var unwrapped bool
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	d.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, unwrapped = w.(*httptest.ResponseRecorder)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	if !unwrapped {
+		t.Error("expected an SSE request to get the original writer")
+	}
+	if len(*entries) != 2 || !strings.Contains((*entries)[1], "GET /events - streaming, bodies not captured (took 1s)") {
+		t.Errorf("unexpected log entries: %v", *entries)
+	}
+}
+
+func TestDebugLoggerFromEnv(t *testing.T) {
+	t.Setenv("DEBUG_HTTP", "")
+	if d, err := utils.DebugLoggerFromEnv(); d != nil || err != nil {
+		t.Errorf("expected no logger when unset, got %v, %v", d, err)
+	}
+
+	t.Setenv("DEBUG_HTTP", "true")
+	t.Setenv("DEBUG_HTTP_BODY_LIMIT", "64")
+	t.Setenv("DEBUG_HTTP_REDACT_HEADERS", "X-Session-Token, X-Other")
+	d, err := utils.DebugLoggerFromEnv()
+	if err != nil || d == nil {
+		t.Fatalf("expected a logger, got %v, %v", d, err)
+	}
+	if d.BodyLimit != 64 || len(d.RedactHeaders) != len(utils.DefaultRedactedHeaders)+2 {
+		t.Errorf("unexpected config: limit %d, redact %v", d.BodyLimit, d.RedactHeaders)
+	}
+
+	t.Setenv("DEBUG_HTTP_BODY_LIMIT", "lots")
+	if _, err := utils.DebugLoggerFromEnv(); err == nil {
+		t.Error("expected an invalid DEBUG_HTTP_BODY_LIMIT to fail")
+	}
+}
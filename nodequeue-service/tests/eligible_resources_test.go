@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func getEligibleResources(qs *queueservicepkg.QueueService, nodeID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	qs.EligibleResourcesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/"+nodeID+"/eligible-resources", nil), nodeID)
+	return w
+}
+
+func TestEligibleResourcesHandler_MixedResources(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for _, spec := range []struct {
+		id       string
+		capacity int
+	}{{"A", 4}, {"B", 3}, {"C", 2}, {"D", 2}, {"E", 1}, {"F", 4}, {"G", 4}} {
+		qs.AddResource(resourcepkg.NewResource(spec.id, spec.capacity))
+	}
+	if err := qs.PauseResource("C"); err != nil {
+		t.Fatalf("PauseResource: %v", err)
+	}
+	busy, _ := qs.CreateNodeOnResource("", "other", 1, "D", nil, nil)
+	if err := qs.AllocateNode(busy.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+	g, _ := qs.GetResource("G")
+	g.MaxPerEntity = 1
+	sibling, _ := qs.CreateNodeOnResource("", "job", 1, "G", nil, nil)
+	if err := qs.AllocateNode(sibling.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+
+	// Weight 2 on A; F is not allowed and E is too small to ever serve it.
+	n, err := qs.CreateNodeOnResource("", "job", 2, "A", nil, []string{"A", "B", "C", "D", "E", "G"})
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource: %v", err)
+	}
+
+	w := getEligibleResources(qs, n.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.EligibleResourcesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.NodeID != n.ID {
+		t.Errorf("expected node_id %s, got %s", n.ID, resp.NodeID)
+	}
+
+	want := []queueservicepkg.EligibleResource{
+		{ResourceID: "B", AvailableCapacity: 3, Allocatable: true},
+		{ResourceID: "G", AvailableCapacity: 3, Code: queueservicepkg.CodeEntityLimit},
+		{ResourceID: "C", AvailableCapacity: 2, Code: queueservicepkg.CodeResourcePaused},
+		{ResourceID: "D", AvailableCapacity: 1, Code: queueservicepkg.CodeCapacityFull},
+	}
+	if len(resp.Resources) != len(want) {
+		t.Fatalf("expected %d resources, got %+v", len(want), resp.Resources)
+	}
+	for i, got := range resp.Resources {
+		w := want[i]
+		if got.ResourceID != w.ResourceID || got.AvailableCapacity != w.AvailableCapacity ||
+			got.Allocatable != w.Allocatable || got.Code != w.Code {
+			t.Errorf("resources[%d]: expected %+v, got %+v", i, w, got)
+		}
+		if !got.Allocatable && got.Reason == "" {
+			t.Errorf("resources[%d]: expected a reason for a non-allocatable resource", i)
+		}
+	}
+
+	// The listing agrees with transfer.
+	if err := qs.TransferAndAllocate(n.ID, "D"); err == nil {
+		t.Error("expected a transfer to a non-allocatable resource to fail")
+	}
+	if err := qs.TransferAndAllocate(n.ID, "B"); err != nil {
+		t.Errorf("expected a transfer to an allocatable resource to succeed, got %v", err)
+	}
+}
+
+func TestEligibleResourcesHandler_Errors(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("A", 1))
+
+	w := getEligibleResources(qs, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
+
+	n, _ := qs.CreateNode("job")
+	if err := qs.CompleteNode(n.ID); err != nil {
+		t.Fatalf("CompleteNode: %v", err)
+	}
+	w = getEligibleResources(qs, n.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeCompleted)
+
+	qs.MaxMoves = 1
+	moved, _ := qs.CreateNode("moved")
+	if err := qs.MoveNode(moved.ID, "A"); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	w = getEligibleResources(qs, moved.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeMoveLimit)
+}
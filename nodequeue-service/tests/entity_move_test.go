@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestMoveEntityNodes_MovesActiveNodesAcrossResources(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	for _, id := range []string{"resource-1", "resource-2", "target"} {
+		qs.AddResource(resourcepkg.NewResource(id, 2))
+	}
+
+	waiting, _ := qs.CreateNodeOnResource("", "acme", 1, "resource-1", nil, nil)
+	serving, _ := qs.CreateNodeOnResource("", "acme", 1, "resource-2", nil, nil)
+	unassigned, _ := qs.CreateNode("acme")
+	done, _ := qs.CreateNodeOnResource("", "acme", 1, "resource-1", nil, nil)
+	onTarget, _ := qs.CreateNodeOnResource("", "acme", 1, "target", nil, nil)
+	other, _ := qs.CreateNodeOnResource("", "globex", 1, "resource-1", nil, nil)
+	if err := qs.AllocateNode(serving.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	if err := qs.CompleteNode(done.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	qs.MoveEntityNodesHandler(w, httptest.NewRequest(http.MethodPost, "/entities/acme/move?to=target", nil), "acme")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.EntityMoveResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Moved != 3 {
+		t.Fatalf("Expected 3 nodes moved, got %d", resp.Moved)
+	}
+
+	target, _ := qs.GetResource("target")
+	if got := ids(target.WaitingQueue); len(got) != 4 || got[0] != onTarget.ID || got[1] != waiting.ID || got[2] != serving.ID || got[3] != unassigned.ID {
+		t.Fatalf("Expected target waiting queue [%s %s %s %s], got %v", onTarget.ID, waiting.ID, serving.ID, unassigned.ID, got)
+	}
+	if n, _ := qs.GetNode(done.ID); n.ResourceID == "target" {
+		t.Fatal("Expected the completed node not to be moved")
+	}
+	if n, _ := qs.GetNode(other.ID); n.ResourceID != "resource-1" {
+		t.Fatalf("Expected another entity's node to stay on resource-1, got %q", n.ResourceID)
+	}
+	if res, _ := qs.GetResource("resource-2"); len(res.Nodes) != 0 {
+		t.Fatalf("Expected the serving node to leave resource-2's service queue, got %v", ids(res.Nodes))
+	}
+
+	persisted, _ := store.ListNodes(context.Background())
+	for _, pn := range persisted {
+		if pn.NodeID == waiting.ID || pn.NodeID == serving.ID || pn.NodeID == unassigned.ID {
+			if pn.ResourceID == nil || *pn.ResourceID != "target" {
+				t.Fatalf("Expected move of %s to be persisted, got %v", pn.NodeID, pn.ResourceID)
+			}
+		}
+	}
+}
+
+func TestMoveEntityNodes_AllOrNothing(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("target", 1))
+
+	free, _ := qs.CreateNodeOnResource("", "acme", 1, "resource-1", nil, nil)
+	if _, err := qs.CreateNodeOnResource("", "acme", 1, "resource-1", nil, []string{"resource-1"}); err != nil {
+		t.Fatalf("CreateNodeOnResource failed: %v", err)
+	}
+
+	if _, err := qs.MoveEntityNodes("acme", "target"); !errors.Is(err, queueservicepkg.ErrResourceNotAllowed) {
+		t.Fatalf("Expected ErrResourceNotAllowed, got %v", err)
+	}
+	if n, _ := qs.GetNode(free.ID); n.ResourceID != "resource-1" {
+		t.Fatalf("Expected no node to move, but %s is on %q", free.ID, n.ResourceID)
+	}
+
+	if _, err := qs.MoveEntityNodes("acme", "missing"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Fatalf("Expected ErrResourceNotFound, got %v", err)
+	}
+	if moved, err := qs.MoveEntityNodes("nobody", "target"); err != nil || moved != 0 {
+		t.Fatalf("Expected 0 moves for an unknown entity, got %d, %v", moved, err)
+	}
+
+	w := httptest.NewRecorder()
+	qs.MoveEntityNodesHandler(w, httptest.NewRequest(http.MethodPost, "/entities/acme/move", nil), "acme")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d without to, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	"nodequeue-service/utils"
+)
+
+func TestGzipMiddleware_CompressesLargeNodeList(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for i := 0; i < 100; i++ {
+		qs.CreateNode(fmt.Sprintf("entity-%d", i))
+	}
+
+	handler := utils.GzipMiddleware(qs.ListNodesHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding gzip, got %q", got)
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip body: %v", err)
+	}
+	var nodes []node.Node
+	if err := json.NewDecoder(gz).Decode(&nodes); err != nil {
+		t.Fatalf("failed to decode gzipped body: %v", err)
+	}
+	if len(nodes) != 100 {
+		t.Fatalf("expected 100 nodes, got %d", len(nodes))
+	}
+}
+
+func TestGzipMiddleware_SkipsSmallResponsesAndNonGzipClients(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.CreateNode("entity-1")
+	handler := utils.GzipMiddleware(qs.ListNodesHandler)
+
+	// Small body: sent uncompressed even though gzip is accepted.
+	req := httptest.NewRequest(http.MethodGet, "/nodes", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding for small response, got %q", got)
+	}
+	var nodes []node.Node
+	if err := json.NewDecoder(w.Body).Decode(&nodes); err != nil || len(nodes) != 1 {
+		t.Errorf("expected plain JSON with 1 node, got %d nodes, err=%v", len(nodes), err)
+	}
+
+	// Large body, client does not accept gzip.
+	for i := 0; i < 100; i++ {
+		qs.CreateNode(fmt.Sprintf("entity-%d", i))
+	}
+	req = httptest.NewRequest(http.MethodGet, "/nodes", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding without Accept-Encoding, got %q", got)
+	}
+}
+
+func TestGzipMiddleware_SkipsEventStreams(t *testing.T) {
+	handler := utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 200; i++ {
+			fmt.Fprintf(w, "data: event-%d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected event stream to be uncompressed, got Content-Encoding %q", got)
+	}
+	if !w.Flushed {
+		t.Error("expected Flush to reach the underlying writer")
+	}
+}
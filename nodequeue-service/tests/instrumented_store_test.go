@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	resourcepkg "nodequeue-service/resource"
+)
+
+var errStoreDown = errors.New("store down")
+
+// failingStore is a Store whose every call fails with errStoreDown.
+type failingStore struct{}
+
+func (failingStore) ListResources(ctx context.Context) ([]*resourcepkg.Resource, error) {
+	return nil, errStoreDown
+}
+func (failingStore) ListNodes(ctx context.Context) ([]db.PersistedNode, error) {
+	return nil, errStoreDown
+}
+func (failingStore) ListAllNodes(ctx context.Context) ([]db.PersistedNode, error) {
+	return nil, errStoreDown
+}
+func (failingStore) ListLatestNodeStates(ctx context.Context) (map[string]db.NodeState, error) {
+	return nil, errStoreDown
+}
+func (failingStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]db.NodeLogRow, error) {
+	return nil, errStoreDown
+}
+func (failingStore) EachNodeLog(ctx context.Context, q db.NodeLogQuery, fn func(db.NodeLogRow) error) error {
+	return errStoreDown
+}
+func (failingStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]db.NodeExtras, error) {
+	return nil, errStoreDown
+}
+func (failingStore) InsertResource(ctx context.Context, r *resourcepkg.Resource) error {
+	return errStoreDown
+}
+func (failingStore) InsertResources(ctx context.Context, resources []*resourcepkg.Resource) error {
+	return errStoreDown
+}
+func (failingStore) SetResourcePaused(ctx context.Context, id string, paused bool) error {
+	return errStoreDown
+}
+func (failingStore) SetResourceLabels(ctx context.Context, id string, labels map[string]string) error {
+	return errStoreDown
+}
+func (failingStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	return errStoreDown
+}
+func (failingStore) PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error {
+	return errStoreDown
+}
+func (failingStore) UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error {
+	return errStoreDown
+}
+func (failingStore) MarkNodeCompleted(ctx context.Context, nodeID string, completed bool) error {
+	return errStoreDown
+}
+func (failingStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	return errStoreDown
+}
+func (failingStore) InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error {
+	return errStoreDown
+}
+func (failingStore) InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error {
+	return errStoreDown
+}
+func (failingStore) InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error {
+	return errStoreDown
+}
+func (failingStore) AddNodeTag(ctx context.Context, nodeID, tag string) error {
+	return errStoreDown
+}
+func (failingStore) RemoveNodeTag(ctx context.Context, nodeID, tag string) error {
+	return errStoreDown
+}
+func (failingStore) SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error {
+	return errStoreDown
+}
+func (failingStore) UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error {
+	return errStoreDown
+}
+func (failingStore) GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error) {
+	return "", errStoreDown
+}
+func (failingStore) UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error {
+	return errStoreDown
+}
+func (failingStore) UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error {
+	return errStoreDown
+}
+func (failingStore) DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, errStoreDown
+}
+func (failingStore) DeleteAllNodes(ctx context.Context) error {
+	return errStoreDown
+}
+func (failingStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
+	return errStoreDown
+}
+func (failingStore) ListArchivedNodes(ctx context.Context, q db.ArchiveQuery) ([]db.ArchivedNode, error) {
+	return nil, errStoreDown
+}
+
+type storeCall struct {
+	op  string
+	d   time.Duration
+	err error
+}
+
+// instrumentedStoreCalls invokes every Store method once and returns each call's error by method name.
+func instrumentedStoreCalls(ctx context.Context, s db.Store) map[string]error {
+	now := time.Now()
+	rid := "resource-1"
+	errs := make(map[string]error)
+	_, errs["ListResources"] = s.ListResources(ctx)
+	_, errs["ListNodes"] = s.ListNodes(ctx)
+	_, errs["ListAllNodes"] = s.ListAllNodes(ctx)
+	_, errs["ListLatestNodeStates"] = s.ListLatestNodeStates(ctx)
+	_, errs["ListNodeLogs"] = s.ListNodeLogs(ctx, []string{"n1"})
+	errs["EachNodeLog"] = s.EachNodeLog(ctx, db.NodeLogQuery{}, func(db.NodeLogRow) error { return nil })
+	_, errs["ListNodeExtras"] = s.ListNodeExtras(ctx, []string{"n1"})
+	errs["InsertResource"] = s.InsertResource(ctx, resourcepkg.NewResource(rid, 1))
+	errs["InsertResources"] = s.InsertResources(ctx, []*resourcepkg.Resource{resourcepkg.NewResource("resource-2", 1)})
+	errs["SetResourcePaused"] = s.SetResourcePaused(ctx, rid, true)
+	errs["SetResourceLabels"] = s.SetResourceLabels(ctx, rid, map[string]string{"region": "eu"})
+	errs["PersistNodeCreated"] = s.PersistNodeCreated(ctx, "n1", "e1", "entity", 1, now)
+	errs["PersistNodeCreatedWithResource"] = s.PersistNodeCreatedWithResource(ctx, "n2", "e2", "entity", 1, now, rid, now)
+	errs["UpdateNodeResource"] = s.UpdateNodeResource(ctx, "n1", &rid)
+	errs["MarkNodeCompleted"] = s.MarkNodeCompleted(ctx, "n1", true)
+	errs["InsertNodeLog"] = s.InsertNodeLog(ctx, "n1", "completed", &rid, now)
+	errs["InsertNodeMoveLog"] = s.InsertNodeMoveLog(ctx, "n1", "moved_to_waiting_queue", &rid, &rid, now)
+	errs["InsertNodeNote"] = s.InsertNodeNote(ctx, "n1", "ops", "note", now)
+	errs["InsertNodeResult"] = s.InsertNodeResult(ctx, "n1", "success", []byte(`{"ok":true}`), now)
+	errs["AddNodeTag"] = s.AddNodeTag(ctx, "n1", "region:eu")
+	errs["RemoveNodeTag"] = s.RemoveNodeTag(ctx, "n1", "region:eu")
+	errs["SetNodeAllowedResources"] = s.SetNodeAllowedResources(ctx, "n1", []string{rid})
+	errs["UpdateNodeRetry"] = s.UpdateNodeRetry(ctx, "n1", 1, nil, false)
+	errs["UpdateNodeDeadline"] = s.UpdateNodeDeadline(ctx, "n1", &now)
+	errs["UpdateNodeCompletionToken"] = s.UpdateNodeCompletionToken(ctx, "n1", "token-1")
+	_, errs["GetNodeCompletionToken"] = s.GetNodeCompletionToken(ctx, "n1")
+	_, errs["DeleteLogsOlderThan"] = s.DeleteLogsOlderThan(ctx, now.Add(-time.Hour))
+	errs["ArchiveCompletedNode"] = s.ArchiveCompletedNode(ctx, "n1", now)
+	_, errs["ListArchivedNodes"] = s.ListArchivedNodes(ctx, db.ArchiveQuery{})
+	errs["DeleteAllNodes"] = s.DeleteAllNodes(ctx)
+	return errs
+}
+
+func recordCalls(calls *[]storeCall) func(op string, d time.Duration, err error) {
+	return func(op string, d time.Duration, err error) {
+		*calls = append(*calls, storeCall{op: op, d: d, err: err})
+	}
+}
+
+func TestInstrumentedStore_ReportsEveryMethod(t *testing.T) {
+	var calls []storeCall
+	store := db.NewInstrumentedStore(db.NewMemoryStore(), recordCalls(&calls))
+
+	errs := instrumentedStoreCalls(context.Background(), store)
+
+	if len(calls) != len(errs) {
+		t.Fatalf("expected %d reported calls, got %d", len(errs), len(calls))
+	}
+	seen := make(map[string]bool)
+	for _, c := range calls {
+		if _, ok := errs[c.op]; !ok {
+			t.Errorf("unexpected op %q", c.op)
+		}
+		if seen[c.op] {
+			t.Errorf("op %q reported twice", c.op)
+		}
+		seen[c.op] = true
+		if c.err != nil || errs[c.op] != nil {
+			t.Errorf("%s: expected no error, got reported=%v returned=%v", c.op, c.err, errs[c.op])
+		}
+		if c.d < 0 {
+			t.Errorf("%s: expected non-negative duration, got %v", c.op, c.d)
+		}
+	}
+}
+
+func TestInstrumentedStore_ForwardsResults(t *testing.T) {
+	inner := db.NewMemoryStore()
+	var calls []storeCall
+	store := db.NewInstrumentedStore(inner, recordCalls(&calls))
+	ctx := context.Background()
+
+	if err := store.InsertResource(ctx, resourcepkg.NewResource("resource-1", 3)); err != nil {
+		t.Fatalf("InsertResource failed: %v", err)
+	}
+	resources, err := store.ListResources(ctx)
+	if err != nil || len(resources) != 1 || resources[0].ID != "resource-1" || resources[0].Capacity != 3 {
+		t.Fatalf("expected resource-1 with capacity 3, got %+v (err=%v)", resources, err)
+	}
+
+	if err := store.PersistNodeCreated(ctx, "n1", "e1", "entity", 2, time.Now()); err != nil {
+		t.Fatalf("PersistNodeCreated failed: %v", err)
+	}
+	// Writes reach the inner store.
+	nodes, _ := inner.ListNodes(ctx)
+	if len(nodes) != 1 || nodes[0].NodeID != "n1" || nodes[0].Weight != 2 {
+		t.Errorf("expected n1 persisted in the inner store, got %+v", nodes)
+	}
+}
+
+func TestInstrumentedStore_ReportsErrorsAndDurations(t *testing.T) {
+	var calls []storeCall
+	store := db.NewInstrumentedStore(failingStore{}, recordCalls(&calls))
+
+	errs := instrumentedStoreCalls(context.Background(), store)
+
+	if len(calls) != len(errs) {
+		t.Fatalf("expected %d reported calls, got %d", len(errs), len(calls))
+	}
+	for _, c := range calls {
+		if !errors.Is(c.err, errStoreDown) {
+			t.Errorf("%s: expected reported errStoreDown, got %v", c.op, c.err)
+		}
+		if !errors.Is(errs[c.op], errStoreDown) {
+			t.Errorf("%s: expected returned errStoreDown, got %v", c.op, errs[c.op])
+		}
+	}
+
+	// Durations cover the inner call.
+	var slow []storeCall
+	timed := db.NewInstrumentedStore(&slowStore{Store: db.NewMemoryStore(), delay: 20 * time.Millisecond}, recordCalls(&slow))
+	if _, err := timed.ListNodes(context.Background()); err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if len(slow) != 1 || slow[0].d < 20*time.Millisecond {
+		t.Errorf("expected one ListNodes call of at least 20ms, got %+v", slow)
+	}
+}
+
+// slowStore delays ListNodes to make the reported duration observable.
+type slowStore struct {
+	db.Store
+	delay time.Duration
+}
+
+func (s *slowStore) ListNodes(ctx context.Context) ([]db.PersistedNode, error) {
+	time.Sleep(s.delay)
+	return s.Store.ListNodes(ctx)
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+)
+
+// exportNodeLogs calls GET /nodes/logs.jsonl and decodes every line, failing on any that does not parse.
+func exportNodeLogs(t *testing.T, qs *queueservicepkg.QueueService, query string) []queueservicepkg.NodeLogLine {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/nodes/logs.jsonl"+query, nil)
+	w := httptest.NewRecorder()
+	qs.ExportNodeLogsHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %q", ct)
+	}
+
+	var lines []queueservicepkg.NodeLogLine
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		var line queueservicepkg.NodeLogLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("line %d does not parse: %v (%q)", len(lines)+1, err, sc.Text())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestExportNodeLogsHandler_StreamsStoreRowsInTimestampOrder(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	base := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	room := "Room 1"
+	ctx := context.Background()
+	// Inserted out of order, and for a node the service no longer holds in memory.
+	for _, row := range []struct {
+		node, action string
+		rid          *string
+		at           time.Duration
+	}{
+		{"b", "moved_to_waiting_queue", &room, 3 * time.Minute},
+		{"a", "created", nil, 0},
+		{"b", "created", nil, time.Minute},
+		{"a", "moved_to_service_queue", &room, 2 * time.Minute},
+	} {
+		if err := store.InsertNodeLog(ctx, row.node, row.action, row.rid, base.Add(row.at)); err != nil {
+			t.Fatalf("InsertNodeLog failed: %v", err)
+		}
+	}
+
+	lines := exportNodeLogs(t, qs, "")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %+v", len(lines), lines)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i].TS.Before(lines[i-1].TS) {
+			t.Errorf("line %d (%s) is older than line %d (%s)", i+1, lines[i].TS, i, lines[i-1].TS)
+		}
+	}
+	if lines[0].NodeID != "a" || lines[0].Action != "created" || lines[0].ResourceID != nil {
+		t.Errorf("unexpected first line: %+v", lines[0])
+	}
+	if lines[2].ResourceID == nil || *lines[2].ResourceID != room {
+		t.Errorf("expected resource_id %q on line 3, got %+v", room, lines[2])
+	}
+
+	// since is inclusive, until exclusive.
+	lines = exportNodeLogs(t, qs, "?since=2025-01-01T09:01:00Z&until=2025-01-01T09:03:00Z")
+	if len(lines) != 2 || lines[0].NodeID != "b" || lines[1].NodeID != "a" {
+		t.Errorf("expected the two rows in [09:01, 09:03), got %+v", lines)
+	}
+}
+
+func TestExportNodeLogsHandler_WithoutStoreUsesMemory(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	first, _ := qs.CreateNode("first")
+	second, _ := qs.CreateNode("second")
+
+	lines := exportNodeLogs(t, qs, "")
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		seen[line.NodeID] = true
+		if i > 0 && line.TS.Before(lines[i-1].TS) {
+			t.Errorf("line %d is out of timestamp order", i+1)
+		}
+	}
+	if !seen[first.ID] || !seen[second.ID] {
+		t.Errorf("expected logs of both nodes, got %+v", lines)
+	}
+}
+
+func TestExportNodeLogsHandler_InvalidRange(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for _, query := range []string{"?since=yesterday", "?since=2025-01-02T00:00:00Z&until=2025-01-01T00:00:00Z"} {
+		req := httptest.NewRequest(http.MethodGet, "/nodes/logs.jsonl"+query, nil)
+		w := httptest.NewRecorder()
+		qs.ExportNodeLogsHandler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+			continue
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestMemoryStore_DeleteLogsOlderThan(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := db.NewMemoryStore()
+	room := "Room 1"
+
+	store.PersistNodeCreated(ctx, "done", "e1", "e1", 1, base)
+	store.PersistNodeCreated(ctx, "active", "e2", "e2", 1, base)
+	for _, id := range []string{"done", "active"} {
+		store.InsertNodeLog(ctx, id, "created", nil, base)
+		store.InsertNodeLog(ctx, id, "moved_to_waiting_queue", &room, base.Add(time.Minute))
+		store.InsertNodeLog(ctx, id, "moved_to_service_queue", &room, base.Add(2*time.Minute))
+	}
+	store.InsertNodeLog(ctx, "done", "completed", &room, base.Add(3*time.Minute))
+	store.MarkNodeCompleted(ctx, "done", true)
+	// A recent row on the completed node falls inside the retention window
+	store.InsertNodeLog(ctx, "done", "reordered", &room, base.Add(time.Hour))
+
+	statesBefore, _ := store.ListLatestNodeStates(ctx)
+
+	deleted, err := store.DeleteLogsOlderThan(ctx, base.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteLogsOlderThan: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 rows deleted, got %d", deleted)
+	}
+
+	logs, _ := store.ListNodeLogs(ctx, []string{"done", "active"})
+	var actions []string
+	for _, l := range logs["done"] {
+		actions = append(actions, l.Action)
+	}
+	want := []string{"created", "completed", "reordered"}
+	if len(actions) != len(want) {
+		t.Fatalf("Expected retained actions %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("Expected retained actions %v, got %v", want, actions)
+			break
+		}
+	}
+	if len(logs["active"]) != 3 {
+		t.Errorf("Expected active node logs untouched, got %d rows", len(logs["active"]))
+	}
+
+	statesAfter, _ := store.ListLatestNodeStates(ctx)
+	if statesAfter["active"] != statesBefore["active"] {
+		t.Errorf("Expected active node state unchanged, got %+v (was %+v)", statesAfter["active"], statesBefore["active"])
+	}
+}
+
+func TestQueueService_CompactNodeLogs(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	done, _ := qs.CreateNode("e1")
+	qs.MoveNode(done.ID, "resource-1")
+	qs.AllocateNode(done.ID)
+	qs.CompleteNode(done.ID)
+
+	active, _ := qs.CreateNode("e2")
+	qs.MoveNode(active.ID, "resource-1")
+
+	deleted, err := qs.CompactNodeLogs(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("CompactNodeLogs: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 rows deleted (waiting + service moves), got %d", deleted)
+	}
+
+	logs, _ := store.ListNodeLogs(context.Background(), []string{done.ID, active.ID})
+	if len(logs[done.ID]) != 2 {
+		t.Errorf("Expected created+completed kept, got %+v", logs[done.ID])
+	}
+	if len(logs[active.ID]) != 2 {
+		t.Errorf("Expected active node logs untouched, got %+v", logs[active.ID])
+	}
+
+	// Memory-only services have nothing to compact
+	n, err := queueservicepkg.NewQueueService().CompactNodeLogs(context.Background(), 0)
+	if n != 0 || err != nil {
+		t.Errorf("Expected no-op without a store, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func longPoll(ctx context.Context, qs *queueservicepkg.QueueService, nodeID string, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/nodes/"+nodeID+"?"+query, nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	qs.GetNodeHandler(w, req, nodeID)
+	return w
+}
+
+func TestGetNodeHandler_LongPollUnblockedByMove(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	n, _ := qs.CreateNode("e1")
+	since := n.Version
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		done <- longPoll(context.Background(), qs, n.ID, fmt.Sprintf("wait=5s&since_version=%d", since))
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("long-poll returned before the node changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+
+	select {
+	case w := <-done:
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var got struct {
+			ResourceID string `json:"resource_id"`
+			Version    int64  `json:"version"`
+		}
+		json.NewDecoder(w.Body).Decode(&got)
+		if got.ResourceID != "resource-1" || got.Version <= since {
+			t.Errorf("Expected moved node with version > %d, got %+v", since, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("long-poll was not unblocked by the move")
+	}
+}
+
+func TestGetNodeHandler_LongPoll(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	n, _ := qs.CreateNode("e1")
+
+	// Already newer than since_version: returns immediately.
+	w := longPoll(context.Background(), qs, n.ID, "wait=5s&since_version=0")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected immediate %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// No change before the wait elapses: 304.
+	w = longPoll(context.Background(), qs, n.ID, fmt.Sprintf("wait=20ms&since_version=%d", n.Version))
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected empty %d on timeout, got %d %q", http.StatusNotModified, w.Code, w.Body.String())
+	}
+
+	// Client disconnect ends the wait early.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	longPoll(ctx, qs, n.ID, fmt.Sprintf("wait=30s&since_version=%d", n.Version))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected long-poll to end on client disconnect, took %v", elapsed)
+	}
+
+	for _, q := range []string{"wait=5s", "wait=soon&since_version=1", "since_version=1", "wait=5s&since_version=-1"} {
+		if w := longPoll(context.Background(), qs, n.ID, q); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", q, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package tests
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"nodequeue-service/db"
 	queueservicepkg "nodequeue-service/queueservice"
 	resourcepkg "nodequeue-service/resource"
 )
@@ -106,3 +112,390 @@ func TestNodesMetricsHandler_ActiveNodeHasOpenWaitingSegmentClosedAtNow(t *testi
 		t.Fatalf("expected end_ts >= start_ts, got start=%v end=%v", seg.StartTS, seg.EndTS)
 	}
 }
+
+func TestNodesMetricsHandler_Filters(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 5))
+
+	oldActive, _ := qs.CreateNode("old-active")
+	oldDone, _ := qs.CreateNode("old-done")
+	qs.CompleteNode(oldDone.ID)
+	time.Sleep(5 * time.Millisecond)
+	mid := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	newActive, _ := qs.CreateNode("new-active")
+	qs.MoveNode(newActive.ID, "resource-1")
+	newDone, _ := qs.CreateNode("new-done")
+	qs.MoveNode(newDone.ID, "resource-2")
+	qs.CompleteNode(newDone.ID)
+
+	get := func(query string) queueservicepkg.NodesMetricsResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/metrics?"+query, nil)
+		w := httptest.NewRecorder()
+		qs.NodesMetricsHandler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp queueservicepkg.NodesMetricsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	after := url.QueryEscape(mid.Format(time.RFC3339Nano))
+	resp := get("created_after=" + after)
+	if len(resp.ActiveNodes) != 1 || resp.ActiveNodes[0].ID != newActive.ID {
+		t.Errorf("expected only %s active, got %+v", newActive.ID, resp.ActiveNodes)
+	}
+	if len(resp.CompletedNodes) != 1 || resp.CompletedNodes[0].ID != newDone.ID {
+		t.Errorf("expected only %s completed, got %+v", newDone.ID, resp.CompletedNodes)
+	}
+
+	resp = get("created_before=" + after)
+	if len(resp.ActiveNodes) != 1 || resp.ActiveNodes[0].ID != oldActive.ID {
+		t.Errorf("expected only %s active, got %+v", oldActive.ID, resp.ActiveNodes)
+	}
+	if len(resp.CompletedNodes) != 1 || resp.CompletedNodes[0].ID != oldDone.ID {
+		t.Errorf("expected only %s completed, got %+v", oldDone.ID, resp.CompletedNodes)
+	}
+
+	resp = get("resource_id=resource-2")
+	if len(resp.ActiveNodes) != 0 || len(resp.CompletedNodes) != 1 || resp.CompletedNodes[0].ID != newDone.ID {
+		t.Errorf("expected only %s (touched resource-2), got %+v", newDone.ID, resp)
+	}
+
+	// No params: everything
+	resp = get("")
+	if len(resp.ActiveNodes) != 2 || len(resp.CompletedNodes) != 2 {
+		t.Errorf("expected 2 active and 2 completed, got %d/%d", len(resp.ActiveNodes), len(resp.CompletedNodes))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes/metrics?created_after=yesterday", nil)
+	w := httptest.NewRecorder()
+	qs.NodesMetricsHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestNodesMetricsHandler_SourceDB(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	done, _ := qs.CreateNode("entity-1")
+	qs.MoveNode(done.ID, "resource-1")
+	qs.AllocateNode(done.ID)
+	qs.CompleteNode(done.ID)
+	active, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(active.ID, "resource-1")
+
+	// A fresh service on the same store has nothing in memory yet
+	fresh := queueservicepkg.NewQueueServiceWithStore(store)
+	fresh.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	get := func(qs *queueservicepkg.QueueService, query string) (int, queueservicepkg.NodesMetricsResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/metrics?"+query, nil)
+		w := httptest.NewRecorder()
+		qs.NodesMetricsHandler(w, req)
+		var resp queueservicepkg.NodesMetricsResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	_, resp := get(fresh, "")
+	if len(resp.ActiveNodes) != 0 || len(resp.CompletedNodes) != 0 {
+		t.Fatalf("expected empty in-memory metrics, got %+v", resp)
+	}
+
+	code, resp := get(fresh, "source=db")
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if len(resp.ActiveNodes) != 1 || resp.ActiveNodes[0].ID != active.ID {
+		t.Errorf("expected active node %s from db, got %+v", active.ID, resp.ActiveNodes)
+	}
+	if len(resp.CompletedNodes) != 1 || resp.CompletedNodes[0].ID != done.ID {
+		t.Fatalf("expected completed node %s from db, got %+v", done.ID, resp.CompletedNodes)
+	}
+	m := resp.CompletedNodes[0]
+	if m.EntityName != "entity-1" || len(m.WaitingSegments) != 1 || m.WaitingSegments[0].ResourceID != "resource-1" {
+		t.Errorf("expected metrics rebuilt from stored logs, got %+v", m)
+	}
+
+	// Filters apply to the db source too
+	_, resp = get(fresh, "source=db&resource_id=missing")
+	if len(resp.ActiveNodes) != 0 || len(resp.CompletedNodes) != 0 {
+		t.Errorf("expected resource filter to exclude all nodes, got %+v", resp)
+	}
+
+	if code, _ := get(queueservicepkg.NewQueueService(), "source=db"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d without a store, got %d", http.StatusServiceUnavailable, code)
+	}
+	if code, _ := get(fresh, "source=cache"); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for unknown source, got %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestNodeMetricsHandler_MatchesBulkEntry(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	r2 := resourcepkg.NewResource("resource-2", 1)
+	qs.AddResource(r1)
+	qs.AddResource(r2)
+
+	n, err := qs.CreateNode("entity-1")
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if _, err := qs.CreateNode("entity-2"); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := qs.MoveNode(n.ID, r1.ID); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if err := qs.MoveNode(n.ID, r2.ID); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if err := qs.AllocateNode(n.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	if err := qs.CompleteNode(n.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	qs.NodesMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/metrics", nil))
+	var bulk queueservicepkg.NodesMetricsResponse
+	if err := json.NewDecoder(w.Body).Decode(&bulk); err != nil {
+		t.Fatalf("failed to decode bulk response: %v", err)
+	}
+	if len(bulk.CompletedNodes) != 1 {
+		t.Fatalf("expected 1 completed node, got %d", len(bulk.CompletedNodes))
+	}
+
+	w = httptest.NewRecorder()
+	qs.NodeMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/"+n.ID+"/metrics", nil), n.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var single queueservicepkg.NodeMetrics
+	if err := json.NewDecoder(w.Body).Decode(&single); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if !reflect.DeepEqual(single, bulk.CompletedNodes[0]) {
+		t.Fatalf("single-node metrics %+v differ from bulk entry %+v", single, bulk.CompletedNodes[0])
+	}
+	if len(single.WaitingSegments) != 2 {
+		t.Fatalf("expected 2 waiting segments, got %d", len(single.WaitingSegments))
+	}
+
+	w = httptest.NewRecorder()
+	qs.NodeMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/missing/metrics", nil), "missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
+}
+
+// logCountingStore counts ListNodeLogs calls on top of a MemoryStore.
+type logCountingStore struct {
+	*db.MemoryStore
+	listNodeLogs int
+	lastIDs      []string
+}
+
+func (s *logCountingStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]db.NodeLogRow, error) {
+	s.listNodeLogs++
+	s.lastIDs = nodeIDs
+	return s.MemoryStore.ListNodeLogs(ctx, nodeIDs)
+}
+
+func TestNodeMetricsBatchHandler_MixOfExistingAndMissing(t *testing.T) {
+	store := &logCountingStore{MemoryStore: db.NewMemoryStore()}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(r1)
+
+	a, _ := qs.CreateNode("entity-a")
+	b, _ := qs.CreateNode("entity-b")
+	if _, err := qs.CreateNode("entity-c"); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := qs.MoveNode(a.ID, r1.ID); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+
+	body := `{"ids": ["` + b.ID + `", "missing-1", "` + a.ID + `", "missing-2"]}`
+	w := httptest.NewRecorder()
+	qs.NodeMetricsBatchHandler(w, httptest.NewRequest(http.MethodPost, "/nodes/metrics/batch", strings.NewReader(body)))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusMultiStatus, w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.NodeMetricsBatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Found != 2 || resp.Missing != 2 || len(resp.Results) != 4 {
+		t.Fatalf("expected 2 found and 2 missing, got %+v", resp)
+	}
+	for i, want := range []string{b.ID, "missing-1", a.ID, "missing-2"} {
+		if resp.Results[i].ID != want {
+			t.Errorf("result %d: expected id %s, got %s", i, want, resp.Results[i].ID)
+		}
+	}
+	for _, i := range []int{0, 2} {
+		res := resp.Results[i]
+		if !res.OK || res.Metrics == nil || res.Metrics.ID != res.ID {
+			t.Errorf("expected metrics for %s, got %+v", res.ID, res)
+		}
+	}
+	for _, i := range []int{1, 3} {
+		res := resp.Results[i]
+		if res.OK || res.Metrics != nil || res.Code != queueservicepkg.CodeNodeNotFound {
+			t.Errorf("expected %s to be reported missing, got %+v", res.ID, res)
+		}
+	}
+	if len(resp.Results[2].Metrics.WaitingSegments) != 1 {
+		t.Errorf("expected one waiting segment for %s, got %+v", a.ID, resp.Results[2].Metrics.WaitingSegments)
+	}
+
+	// Only the existing nodes' logs are fetched, in one call.
+	if store.listNodeLogs != 1 || len(store.lastIDs) != 2 {
+		t.Errorf("expected one ListNodeLogs call for 2 ids, got %d calls (last ids %v)", store.listNodeLogs, store.lastIDs)
+	}
+}
+
+func TestNodeMetricsBatchHandler_AllFound(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	n, _ := qs.CreateNode("entity-1")
+
+	w := httptest.NewRecorder()
+	qs.NodeMetricsBatchHandler(w, httptest.NewRequest(http.MethodPost, "/nodes/metrics/batch", strings.NewReader(`{"ids": ["`+n.ID+`"]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	qs.NodeMetricsBatchHandler(w, httptest.NewRequest(http.MethodPost, "/nodes/metrics/batch", strings.NewReader(`{"ids": []}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for empty ids, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+}
+
+func TestGetNodeMetrics_CountsChurnAcrossCycles(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.Retry = queueservicepkg.RetryPolicy{MaxAttempts: 5}
+	qs.AddResource(resourcepkg.NewResource("Room 1", 1))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 1))
+
+	n, err := qs.CreateNodeOnResource("", "entity-1", 1, "Room 1", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource failed: %v", err)
+	}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"allocate", func() error { return qs.AllocateNode(n.ID) }},
+		{"fail", func() error { return qs.FailNode(n.ID, "flaky") }},
+		{"allocate", func() error { return qs.AllocateNode(n.ID) }},
+		{"move out of service", func() error { return qs.MoveNode(n.ID, "Room 2") }},
+		{"allocate", func() error { return qs.AllocateNode(n.ID) }},
+		{"fail", func() error { return qs.FailNode(n.ID, "flaky") }},
+		{"allocate", func() error { return qs.AllocateNode(n.ID) }},
+		{"complete", func() error { return qs.CompleteNode(n.ID) }},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s failed: %v", step.name, err)
+		}
+	}
+
+	m, err := qs.GetNodeMetrics(n.ID)
+	if err != nil {
+		t.Fatalf("GetNodeMetrics failed: %v", err)
+	}
+	if m.WaitingCount != 4 || len(m.WaitingSegments) != 4 {
+		t.Errorf("expected 4 waiting segments, got count=%d segments=%d", m.WaitingCount, len(m.WaitingSegments))
+	}
+	if m.ServiceEntries != 4 {
+		t.Errorf("expected 4 service entries, got %d", m.ServiceEntries)
+	}
+	if m.Deallocations != 3 {
+		t.Errorf("expected 3 deallocations, got %d", m.Deallocations)
+	}
+	if m.Failures != 2 {
+		t.Errorf("expected 2 failures, got %d", m.Failures)
+	}
+	if m.ChurnScore != 6 {
+		t.Errorf("expected churn_score 6, got %d", m.ChurnScore)
+	}
+
+	// A node that waited once and was served once has no churn.
+	calm, _ := qs.CreateNodeOnResource("", "entity-2", 1, "Room 1", nil, nil)
+	qs.AllocateNode(calm.ID)
+	qs.CompleteNode(calm.ID)
+	if m, _ := qs.GetNodeMetrics(calm.ID); m.ChurnScore != 0 || m.Deallocations != 0 || m.ServiceEntries != 1 {
+		t.Errorf("expected no churn for a single cycle, got %+v", m)
+	}
+}
+
+func TestNodesMetricsHandler_SummaryOmitsSegments(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	active, _ := qs.CreateNodeOnResource("", "active", 1, "resource-1", nil, nil)
+	done, _ := qs.CreateNodeOnResource("", "done", 1, "resource-1", nil, nil)
+	qs.AllocateNode(done.ID)
+	qs.CompleteNode(done.ID)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/metrics"+query, nil)
+		w := httptest.NewRecorder()
+		qs.NodesMetricsHandler(w, req)
+		return w
+	}
+
+	w := get("?detail=summary")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "waiting_segments") {
+		t.Errorf("expected no waiting_segments in summary, got %s", w.Body.String())
+	}
+	var resp queueservicepkg.NodesMetricsSummaryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.ActiveNodes) != 1 || resp.ActiveNodes[0].ID != active.ID || resp.ActiveNodes[0].WaitingCount != 1 {
+		t.Errorf("expected %s active with one wait, got %+v", active.ID, resp.ActiveNodes)
+	}
+	if len(resp.CompletedNodes) != 1 || resp.CompletedNodes[0].ID != done.ID || resp.CompletedNodes[0].ServiceEntries != 1 {
+		t.Errorf("expected %s completed with one service entry, got %+v", done.ID, resp.CompletedNodes)
+	}
+
+	// The default, and detail=full, keep the segments.
+	for _, query := range []string{"", "?detail=full"} {
+		if w := get(query); !strings.Contains(w.Body.String(), "waiting_segments") {
+			t.Errorf("%q: expected waiting_segments, got %s", query, w.Body.String())
+		}
+	}
+
+	w = get("?detail=everything")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+}
@@ -2,16 +2,37 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"nodequeue-service/db"
 	"nodequeue-service/node"
 	queueservicepkg "nodequeue-service/queueservice"
 	resourcepkg "nodequeue-service/resource"
+	"nodequeue-service/utils"
 )
 
+// assertErrorCode decodes an ErrorResponse from w and checks its machine-readable code.
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, want string) {
+	t.Helper()
+	var resp utils.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if resp.Code != want {
+		t.Errorf("Expected error code '%s', got '%s' (error=%q)", want, resp.Code, resp.Error)
+	}
+}
+
 func TestCreateNodeHandler(t *testing.T) {
 	qs := queueservicepkg.NewQueueService()
 
@@ -68,6 +89,139 @@ func TestCreateNodeHandler(t *testing.T) {
 	}
 }
 
+func TestCreateNodeHandler_ClientSuppliedID(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.CreateNodeHandler(w, req)
+		return w
+	}
+
+	// Supplied valid UUID is used as-is
+	const id = "6f1c2d3e-4b5a-4c7d-8e9f-0a1b2c3d4e5f"
+	w := post(`{"id": "` + id + `", "entity_name": "job"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if n, err := qs.GetNode(id); err != nil || n.Entity.Name != "job" {
+		t.Fatalf("Expected node stored under supplied ID, got err=%v", err)
+	}
+
+	// Duplicate is rejected
+	w = post(`{"id": "` + id + `", "entity_name": "job"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for duplicate ID, got %d", http.StatusConflict, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeExists)
+
+	// Not a UUID
+	w = post(`{"id": "job-42", "entity_name": "job"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid ID, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp utils.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Fields["id"] == "" {
+		t.Errorf("Expected a field error for id, got %+v (err=%v)", resp, err)
+	}
+}
+
+func TestCreateNodeWithID_CanonicalizesUUIDs(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	const id = "6f1c2d3e-4b5a-4c7d-8e9f-0a1b2c3d4e5f"
+
+	created, err := qs.CreateNodeWithID(strings.ToUpper(id), "job")
+	if err != nil {
+		t.Fatalf("CreateNodeWithID failed: %v", err)
+	}
+	if created.ID != id {
+		t.Errorf("Expected the node stored under %s, got %s", id, created.ID)
+	}
+	for _, variant := range []string{id, "urn:uuid:" + id, "{" + id + "}", strings.ReplaceAll(id, "-", "")} {
+		if _, err := qs.CreateNodeWithID(variant, "job"); !errors.Is(err, queueservicepkg.ErrNodeExists) {
+			t.Errorf("%s: expected ErrNodeExists, got %v", variant, err)
+		}
+	}
+}
+
+func TestCreateNodeHandler_RequireCapacity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/nodes?require_capacity=true", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.CreateNodeHandler(w, req)
+		return w
+	}
+
+	// Capacity available: the node is created straight into service
+	w := post(`{"entity_name": "first", "resource_id": "resource-1"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created node.Node
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resource1.IsInService(created.ID) {
+		t.Error("Expected created node to be in service")
+	}
+
+	// Full: 503 and nothing created
+	w = post(`{"entity_name": "second", "resource_id": "resource-1"}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeCapacityUnavailable)
+	if n := len(qs.ListNodes()); n != 1 {
+		t.Errorf("Expected no node created when full, got %d nodes", n)
+	}
+	if _, waiting := resource1.QueueSnapshot(); len(waiting) != 0 {
+		t.Errorf("Expected nothing queued when full, got %d waiting", len(waiting))
+	}
+
+	// The flag needs a resource
+	w = post(`{"entity_name": "third"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without resource_id, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+
+	// Without the flag a node still waits on a full resource
+	req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(`{"entity_name": "fourth", "resource_id": "resource-1"}`))
+	w = httptest.NewRecorder()
+	qs.CreateNodeHandler(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d without the flag, got %d", http.StatusCreated, w.Code)
+	}
+	if _, waiting := resource1.QueueSnapshot(); len(waiting) != 1 {
+		t.Errorf("Expected the node to wait without the flag, got %d waiting", len(waiting))
+	}
+}
+
+func TestCreateNodeHandler_UniqueActiveEntity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.UniqueActiveEntity = true
+	existing, _ := qs.CreateNode("acme")
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(`{"entity_name": "acme"}`))
+	w := httptest.NewRecorder()
+	qs.CreateNodeHandler(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	var resp utils.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if resp.Code != queueservicepkg.CodeEntityActive || resp.NodeID != existing.ID {
+		t.Errorf("Expected entity_active naming %s, got %+v", existing.ID, resp)
+	}
+}
+
 func TestMoveNodeHandler(t *testing.T) {
 	qs := queueservicepkg.NewQueueService()
 	resource1 := resourcepkg.NewResource("resource-1", 3)
@@ -107,6 +261,7 @@ func TestMoveNodeHandler(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
 
 	// Test non-existent node
 	reqBody = node.MoveNodeRequest{TargetResourceID: "resource-1"}
@@ -120,6 +275,7 @@ func TestMoveNodeHandler(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
 
 	// Test non-existent resource
 	reqBody = node.MoveNodeRequest{TargetResourceID: "non-existent"}
@@ -133,8 +289,98 @@ func TestMoveNodeHandler(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
+
+func TestMoveNodeHandler_UnknownLane(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	r1.LaneOrder = []string{"priority"}
+	qs.AddResource(r1)
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	created, _ := qs.CreateNode("test-entity")
+	move := func(req node.MoveNodeRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		jsonBody, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		qs.MoveNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/move", bytes.NewBuffer(jsonBody)), created.ID)
+		return w
+	}
+
+	// A typo, or a lane only another resource defines, is rejected rather than queued as default.
+	for _, req := range []node.MoveNodeRequest{
+		{TargetResourceID: "resource-1", Lane: "priorty"},
+		{TargetResourceID: "resource-2", Lane: "priority"},
+	} {
+		w := move(req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected status %d, got %d: %s", req, http.StatusBadRequest, w.Code, w.Body.String())
+			continue
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+	if got, _ := qs.GetNode(created.ID); got.ResourceID != "" {
+		t.Errorf("Expected rejected moves to leave the node unassigned, got %q", got.ResourceID)
+	}
+	if err := qs.MoveNodeToLane(created.ID, "resource-1", "priorty"); !errors.Is(err, queueservicepkg.ErrUnknownLane) {
+		t.Errorf("Expected ErrUnknownLane, got %v", err)
+	}
+
+	// Listed lanes and the default lane are accepted.
+	for _, req := range []node.MoveNodeRequest{
+		{TargetResourceID: "resource-1", Lane: "priority"},
+		{TargetResourceID: "resource-2", Lane: resourcepkg.DefaultLane},
+	} {
+		if w := move(req); w.Code != http.StatusOK {
+			t.Errorf("%+v: expected status %d, got %d: %s", req, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
 }
 
+func TestMoveNodeHandler_FromResourceID(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-3", 1))
+
+	created, _ := qs.CreateNode("test-entity")
+	qs.MoveNode(created.ID, "resource-1")
+
+	move := func(req node.MoveNodeRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		jsonBody, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		qs.MoveNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/move", bytes.NewBuffer(jsonBody)), created.ID)
+		return w
+	}
+
+	// Matching: the node is on resource-1.
+	if w := move(node.MoveNodeRequest{TargetResourceID: "resource-2", FromResourceID: "resource-1"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Mismatching: a stale client still thinks the node is on resource-1.
+	w := move(node.MoveNodeRequest{TargetResourceID: "resource-3", FromResourceID: "resource-1"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceMismatch)
+	if got, _ := qs.GetNode(created.ID); got.ResourceID != "resource-2" {
+		t.Errorf("Expected rejected move to leave node on resource-2, got %q", got.ResourceID)
+	}
+	if err := qs.MoveNodeIf(created.ID, "resource-1", "resource-3", ""); !errors.Is(err, queueservicepkg.ErrResourceMismatch) {
+		t.Errorf("Expected ErrResourceMismatch, got %v", err)
+	}
+
+	// Omitted: the move is unconditional.
+	if w := move(node.MoveNodeRequest{TargetResourceID: "resource-3"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got, _ := qs.GetNode(created.ID); got.ResourceID != "resource-3" {
+		t.Errorf("Expected node on resource-3, got %q", got.ResourceID)
+	}
+}
 func TestCompleteNodeHandler(t *testing.T) {
 	qs := queueservicepkg.NewQueueService()
 	resource1 := resourcepkg.NewResource("resource-1", 3)
@@ -171,6 +417,216 @@ func TestCompleteNodeHandler(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
+
+	// Test completing an already completed node
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/complete", nil)
+	w = httptest.NewRecorder()
+
+	qs.CompleteNodeHandler(w, req, created.ID)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeCompleted)
+}
+
+func TestCompleteNodeHandler_CompletionToken(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	room := resourcepkg.NewResource("resource-1", 1)
+	room.AutoPromote = true
+	qs.AddResource(room)
+	completions := make(chan queueservicepkg.CompletionEvent, 4)
+	qs.OnComplete = func(ev queueservicepkg.CompletionEvent) { completions <- ev }
+
+	created, _ := qs.CreateNode("test-entity")
+	next, _ := qs.CreateNode("next-entity")
+	qs.MoveNode(created.ID, "resource-1")
+	qs.MoveNode(next.ID, "resource-1")
+	qs.AllocateNode(created.ID)
+
+	complete := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/complete", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.CompleteNodeHandler(w, req, created.ID)
+		return w
+	}
+
+	if w := complete(`{"outcome": "success", "completion_token": "tok-1"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	<-completions
+	first, _ := qs.GetNode(created.ID)
+
+	// Replaying the same token succeeds and changes nothing.
+	w := complete(`{"outcome": "success", "completion_token": "tok-1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected replay to return %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var replayed node.Node
+	if err := json.NewDecoder(w.Body).Decode(&replayed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !replayed.Completed || replayed.Version != first.Version || len(replayed.Log) != len(first.Log) {
+		t.Errorf("Expected the unchanged completed node, got version %d with %d log entries (was %d, %d)",
+			replayed.Version, len(replayed.Log), first.Version, len(first.Log))
+	}
+	if replayed.Result == nil || replayed.Result.Outcome != node.OutcomeSuccess {
+		t.Errorf("Expected the original result, got %+v", replayed.Result)
+	}
+	select {
+	case ev := <-completions:
+		t.Errorf("Expected no second completion event, got %+v", ev)
+	default:
+	}
+	// Auto-promotion ran once, for the original completion only.
+	if n, _ := qs.GetNode(next.ID); !room.IsInService(n.ID) {
+		t.Errorf("Expected %s promoted into service", next.ID)
+	}
+
+	// A different token, or none, is still an error.
+	for _, body := range []string{`{"completion_token": "tok-2"}`, ""} {
+		w := complete(body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+			continue
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeNodeCompleted)
+	}
+
+	if w := complete(`{"completion_token": "` + strings.Repeat("x", node.MaxCompletionTokenLength+1) + `"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an oversized token to be rejected, got %d", w.Code)
+	}
+}
+
+func TestCompleteNodeWithToken_AfterNodeLeavesMemory(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	created, _ := qs.CreateNode("test-entity")
+	if err := qs.CompleteNodeWithToken(created.ID, nil, "tok-1"); err != nil {
+		t.Fatalf("CompleteNodeWithToken failed: %v", err)
+	}
+
+	// Archived: the node is dropped from memory but its token is still in the store.
+	if n, err := qs.PurgeCompletedNodes(context.Background(), 0); err != nil || n != 1 {
+		t.Fatalf("Expected 1 node purged, got %d, %v", n, err)
+	}
+	if err := qs.CompleteNodeWithToken(created.ID, nil, "tok-1"); err != nil {
+		t.Errorf("Expected a retry after archiving to succeed, got %v", err)
+	}
+	if err := qs.CompleteNodeWithToken(created.ID, nil, "tok-2"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound for a different token, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/complete", bytes.NewBufferString(`{"completion_token": "tok-1"}`))
+	w := httptest.NewRecorder()
+	qs.CompleteNodeHandler(w, req, created.ID)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Restarted: a fresh service restored from the same store accepts the retry too.
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	if err := restarted.CompleteNodeWithToken(created.ID, nil, "tok-1"); err != nil {
+		t.Errorf("Expected a retry after restart to succeed, got %v", err)
+	}
+}
+
+func TestCompleteNodeHandler_WithResult(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 3))
+	events := make(chan queueservicepkg.CompletionEvent, 2)
+	qs.OnComplete = func(ev queueservicepkg.CompletionEvent) { events <- ev }
+
+	complete := func(id, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/nodes/"+id+"/complete", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.CompleteNodeHandler(w, req, id)
+		return w
+	}
+	get := func(id string) *node.Node {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/"+id, nil)
+		w := httptest.NewRecorder()
+		qs.GetNodeHandler(w, req, id)
+		var n node.Node
+		if err := json.NewDecoder(w.Body).Decode(&n); err != nil {
+			t.Fatalf("Failed to decode node: %v", err)
+		}
+		return &n
+	}
+
+	withResult, _ := qs.CreateNode("with-result")
+	if w := complete(withResult.ID, `{"outcome": "success", "result": {"score": 3}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	got := get(withResult.ID)
+	if got.Result == nil || got.Result.Outcome != node.OutcomeSuccess || string(got.Result.Result) != `{"score":3}` {
+		t.Errorf("Expected success result on GET, got %+v", got.Result)
+	}
+	if ev := <-events; ev.Result == nil || ev.Result.Outcome != node.OutcomeSuccess {
+		t.Errorf("Expected result in completion event, got %+v", ev.Result)
+	}
+	rows, _ := store.ListNodeExtras(context.Background(), []string{withResult.ID})
+	if rr := rows[withResult.ID].Result; rr == nil || rr.Outcome != node.OutcomeSuccess {
+		t.Errorf("Expected persisted result, got %+v", rows)
+	}
+
+	without, _ := qs.CreateNode("without-result")
+	if w := complete(without.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d without a body, got %d", http.StatusOK, w.Code)
+	}
+	if got := get(without.ID); !got.Completed || got.Result != nil {
+		t.Errorf("Expected completed node without result, got completed=%v result=%+v", got.Completed, got.Result)
+	}
+	if ev := <-events; ev.Result != nil {
+		t.Errorf("Expected no result in completion event, got %+v", ev.Result)
+	}
+
+	// Invalid payloads leave the node active.
+	invalid, _ := qs.CreateNode("invalid")
+	for _, body := range []string{
+		`{"outcome": "maybe"}`,
+		`{"result": {"score": 3}}`,
+		`{"outcome": "failure", "result": "` + strings.Repeat("x", node.MaxResultBytes) + `"}`,
+	} {
+		w := complete(invalid.ID, body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %.40s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+	if got := get(invalid.ID); got.Completed {
+		t.Error("Expected node to stay active after invalid completions")
+	}
+}
+
+func TestRestoreFromStore_RestoresNodeResult(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	n, _ := qs.CreateNode("entity")
+	result := &node.NodeResult{Outcome: node.OutcomeFailure, Result: json.RawMessage(`{"reason":"timeout"}`)}
+	if err := qs.CompleteNodeWithResult(n.ID, result); err != nil {
+		t.Fatalf("CompleteNodeWithResult failed: %v", err)
+	}
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	got, err := restarted.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("GetNode failed: %v", err)
+	}
+	if got.Result == nil || got.Result.Outcome != node.OutcomeFailure || string(got.Result.Result) != `{"reason":"timeout"}` {
+		t.Errorf("Expected restored failure result, got %+v", got.Result)
+	}
 }
 
 func TestAllocateNodeHandler(t *testing.T) {
@@ -198,6 +654,62 @@ func TestAllocateNodeHandler(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeCapacityFull)
+
+	// Allocate first node again - already in service
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+node1.ID+"/allocate", nil)
+	w = httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, node1.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeInService)
+}
+
+func TestAllocateNodeHandler_Idempotent(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+
+	// First and retried allocation both succeed
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/nodes/"+node1.ID+"/allocate?idempotent=true", nil)
+		w := httptest.NewRecorder()
+		qs.AllocateNodeHandler(w, req, node1.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Attempt %d: expected status %d, got %d", i+1, http.StatusOK, w.Code)
+		}
+		var got node.Node
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.ID != node1.ID {
+			t.Fatalf("Attempt %d: expected node1 in response, got id %q (err=%v)", i+1, got.ID, err)
+		}
+	}
+
+	// The retry must not log a second allocation
+	n, _ := qs.GetNode(node1.ID)
+	allocations := 0
+	for _, entry := range n.Log {
+		if entry.Action == "moved_to_service_queue" {
+			allocations++
+		}
+	}
+	if allocations != 1 {
+		t.Errorf("Expected exactly 1 allocation log entry, got %d", allocations)
+	}
+
+	// Other errors are still reported in idempotent mode
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+node2.ID+"/allocate?idempotent=true", nil)
+	w := httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, node2.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeCapacityFull)
 }
 
 func TestGetNodeHandler(t *testing.T) {
@@ -232,6 +744,7 @@ func TestGetNodeHandler(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
 }
 
 func TestListNodesHandler(t *testing.T) {
@@ -281,3 +794,968 @@ func TestListResourcesHandler(t *testing.T) {
 		t.Errorf("Expected 2 resources, got %d", len(resources))
 	}
 }
+
+func TestListResourcesHandler_SortByUtilization(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("idle", 2))
+	qs.AddResource(resourcepkg.NewResource("busy", 2))
+	qs.AddResource(resourcepkg.NewResource("half", 2))
+	qs.AddResource(resourcepkg.NewResource("queued", 4))
+
+	place := func(resourceID string, allocate bool) {
+		n, _ := qs.CreateNode("entity")
+		qs.MoveNode(n.ID, resourceID)
+		if allocate {
+			if err := qs.AllocateNode(n.ID); err != nil {
+				t.Fatalf("AllocateNode on %s failed: %v", resourceID, err)
+			}
+		}
+	}
+	place("busy", true)
+	place("busy", true)
+	place("half", true)
+	place("queued", true)
+	place("queued", false)
+	place("queued", false)
+
+	list := func(query string) (int, []queueservicepkg.ResourceLoad) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/resources?"+query, nil)
+		w := httptest.NewRecorder()
+		qs.ListResourcesHandler(w, req)
+		var loads []queueservicepkg.ResourceLoad
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&loads); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w.Code, loads
+	}
+	ids := func(loads []queueservicepkg.ResourceLoad) []string {
+		out := make([]string, len(loads))
+		for i, l := range loads {
+			out[i] = l.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"busy", "half", "idle", "queued"}},
+		{"sort=utilization&order=desc", []string{"busy", "half", "queued", "idle"}},
+		{"sort=utilization&order=asc", []string{"idle", "queued", "half", "busy"}},
+		{"sort=waiting&order=desc", []string{"queued", "busy", "half", "idle"}},
+		{"sort=id&order=desc", []string{"queued", "idle", "half", "busy"}},
+	}
+	for _, tt := range tests {
+		code, loads := list(tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("%q: expected status %d, got %d", tt.query, http.StatusOK, code)
+		}
+		if got := ids(loads); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: expected order %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	_, loads := list("sort=utilization&order=desc")
+	if busy := loads[0]; busy.Utilization != 1 || busy.ServiceCount != 2 || busy.WaitingDepth != 0 {
+		t.Errorf("Expected busy fully utilized with 2 in service, got %+v", busy)
+	}
+	if queued := loads[2]; queued.Utilization != 0.25 || queued.WaitingDepth != 2 {
+		t.Errorf("Expected queued at 0.25 utilization with 2 waiting, got %+v", queued)
+	}
+
+	for _, query := range []string{"sort=load", "order=up"} {
+		if code, _ := list(query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", query, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestExportResourcesCSVHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+	runtime := resourcepkg.NewResource("resource-2", 3)
+	runtime.AutoPromote = true
+	qs.CreateResource(runtime)
+
+	w := httptest.NewRecorder()
+	qs.ExportResourcesCSVHandler(w, httptest.NewRequest(http.MethodGet, "/resources.csv", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Expected 200 text/csv, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	path := filepath.Join(t.TempDir(), "config.txt")
+	os.WriteFile(path, w.Body.Bytes(), 0o644)
+	loaded := resourcepkg.LoadResources(path)
+	if len(loaded) != 2 || loaded[0].ID != "resource-1" || loaded[1].ID != "resource-2" ||
+		loaded[1].Capacity != 3 || !loaded[1].AutoPromote {
+		t.Errorf("Expected exported CSV to re-import the same resources, got %d resources", len(loaded))
+	}
+}
+
+func TestReorderNodeHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+
+	// Move second node to the front
+	req := httptest.NewRequest(http.MethodPut, "/nodes/"+node2.ID+"/position", bytes.NewBufferString(`{"position": 0}`))
+	w := httptest.NewRecorder()
+	qs.ReorderNodeHandler(w, req, node2.ID)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if resource1.WaitingQueue[0].ID != node2.ID {
+		t.Errorf("Expected node2 at front of waiting queue, got '%s'", resource1.WaitingQueue[0].ID)
+	}
+
+	// Missing position
+	req = httptest.NewRequest(http.MethodPut, "/nodes/"+node2.ID+"/position", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	qs.ReorderNodeHandler(w, req, node2.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+
+	// Non-existent node
+	req = httptest.NewRequest(http.MethodPut, "/nodes/non-existent/position", bytes.NewBufferString(`{"position": 0}`))
+	w = httptest.NewRecorder()
+	qs.ReorderNodeHandler(w, req, "non-existent")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
+}
+
+func TestSwapWaitingNodesHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	ids := make([]string, 0, 4)
+	for _, name := range []string{"entity-1", "entity-2", "entity-3", "entity-4"} {
+		n, _ := qs.CreateNode(name)
+		qs.MoveNode(n.ID, "resource-1")
+		ids = append(ids, n.ID)
+	}
+
+	swap := func(a, b string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := `{"node_a": "` + a + `", "node_b": "` + b + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/resources/resource-1/swap", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.SwapWaitingNodesHandler(w, req, "resource-1")
+		return w
+	}
+
+	if w := swap(ids[0], ids[2]); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	assertWaitingOrder(t, resource1, ids[2], ids[1], ids[0], ids[3])
+	for _, id := range []string{ids[0], ids[2]} {
+		n, _ := qs.GetNode(id)
+		if last := n.Log[len(n.Log)-1]; last.Action != "swapped" || last.ResourceID != "resource-1" {
+			t.Errorf("Expected a swapped log entry on %s, got %+v", id, last)
+		}
+	}
+	if n, _ := qs.GetNode(ids[1]); n.Log[len(n.Log)-1].Action == "swapped" {
+		t.Errorf("Expected no swapped log entry on an unswapped node")
+	}
+
+	// A node in service cannot be swapped; nothing changes.
+	qs.AllocateNode(ids[2])
+	w := swap(ids[2], ids[3])
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeInService)
+	assertWaitingOrder(t, resource1, ids[1], ids[0], ids[3])
+
+	w = swap(ids[1], "non-existent")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := swap(ids[1], ids[1]); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a self-swap, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestExpediteAndDeferNodeHandlers(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+node2.ID+"/expedite", nil)
+	w := httptest.NewRecorder()
+	qs.ExpediteNodeHandler(w, req, node2.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if resource1.WaitingQueue[0].ID != node2.ID {
+		t.Errorf("Expected node2 at front of waiting queue, got '%s'", resource1.WaitingQueue[0].ID)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+node2.ID+"/defer", nil)
+	w = httptest.NewRecorder()
+	qs.DeferNodeHandler(w, req, node2.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if resource1.WaitingQueue[1].ID != node2.ID {
+		t.Errorf("Expected node2 at back of waiting queue, got '%s'", resource1.WaitingQueue[1].ID)
+	}
+
+	// In-service node
+	qs.AllocateNode(node1.ID)
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+node1.ID+"/expedite", nil)
+	w = httptest.NewRecorder()
+	qs.ExpediteNodeHandler(w, req, node1.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeInService)
+}
+
+func TestCanAllocateHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+
+	check := func(nodeID string) queueservicepkg.CanAllocateResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/"+nodeID+"/can-allocate", nil)
+		w := httptest.NewRecorder()
+		qs.CanAllocateHandler(w, req, nodeID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp queueservicepkg.CanAllocateResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := check(node1.ID); !resp.Allowed {
+		t.Errorf("Expected node1 to be allocatable, got reason '%s'", resp.Reason)
+	}
+
+	// Dry run must not mutate state
+	if len(resource1.Nodes) != 0 {
+		t.Errorf("Expected dry run to leave service queue empty, got %d nodes", len(resource1.Nodes))
+	}
+
+	if err := qs.AllocateNode(node1.ID); err != nil {
+		t.Fatalf("Failed to allocate node1: %v", err)
+	}
+
+	if resp := check(node2.ID); resp.Allowed || resp.Code != queueservicepkg.CodeCapacityFull {
+		t.Errorf("Expected node2 to be blocked by capacity, got %+v", resp)
+	}
+	if resp := check(node1.ID); resp.Allowed || resp.Code != queueservicepkg.CodeNodeInService {
+		t.Errorf("Expected node1 to be blocked as already in service, got %+v", resp)
+	}
+
+	// Non-existent node
+	req := httptest.NewRequest(http.MethodGet, "/nodes/non-existent/can-allocate", nil)
+	w := httptest.NewRecorder()
+	qs.CanAllocateHandler(w, req, "non-existent")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotFound)
+}
+
+func TestGetResourceHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+	qs.AllocateNode(node1.ID)
+
+	req := httptest.NewRequest(http.MethodGet, "/resources/resource-1", nil)
+	w := httptest.NewRecorder()
+	qs.GetResourceHandler(w, req, "resource-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var detail queueservicepkg.ResourceDetailResponse
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if detail.ServiceCount != 1 || detail.WaitingCount != 1 || detail.AvailableCapacity != 1 {
+		t.Errorf("Unexpected counts: %+v", detail)
+	}
+	if detail.Service[0].ID != node1.ID || detail.Service[0].Status != "service" || detail.Service[0].EntityName != "entity-1" {
+		t.Errorf("Unexpected service summary: %+v", detail.Service[0])
+	}
+	if detail.Waiting[0].ID != node2.ID || detail.Waiting[0].Status != "waiting" {
+		t.Errorf("Unexpected waiting summary: %+v", detail.Waiting[0])
+	}
+	if detail.Waiting[0].Node != nil {
+		t.Error("Full node should not be embedded without ?include=nodes")
+	}
+
+	// With full nodes
+	req = httptest.NewRequest(http.MethodGet, "/resources/resource-1?include=nodes", nil)
+	w = httptest.NewRecorder()
+	qs.GetResourceHandler(w, req, "resource-1")
+	detail = queueservicepkg.ResourceDetailResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if detail.Waiting[0].Node == nil || len(detail.Waiting[0].Node.Log) == 0 {
+		t.Error("Expected full node with log when ?include=nodes is set")
+	}
+
+	// Non-existent resource
+	req = httptest.NewRequest(http.MethodGet, "/resources/non-existent", nil)
+	w = httptest.NewRecorder()
+	qs.GetResourceHandler(w, req, "non-existent")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
+
+func TestListWaitingHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 5))
+
+	n1, _ := qs.CreateNode("entity-1")
+	n2, _ := qs.CreateNode("entity-2")
+	n3, _ := qs.CreateNode("entity-3")
+	svc, _ := qs.CreateNode("in-service")
+	qs.MoveNode(n1.ID, "resource-2")
+	qs.MoveNode(n2.ID, "resource-1")
+	qs.MoveNode(n3.ID, "resource-1")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.AllocateNode(svc.ID)
+	// Reordering does not change how long n3 has been waiting
+	qs.ReorderWaitingNode(n3.ID, 0)
+
+	decode := func(w *httptest.ResponseRecorder) []queueservicepkg.WaitingNode {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var out []queueservicepkg.WaitingNode
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return out
+	}
+
+	// Default sort: longest waiting first, service nodes excluded
+	w := httptest.NewRecorder()
+	qs.ListWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/waiting", nil))
+	got := decode(w)
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 waiting nodes, got %d", len(got))
+	}
+	if got[0].ID != n1.ID || got[1].ID != n2.ID || got[2].ID != n3.ID {
+		t.Errorf("Expected age order n1,n2,n3, got %s,%s,%s", got[0].ID, got[1].ID, got[2].ID)
+	}
+	if got[2].ResourceID != "resource-1" || got[2].Position != 0 {
+		t.Errorf("Expected n3 at resource-1 position 0, got %+v", got[2])
+	}
+
+	// Scoped by resource, sorted by position
+	w = httptest.NewRecorder()
+	qs.ListWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/waiting?resource_id=resource-1&sort=position", nil))
+	got = decode(w)
+	if len(got) != 2 || got[0].ID != n3.ID || got[1].ID != n2.ID {
+		t.Errorf("Expected resource-1 queue n3,n2, got %+v", got)
+	}
+
+	// Errors
+	w = httptest.NewRecorder()
+	qs.ListWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/waiting?resource_id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown resource, got %d", http.StatusNotFound, w.Code)
+	}
+	w = httptest.NewRecorder()
+	qs.ListWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/waiting?sort=name", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown sort, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+}
+
+func TestResourceWaitingHandler_MatchesAllocationOrder(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 10)
+	r1.LaneOrder = []string{"priority"}
+	qs.AddResource(r1)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		qs.ResourceWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/resources/resource-1/waiting"+query, nil), "resource-1")
+		return w
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("Expected 200 with [] for an empty queue, got %d %q", w.Code, w.Body.String())
+	}
+
+	a, _ := qs.CreateNode("entity-a")
+	b, _ := qs.CreateNode("entity-b")
+	c, _ := qs.CreateNode("entity-c")
+	d, _ := qs.CreateNode("entity-d")
+	qs.MoveNode(a.ID, "resource-1")
+	qs.MoveNode(b.ID, "resource-1")
+	qs.MoveNodeToLane(c.ID, "resource-1", "priority")
+	qs.MoveNode(d.ID, "resource-1")
+	qs.ReorderWaitingNode(d.ID, 0)
+
+	var ids []string
+	if err := json.NewDecoder(get("").Body).Decode(&ids); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	var entries []queueservicepkg.WaitingEntry
+	w = get("?include=entity")
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(entries) != len(ids) {
+		t.Fatalf("Expected %d entries, got %+v", len(ids), entries)
+	}
+	entityOf := map[string]string{a.ID: "entity-a", b.ID: "entity-b", c.ID: "entity-c", d.ID: "entity-d"}
+	for i, e := range entries {
+		if e.ID != ids[i] || e.EntityName != entityOf[e.ID] {
+			t.Errorf("Entry %d: expected %s with its entity name, got %+v", i, ids[i], e)
+		}
+	}
+
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if !slices.Equal(ids, allocated) {
+		t.Errorf("Expected waiting order %v to match allocation order %v", ids, allocated)
+	}
+	if want := []string{c.ID, d.ID, a.ID, b.ID}; !slices.Equal(ids, want) {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+
+	w = get("?include=nodes")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown include, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	qs.ResourceWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/resources/missing/waiting", nil), "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown resource, got %d", w.Code)
+	}
+}
+
+func TestOldestWaitingHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	oldest := func() (int, queueservicepkg.WaitingNode) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		qs.OldestWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/resources/resource-1/oldest", nil), "resource-1")
+		var out queueservicepkg.WaitingNode
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		} else if w.Body.Len() != 0 {
+			t.Errorf("Expected no body with status %d, got %q", w.Code, w.Body.String())
+		}
+		return w.Code, out
+	}
+
+	if code, _ := oldest(); code != http.StatusNoContent {
+		t.Fatalf("Expected status %d for an empty queue, got %d", http.StatusNoContent, code)
+	}
+
+	svc, _ := qs.CreateNode("in-service")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.AllocateNode(svc.ID)
+	if code, _ := oldest(); code != http.StatusNoContent {
+		t.Fatalf("Expected service nodes to be ignored, got status %d", code)
+	}
+
+	n1, _ := qs.CreateNode("entity-1")
+	n2, _ := qs.CreateNode("entity-2")
+	n3, _ := qs.CreateNode("entity-3")
+	for _, n := range []string{n1.ID, n2.ID, n3.ID} {
+		qs.MoveNode(n, "resource-1")
+		time.Sleep(2 * time.Millisecond)
+	}
+	// Queue position does not matter, only how long the node has waited.
+	qs.ReorderWaitingNode(n3.ID, 0)
+
+	code, got := oldest()
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if got.ID != n1.ID || got.Position != 1 || got.EntityName != "entity-1" {
+		t.Errorf("Expected n1 at position 1, got %+v", got)
+	}
+	if got.WaitingMs < 4 {
+		t.Errorf("Expected n1 to have waited at least 4ms, got %d", got.WaitingMs)
+	}
+
+	// Re-entering the waiting queue restarts the clock.
+	qs.MoveNode(n1.ID, "resource-2")
+	qs.MoveNode(n1.ID, "resource-1")
+	if _, got = oldest(); got.ID != n2.ID {
+		t.Errorf("Expected n2 after n1 re-queued, got %+v", got)
+	}
+
+	w := httptest.NewRecorder()
+	qs.OldestWaitingHandler(w, httptest.NewRequest(http.MethodGet, "/resources/missing/oldest", nil), "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown resource, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
+
+func TestStatsHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 3))
+
+	svc, _ := qs.CreateNode("svc")
+	waiting, _ := qs.CreateNode("waiting")
+	done, _ := qs.CreateNode("done")
+	qs.CreateNode("unassigned")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.MoveNode(waiting.ID, "resource-2")
+	qs.MoveNode(done.ID, "resource-1")
+	qs.AllocateNode(svc.ID)
+	qs.CompleteNode(done.ID)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	w := httptest.NewRecorder()
+	qs.StatsHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var st queueservicepkg.Stats
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := queueservicepkg.Stats{
+		TotalNodes:        4,
+		Unassigned:        1,
+		Waiting:           1,
+		InService:         1,
+		Completed:         1,
+		TotalResources:    2,
+		TotalCapacity:     5,
+		CompletedLastHour: 1,
+	}
+	if st != want {
+		t.Errorf("Expected %+v, got %+v", want, st)
+	}
+}
+
+func TestFillResourceHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+	for _, name := range []string{"e1", "e2", "e3"} {
+		n, _ := qs.CreateNode(name)
+		qs.MoveNode(n.ID, "resource-1")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/resources/resource-1/fill", nil)
+	w := httptest.NewRecorder()
+	qs.FillResourceHandler(w, req, "resource-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp queueservicepkg.FillResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Allocated) != 2 || resp.Waiting != 1 {
+		t.Errorf("Expected 2 allocated and 1 waiting, got %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/resources/missing/fill", nil)
+	w = httptest.NewRecorder()
+	qs.FillResourceHandler(w, req, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
+
+func TestPauseResumeResourceHandlers(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	n, _ := qs.CreateNode("entity-1")
+	qs.MoveNode(n.ID, "resource-1")
+
+	req := httptest.NewRequest(http.MethodPost, "/resources/resource-1/pause", nil)
+	w := httptest.NewRecorder()
+	qs.PauseResourceHandler(w, req, "resource-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var detail queueservicepkg.ResourceDetailResponse
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !detail.Paused {
+		t.Error("Expected paused=true in response")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/allocate", nil)
+	w = httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, n.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourcePaused)
+
+	req = httptest.NewRequest(http.MethodPost, "/resources/resource-1/resume", nil)
+	w = httptest.NewRecorder()
+	qs.ResumeResourceHandler(w, req, "resource-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/allocate", nil)
+	w = httptest.NewRecorder()
+	qs.AllocateNodeHandler(w, req, n.ID)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d after resume, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAddNodeNoteHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	n, _ := qs.CreateNode("entity-1")
+
+	body := `{"author": "alice", "text": "escalated by support"}`
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/notes", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	qs.AddNodeNoteHandler(w, req, n.ID)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	// The note is included in the node JSON
+	req = httptest.NewRequest(http.MethodGet, "/nodes/"+n.ID, nil)
+	w = httptest.NewRecorder()
+	qs.GetNodeHandler(w, req, n.ID)
+	var resp struct {
+		Notes []node.NodeNote `json:"notes"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Notes) != 1 || resp.Notes[0].Author != "alice" || resp.Notes[0].Text != "escalated by support" {
+		t.Errorf("Expected the note in node JSON, got %+v", resp.Notes)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/notes", bytes.NewBufferString(`{"author": "alice"}`))
+	w = httptest.NewRecorder()
+	qs.AddNodeNoteHandler(w, req, n.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var errResp utils.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil || errResp.Fields["text"] == "" {
+		t.Errorf("Expected a field error for text, got %+v", errResp)
+	}
+}
+
+func TestGetNodeHandler_BlockedReason(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	full := resourcepkg.NewResource("full", 1)
+	limited := resourcepkg.NewResource("limited", 5)
+	limited.MaxPerEntity = 1
+	paused := resourcepkg.NewResource("paused", 5)
+	open := resourcepkg.NewResource("open", 5)
+	for _, r := range []*resourcepkg.Resource{full, limited, paused, open} {
+		qs.AddResource(r)
+	}
+	qs.PauseResource("paused")
+
+	place := func(entity, resourceID string, allocate bool) string {
+		t.Helper()
+		n, _ := qs.CreateNode(entity)
+		qs.MoveNode(n.ID, resourceID)
+		if allocate {
+			if err := qs.AllocateNode(n.ID); err != nil {
+				t.Fatalf("AllocateNode failed: %v", err)
+			}
+		}
+		return n.ID
+	}
+	fullOccupant := place("e1", "full", true)
+	fullWaiter := place("e2", "full", false)
+	place("tenant-a", "limited", true)
+	limitedWaiter := place("tenant-a", "limited", false)
+	pausedWaiter := place("e3", "paused", false)
+	openWaiter := place("e4", "open", false)
+	unassigned, _ := qs.CreateNode("e5")
+
+	cases := []struct {
+		name   string
+		nodeID string
+		want   string
+	}{
+		{"capacity", fullWaiter, queueservicepkg.CodeCapacityFull},
+		{"entity limit", limitedWaiter, queueservicepkg.CodeEntityLimit},
+		{"paused", pausedWaiter, queueservicepkg.CodeResourcePaused},
+		{"allocatable", openWaiter, ""},
+		{"in service", fullOccupant, ""},
+		{"unassigned", unassigned.ID, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/nodes/"+tc.nodeID, nil)
+			w := httptest.NewRecorder()
+			qs.GetNodeHandler(w, req, tc.nodeID)
+			var resp struct {
+				ID            string  `json:"id"`
+				BlockedReason *string `json:"blocked_reason"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.ID != tc.nodeID {
+				t.Errorf("Expected node %s, got %s", tc.nodeID, resp.ID)
+			}
+			got := ""
+			if resp.BlockedReason != nil {
+				got = *resp.BlockedReason
+			}
+			if got != tc.want {
+				t.Errorf("Expected blocked_reason %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	// Recomputed on read: freeing the slot clears the reason
+	qs.CompleteNode(fullOccupant)
+	view, _ := qs.GetNodeView(fullWaiter)
+	if view.BlockedReason != "" {
+		t.Errorf("Expected no blocked_reason after capacity freed, got %q", view.BlockedReason)
+	}
+}
+
+func TestCompleteNodeHandler_StrictLifecycle(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.StrictLifecycle = true
+	n, _ := qs.CreateNode("entity-1")
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/complete", nil)
+	w := httptest.NewRecorder()
+	qs.CompleteNodeHandler(w, req, n.ID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeNodeNotInService)
+}
+
+func TestListNodesHandler_SummaryOmitsLog(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	created, _ := qs.CreateNode("entity-1")
+
+	decode := func(w *httptest.ResponseRecorder) []map[string]any {
+		t.Helper()
+		var out []map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return out
+	}
+
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes", nil))
+	if nodes := decode(w); len(nodes) != 1 || nodes[0]["log"] != nil {
+		t.Errorf("Expected summary without log by default, got %v", nodes)
+	}
+
+	w = httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?fields=full", nil))
+	if nodes := decode(w); len(nodes) != 1 || nodes[0]["log"] == nil {
+		t.Errorf("Expected log with fields=full, got %v", nodes)
+	}
+
+	// Detail defaults to full; fields=summary drops the log there too.
+	w = httptest.NewRecorder()
+	qs.GetNodeHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/"+created.ID, nil), created.ID)
+	var detail map[string]any
+	json.NewDecoder(w.Body).Decode(&detail)
+	if logs, ok := detail["log"].([]any); !ok || len(logs) != 1 {
+		t.Errorf("Expected full detail with 1 log entry, got %v", detail["log"])
+	}
+	w = httptest.NewRecorder()
+	qs.GetNodeHandler(w, httptest.NewRequest(http.MethodGet, "/nodes/"+created.ID+"?fields=summary", nil), created.ID)
+	detail = nil
+	json.NewDecoder(w.Body).Decode(&detail)
+	if _, ok := detail["log"]; ok {
+		t.Errorf("Expected no log with fields=summary, got %v", detail["log"])
+	}
+
+	w = httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?fields=everything", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid fields, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListNodesHandler_CamelCaseNaming(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	n, _ := qs.CreateNode("entity-1")
+	qs.MoveNode(n.ID, "resource-1")
+
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?naming=camel&fields=full", nil))
+	var nodes []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&nodes); err != nil || len(nodes) != 1 {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if nodes[0]["resourceId"] != "resource-1" || nodes[0]["createdAt"] == nil {
+		t.Errorf("Expected camelCase keys, got %v", nodes[0])
+	}
+	if _, ok := nodes[0]["resource_id"]; ok {
+		t.Error("Expected no snake_case keys with naming=camel")
+	}
+	logs, _ := nodes[0]["log"].([]any)
+	if len(logs) != 2 || logs[1].(map[string]any)["resourceId"] != "resource-1" {
+		t.Errorf("Expected nested log entries to be re-keyed, got %v", nodes[0]["log"])
+	}
+}
+
+func TestBulkActionHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+
+	a, _ := qs.CreateNode("entity-1")
+	b, _ := qs.CreateNode("entity-2")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/nodes/bulk", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.BulkActionHandler(w, req)
+		return w
+	}
+
+	w := post(`{"ids": ["` + a.ID + `", "missing", "` + b.ID + `"], "action": "complete"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp queueservicepkg.BulkActionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 || len(resp.Results) != 3 {
+		t.Fatalf("Expected 2 succeeded and 1 failed, got %+v", resp)
+	}
+	if r := resp.Results[1]; r.ID != "missing" || r.OK || r.Code != queueservicepkg.CodeNodeNotFound {
+		t.Errorf("Expected node_not_found for the missing ID, got %+v", r)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if n, _ := qs.GetNode(id); !n.Completed {
+			t.Errorf("Expected node %s completed", id)
+		}
+	}
+
+	for _, body := range []string{
+		`{"ids": [], "action": "complete"}`,
+		`{"ids": ["x"], "action": "archive"}`,
+		`{"ids": ["x"], "action": "move"}`,
+	} {
+		w := post(body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+}
+
+func TestGetNodeHandler_CurrentDurations(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+	waiting, _ := qs.CreateNodeOnResource("", "e1", 1, "Room 1", nil, nil)
+	serviced, _ := qs.CreateNodeOnResource("", "e2", 1, "Room 1", nil, nil)
+	done, _ := qs.CreateNodeOnResource("", "e3", 1, "Room 1", nil, nil)
+	unassigned, _ := qs.CreateNode("e4")
+	time.Sleep(30 * time.Millisecond)
+	if err := qs.AllocateNode(serviced.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	qs.CompleteNode(done.ID)
+	time.Sleep(20 * time.Millisecond)
+
+	get := func(nodeID string) (waitMS, serviceMS *int64) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/"+nodeID, nil)
+		w := httptest.NewRecorder()
+		qs.GetNodeHandler(w, req, nodeID)
+		var resp struct {
+			CurrentWaitMS    *int64 `json:"current_wait_ms"`
+			CurrentServiceMS *int64 `json:"current_service_ms"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.CurrentWaitMS, resp.CurrentServiceMS
+	}
+
+	waitMS, serviceMS := get(waiting.ID)
+	if waitMS == nil || *waitMS < 50 || serviceMS != nil {
+		t.Errorf("Expected a waiting node to report at least 50ms waiting and no service time, got %v/%v", waitMS, serviceMS)
+	}
+	// The service timer restarts on allocation rather than counting the wait.
+	waitMS, serviceMS = get(serviced.ID)
+	if serviceMS == nil || *serviceMS < 20 || *serviceMS >= 50 || waitMS != nil {
+		t.Errorf("Expected a serviced node to report 20-50ms in service and no wait, got %v/%v", waitMS, serviceMS)
+	}
+	for _, id := range []string{done.ID, unassigned.ID} {
+		if waitMS, serviceMS := get(id); waitMS != nil || serviceMS != nil {
+			t.Errorf("Expected no timers for %s, got %v/%v", id, waitMS, serviceMS)
+		}
+	}
+
+	// Moving a serviced node back to waiting elsewhere starts a fresh wait.
+	qs.AddResource(resourcepkg.NewResource("Room 2", 5))
+	if err := qs.MoveNode(serviced.ID, "Room 2"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if waitMS, serviceMS := get(serviced.ID); waitMS == nil || *waitMS >= 20 || serviceMS != nil {
+		t.Errorf("Expected a fresh wait after the move, got %v/%v", waitMS, serviceMS)
+	}
+}
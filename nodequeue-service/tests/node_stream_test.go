@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func newListNodesService(tb testing.TB, count int) *queueservicepkg.QueueService {
+	tb.Helper()
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", count))
+	for i := 0; i < count; i++ {
+		n, err := qs.CreateNode("entity-1")
+		if err != nil {
+			tb.Fatalf("CreateNode failed: %v", err)
+		}
+		if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+			tb.Fatalf("MoveNode failed: %v", err)
+		}
+	}
+	return qs
+}
+
+func listNodes(t *testing.T, qs *queueservicepkg.QueueService, query string) ([]map[string]any, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /nodes%s: expected status %d, got %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+	}
+	var nodes []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &nodes); err != nil {
+		t.Fatalf("GET /nodes%s: failed to decode response: %v", query, err)
+	}
+	return nodes, w.Header().Get("X-Next-Cursor")
+}
+
+func TestListNodesHandler_StreamMatchesBuffered(t *testing.T) {
+	// More nodes than one stream batch, so the batching is exercised.
+	qs := newListNodesService(t, 600)
+
+	for _, query := range []string{"?fields=full", "?naming=camel"} {
+		buffered, _ := listNodes(t, qs, query+"&limit=600")
+		streamed, next := listNodes(t, qs, query+"&stream=true")
+		if next != "" {
+			t.Fatalf("Expected no next cursor for a full stream, got %q", next)
+		}
+		if len(streamed) != 600 {
+			t.Fatalf("Expected 600 streamed nodes, got %d", len(streamed))
+		}
+		if !reflect.DeepEqual(streamed, buffered) {
+			t.Fatalf("Streamed nodes differ from buffered nodes for %s", query)
+		}
+	}
+}
+
+func TestListNodesHandler_CursorPagination(t *testing.T) {
+	qs := newListNodesService(t, 25)
+
+	for _, stream := range []string{"false", "true"} {
+		seen := make(map[string]bool)
+		prev := ""
+		after := ""
+		for pages := 1; ; pages++ {
+			nodes, next := listNodes(t, qs, "?limit=10&stream="+stream+"&after="+after)
+			for _, n := range nodes {
+				id := n["id"].(string)
+				if seen[id] {
+					t.Fatalf("stream=%s: node %s returned twice", stream, id)
+				}
+				if id <= prev {
+					t.Fatalf("stream=%s: expected IDs in ascending order, got %s after %s", stream, id, prev)
+				}
+				seen[id] = true
+				prev = id
+			}
+			if next == "" {
+				if pages != 3 {
+					t.Fatalf("stream=%s: expected 3 pages, got %d", stream, pages)
+				}
+				break
+			}
+			after = next
+		}
+		if len(seen) != 25 {
+			t.Fatalf("stream=%s: expected to page through 25 nodes, got %d", stream, len(seen))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?limit=0&stream=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for invalid limit/stream, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	fields, _ := resp["fields"].(map[string]any)
+	if fields["limit"] == nil || fields["stream"] == nil {
+		t.Fatalf("Expected limit and stream field errors, got %v", resp)
+	}
+}
+
+func TestListNodesHandler_AfterAcceptsCursorTokens(t *testing.T) {
+	qs := newListNodesService(t, 25)
+
+	// A limit-only first page is in cursor order; its X-Next-Cursor also works as ?after=.
+	seen := make(map[string]bool)
+	nodes, next := listNodes(t, qs, "?limit=10")
+	for pages := 1; ; pages++ {
+		for _, n := range nodes {
+			id := n["id"].(string)
+			if seen[id] {
+				t.Fatalf("node %s returned twice", id)
+			}
+			seen[id] = true
+		}
+		if next == "" {
+			break
+		}
+		nodes, next = listNodes(t, qs, "?limit=10&after="+next)
+	}
+	if len(seen) != 25 {
+		t.Fatalf("expected to page through 25 nodes, got %d", len(seen))
+	}
+
+	cursor := queueservicepkg.NodeCursor{CreatedAt: time.Now(), ID: "a"}.Encode()
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?after=a&cursor="+cursor, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for after combined with cursor, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if fields, _ := resp["fields"].(map[string]any); fields["after"] == nil || fields["cursor"] != nil {
+		t.Fatalf("Expected only an after field error, got %v", resp)
+	}
+}
+
+func TestListNodesHandler_CreatedAtCursorPagination(t *testing.T) {
+	for _, stream := range []string{"false", "true"} {
+		qs := newListNodesService(t, 25)
+		seen := make(map[string]bool)
+		cursor := ""
+		for pages := 1; ; pages++ {
+			nodes, next := listNodes(t, qs, "?limit=10&stream="+stream+"&cursor="+cursor)
+			for _, n := range nodes {
+				id := n["id"].(string)
+				if seen[id] {
+					t.Fatalf("stream=%s: node %s returned twice", stream, id)
+				}
+				seen[id] = true
+			}
+			if pages == 1 {
+				// Nodes created mid-iteration sort after the cursor and must show up later.
+				for i := 0; i < 5; i++ {
+					if _, err := qs.CreateNode("entity-2"); err != nil {
+						t.Fatalf("CreateNode failed: %v", err)
+					}
+				}
+			}
+			if next == "" {
+				if pages != 3 {
+					t.Fatalf("stream=%s: expected 3 pages, got %d", stream, pages)
+				}
+				break
+			}
+			cursor = next
+		}
+		for _, n := range qs.ListNodes() {
+			if !seen[n.ID] {
+				t.Fatalf("stream=%s: node %s was skipped", stream, n.ID)
+			}
+		}
+		if len(seen) != 30 {
+			t.Fatalf("stream=%s: expected to page through 30 nodes, got %d", stream, len(seen))
+		}
+	}
+
+	qs := newListNodesService(t, 1)
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?limit=0&stream=maybe&cursor=!!", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for invalid limit/stream/cursor, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	fields, _ := resp["fields"].(map[string]any)
+	if fields["limit"] == nil || fields["stream"] == nil || fields["cursor"] == nil {
+		t.Fatalf("Expected limit, stream and cursor field errors, got %v", resp)
+	}
+}
+
+func TestNodeCursor_EncodeDecode(t *testing.T) {
+	c := queueservicepkg.NodeCursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), ID: "node-1"}
+	got, err := queueservicepkg.DecodeNodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("DecodeNodeCursor failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Fatalf("Expected %+v after round trip, got %+v", c, got)
+	}
+
+	later := queueservicepkg.NodeCursor{CreatedAt: c.CreatedAt, ID: "node-2"}
+	if !c.Before(later) || later.Before(c) || c.Before(c) {
+		t.Fatal("Expected equal timestamps to be ordered by ID")
+	}
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := queueservicepkg.DecodeNodeCursor(token); err == nil {
+			t.Fatalf("Expected DecodeNodeCursor(%q) to fail", token)
+		}
+	}
+}
+
+// discardResponseWriter drops the body so benchmarks measure the handler, not a response buffer.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func benchmarkListNodesHandler(b *testing.B, query string) {
+	qs := newListNodesService(b, 10000)
+	req := httptest.NewRequest(http.MethodGet, "/nodes"+query, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		qs.ListNodesHandler(&discardResponseWriter{header: make(http.Header)}, req)
+	}
+}
+
+func BenchmarkListNodesHandler_Buffered(b *testing.B) {
+	benchmarkListNodesHandler(b, "?fields=full")
+}
+
+func BenchmarkListNodesHandler_Streaming(b *testing.B) {
+	benchmarkListNodesHandler(b, "?fields=full&stream=true")
+}
+
+func TestListNodesHandler_QueueFilter(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	served, _ := qs.CreateNodeOnResource("", "entity-1", 1, "resource-1", nil, nil)
+	waiting, _ := qs.CreateNodeOnResource("", "entity-2", 1, "resource-1", nil, nil)
+	elsewhere, _ := qs.CreateNodeOnResource("", "entity-3", 1, "resource-2", nil, nil)
+	unassigned, _ := qs.CreateNode("entity-4")
+	if err := qs.AllocateNode(served.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"?queue=service", []string{served.ID}},
+		{"?queue=waiting", []string{waiting.ID, elsewhere.ID}},
+		{"?resource_id=resource-1", []string{served.ID, waiting.ID}},
+		{"?resource_id=resource-1&queue=waiting", []string{waiting.ID}},
+		{"?resource_id=resource-2&queue=service", []string{}},
+		{"?queue=service&stream=true", []string{served.ID}},
+		{"", []string{served.ID, waiting.ID, elsewhere.ID, unassigned.ID}},
+	}
+	for _, tc := range cases {
+		nodes, _ := listNodes(t, qs, tc.query)
+		got := make(map[string]bool, len(nodes))
+		for _, n := range nodes {
+			got[n["id"].(string)] = true
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("GET /nodes%s: expected %v, got %v", tc.query, tc.want, got)
+		}
+		for _, id := range tc.want {
+			if !got[id] {
+				t.Fatalf("GET /nodes%s: expected %s in %v", tc.query, id, got)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?queue=done", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for an invalid queue, got %d", http.StatusBadRequest, w.Code)
+	}
+	w = httptest.NewRecorder()
+	qs.ListNodesHandler(w, httptest.NewRequest(http.MethodGet, "/nodes?resource_id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d for an unknown resource, got %d", http.StatusNotFound, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	nodepkg "nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// completeWithOutcomes creates, allocates and completes one node per outcome on rid.
+func completeWithOutcomes(t *testing.T, qs *queueservicepkg.QueueService, rid string, outcomes ...string) {
+	t.Helper()
+	for _, outcome := range outcomes {
+		n, err := qs.CreateNodeOnResource("", "entity", 1, rid, nil, nil)
+		if err != nil {
+			t.Fatalf("CreateNodeOnResource: %v", err)
+		}
+		if err := qs.AllocateNode(n.ID); err != nil {
+			t.Fatalf("AllocateNode: %v", err)
+		}
+		if err := qs.CompleteNodeWithResult(n.ID, &nodepkg.NodeResult{Outcome: outcome}); err != nil {
+			t.Fatalf("CompleteNodeWithResult: %v", err)
+		}
+	}
+}
+
+func getOutcomes(t *testing.T, qs *queueservicepkg.QueueService, query string) queueservicepkg.OutcomesResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/nodes/outcomes"+query, nil)
+	w := httptest.NewRecorder()
+	qs.OutcomesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.OutcomesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestOutcomesHandler_CountsByOutcomeAndResource(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store db.Store
+	}{
+		{"memory", nil},
+		{"store", db.NewMemoryStore()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			qs := queueservicepkg.NewQueueServiceWithStore(tc.store)
+			qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+			qs.AddResource(resourcepkg.NewResource("Room 2", 5))
+			completeWithOutcomes(t, qs, "Room 1", nodepkg.OutcomeSuccess, nodepkg.OutcomeSuccess, nodepkg.OutcomeCancelled)
+			completeWithOutcomes(t, qs, "Room 2", nodepkg.OutcomeFailure)
+			// Completed without an outcome: not counted.
+			plain, _ := qs.CreateNodeOnResource("", "entity", 1, "Room 2", nil, nil)
+			qs.AllocateNode(plain.ID)
+			qs.CompleteNode(plain.ID)
+
+			resp := getOutcomes(t, qs, "")
+			if resp.Total != 4 || resp.ByOutcome[nodepkg.OutcomeSuccess] != 2 ||
+				resp.ByOutcome[nodepkg.OutcomeFailure] != 1 || resp.ByOutcome[nodepkg.OutcomeCancelled] != 1 {
+				t.Errorf("unexpected totals: %+v", resp.OutcomeSummary)
+			}
+			if resp.SuccessRate == nil || *resp.SuccessRate < 0.66 || *resp.SuccessRate > 0.67 {
+				t.Errorf("expected a success rate of 2/3, got %v", resp.SuccessRate)
+			}
+			room1, room2 := resp.ByResource["Room 1"], resp.ByResource["Room 2"]
+			if room1 == nil || room1.Total != 3 || room1.SuccessRate == nil || *room1.SuccessRate != 1 {
+				t.Errorf("unexpected Room 1 summary: %+v", room1)
+			}
+			if room2 == nil || room2.Total != 1 || room2.SuccessRate == nil || *room2.SuccessRate != 0 {
+				t.Errorf("unexpected Room 2 summary: %+v", room2)
+			}
+
+			scoped := getOutcomes(t, qs, "?resource_id=Room%202")
+			if scoped.Total != 1 || len(scoped.ByResource) != 1 || scoped.ByOutcome[nodepkg.OutcomeFailure] != 1 {
+				t.Errorf("expected only Room 2's failure, got %+v", scoped)
+			}
+
+			future := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+			if later := getOutcomes(t, qs, "?since="+future); later.Total != 0 || later.SuccessRate != nil {
+				t.Errorf("expected nothing since an hour from now, got %+v", later)
+			}
+			if earlier := getOutcomes(t, qs, "?until="+future); earlier.Total != 4 {
+				t.Errorf("expected all 4 before an hour from now, got %d", earlier.Total)
+			}
+		})
+	}
+}
+
+func TestOutcomesHandler_InvalidQuery(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for _, query := range []string{
+		"?since=yesterday",
+		"?since=2025-02-01T00:00:00Z&until=2025-01-01T00:00:00Z",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/nodes/outcomes"+query, nil)
+		w := httptest.NewRecorder()
+		qs.OutcomesHandler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+			continue
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// completionFailingStore is a MemoryStore whose MarkNodeCompleted always fails.
+type completionFailingStore struct {
+	*db.MemoryStore
+}
+
+func (completionFailingStore) MarkNodeCompleted(ctx context.Context, nodeID string, completed bool) error {
+	return errStoreDown
+}
+
+func TestPersistMode_CreateNode(t *testing.T) {
+	for _, mode := range []string{queueservicepkg.PersistBestEffort, queueservicepkg.PersistStrict} {
+		t.Run(mode, func(t *testing.T) {
+			qs := queueservicepkg.NewQueueServiceWithStore(failingStore{})
+			qs.PersistMode = mode
+			qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+			unassigned, err := qs.CreateNode("entity-1")
+			assigned, errAssigned := qs.CreateNodeOnResource("", "entity-2", 1, "resource-1", nil, nil)
+			res, _ := qs.GetResource("resource-1")
+
+			if mode == queueservicepkg.PersistBestEffort {
+				if err != nil || errAssigned != nil {
+					t.Fatalf("Expected best-effort creates to succeed, got %v, %v", err, errAssigned)
+				}
+				if _, err := qs.GetNode(unassigned.ID); err != nil {
+					t.Errorf("Expected node to be kept in memory, got %v", err)
+				}
+				if len(res.WaitingQueue) != 1 || res.WaitingQueue[0].ID != assigned.ID {
+					t.Errorf("Expected assigned node waiting on resource-1, got %v", ids(res.WaitingQueue))
+				}
+				return
+			}
+
+			if !errors.Is(err, queueservicepkg.ErrPersistFailed) || !errors.Is(errAssigned, queueservicepkg.ErrPersistFailed) {
+				t.Fatalf("Expected ErrPersistFailed, got %v, %v", err, errAssigned)
+			}
+			if unassigned != nil || assigned != nil {
+				t.Errorf("Expected no nodes returned, got %v, %v", unassigned, assigned)
+			}
+			if nodes := qs.ListNodes(); len(nodes) != 0 {
+				t.Errorf("Expected failed creates to be rolled back, got %d nodes", len(nodes))
+			}
+			if len(res.WaitingQueue) != 0 {
+				t.Errorf("Expected empty waiting queue, got %v", ids(res.WaitingQueue))
+			}
+		})
+	}
+}
+
+func TestPersistMode_CompleteNode(t *testing.T) {
+	for _, mode := range []string{queueservicepkg.PersistBestEffort, queueservicepkg.PersistStrict} {
+		t.Run(mode, func(t *testing.T) {
+			qs := queueservicepkg.NewQueueServiceWithStore(completionFailingStore{db.NewMemoryStore()})
+			qs.PersistMode = mode
+			qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+			n, err := qs.CreateNodeOnResource("", "entity", 1, "resource-1", nil, nil)
+			if err != nil {
+				t.Fatalf("CreateNodeOnResource failed: %v", err)
+			}
+			if err := qs.AllocateNode(n.ID); err != nil {
+				t.Fatalf("AllocateNode failed: %v", err)
+			}
+
+			err = qs.CompleteNode(n.ID)
+			got, _ := qs.GetNode(n.ID)
+			res, _ := qs.GetResource("resource-1")
+
+			if mode == queueservicepkg.PersistBestEffort {
+				if err != nil || !got.Completed {
+					t.Fatalf("Expected best-effort completion to succeed, got %v (completed=%v)", err, got.Completed)
+				}
+				return
+			}
+
+			if !errors.Is(err, queueservicepkg.ErrPersistFailed) {
+				t.Fatalf("Expected ErrPersistFailed, got %v", err)
+			}
+			if got.Completed || got.ResourceID != "resource-1" || !res.IsInService(n.ID) {
+				t.Errorf("Expected node still in service on resource-1, got completed=%v resource=%q", got.Completed, got.ResourceID)
+			}
+		})
+	}
+}
+
+func TestPersistMode_StrictHandlersReturn500(t *testing.T) {
+	qs := queueservicepkg.NewQueueServiceWithStore(failingStore{})
+	qs.PersistMode = queueservicepkg.PersistStrict
+
+	w := httptest.NewRecorder()
+	qs.CreateNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(`{"entity_name": "entity"}`)))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodePersistFailed)
+}
+
+func TestParsePersistMode(t *testing.T) {
+	if mode, err := queueservicepkg.ParsePersistMode(""); err != nil || mode != queueservicepkg.PersistBestEffort {
+		t.Errorf("Expected empty mode to default to best_effort, got %q (err=%v)", mode, err)
+	}
+	if _, err := queueservicepkg.ParsePersistMode("sometimes"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// fakeClock is a manually advanced clock for monitors.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func setupPressure(t *testing.T) (*queueservicepkg.QueueService, *resourcepkg.Resource, *queueservicepkg.PressureMonitor, *fakeClock, *[]queueservicepkg.PressureEvent) {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	res := resourcepkg.NewResource("resource-1", 1)
+	res.PressureWaiting = 1
+	res.PressureSeconds = 30
+	qs.AddResource(res)
+
+	events := make([]queueservicepkg.PressureEvent, 0)
+	monitor := queueservicepkg.NewPressureMonitor(qs, func(ev queueservicepkg.PressureEvent) {
+		events = append(events, ev)
+	})
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	monitor.Now = clock.Now
+	return qs, res, monitor, clock, &events
+}
+
+func TestPressureMonitor_FiresOnceForSustainedPressure(t *testing.T) {
+	qs, _, monitor, clock, events := setupPressure(t)
+
+	svc, _ := qs.CreateNode("svc")
+	w1, _ := qs.CreateNode("w1")
+	w2, _ := qs.CreateNode("w2")
+	for _, id := range []string{svc.ID, w1.ID, w2.ID} {
+		qs.MoveNode(id, "resource-1")
+	}
+	qs.AllocateNode(svc.ID)
+
+	// Full with 2 waiting (> 1), but not yet for 30s
+	monitor.Check()
+	clock.Advance(29 * time.Second)
+	monitor.Check()
+	if len(*events) != 0 {
+		t.Fatalf("expected no event before the window elapses, got %d", len(*events))
+	}
+
+	clock.Advance(time.Second)
+	monitor.Check()
+	if len(*events) != 1 {
+		t.Fatalf("expected 1 event once the window elapses, got %d", len(*events))
+	}
+	ev := (*events)[0]
+	if ev.Type != queueservicepkg.EventResourcePressure || ev.ResourceID != "resource-1" || ev.WaitingDepth != 2 || ev.DurationSeconds != 30 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	// Sustained condition is debounced
+	clock.Advance(time.Minute)
+	monitor.Check()
+	if len(*events) != 1 {
+		t.Fatalf("expected no repeat while pressure persists, got %d events", len(*events))
+	}
+
+	// Clearing re-arms the monitor
+	qs.CompleteNode(w2.ID)
+	monitor.Check()
+	qs.MoveNode(mustCreate(t, qs, "w3"), "resource-1")
+	monitor.Check()
+	clock.Advance(30 * time.Second)
+	monitor.Check()
+	if len(*events) != 2 {
+		t.Fatalf("expected a new event after the condition cleared and returned, got %d", len(*events))
+	}
+}
+
+func TestPressureMonitor_IgnoresWhenNotFull(t *testing.T) {
+	qs, res, monitor, clock, events := setupPressure(t)
+	res.Capacity = 5
+
+	for _, name := range []string{"w1", "w2", "w3"} {
+		qs.MoveNode(mustCreate(t, qs, name), "resource-1")
+	}
+	monitor.Check()
+	clock.Advance(time.Hour)
+	monitor.Check()
+	if len(*events) != 0 {
+		t.Fatalf("expected no event while capacity is available, got %d", len(*events))
+	}
+}
+
+func TestPressureMonitor_RunStopsOnCancel(t *testing.T) {
+	_, _, monitor, _, _ := setupPressure(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestPressureNotifier_PostsWebhook(t *testing.T) {
+	received := make(chan queueservicepkg.PressureEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev queueservicepkg.PressureEvent
+		_ = json.NewDecoder(r.Body).Decode(&ev)
+		received <- ev
+	}))
+	defer srv.Close()
+
+	notify := queueservicepkg.PressureNotifier(queueservicepkg.NewWebhook(srv.URL))
+	notify(queueservicepkg.PressureEvent{Type: queueservicepkg.EventResourcePressure, ResourceID: "resource-1", WaitingDepth: 4})
+
+	select {
+	case ev := <-received:
+		if ev.ResourceID != "resource-1" || ev.WaitingDepth != 4 {
+			t.Errorf("unexpected webhook payload: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func mustCreate(t *testing.T, qs *queueservicepkg.QueueService, name string) string {
+	t.Helper()
+	n, err := qs.CreateNode(name)
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	return n.ID
+}
+
+func TestCompletionNotifier_PostsMetrics(t *testing.T) {
+	received := make(chan queueservicepkg.CompletionEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev queueservicepkg.CompletionEvent
+		_ = json.NewDecoder(r.Body).Decode(&ev)
+		received <- ev
+	}))
+	defer srv.Close()
+
+	qs := queueservicepkg.NewQueueService()
+	qs.OnComplete = queueservicepkg.CompletionNotifier(queueservicepkg.NewWebhook(srv.URL))
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	id := mustCreate(t, qs, "e1")
+	qs.MoveNode(id, "resource-1")
+	time.Sleep(5 * time.Millisecond)
+	qs.AllocateNode(id)
+	qs.CompleteNode(id)
+
+	select {
+	case ev := <-received:
+		if ev.Version != queueservicepkg.CompletionEventVersion || ev.Type != queueservicepkg.EventNodeCompleted {
+			t.Errorf("unexpected version/type: %d %s", ev.Version, ev.Type)
+		}
+		if ev.NodeID != id || ev.ResourceID != "resource-1" || !ev.Metrics.Completed {
+			t.Errorf("unexpected webhook payload: %+v", ev)
+		}
+		segs := ev.Metrics.WaitingSegments
+		if len(segs) != 1 || segs[0].ResourceID != "resource-1" || segs[0].DurationMS < 5 {
+			t.Fatalf("expected one waiting segment of at least 5ms on resource-1, got %+v", segs)
+		}
+		if segs[0].DurationMS != segs[0].EndTS.Sub(segs[0].StartTS).Milliseconds() {
+			t.Errorf("segment duration does not match its timestamps: %+v", segs[0])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	"nodequeue-service/utils"
+)
+
+// serveWithProblems serves req through ProblemMiddleware and GzipMiddleware, as main wires it for
+// ERROR_FORMAT=problem.
+func serveWithProblems(handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	utils.ProblemMiddleware(utils.GzipMiddleware(handler)).ServeHTTP(w, req)
+	return w
+}
+
+func TestErrorFormat_SimpleByDefault(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	req := httptest.NewRequest(http.MethodGet, "/resources/missing", nil)
+	w := httptest.NewRecorder()
+	utils.GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		qs.GetResourceHandler(w, r, "missing")
+	}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["error"] != "resource not found" || resp["code"] != queueservicepkg.CodeResourceNotFound {
+		t.Errorf("expected the simple error envelope, got %v", resp)
+	}
+	if _, ok := resp["type"]; ok {
+		t.Errorf("expected no problem fields in simple mode, got %v", resp)
+	}
+}
+
+func TestErrorFormat_Problem(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+
+	req := httptest.NewRequest(http.MethodGet, "/resources/missing?include=nodes", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := serveWithProblems(func(w http.ResponseWriter, r *http.Request) {
+		qs.GetResourceHandler(w, r, "missing")
+	}, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != utils.ProblemContentType {
+		t.Errorf("expected %s, got %q", utils.ProblemContentType, ct)
+	}
+	var p utils.Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := utils.Problem{
+		Type:     "urn:nodequeue:problem:resource_not_found",
+		Title:    "Resource not found",
+		Status:   http.StatusNotFound,
+		Detail:   "resource not found",
+		Instance: "/resources/missing",
+		Code:     queueservicepkg.CodeResourceNotFound,
+	}
+	if p.Type != want.Type || p.Title != want.Title || p.Status != want.Status ||
+		p.Detail != want.Detail || p.Instance != want.Instance || p.Code != want.Code {
+		t.Errorf("expected %+v, got %+v", want, p)
+	}
+}
+
+func TestErrorFormat_ProblemKeepsValidationFields(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(`{"entity_name": ""}`))
+	w := serveWithProblems(qs.CreateNodeHandler, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != utils.ProblemContentType {
+		t.Errorf("expected %s, got %q", utils.ProblemContentType, ct)
+	}
+	var p utils.Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if p.Type != "urn:nodequeue:problem:invalid_request" || p.Title != "Invalid request" ||
+		p.Status != http.StatusBadRequest || p.Instance != "/nodes" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	if p.Fields["entity_name"] != "is required" {
+		t.Errorf("expected the entity_name field error as an extension member, got %v", p.Fields)
+	}
+}
+
+func TestNewProblem_WithoutCodeIsAboutBlank(t *testing.T) {
+	p := utils.NewProblem(http.StatusServiceUnavailable, utils.ErrorResponse{Error: "try later"})
+	if p.Type != "about:blank" || p.Title != "Service Unavailable" || p.Status != 503 || p.Detail != "try later" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+}
@@ -1,8 +1,15 @@
 package tests
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
+	"nodequeue-service/db"
 	queueservicepkg "nodequeue-service/queueservice"
 	resourcepkg "nodequeue-service/resource"
 )
@@ -119,6 +126,44 @@ func TestQueueService_MoveNode(t *testing.T) {
 	}
 }
 
+func TestQueueService_MoveNode_RecordsSourceResource(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	node, _ := qs.CreateNode("test-entity")
+	qs.MoveNode(node.ID, "resource-1")
+
+	events, cancel := qs.Subscribe()
+	defer cancel()
+	if err := qs.MoveNode(node.ID, "resource-2"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+
+	got, _ := qs.GetNode(node.ID)
+	first, last := got.Log[len(got.Log)-2], got.Log[len(got.Log)-1]
+	if first.ResourceID != "resource-1" || first.FromResourceID != "" {
+		t.Errorf("Expected the first assignment to have no source, got %+v", first)
+	}
+	if last.Action != "moved_to_waiting_queue" || last.ResourceID != "resource-2" || last.FromResourceID != "resource-1" {
+		t.Errorf("Expected a move from resource-1 to resource-2, got %+v", last)
+	}
+	if ev := <-events; ev.ResourceID != "resource-2" || ev.FromResourceID != "resource-1" {
+		t.Errorf("Expected the move event to carry both resources, got %+v", ev)
+	}
+
+	logs, _ := store.ListNodeLogs(context.Background(), []string{node.ID})
+	rows := logs[node.ID]
+	row := rows[len(rows)-1]
+	if row.ResourceID == nil || *row.ResourceID != "resource-2" || row.FromResourceID == nil || *row.FromResourceID != "resource-1" {
+		t.Errorf("Expected the persisted move from resource-1 to resource-2, got %+v", row)
+	}
+	if prev := rows[len(rows)-2]; prev.FromResourceID != nil {
+		t.Errorf("Expected no source on the first assignment row, got %q", *prev.FromResourceID)
+	}
+}
+
 func TestQueueService_MoveNode_Errors(t *testing.T) {
 	qs := queueservicepkg.NewQueueService()
 	resource1 := resourcepkg.NewResource("resource-1", 1)
@@ -128,14 +173,133 @@ func TestQueueService_MoveNode_Errors(t *testing.T) {
 
 	// Try to move non-existent node
 	err := qs.MoveNode("non-existent", "resource-1")
-	if err == nil {
-		t.Error("Should return error for non-existent node")
+	if !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
 	}
 
 	// Try to move to non-existent resource
 	err = qs.MoveNode(node.ID, "non-existent")
-	if err == nil {
-		t.Error("Should return error for non-existent resource")
+	if !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestQueueService_MoveNode_AllowedResources(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	for _, id := range []string{"resource-1", "resource-2", "resource-3"} {
+		qs.AddResource(resourcepkg.NewResource(id, 1))
+	}
+
+	node, err := qs.CreateTaggedNode("", "test-entity", 1, nil, []string{"resource-2", "resource-1"})
+	if err != nil {
+		t.Fatalf("CreateTaggedNode failed: %v", err)
+	}
+	if err := qs.MoveNode(node.ID, "resource-1"); err != nil {
+		t.Fatalf("Expected move to an allowed resource to succeed, got %v", err)
+	}
+	if err := qs.MoveNode(node.ID, "resource-2"); err != nil {
+		t.Fatalf("Expected move to an allowed resource to succeed, got %v", err)
+	}
+
+	err = qs.MoveNode(node.ID, "resource-3")
+	if !errors.Is(err, queueservicepkg.ErrResourceNotAllowed) {
+		t.Fatalf("Expected ErrResourceNotAllowed, got %v", err)
+	}
+	if got, _ := qs.GetNode(node.ID); got.ResourceID != "resource-2" {
+		t.Errorf("Expected rejected move to leave node on resource-2, got %q", got.ResourceID)
+	}
+	if err := qs.TransferAndAllocate(node.ID, "resource-3"); !errors.Is(err, queueservicepkg.ErrResourceNotAllowed) {
+		t.Errorf("Expected ErrResourceNotAllowed from transfer, got %v", err)
+	}
+
+	// A node without a whitelist may use any resource.
+	free, _ := qs.CreateNode("free-entity")
+	if err := qs.MoveNode(free.ID, "resource-3"); err != nil {
+		t.Errorf("Expected unrestricted node to move anywhere, got %v", err)
+	}
+
+	// The whitelist survives a restart.
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	for _, id := range []string{"resource-1", "resource-2", "resource-3"} {
+		restarted.AddResource(resourcepkg.NewResource(id, 1))
+	}
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	got, err := restarted.GetNode(node.ID)
+	if err != nil {
+		t.Fatalf("Expected restored node, got %v", err)
+	}
+	if !slices.Equal(got.AllowedResources, []string{"resource-1", "resource-2"}) {
+		t.Errorf("Expected restored allowed resources [resource-1 resource-2], got %v", got.AllowedResources)
+	}
+	if err := restarted.MoveNode(node.ID, "resource-3"); !errors.Is(err, queueservicepkg.ErrResourceNotAllowed) {
+		t.Errorf("Expected ErrResourceNotAllowed after restore, got %v", err)
+	}
+}
+
+func TestQueueService_DrainResource_SkipsDisallowedNodes(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	from := resourcepkg.NewResource("resource-1", 1)
+	to := resourcepkg.NewResource("resource-2", 1)
+	qs.AddResource(from)
+	qs.AddResource(to)
+
+	pinned, _ := qs.CreateTaggedNode("", "pinned", 1, nil, []string{"resource-1"})
+	free, _ := qs.CreateNode("free")
+	qs.MoveNode(pinned.ID, "resource-1")
+	qs.MoveNode(free.ID, "resource-1")
+
+	moved, err := qs.DrainResource("resource-1", "resource-2", true)
+	if err != nil {
+		t.Fatalf("Failed to drain resource: %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("Expected 1 node moved, got %d", moved)
+	}
+	if got := ids(from.WaitingQueue); len(got) != 1 || got[0] != pinned.ID {
+		t.Errorf("Expected pinned node to stay on resource-1, got %v", got)
+	}
+	if got := ids(to.WaitingQueue); len(got) != 1 || got[0] != free.ID {
+		t.Errorf("Expected free node on resource-2, got %v", got)
+	}
+}
+
+func TestQueueService_MoveNode_MaxMoves(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.MaxMoves = 3
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	node, _ := qs.CreateNode("test-entity")
+
+	// Three transitions are allowed: unassigned -> 1 -> 2 -> 1.
+	for i, target := range []string{"resource-1", "resource-2", "resource-1"} {
+		if err := qs.MoveNode(node.ID, target); err != nil {
+			t.Fatalf("Move %d to %s failed: %v", i+1, target, err)
+		}
+	}
+	// Re-queueing onto the current resource is not a transition.
+	if err := qs.MoveNode(node.ID, "resource-1"); err != nil {
+		t.Fatalf("Expected move to the current resource to succeed, got %v", err)
+	}
+
+	err := qs.MoveNode(node.ID, "resource-2")
+	if !errors.Is(err, queueservicepkg.ErrMoveLimit) {
+		t.Fatalf("Expected ErrMoveLimit, got %v", err)
+	}
+	if got, _ := qs.GetNode(node.ID); got.ResourceID != "resource-1" {
+		t.Errorf("Expected rejected move to leave node on resource-1, got %q", got.ResourceID)
+	}
+	if err := qs.TransferAndAllocate(node.ID, "resource-2"); !errors.Is(err, queueservicepkg.ErrMoveLimit) {
+		t.Errorf("Expected ErrMoveLimit from transfer, got %v", err)
+	}
+
+	// 0 means unlimited.
+	qs.MaxMoves = 0
+	if err := qs.MoveNode(node.ID, "resource-2"); err != nil {
+		t.Errorf("Expected unlimited moves with MaxMoves=0, got %v", err)
 	}
 }
 
@@ -177,8 +341,8 @@ func TestQueueService_AllocateNode_Errors(t *testing.T) {
 
 	// Not assigned
 	node, _ := qs.CreateNode("test-entity")
-	if err := qs.AllocateNode(node.ID); err == nil {
-		t.Error("Should return error when allocating unassigned node")
+	if err := qs.AllocateNode(node.ID); !errors.Is(err, queueservicepkg.ErrNodeNotAssigned) {
+		t.Errorf("Expected ErrNodeNotAssigned, got %v", err)
 	}
 
 	// Capacity exceeded
@@ -190,8 +354,8 @@ func TestQueueService_AllocateNode_Errors(t *testing.T) {
 	if err := qs.AllocateNode(node1.ID); err != nil {
 		t.Fatalf("Expected first allocation to succeed, got %v", err)
 	}
-	if err := qs.AllocateNode(node2.ID); err == nil {
-		t.Error("Should return error when allocating over capacity")
+	if err := qs.AllocateNode(node2.ID); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("Expected ErrCapacityFull, got %v", err)
 	}
 }
 
@@ -248,8 +412,8 @@ func TestQueueService_CompleteNode_Errors(t *testing.T) {
 	qs.CompleteNode(node.ID)
 
 	err = qs.CompleteNode(node.ID)
-	if err == nil {
-		t.Error("Should return error for already completed node")
+	if !errors.Is(err, queueservicepkg.ErrNodeCompleted) {
+		t.Errorf("Expected ErrNodeCompleted, got %v", err)
 	}
 }
 
@@ -263,8 +427,8 @@ func TestQueueService_MoveCompletedNode(t *testing.T) {
 
 	// Try to move completed node
 	err := qs.MoveNode(node.ID, "resource-1")
-	if err == nil {
-		t.Error("Should not be able to move completed node")
+	if !errors.Is(err, queueservicepkg.ErrNodeCompleted) {
+		t.Errorf("Expected ErrNodeCompleted when moving completed node, got %v", err)
 	}
 }
 
@@ -331,3 +495,996 @@ func TestQueueService_ListNodes(t *testing.T) {
 		t.Errorf("Expected 3 nodes, got %d", len(nodes))
 	}
 }
+
+func TestQueueService_ListReturnsSnapshots(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	n, _ := qs.CreateNode("entity-1")
+
+	before := qs.ListNodes()[0]
+	resources := qs.ListResources()
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+
+	if before == n {
+		t.Fatal("ListNodes should not return the live node")
+	}
+	if len(before.Log) != 1 || before.ResourceID != "" {
+		t.Errorf("expected snapshot to keep its pre-move state, got log=%d resource=%q", len(before.Log), before.ResourceID)
+	}
+	if len(resources[0].WaitingQueue) != 0 {
+		t.Errorf("expected resource snapshot to keep an empty waiting queue, got %d", len(resources[0].WaitingQueue))
+	}
+}
+
+// TestQueueService_ListWhileMutating is meant for go test -race: encoding list results must not
+// race with moves, allocations and completions running concurrently.
+func TestQueueService_ListWhileMutating(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				n, err := qs.CreateNode("entity-1")
+				if err != nil {
+					t.Errorf("CreateNode failed: %v", err)
+					return
+				}
+				qs.MoveNode(n.ID, "resource-1")
+				qs.AllocateNode(n.ID)
+				qs.CompleteNode(n.ID)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		if _, err := json.Marshal(qs.ListNodes()); err != nil {
+			t.Fatalf("marshalling nodes failed: %v", err)
+		}
+		if _, err := json.Marshal(qs.ListResources()); err != nil {
+			t.Fatalf("marshalling resources failed: %v", err)
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+func BenchmarkQueueService_ListNodes(b *testing.B) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1000))
+	for i := 0; i < 1000; i++ {
+		n, _ := qs.CreateNode("entity-1")
+		qs.MoveNode(n.ID, "resource-1")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		qs.ListNodes()
+	}
+}
+
+func TestQueueService_ReorderWaitingNode(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	node3, _ := qs.CreateNode("entity-3")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+	qs.MoveNode(node3.ID, "resource-1")
+
+	if err := qs.ReorderWaitingNode(node3.ID, 0); err != nil {
+		t.Fatalf("Failed to reorder node: %v", err)
+	}
+	if resource1.WaitingQueue[0].ID != node3.ID {
+		t.Errorf("Expected node3 at front of waiting queue, got '%s'", resource1.WaitingQueue[0].ID)
+	}
+
+	retrievedNode, _ := qs.GetNode(node3.ID)
+	last := retrievedNode.Log[len(retrievedNode.Log)-1]
+	if last.Action != "reordered" || last.ResourceID != "resource-1" {
+		t.Errorf("Expected reordered log entry for resource-1, got %+v", last)
+	}
+
+	// Nodes in the service queue are rejected
+	if err := qs.AllocateNode(node3.ID); err != nil {
+		t.Fatalf("Failed to allocate node: %v", err)
+	}
+	if err := qs.ReorderWaitingNode(node3.ID, 1); !errors.Is(err, queueservicepkg.ErrNodeInService) {
+		t.Errorf("Expected ErrNodeInService, got %v", err)
+	}
+
+	// Unassigned and unknown nodes are rejected
+	unassigned, _ := qs.CreateNode("entity-4")
+	if err := qs.ReorderWaitingNode(unassigned.ID, 0); !errors.Is(err, queueservicepkg.ErrNodeNotAssigned) {
+		t.Errorf("Expected ErrNodeNotAssigned, got %v", err)
+	}
+	if err := qs.ReorderWaitingNode("non-existent", 0); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestQueueService_ExpediteAndDeferNode(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	svc, _ := qs.CreateNode("svc")
+	n1, _ := qs.CreateNode("n1")
+	n2, _ := qs.CreateNode("n2")
+	n3, _ := qs.CreateNode("n3")
+	for _, id := range []string{svc.ID, n1.ID, n2.ID, n3.ID} {
+		qs.MoveNode(id, "resource-1")
+	}
+	qs.AllocateNode(svc.ID)
+
+	waitingIDs := func() []string {
+		_, waiting := resource1.QueueSnapshot()
+		ids := make([]string, len(waiting))
+		for i, n := range waiting {
+			ids[i] = n.ID
+		}
+		return ids
+	}
+
+	if err := qs.ExpediteNode(n3.ID); err != nil {
+		t.Fatalf("Failed to expedite node: %v", err)
+	}
+	if got := waitingIDs(); got[0] != n3.ID || got[1] != n1.ID || got[2] != n2.ID {
+		t.Errorf("Expected order n3,n1,n2 after expedite, got %v", got)
+	}
+
+	if err := qs.DeferNode(n3.ID); err != nil {
+		t.Fatalf("Failed to defer node: %v", err)
+	}
+	if got := waitingIDs(); got[0] != n1.ID || got[1] != n2.ID || got[2] != n3.ID {
+		t.Errorf("Expected order n1,n2,n3 after defer, got %v", got)
+	}
+
+	updated, _ := qs.GetNode(n3.ID)
+	if last := updated.Log[len(updated.Log)-1]; last.Action != "deferred" || last.ResourceID != "resource-1" {
+		t.Errorf("Expected deferred log entry, got %+v", last)
+	}
+	if prev := updated.Log[len(updated.Log)-2]; prev.Action != "expedited" {
+		t.Errorf("Expected expedited log entry, got %+v", prev)
+	}
+
+	// Service and completed nodes are rejected
+	if err := qs.ExpediteNode(svc.ID); !errors.Is(err, queueservicepkg.ErrNodeInService) {
+		t.Errorf("Expected ErrNodeInService, got %v", err)
+	}
+	qs.CompleteNode(n2.ID)
+	if err := qs.DeferNode(n2.ID); !errors.Is(err, queueservicepkg.ErrNodeCompleted) {
+		t.Errorf("Expected ErrNodeCompleted, got %v", err)
+	}
+}
+
+func TestQueueService_ReserveAndClaimCapacity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	reservationID, err := qs.ReserveCapacity("resource-1", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+	if _, err := qs.ReserveCapacity("resource-1", time.Minute); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("Expected ErrCapacityFull for second reservation, got %v", err)
+	}
+
+	node1, _ := qs.CreateNode("entity-1")
+	node2, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(node1.ID, "resource-1")
+	qs.MoveNode(node2.ID, "resource-1")
+
+	// Regular allocation is blocked by the hold
+	if err := qs.AllocateNode(node2.ID); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("Expected ErrCapacityFull while slot is reserved, got %v", err)
+	}
+
+	if err := qs.ClaimReservation(reservationID, node1.ID); err != nil {
+		t.Fatalf("Failed to claim reservation: %v", err)
+	}
+	if !resource1.IsInService(node1.ID) {
+		t.Error("Expected node1 in service after claim")
+	}
+	if resource1.ActiveReservations() != 0 {
+		t.Errorf("Expected claimed reservation to be released, got %d active", resource1.ActiveReservations())
+	}
+
+	// A reservation can only be claimed once
+	if err := qs.ClaimReservation(reservationID, node2.ID); !errors.Is(err, queueservicepkg.ErrReservationNotFound) {
+		t.Errorf("Expected ErrReservationNotFound on second claim, got %v", err)
+	}
+
+	if _, err := qs.ReserveCapacity("non-existent", time.Minute); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+	if _, err := qs.ReserveCapacity("resource-1", 0); !errors.Is(err, queueservicepkg.ErrInvalidTTL) {
+		t.Errorf("Expected ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestQueueService_ClaimReservationAfterExpiryFails(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	reservationID, err := qs.ReserveCapacity("resource-1", 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+
+	node, _ := qs.CreateNode("entity-1")
+	qs.MoveNode(node.ID, "resource-1")
+
+	time.Sleep(60 * time.Millisecond)
+
+	if err := qs.ClaimReservation(reservationID, node.ID); !errors.Is(err, queueservicepkg.ErrReservationNotFound) {
+		t.Errorf("Expected ErrReservationNotFound after expiry, got %v", err)
+	}
+	if resource1.IsInService(node.ID) {
+		t.Error("Node should not be in service after failed claim")
+	}
+
+	// Capacity is available again for regular allocation
+	if err := qs.AllocateNode(node.ID); err != nil {
+		t.Errorf("Expected allocation to succeed after reservation expiry, got %v", err)
+	}
+}
+
+func TestQueueService_ClaimReservationRechecksWeight(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 2)
+	qs.AddResource(resource1)
+
+	served, _ := qs.CreateNodeOnResource("", "entity-1", 1, "resource-1", nil, nil)
+	if err := qs.AllocateNode(served.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+	reservationID, err := qs.ReserveCapacity("resource-1", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to reserve capacity: %v", err)
+	}
+
+	// The reservation holds one unit; a weight-2 node would need 3 of the 2.
+	heavy, _ := qs.CreateNodeOnResource("", "entity-2", 2, "resource-1", nil, nil)
+	if err := qs.ClaimReservation(reservationID, heavy.ID); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("Expected ErrCapacityFull claiming with a heavier node, got %v", err)
+	}
+	if resource1.IsInService(heavy.ID) || resource1.ActiveReservations() != 1 {
+		t.Errorf("Expected the failed claim to change nothing, got in service %v, %d reservations",
+			resource1.IsInService(heavy.ID), resource1.ActiveReservations())
+	}
+
+	light, _ := qs.CreateNodeOnResource("", "entity-3", 1, "resource-1", nil, nil)
+	if err := qs.ClaimReservation(reservationID, light.ID); err != nil {
+		t.Fatalf("Failed to claim reservation with a weight-1 node: %v", err)
+	}
+	if resource1.GetAvailableCapacity() != 0 {
+		t.Errorf("Expected the resource exactly full, got %d available", resource1.GetAvailableCapacity())
+	}
+}
+func TestQueueService_DrainResource(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	from := resourcepkg.NewResource("resource-1", 2)
+	to := resourcepkg.NewResource("resource-2", 1)
+	qs.AddResource(from)
+	qs.AddResource(to)
+
+	existing, _ := qs.CreateNode("existing")
+	qs.MoveNode(existing.ID, "resource-2")
+	qs.AllocateNode(existing.ID)
+
+	svc, _ := qs.CreateNode("svc")
+	w1, _ := qs.CreateNode("w1")
+	w2, _ := qs.CreateNode("w2")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.MoveNode(w1.ID, "resource-1")
+	qs.MoveNode(w2.ID, "resource-1")
+	qs.AllocateNode(svc.ID)
+
+	// Waiting-only drain into a full resource still succeeds (moves land in the waiting queue)
+	moved, err := qs.DrainResource("resource-1", "resource-2", false)
+	if err != nil {
+		t.Fatalf("Failed to drain resource: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("Expected 2 nodes moved, got %d", moved)
+	}
+	if got := ids(to.WaitingQueue); len(got) != 2 || got[0] != w1.ID || got[1] != w2.ID {
+		t.Errorf("Expected target waiting queue [w1 w2], got %v", got)
+	}
+	if len(to.Nodes) != 1 || !to.IsFull() {
+		t.Error("Target service queue should be unchanged by drain")
+	}
+	if len(from.WaitingQueue) != 0 || len(from.Nodes) != 1 {
+		t.Errorf("Expected source to keep only its service node, got waiting=%d service=%d", len(from.WaitingQueue), len(from.Nodes))
+	}
+	for _, id := range []string{w1.ID, w2.ID} {
+		n, _ := qs.GetNode(id)
+		if n.ResourceID != "resource-2" {
+			t.Errorf("Expected node %s ResourceID 'resource-2', got '%s'", id, n.ResourceID)
+		}
+		last := n.Log[len(n.Log)-1]
+		if last.Action != "moved_to_waiting_queue" || last.ResourceID != "resource-2" {
+			t.Errorf("Expected moved_to_waiting_queue log for resource-2, got %+v", last)
+		}
+	}
+
+	// Including service nodes moves them back into the target's waiting queue and frees capacity
+	moved, err = qs.DrainResource("resource-1", "resource-2", true)
+	if err != nil {
+		t.Fatalf("Failed to drain service nodes: %v", err)
+	}
+	if moved != 1 || from.GetAvailableCapacity() != 2 {
+		t.Errorf("Expected 1 service node drained and source fully free, got moved=%d available=%d", moved, from.GetAvailableCapacity())
+	}
+	if got := ids(to.WaitingQueue); len(got) != 3 || got[2] != svc.ID {
+		t.Errorf("Expected service node appended to target waiting queue, got %v", got)
+	}
+
+	// Error cases
+	if _, err := qs.DrainResource("resource-1", "resource-1", false); !errors.Is(err, queueservicepkg.ErrSameResource) {
+		t.Errorf("Expected ErrSameResource, got %v", err)
+	}
+	if _, err := qs.DrainResource("non-existent", "resource-2", false); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+	if _, err := qs.DrainResource("resource-1", "non-existent", false); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound for target, got %v", err)
+	}
+}
+
+func TestQueueService_CompleteNode_AutoPromote(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	resource1.AutoPromote = true
+	qs.AddResource(resource1)
+
+	svc, _ := qs.CreateNode("svc")
+	w1, _ := qs.CreateNode("w1")
+	w2, _ := qs.CreateNode("w2")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.MoveNode(w1.ID, "resource-1")
+	qs.MoveNode(w2.ID, "resource-1")
+	if err := qs.AllocateNode(svc.ID); err != nil {
+		t.Fatalf("Failed to allocate node: %v", err)
+	}
+
+	// Completing a waiting node frees no slot, so nothing is promoted
+	if err := qs.CompleteNode(w2.ID); err != nil {
+		t.Fatalf("Failed to complete waiting node: %v", err)
+	}
+	if resource1.IsInService(w1.ID) {
+		t.Error("No node should be promoted when a waiting node completes")
+	}
+
+	// Completing the service node frees a slot; the head of the waiting queue is promoted
+	if err := qs.CompleteNode(svc.ID); err != nil {
+		t.Fatalf("Failed to complete service node: %v", err)
+	}
+	if !resource1.IsInService(w1.ID) {
+		t.Fatal("Expected w1 to be auto-promoted into service")
+	}
+	promoted, _ := qs.GetNode(w1.ID)
+	last := promoted.Log[len(promoted.Log)-1]
+	if last.Action != "moved_to_service_queue" || last.ResourceID != "resource-1" {
+		t.Errorf("Expected moved_to_service_queue log for resource-1, got %+v", last)
+	}
+}
+
+func TestQueueService_CompleteNode_NoAutoPromoteByDefault(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(resource1)
+
+	svc, _ := qs.CreateNode("svc")
+	w1, _ := qs.CreateNode("w1")
+	qs.MoveNode(svc.ID, "resource-1")
+	qs.MoveNode(w1.ID, "resource-1")
+	qs.AllocateNode(svc.ID)
+
+	if err := qs.CompleteNode(svc.ID); err != nil {
+		t.Fatalf("Failed to complete service node: %v", err)
+	}
+	if resource1.IsInService(w1.ID) {
+		t.Error("Node should not be promoted when AutoPromote is disabled")
+	}
+}
+
+func TestQueueService_AllocateNode_MaxPerEntity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 5)
+	resource1.MaxPerEntity = 1
+	qs.AddResource(resource1)
+
+	a1, _ := qs.CreateNode("tenant-a")
+	a2, _ := qs.CreateNode("tenant-a")
+	b1, _ := qs.CreateNode("tenant-b")
+	for _, n := range []string{a1.ID, a2.ID, b1.ID} {
+		qs.MoveNode(n, "resource-1")
+	}
+
+	if err := qs.AllocateNode(a1.ID); err != nil {
+		t.Fatalf("Failed to allocate first tenant-a node: %v", err)
+	}
+	// Second node for the same entity is blocked despite free capacity
+	if err := qs.AllocateNode(a2.ID); !errors.Is(err, queueservicepkg.ErrEntityLimit) {
+		t.Fatalf("Expected ErrEntityLimit for second tenant-a node, got %v", err)
+	}
+	if err := qs.CanAllocate(a2.ID); !errors.Is(err, queueservicepkg.ErrEntityLimit) {
+		t.Errorf("Expected CanAllocate to report ErrEntityLimit, got %v", err)
+	}
+	// A different entity proceeds
+	if err := qs.AllocateNode(b1.ID); err != nil {
+		t.Fatalf("Expected tenant-b node to be allocated, got %v", err)
+	}
+
+	// Once tenant-a's service node completes, its next node may be allocated
+	if err := qs.CompleteNode(a1.ID); err != nil {
+		t.Fatalf("Failed to complete node: %v", err)
+	}
+	if err := qs.AllocateNode(a2.ID); err != nil {
+		t.Errorf("Expected tenant-a node to be allocated after completion, got %v", err)
+	}
+}
+
+func TestQueueService_AutoPromote_SkipsEntityAtLimit(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 2)
+	resource1.AutoPromote = true
+	resource1.MaxPerEntity = 1
+	qs.AddResource(resource1)
+
+	a1, _ := qs.CreateNode("tenant-a")
+	b1, _ := qs.CreateNode("tenant-b")
+	a2, _ := qs.CreateNode("tenant-a")
+	b2, _ := qs.CreateNode("tenant-b")
+	for _, n := range []string{a1.ID, b1.ID, a2.ID, b2.ID} {
+		qs.MoveNode(n, "resource-1")
+	}
+	qs.AllocateNode(a1.ID)
+	qs.AllocateNode(b1.ID)
+
+	// b1 frees a slot; a2 is at the head but tenant-a is at its limit, so b2 is promoted
+	if err := qs.CompleteNode(b1.ID); err != nil {
+		t.Fatalf("Failed to complete node: %v", err)
+	}
+	if resource1.IsInService(a2.ID) {
+		t.Error("tenant-a node should not be promoted while tenant-a is at its limit")
+	}
+	if !resource1.IsInService(b2.ID) {
+		t.Error("Expected tenant-b node to be auto-promoted past the blocked head")
+	}
+}
+
+func TestQueueService_AllocateNode_FIFOStrict(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 5)
+	resource1.FIFOStrict = true
+	qs.AddResource(resource1)
+
+	first, _ := qs.CreateNode("e1")
+	second, _ := qs.CreateNode("e2")
+	qs.MoveNode(first.ID, "resource-1")
+	qs.MoveNode(second.ID, "resource-1")
+
+	// second may not jump the queue
+	if err := qs.AllocateNode(second.ID); !errors.Is(err, queueservicepkg.ErrNotQueueHead) {
+		t.Fatalf("Expected ErrNotQueueHead for non-head node, got %v", err)
+	}
+	if err := qs.CanAllocate(second.ID); !errors.Is(err, queueservicepkg.ErrNotQueueHead) {
+		t.Errorf("Expected CanAllocate to report ErrNotQueueHead, got %v", err)
+	}
+	if resource1.IsInService(second.ID) {
+		t.Error("Non-head node should stay waiting")
+	}
+
+	if err := qs.AllocateNode(first.ID); err != nil {
+		t.Fatalf("Expected head node to be allocated, got %v", err)
+	}
+	// second is now at the head
+	if err := qs.AllocateNode(second.ID); err != nil {
+		t.Errorf("Expected new head node to be allocated, got %v", err)
+	}
+}
+
+func TestQueueService_FillResource_FIFOStrictStopsAtBlockedHead(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 3)
+	resource1.FIFOStrict = true
+	resource1.MaxPerEntity = 1
+	qs.AddResource(resource1)
+
+	a1, _ := qs.CreateNode("tenant-a")
+	a2, _ := qs.CreateNode("tenant-a")
+	b1, _ := qs.CreateNode("tenant-b")
+	for _, id := range []string{a1.ID, a2.ID, b1.ID} {
+		qs.MoveNode(id, "resource-1")
+	}
+
+	// a2 is blocked by the entity limit, and b1 may not pass it
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if len(allocated) != 1 || allocated[0] != a1.ID {
+		t.Errorf("Expected only [%s] allocated, got %v", a1.ID, allocated)
+	}
+}
+
+func TestQueueService_FillResource(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 3)
+	resource1.MaxPerEntity = 1
+	qs.AddResource(resource1)
+
+	a1, _ := qs.CreateNode("tenant-a")
+	a2, _ := qs.CreateNode("tenant-a")
+	b1, _ := qs.CreateNode("tenant-b")
+	c1, _ := qs.CreateNode("tenant-c")
+	d1, _ := qs.CreateNode("tenant-d")
+	for _, id := range []string{a1.ID, a2.ID, b1.ID, c1.ID, d1.ID} {
+		qs.MoveNode(id, "resource-1")
+	}
+
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	// a2 is passed over (tenant-a at its limit); d1 stays waiting once full
+	want := []string{a1.ID, b1.ID, c1.ID}
+	if len(allocated) != len(want) {
+		t.Fatalf("Expected %d allocated, got %v", len(want), allocated)
+	}
+	for i := range want {
+		if allocated[i] != want[i] {
+			t.Errorf("Expected allocation order %v, got %v", want, allocated)
+			break
+		}
+	}
+	if !resource1.IsWaiting(a2.ID) || !resource1.IsWaiting(d1.ID) {
+		t.Error("Expected a2 and d1 to remain waiting")
+	}
+
+	// Already full: no-op
+	allocated, err = qs.FillResource("resource-1")
+	if err != nil || len(allocated) != 0 {
+		t.Errorf("Expected empty no-op fill on a full resource, got %v (err=%v)", allocated, err)
+	}
+
+	if _, err := qs.FillResource("missing"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestQueueService_PauseResume(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	resource1 := resourcepkg.NewResource("resource-1", 2)
+	resource1.AutoPromote = true
+	qs.AddResource(resource1)
+
+	n1, _ := qs.CreateNode("entity-1")
+	n2, _ := qs.CreateNode("entity-2")
+
+	if err := qs.PauseResource("resource-1"); err != nil {
+		t.Fatalf("PauseResource failed: %v", err)
+	}
+
+	// Moves are still accepted while paused
+	if err := qs.MoveNode(n1.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode into paused resource failed: %v", err)
+	}
+	qs.MoveNode(n2.ID, "resource-1")
+
+	if err := qs.AllocateNode(n1.ID); !errors.Is(err, queueservicepkg.ErrResourcePaused) {
+		t.Errorf("Expected ErrResourcePaused, got %v", err)
+	}
+	if _, err := qs.FillResource("resource-1"); !errors.Is(err, queueservicepkg.ErrResourcePaused) {
+		t.Errorf("Expected ErrResourcePaused from fill, got %v", err)
+	}
+
+	// Resume auto-promotes into the free slots
+	if err := qs.ResumeResource("resource-1"); err != nil {
+		t.Fatalf("ResumeResource failed: %v", err)
+	}
+	if resource1.IsPaused() {
+		t.Error("Expected resource to be resumed")
+	}
+	if !resource1.IsInService(n1.ID) || !resource1.IsInService(n2.ID) {
+		t.Error("Expected waiting nodes to be promoted on resume")
+	}
+
+	if err := qs.PauseResource("missing"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestQueueService_AddNodeNote(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	n, _ := qs.CreateNode("entity-1")
+
+	if _, err := qs.AddNodeNote(n.ID, "alice", "escalated by support"); err != nil {
+		t.Fatalf("AddNodeNote failed: %v", err)
+	}
+	qs.CompleteNode(n.ID)
+	// Notes are still accepted after completion
+	if _, err := qs.AddNodeNote(n.ID, "bob", "customer called back"); err != nil {
+		t.Fatalf("AddNodeNote on completed node failed: %v", err)
+	}
+
+	got, _ := qs.GetNode(n.ID)
+	if len(got.Notes) != 2 {
+		t.Fatalf("Expected 2 notes, got %d", len(got.Notes))
+	}
+	if got.Notes[0].Author != "alice" || got.Notes[1].Text != "customer called back" {
+		t.Errorf("Unexpected notes: %+v", got.Notes)
+	}
+	if got.Notes[1].Timestamp.Before(got.Notes[0].Timestamp) {
+		t.Error("Expected notes in timestamp order")
+	}
+
+	if _, err := qs.AddNodeNote("missing", "alice", "x"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestQueueService_CompleteNode_StrictLifecycle(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.StrictLifecycle = true
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	unassigned, _ := qs.CreateNode("entity-1")
+	if err := qs.CompleteNode(unassigned.ID); !errors.Is(err, queueservicepkg.ErrNodeNotInService) {
+		t.Errorf("Expected ErrNodeNotInService for unassigned node, got %v", err)
+	}
+
+	waiting, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(waiting.ID, "resource-1")
+	if err := qs.CompleteNode(waiting.ID); !errors.Is(err, queueservicepkg.ErrNodeNotInService) {
+		t.Errorf("Expected ErrNodeNotInService for waiting node, got %v", err)
+	}
+	if got, _ := qs.GetNode(waiting.ID); got.Completed {
+		t.Error("Rejected completion must not mark the node completed")
+	}
+
+	qs.AllocateNode(waiting.ID)
+	if err := qs.CompleteNode(waiting.ID); err != nil {
+		t.Errorf("Expected in-service node to complete, got %v", err)
+	}
+}
+
+func TestQueueService_CompleteNode_LenientByDefault(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	unassigned, _ := qs.CreateNode("entity-1")
+	if err := qs.CompleteNode(unassigned.ID); err != nil {
+		t.Errorf("Expected unassigned node to complete in lenient mode, got %v", err)
+	}
+	waiting, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(waiting.ID, "resource-1")
+	if err := qs.CompleteNode(waiting.ID); err != nil {
+		t.Errorf("Expected waiting node to complete in lenient mode, got %v", err)
+	}
+}
+
+func TestQueueService_LanesFillInPriorityOrder(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 2)
+	r1.LaneOrder = []string{"priority", "standard"}
+	qs.AddResource(r1)
+
+	std1, _ := qs.CreateNode("e1")
+	std2, _ := qs.CreateNode("e2")
+	pri, _ := qs.CreateNode("e3")
+	qs.MoveNodeToLane(std1.ID, "resource-1", "standard")
+	qs.MoveNodeToLane(std2.ID, "resource-1", "standard")
+	qs.MoveNodeToLane(pri.ID, "resource-1", "priority")
+
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if len(allocated) != 2 || allocated[0] != pri.ID || allocated[1] != std1.ID {
+		t.Errorf("Expected priority lane drained first [%s %s], got %v", pri.ID, std1.ID, allocated)
+	}
+	if r1.LaneOf(std2.ID) != "standard" {
+		t.Errorf("Expected %s still waiting in standard lane", std2.ID)
+	}
+}
+
+func TestQueueService_ReservedForPriority(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 3)
+	r1.LaneOrder = []string{"priority", "standard"}
+	r1.ReservedForPriority = 1
+	qs.AddResource(r1)
+
+	std1, _ := qs.CreateNode("e1")
+	std2, _ := qs.CreateNode("e2")
+	std3, _ := qs.CreateNode("e3")
+	pri, _ := qs.CreateNode("e4")
+	for _, id := range []string{std1.ID, std2.ID, std3.ID} {
+		qs.MoveNodeToLane(id, "resource-1", "standard")
+	}
+
+	if err := qs.AllocateNode(std1.ID); err != nil {
+		t.Fatalf("AllocateNode(std1) failed: %v", err)
+	}
+	if err := qs.AllocateNode(std2.ID); err != nil {
+		t.Fatalf("AllocateNode(std2) failed: %v", err)
+	}
+	// One slot is free, but it is held for the priority lane.
+	if err := qs.AllocateNode(std3.ID); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Fatalf("Expected ErrCapacityFull for a normal node, got %v", err)
+	}
+	if r1.AllocateWaitingNode(std3.ID) {
+		t.Fatal("Expected the resource to refuse the reserved slot to a normal node")
+	}
+	if allocated, _ := qs.FillResource("resource-1"); len(allocated) != 0 {
+		t.Fatalf("Expected fill to leave the reserved slot empty, got %v", allocated)
+	}
+
+	qs.MoveNodeToLane(pri.ID, "resource-1", "priority")
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if len(allocated) != 1 || allocated[0] != pri.ID {
+		t.Errorf("Expected the priority node to take the reserved slot, got %v", allocated)
+	}
+	if !r1.IsWaiting(std3.ID) {
+		t.Errorf("Expected %s to keep waiting", std3.ID)
+	}
+}
+
+func TestQueueService_DefaultLaneUnchanged(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(r1)
+
+	a, _ := qs.CreateNode("e1")
+	b, _ := qs.CreateNode("e2")
+	qs.MoveNode(a.ID, "resource-1")
+	qs.MoveNodeToLane(b.ID, "resource-1", "")
+
+	if r1.LaneOf(a.ID) != resourcepkg.DefaultLane || r1.LaneOf(b.ID) != resourcepkg.DefaultLane {
+		t.Error("Expected nodes in the default lane")
+	}
+	if next := r1.NextWaitingNode(); next == nil || next.ID != a.ID {
+		t.Error("Expected FIFO order in the default lane")
+	}
+	allocated, _ := qs.FillResource("resource-1")
+	if len(allocated) != 1 || allocated[0] != a.ID {
+		t.Errorf("Expected FIFO allocation of %s, got %v", a.ID, allocated)
+	}
+}
+
+func TestQueueService_FillWithMixedWeightsFillsExactly(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	r1 := resourcepkg.NewResource("resource-1", 6)
+	qs.AddResource(r1)
+
+	// Queue order: 4, 3, 2 -> 4 fits, 3 does not (2 left), 2 fills the resource exactly.
+	w4, _ := qs.CreateWeightedNode("", "e1", 4)
+	w3, _ := qs.CreateWeightedNode("", "e2", 3)
+	w2, _ := qs.CreateWeightedNode("", "e3", 2)
+	for _, n := range []string{w4.ID, w3.ID, w2.ID} {
+		qs.MoveNode(n, "resource-1")
+	}
+
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if len(allocated) != 2 || allocated[0] != w4.ID || allocated[1] != w2.ID {
+		t.Errorf("Expected [%s %s] allocated, got %v", w4.ID, w2.ID, allocated)
+	}
+	if !r1.IsFull() || r1.GetAvailableCapacity() != 0 {
+		t.Errorf("Expected resource filled exactly, available=%d", r1.GetAvailableCapacity())
+	}
+	if err := qs.AllocateNode(w3.ID); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("Expected ErrCapacityFull for the weight-3 node, got %v", err)
+	}
+
+	// Completing the weight-4 node frees enough for the weight-3 node.
+	qs.CompleteNode(w4.ID)
+	if err := qs.AllocateNode(w3.ID); err != nil {
+		t.Errorf("Expected weight-3 node to fit after completion, got %v", err)
+	}
+}
+
+func TestQueueService_WeightPersistedAndRestored(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+	n, _ := qs.CreateWeightedNode("", "e1", 3)
+	qs.MoveNode(n.ID, "resource-1")
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	restarted.AddResource(resourcepkg.NewResource("resource-1", 5))
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	got, err := restarted.GetNode(n.ID)
+	if err != nil || got.Weight != 3 {
+		t.Fatalf("Expected restored node with weight 3, err=%v", err)
+	}
+}
+
+func TestQueueService_CreateNodeOnResourcePersistsAssignmentAtomically(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+
+	n, err := qs.CreateNodeOnResource("", "e1", 1, "resource-1", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource failed: %v", err)
+	}
+	if n.ResourceID != "resource-1" {
+		t.Errorf("Expected node on resource-1, got %q", n.ResourceID)
+	}
+
+	nodes, err := store.ListNodes(context.Background())
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf("Expected 1 persisted node, got %d (err=%v)", len(nodes), err)
+	}
+	if nodes[0].ResourceID == nil || *nodes[0].ResourceID != "resource-1" {
+		t.Errorf("Expected persisted resource resource-1, got %v", nodes[0].ResourceID)
+	}
+
+	logs, err := store.ListNodeLogs(context.Background(), []string{n.ID})
+	if err != nil {
+		t.Fatalf("ListNodeLogs failed: %v", err)
+	}
+	rows := logs[n.ID]
+	if len(rows) != 2 || rows[0].Action != "created" || rows[1].Action != "moved_to_waiting_queue" {
+		t.Fatalf("Expected created and moved_to_waiting_queue logs, got %+v", rows)
+	}
+	if rows[1].ResourceID == nil || *rows[1].ResourceID != "resource-1" {
+		t.Errorf("Expected move log on resource-1, got %v", rows[1].ResourceID)
+	}
+}
+
+func TestQueueService_CreateNodeOnMissingResource(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	n, err := qs.CreateNodeOnResource("", "e1", 1, "missing", nil, nil)
+	if !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Fatalf("Expected ErrResourceNotFound, got %v", err)
+	}
+	if n == nil || n.ResourceID != "" {
+		t.Fatalf("Expected the node to be created unassigned, got %+v", n)
+	}
+
+	logs, _ := store.ListNodeLogs(context.Background(), []string{n.ID})
+	if rows := logs[n.ID]; len(rows) != 1 || rows[0].Action != "created" {
+		t.Errorf("Expected only a created log, got %+v", rows)
+	}
+}
+
+func TestQueueService_BulkAction(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	resource1 := resourcepkg.NewResource("resource-1", 1)
+	resource1.AutoPromote = true
+	qs.AddResource(resource1)
+	qs.AddResource(resourcepkg.NewResource("resource-2", 5))
+
+	inService, _ := qs.CreateNode("e1")
+	waiting, _ := qs.CreateNode("e2")
+	done, _ := qs.CreateNode("e3")
+	for _, id := range []string{inService.ID, waiting.ID} {
+		qs.MoveNode(id, "resource-1")
+	}
+	qs.AllocateNode(inService.ID)
+	qs.CompleteNode(done.ID)
+
+	results, err := qs.BulkAction([]string{inService.ID, "non-existent", done.ID}, "cancel", "")
+	if err != nil {
+		t.Fatalf("BulkAction failed: %v", err)
+	}
+	wantCodes := []string{"", queueservicepkg.CodeNodeNotFound, queueservicepkg.CodeNodeCompleted}
+	if len(results) != len(wantCodes) {
+		t.Fatalf("Expected %d results, got %d", len(wantCodes), len(results))
+	}
+	for i, want := range wantCodes {
+		if results[i].OK != (want == "") || results[i].Code != want {
+			t.Errorf("Result %d: expected code %q, got %+v", i, want, results[i])
+		}
+	}
+	// The valid ID was applied despite the failures around it.
+	if n, _ := qs.GetNode(inService.ID); !n.Completed || n.Result == nil || n.Result.Outcome != "cancelled" {
+		t.Errorf("Expected node cancelled, got completed=%v result=%+v", n.Completed, n.Result)
+	}
+	// Freeing the slot auto-promotes the waiting node.
+	if !resource1.IsInService(waiting.ID) {
+		t.Error("Expected waiting node to be auto-promoted after the bulk cancel")
+	}
+
+	results, err = qs.BulkAction([]string{waiting.ID, inService.ID}, "move", "resource-2")
+	if err != nil {
+		t.Fatalf("BulkAction failed: %v", err)
+	}
+	if !results[0].OK || results[1].OK {
+		t.Errorf("Expected only the active node moved, got %+v", results)
+	}
+	if n, _ := qs.GetNode(waiting.ID); n.ResourceID != "resource-2" {
+		t.Errorf("Expected node moved to resource-2, got %q", n.ResourceID)
+	}
+	persisted, _ := store.ListNodes(context.Background())
+	for _, pn := range persisted {
+		if pn.NodeID == waiting.ID && (pn.ResourceID == nil || *pn.ResourceID != "resource-2") {
+			t.Errorf("Expected the move persisted, got %v", pn.ResourceID)
+		}
+		if pn.NodeID == inService.ID {
+			t.Error("Expected the cancel persisted")
+		}
+	}
+
+	if _, err := qs.BulkAction([]string{waiting.ID}, "archive", ""); !errors.Is(err, queueservicepkg.ErrInvalidBulkAction) {
+		t.Errorf("Expected ErrInvalidBulkAction, got %v", err)
+	}
+}
+
+func TestQueueService_UniqueActiveEntity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.UniqueActiveEntity = true
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	first, err := qs.CreateNode("acme")
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	// A second active node for the entity is rejected, naming the existing one.
+	_, err = qs.CreateNodeOnResource("", "acme", 1, "resource-1", nil, nil)
+	var active *queueservicepkg.EntityActiveError
+	if !errors.As(err, &active) || !errors.Is(err, queueservicepkg.ErrEntityActive) {
+		t.Fatalf("Expected EntityActiveError, got %v", err)
+	}
+	if active.NodeID != first.ID {
+		t.Errorf("Expected existing node %s in the error, got %s", first.ID, active.NodeID)
+	}
+	if n := len(qs.ListNodes()); n != 1 {
+		t.Errorf("Expected no node created, got %d nodes", n)
+	}
+
+	// Other entities are unaffected.
+	if _, err := qs.CreateNode("globex"); err != nil {
+		t.Errorf("Expected a different entity to be created, got %v", err)
+	}
+
+	// Once the active node completes, the entity may have a new one.
+	if err := qs.CompleteNode(first.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+	if _, err := qs.CreateNode("acme"); err != nil {
+		t.Errorf("Expected a new node after completion, got %v", err)
+	}
+
+	// Without the mode duplicates are allowed.
+	qs.UniqueActiveEntity = false
+	if _, err := qs.CreateNode("acme"); err != nil {
+		t.Errorf("Expected duplicate allowed with the mode off, got %v", err)
+	}
+}
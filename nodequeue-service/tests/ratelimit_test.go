@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"nodequeue-service/utils"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func doLimited(h http.Handler, path, apiKey, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(utils.APIKeyHeader, apiKey)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_BurstThenRecover(t *testing.T) {
+	h := utils.NewRateLimiter(20, 3).Handler(okHandler())
+
+	for i := 0; i < 3; i++ {
+		if w := doLimited(h, "/nodes", "", "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: expected 200, got %d", i, w.Code)
+		}
+	}
+
+	w := doLimited(h, "/nodes", "", "10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 past the burst, got %d", w.Code)
+	}
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retry < 1 {
+		t.Errorf("expected a positive Retry-After, got %q", w.Header().Get("Retry-After"))
+	}
+	assertErrorCode(t, w, utils.CodeRateLimited)
+
+	// Another client is unaffected
+	if w := doLimited(h, "/nodes", "", "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected a different IP to be allowed, got %d", w.Code)
+	}
+
+	// One token refills every 50ms
+	time.Sleep(60 * time.Millisecond)
+	if w := doLimited(h, "/nodes", "", "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected recovery after refill, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_KeysByAPIKeyAndExemptsHealthz(t *testing.T) {
+	rl := utils.NewRateLimiter(1, 1)
+	rl.SetAPIKeys([]string{"key-a", "key-b"})
+	h := rl.Handler(okHandler())
+
+	// Same IP, different configured API keys: separate buckets
+	if w := doLimited(h, "/nodes", "key-a", "10.0.0.1:1"); w.Code != http.StatusOK {
+		t.Fatalf("expected key-a to be allowed, got %d", w.Code)
+	}
+	if w := doLimited(h, "/nodes", "key-b", "10.0.0.1:1"); w.Code != http.StatusOK {
+		t.Fatalf("expected key-b to be allowed, got %d", w.Code)
+	}
+	if w := doLimited(h, "/nodes", "key-a", "10.0.0.9:1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected key-a to be limited regardless of IP, got %d", w.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		if w := doLimited(h, "/healthz", "key-a", "10.0.0.1:1"); w.Code != http.StatusOK {
+			t.Fatalf("expected /healthz to be exempt, got %d", w.Code)
+		}
+	}
+}
+
+func TestRateLimiter_CleanupDropsIdleClients(t *testing.T) {
+	rl := utils.NewRateLimiter(10, 1)
+	h := rl.Handler(okHandler())
+	doLimited(h, "/nodes", "", "10.0.0.1:1")
+	doLimited(h, "/nodes", "", "10.0.0.2:1")
+
+	if removed := rl.Cleanup(time.Hour); removed != 0 {
+		t.Errorf("expected no active clients to be removed, got %d", removed)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if removed := rl.Cleanup(time.Millisecond); removed != 2 || rl.Clients() != 0 {
+		t.Errorf("expected 2 idle clients removed, got %d (remaining %d)", removed, rl.Clients())
+	}
+}
+
+func TestRateLimiter_UnknownAPIKeysAreKeyedByIP(t *testing.T) {
+	rl := utils.NewRateLimiter(1, 1)
+	rl.SetAPIKeys([]string{"key-a"})
+	h := rl.Handler(okHandler())
+
+	if w := doLimited(h, "/nodes", "", "10.0.0.1:1"); w.Code != http.StatusOK {
+		t.Fatalf("expected the first request to be allowed, got %d", w.Code)
+	}
+	// A fresh, unconfigured key per request does not get a new bucket.
+	for i := 0; i < 5; i++ {
+		if w := doLimited(h, "/nodes", "random-"+strconv.Itoa(i), "10.0.0.1:2"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d with an unknown key: expected 429, got %d", i, w.Code)
+		}
+	}
+	if rl.Clients() != 1 {
+		t.Errorf("expected unknown keys to share the IP's bucket, got %d clients", rl.Clients())
+	}
+}
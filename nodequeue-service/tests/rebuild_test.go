@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestRebuildFromLogs_ReplaysOutOfOrderLogs(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+
+	store := db.NewMemoryStore()
+	for _, id := range []string{"n_svc", "n_late", "n_early", "n_done", "n_gone"} {
+		if err := store.PersistNodeCreated(ctx, id, "entity-"+id, "entity-"+id, 1, at(0)); err != nil {
+			t.Fatalf("PersistNodeCreated(%s): %v", id, err)
+		}
+	}
+	room1, room2 := "Room 1", "Room 2"
+	// Logs written out of timestamp order, e.g. by writers racing each other.
+	logs := []struct {
+		nodeID, action string
+		resourceID     *string
+		ts             int
+	}{
+		{"n_svc", "moved_to_service_queue", &room1, 30},
+		{"n_done", "completed", nil, 40},
+		{"n_late", "moved_to_waiting_queue", &room1, 20},
+		{"n_svc", "moved_to_waiting_queue", &room1, 10},
+		{"n_early", "moved_to_waiting_queue", &room1, 15},
+		{"n_done", "moved_to_waiting_queue", &room2, 5},
+		{"n_early", "reordered", &room1, 25},
+		{"n_gone", "completed", &room2, 35},
+		{"n_gone", "moved_to_waiting_queue", &room2, 5},
+	}
+	for _, l := range logs {
+		if err := store.InsertNodeLog(ctx, l.nodeID, l.action, l.resourceID, at(l.ts)); err != nil {
+			t.Fatalf("InsertNodeLog: %v", err)
+		}
+	}
+	// The summarized columns are wrong and must be ignored.
+	store.UpdateNodeResource(ctx, "n_svc", &room2)
+	store.MarkNodeCompleted(ctx, "n_late", true)
+	store.MarkNodeCompleted(ctx, "n_gone", true)
+
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 5))
+	// A restore trusts those columns: n_svc lands on Room 2 and n_late is left out.
+	if err := qs.RestoreFromStore(ctx); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+	if _, err := qs.GetNode("n_late"); err == nil {
+		t.Fatal("expected the restore to skip n_late")
+	}
+
+	summary, err := qs.RebuildFromLogs()
+	if err != nil {
+		t.Fatalf("RebuildFromLogs: %v", err)
+	}
+	if summary.NodesRestored != 4 || summary.QueuesRebuilt != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	r1, _ := qs.GetResource("Room 1")
+	if got := ids(r1.Nodes); !slices.Equal(got, []string{"n_svc"}) {
+		t.Errorf("expected Room 1 service [n_svc], got %v", got)
+	}
+	if got := ids(r1.WaitingQueue); !slices.Equal(got, []string{"n_early", "n_late"}) {
+		t.Errorf("expected Room 1 waiting [n_early n_late], got %v", got)
+	}
+	r2, _ := qs.GetResource("Room 2")
+	if len(r2.Nodes) != 0 || len(r2.WaitingQueue) != 0 {
+		t.Errorf("expected Room 2 empty, got %v / %v", ids(r2.Nodes), ids(r2.WaitingQueue))
+	}
+	if _, err := qs.GetNode("n_gone"); err == nil {
+		t.Error("expected a completed node that was not in memory to stay out")
+	}
+	// n_done was restored as waiting on Room 2; its log says it completed.
+	if done, err := qs.GetNode("n_done"); err != nil || !done.Completed || done.ResourceID != "" {
+		t.Errorf("expected n_done completed and unassigned, got %+v (err=%v)", done, err)
+	}
+
+	svc, _ := qs.GetNode("n_svc")
+	actions := make([]string, 0, len(svc.Log))
+	for _, entry := range svc.Log {
+		actions = append(actions, entry.Action)
+	}
+	if !slices.Equal(actions, []string{"moved_to_waiting_queue", "moved_to_service_queue"}) {
+		t.Errorf("expected the log replayed in timestamp order, got %v", actions)
+	}
+	if svc.ResourceID != "Room 1" || svc.Completed {
+		t.Errorf("expected n_svc active on Room 1, got %q completed=%v", svc.ResourceID, svc.Completed)
+	}
+	if violations := qs.CheckConsistency(); len(violations) != 0 {
+		t.Errorf("expected a consistent rebuild, got %+v", violations)
+	}
+}
+
+func TestRebuildFromLogs_OrphanedClearsAssignment(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	room9 := "Room 9"
+
+	store := db.NewMemoryStore()
+	if err := store.PersistNodeCreatedWithResource(ctx, "n_orphan", "entity", "entity", 1, base, room9, base); err != nil {
+		t.Fatalf("PersistNodeCreatedWithResource: %v", err)
+	}
+	if err := store.InsertNodeLog(ctx, "n_orphan", "orphaned", nil, base.Add(time.Second)); err != nil {
+		t.Fatalf("InsertNodeLog: %v", err)
+	}
+
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+	if _, err := qs.RebuildFromLogs(); err != nil {
+		t.Fatalf("RebuildFromLogs: %v", err)
+	}
+
+	n, err := qs.GetNode("n_orphan")
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if n.ResourceID != "" || n.Completed {
+		t.Errorf("expected the orphaned node active and unassigned, got resource %q completed=%v", n.ResourceID, n.Completed)
+	}
+	if violations := qs.CheckConsistency(); len(violations) != 0 {
+		t.Errorf("expected a consistent rebuild, got %+v", violations)
+	}
+}
+
+func TestRebuildFromLogsHandler_RequiresStore(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	req := httptest.NewRequest(http.MethodPost, "/admin/rebuild", nil)
+	w := httptest.NewRecorder()
+	qs.RebuildFromLogsHandler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeStoreUnavailable)
+}
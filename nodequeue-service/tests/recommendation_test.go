@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// seedServedNode persists a completed node that waited on rid for wait and was then in service
+// for service, finishing at end.
+func seedServedNode(t *testing.T, store *db.MemoryStore, id, rid string, end time.Time, wait, service time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	served := end.Add(-service)
+	queued := served.Add(-wait)
+	if err := store.PersistNodeCreated(ctx, id, id, "entity", 1, queued); err != nil {
+		t.Fatalf("PersistNodeCreated: %v", err)
+	}
+	for _, row := range []struct {
+		action string
+		at     time.Time
+	}{
+		{"moved_to_waiting_queue", queued},
+		{"moved_to_service_queue", served},
+		{"completed", end},
+	} {
+		if err := store.InsertNodeLog(ctx, id, row.action, &rid, row.at); err != nil {
+			t.Fatalf("InsertNodeLog: %v", err)
+		}
+	}
+	if err := store.MarkNodeCompleted(ctx, id, false); err != nil {
+		t.Fatalf("MarkNodeCompleted: %v", err)
+	}
+}
+
+func getRecommendation(t *testing.T, qs *queueservicepkg.QueueService, rid, query string) queueservicepkg.CapacityRecommendation {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/resources/"+url.PathEscape(rid)+"/recommendation"+query, nil)
+	w := httptest.NewRecorder()
+	qs.RecommendationHandler(w, req, rid)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var rec queueservicepkg.CapacityRecommendation
+	if err := json.NewDecoder(w.Body).Decode(&rec); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return rec
+}
+
+func TestRecommendation_HighWaitSuggestsIncrease(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+
+	now := time.Now()
+	for i, id := range []string{"n1", "n2", "n3", "n4", "n5", "n6", "n7", "n8", "n9", "n10"} {
+		seedServedNode(t, store, id, "Room 1", now.Add(-time.Duration(i)*time.Minute), 10*time.Minute, 2*time.Minute)
+	}
+	// Outside the window: must not count.
+	seedServedNode(t, store, "old", "Room 1", now.Add(-3*time.Hour), time.Hour, time.Minute)
+
+	rec := getRecommendation(t, qs, "Room 1", "")
+	if rec.Stats.WaitSamples != 10 {
+		t.Errorf("expected 10 wait samples, got %d", rec.Stats.WaitSamples)
+	}
+	if rec.Stats.P90WaitMS != (10 * time.Minute).Milliseconds() {
+		t.Errorf("expected p90 of 10m, got %dms", rec.Stats.P90WaitMS)
+	}
+	// p90 is twice the 5m target: double the capacity.
+	if rec.Action != queueservicepkg.RecommendIncrease || rec.Delta != 2 || rec.RecommendedCapacity != 4 {
+		t.Errorf("expected increase by 2 to 4, got %s by %d to %d", rec.Action, rec.Delta, rec.RecommendedCapacity)
+	}
+	if rec.CurrentCapacity != 2 || rec.Rationale == "" {
+		t.Errorf("expected current capacity and a rationale, got %+v", rec)
+	}
+
+	// A looser target turns the same history into no change; the resource did fill up, so it is
+	// not a decrease either.
+	if rec := getRecommendation(t, qs, "Room 1", "?target_p90_wait=15m"); rec.Action != queueservicepkg.RecommendNone {
+		t.Errorf("expected no change against a 15m target, got %s (%s)", rec.Action, rec.Rationale)
+	}
+}
+
+func TestRecommendation_LowUtilizationSuggestsDecrease(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 10))
+
+	// Three back-to-back 5m services with no wait: one unit busy for 15 of 60 minutes.
+	now := time.Now()
+	for i, id := range []string{"n1", "n2", "n3"} {
+		seedServedNode(t, store, id, "Room 1", now.Add(-time.Duration(i*5)*time.Minute), 0, 5*time.Minute)
+	}
+
+	rec := getRecommendation(t, qs, "Room 1", "")
+	if rec.Stats.PeakInService != 1 {
+		t.Errorf("expected peak of 1 in service, got %d", rec.Stats.PeakInService)
+	}
+	if u := rec.Stats.Utilization; u < 0.02 || u > 0.03 {
+		t.Errorf("expected utilization of 15/600, got %v", u)
+	}
+	if rec.Action != queueservicepkg.RecommendDecrease || rec.Delta != -9 || rec.RecommendedCapacity != 1 {
+		t.Errorf("expected decrease by 9 to 1, got %s by %d to %d", rec.Action, rec.Delta, rec.RecommendedCapacity)
+	}
+}
+
+func TestRecommendationHandler_Errors(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+
+	req := httptest.NewRequest(http.MethodGet, "/resources/missing/recommendation", nil)
+	w := httptest.NewRecorder()
+	qs.RecommendationHandler(w, req, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/resources/Room%201/recommendation?window=soon", nil)
+	w = httptest.NewRecorder()
+	qs.RecommendationHandler(w, req, "Room 1")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+
+	// No history at all: nothing to recommend.
+	if rec := getRecommendation(t, qs, "Room 1", ""); rec.Action != queueservicepkg.RecommendNone || rec.RecommendedCapacity != 2 {
+		t.Errorf("expected no change without history, got %+v", rec)
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestSetResourceRedirect_RedirectsMovesUntilCleared(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 2))
+	qs.PauseResource("Room 1")
+
+	if err := qs.SetResourceRedirect("Room 1", "Room 2"); err != nil {
+		t.Fatalf("SetResourceRedirect: %v", err)
+	}
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, "Room 1"); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	got, _ := qs.GetNode(n.ID)
+	if got.ResourceID != "Room 2" {
+		t.Fatalf("expected the move to land on Room 2, got %q", got.ResourceID)
+	}
+	room2, _ := qs.GetResource("Room 2")
+	if !room2.IsWaiting(n.ID) {
+		t.Error("expected the node waiting on Room 2")
+	}
+	logs := got.Log
+	if len(logs) < 2 {
+		t.Fatalf("expected redirected and move entries, got %+v", logs)
+	}
+	redirected, moved := logs[len(logs)-2], logs[len(logs)-1]
+	if redirected.Action != "redirected" || redirected.ResourceID != "Room 1" {
+		t.Errorf("expected a redirected entry noting Room 1, got %+v", redirected)
+	}
+	if moved.Action != "moved_to_waiting_queue" || moved.ResourceID != "Room 2" {
+		t.Errorf("expected a move onto Room 2, got %+v", moved)
+	}
+	// Room 2 itself is not redirected.
+	if err := qs.MoveNode(n.ID, "Room 2"); err != nil {
+		t.Fatalf("MoveNode(Room 2): %v", err)
+	}
+
+	if err := qs.SetResourceRedirect("Room 1", ""); err != nil {
+		t.Fatalf("clearing the redirect: %v", err)
+	}
+	if err := qs.MoveNode(n.ID, "Room 1"); err != nil {
+		t.Fatalf("MoveNode after clearing: %v", err)
+	}
+	got, _ = qs.GetNode(n.ID)
+	if got.ResourceID != "Room 1" {
+		t.Errorf("expected the node on Room 1 once the redirect is cleared, got %q", got.ResourceID)
+	}
+	if prev := got.Log[len(got.Log)-2]; prev.Action == "redirected" {
+		t.Errorf("expected no redirected entry after clearing, got %+v", got.Log)
+	}
+}
+
+func TestSetResourceRedirect_Errors(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+
+	if err := qs.SetResourceRedirect("Room 9", "Room 1"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("unknown source: expected ErrResourceNotFound, got %v", err)
+	}
+	if err := qs.SetResourceRedirect("Room 1", "Room 9"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("unknown target: expected ErrResourceNotFound, got %v", err)
+	}
+	if err := qs.SetResourceRedirect("Room 1", "Room 1"); !errors.Is(err, queueservicepkg.ErrSameResource) {
+		t.Errorf("self redirect: expected ErrSameResource, got %v", err)
+	}
+}
+
+func TestSetResourceRedirectHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 2))
+
+	post := func(body string) queueservicepkg.ResourceDetailResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/resources/Room%201/redirect", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.SetResourceRedirectHandler(w, req, "Room 1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var detail queueservicepkg.ResourceDetailResponse
+		if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return detail
+	}
+
+	if detail := post(`{"to": "Room 2"}`); detail.RedirectTo != "Room 2" {
+		t.Errorf("expected redirect_to Room 2, got %q", detail.RedirectTo)
+	}
+	if detail := post(`{"to": ""}`); detail.RedirectTo != "" {
+		t.Errorf("expected the redirect cleared, got %q", detail.RedirectTo)
+	}
+}
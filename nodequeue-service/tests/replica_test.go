@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// recordingDB is a database/sql driver that records every statement it is sent and answers each
+// query with no rows.
+type recordingDB struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (d *recordingDB) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, query)
+}
+
+func (d *recordingDB) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.statements)
+}
+
+func (d *recordingDB) Connect(context.Context) (driver.Conn, error) { return recordingConn{d}, nil }
+func (d *recordingDB) Driver() driver.Driver                        { return nil }
+
+type recordingConn struct{ d *recordingDB }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+func (c recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(query)
+	return emptyRows{}, nil
+}
+
+func (c recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+func openRecordingDB(t *testing.T) (*recordingDB, *sql.DB) {
+	t.Helper()
+	d := &recordingDB{}
+	conn := sql.OpenDB(d)
+	t.Cleanup(func() { conn.Close() })
+	return d, conn
+}
+
+func TestPostgresStoreWithReplica_RoutesReadsAndWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, primaryConn := openRecordingDB(t)
+	replica, replicaConn := openRecordingDB(t)
+	store := db.NewPostgresStoreWithReplica(primaryConn, replicaConn)
+
+	reads := map[string]func(ctx context.Context) error{
+		"ListResources": func(ctx context.Context) error { _, err := store.ListResources(ctx); return err },
+		"ListNodes":     func(ctx context.Context) error { _, err := store.ListNodes(ctx); return err },
+		"ListAllNodes":  func(ctx context.Context) error { _, err := store.ListAllNodes(ctx); return err },
+		"ListLatestNodeStates": func(ctx context.Context) error {
+			_, err := store.ListLatestNodeStates(ctx)
+			return err
+		},
+		"ListNodeLogs":   func(ctx context.Context) error { _, err := store.ListNodeLogs(ctx, []string{"n1"}); return err },
+		"ListNodeExtras": func(ctx context.Context) error { _, err := store.ListNodeExtras(ctx, []string{"n1"}); return err },
+		"EachNodeLog": func(ctx context.Context) error {
+			return store.EachNodeLog(ctx, db.NodeLogQuery{}, func(db.NodeLogRow) error { return nil })
+		},
+		"ListArchivedNodes": func(ctx context.Context) error {
+			_, err := store.ListArchivedNodes(ctx, db.ArchiveQuery{})
+			return err
+		},
+	}
+	for name, read := range reads {
+		before := replica.count()
+		if err := read(ctx); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if replica.count() == before {
+			t.Errorf("%s did not query the replica", name)
+		}
+	}
+	if n := primary.count(); n != 0 {
+		t.Errorf("expected no reads on the primary, got %d: %v", n, primary.statements)
+	}
+
+	now := time.Now()
+	writes := map[string]func() error{
+		"InsertResource":     func() error { return store.InsertResource(ctx, resourcepkg.NewResource("Room 1", 2)) },
+		"PersistNodeCreated": func() error { return store.PersistNodeCreated(ctx, "n1", "e1", "e1", 1, now) },
+		"InsertNodeLog":      func() error { return store.InsertNodeLog(ctx, "n1", "created", nil, now) },
+		"MarkNodeCompleted":  func() error { return store.MarkNodeCompleted(ctx, "n1", true) },
+	}
+	replicaReads := replica.count()
+	for name, write := range writes {
+		before := primary.count()
+		if err := write(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if primary.count() == before {
+			t.Errorf("%s did not reach the primary", name)
+		}
+	}
+	if replica.count() != replicaReads {
+		t.Errorf("writes reached the replica: %v", replica.statements[replicaReads:])
+	}
+
+	// Forced primary reads skip the replica.
+	if _, err := store.ListNodes(db.WithPrimaryReads(ctx)); err != nil {
+		t.Fatalf("ListNodes: %v", err)
+	}
+	if replica.count() != replicaReads {
+		t.Error("forced primary read queried the replica")
+	}
+}
+
+func TestPostgresStoreWithReplica_FallsBackToPrimary(t *testing.T) {
+	primary, primaryConn := openRecordingDB(t)
+	store := db.NewPostgresStoreWithReplica(primaryConn, nil)
+	if _, err := store.ListNodes(context.Background()); err != nil {
+		t.Fatalf("ListNodes: %v", err)
+	}
+	if primary.count() != 1 {
+		t.Errorf("expected the read on the primary, got %v", primary.statements)
+	}
+}
+
+func TestRestoreFromStore_ReadsFromPrimary(t *testing.T) {
+	primary, primaryConn := openRecordingDB(t)
+	replica, replicaConn := openRecordingDB(t)
+	qs := queueservicepkg.NewQueueServiceWithStore(db.NewPostgresStoreWithReplica(primaryConn, replicaConn))
+
+	if err := qs.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+	if primary.count() == 0 {
+		t.Error("expected restore to read from the primary")
+	}
+	if n := replica.count(); n != 0 {
+		t.Errorf("expected restore not to touch the replica, got %v", replica.statements)
+	}
+}
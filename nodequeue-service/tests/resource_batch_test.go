@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func postResourcesBatch(t *testing.T, qs *queueservicepkg.QueueService, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/resources/batch", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	qs.CreateResourcesHandler(w, req)
+	return w
+}
+
+func TestCreateResourcesHandler_DuplicatesPartiallySucceed(t *testing.T) {
+	var calls []storeCall
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(db.NewInstrumentedStore(store, recordCalls(&calls)))
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+
+	w := postResourcesBatch(t, qs, `[
+		{"id": "Room 4", "capacity": 2},
+		{"id": "Room 1", "capacity": 3},
+		{"id": "Room 5", "capacity": 3, "max_per_entity": 1},
+		{"id": "Room 4", "capacity": 1}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.CreateResourcesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 2 || len(resp.Results) != 4 {
+		t.Fatalf("expected 2 succeeded and 2 failed, got %+v", resp)
+	}
+	for i, want := range []struct {
+		id   string
+		ok   bool
+		code string
+	}{
+		{"Room 4", true, ""},
+		{"Room 1", false, queueservicepkg.CodeResourceExists},
+		{"Room 5", true, ""},
+		{"Room 4", false, queueservicepkg.CodeResourceExists},
+	} {
+		got := resp.Results[i]
+		if got.ID != want.id || got.OK != want.ok || got.Code != want.code {
+			t.Errorf("result %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// The first Room 4 wins, the existing Room 1 is untouched, and options are applied.
+	if r, _ := qs.GetResource("Room 4"); r == nil || r.Capacity != 2 {
+		t.Errorf("expected Room 4 with capacity 2, got %+v", r)
+	}
+	if r, _ := qs.GetResource("Room 1"); r == nil || r.Capacity != 5 {
+		t.Errorf("expected Room 1 to keep capacity 5, got %+v", r)
+	}
+	if r, _ := qs.GetResource("Room 5"); r == nil || r.MaxPerEntity != 1 {
+		t.Errorf("expected Room 5 with max_per_entity 1, got %+v", r)
+	}
+
+	// Both new resources were persisted in a single store call.
+	if len(calls) != 1 || calls[0].op != "InsertResources" {
+		t.Errorf("expected one InsertResources call, got %+v", calls)
+	}
+	persisted, err := store.ListResources(context.Background())
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if len(persisted) != 2 {
+		t.Errorf("expected Room 4 and Room 5 persisted, got %d resources", len(persisted))
+	}
+}
+
+func TestCreateResourcesHandler_InvalidBatch(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for _, body := range []string{
+		`[]`,
+		`{"id": "Room 4", "capacity": 2}`,
+		`[{"id": "Room 4", "capacity": 2}, {"id": "Room 5", "capacity": 0}]`,
+	} {
+		w := postResourcesBatch(t, qs, body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+			continue
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+	// A rejected batch creates nothing, not even its valid items.
+	if _, err := qs.GetResource("Room 4"); err == nil {
+		t.Error("expected no resources created from an invalid batch")
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestCloneResourceHandler_CopiesConfigNotQueues(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	src := resourcepkg.NewResource("Room 1", 3)
+	src.AutoPromote = true
+	src.MaxPerEntity = 2
+	src.FIFOStrict = true
+	src.PressureWaiting = 4
+	src.PressureSeconds = 30
+	src.MaxWaitMS = 60000
+	src.LaneOrder = []string{"priority", "standard"}
+	src.ReservedForPriority = 1
+	src.Labels = map[string]string{"region": "eu"}
+	qs.AddResource(src)
+	src.SetPaused(true)
+	src.Reserve("held", time.Now().Add(time.Minute))
+	for _, id := range []string{"a", "b"} {
+		if _, err := qs.CreateNodeOnResource(id, "entity-"+id, 1, "Room 1", nil, nil); err != nil {
+			t.Fatalf("CreateNodeOnResource(%s): %v", id, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/resources/Room%201/clone", bytes.NewBufferString(`{"new_id": "Room 2"}`))
+	w := httptest.NewRecorder()
+	qs.CloneResourceHandler(w, req, "Room 1")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		ID                  string            `json:"id"`
+		Capacity            int               `json:"capacity"`
+		WaitingQueue        []json.RawMessage `json:"waiting_queue"`
+		Nodes               []json.RawMessage `json:"nodes"`
+		Paused              bool              `json:"paused"`
+		MaxWaitMS           int64             `json:"max_wait_ms"`
+		ReservedForPriority int               `json:"reserved_for_priority"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "Room 2" || resp.Capacity != 3 || resp.MaxWaitMS != 60000 || resp.ReservedForPriority != 1 {
+		t.Errorf("expected Room 2 with the source's config, got %+v", resp)
+	}
+	if len(resp.WaitingQueue) != 0 || len(resp.Nodes) != 0 || resp.Paused {
+		t.Errorf("expected empty, unpaused queues, got %+v", resp)
+	}
+
+	clone, err := qs.GetResource("Room 2")
+	if err != nil {
+		t.Fatalf("GetResource: %v", err)
+	}
+	if !clone.AutoPromote || clone.MaxPerEntity != 2 || !clone.FIFOStrict ||
+		clone.PressureWaiting != 4 || clone.PressureSeconds != 30 ||
+		!slices.Equal(clone.LaneOrder, src.LaneOrder) || clone.Labels["region"] != "eu" {
+		t.Errorf("expected config copied from Room 1, got %+v", clone)
+	}
+	if clone.ActiveReservations() != 0 {
+		t.Errorf("expected no reservations copied, got %d", clone.ActiveReservations())
+	}
+	// The clone's lane order is its own.
+	clone.LaneOrder[0] = "changed"
+	if src.LaneOrder[0] != "priority" {
+		t.Errorf("expected the source's lanes untouched, got %v", src.LaneOrder)
+	}
+	if service, waiting := src.QueueSnapshot(); len(service)+len(waiting) != 2 {
+		t.Errorf("expected the source to keep its 2 nodes, got %d", len(service)+len(waiting))
+	}
+}
+
+func TestCloneResourceHandler_Errors(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("Room 1", 3))
+	qs.AddResource(resourcepkg.NewResource("Room 2", 1))
+
+	for _, tc := range []struct {
+		name   string
+		source string
+		body   string
+		status int
+		code   string
+	}{
+		{"existing new_id", "Room 1", `{"new_id": "Room 2"}`, http.StatusConflict, queueservicepkg.CodeResourceExists},
+		{"missing source", "Room 9", `{"new_id": "Room 3"}`, http.StatusNotFound, queueservicepkg.CodeResourceNotFound},
+		{"blank new_id", "Room 1", `{"new_id": " "}`, http.StatusBadRequest, queueservicepkg.CodeInvalidRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/resources/x/clone", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+			qs.CloneResourceHandler(w, req, tc.source)
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d", tc.status, w.Code)
+			}
+			assertErrorCode(t, w, tc.code)
+		})
+	}
+	if r, _ := qs.GetResource("Room 2"); r.Capacity != 1 {
+		t.Errorf("expected Room 2 untouched, got capacity %d", r.Capacity)
+	}
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestCanonicalResourceID(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		fold     bool
+	}{
+		{"Room 1", "Room 1", false},
+		{"  Room \t 1\n", "Room 1", false},
+		{"ROOM 1", "ROOM 1", false},
+		{" ROOM  1", "room 1", true},
+	} {
+		got, err := resourcepkg.CanonicalResourceID(tc.in, tc.fold)
+		if err != nil || got != tc.want {
+			t.Errorf("CanonicalResourceID(%q, %v) = %q, %v; want %q", tc.in, tc.fold, got, err, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "   ", strings.Repeat("r", resourcepkg.MaxIDLength+1)} {
+		if _, err := resourcepkg.CanonicalResourceID(in, false); !errors.Is(err, resourcepkg.ErrInvalidResourceID) {
+			t.Errorf("CanonicalResourceID(%q): expected ErrInvalidResourceID, got %v", in, err)
+		}
+	}
+}
+
+func TestResourceIDs_WhitespaceVariantsNameOneResource(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("  Room   1 ", 2))
+
+	if _, err := qs.GetResource("Room 1"); err != nil {
+		t.Fatalf("expected the resource registered as Room 1: %v", err)
+	}
+	if err := qs.CreateResource(resourcepkg.NewResource("Room 1\t", 3)); !errors.Is(err, queueservicepkg.ErrResourceExists) {
+		t.Errorf("expected a whitespace variant to collide, got %v", err)
+	}
+	if err := qs.CreateResource(resourcepkg.NewResource(" ", 3)); !errors.Is(err, resourcepkg.ErrInvalidResourceID) {
+		t.Errorf("expected a blank ID to be rejected, got %v", err)
+	}
+
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, " Room 1 "); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	if got, _ := qs.GetNode(n.ID); got.ResourceID != "Room 1" {
+		t.Errorf("expected the node on Room 1, got %q", got.ResourceID)
+	}
+
+	// Case still matters by default.
+	if err := qs.MoveNode(n.ID, "room 1"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+		t.Errorf("expected room 1 to be a different (missing) resource, got %v", err)
+	}
+}
+
+func TestResourceIDs_CaseInsensitive(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.FoldResourceIDCase = true
+	if err := qs.CreateResource(resourcepkg.NewResource("Room 1", 2)); err != nil {
+		t.Fatalf("CreateResource: %v", err)
+	}
+	if err := qs.CreateResource(resourcepkg.NewResource("ROOM 1", 2)); !errors.Is(err, queueservicepkg.ErrResourceExists) {
+		t.Errorf("expected a case variant to collide, got %v", err)
+	}
+
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, "rOoM  1"); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	if got, _ := qs.GetNode(n.ID); got.ResourceID != "room 1" {
+		t.Errorf("expected the node on room 1, got %q", got.ResourceID)
+	}
+
+	// The batch endpoint reports a case variant within the batch as a duplicate.
+	req := httptest.NewRequest(http.MethodPost, "/resources/batch",
+		bytes.NewBufferString(`[{"id": "Room 2", "capacity": 1}, {"id": "room 2 ", "capacity": 1}]`))
+	w := httptest.NewRecorder()
+	qs.CreateResourcesHandler(w, req)
+	if !strings.Contains(w.Body.String(), `"succeeded":1`) {
+		t.Errorf("expected one of the two variants created, got %s", w.Body.String())
+	}
+}
+
+func TestFillResource_WhitespaceAndCaseVariants(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.FoldResourceIDCase = true
+	qs.AddResource(resourcepkg.NewResource("Room 1", 1))
+	for _, name := range []string{"e1", "e2", "e3"} {
+		n, _ := qs.CreateNode(name)
+		if err := qs.MoveNode(n.ID, "room 1"); err != nil {
+			t.Fatalf("MoveNode: %v", err)
+		}
+	}
+
+	allocated, err := qs.FillResource(" ROOM  1 ")
+	if err != nil || len(allocated) != 1 {
+		t.Fatalf("FillResource: expected 1 allocated, got %v, %v", allocated, err)
+	}
+
+	qs.AddResource(resourcepkg.NewResource("Room 2", 2))
+	for _, name := range []string{"e4", "e5"} {
+		n, _ := qs.CreateNode(name)
+		if err := qs.MoveNode(n.ID, "room 2"); err != nil {
+			t.Fatalf("MoveNode: %v", err)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/resources/ROOM%202/fill", nil)
+	w := httptest.NewRecorder()
+	qs.FillResourceHandler(w, req, "\tROOM 2 ")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.FillResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ResourceID != "room 2" || len(resp.Allocated) != 2 || resp.Waiting != 0 {
+		t.Errorf("expected 2 allocated on room 2 and none waiting, got %+v", resp)
+	}
+}
+
+func TestLoadResources_NormalizesIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(path, []byte("Name,Capacity\n Room  1 ,2\nRoom 1,3\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, _, err := resourcepkg.LoadResourcesWithOptions(path, resourcepkg.LoadOptions{Strict: true})
+	var cfgErr *resourcepkg.ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 1 || !strings.Contains(cfgErr.Problems[0].Reason, "duplicate") {
+		t.Fatalf("expected the whitespace variant reported as a duplicate, got %v", err)
+	}
+
+	resources, _, err := resourcepkg.LoadResourcesWithOptions(path, resourcepkg.LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadResourcesWithOptions: %v", err)
+	}
+	if len(resources) == 0 || resources[0].ID != "Room 1" {
+		t.Errorf("expected Room 1 loaded, got %+v", resources)
+	}
+
+	// With FoldIDCase, rows differing only in case are duplicates too.
+	if err := os.WriteFile(path, []byte("Name,Capacity\nRoom 1,2\nROOM 1,3\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	_, _, err = resourcepkg.LoadResourcesWithOptions(path, resourcepkg.LoadOptions{Strict: true, FoldIDCase: true})
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 1 || !strings.Contains(cfgErr.Problems[0].Reason, "duplicate") {
+		t.Errorf("expected the case variant reported as a duplicate, got %v", err)
+	}
+}
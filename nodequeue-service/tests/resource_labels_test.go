@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"nodequeue-service/db"
+	nodepkg "nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// newLabelledService returns a service with three resources: Room 1 (region=eu, hw=gpu),
+// Room 2 (region=eu) and Room 3 (region=us).
+func newLabelledService(t *testing.T, store db.Store) *queueservicepkg.QueueService {
+	t.Helper()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	for _, spec := range []struct {
+		id     string
+		labels map[string]string
+	}{
+		{"Room 1", map[string]string{"region": "eu", "hw": "gpu"}},
+		{"Room 2", map[string]string{"region": "eu"}},
+		{"Room 3", map[string]string{"region": "us"}},
+	} {
+		r := resourcepkg.NewResource(spec.id, 2)
+		r.Labels = spec.labels
+		if err := qs.CreateResource(r); err != nil {
+			t.Fatalf("CreateResource(%s): %v", spec.id, err)
+		}
+	}
+	return qs
+}
+
+func listResourceIDs(t *testing.T, qs *queueservicepkg.QueueService, query string) (*httptest.ResponseRecorder, []string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	qs.ListResourcesHandler(w, httptest.NewRequest(http.MethodGet, "/resources"+query, nil))
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+	var resp []struct {
+		ID     string            `json:"id"`
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	ids := make([]string, 0, len(resp))
+	for _, r := range resp {
+		ids = append(ids, r.ID)
+	}
+	return w, ids
+}
+
+func TestListResourcesHandler_LabelFilter(t *testing.T) {
+	qs := newLabelledService(t, nil)
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Room 1", "Room 2", "Room 3"}},
+		{"?label=region=eu", []string{"Room 1", "Room 2"}},
+		{"?label=region=eu&label=hw=gpu", []string{"Room 1"}},
+		{"?label=region=eu&sort=id&order=desc", []string{"Room 2", "Room 1"}},
+		{"?label=region=ap", []string{}},
+		{"?label=hw=", []string{}},
+	} {
+		w, ids := listResourceIDs(t, qs, tc.query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %s", tc.query, w.Code, w.Body.String())
+		}
+		if !slices.Equal(ids, tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.query, tc.want, ids)
+		}
+	}
+
+	for _, query := range []string{"?label=region", "?label==eu"} {
+		w, _ := listResourceIDs(t, qs, query)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+}
+
+func TestCreateResourceHandler_LabelsPersisted(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	body := `{"id": "Room 1", "capacity": 2, "labels": {"region": "eu"}}`
+	w := httptest.NewRecorder()
+	qs.CreateResourceHandler(w, httptest.NewRequest(http.MethodPost, "/resources", bytes.NewBufferString(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Labels["region"] != "eu" {
+		t.Errorf("expected labels in the response, got %v", resp.Labels)
+	}
+
+	persisted, err := store.ListResources(context.Background())
+	if err != nil || len(persisted) != 1 {
+		t.Fatalf("ListResources: %v, %d resources", err, len(persisted))
+	}
+	if !maps.Equal(persisted[0].Labels, map[string]string{"region": "eu"}) {
+		t.Errorf("expected persisted labels, got %v", persisted[0].Labels)
+	}
+
+	for _, body := range []string{
+		`{"id": "Room 2", "capacity": 2, "labels": {"": "eu"}}`,
+		`{"id": "Room 2", "capacity": 2, "labels": {"a=b": "eu"}}`,
+		`{"id": "Room 2", "capacity": 2, "labels": {"region": "eu;us"}}`,
+	} {
+		w := httptest.NewRecorder()
+		qs.CreateResourceHandler(w, httptest.NewRequest(http.MethodPost, "/resources", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestPatchResourceHandler_MergesLabels(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := newLabelledService(t, store)
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		qs.PatchResourceHandler(w, httptest.NewRequest(http.MethodPatch, "/resources/"+url.PathEscape(id), bytes.NewBufferString(body)), id)
+		return w
+	}
+
+	w := patch("Room 1", `{"labels": {"region": "us", "hw": null, "tier": "gold"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := map[string]string{"region": "us", "tier": "gold"}
+	var resp struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !maps.Equal(resp.Labels, want) {
+		t.Errorf("expected labels %v, got %v", want, resp.Labels)
+	}
+	if _, ids := listResourceIDs(t, qs, "?label=region=us"); !slices.Equal(ids, []string{"Room 1", "Room 3"}) {
+		t.Errorf("expected the patch to change filtering, got %v", ids)
+	}
+
+	persisted, err := store.ListResources(context.Background())
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if !maps.Equal(persisted[0].Labels, want) {
+		t.Errorf("expected persisted labels %v, got %v", want, persisted[0].Labels)
+	}
+
+	if w := patch("Room 9", `{"labels": {"region": "us"}}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown resource, got %d", w.Code)
+	}
+	for _, body := range []string{`{}`, `{"labels": {"bad;key": "x"}}`} {
+		w := patch("Room 1", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+		assertErrorCode(t, w, queueservicepkg.CodeInvalidRequest)
+	}
+}
+
+func TestCreateNodeHandler_ResourceLabelsConstrainPlacement(t *testing.T) {
+	qs := newLabelledService(t, nil)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		qs.CreateNodeHandler(w, httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := create(`{"entity_name": "job", "resource_labels": {"region": "eu"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created nodepkg.Node
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !slices.Equal(created.AllowedResources, []string{"Room 1", "Room 2"}) {
+		t.Errorf("expected the eu resources as allowed_resources, got %v", created.AllowedResources)
+	}
+	if err := qs.MoveNode(created.ID, "Room 3"); !errors.Is(err, queueservicepkg.ErrResourceNotAllowed) {
+		t.Errorf("expected a move to a us resource to fail with ErrResourceNotAllowed, got %v", err)
+	}
+	if err := qs.MoveNode(created.ID, "Room 2"); err != nil {
+		t.Errorf("expected a move to an eu resource to succeed, got %v", err)
+	}
+
+	// Intersected with allowed_resources.
+	w = create(`{"entity_name": "job", "resource_labels": {"region": "eu"}, "allowed_resources": ["Room 2", "Room 3"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !slices.Equal(created.AllowedResources, []string{"Room 2"}) {
+		t.Errorf("expected allowed_resources [Room 2], got %v", created.AllowedResources)
+	}
+
+	w = create(`{"entity_name": "job", "resource_id": "Room 1", "resource_labels": {"hw": "gpu"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a matching resource_id, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		body string
+		code string
+	}{
+		{`{"entity_name": "job", "resource_labels": {"region": "ap"}}`, queueservicepkg.CodeNoMatchingResources},
+		{`{"entity_name": "job", "resource_labels": {"region": "us"}, "allowed_resources": ["Room 1"]}`, queueservicepkg.CodeNoMatchingResources},
+		{`{"entity_name": "job", "resource_id": "Room 3", "resource_labels": {"region": "eu"}}`, queueservicepkg.CodeResourceNotAllowed},
+	} {
+		before := len(qs.ListNodes())
+		w := create(tc.body)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", tc.body, w.Code, w.Body.String())
+		}
+		assertErrorCode(t, w, tc.code)
+		if after := len(qs.ListNodes()); after != before {
+			t.Errorf("%s: expected no node to be created, got %d more", tc.body, after-before)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func postRelease(qs *queueservicepkg.QueueService, resourceID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	qs.ReleaseResourceHandler(w, httptest.NewRequest(http.MethodPost, "/resources/"+resourceID+"/release", nil), resourceID)
+	return w
+}
+
+func TestReleaseResourceHandler_FreesAllCapacity(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	res := resourcepkg.NewResource("resource-1", 4)
+	res.AutoPromote = true
+	qs.AddResource(res)
+
+	var service []string
+	for _, spec := range []struct {
+		entity string
+		weight int
+	}{{"a", 1}, {"b", 2}, {"c", 1}} {
+		n, err := qs.CreateNodeOnResource("", spec.entity, spec.weight, "resource-1", nil, nil)
+		if err != nil {
+			t.Fatalf("CreateNodeOnResource(%s): %v", spec.entity, err)
+		}
+		if err := qs.AllocateNode(n.ID); err != nil {
+			t.Fatalf("AllocateNode(%s): %v", spec.entity, err)
+		}
+		service = append(service, n.ID)
+	}
+	waiting, _ := qs.CreateNodeOnResource("", "d", 1, "resource-1", nil, nil)
+	if got := res.GetAvailableCapacity(); got != 0 {
+		t.Fatalf("expected a full resource before release, got %d available", got)
+	}
+
+	w := postRelease(qs, "resource-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.ReleaseResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Released != 3 || resp.ResourceID != "resource-1" {
+		t.Errorf("expected 3 released from resource-1, got %+v", resp)
+	}
+
+	svc, wait := res.QueueSnapshot()
+	if len(svc) != 0 {
+		t.Errorf("expected an empty service queue, got %v", ids(svc))
+	}
+	// Released nodes go ahead of the node that was already waiting; auto-promotion does not
+	// refill the freed slots.
+	if want := append(slices.Clone(service), waiting.ID); !slices.Equal(ids(wait), want) {
+		t.Errorf("expected waiting queue %v, got %v", want, ids(wait))
+	}
+	if got := res.GetAvailableCapacity(); got != res.Capacity {
+		t.Errorf("expected all %d capacity units available, got %d", res.Capacity, got)
+	}
+
+	n, err := qs.GetNode(service[0])
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	last := n.Log[len(n.Log)-2:]
+	if last[0].Action != "released" || last[1].Action != "moved_to_waiting_queue" || last[0].ResourceID != "resource-1" {
+		t.Errorf("expected released then moved_to_waiting_queue logs, got %+v", last)
+	}
+	states, err := store.ListLatestNodeStates(context.Background())
+	if err != nil {
+		t.Fatalf("ListLatestNodeStates: %v", err)
+	}
+	for _, id := range service {
+		if states[id].Queue != db.QueueKindWaiting {
+			t.Errorf("expected node %s persisted as waiting, got %+v", id, states[id])
+		}
+	}
+
+	// Released nodes can be allocated again.
+	if err := qs.AllocateNode(service[1]); err != nil {
+		t.Errorf("expected a released node to be allocatable, got %v", err)
+	}
+
+	// Releasing a resource with nothing in service is a no-op.
+	other := resourcepkg.NewResource("resource-2", 1)
+	qs.AddResource(other)
+	if released, err := qs.ReleaseResource("resource-2"); err != nil || released != 0 {
+		t.Errorf("expected 0 released, got %d, %v", released, err)
+	}
+}
+
+func TestReleaseResource_ReleasedNodesJoinDefaultLane(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	res := resourcepkg.NewResource("resource-1", 2)
+	res.LaneOrder = []string{"priority"}
+	qs.AddResource(res)
+
+	inService, _ := qs.CreateNodeOnResource("", "svc", 1, "resource-1", nil, nil)
+	if err := qs.AllocateNode(inService.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+	urgent, _ := qs.CreateNode("urgent")
+	if err := qs.MoveNodeToLane(urgent.ID, "resource-1", "priority"); err != nil {
+		t.Fatalf("MoveNodeToLane: %v", err)
+	}
+	normal, _ := qs.CreateNodeOnResource("", "normal", 1, "resource-1", nil, nil)
+
+	if _, err := qs.ReleaseResource("resource-1"); err != nil {
+		t.Fatalf("ReleaseResource: %v", err)
+	}
+	_, wait := res.QueueSnapshot()
+	if want := []string{urgent.ID, inService.ID, normal.ID}; !slices.Equal(ids(wait), want) {
+		t.Errorf("expected waiting queue %v, got %v", want, ids(wait))
+	}
+	if lane := res.LaneOf(inService.ID); lane != resourcepkg.DefaultLane {
+		t.Errorf("expected the released node in the default lane, got %q", lane)
+	}
+}
+
+func TestReleaseResourceHandler_UnknownResource(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	w := postRelease(qs, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceNotFound)
+}
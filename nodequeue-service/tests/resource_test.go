@@ -1,7 +1,17 @@
 package tests
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"nodequeue-service/node"
 	"nodequeue-service/resource"
@@ -173,3 +183,446 @@ func TestResource_IsFull(t *testing.T) {
 		t.Error("Resource should be full with 2 nodes in service")
 	}
 }
+
+func TestResource_WeightedCapacity(t *testing.T) {
+	resource := resource.NewResource("test-resource", 5)
+
+	heavy := &node.Node{ID: "heavy", Entity: &node.Entity{Name: "entity-1"}, Weight: 3}
+	medium := &node.Node{ID: "medium", Entity: &node.Entity{Name: "entity-2"}, Weight: 2}
+	light := &node.Node{ID: "light", Entity: &node.Entity{Name: "entity-3"}}
+	resource.AddNode(heavy)
+	resource.AddNode(light)
+	resource.AddNode(medium)
+
+	if !resource.AllocateWaitingNode(heavy.ID) {
+		t.Fatal("Failed to allocate weight-3 node")
+	}
+	if got := resource.GetAvailableCapacity(); got != 2 {
+		t.Errorf("Expected available capacity 2 after weight-3 node, got %d", got)
+	}
+	if !resource.AllocateWaitingNode(medium.ID) {
+		t.Fatal("Failed to allocate weight-2 node into the remaining 2 units")
+	}
+	if !resource.IsFull() || resource.GetAvailableCapacity() != 0 {
+		t.Errorf("Expected resource exactly full, available=%d", resource.GetAvailableCapacity())
+	}
+	if resource.AllocateWaitingNode(light.ID) {
+		t.Error("Expected allocation to fail on a full resource")
+	}
+
+	// Freeing the weight-2 node leaves room for the light node, but not for another heavy one.
+	resource.RemoveNode(medium.ID)
+	other := &node.Node{ID: "other-heavy", Entity: &node.Entity{Name: "entity-4"}, Weight: 3}
+	resource.AddNode(other)
+	if resource.AllocateWaitingNode(other.ID) {
+		t.Error("Expected weight-3 node not to fit in 2 remaining units")
+	}
+	if !resource.AllocateWaitingNode(light.ID) {
+		t.Error("Expected weight-1 node to fit")
+	}
+}
+
+func TestResource_ReorderWaitingNode(t *testing.T) {
+	r := resource.NewResource("test-resource", 1)
+	for _, id := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		r.AddNode(&node.Node{ID: id, Entity: &node.Entity{Name: id}})
+	}
+
+	// Move to front
+	if err := r.ReorderWaitingNode("test-resource", "node-3", 0); err != nil {
+		t.Fatalf("Failed to move node-3 to front: %v", err)
+	}
+	assertWaitingOrder(t, r, "node-3", "node-1", "node-2", "node-4")
+
+	// Move to back (clamped)
+	if err := r.ReorderWaitingNode("test-resource", "node-3", 99); err != nil {
+		t.Fatalf("Failed to move node-3 to back: %v", err)
+	}
+	assertWaitingOrder(t, r, "node-1", "node-2", "node-4", "node-3")
+
+	// Move to middle
+	if err := r.ReorderWaitingNode("test-resource", "node-1", 2); err != nil {
+		t.Fatalf("Failed to move node-1 to middle: %v", err)
+	}
+	assertWaitingOrder(t, r, "node-2", "node-4", "node-1", "node-3")
+
+	// Negative position clamps to front
+	if err := r.ReorderWaitingNode("test-resource", "node-3", -5); err != nil {
+		t.Fatalf("Failed to move node-3 with negative position: %v", err)
+	}
+	assertWaitingOrder(t, r, "node-3", "node-2", "node-4", "node-1")
+
+	// Nodes in the service queue cannot be reordered
+	r.AllocateWaitingNode("node-3")
+	if err := r.ReorderWaitingNode("test-resource", "node-3", 0); !errors.Is(err, resource.ErrNodeNotWaiting) {
+		t.Errorf("Should not reorder a node in the service queue, got %v", err)
+	}
+	if err := r.ReorderWaitingNode("test-resource", "non-existent", 0); !errors.Is(err, resource.ErrNodeNotWaiting) {
+		t.Errorf("Should not reorder a node that is not present, got %v", err)
+	}
+	if err := r.ReorderWaitingNode("other-resource", "node-2", 0); !errors.Is(err, resource.ErrWrongResource) {
+		t.Errorf("Should not reorder on a different resource ID, got %v", err)
+	}
+	assertWaitingOrder(t, r, "node-2", "node-4", "node-1")
+}
+
+func TestResource_SwapWaitingNodes(t *testing.T) {
+	resource := resource.NewResource("test-resource", 1)
+	for _, id := range []string{"node-1", "node-2", "node-3", "node-4"} {
+		resource.AddNode(&node.Node{ID: id, Entity: &node.Entity{Name: id}})
+	}
+
+	if !resource.SwapWaitingNodes("node-1", "node-3") {
+		t.Fatal("Failed to swap node-1 and node-3")
+	}
+	assertWaitingOrder(t, resource, "node-3", "node-2", "node-1", "node-4")
+
+	resource.AllocateWaitingNode("node-3")
+	if resource.SwapWaitingNodes("node-3", "node-2") {
+		t.Error("Should not swap a node in the service queue")
+	}
+	if resource.SwapWaitingNodes("node-2", "non-existent") {
+		t.Error("Should not swap a node that is not present")
+	}
+	if resource.SwapWaitingNodes("node-2", "node-2") {
+		t.Error("Should not swap a node with itself")
+	}
+	assertWaitingOrder(t, resource, "node-2", "node-1", "node-4")
+}
+
+func TestResource_SwapWaitingNodesAcrossLanes(t *testing.T) {
+	r := resource.NewResource("test-resource", 1)
+	r.LaneOrder = []string{"priority"}
+	r.AddNodeToLane(&node.Node{ID: "pri"}, "priority")
+	r.AddNodeToLane(&node.Node{ID: "std-1"}, "")
+	r.AddNodeToLane(&node.Node{ID: "std-2"}, "")
+
+	if !r.SwapWaitingNodes("pri", "std-2") {
+		t.Fatal("Failed to swap across lanes")
+	}
+	assertWaitingOrder(t, r, "std-2", "std-1", "pri")
+	if r.LaneOf("std-2") != "priority" || r.LaneOf("pri") != resource.DefaultLane {
+		t.Errorf("Expected the nodes to trade lanes, got std-2=%q pri=%q", r.LaneOf("std-2"), r.LaneOf("pri"))
+	}
+}
+
+func assertWaitingOrder(t *testing.T, r *resource.Resource, want ...string) {
+	t.Helper()
+	if len(r.WaitingQueue) != len(want) {
+		t.Fatalf("Expected %d waiting nodes, got %d", len(want), len(r.WaitingQueue))
+	}
+	for i, id := range want {
+		if r.WaitingQueue[i].ID != id {
+			t.Fatalf("Expected waiting queue %v, got position %d = '%s'", want, i, r.WaitingQueue[i].ID)
+		}
+	}
+}
+
+func TestResource_ReservationsConsumeCapacityUntilExpiry(t *testing.T) {
+	resource := resource.NewResource("test-resource", 2)
+
+	if !resource.Reserve("res-1", time.Now().Add(50*time.Millisecond)) {
+		t.Fatal("Failed to reserve first slot")
+	}
+	if !resource.Reserve("res-2", time.Now().Add(time.Hour)) {
+		t.Fatal("Failed to reserve second slot")
+	}
+	if resource.Reserve("res-3", time.Now().Add(time.Hour)) {
+		t.Error("Should not reserve beyond capacity")
+	}
+	if !resource.IsFull() {
+		t.Error("Resource should be full with 2 active reservations")
+	}
+	if resource.GetAvailableCapacity() != 0 {
+		t.Errorf("Expected available capacity 0, got %d", resource.GetAvailableCapacity())
+	}
+
+	// Reserved slots cannot be taken by regular allocation
+	node1 := &node.Node{ID: "node-1", Entity: &node.Entity{Name: "entity-1"}}
+	resource.AddNode(node1)
+	if resource.AllocateWaitingNode(node1.ID) {
+		t.Error("Regular allocation should not take a reserved slot")
+	}
+
+	// After the short reservation expires, one slot frees up
+	time.Sleep(80 * time.Millisecond)
+	if resource.HasReservation("res-1") {
+		t.Error("Expired reservation should not be active")
+	}
+	if resource.GetAvailableCapacity() != 1 {
+		t.Errorf("Expected available capacity 1 after expiry, got %d", resource.GetAvailableCapacity())
+	}
+	if !resource.AllocateWaitingNode(node1.ID) {
+		t.Error("Allocation should succeed once the reservation expired")
+	}
+}
+
+func TestTransferNodes_OppositeDirectionsDoNotDeadlock(t *testing.T) {
+	a := resource.NewResource("resource-a", 1)
+	b := resource.NewResource("resource-b", 1)
+	a.AddNode(&node.Node{ID: "node-1", Entity: &node.Entity{Name: "entity-1"}})
+	b.AddNode(&node.Node{ID: "node-2", Entity: &node.Entity{Name: "entity-2"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); resource.TransferNodes(a, b, true, nil) }()
+		go func() { defer wg.Done(); resource.TransferNodes(b, a, true, nil) }()
+	}
+	wg.Wait()
+
+	if total := len(a.WaitingQueue) + len(b.WaitingQueue); total != 2 {
+		t.Errorf("Expected 2 nodes across both resources, got %d", total)
+	}
+}
+
+func TestLoadResources_AutoPromoteColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.txt")
+	content := "Name,Capacity\nRoom A,2,true\nRoom B,3\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	resources := resource.LoadResources(path)
+	if len(resources) != 2 {
+		t.Fatalf("Expected 2 resources, got %d", len(resources))
+	}
+	if resources[0].ID != "Room A" || !resources[0].AutoPromote {
+		t.Errorf("Expected Room A with auto_promote enabled, got %+v", resources[0])
+	}
+	if resources[1].ID != "Room B" || resources[1].AutoPromote {
+		t.Errorf("Expected Room B with auto_promote disabled, got %+v", resources[1])
+	}
+}
+
+func TestLoadResources_MaxPerEntityColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.txt")
+	content := "Name,Capacity,AutoPromote,MaxPerEntity\nRoom A,4,false,2\nRoom B,3\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	resources := resource.LoadResources(path)
+	if len(resources) != 2 {
+		t.Fatalf("Expected 2 resources, got %d", len(resources))
+	}
+	if resources[0].MaxPerEntity != 2 {
+		t.Errorf("Expected Room A max_per_entity 2, got %d", resources[0].MaxPerEntity)
+	}
+	if resources[1].MaxPerEntity != 0 {
+		t.Errorf("Expected Room B to be unlimited, got %d", resources[1].MaxPerEntity)
+	}
+}
+
+func TestResource_Lanes(t *testing.T) {
+	r := resource.NewResource("room", 5)
+	r.LaneOrder = []string{"priority", "standard"}
+
+	s1 := &node.Node{ID: "s1"}
+	p1 := &node.Node{ID: "p1"}
+	d1 := &node.Node{ID: "d1"}
+	s2 := &node.Node{ID: "s2"}
+	p2 := &node.Node{ID: "p2"}
+	r.AddNodeToLane(s1, "standard")
+	r.AddNodeToLane(p1, "priority")
+	r.AddNode(d1) // default lane, unlisted: drains last
+	r.AddNodeToLane(s2, "standard")
+	r.AddNodeToLane(p2, "priority")
+
+	assertWaitingOrder(t, r, "p1", "p2", "s1", "s2", "d1")
+	if got := r.NextWaitingNode(); got != p1 {
+		t.Errorf("Expected p1 at the head, got %v", got.ID)
+	}
+	if lane := r.LaneOf("s2"); lane != "standard" {
+		t.Errorf("Expected s2 in standard lane, got %q", lane)
+	}
+	if lane := r.LaneOf("d1"); lane != resource.DefaultLane {
+		t.Errorf("Expected d1 in default lane, got %q", lane)
+	}
+
+	// Reordering is confined to the node's lane
+	r.ReorderWaitingNode(r.ID, "s2", 0)
+	assertWaitingOrder(t, r, "p1", "p2", "s2", "s1", "d1")
+	r.ReorderWaitingNode(r.ID, "p1", 99)
+	assertWaitingOrder(t, r, "p2", "p1", "s2", "s1", "d1")
+
+	lanes := r.Lanes()
+	if len(lanes["priority"]) != 2 || len(lanes["standard"]) != 2 || len(lanes[resource.DefaultLane]) != 1 {
+		t.Errorf("Unexpected lanes: %v", lanes)
+	}
+
+	r.RemoveNode("p2")
+	r.AllocateWaitingNode("p1")
+	if lane := r.LaneOf("p1"); lane != "" {
+		t.Errorf("Expected no lane once in service, got %q", lane)
+	}
+	// A new priority node still jumps ahead of standard
+	p3 := &node.Node{ID: "p3"}
+	r.AddNodeToLane(p3, "priority")
+	assertWaitingOrder(t, r, "p3", "s2", "s1", "d1")
+}
+
+func TestResource_LanesJSON(t *testing.T) {
+	r := resource.NewResource("room", 2)
+	r.LaneOrder = []string{"priority"}
+	r.AddNodeToLane(&node.Node{ID: "a"}, "priority")
+	r.AddNode(&node.Node{ID: "b"})
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got struct {
+		ID           string              `json:"id"`
+		WaitingQueue []json.RawMessage   `json:"waiting_queue"`
+		LaneOrder    []string            `json:"lane_order"`
+		Lanes        map[string][]string `json:"lanes"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.ID != "room" || len(got.WaitingQueue) != 2 || len(got.LaneOrder) != 1 {
+		t.Errorf("Expected regular resource fields to be kept, got %s", data)
+	}
+	if len(got.Lanes["priority"]) != 1 || got.Lanes["priority"][0] != "a" || len(got.Lanes["default"]) != 1 {
+		t.Errorf("Expected lanes in JSON, got %v", got.Lanes)
+	}
+}
+
+func TestWriteCSV_RoundTripsThroughLoadResources(t *testing.T) {
+	a := resource.NewResource("Room 1", 5)
+	a.AutoPromote = true
+	a.MaxPerEntity = 2
+	a.PressureWaiting = 10
+	a.PressureSeconds = 60
+	a.FIFOStrict = true
+	a.MaxWaitMS = 300000
+	a.AllocRatePerSec = 2.5
+	a.Labels = map[string]string{"region": "eu", "hw": "gpu"}
+	a.LaneOrder = []string{"priority", "standard"}
+	a.ReservedForPriority = 2
+	b := resource.NewResource("Room, \"B\"", 3)
+
+	var buf bytes.Buffer
+	if err := resource.WriteCSV(&buf, []*resource.Resource{a, b}); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Name,Capacity,") {
+		t.Errorf("Expected header row, got %q", buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	loaded := resource.LoadResources(path)
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 resources after re-import, got %d", len(loaded))
+	}
+	for i, want := range []*resource.Resource{a, b} {
+		got := loaded[i]
+		if got.ID != want.ID || got.Capacity != want.Capacity || got.AutoPromote != want.AutoPromote ||
+			got.MaxPerEntity != want.MaxPerEntity || got.PressureWaiting != want.PressureWaiting ||
+			got.PressureSeconds != want.PressureSeconds || got.FIFOStrict != want.FIFOStrict ||
+			got.MaxWaitMS != want.MaxWaitMS || got.AllocRatePerSec != want.AllocRatePerSec ||
+			!maps.Equal(got.Labels, want.Labels) || !slices.Equal(got.LaneOrder, want.LaneOrder) ||
+			got.ReservedForPriority != want.ReservedForPriority {
+			t.Errorf("Resource %d did not round-trip: got %s/%d, want %s/%d", i, got.ID, got.Capacity, want.ID, want.Capacity)
+		}
+	}
+}
+
+func TestLoadResources_LanesAndReservedForPriority(t *testing.T) {
+	path := writeConfig(t, "Name,Capacity\n"+
+		"Room A,4,,,,,,,,,gold; silver,1\n"+
+		"Room B,4,,,,,,,,,,1\n"+
+		"Room C,4,,,,,,,,,gold;gold\n"+
+		"Room D,4,,,,,,,,,gold,4\n")
+
+	resources, report, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadResourcesWithOptions failed: %v", err)
+	}
+	if len(resources) != 1 || !slices.Equal(resources[0].LaneOrder, []string{"gold", "silver"}) || resources[0].ReservedForPriority != 1 {
+		t.Fatalf("Expected Room A with lanes gold, silver and 1 reserved unit, got %+v", resources)
+	}
+	// A reservation without lanes, duplicate lanes and a reservation of the whole capacity are rejected.
+	if len(report.Skipped) != 3 {
+		t.Errorf("Expected 3 skipped rows, got %+v", report.Skipped)
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadResourcesWithOptions_StrictRejectsDuplicateIDs(t *testing.T) {
+	path := writeConfig(t, "Name,Capacity\nRoom A,2\nRoom B,3\nRoom A,4\n")
+
+	_, _, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{Strict: true})
+	var cfgErr *resource.ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected *ConfigError, got %v", err)
+	}
+	if len(cfgErr.Problems) != 1 || cfgErr.Problems[0].Line != 4 {
+		t.Fatalf("Expected one problem on line 4, got %+v", cfgErr.Problems)
+	}
+	if !strings.Contains(cfgErr.Error(), "line 4") || !strings.Contains(cfgErr.Error(), "first defined on line 2") {
+		t.Errorf("Expected error to name both lines, got %q", cfgErr.Error())
+	}
+
+	// Lenient mode keeps loading.
+	resources, report, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{})
+	if err != nil || len(resources) != 3 || len(report.Skipped) != 0 {
+		t.Errorf("Expected lenient load of 3 rows, got %d resources, report %+v, err %v", len(resources), report, err)
+	}
+}
+
+func TestLoadResourcesWithOptions_StrictRejectsNonPositiveCapacities(t *testing.T) {
+	path := writeConfig(t, "Room A,2\nRoom B,-1\nRoom C,0\nRoom D,lots\n")
+
+	_, _, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{Strict: true})
+	var cfgErr *resource.ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected *ConfigError, got %v", err)
+	}
+	lines := make([]int, 0, len(cfgErr.Problems))
+	for _, p := range cfgErr.Problems {
+		lines = append(lines, p.Line)
+	}
+	if !slices.Equal(lines, []int{2, 3, 4}) {
+		t.Errorf("Expected problems on lines 2, 3, 4, got %+v", cfgErr.Problems)
+	}
+
+	// Lenient mode only skips the unparseable row and reports it.
+	resources, report, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{})
+	if err != nil {
+		t.Fatalf("Lenient load failed: %v", err)
+	}
+	if len(resources) != 3 || report.Loaded != 3 || report.UsedDefaults {
+		t.Errorf("Expected 3 resources from the file, got %d (report %+v)", len(resources), report)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Line != 4 {
+		t.Errorf("Expected line 4 to be skipped, got %+v", report.Skipped)
+	}
+}
+
+func TestLoadResourcesWithOptions_DefaultCapacity(t *testing.T) {
+	path := writeConfig(t, "Room A\nRoom B,,true\nRoom C,2\n")
+
+	resources, report, err := resource.LoadResourcesWithOptions(path, resource.LoadOptions{Strict: true, DefaultCapacity: 7})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(resources) != 3 || len(report.Skipped) != 0 {
+		t.Fatalf("Expected 3 resources, got %d (report %+v)", len(resources), report)
+	}
+	if resources[0].Capacity != 7 || resources[1].Capacity != 7 || !resources[1].AutoPromote || resources[2].Capacity != 2 {
+		t.Errorf("Unexpected capacities: %d, %d, %d", resources[0].Capacity, resources[1].Capacity, resources[2].Capacity)
+	}
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// listFailingStore wraps a Store so that the list method behind one restore source fails with
+// errStoreDown.
+type listFailingStore struct {
+	db.Store
+	failing string
+}
+
+func (s *listFailingStore) ListResources(ctx context.Context) ([]*resourcepkg.Resource, error) {
+	if s.failing == queueservicepkg.RestoreSourceResources {
+		return nil, errStoreDown
+	}
+	return s.Store.ListResources(ctx)
+}
+
+func (s *listFailingStore) ListNodes(ctx context.Context) ([]db.PersistedNode, error) {
+	if s.failing == queueservicepkg.RestoreSourceNodes {
+		return nil, errStoreDown
+	}
+	return s.Store.ListNodes(ctx)
+}
+
+func (s *listFailingStore) ListLatestNodeStates(ctx context.Context) (map[string]db.NodeState, error) {
+	if s.failing == queueservicepkg.RestoreSourceNodeStates {
+		return nil, errStoreDown
+	}
+	return s.Store.ListLatestNodeStates(ctx)
+}
+
+func (s *listFailingStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]db.NodeLogRow, error) {
+	if s.failing == queueservicepkg.RestoreSourceNodeLogs {
+		return nil, errStoreDown
+	}
+	return s.Store.ListNodeLogs(ctx, nodeIDs)
+}
+
+func (s *listFailingStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]db.NodeExtras, error) {
+	if s.failing == queueservicepkg.RestoreSourceNodeExtras {
+		return nil, errStoreDown
+	}
+	return s.Store.ListNodeExtras(ctx, nodeIDs)
+}
+
+// seedRestoreStore returns a MemoryStore holding resource "Room 1" with node "served" in service
+// (tagged "vip") and node "queued" waiting.
+func seedRestoreStore(t *testing.T) *db.MemoryStore {
+	t.Helper()
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := qs.CreateResource(resourcepkg.NewResource("Room 1", 2)); err != nil {
+		t.Fatalf("CreateResource: %v", err)
+	}
+	served, err := qs.CreateNodeOnResource("", "served", 1, "Room 1", []string{"vip"}, nil)
+	if err != nil {
+		t.Fatalf("CreateNodeOnResource: %v", err)
+	}
+	if err := qs.AllocateNode(served.ID); err != nil {
+		t.Fatalf("AllocateNode: %v", err)
+	}
+	if _, err := qs.CreateNodeOnResource("", "queued", 1, "Room 1", nil, nil); err != nil {
+		t.Fatalf("CreateNodeOnResource: %v", err)
+	}
+	return store
+}
+
+// restartFrom restores a new service from store as main does on startup: resources from the
+// store (falling back to a default Room 1), then nodes.
+func restartFrom(store db.Store, strict bool) (*queueservicepkg.QueueService, error, error) {
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.RestoreStrict = strict
+	resources, resErr := qs.RestoreResourcesFromStore(context.Background())
+	if len(resources) == 0 && (resErr == nil || !strict) {
+		qs.AddResource(resourcepkg.NewResource("Room 1", 2))
+	}
+	return qs, resErr, qs.RestoreFromStore(context.Background())
+}
+
+func getReady(t *testing.T, qs *queueservicepkg.QueueService) queueservicepkg.ReadyResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	qs.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /readyz 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp queueservicepkg.ReadyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// failedSources returns the sources resp reports as failed.
+func failedSources(resp queueservicepkg.ReadyResponse) []string {
+	failed := make([]string, 0)
+	if resp.Restore == nil {
+		return failed
+	}
+	for _, s := range resp.Restore.Sources {
+		if !s.OK {
+			failed = append(failed, s.Source)
+		}
+	}
+	return failed
+}
+
+func TestRestore_FullRestoreReportedInReadyz(t *testing.T) {
+	qs, resErr, err := restartFrom(seedRestoreStore(t), false)
+	if resErr != nil || err != nil {
+		t.Fatalf("restore failed: %v, %v", resErr, err)
+	}
+	resp := getReady(t, qs)
+	if resp.Status != "ready" || resp.Restore == nil || resp.Restore.Partial {
+		t.Fatalf("expected a complete restore, got %+v", resp)
+	}
+	var sources []string
+	for _, s := range resp.Restore.Sources {
+		sources = append(sources, s.Source)
+	}
+	want := []string{
+		queueservicepkg.RestoreSourceResources,
+		queueservicepkg.RestoreSourceNodes,
+		queueservicepkg.RestoreSourceNodeStates,
+		queueservicepkg.RestoreSourceNodeExtras,
+	}
+	if !slices.Equal(sources, want) {
+		t.Errorf("expected sources %v, got %v", want, sources)
+	}
+
+	// Without a store nothing is restored, so nothing is reported.
+	if resp := getReady(t, queueservicepkg.NewQueueService()); resp.Restore != nil {
+		t.Errorf("expected no restore status without a store, got %+v", resp.Restore)
+	}
+}
+
+func TestRestore_EachSourceFailingIndependently(t *testing.T) {
+	for _, tc := range []struct {
+		source string
+		check  func(t *testing.T, qs *queueservicepkg.QueueService)
+	}{
+		{queueservicepkg.RestoreSourceResources, func(t *testing.T, qs *queueservicepkg.QueueService) {
+			// Nodes are still restored onto the fallback resource.
+			if got := len(qs.ListNodes()); got != 2 {
+				t.Errorf("expected 2 nodes restored, got %d", got)
+			}
+		}},
+		{queueservicepkg.RestoreSourceNodes, func(t *testing.T, qs *queueservicepkg.QueueService) {
+			if got := len(qs.ListNodes()); got != 0 {
+				t.Errorf("expected no nodes restored, got %d", got)
+			}
+			if _, err := qs.GetResource("Room 1"); err != nil {
+				t.Errorf("expected resources restored, got %v", err)
+			}
+		}},
+		{queueservicepkg.RestoreSourceNodeStates, func(t *testing.T, qs *queueservicepkg.QueueService) {
+			// Placements are replayed from the node logs instead.
+			res, _ := qs.GetResource("Room 1")
+			if len(res.Nodes) != 1 || res.Nodes[0].Entity.Name != "served" {
+				t.Errorf("expected served in service, got %v", ids(res.Nodes))
+			}
+			if len(res.WaitingQueue) != 1 || res.WaitingQueue[0].Entity.Name != "queued" {
+				t.Errorf("expected queued waiting, got %v", ids(res.WaitingQueue))
+			}
+		}},
+		{queueservicepkg.RestoreSourceNodeExtras, func(t *testing.T, qs *queueservicepkg.QueueService) {
+			res, _ := qs.GetResource("Room 1")
+			if len(res.Nodes) != 1 {
+				t.Fatalf("expected 1 node in service, got %v", ids(res.Nodes))
+			}
+			if tags := res.Nodes[0].Tags; len(tags) != 0 {
+				t.Errorf("expected the node restored without tags, got %v", tags)
+			}
+		}},
+	} {
+		t.Run(tc.source, func(t *testing.T) {
+			store := &listFailingStore{Store: seedRestoreStore(t), failing: tc.source}
+
+			qs, resErr, err := restartFrom(store, false)
+			if err != nil {
+				t.Fatalf("expected RestoreFromStore to succeed partially, got %v", err)
+			}
+			if (resErr != nil) != (tc.source == queueservicepkg.RestoreSourceResources) {
+				t.Errorf("unexpected RestoreResourcesFromStore error %v", resErr)
+			}
+			resp := getReady(t, qs)
+			if resp.Restore == nil || !resp.Restore.Partial {
+				t.Fatalf("expected a partial restore, got %+v", resp.Restore)
+			}
+			if failed := failedSources(resp); !slices.Equal(failed, []string{tc.source}) {
+				t.Errorf("expected only %s failed, got %v", tc.source, failed)
+			}
+			for _, s := range resp.Restore.Sources {
+				if !s.OK && s.Error != errStoreDown.Error() {
+					t.Errorf("expected the store error reported, got %q", s.Error)
+				}
+			}
+			tc.check(t, qs)
+
+			qs, resErr, err = restartFrom(store, true)
+			if err == nil && resErr == nil {
+				t.Fatal("expected a strict restore to fail")
+			}
+			if !errors.Is(resErr, errStoreDown) && !errors.Is(err, errStoreDown) {
+				t.Errorf("expected the store error, got %v, %v", resErr, err)
+			}
+			if tc.source != queueservicepkg.RestoreSourceResources && len(qs.ListNodes()) != 0 {
+				t.Errorf("expected a strict restore to restore no nodes, got %d", len(qs.ListNodes()))
+			}
+		})
+	}
+}
+
+func TestRestore_NodeLogsFailing(t *testing.T) {
+	// A node without a latest state makes the restore replay node logs.
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := &stubStore{
+		nodes: []db.PersistedNode{
+			{NodeID: "n_svc", EntityName: "e1", ResourceID: ptr("Room 1"), CreatedAt: base},
+		},
+		logs: map[string][]db.NodeLogRow{
+			"n_svc": {{NodeID: "n_svc", Action: "moved_to_service_queue", ResourceID: ptr("Room 1"), TS: base.Add(time.Second)}},
+		},
+	}
+	store := &listFailingStore{Store: stub, failing: queueservicepkg.RestoreSourceNodeLogs}
+
+	qs, _, err := restartFrom(store, false)
+	if err != nil {
+		t.Fatalf("expected RestoreFromStore to succeed partially, got %v", err)
+	}
+	if failed := failedSources(getReady(t, qs)); !slices.Equal(failed, []string{queueservicepkg.RestoreSourceNodeLogs}) {
+		t.Errorf("expected only node_logs failed, got %v", failed)
+	}
+	res, _ := qs.GetResource("Room 1")
+	if len(res.WaitingQueue) != 1 || res.WaitingQueue[0].ID != "n_svc" {
+		t.Errorf("expected the node restored as waiting, got service %v waiting %v", ids(res.Nodes), ids(res.WaitingQueue))
+	}
+
+	qs, _, err = restartFrom(store, true)
+	if !errors.Is(err, errStoreDown) {
+		t.Fatalf("expected a strict restore to fail with the store error, got %v", err)
+	}
+	if len(qs.ListNodes()) != 0 {
+		t.Errorf("expected a strict restore to restore no nodes, got %d", len(qs.ListNodes()))
+	}
+}
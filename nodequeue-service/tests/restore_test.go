@@ -2,6 +2,11 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
@@ -14,6 +19,8 @@ import (
 type stubStore struct {
 	nodes  []db.PersistedNode
 	states map[string]db.NodeState
+	notes  map[string][]db.NodeNoteRow
+	logs   map[string][]db.NodeLogRow
 }
 
 func (s *stubStore) ListResources(ctx context.Context) ([]*resourcepkg.Resource, error) {
@@ -24,15 +31,54 @@ func (s *stubStore) ListNodes(ctx context.Context) ([]db.PersistedNode, error) {
 	return s.nodes, nil
 }
 
+func (s *stubStore) ListAllNodes(ctx context.Context) ([]db.PersistedNode, error) {
+	return s.nodes, nil
+}
+
 func (s *stubStore) ListLatestNodeStates(ctx context.Context) (map[string]db.NodeState, error) {
 	return s.states, nil
 }
 
 func (s *stubStore) ListNodeLogs(ctx context.Context, nodeIDs []string) (map[string][]db.NodeLogRow, error) {
-	return map[string][]db.NodeLogRow{}, nil
+	out := make(map[string][]db.NodeLogRow)
+	for _, id := range nodeIDs {
+		if rows, ok := s.logs[id]; ok {
+			out[id] = rows
+		}
+	}
+	return out, nil
+}
+
+func (s *stubStore) EachNodeLog(ctx context.Context, q db.NodeLogQuery, fn func(db.NodeLogRow) error) error {
+	return nil
+}
+
+func (s *stubStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]db.NodeExtras, error) {
+	out := make(map[string]db.NodeExtras)
+	for _, id := range nodeIDs {
+		if notes, ok := s.notes[id]; ok {
+			out[id] = db.NodeExtras{Notes: notes}
+		}
+	}
+	return out, nil
 }
 
-func (s *stubStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, createdAt time.Time) error {
+func (s *stubStore) InsertResource(ctx context.Context, r *resourcepkg.Resource) error {
+	return nil
+}
+func (s *stubStore) InsertResources(ctx context.Context, resources []*resourcepkg.Resource) error {
+	return nil
+}
+func (s *stubStore) SetResourcePaused(ctx context.Context, id string, paused bool) error {
+	return nil
+}
+func (s *stubStore) SetResourceLabels(ctx context.Context, id string, labels map[string]string) error {
+	return nil
+}
+func (s *stubStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	return nil
+}
+func (s *stubStore) PersistNodeCreatedWithResource(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time, resourceID string, assignedAt time.Time) error {
 	return nil
 }
 func (s *stubStore) UpdateNodeResource(ctx context.Context, nodeID string, resourceID *string) error {
@@ -44,6 +90,55 @@ func (s *stubStore) MarkNodeCompleted(ctx context.Context, nodeID string, comple
 func (s *stubStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
 	return nil
 }
+func (s *stubStore) InsertNodeMoveLog(ctx context.Context, nodeID, action string, resourceID, fromResourceID *string, ts time.Time) error {
+	return nil
+}
+func (s *stubStore) InsertNodeNote(ctx context.Context, nodeID, author, text string, ts time.Time) error {
+	if s.notes == nil {
+		s.notes = make(map[string][]db.NodeNoteRow)
+	}
+	s.notes[nodeID] = append(s.notes[nodeID], db.NodeNoteRow{NodeID: nodeID, Author: author, Text: text, TS: ts})
+	return nil
+}
+func (s *stubStore) InsertNodeResult(ctx context.Context, nodeID, outcome string, result []byte, ts time.Time) error {
+	return nil
+}
+func (s *stubStore) AddNodeTag(ctx context.Context, nodeID, tag string) error {
+	return nil
+}
+func (s *stubStore) RemoveNodeTag(ctx context.Context, nodeID, tag string) error {
+	return nil
+}
+func (s *stubStore) SetNodeAllowedResources(ctx context.Context, nodeID string, resourceIDs []string) error {
+	return nil
+}
+func (s *stubStore) UpdateNodeCompletionToken(ctx context.Context, nodeID, token string) error {
+	return nil
+}
+func (s *stubStore) GetNodeCompletionToken(ctx context.Context, nodeID string) (string, error) {
+	return "", nil
+}
+func (s *stubStore) UpdateNodeDeadline(ctx context.Context, nodeID string, deadline *time.Time) error {
+	return nil
+}
+func (s *stubStore) UpdateNodeRetry(ctx context.Context, nodeID string, attempts int, notBefore *time.Time, failed bool) error {
+	return nil
+}
+func (s *stubStore) DeleteLogsOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	return 0, nil
+}
+func (s *stubStore) DeleteAllNodes(ctx context.Context) error {
+	s.nodes = nil
+	s.states = nil
+	s.notes = nil
+	return nil
+}
+func (s *stubStore) ArchiveCompletedNode(ctx context.Context, nodeID string, archivedAt time.Time) error {
+	return nil
+}
+func (s *stubStore) ListArchivedNodes(ctx context.Context, q db.ArchiveQuery) ([]db.ArchivedNode, error) {
+	return nil, nil
+}
 
 func ptr[T any](v T) *T { return &v }
 
@@ -100,6 +195,48 @@ func TestRestoreFromStore_RebuildsQueuesAndOrder(t *testing.T) {
 	}
 }
 
+func TestRestoreFromStore_BackfillsMissingStateFromLogs(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := &stubStore{
+		nodes: []db.PersistedNode{
+			{NodeID: "n_wait", EntityName: "e1", ResourceID: ptr("Room 1"), CreatedAt: base},
+			{NodeID: "n_svc_no_state", EntityName: "e2", ResourceID: ptr("Room 1"), CreatedAt: base.Add(time.Minute)},
+			{NodeID: "n_requeued", EntityName: "e3", ResourceID: ptr("Room 1"), CreatedAt: base.Add(2 * time.Minute)},
+		},
+		states: map[string]db.NodeState{
+			"n_wait": {Queue: db.QueueKindWaiting, TS: base.Add(30 * time.Minute)},
+		},
+		logs: map[string][]db.NodeLogRow{
+			"n_svc_no_state": {
+				{NodeID: "n_svc_no_state", Action: "created", TS: base.Add(time.Minute)},
+				{NodeID: "n_svc_no_state", Action: "moved_to_waiting_queue", ResourceID: ptr("Room 1"), TS: base.Add(2 * time.Minute)},
+				{NodeID: "n_svc_no_state", Action: "moved_to_service_queue", ResourceID: ptr("Room 1"), TS: base.Add(3 * time.Minute)},
+			},
+			"n_requeued": {
+				{NodeID: "n_requeued", Action: "moved_to_service_queue", ResourceID: ptr("Room 1"), TS: base.Add(3 * time.Minute)},
+				{NodeID: "n_requeued", Action: "service_timeout", ResourceID: ptr("Room 1"), TS: base.Add(4 * time.Minute)},
+				{NodeID: "n_requeued", Action: "moved_to_waiting_queue", ResourceID: ptr("Room 1"), TS: base.Add(4 * time.Minute)},
+			},
+		},
+	}
+
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+	if err := qs.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+
+	room1, _ := qs.GetResource("Room 1")
+	if got := ids(room1.Nodes); len(got) != 1 || got[0] != "n_svc_no_state" {
+		t.Fatalf("expected service queue [n_svc_no_state], got %v", got)
+	}
+	// n_requeued re-entered waiting at 4m, before n_wait's state at 30m.
+	if got := ids(room1.WaitingQueue); len(got) != 2 || got[0] != "n_requeued" || got[1] != "n_wait" {
+		t.Fatalf("expected waiting queue [n_requeued n_wait], got %v", got)
+	}
+}
+
 func ids(ns []*nodepkg.Node) []string {
 	out := make([]string, 0, len(ns))
 	for _, n := range ns {
@@ -107,3 +244,308 @@ func ids(ns []*nodepkg.Node) []string {
 	}
 	return out
 }
+
+func TestRestoreFromStore_RehydratesNotes(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &stubStore{
+		nodes: []db.PersistedNode{
+			{NodeID: "n1", EntityName: "e1", CreatedAt: base},
+		},
+		states: map[string]db.NodeState{},
+		notes: map[string][]db.NodeNoteRow{
+			"n1": {
+				{NodeID: "n1", Author: "bob", Text: "second", TS: base.Add(2 * time.Minute)},
+				{NodeID: "n1", Author: "alice", Text: "first", TS: base.Add(1 * time.Minute)},
+			},
+		},
+	}
+
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := qs.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+
+	n, err := qs.GetNode("n1")
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if len(n.Notes) != 2 || n.Notes[0].Text != "first" || n.Notes[1].Text != "second" {
+		t.Errorf("Expected notes restored in timestamp order, got %+v", n.Notes)
+	}
+}
+
+// extrasCountingStore counts ListNodeExtras calls on a MemoryStore.
+type extrasCountingStore struct {
+	*db.MemoryStore
+	extrasCalls int
+}
+
+func (s *extrasCountingStore) ListNodeExtras(ctx context.Context, nodeIDs []string) (map[string]db.NodeExtras, error) {
+	s.extrasCalls++
+	return s.MemoryStore.ListNodeExtras(ctx, nodeIDs)
+}
+
+func TestRestoreFromStore_RehydratesExtrasInOneQuery(t *testing.T) {
+	store := &extrasCountingStore{MemoryStore: db.NewMemoryStore()}
+	ctx := context.Background()
+
+	before := queueservicepkg.NewQueueServiceWithStore(store)
+	n, err := before.CreateTaggedNode("", "e1", 1, []string{"region:eu", "gpu"}, []string{"Room 2", "Room 1"})
+	if err != nil {
+		t.Fatalf("CreateTaggedNode: %v", err)
+	}
+	if _, err := before.AddNodeNote(n.ID, "alice", "needs a second look"); err != nil {
+		t.Fatalf("AddNodeNote: %v", err)
+	}
+	// A result row left by an earlier attempt, written directly so the node stays active.
+	if err := store.InsertNodeResult(ctx, n.ID, nodepkg.OutcomeFailure, []byte(`{"code":7}`), time.Now()); err != nil {
+		t.Fatalf("InsertNodeResult: %v", err)
+	}
+	other, _ := before.CreateNode("e2")
+
+	// Restart: a fresh service over the same store.
+	after := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := after.RestoreFromStore(ctx); err != nil {
+		t.Fatalf("RestoreFromStore: %v", err)
+	}
+	if store.extrasCalls != 1 {
+		t.Errorf("Expected one ListNodeExtras call, got %d", store.extrasCalls)
+	}
+
+	got, err := after.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if len(got.Tags) != 2 || !got.HasTags([]string{"gpu", "region:eu"}) {
+		t.Errorf("Expected tags restored, got %v", got.Tags)
+	}
+	if len(got.AllowedResources) != 2 || got.AllowedResources[0] != "Room 1" || got.AllowedResources[1] != "Room 2" {
+		t.Errorf("Expected allowed resources restored, got %v", got.AllowedResources)
+	}
+	if len(got.Notes) != 1 || got.Notes[0].Author != "alice" || got.Notes[0].Text != "needs a second look" {
+		t.Errorf("Expected note restored, got %+v", got.Notes)
+	}
+	if got.Result == nil || got.Result.Outcome != nodepkg.OutcomeFailure || string(got.Result.Result) != `{"code":7}` {
+		t.Errorf("Expected result restored, got %+v", got.Result)
+	}
+
+	plain, err := after.GetNode(other.ID)
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if len(plain.Tags) != 0 || len(plain.Notes) != 0 || plain.Result != nil || len(plain.AllowedResources) != 0 {
+		t.Errorf("Expected no extras on %s, got %+v", other.ID, plain)
+	}
+}
+
+func TestMergeFromStore_DBWinsAndKeepsUnknownNodes(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &stubStore{states: map[string]db.NodeState{}}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+
+	// Both sources know shared; only memory knows fresh (created since boot).
+	shared, _ := qs.CreateNode("memory-name")
+	fresh, _ := qs.CreateNode("fresh")
+	qs.MoveNode(shared.ID, "Room 1")
+	qs.MoveNode(fresh.ID, "Room 1")
+
+	// Only the DB knows db-only; archived is completed and no longer in memory.
+	store.nodes = []db.PersistedNode{
+		{NodeID: shared.ID, EntityName: "db-name", ResourceID: ptr("Room 1"), CreatedAt: base},
+		{NodeID: "db-only", EntityName: "e3", ResourceID: ptr("Room 1"), CreatedAt: base.Add(time.Minute)},
+		{NodeID: "archived", EntityName: "e4", Completed: true, CreatedAt: base},
+	}
+	store.states[shared.ID] = db.NodeState{Queue: db.QueueKindService, TS: base.Add(time.Minute)}
+	store.states["db-only"] = db.NodeState{Queue: db.QueueKindWaiting, TS: base.Add(2 * time.Minute)}
+
+	for run := 1; run <= 2; run++ {
+		summary, err := qs.MergeFromStore()
+		if err != nil {
+			t.Fatalf("run %d: MergeFromStore: %v", run, err)
+		}
+		want := queueservicepkg.RestoreSummary{NodesRestored: 2, NodesKept: 1, QueuesRebuilt: 1}
+		if summary != want {
+			t.Errorf("run %d: expected summary %+v, got %+v", run, want, summary)
+		}
+
+		if n := len(qs.ListNodes()); n != 3 {
+			t.Errorf("run %d: expected 3 nodes, got %d", run, n)
+		}
+		if _, err := qs.GetNode("archived"); !errors.Is(err, queueservicepkg.ErrNodeNotFound) {
+			t.Errorf("run %d: expected archived node not brought back, got %v", run, err)
+		}
+		n, err := qs.GetNode(shared.ID)
+		if err != nil {
+			t.Fatalf("run %d: GetNode: %v", run, err)
+		}
+		if n.Entity.Name != "db-name" {
+			t.Errorf("run %d: expected DB to win for shared node, got entity %q", run, n.Entity.Name)
+		}
+		if len(n.Log) == 0 {
+			t.Errorf("run %d: expected in-memory log kept for shared node", run)
+		}
+
+		room1, _ := qs.GetResource("Room 1")
+		service, waiting := room1.QueueSnapshot()
+		if got := ids(service); len(got) != 1 || got[0] != shared.ID {
+			t.Errorf("run %d: expected service queue [%s], got %v", run, shared.ID, got)
+		}
+		if got := ids(waiting); len(got) != 2 || got[0] != "db-only" || got[1] != fresh.ID {
+			t.Errorf("run %d: expected waiting queue [db-only %s], got %v", run, fresh.ID, got)
+		}
+	}
+
+	if _, err := queueservicepkg.NewQueueService().MergeFromStore(); !errors.Is(err, queueservicepkg.ErrStoreUnavailable) {
+		t.Errorf("Expected ErrStoreUnavailable without a store, got %v", err)
+	}
+}
+
+func TestReconcileOrphans(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newStore := func() *stubStore {
+		return &stubStore{
+			nodes: []db.PersistedNode{
+				{NodeID: "n_ok", EntityName: "e1", ResourceID: ptr("Room 1"), CreatedAt: base},
+				{NodeID: "n_orphan", EntityName: "e2", ResourceID: ptr("Room 9"), CreatedAt: base.Add(time.Minute)},
+			},
+			states: map[string]db.NodeState{},
+		}
+	}
+
+	t.Run("clears assignment", func(t *testing.T) {
+		qs := queueservicepkg.NewQueueServiceWithStore(newStore())
+		qs.AddResource(resourcepkg.NewResource("Room 1", 5))
+		if err := qs.RestoreFromStore(context.Background()); err != nil {
+			t.Fatalf("RestoreFromStore failed: %v", err)
+		}
+
+		// Stuck before reconciling
+		if err := qs.AllocateNode("n_orphan"); !errors.Is(err, queueservicepkg.ErrResourceNotFound) {
+			t.Fatalf("Expected ErrResourceNotFound for orphan, got %v", err)
+		}
+
+		orphans := qs.ReconcileOrphans()
+		if len(orphans) != 1 || orphans[0].NodeID != "n_orphan" || orphans[0].MissingResourceID != "Room 9" || orphans[0].Action != queueservicepkg.OrphanCleared {
+			t.Fatalf("Unexpected orphans: %+v", orphans)
+		}
+		n, _ := qs.GetNode("n_orphan")
+		if n.ResourceID != "" {
+			t.Errorf("Expected assignment cleared, got %q", n.ResourceID)
+		}
+		if last := n.Log[len(n.Log)-1]; last.Action != "orphaned" || last.ResourceID != "Room 9" {
+			t.Errorf("Expected orphaned log entry, got %+v", last)
+		}
+		// The node can be moved and allocated again
+		if err := qs.MoveNode("n_orphan", "Room 1"); err != nil {
+			t.Errorf("MoveNode after reconcile failed: %v", err)
+		}
+		if ok, _ := qs.GetNode("n_ok"); ok.ResourceID != "Room 1" {
+			t.Error("Expected healthy node untouched")
+		}
+		if again := qs.ReconcileOrphans(); len(again) != 0 {
+			t.Errorf("Expected reconcile to be idempotent, got %+v", again)
+		}
+	})
+
+	t.Run("moves to fallback", func(t *testing.T) {
+		qs := queueservicepkg.NewQueueServiceWithStore(newStore())
+		room1 := resourcepkg.NewResource("Room 1", 5)
+		qs.AddResource(room1)
+		qs.OrphanFallbackResourceID = "Room 1"
+		if err := qs.RestoreFromStore(context.Background()); err != nil {
+			t.Fatalf("RestoreFromStore failed: %v", err)
+		}
+
+		orphans := qs.ReconcileOrphans()
+		if len(orphans) != 1 || orphans[0].Action != queueservicepkg.OrphanMoved || orphans[0].FallbackResourceID != "Room 1" {
+			t.Fatalf("Unexpected orphans: %+v", orphans)
+		}
+		if !room1.IsWaiting("n_orphan") {
+			t.Error("Expected orphan in fallback waiting queue")
+		}
+		n, _ := qs.GetNode("n_orphan")
+		if last := n.Log[len(n.Log)-1]; last.ResourceID != "Room 1" || last.FromResourceID != "Room 9" {
+			t.Errorf("Expected a move from the missing resource to the fallback, got %+v", last)
+		}
+		if err := qs.AllocateNode("n_orphan"); err != nil {
+			t.Errorf("Expected orphan to be allocatable after reconcile, got %v", err)
+		}
+	})
+}
+
+func TestReconcileOrphansHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueServiceWithStore(&stubStore{
+		nodes: []db.PersistedNode{
+			{NodeID: "n_orphan", EntityName: "e1", ResourceID: ptr("gone"), CreatedAt: time.Now()},
+		},
+	})
+	if err := qs.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/reconcile", nil)
+	w := httptest.NewRecorder()
+	qs.ReconcileOrphansHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp queueservicepkg.ReconcileResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Orphans) != 1 || resp.Orphans[0].NodeID != "n_orphan" {
+		t.Errorf("Expected n_orphan reconciled, got %+v", resp.Orphans)
+	}
+}
+
+func TestRestore_ResourceSettingsSurviveRestart(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+
+	room := resourcepkg.NewResource("Room 1", 4)
+	room.AutoPromote = true
+	room.MaxPerEntity = 2
+	room.FIFOStrict = true
+	room.PressureWaiting = 3
+	room.PressureSeconds = 60
+	room.MaxWaitMS = 30000
+	room.LaneOrder = []string{"priority", "standard"}
+	room.ReservedForPriority = 1
+	room.AllocRatePerSec = 2.5
+	room.Labels = map[string]string{"region": "eu"}
+	if err := qs.CreateResource(room); err != nil {
+		t.Fatalf("CreateResource: %v", err)
+	}
+	if _, err := qs.CloneResource("Room 1", "Room 2"); err != nil {
+		t.Fatalf("CloneResource: %v", err)
+	}
+	batched := resourcepkg.NewResource("Room 3", 2)
+	batched.AutoPromote = true
+	batched.LaneOrder = []string{"fast"}
+	qs.CreateResources([]*resourcepkg.Resource{batched})
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	if _, err := restarted.RestoreResourcesFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreResourcesFromStore: %v", err)
+	}
+	for _, id := range []string{"Room 1", "Room 2"} {
+		got, err := restarted.GetResource(id)
+		if err != nil {
+			t.Fatalf("GetResource(%s): %v", id, err)
+		}
+		if got.Capacity != 4 || !got.AutoPromote || got.MaxPerEntity != 2 || !got.FIFOStrict ||
+			got.PressureWaiting != 3 || got.PressureSeconds != 60 || got.MaxWaitMS != 30000 ||
+			!slices.Equal(got.LaneOrder, room.LaneOrder) || got.ReservedForPriority != 1 ||
+			got.AllocRatePerSec != 2.5 || got.Labels["region"] != "eu" {
+			t.Errorf("%s: settings not restored, got %+v", id, got)
+		}
+	}
+	got, err := restarted.GetResource("Room 3")
+	if err != nil {
+		t.Fatalf("GetResource(Room 3): %v", err)
+	}
+	if got.Capacity != 2 || !got.AutoPromote || !slices.Equal(got.LaneOrder, []string{"fast"}) {
+		t.Errorf("Room 3: settings not restored, got %+v", got)
+	}
+}
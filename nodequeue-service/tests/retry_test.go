@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestRetryPolicy_BackoffSchedule(t *testing.T) {
+	p := queueservicepkg.RetryPolicy{MaxAttempts: 10, BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	uncapped := queueservicepkg.RetryPolicy{BaseBackoff: time.Second}
+	if got := uncapped.Backoff(200); got <= 0 {
+		t.Errorf("Expected uncapped backoff not to overflow, got %v", got)
+	}
+}
+
+func TestFailNode_RequeuesWithBackoff(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.Retry = queueservicepkg.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Hour, MaxBackoff: 4 * time.Hour}
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(r1)
+
+	failing, _ := qs.CreateNode("e1")
+	next, _ := qs.CreateNode("e2")
+	qs.MoveNode(failing.ID, "resource-1")
+	qs.MoveNode(next.ID, "resource-1")
+	if err := qs.AllocateNode(failing.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+
+	before := time.Now()
+	if err := qs.FailNode(failing.ID, "worker crashed"); err != nil {
+		t.Fatalf("FailNode failed: %v", err)
+	}
+
+	if failing.Attempts != 1 || failing.Completed || failing.FailureReason != "worker crashed" {
+		t.Errorf("Expected 1 attempt, not completed, reason recorded; got attempts=%d", failing.Attempts)
+	}
+	if failing.NotBeforeTS == nil || failing.NotBeforeTS.Before(before.Add(time.Hour)) {
+		t.Errorf("Expected not_before_ts about an hour out, got %v", failing.NotBeforeTS)
+	}
+	if !r1.IsWaiting(failing.ID) {
+		t.Error("Expected failed node back in the waiting queue")
+	}
+
+	// The backing-off node is skipped; the node behind it gets the slot.
+	if err := qs.AllocateNode(failing.ID); !errors.Is(err, queueservicepkg.ErrNodeBackingOff) {
+		t.Errorf("Expected ErrNodeBackingOff, got %v", err)
+	}
+	allocated, err := qs.FillResource("resource-1")
+	if err != nil {
+		t.Fatalf("FillResource failed: %v", err)
+	}
+	if len(allocated) != 1 || allocated[0] != next.ID {
+		t.Errorf("Expected fill to skip the backing-off node and allocate %s, got %v", next.ID, allocated)
+	}
+	if err := qs.FailNode(failing.ID, "again"); !errors.Is(err, queueservicepkg.ErrNodeNotInService) {
+		t.Errorf("Expected ErrNodeNotInService for a waiting node, got %v", err)
+	}
+}
+
+func TestFailNode_BackoffExpires(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.Retry = queueservicepkg.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	n, _ := qs.CreateNode("e1")
+	qs.MoveNode(n.ID, "resource-1")
+	qs.AllocateNode(n.ID)
+	qs.FailNode(n.ID, "flaky")
+
+	time.Sleep(5 * time.Millisecond)
+	if err := qs.AllocateNode(n.ID); err != nil {
+		t.Errorf("Expected allocation after backoff to succeed, got %v", err)
+	}
+}
+
+func TestFailNode_MaxAttemptsIsTerminal(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.Retry = queueservicepkg.RetryPolicy{MaxAttempts: 2}
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(r1)
+
+	n, _ := qs.CreateNode("e1")
+	qs.MoveNode(n.ID, "resource-1")
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := qs.AllocateNode(n.ID); err != nil {
+			t.Fatalf("attempt %d: AllocateNode failed: %v", attempt, err)
+		}
+		if err := qs.FailNode(n.ID, "boom"); err != nil {
+			t.Fatalf("attempt %d: FailNode failed: %v", attempt, err)
+		}
+	}
+
+	if !n.Completed || !n.Failed || n.Attempts != 2 || n.ResourceID != "" {
+		t.Errorf("Expected terminal failure after 2 attempts; completed=%t failed=%t attempts=%d resource=%q",
+			n.Completed, n.Failed, n.Attempts, n.ResourceID)
+	}
+	if r1.IsWaiting(n.ID) || r1.IsInService(n.ID) {
+		t.Error("Expected failed node removed from resource queues")
+	}
+	if last := n.Log[len(n.Log)-1].Action; last != "failed" {
+		t.Errorf("Expected last log action failed, got %s", last)
+	}
+	if err := qs.FailNode(n.ID, "boom"); !errors.Is(err, queueservicepkg.ErrNodeCompleted) {
+		t.Errorf("Expected ErrNodeCompleted, got %v", err)
+	}
+}
+
+func TestFailNode_RestoreHonorsBackoff(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.Retry = queueservicepkg.RetryPolicy{MaxAttempts: 5, BaseBackoff: time.Hour}
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	n, _ := qs.CreateNode("e1")
+	qs.MoveNode(n.ID, "resource-1")
+	qs.AllocateNode(n.ID)
+	qs.FailNode(n.ID, "flaky")
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	r1 := resourcepkg.NewResource("resource-1", 1)
+	restarted.AddResource(r1)
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+
+	got, err := restarted.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("GetNode failed: %v", err)
+	}
+	if got.Attempts != 1 || got.NotBeforeTS == nil || !got.NotBeforeTS.Equal(*n.NotBeforeTS) {
+		t.Errorf("Expected attempts and not_before_ts restored, got attempts=%d not_before=%v", got.Attempts, got.NotBeforeTS)
+	}
+	if !r1.IsWaiting(n.ID) {
+		t.Error("Expected restored node in the waiting queue")
+	}
+	if err := restarted.AllocateNode(n.ID); !errors.Is(err, queueservicepkg.ErrNodeBackingOff) {
+		t.Errorf("Expected restored node to still be backing off, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func setupServiceTimeout(t *testing.T, policy string) (*queueservicepkg.QueueService, *resourcepkg.Resource, *queueservicepkg.ServiceTimeoutWatchdog, *fakeClock, string) {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	res := resourcepkg.NewResource("resource-1", 1)
+	qs.AddResource(res)
+
+	n, _ := qs.CreateNode("entity")
+	qs.MoveNode(n.ID, "resource-1")
+	if err := qs.AllocateNode(n.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+
+	watchdog := queueservicepkg.NewServiceTimeoutWatchdog(qs, 10*time.Minute, policy)
+	// Log timestamps use the real clock, so start the fake one from it.
+	clock := &fakeClock{now: time.Now()}
+	watchdog.Now = clock.Now
+	return qs, res, watchdog, clock, n.ID
+}
+
+func hasLogAction(n *node.Node, action string) bool {
+	for _, entry := range n.Log {
+		if entry.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+func TestServiceTimeoutWatchdog_RequeuesStuckNode(t *testing.T) {
+	qs, res, watchdog, clock, nodeID := setupServiceTimeout(t, queueservicepkg.ServiceTimeoutRequeue)
+
+	clock.Advance(9 * time.Minute)
+	if got := watchdog.Check(context.Background()); len(got) != 0 {
+		t.Fatalf("expected nothing reclaimed before the timeout, got %v", got)
+	}
+
+	clock.Advance(time.Minute)
+	got := watchdog.Check(context.Background())
+	if len(got) != 1 || got[0] != nodeID {
+		t.Fatalf("expected %s reclaimed after the timeout, got %v", nodeID, got)
+	}
+	if res.IsInService(nodeID) || !res.IsWaiting(nodeID) {
+		t.Error("expected the node back in the waiting queue")
+	}
+	n, _ := qs.GetNode(nodeID)
+	if n.Completed || !hasLogAction(n, "service_timeout") {
+		t.Errorf("expected an active node with a service_timeout log entry, got completed=%v log=%+v", n.Completed, n.Log)
+	}
+
+	// Already reclaimed: a second check is a no-op.
+	if got := watchdog.Check(context.Background()); len(got) != 0 {
+		t.Errorf("expected nothing reclaimed twice, got %v", got)
+	}
+}
+
+func TestServiceTimeoutWatchdog_CancelsStuckNode(t *testing.T) {
+	qs, res, watchdog, clock, nodeID := setupServiceTimeout(t, queueservicepkg.ServiceTimeoutCancel)
+
+	clock.Advance(10 * time.Minute)
+	if got := watchdog.Check(context.Background()); len(got) != 1 {
+		t.Fatalf("expected 1 node reclaimed, got %v", got)
+	}
+	if res.IsInService(nodeID) || res.IsWaiting(nodeID) {
+		t.Error("expected the node removed from the resource")
+	}
+	n, _ := qs.GetNode(nodeID)
+	if !n.Completed || n.Result == nil || n.Result.Outcome != node.OutcomeCancelled {
+		t.Errorf("expected a completed node with outcome %q, got completed=%v result=%+v", node.OutcomeCancelled, n.Completed, n.Result)
+	}
+	if !hasLogAction(n, "service_timeout") {
+		t.Errorf("expected a service_timeout log entry, got %+v", n.Log)
+	}
+}
+
+func TestServiceTimeoutWatchdog_IgnoresWaitingAndCompletedNodes(t *testing.T) {
+	qs, _, watchdog, clock, nodeID := setupServiceTimeout(t, queueservicepkg.ServiceTimeoutRequeue)
+	waiting, _ := qs.CreateNode("waiting")
+	qs.MoveNode(waiting.ID, "resource-1")
+	qs.CompleteNode(nodeID)
+
+	clock.Advance(time.Hour)
+	if got := watchdog.Check(context.Background()); len(got) != 0 {
+		t.Errorf("expected nothing reclaimed, got %v", got)
+	}
+}
+
+func TestServiceTimeoutWatchdog_RunStopsOnCancel(t *testing.T) {
+	_, _, watchdog, _, _ := setupServiceTimeout(t, queueservicepkg.ServiceTimeoutRequeue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchdog.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return after cancellation")
+	}
+}
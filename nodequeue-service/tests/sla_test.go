@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func setupSLA(t *testing.T) (*queueservicepkg.QueueService, *queueservicepkg.SLAMonitor, *fakeClock, *[]queueservicepkg.SLAEvent, time.Time) {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	res := resourcepkg.NewResource("resource-1", 1)
+	res.MaxWaitMS = 1000
+	qs.AddResource(res)
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	waiting, err := qs.ListWaiting("resource-1", "")
+	if err != nil || len(waiting) != 1 {
+		t.Fatalf("Expected 1 waiting node, got %v (%v)", waiting, err)
+	}
+
+	events := make([]queueservicepkg.SLAEvent, 0)
+	monitor := queueservicepkg.NewSLAMonitor(qs, func(ev queueservicepkg.SLAEvent) {
+		events = append(events, ev)
+	})
+	clock := &fakeClock{now: waiting[0].WaitingSince}
+	monitor.Now = clock.Now
+	return qs, monitor, clock, &events, waiting[0].WaitingSince
+}
+
+func TestSLABreaches_JustUnderAndJustOver(t *testing.T) {
+	qs, _, _, _, since := setupSLA(t)
+
+	if got := qs.SLABreaches(since.Add(1000 * time.Millisecond)); len(got) != 0 {
+		t.Fatalf("Expected no breach at exactly the SLA, got %v", got)
+	}
+	got := qs.SLABreaches(since.Add(1001 * time.Millisecond))
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 breach just over the SLA, got %d", len(got))
+	}
+	if got[0].ResourceID != "resource-1" || got[0].WaitingMs != 1001 || got[0].MaxWaitMS != 1000 {
+		t.Fatalf("Unexpected breach: %+v", got[0])
+	}
+
+	// A node waiting on a resource without an SLA never breaches.
+	other, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(other.ID, "resource-2")
+	if got := qs.SLABreaches(since.Add(time.Hour)); len(got) != 1 {
+		t.Fatalf("Expected only the SLA resource's node to breach, got %d", len(got))
+	}
+}
+
+func TestSLAMonitor_FiresOncePerWait(t *testing.T) {
+	qs, monitor, clock, events, _ := setupSLA(t)
+	sub, cancel := qs.Subscribe()
+	defer cancel()
+
+	clock.Advance(999 * time.Millisecond)
+	monitor.Check()
+	if len(*events) != 0 {
+		t.Fatalf("Expected no event just under the SLA, got %d", len(*events))
+	}
+
+	clock.Advance(2 * time.Millisecond)
+	monitor.Check()
+	clock.Advance(time.Second)
+	monitor.Check()
+	if len(*events) != 1 {
+		t.Fatalf("Expected 1 event once the SLA is crossed, got %d", len(*events))
+	}
+	ev := (*events)[0]
+	if ev.Type != queueservicepkg.EventSLABreach || ev.ResourceID != "resource-1" || ev.WaitingMs != 1001 {
+		t.Fatalf("Unexpected event: %+v", ev)
+	}
+	select {
+	case published := <-sub:
+		if published.Action != queueservicepkg.EventSLABreach || published.NodeID != ev.NodeID {
+			t.Fatalf("Unexpected published event: %+v", published)
+		}
+	default:
+		t.Fatal("Expected the breach to be published to subscribers")
+	}
+
+	// Allocation ends the wait.
+	if err := qs.AllocateNode(ev.NodeID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	if fired := monitor.Check(); len(fired) != 0 {
+		t.Fatalf("Expected no event after allocation, got %d", len(fired))
+	}
+}
+
+func TestSLABreachesHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	res := resourcepkg.NewResource("resource-1", 1)
+	res.MaxWaitMS = 1
+	qs.AddResource(res)
+	n, _ := qs.CreateNode("entity-1")
+	qs.MoveNode(n.ID, "resource-1")
+	time.Sleep(5 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	qs.SLABreachesHandler(w, httptest.NewRequest(http.MethodGet, "/sla/breaches", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var breaches []queueservicepkg.SLABreach
+	if err := json.NewDecoder(w.Body).Decode(&breaches); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(breaches) != 1 || breaches[0].NodeID != n.ID {
+		t.Fatalf("Expected node %s to be reported, got %+v", n.ID, breaches)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+)
+
+// flakyStore is a MemoryStore whose node writes fail with errStoreDown while down is set.
+type flakyStore struct {
+	*db.MemoryStore
+	down atomic.Bool
+}
+
+func (s *flakyStore) PersistNodeCreated(ctx context.Context, nodeID, entityID, entityName string, weight int, createdAt time.Time) error {
+	if s.down.Load() {
+		return errStoreDown
+	}
+	return s.MemoryStore.PersistNodeCreated(ctx, nodeID, entityID, entityName, weight, createdAt)
+}
+
+func (s *flakyStore) InsertNodeLog(ctx context.Context, nodeID, action string, resourceID *string, ts time.Time) error {
+	if s.down.Load() {
+		return errStoreDown
+	}
+	return s.MemoryStore.InsertNodeLog(ctx, nodeID, action, resourceID, ts)
+}
+
+func getStoreHealth(t *testing.T, qs *queueservicepkg.QueueService) queueservicepkg.StoreHealth {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/admin/store-health", nil)
+	w := httptest.NewRecorder()
+	qs.StoreHealthHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var health queueservicepkg.StoreHealth
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return health
+}
+
+func readyStatus(qs *queueservicepkg.QueueService) int {
+	w := httptest.NewRecorder()
+	qs.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w.Code
+}
+
+func TestStoreHealth_CountsFailuresAndRecovers(t *testing.T) {
+	store := &flakyStore{MemoryStore: db.NewMemoryStore()}
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.StoreDegradeAfter = 3
+
+	if _, err := qs.CreateNode("ok"); err != nil {
+		t.Fatalf("CreateNode: %v", err)
+	}
+	health := getStoreHealth(t, qs)
+	if !health.Enabled || health.ConsecutiveFailures != 0 || health.LastSuccessAt == nil || health.LastError != "" {
+		t.Errorf("unexpected health after a successful create: %+v", health)
+	}
+	if ops := health.Operations; ops["PersistNodeCreated"].Successes != 1 || ops["InsertNodeLog"].Successes != 1 {
+		t.Errorf("expected one success per write, got %+v", ops)
+	}
+
+	store.down.Store(true)
+	// Each create fails the node row and the log entry: four failures in a row.
+	qs.CreateNode("lost-1")
+	qs.CreateNode("lost-2")
+	health = getStoreHealth(t, qs)
+	if health.ConsecutiveFailures != 4 || !health.Degraded {
+		t.Errorf("expected 4 consecutive failures and degraded, got %+v", health)
+	}
+	if ops := health.Operations; ops["PersistNodeCreated"].Failures != 2 || ops["InsertNodeLog"].Failures != 2 {
+		t.Errorf("expected two failures per write, got %+v", ops)
+	}
+	if health.LastErrorAt == nil || !strings.Contains(health.LastError, errStoreDown.Error()) {
+		t.Errorf("expected the last error to be recorded, got %q at %v", health.LastError, health.LastErrorAt)
+	}
+	if code := readyStatus(qs); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz 503 while degraded, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	qs.MetricsHandler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`nodequeue_store_operations_total{op="InsertNodeLog",result="failure"} 2`,
+		`nodequeue_store_operations_total{op="PersistNodeCreated",result="success"} 1`,
+		"nodequeue_store_consecutive_failures 4",
+		"nodequeue_store_degraded 1",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected /metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+
+	store.down.Store(false)
+	qs.CreateNode("back")
+	if health := getStoreHealth(t, qs); health.ConsecutiveFailures != 0 || health.Degraded {
+		t.Errorf("expected recovery after a successful write, got %+v", health)
+	}
+	if code := readyStatus(qs); code != http.StatusOK {
+		t.Errorf("expected /readyz 200 after recovery, got %d", code)
+	}
+}
+
+func TestStoreHealth_WithoutStore(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.StoreDegradeAfter = 1
+	qs.CreateNode("memory-only")
+	if health := getStoreHealth(t, qs); health.Enabled || health.Degraded || len(health.Operations) != 0 {
+		t.Errorf("expected a disabled, healthy report without a store, got %+v", health)
+	}
+	if code := readyStatus(qs); code != http.StatusOK {
+		t.Errorf("expected /readyz 200 without a store, got %d", code)
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+type tagsBody struct {
+	NodeID string   `json:"node_id"`
+	Tags   []string `json:"tags"`
+}
+
+func TestNodeTagHandlers_AddAndRemove(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	n, _ := qs.CreateNode("entity")
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/tags", bytes.NewBufferString(`{"tags": ["region:eu", "customer:acme", "region:eu"]}`))
+	w := httptest.NewRecorder()
+	qs.AddNodeTagsHandler(w, req, n.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var body tagsBody
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !slices.Equal(body.Tags, []string{"customer:acme", "region:eu"}) {
+		t.Errorf("expected sorted, de-duplicated tags, got %v", body.Tags)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/nodes/"+n.ID+"/tags/region:eu", nil)
+	w = httptest.NewRecorder()
+	qs.RemoveNodeTagHandler(w, req, n.ID, "region:eu")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	got, _ := qs.GetNode(n.ID)
+	if !slices.Equal(got.Tags, []string{"customer:acme"}) {
+		t.Errorf("expected only customer:acme left, got %v", got.Tags)
+	}
+
+	// Removing a missing tag is a no-op.
+	if tags, err := qs.RemoveNodeTag(n.ID, "region:eu"); err != nil || len(tags) != 1 {
+		t.Errorf("expected a no-op removal, got %v (err=%v)", tags, err)
+	}
+
+	for _, body := range []string{`{"tags": []}`, `{"tags": ["has space"]}`, `{"tags": ["a/b"]}`} {
+		req = httptest.NewRequest(http.MethodPost, "/nodes/"+n.ID+"/tags", bytes.NewBufferString(body))
+		w = httptest.NewRecorder()
+		qs.AddNodeTagsHandler(w, req, n.ID)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/missing/tags", bytes.NewBufferString(`{"tags": ["a"]}`))
+	w = httptest.NewRecorder()
+	qs.AddNodeTagsHandler(w, req, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown node, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestListNodesHandler_FiltersByAllTags(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	create := func(body string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		qs.CreateNodeHandler(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		var created struct {
+			ID   string   `json:"id"`
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return created.ID
+	}
+	both := create(`{"entity_name": "a", "tags": ["region:eu", "customer:acme"]}`)
+	create(`{"entity_name": "b", "tags": ["region:eu"]}`)
+	create(`{"entity_name": "c", "resource_id": "resource-1", "tags": ["customer:acme"]}`)
+	create(`{"entity_name": "d"}`)
+
+	list := func(query string) (int, []string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes?"+query, nil)
+		w := httptest.NewRecorder()
+		qs.ListNodesHandler(w, req)
+		var nodes []struct {
+			ID string `json:"id"`
+		}
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&nodes); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		ids := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			ids = append(ids, n.ID)
+		}
+		return w.Code, ids
+	}
+
+	if _, ids := list(""); len(ids) != 4 {
+		t.Errorf("expected all 4 nodes without a filter, got %d", len(ids))
+	}
+	if _, ids := list("tag=region:eu"); len(ids) != 2 {
+		t.Errorf("expected 2 nodes tagged region:eu, got %d", len(ids))
+	}
+	if _, ids := list("tag=customer:acme"); len(ids) != 2 {
+		t.Errorf("expected 2 nodes tagged customer:acme (including the one created on a resource), got %d", len(ids))
+	}
+	if _, ids := list("tag=region:eu&tag=customer:acme"); len(ids) != 1 || ids[0] != both {
+		t.Errorf("expected only %s to carry both tags, got %v", both, ids)
+	}
+	if code, _ := list("tag=bad%20tag"); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid tag, got %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestRestoreFromStore_RestoresNodeTags(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	n, _ := qs.CreateTaggedNode("", "entity", 1, []string{"job:export", "region:eu"}, nil)
+	qs.AddNodeTags(n.ID, []string{"customer:acme"})
+	qs.RemoveNodeTag(n.ID, "region:eu")
+
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	got, err := restarted.GetNode(n.ID)
+	if err != nil {
+		t.Fatalf("GetNode failed: %v", err)
+	}
+	if !slices.Equal(got.Tags, []string{"customer:acme", "job:export"}) {
+		t.Errorf("expected restored tags [customer:acme job:export], got %v", got.Tags)
+	}
+}
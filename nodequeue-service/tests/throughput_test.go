@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+func TestBucketCompletions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 7, 0, 0, time.UTC)
+	completions := []time.Time{
+		time.Date(2025, 1, 1, 11, 40, 0, 0, time.UTC), // before the first bucket
+		time.Date(2025, 1, 1, 11, 50, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 11, 54, 59, 0, time.UTC),
+		time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 12, 8, 0, 0, time.UTC), // after now
+	}
+
+	buckets := queueservicepkg.BucketCompletions(completions, now, 5*time.Minute, 15*time.Minute)
+
+	// now-window is 11:52, which falls in the 11:50 bucket; the last bucket contains 12:07.
+	want := []struct {
+		start string
+		count int
+	}{
+		{"11:50", 2}, {"11:55", 0}, {"12:00", 0}, {"12:05", 1},
+	}
+	if len(buckets) != len(want) {
+		t.Fatalf("expected %d buckets, got %d: %+v", len(want), len(buckets), buckets)
+	}
+	for i, w := range want {
+		if got := buckets[i].BucketStart.Format("15:04"); got != w.start || buckets[i].Count != w.count {
+			t.Errorf("bucket %d: expected %s=%d, got %s=%d", i, w.start, w.count, got, buckets[i].Count)
+		}
+	}
+}
+
+func TestThroughputHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 2))
+
+	for _, rid := range []string{"resource-1", "resource-1", "resource-2"} {
+		n, _ := qs.CreateNode("entity")
+		qs.MoveNode(n.ID, rid)
+		qs.CompleteNode(n.ID)
+	}
+	waiting, _ := qs.CreateNode("entity")
+	qs.MoveNode(waiting.ID, "resource-1")
+
+	get := func(query string) (int, []queueservicepkg.ThroughputBucket) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/nodes/throughput?"+query, nil)
+		w := httptest.NewRecorder()
+		qs.ThroughputHandler(w, req)
+		var buckets []queueservicepkg.ThroughputBucket
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&buckets); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w.Code, buckets
+	}
+	total := func(buckets []queueservicepkg.ThroughputBucket) int {
+		sum := 0
+		for _, b := range buckets {
+			sum += b.Count
+		}
+		return sum
+	}
+
+	code, buckets := get("")
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	// Default 6h window in 5m buckets.
+	if len(buckets) < 72 || total(buckets) != 3 {
+		t.Errorf("expected >= 72 buckets totalling 3 completions, got %d totalling %d", len(buckets), total(buckets))
+	}
+
+	_, buckets = get("bucket=1h&window=2h&resource_id=resource-1")
+	if total(buckets) != 2 {
+		t.Errorf("expected 2 completions on resource-1, got %d", total(buckets))
+	}
+
+	for _, query := range []string{"bucket=0s", "window=soon", "bucket=1s&window=24h"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, code)
+		}
+	}
+}
+
+func TestThroughputHandler_PrefersStore(t *testing.T) {
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	n, _ := qs.CreateNode("entity")
+	qs.MoveNode(n.ID, "resource-1")
+	qs.CompleteNode(n.ID)
+
+	// A fresh service on the same store has nothing in memory but still sees the completion.
+	fresh := queueservicepkg.NewQueueServiceWithStore(store)
+	req := httptest.NewRequest(http.MethodGet, "/nodes/throughput?bucket=1h&window=1h", nil)
+	w := httptest.NewRecorder()
+	fresh.ThroughputHandler(w, req)
+
+	var buckets []queueservicepkg.ThroughputBucket
+	if err := json.NewDecoder(w.Body).Decode(&buckets); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	count := 0
+	for _, b := range buckets {
+		count += b.Count
+	}
+	if count != 1 {
+		t.Errorf("expected 1 completion from the store, got %d", count)
+	}
+}
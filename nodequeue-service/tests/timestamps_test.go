@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"nodequeue-service/db"
+	nodepkg "nodequeue-service/node"
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// withLocalZone runs the test with time.Local set to a non-UTC zone.
+func withLocalZone(t *testing.T) {
+	t.Helper()
+	prev := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	t.Cleanup(func() { time.Local = prev })
+}
+
+func TestTimestamps_UTCRegardlessOfLocalZone(t *testing.T) {
+	withLocalZone(t)
+
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+
+	n, _ := qs.CreateNode("entity")
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if _, err := qs.AddNodeNote(n.ID, "ops", "checked"); err != nil {
+		t.Fatalf("AddNodeNote failed: %v", err)
+	}
+
+	got, _ := qs.GetNode(n.ID)
+	if got.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected CreatedAt in UTC, got %v", got.CreatedAt.Location())
+	}
+	for _, entry := range got.Log {
+		if entry.Timestamp.Location() != time.UTC {
+			t.Errorf("Expected %s log timestamp in UTC, got %v", entry.Action, entry.Timestamp.Location())
+		}
+	}
+	if got.Notes[0].Timestamp.Location() != time.UTC {
+		t.Errorf("Expected note timestamp in UTC, got %v", got.Notes[0].Timestamp.Location())
+	}
+
+	raw, err := json.Marshal(got.Log[0])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var entry struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil || !strings.HasSuffix(entry.Timestamp, "Z") {
+		t.Errorf("Expected a UTC RFC 3339 timestamp, got %s (err=%v)", raw, err)
+	}
+
+	// Timestamps read back from the store are normalized too, whatever zone they were written in.
+	local := time.Now()
+	if err := store.PersistNodeCreated(context.Background(), "00000000-0000-0000-0000-000000000001", "e1", "restored", 1, local); err != nil {
+		t.Fatalf("PersistNodeCreated failed: %v", err)
+	}
+	restarted := queueservicepkg.NewQueueServiceWithStore(store)
+	if err := restarted.RestoreFromStore(context.Background()); err != nil {
+		t.Fatalf("RestoreFromStore failed: %v", err)
+	}
+	restored, err := restarted.GetNode("00000000-0000-0000-0000-000000000001")
+	if err != nil {
+		t.Fatalf("Expected restored node, got %v", err)
+	}
+	if restored.CreatedAt.Location() != time.UTC || !restored.CreatedAt.Equal(local) {
+		t.Errorf("Expected CreatedAt %v in UTC, got %v", local.UTC(), restored.CreatedAt)
+	}
+}
+
+func TestTimestamps_PersistedLogMatchesInMemoryLog(t *testing.T) {
+	withLocalZone(t)
+
+	store := db.NewMemoryStore()
+	qs := queueservicepkg.NewQueueServiceWithStore(store)
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	n, _ := qs.CreateNode("entity")
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if err := qs.TransferAndAllocate(n.ID, "resource-2"); err != nil {
+		t.Fatalf("TransferAndAllocate failed: %v", err)
+	}
+	if err := qs.CompleteNode(n.ID); err != nil {
+		t.Fatalf("CompleteNode failed: %v", err)
+	}
+
+	got, _ := qs.GetNode(n.ID)
+	logs, err := store.ListNodeLogs(context.Background(), []string{n.ID})
+	if err != nil {
+		t.Fatalf("ListNodeLogs failed: %v", err)
+	}
+	rows := logs[n.ID]
+	if len(rows) != len(got.Log) {
+		t.Fatalf("Expected %d persisted log rows, got %d", len(got.Log), len(rows))
+	}
+	for i, entry := range got.Log {
+		if rows[i].Action != entry.Action || rows[i].TS.Location() != time.UTC || !rows[i].TS.Equal(entry.Timestamp) {
+			t.Errorf("Expected persisted %s at %v (UTC), got %s at %v", entry.Action, entry.Timestamp, rows[i].Action, rows[i].TS)
+		}
+	}
+	if !got.CreatedAt.Equal(got.Log[0].Timestamp) {
+		t.Errorf("Expected CreatedAt %v to match the created log entry %v", got.CreatedAt, got.Log[0].Timestamp)
+	}
+}
+
+func TestNodeLog_TimeFormat(t *testing.T) {
+	withLocalZone(t)
+
+	ts := time.Date(2024, 5, 1, 17, 0, 0, 123456789, time.Local)
+	n := &nodepkg.Node{Log: []nodepkg.NodeLog{{Action: "created", Timestamp: ts}}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", `"2024-05-01T12:00:00.123456789Z"`},
+		{nodepkg.TimeFormatRFC3339Nano, `"2024-05-01T12:00:00.123456789Z"`},
+		{nodepkg.TimeFormatRFC3339, `"2024-05-01T12:00:00Z"`},
+		{nodepkg.TimeFormatUnixMilli, `1714564800123`},
+	}
+	for _, tt := range tests {
+		n.SetTimeFormat(tt.format)
+		raw, err := json.Marshal(n.Snapshot().Log[0])
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		want := `{"action":"created","timestamp":` + tt.want + `}`
+		if string(raw) != want {
+			t.Errorf("time format %q: expected %s, got %s", tt.format, want, raw)
+		}
+	}
+}
+
+func TestQueueService_TimeFormat(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.TimeFormat = nodepkg.TimeFormatUnixMilli
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	other := queueservicepkg.NewQueueService()
+
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, "resource-1"); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	plain, _ := other.CreateNode("entity-1")
+
+	var got struct {
+		Log []struct {
+			Timestamp json.RawMessage `json:"timestamp"`
+		} `json:"log"`
+	}
+	for _, tc := range []struct {
+		qs     *queueservicepkg.QueueService
+		id     string
+		quoted bool
+	}{{qs, n.ID, false}, {other, plain.ID, true}} {
+		view, err := tc.qs.GetNodeView(tc.id)
+		if err != nil {
+			t.Fatalf("GetNodeView: %v", err)
+		}
+		raw, _ := json.Marshal(view)
+		if err := json.Unmarshal(raw, &got); err != nil || len(got.Log) == 0 {
+			t.Fatalf("unexpected node JSON %s: %v", raw, err)
+		}
+		for _, entry := range got.Log {
+			if quoted := strings.HasPrefix(string(entry.Timestamp), `"`); quoted != tc.quoted {
+				t.Errorf("expected quoted=%v timestamps, got %s", tc.quoted, raw)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+	"nodequeue-service/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs an in-memory span recorder as the global tracer provider for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = tp.Shutdown(context.Background())
+	})
+	return sr
+}
+
+func spanAttr(s sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracing_CreateAndMoveFlow(t *testing.T) {
+	sr := recordSpans(t)
+
+	qs := queueservicepkg.NewQueueServiceWithStore(&stubStore{})
+	qs.AddResource(resourcepkg.NewResource("resource-1", 5))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nodes", qs.CreateNodeHandler)
+	handler := tracing.Middleware(mux, mux)
+
+	body := []byte(`{"entity_name": "traced", "resource_id": "resource-1"}`)
+	req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		byName[s.Name()] = s
+	}
+
+	root, ok := byName["POST /nodes"]
+	if !ok {
+		t.Fatalf("expected a request span, got %v", spanNames(sr))
+	}
+	create, ok := byName["QueueService.CreateNode"]
+	if !ok {
+		t.Fatalf("expected a CreateNode span, got %v", spanNames(sr))
+	}
+	// Creating with a resource assigns the node in the same operation, so there is no MoveNode span.
+	if _, ok := byName["QueueService.MoveNode"]; ok {
+		t.Errorf("expected no separate MoveNode span, got %v", spanNames(sr))
+	}
+	persist, ok := byName["store.PersistNodeCreatedWithResource"]
+	if !ok {
+		t.Fatalf("expected a store span, got %v", spanNames(sr))
+	}
+
+	if create.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Error("expected service span to be a child of the request span")
+	}
+	if persist.Parent().SpanID() != create.SpanContext().SpanID() {
+		t.Error("expected store span to be a child of the CreateNode span")
+	}
+
+	if spanAttr(create, "node.id") == "" {
+		t.Error("expected node.id on the create span")
+	}
+	if got := spanAttr(create, "resource.target_id"); got != "resource-1" {
+		t.Errorf("expected resource.target_id resource-1, got %q", got)
+	}
+	if got := spanAttr(root, "http.response.status_code"); got != "201" {
+		t.Errorf("expected status code attribute 201, got %q", got)
+	}
+}
+
+func spanNames(sr *tracetest.SpanRecorder) []string {
+	names := make([]string, 0)
+	for _, s := range sr.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+)
+
+// newTransferService returns a service with resource A (capacity 2) and B (capacity 1), plus a
+// node in service on A.
+func newTransferService(t *testing.T) (*queueservicepkg.QueueService, string) {
+	t.Helper()
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("A", 2))
+	qs.AddResource(resourcepkg.NewResource("B", 1))
+
+	n, _ := qs.CreateNode("entity-1")
+	if err := qs.MoveNode(n.ID, "A"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if err := qs.AllocateNode(n.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+	return qs, n.ID
+}
+
+func TestTransferAndAllocate_MovesSlot(t *testing.T) {
+	qs, nodeID := newTransferService(t)
+
+	if err := qs.TransferAndAllocate(nodeID, "B"); err != nil {
+		t.Fatalf("TransferAndAllocate failed: %v", err)
+	}
+
+	a, _ := qs.GetResource("A")
+	b, _ := qs.GetResource("B")
+	if a.IsInService(nodeID) || a.GetAvailableCapacity() != 2 {
+		t.Errorf("expected A's slot to be freed, available=%d", a.GetAvailableCapacity())
+	}
+	if !b.IsInService(nodeID) || !b.IsFull() {
+		t.Errorf("expected node in service on B")
+	}
+
+	n, _ := qs.GetNode(nodeID)
+	if n.ResourceID != "B" {
+		t.Errorf("expected resource B, got %q", n.ResourceID)
+	}
+	last := n.Log[len(n.Log)-2:]
+	if last[0].Action != "moved_to_waiting_queue" || last[1].Action != "moved_to_service_queue" || last[1].ResourceID != "B" {
+		t.Errorf("unexpected log tail: %+v", last)
+	}
+}
+
+func TestTransferAndAllocate_TargetFullLeavesNodeInPlace(t *testing.T) {
+	qs, nodeID := newTransferService(t)
+
+	other, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(other.ID, "B")
+	qs.AllocateNode(other.ID)
+
+	err := qs.TransferAndAllocate(nodeID, "B")
+	if !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Fatalf("expected ErrCapacityFull, got %v", err)
+	}
+
+	a, _ := qs.GetResource("A")
+	if !a.IsInService(nodeID) {
+		t.Error("expected node to stay in service on A")
+	}
+	n, _ := qs.GetNode(nodeID)
+	if n.ResourceID != "A" || n.Log[len(n.Log)-1].Action != "moved_to_service_queue" {
+		t.Errorf("expected node state unchanged, got resource=%q log=%+v", n.ResourceID, n.Log)
+	}
+}
+
+func TestTransferAndAllocate_ContendedSlot(t *testing.T) {
+	qs, first := newTransferService(t)
+
+	second, _ := qs.CreateNode("entity-2")
+	qs.MoveNode(second.ID, "A")
+	qs.AllocateNode(second.ID)
+
+	// A third node is already waiting on B and races for the same slot with a plain allocate.
+	waiter, _ := qs.CreateNode("entity-3")
+	qs.MoveNode(waiter.ID, "B")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, nodeID := range []string{first, second.ID} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = qs.TransferAndAllocate(nodeID, "B")
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[2] = qs.AllocateNode(waiter.ID)
+	}()
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, queueservicepkg.ErrCapacityFull):
+			t.Errorf("call %d: expected ErrCapacityFull for the losers, got %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one winner for B's slot, got %d (%v)", succeeded, errs)
+	}
+
+	b, _ := qs.GetResource("B")
+	if service, _ := b.QueueSnapshot(); len(service) != 1 {
+		t.Errorf("expected exactly one node in service on B, got %d", len(service))
+	}
+	a, _ := qs.GetResource("A")
+	for i, nodeID := range []string{first, second.ID} {
+		if errs[i] != nil && !a.IsInService(nodeID) {
+			t.Errorf("losing transfer of %s should leave it in service on A", nodeID)
+		}
+	}
+}
+
+func TestTransferNodeHandler(t *testing.T) {
+	qs, nodeID := newTransferService(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+nodeID+"/transfer", strings.NewReader(`{"target_resource_id": "B"}`))
+	w := httptest.NewRecorder()
+	qs.TransferNodeHandler(w, req, nodeID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/nodes/"+nodeID+"/transfer", strings.NewReader(`{"target_resource_id": "B"}`))
+	w = httptest.NewRecorder()
+	qs.TransferNodeHandler(w, req, nodeID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for same-resource transfer, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTransferAndAllocate_FIFOStrictTargetWithWaitingNodes(t *testing.T) {
+	qs, nodeID := newTransferService(t)
+	b, _ := qs.GetResource("B")
+	b.FIFOStrict = true
+
+	waiting, _ := qs.CreateNode("entity-2")
+	if err := qs.MoveNode(waiting.ID, "B"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+
+	err := qs.TransferAndAllocate(nodeID, "B")
+	if !errors.Is(err, queueservicepkg.ErrNotQueueHead) {
+		t.Fatalf("expected ErrNotQueueHead, got %v", err)
+	}
+	a, _ := qs.GetResource("A")
+	if !a.IsInService(nodeID) || b.IsInService(nodeID) {
+		t.Error("expected node to stay in service on A")
+	}
+
+	// Once the queue is empty the transfer goes through.
+	if err := qs.MoveNode(waiting.ID, "A"); err != nil {
+		t.Fatalf("MoveNode failed: %v", err)
+	}
+	if err := qs.TransferAndAllocate(nodeID, "B"); err != nil {
+		t.Fatalf("TransferAndAllocate failed: %v", err)
+	}
+}
+
+func TestTransferAndAllocate_PriorityLaneUsesReservedCapacity(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	for _, id := range []string{"A", "B"} {
+		r := resourcepkg.NewResource(id, 2)
+		r.LaneOrder = []string{"priority"}
+		r.ReservedForPriority = 1
+		qs.AddResource(r)
+	}
+	// B's one unreserved unit is taken.
+	busy, _ := qs.CreateNode("entity-busy")
+	qs.MoveNode(busy.ID, "B")
+	if err := qs.AllocateNode(busy.ID); err != nil {
+		t.Fatalf("AllocateNode failed: %v", err)
+	}
+
+	normal, _ := qs.CreateNode("entity-normal")
+	urgent, _ := qs.CreateNode("entity-urgent")
+	qs.MoveNode(normal.ID, "A")
+	qs.MoveNodeToLane(urgent.ID, "A", "priority")
+
+	if err := qs.TransferAndAllocate(normal.ID, "B"); !errors.Is(err, queueservicepkg.ErrCapacityFull) {
+		t.Errorf("expected a default-lane node to be refused the reserved unit, got %v", err)
+	}
+	if err := qs.TransferAndAllocate(urgent.ID, "B"); err != nil {
+		t.Fatalf("expected a priority-lane node to use the reserved unit, got %v", err)
+	}
+	if b, _ := qs.GetResource("B"); !b.IsInService(urgent.ID) {
+		t.Error("expected the priority node in service on B")
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+	"nodequeue-service/utils"
+)
+
+// decodeValidationError asserts a 400 invalid_request response and returns its fields map.
+func decodeValidationError(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp utils.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if resp.Code != queueservicepkg.CodeInvalidRequest {
+		t.Errorf("Expected code '%s', got '%s'", queueservicepkg.CodeInvalidRequest, resp.Code)
+	}
+	return resp.Fields
+}
+
+func TestCreateNodeHandler_Validation(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+
+	cases := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"empty entity_name", `{"entity_name": ""}`, "entity_name"},
+		{"whitespace entity_name", `{"entity_name": "   "}`, "entity_name"},
+		{"unknown field", `{"entity_name": "e", "priority": 1}`, "priority"},
+		{"wrong type", `{"entity_name": 123}`, "entity_name"},
+		{"malformed JSON", `{"entity_name": `, "body"},
+		{"empty body", ``, "body"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/nodes", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+			qs.CreateNodeHandler(w, req)
+
+			fields := decodeValidationError(t, w)
+			if _, ok := fields[tc.field]; !ok {
+				t.Errorf("Expected field '%s' in %v", tc.field, fields)
+			}
+		})
+	}
+}
+
+func TestCreateResourceHandler(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+
+	req := httptest.NewRequest(http.MethodPost, "/resources", bytes.NewBufferString(`{"id": "resource-1", "capacity": 2}`))
+	w := httptest.NewRecorder()
+	qs.CreateResourceHandler(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if r, err := qs.GetResource("resource-1"); err != nil || r.Capacity != 2 {
+		t.Errorf("Expected resource-1 with capacity 2, got %v, %v", r, err)
+	}
+
+	// Duplicate ID
+	req = httptest.NewRequest(http.MethodPost, "/resources", bytes.NewBufferString(`{"id": "resource-1", "capacity": 2}`))
+	w = httptest.NewRecorder()
+	qs.CreateResourceHandler(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	assertErrorCode(t, w, queueservicepkg.CodeResourceExists)
+
+	cases := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"zero capacity", `{"id": "resource-2", "capacity": 0}`, "capacity"},
+		{"negative capacity", `{"id": "resource-2", "capacity": -1}`, "capacity"},
+		{"capacity wrong type", `{"id": "resource-2", "capacity": "five"}`, "capacity"},
+		{"missing id", `{"capacity": 1}`, "id"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/resources", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+			qs.CreateResourceHandler(w, req)
+
+			fields := decodeValidationError(t, w)
+			if _, ok := fields[tc.field]; !ok {
+				t.Errorf("Expected field '%s' in %v", tc.field, fields)
+			}
+		})
+	}
+}
+
+func TestMoveNodeHandler_UnknownField(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	created, _ := qs.CreateNode("entity-1")
+
+	req := httptest.NewRequest(http.MethodPost, "/nodes/"+created.ID+"/move", bytes.NewBufferString(`{"target": "resource-1"}`))
+	w := httptest.NewRecorder()
+	qs.MoveNodeHandler(w, req, created.ID)
+
+	fields := decodeValidationError(t, w)
+	if fields["target"] != "unknown field" {
+		t.Errorf("Expected 'target' reported as unknown field, got %v", fields)
+	}
+}
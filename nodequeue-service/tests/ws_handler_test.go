@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	queueservicepkg "nodequeue-service/queueservice"
+	resourcepkg "nodequeue-service/resource"
+
+	"github.com/gorilla/websocket"
+)
+
+// readResponse reads frames until the response for the given command id arrives,
+// collecting any event frames seen along the way.
+func readResponse(t *testing.T, conn *websocket.Conn, id string, events *[]queueservicepkg.NodeEvent) queueservicepkg.WSFrame {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var frame queueservicepkg.WSFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("failed to read frame: %v", err)
+		}
+		switch frame.Type {
+		case "event":
+			*events = append(*events, *frame.Event)
+		case "response":
+			if frame.ID != id {
+				t.Fatalf("expected response id %s, got %s", id, frame.ID)
+			}
+			return frame
+		default:
+			t.Fatalf("unexpected frame type %q", frame.Type)
+		}
+	}
+}
+
+func TestWebSocketHandler_CreateMoveAllocateComplete(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 1))
+	qs.AddResource(resourcepkg.NewResource("resource-2", 1))
+
+	srv := httptest.NewServer(http.HandlerFunc(qs.WebSocketHandler))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	var events []queueservicepkg.NodeEvent
+
+	send := func(cmd queueservicepkg.WSCommand) queueservicepkg.WSFrame {
+		t.Helper()
+		if err := conn.WriteJSON(cmd); err != nil {
+			t.Fatalf("failed to write command: %v", err)
+		}
+		return readResponse(t, conn, cmd.ID, &events)
+	}
+
+	resp := send(queueservicepkg.WSCommand{ID: "1", Op: "create", EntityName: "entity-1", ResourceID: "resource-1"})
+	if !resp.OK || resp.Node == nil {
+		t.Fatalf("create failed: %+v", resp)
+	}
+	nodeID := resp.Node.ID
+	if resp.Node.ResourceID != "resource-1" {
+		t.Fatalf("expected node in resource-1, got %q", resp.Node.ResourceID)
+	}
+
+	resp = send(queueservicepkg.WSCommand{ID: "2", Op: "move", NodeID: nodeID, TargetResourceID: "resource-2"})
+	if !resp.OK || resp.Node.ResourceID != "resource-2" {
+		t.Fatalf("move failed: %+v", resp)
+	}
+
+	resp = send(queueservicepkg.WSCommand{ID: "3", Op: "allocate", NodeID: nodeID})
+	if !resp.OK {
+		t.Fatalf("allocate failed: %+v", resp)
+	}
+
+	// Allocating again must fail with a correlated error response.
+	resp = send(queueservicepkg.WSCommand{ID: "4", Op: "allocate", NodeID: nodeID})
+	if resp.OK || resp.Code != queueservicepkg.CodeNodeInService {
+		t.Fatalf("expected node_in_service error, got %+v", resp)
+	}
+
+	resp = send(queueservicepkg.WSCommand{ID: "5", Op: "complete", NodeID: nodeID})
+	if !resp.OK || !resp.Node.Completed {
+		t.Fatalf("complete failed: %+v", resp)
+	}
+
+	resp = send(queueservicepkg.WSCommand{ID: "6", Op: "bogus"})
+	if resp.OK || resp.Code != queueservicepkg.CodeInvalidRequest {
+		t.Fatalf("expected invalid_request for unknown op, got %+v", resp)
+	}
+
+	// Events are delivered asynchronously; wait for the full lifecycle to arrive.
+	want := []string{"created", "moved_to_waiting_queue", "moved_to_waiting_queue", "moved_to_service_queue", "completed"}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(events) < len(want) && time.Now().Before(deadline) {
+		_ = conn.SetReadDeadline(deadline)
+		var frame queueservicepkg.WSFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("failed to read event frame: %v", err)
+		}
+		if frame.Type == "event" {
+			events = append(events, *frame.Event)
+		}
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, action := range want {
+		if events[i].Action != action || events[i].NodeID != nodeID {
+			t.Errorf("event %d: expected %s for %s, got %+v", i, action, nodeID, events[i])
+		}
+	}
+}
+
+func TestInternals_CountsWebSocketSubscribers(t *testing.T) {
+	qs := queueservicepkg.NewQueueService()
+	qs.AddResource(resourcepkg.NewResource("resource-1", 2))
+
+	waitForSubscribers := func(want int) queueservicepkg.InternalsResponse {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got := qs.Internals()
+			if got.EventSubscribers == want {
+				return got
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d event subscribers, got %d", want, got.EventSubscribers)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	before := waitForSubscribers(0)
+	if len(before.Resources) != 1 || before.Resources[0].ID != "resource-1" || before.LastRestoreAt != nil {
+		t.Errorf("unexpected internals: %+v", before)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(qs.WebSocketHandler))
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+
+	if open := waitForSubscribers(1); open.Goroutines == 0 {
+		t.Error("expected a goroutine count")
+	}
+
+	conn.Close()
+	waitForSubscribers(0)
+}
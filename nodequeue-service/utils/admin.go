@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Error codes returned by AdminGuard.
+const (
+	CodeAdminDisabled = "admin_disabled"
+	CodeUnauthorized  = "unauthorized"
+)
+
+// AdminGuard protects destructive admin endpoints. They are refused unless Enabled is set, and
+// then require the APIKeyHeader to equal APIKey. An empty APIKey refuses every request, so
+// enabling admin without a key does not open the endpoints.
+type AdminGuard struct {
+	Enabled bool
+	APIKey  string
+}
+
+// AdminGuardFromEnv reads ENABLE_ADMIN (a bool, default false) and ADMIN_API_KEY.
+func AdminGuardFromEnv() AdminGuard {
+	enabled, _ := strconv.ParseBool(os.Getenv("ENABLE_ADMIN"))
+	return AdminGuard{Enabled: enabled, APIKey: os.Getenv("ADMIN_API_KEY")}
+}
+
+// Wrap returns next guarded by g: 403 admin_disabled when disabled, 401 unauthorized on a
+// missing or wrong key.
+func (g AdminGuard) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !g.Enabled {
+			RespondWithErrorCode(w, http.StatusForbidden, CodeAdminDisabled, "admin endpoints are disabled (set ENABLE_ADMIN=true)")
+			return
+		}
+		key := r.Header.Get(APIKeyHeader)
+		if g.APIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(g.APIKey)) != 1 {
+			RespondWithErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid "+APIKeyHeader)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDebugBodyLimit is how many bytes of each request and response body DebugLogger logs
+// unless DEBUG_HTTP_BODY_LIMIT says otherwise.
+const DefaultDebugBodyLimit = 2048
+
+// DefaultRedactedHeaders are always redacted by DebugLoggerFromEnv; DEBUG_HTTP_REDACT_HEADERS adds
+// to them.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", APIKeyHeader}
+
+// redacted replaces the value of a header listed in DebugLogger.RedactHeaders.
+const redacted = "[REDACTED]"
+
+// DebugLogger logs each request's method, path, status and duration together with its headers
+// and the start of its request and response bodies, for troubleshooting client integrations.
+//
+// Bodies are copied as the handler reads and writes them, so the response is neither buffered
+// nor changed. Server-Sent Event and WebSocket requests are passed through untouched and only
+// logged with their duration.
+type DebugLogger struct {
+	// BodyLimit caps how many bytes of each body are logged; longer bodies are truncated.
+	BodyLimit int
+	// RedactHeaders lists headers (case-insensitive) whose values are logged as "[REDACTED]".
+	RedactHeaders []string
+	// Logf receives one entry per request; Now times requests. Both may be replaced in tests.
+	Logf func(format string, args ...any)
+	Now  func() time.Time
+}
+
+// NewDebugLogger returns a DebugLogger with DefaultDebugBodyLimit and DefaultRedactedHeaders
+// that writes to the standard logger.
+func NewDebugLogger() *DebugLogger {
+	return &DebugLogger{
+		BodyLimit:     DefaultDebugBodyLimit,
+		RedactHeaders: slices.Clone(DefaultRedactedHeaders),
+		Logf:          log.Printf,
+		Now:           time.Now,
+	}
+}
+
+// DebugLoggerFromEnv builds a DebugLogger when DEBUG_HTTP is true, and returns nil (no logging,
+// and no middleware to install) otherwise. DEBUG_HTTP_BODY_LIMIT sets BodyLimit and
+// DEBUG_HTTP_REDACT_HEADERS is a comma-separated list of extra headers to redact.
+func DebugLoggerFromEnv() (*DebugLogger, error) {
+	raw := os.Getenv("DEBUG_HTTP")
+	if raw == "" {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEBUG_HTTP %q: %w", raw, err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	d := NewDebugLogger()
+	if raw := os.Getenv("DEBUG_HTTP_BODY_LIMIT"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid DEBUG_HTTP_BODY_LIMIT %q: must be a non-negative integer", raw)
+		}
+		d.BodyLimit = limit
+	}
+	for _, name := range strings.Split(os.Getenv("DEBUG_HTTP_REDACT_HEADERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			d.RedactHeaders = append(d.RedactHeaders, name)
+		}
+	}
+	return d, nil
+}
+
+// Handler wraps next with request/response logging.
+func (d *DebugLogger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := d.Now()
+		if isStreamingRequest(r) {
+			next.ServeHTTP(w, r)
+			d.Logf("[HTTP] %s %s - streaming, bodies not captured (took %v)\n  request headers: %s",
+				r.Method, r.URL.RequestURI(), d.Now().Sub(start), d.formatHeaders(r.Header))
+			return
+		}
+
+		reqBody := &debugBuffer{limit: d.BodyLimit}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &teeReadCloser{ReadCloser: r.Body, buf: reqBody}
+		}
+		dw := &debugResponseWriter{ResponseWriter: w, status: http.StatusOK, body: debugBuffer{limit: d.BodyLimit}}
+
+		next.ServeHTTP(dw, r)
+
+		d.Logf("[HTTP] %s %s - %d (took %v)\n  request headers: %s\n  request body: %s\n  response headers: %s\n  response body: %s",
+			r.Method, r.URL.RequestURI(), dw.status, d.Now().Sub(start),
+			d.formatHeaders(r.Header), reqBody.String(""),
+			d.formatHeaders(w.Header()), dw.body.String(w.Header().Get("Content-Encoding")))
+	})
+}
+
+// formatHeaders renders h sorted by name, one "Name: value" per header, with redacted values.
+func (d *DebugLogger) formatHeaders(h http.Header) string {
+	if len(h) == 0 {
+		return "(none)"
+	}
+	parts := make([]string, 0, len(h))
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		value := strings.Join(h.Values(name), ", ")
+		if slices.ContainsFunc(d.RedactHeaders, func(r string) bool { return strings.EqualFold(r, name) }) {
+			value = redacted
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// debugBuffer keeps the first limit bytes written to it and counts the rest.
+type debugBuffer struct {
+	limit int
+	data  []byte
+	total int
+}
+
+func (b *debugBuffer) Write(p []byte) {
+	b.total += len(p)
+	if room := b.limit - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+}
+
+// String quotes the captured body, noting truncation. An encoded body (e.g. gzip) is only sized.
+func (b *debugBuffer) String(encoding string) string {
+	switch {
+	case b.total == 0:
+		return "(empty)"
+	case encoding != "":
+		return fmt.Sprintf("(%d bytes, %s-encoded)", b.total, encoding)
+	case b.total > len(b.data):
+		return fmt.Sprintf("%q... (truncated, %d bytes)", b.data, b.total)
+	}
+	return strconv.Quote(string(b.data))
+}
+
+// teeReadCloser copies what the handler reads from a request body into buf.
+type teeReadCloser struct {
+	io.ReadCloser
+	buf *debugBuffer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	return n, err
+}
+
+// debugResponseWriter records the status and copies the body while writing straight through.
+type debugResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        debugBuffer
+}
+
+// Unwrap returns the underlying writer (see http.ResponseController).
+func (w *debugResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *debugResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *debugResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.body.Write(p[:n])
+	return n, err
+}
+
+func (w *debugResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes through to the underlying writer so /ws upgrades keep working.
+func (w *debugResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("utils: response does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
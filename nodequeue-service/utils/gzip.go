@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// GzipMinSize is the smallest response body (in bytes) that GzipMiddleware will compress.
+// Smaller bodies are sent as-is since gzip overhead outweighs the savings.
+const GzipMinSize = 1024
+
+// GzipMiddleware compresses responses with gzip when the client sends Accept-Encoding: gzip.
+//
+// Bodies below GzipMinSize, Server-Sent Event streams, and WebSocket upgrades are passed through
+// uncompressed.
+func GzipMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r) || isStreamingRequest(r) {
+			next(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.Close()
+		next(gw, r)
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func isStreamingRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// gzipResponseWriter buffers the first GzipMinSize bytes to decide whether to compress, then
+// either streams through a gzip.Writer or writes the buffer verbatim on Close.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	gz          *gzip.Writer
+	passthrough bool
+	err         error
+}
+
+// Unwrap returns the underlying writer (see http.ResponseController).
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		// A previous write failed; stop writing rather than emit a truncated/corrupt stream.
+		return 0, w.err
+	}
+	w.wroteHeader = true
+
+	switch {
+	case w.gz != nil:
+		return w.writeGzip(p)
+	case w.passthrough:
+		return w.writeRaw(p)
+	}
+
+	// Never compress event streams or responses that are already encoded.
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.writeRaw(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() < GzipMinSize {
+		return len(p), nil
+	}
+	if err := w.startGzip(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *gzipResponseWriter) startGzip() error {
+	h := w.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	buffered := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	_, err := w.writeGzip(buffered)
+	return err
+}
+
+func (w *gzipResponseWriter) startPassthrough() error {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	buffered := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	_, err := w.writeRaw(buffered)
+	return err
+}
+
+func (w *gzipResponseWriter) writeGzip(p []byte) (int, error) {
+	n, err := w.gz.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *gzipResponseWriter) writeRaw(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := w.ResponseWriter.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Flush sends any buffered data to the client. Flushing before the size threshold is reached
+// commits to an uncompressed response so streaming handlers are never held back.
+func (w *gzipResponseWriter) Flush() {
+	if w.err != nil {
+		return
+	}
+	switch {
+	case w.gz != nil:
+		if err := w.gz.Flush(); err != nil {
+			w.err = err
+			return
+		}
+	case !w.passthrough:
+		if err := w.startPassthrough(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response: it terminates the gzip stream, or writes out a small buffered body
+// uncompressed.
+func (w *gzipResponseWriter) Close() error {
+	if w.gz != nil {
+		if w.err != nil {
+			return w.err
+		}
+		return w.gz.Close()
+	}
+	if w.passthrough {
+		return w.err
+	}
+	// Below the threshold (or nothing written at all): send as-is.
+	return w.startPassthrough()
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CamelCaseKeys returns v as generic JSON with every object key converted from snake_case to
+// camelCase (e.g. "resource_id" -> "resourceId"). Values are left untouched.
+//
+// It round-trips through encoding/json, so it is meant for response-sized payloads where a
+// client opted into camelCase, not hot paths.
+func CamelCaseKeys(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return camelCaseValue(generic), nil
+}
+
+func camelCaseValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[SnakeToCamel(k)] = camelCaseValue(val)
+		}
+		return out
+	case []any:
+		for i, val := range t {
+			t[i] = camelCaseValue(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// SnakeToCamel converts a snake_case identifier to camelCase.
+func SnakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, p := range parts[1:] {
+		if p == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Error formats accepted by ERROR_FORMAT.
+const (
+	// ErrorFormatSimple writes errors as an ErrorResponse ({"error": "...", "code": "..."}).
+	ErrorFormatSimple = "simple"
+	// ErrorFormatProblem writes errors as RFC 7807 application/problem+json (see Problem and
+	// ProblemMiddleware).
+	ErrorFormatProblem = "problem"
+)
+
+// ValidErrorFormats lists the accepted ERROR_FORMAT values.
+var ValidErrorFormats = []string{ErrorFormatSimple, ErrorFormatProblem}
+
+// ProblemTypeBase prefixes an error code to form its Problem.Type, e.g.
+// "urn:nodequeue:problem:node_not_found". Errors without a code use "about:blank".
+var ProblemTypeBase = "urn:nodequeue:problem:"
+
+// ProblemContentType is the media type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 form of an ErrorResponse, written for requests served through
+// ProblemMiddleware. Code, Fields and NodeID are kept as extension members.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	NodeID   string            `json:"node_id,omitempty"`
+}
+
+// NewProblem converts an ErrorResponse written with statusCode. The type and title depend only on
+// the error code, so they stay the same for every occurrence: the code "node_not_found" becomes
+// type ProblemTypeBase+"node_not_found" and title "Node not found". Without a code the type is
+// "about:blank" and the title is the HTTP status text.
+func NewProblem(statusCode int, e ErrorResponse) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: e.Error,
+		Code:   e.Code,
+		Fields: e.Fields,
+		NodeID: e.NodeID,
+	}
+	if e.Code != "" {
+		p.Type = ProblemTypeBase + e.Code
+		title := strings.ReplaceAll(e.Code, "_", " ")
+		p.Title = strings.ToUpper(title[:1]) + title[1:]
+	}
+	return p
+}
+
+// respondWithProblem writes e as a Problem with the given instance.
+func respondWithProblem(w http.ResponseWriter, statusCode int, e ErrorResponse, instance string) {
+	p := NewProblem(statusCode, e)
+	p.Instance = instance
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// problemWriter marks a response as one whose errors are written as Problems, and carries the
+// request path for Problem.Instance down to the handler's writer.
+type problemWriter struct {
+	http.ResponseWriter
+	instance string
+}
+
+func (w *problemWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *problemWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes through to the underlying writer so /ws upgrades keep working.
+func (w *problemWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("utils: response does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// problemInstance finds the problemWriter under w, following Unwrap through wrapping writers
+// such as GzipMiddleware's, and returns its instance. ok is false if there is none, i.e. the
+// request was not served through ProblemMiddleware.
+func problemInstance(w http.ResponseWriter) (instance string, ok bool) {
+	for {
+		switch v := w.(type) {
+		case *problemWriter:
+			return v.instance, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return "", false
+		}
+	}
+}
+
+// ProblemMiddleware makes error responses written under it RFC 7807 Problems, with the request
+// path as their instance. Install it for ERROR_FORMAT=problem; without it errors are written as a
+// plain ErrorResponse.
+func ProblemMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&problemWriter{ResponseWriter: w, instance: r.URL.Path}, r)
+	})
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// CodeRateLimited is the ErrorResponse.Code for requests rejected by RateLimiter.
+const CodeRateLimited = "rate_limited"
+
+// APIKeyHeader identifies a client for rate limiting (see RateLimiter.SetAPIKeys) and admin
+// requests (see AdminGuard).
+const APIKeyHeader = "X-API-Key"
+
+// rateLimitExemptPaths are never rate limited so probes keep working under load.
+var rateLimitExemptPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// RateLimiter is a per-client token-bucket limiter, keyed by client IP or, for keys configured
+// with SetAPIKeys, by API key.
+//
+// Each client gets its own bucket refilled at rps with the given burst. Idle buckets are dropped
+// by Cleanup so the map does not grow without bound. Unknown API keys are ignored, so a client
+// cannot escape its limit (or grow the map) by sending a new key with every request.
+type RateLimiter struct {
+	rps     rate.Limit
+	burst   int
+	apiKeys map[string]bool
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter constructs a RateLimiter allowing rps requests per second per client with the
+// given burst.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rps:     rate.Limit(rps),
+		burst:   burst,
+		clients: make(map[string]*clientLimiter),
+	}
+}
+
+// SetAPIKeys sets the API keys that get a bucket of their own, shared by every client sending that
+// key. Requests with any other key, or none, are keyed by IP. Call it before serving requests.
+func (rl *RateLimiter) SetAPIKeys(keys []string) {
+	rl.apiKeys = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			rl.apiKeys[key] = true
+		}
+	}
+}
+
+// RateLimiterFromEnv builds a RateLimiter from RATE_LIMIT_RPS, RATE_LIMIT_BURST and
+// RATE_LIMIT_API_KEYS. It returns nil (rate limiting disabled) when RATE_LIMIT_RPS is unset or not
+// a positive number. RATE_LIMIT_BURST defaults to the RPS rounded up. RATE_LIMIT_API_KEYS is a
+// comma-separated list of keys for SetAPIKeys.
+func RateLimiterFromEnv() *RateLimiter {
+	rps, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_RPS"), 64)
+	if err != nil || rps <= 0 {
+		return nil
+	}
+	burst, err := strconv.Atoi(os.Getenv("RATE_LIMIT_BURST"))
+	if err != nil || burst <= 0 {
+		burst = int(math.Ceil(rps))
+	}
+	rl := NewRateLimiter(rps, burst)
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("RATE_LIMIT_API_KEYS"), ",") {
+		keys = append(keys, strings.TrimSpace(key))
+	}
+	rl.SetAPIKeys(keys)
+	return rl
+}
+
+// clientKey identifies the caller: a configured API key when present, otherwise the remote IP.
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" && rl.apiKeys[key] {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func (rl *RateLimiter) limiterFor(key string, now time.Time) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	c, ok := rl.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// Handler wraps next, rejecting requests over the client's limit with 429 and a Retry-After
+// header (whole seconds until a token is available).
+func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rateLimitExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		res := rl.limiterFor(rl.clientKey(r), now).ReserveN(now, 1)
+		if delay := res.DelayFrom(now); delay > 0 {
+			// Give the token back; this request is rejected, not queued.
+			res.CancelAt(now)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			RespondWithErrorCode(w, http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Cleanup drops clients not seen for longer than idle and returns how many were removed.
+func (rl *RateLimiter) Cleanup(idle time.Duration) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	cutoff := time.Now().Add(-idle)
+	removed := 0
+	for key, c := range rl.clients {
+		if c.lastSeen.Before(cutoff) {
+			delete(rl.clients, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Clients returns the number of tracked clients.
+func (rl *RateLimiter) Clients() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.clients)
+}
+
+// RunCleanup calls Cleanup every interval until ctx is cancelled.
+func (rl *RateLimiter) RunCleanup(ctx context.Context, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.Cleanup(idle)
+		}
+	}
+}
@@ -2,16 +2,36 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strings"
 )
 
 // ErrorResponse is a consistent JSON error envelope returned by handlers in this service.
+//
+// Code is a stable, machine-readable identifier (e.g. "node_not_found"); Error is the
+// human-readable message and may change wording over time. Fields is set for request validation
+// failures and maps each offending JSON field to what was wrong with it.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string            `json:"error"`
+	Code   string            `json:"code,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+	// NodeID names an existing node the request conflicts with (see entity_active).
+	NodeID string `json:"node_id,omitempty"`
 }
 
-// respondWithJSON writes a JSON response with the given status code.
+// respondWithJSON writes a JSON response with the given status code. An ErrorResponse payload is
+// written as a Problem instead when the request is served through ProblemMiddleware.
 func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
+	if e, ok := payload.(ErrorResponse); ok {
+		if instance, ok := problemInstance(w); ok {
+			respondWithProblem(w, statusCode, e, instance)
+			return
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	_ = json.NewEncoder(w).Encode(payload)
@@ -20,3 +40,108 @@ func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{})
 func RespondWithError(w http.ResponseWriter, statusCode int, message string) {
 	RespondWithJSON(w, statusCode, ErrorResponse{Error: message})
 }
+
+// RespondWithErrorCode writes an ErrorResponse including a machine-readable error code.
+func RespondWithErrorCode(w http.ResponseWriter, statusCode int, code, message string) {
+	RespondWithJSON(w, statusCode, ErrorResponse{Error: message, Code: code})
+}
+
+// ValidationError reports which request body fields failed decoding or validation.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e.Fields[k])
+	}
+	return "invalid request body: " + strings.Join(parts, "; ")
+}
+
+// Validator is implemented by request payloads that can check their own fields.
+// Validate returns a field->message map; an empty map means the payload is valid.
+type Validator interface {
+	Validate() map[string]string
+}
+
+// DecodeAndValidate decodes a JSON request body into dst, rejecting unknown fields and
+// type mismatches, then runs dst.Validate() if dst implements Validator.
+//
+// All failures are returned as *ValidationError so handlers can report them per field.
+func DecodeAndValidate(r *http.Request, dst interface{}) error {
+	return decodeAndValidate(r, dst, false)
+}
+
+// DecodeOptionalAndValidate is DecodeAndValidate for endpoints whose body may be omitted: an empty
+// body leaves dst untouched and is not an error.
+func DecodeOptionalAndValidate(r *http.Request, dst interface{}) error {
+	return decodeAndValidate(r, dst, true)
+}
+
+func decodeAndValidate(r *http.Request, dst interface{}, optional bool) error {
+	if optional && r.Body == nil {
+		return nil
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		if optional && errors.Is(err, io.EOF) {
+			return nil
+		}
+		return &ValidationError{Fields: decodeErrorFields(err)}
+	}
+
+	if v, ok := dst.(Validator); ok {
+		if fields := v.Validate(); len(fields) > 0 {
+			return &ValidationError{Fields: fields}
+		}
+	}
+	return nil
+}
+
+// decodeErrorFields maps a json decoding error onto the offending field.
+func decodeErrorFields(err error) map[string]string {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return map[string]string{field: fmt.Sprintf("must be of type %s", typeErr.Type)}
+	case errors.As(err, &syntaxErr):
+		return map[string]string{"body": fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}
+	case errors.Is(err, io.EOF):
+		return map[string]string{"body": "request body is required"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return map[string]string{"body": "malformed JSON"}
+	}
+
+	// encoding/json has no typed error for unknown fields: `json: unknown field "foo"`.
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return map[string]string{strings.Trim(name, `"`): "unknown field"}
+	}
+	return map[string]string{"body": err.Error()}
+}
+
+// RespondWithValidationError writes a 400 ErrorResponse with per-field messages.
+// Errors that are not *ValidationError are reported against the "body" field.
+func RespondWithValidationError(w http.ResponseWriter, code string, err error) {
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) {
+		vErr = &ValidationError{Fields: map[string]string{"body": err.Error()}}
+	}
+	RespondWithJSON(w, http.StatusBadRequest, ErrorResponse{
+		Error:  "Invalid request body",
+		Code:   code,
+		Fields: vErr.Fields,
+	})
+}